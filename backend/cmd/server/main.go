@@ -12,16 +12,30 @@ import (
 	chatApp "unlimited-corp/internal/application/chat"
 	companyApp "unlimited-corp/internal/application/company"
 	employeeApp "unlimited-corp/internal/application/employee"
+	"unlimited-corp/internal/application/executor"
+	hotspotApp "unlimited-corp/internal/application/hotspot"
+	publishingApp "unlimited-corp/internal/application/publishing"
+	"unlimited-corp/internal/application/scheduler"
 	skillcardApp "unlimited-corp/internal/application/skillcard"
 	taskApp "unlimited-corp/internal/application/task"
 	userApp "unlimited-corp/internal/application/user"
+	workflowApp "unlimited-corp/internal/application/workflow"
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/domain/task"
 	"unlimited-corp/internal/infrastructure/cache"
 	"unlimited-corp/internal/infrastructure/config"
 	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/internal/infrastructure/hotspotsource"
+	"unlimited-corp/internal/infrastructure/inprocess"
+	"unlimited-corp/internal/infrastructure/outbox"
 	"unlimited-corp/internal/infrastructure/persistence"
+	"unlimited-corp/internal/infrastructure/publisher"
+	"unlimited-corp/internal/infrastructure/temporal"
 	httpServer "unlimited-corp/internal/interfaces/http"
 	"unlimited-corp/pkg/jwt"
 	"unlimited-corp/pkg/logger"
+	"unlimited-corp/pkg/secret"
 )
 
 func main() {
@@ -73,17 +87,167 @@ func main() {
 	employeeRepo := persistence.NewEmployeeRepository(db)
 	taskRepo := persistence.NewTaskRepository(db.DB)
 	chatRepo := persistence.NewChatRepository(db.DB)
+	workflowTemplateRepo := persistence.NewWorkflowTemplateRepository(db.DB)
+	hotspotRepo := persistence.NewHotspotRepository(db.DB)
+	publishingRepo := persistence.NewPublishingRepository(db.DB)
+	// 发布凭证加密存储，Worker 须配置相同密钥；未配置密钥时凭证接口返回 503
+	if cfg.Publishing.EncryptionKey != "" {
+		box, err := secret.NewBox(cfg.Publishing.EncryptionKey)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to create publishing credential cipher: %v", err))
+		}
+		publishingRepo.SetSecretBox(box)
+	} else {
+		logger.Warn("Publishing credentials are unavailable: set PUBLISHING_ENCRYPTION_KEY")
+	}
+	outboxRepo := persistence.NewOutboxRepository(db.DB)
+	txManager := database.NewTxManager(db.DB)
+
+	// 事件总线：redis 与 kafka 驱动下事件经消息中间件在 API 服务与 Worker 之间共享
+	var brokerBus interface {
+		eventbus.EventBus
+		Start(ctx context.Context)
+		Stop()
+	}
+	switch cfg.EventBus.Driver {
+	case "", config.EventBusDriverMemory:
+	case config.EventBusDriverRedis:
+		if redis == nil {
+			logger.Fatal("Event bus driver redis requires a redis connection")
+		}
+		brokerBus = newRedisBus(cfg, redis)
+	case config.EventBusDriverKafka:
+		if len(cfg.Kafka.Brokers) == 0 {
+			logger.Fatal("Event bus driver kafka requires kafka.brokers")
+		}
+		brokerBus = newKafkaBus(cfg)
+	default:
+		logger.Fatal(fmt.Sprintf("Unknown event bus driver %q", cfg.EventBus.Driver))
+	}
+	if brokerBus != nil {
+		eventbus.SetEventBus(brokerBus)
+		defer brokerBus.Stop()
+	}
+
+	// 事件发件箱：事件与业务数据在同一事务中写入，由中继按顺序投递；消费者按事件 ID 去重
+	eventBus := eventbus.GetEventBus()
+	eventBus.SetDedupStore(outboxRepo)
+	var outboxRelay *outbox.Relay
+	if cfg.EventBus.Outbox.Enabled {
+		outboxRelay = newOutboxRelay(cfg, outboxRepo, txManager, eventBus)
+		eventBus.SetOutbox(outboxRelay)
+	}
+
+	// 热点数据源
+	hotspotSources, err := hotspotsource.NewRegistry(cfg.Hotspot)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to load hotspot sources: %v", err))
+	}
+
+	// 内容发布平台
+	publishers, err := publisher.NewRegistry(cfg.Publishing)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to load publishing platforms: %v", err))
+	}
 
 	// 初始化服务
 	userService := userApp.NewService(userRepo, jwt.GetManager())
 	companyService := companyApp.NewService(companyRepo)
 	skillCardService := skillcardApp.NewService(skillCardRepo)
-	employeeService := employeeApp.NewService(employeeRepo)
+	employeeService := employeeApp.NewService(employeeRepo, employeeRepo)
+	employeeService.SetUnitOfWork(txManager)
 	taskService := taskApp.NewService(taskRepo)
+	taskService.SetUnitOfWork(txManager)
+	taskService.SetApprovalRepository(taskRepo)
+	taskService.SetAttemptRepository(taskRepo)
+	taskService.SetStepRepository(taskRepo)
+	taskService.SetScheduleRepository(taskRepo)
 	chatService := chatApp.NewService(chatRepo, chatRepo)
+	workflowService := workflowApp.NewService(workflowTemplateRepo, taskService, skillCardRepo)
+	hotspotService := hotspotApp.NewService(hotspotRepo, hotspotSources, cfg.Hotspot.Limit)
+	publishingService := publishingApp.NewService(publishingRepo, publishers)
+	taskScheduler := scheduler.NewTaskScheduler(taskRepo, taskRepo, employeeRepo, employeeRepo, companyRepo, txManager)
+
+	// 共享执行槽位按套餐权重在公司间公平分配
+	taskScheduler.SetExecutionSlots(cfg.Scheduler.ExecutionSlots)
+	// 多个实例依次派发，并共享公平调度的虚拟时间
+	taskScheduler.SetDispatchRepository(taskRepo)
+
+	// 启动任务调度：事件驱动即时调度，定时轮询兜底
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	taskScheduler.Start()
+	pollInterval := cfg.Scheduler.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = 30 * time.Second
+	}
+	taskScheduler.StartBackgroundScheduler(schedulerCtx, pollInterval)
+
+	// 租约过期的卡死任务按策略重新排队或失败
+	recoveryCfg := cfg.Scheduler.Recovery
+	taskScheduler.SetRecoveryPolicy(scheduler.RecoveryPolicy{
+		LeaseTTL:       recoveryCfg.LeaseTTL,
+		OnExpiry:       task.RecoveryAction(recoveryCfg.OnExpiry),
+		MaxRequeues:    recoveryCfg.MaxRequeues,
+		EmployeeStatus: employee.Status(recoveryCfg.EmployeeStatus),
+	})
+	reaperInterval := recoveryCfg.ReaperInterval
+	if reaperInterval <= 0 {
+		reaperInterval = 15 * time.Second
+	}
+	taskScheduler.StartLeaseReaper(schedulerCtx, reaperInterval)
+
+	// 按员工工作日历自动上下线
+	availabilityManager := employeeApp.NewAvailabilityManager(employeeRepo, employeeRepo, companyRepo, txManager)
+	availabilityManager.Start(schedulerCtx, time.Minute)
+
+	switch cfg.Workflow.Engine {
+	case config.WorkflowEngineInProcess:
+		// 单机部署：工作流在服务进程内执行，步骤检查点写入数据库，重启后从检查点继续
+		workflowEngine := newInProcessEngine(cfg, skillCardRepo, taskRepo, employeeRepo, txManager, hotspotService, publishingService)
+		defer workflowEngine.Stop()
+		taskService.SetWorkflowEngine(workflowEngine)
+		recovered, err := workflowEngine.Recover(context.Background())
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Failed to recover in-process workflows: %v", err))
+		}
+		logger.Info(fmt.Sprintf("In-process workflow engine started, %d workflows recovered (task schedules need Temporal)", recovered))
+	case config.WorkflowEngineTemporal, "":
+		// 连接Temporal：有工作流定义的任务在分配后由工作流执行，暂停/恢复/取消通过信号下发
+		temporalClient, err := temporal.NewTemporalClient(context.Background(), &temporal.Config{
+			HostPort:  cfg.Temporal.Host,
+			Namespace: cfg.Temporal.Namespace,
+			TaskQueue: cfg.Temporal.TaskQueue,
+		})
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Failed to connect temporal: %v (tasks will not run workflows)", err))
+		} else {
+			defer temporalClient.Close()
+			taskService.SetWorkflowEngine(temporal.NewWorkflowEngine(temporalClient))
+			// 延时启动与周期任务由Temporal Schedule触发
+			taskService.SetScheduleEngine(temporal.NewScheduleEngine(temporalClient))
+			logger.Info("Temporal connected")
+		}
+	default:
+		logger.Fatal(fmt.Sprintf("Unknown workflow engine %q", cfg.Workflow.Engine))
+	}
+	taskService.Start()
+	defer taskService.Stop()
+
+	// 订阅者就绪后再开始消费事件
+	if brokerBus != nil {
+		brokerBus.Start(schedulerCtx)
+		logger.Info(fmt.Sprintf("Event bus %s started", cfg.EventBus.Driver))
+	}
+
+	// 订阅者就绪后再开始投递发件箱中的事件，包括上次退出时未投递的
+	if outboxRelay != nil {
+		outboxRelay.Start(schedulerCtx)
+		logger.Info("Event outbox relay started")
+	}
 
 	// 创建HTTP服务器
-	server := httpServer.NewServer(userService, companyService, skillCardService, employeeService, taskService, chatService)
+	server := httpServer.NewServer(userService, companyService, skillCardService, employeeService, taskService, chatService, workflowService, hotspotService, publishingService, taskScheduler, cfg.Ops.Token)
 	engine := server.Setup(cfg.App.Mode)
 
 	// 启动服务器
@@ -107,6 +271,8 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down server...")
+	taskScheduler.Stop()
+	stopScheduler()
 
 	// 设置5秒超时
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -118,3 +284,68 @@ func main() {
 
 	logger.Info("Server exited")
 }
+
+// newInProcessEngine creates the in-process workflow engine, running skills,
+// hotspot analyses and publishing with the same activities the Temporal
+// worker registers
+func newInProcessEngine(cfg *config.Config, skillCardRepo *persistence.SkillCardRepository, taskRepo *persistence.TaskRepository, employeeRepo *persistence.EmployeeRepository, uow database.UnitOfWork, hotspotService *hotspotApp.Service, publishingService *publishingApp.Service) *inprocess.WorkflowEngine {
+	skillExecutor := executor.NewSkillExecutor(skillCardRepo)
+	skillExecutor.RegisterAIProvider(executor.NewOpenAIProvider())
+	skillExecutor.RegisterAIProvider(executor.NewClaudeProvider())
+
+	activities := temporal.NewActivities(skillExecutor, taskRepo, employeeRepo, eventbus.GetEventBus())
+	leaseTTL := cfg.Scheduler.Recovery.LeaseTTL
+	if leaseTTL <= 0 {
+		leaseTTL = time.Minute
+	}
+	activities.SetLeases(taskRepo, leaseTTL)
+	activities.SetApprovals(taskRepo)
+	activities.SetSteps(taskRepo)
+	activities.SetUnitOfWork(uow)
+	activities.SetHotspots(hotspotService)
+	activities.SetPublisher(publishingService)
+
+	return inprocess.NewWorkflowEngine(activities, taskRepo, taskRepo, taskRepo)
+}
+
+// newOutboxRelay creates the relay delivering the event outbox to the bus
+func newOutboxRelay(cfg *config.Config, store outbox.Store, uow database.UnitOfWork, bus outbox.Deliverer) *outbox.Relay {
+	outboxCfg := cfg.EventBus.Outbox
+	return outbox.NewRelay(store, uow, bus, outbox.Options{
+		PollInterval: outboxCfg.PollInterval,
+		BatchSize:    outboxCfg.BatchSize,
+		MaxAttempts:  outboxCfg.MaxAttempts,
+		Retention:    outboxCfg.Retention,
+	})
+}
+
+// newRedisBus creates the event bus sharing events through a Redis stream
+func newRedisBus(cfg *config.Config, redis *cache.Redis) *eventbus.RedisBus {
+	redisCfg := cfg.EventBus.Redis
+	return eventbus.NewRedisBus(redis.Client(), eventbus.RedisOptions{
+		Stream:        redisCfg.Stream,
+		Instance:      redisCfg.Instance,
+		BatchSize:     redisCfg.BatchSize,
+		Block:         redisCfg.Block,
+		ClaimInterval: redisCfg.ClaimInterval,
+		ClaimMinIdle:  redisCfg.ClaimMinIdle,
+		MaxDeliveries: redisCfg.MaxDeliveries,
+		MaxLen:        redisCfg.MaxLen,
+		Retention:     redisCfg.Retention,
+	})
+}
+
+// newKafkaBus creates the event bus sharing events through Kafka
+func newKafkaBus(cfg *config.Config) *eventbus.KafkaBus {
+	kafkaCfg := cfg.EventBus.Kafka
+	return eventbus.NewKafkaBus(eventbus.KafkaOptions{
+		Brokers:           cfg.Kafka.Brokers,
+		TopicPrefix:       kafkaCfg.TopicPrefix,
+		Instance:          kafkaCfg.Instance,
+		Partitions:        kafkaCfg.Partitions,
+		ReplicationFactor: kafkaCfg.ReplicationFactor,
+		MaxDeliveries:     kafkaCfg.MaxDeliveries,
+		RetryDelay:        kafkaCfg.RetryDelay,
+		Retention:         kafkaCfg.Retention,
+	})
+}
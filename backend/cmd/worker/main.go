@@ -5,10 +5,22 @@ import (
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
+	"unlimited-corp/internal/application/executor"
+	hotspotApp "unlimited-corp/internal/application/hotspot"
+	publishingApp "unlimited-corp/internal/application/publishing"
+	"unlimited-corp/internal/infrastructure/cache"
 	"unlimited-corp/internal/infrastructure/config"
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/internal/infrastructure/hotspotsource"
+	"unlimited-corp/internal/infrastructure/outbox"
+	"unlimited-corp/internal/infrastructure/persistence"
+	"unlimited-corp/internal/infrastructure/publisher"
 	"unlimited-corp/internal/infrastructure/temporal"
 	"unlimited-corp/pkg/logger"
+	"unlimited-corp/pkg/secret"
 
 	"go.temporal.io/sdk/client"
 	"go.temporal.io/sdk/worker"
@@ -31,6 +43,94 @@ func main() {
 
 	logger.Info("Starting Temporal Worker...")
 
+	// 连接数据库
+	db, err := database.Connect(&cfg.Database)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to connect database: %v", err))
+	}
+	defer db.Close()
+	logger.Info("Database connected")
+
+	// 初始化活动依赖
+	skillCardRepo := persistence.NewSkillCardRepository(db)
+	employeeRepo := persistence.NewEmployeeRepository(db)
+	taskRepo := persistence.NewTaskRepository(db.DB)
+
+	skillExecutor := executor.NewSkillExecutor(skillCardRepo)
+	skillExecutor.RegisterAIProvider(executor.NewOpenAIProvider())
+	skillExecutor.RegisterAIProvider(executor.NewClaudeProvider())
+
+	txManager := database.NewTxManager(db.DB)
+
+	// redis 与 kafka 驱动下事件经消息中间件投递给 API 服务的订阅者；Worker 只发布不消费
+	switch cfg.EventBus.Driver {
+	case "", config.EventBusDriverMemory:
+	case config.EventBusDriverRedis:
+		redis, err := cache.Connect(&cfg.Redis)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to connect redis: %v", err))
+		}
+		defer redis.Close()
+		logger.Info("Redis connected")
+		eventbus.SetEventBus(newRedisBus(cfg, redis))
+	case config.EventBusDriverKafka:
+		if len(cfg.Kafka.Brokers) == 0 {
+			logger.Fatal("Event bus driver kafka requires kafka.brokers")
+		}
+		kafkaBus := newKafkaBus(cfg)
+		// 退出前写完缓冲中的事件
+		defer kafkaBus.Stop()
+		eventbus.SetEventBus(kafkaBus)
+	default:
+		logger.Fatal(fmt.Sprintf("Unknown event bus driver %q", cfg.EventBus.Driver))
+	}
+
+	// 事件写入发件箱，由服务进程的中继投递给订阅者（通知、调度器等）
+	eventBus := eventbus.GetEventBus()
+	if cfg.EventBus.Outbox.Enabled {
+		eventBus.SetOutbox(outbox.NewRelay(persistence.NewOutboxRepository(db.DB), txManager, eventBus, outbox.Options{}))
+	}
+
+	activities := temporal.NewActivities(skillExecutor, taskRepo, employeeRepo, eventBus)
+	// 任务状态变更与其事件在同一事务中写入
+	activities.SetUnitOfWork(txManager)
+	// 活动心跳同时续约调度器的任务租约，避免长任务被当作卡死任务回收
+	leaseTTL := cfg.Scheduler.Recovery.LeaseTTL
+	if leaseTTL <= 0 {
+		leaseTTL = time.Minute
+	}
+	activities.SetLeases(taskRepo, leaseTTL)
+	// 审批节点的待审内容与审批结果
+	activities.SetApprovals(taskRepo)
+	// 记录每次运行的工作流步骤
+	activities.SetSteps(taskRepo)
+	// 任务计划触发时创建任务并记录触发历史
+	activities.SetSchedules(taskRepo, txManager)
+
+	// 热点分析：配置的数据源加上公司最新上传的 CSV，趋势对比上一次快照
+	hotspotSources, err := hotspotsource.NewRegistry(cfg.Hotspot)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to load hotspot sources: %v", err))
+	}
+	activities.SetHotspots(hotspotApp.NewService(persistence.NewHotspotRepository(db.DB), hotspotSources, cfg.Hotspot.Limit))
+
+	// 内容发布：按平台适配器检查格式，使用公司配置的平台凭证
+	publishers, err := publisher.NewRegistry(cfg.Publishing)
+	if err != nil {
+		logger.Fatal(fmt.Sprintf("Failed to load publishing platforms: %v", err))
+	}
+	publishingRepo := persistence.NewPublishingRepository(db.DB)
+	if cfg.Publishing.EncryptionKey != "" {
+		box, err := secret.NewBox(cfg.Publishing.EncryptionKey)
+		if err != nil {
+			logger.Fatal(fmt.Sprintf("Failed to create publishing credential cipher: %v", err))
+		}
+		publishingRepo.SetSecretBox(box)
+	} else {
+		logger.Warn("Publishing credentials are unavailable: set PUBLISHING_ENCRYPTION_KEY")
+	}
+	activities.SetPublisher(publishingApp.NewService(publishingRepo, publishers))
+
 	// 创建Temporal客户端
 	c, err := client.Dial(client.Options{
 		HostPort:  cfg.Temporal.Host,
@@ -50,13 +150,11 @@ func main() {
 	w.RegisterWorkflow(temporal.ContentCreationWorkflow)
 	w.RegisterWorkflow(temporal.HotspotTrackingWorkflow)
 	w.RegisterWorkflow(temporal.AutoOpsWorkflow)
+	w.RegisterWorkflow(temporal.DefinitionWorkflow)
+	w.RegisterWorkflow(temporal.ScheduledTaskWorkflow)
 
 	// 注册活动
-	w.RegisterActivity(temporal.ExecuteSkillActivity)
-	w.RegisterActivity(temporal.UpdateTaskStatusActivity)
-	w.RegisterActivity(temporal.AnalyzeHotspotsActivity)
-	w.RegisterActivity(temporal.GenerateContentSuggestionsActivity)
-	w.RegisterActivity(temporal.PublishContentActivity)
+	temporal.RegisterActivities(w, activities)
 
 	logger.Info(fmt.Sprintf("Worker registered with task queue: %s", cfg.Temporal.TaskQueue))
 
@@ -78,3 +176,34 @@ func main() {
 	w.Stop()
 	logger.Info("Worker stopped")
 }
+
+// newRedisBus creates the event bus sharing events through a Redis stream
+func newRedisBus(cfg *config.Config, redis *cache.Redis) *eventbus.RedisBus {
+	redisCfg := cfg.EventBus.Redis
+	return eventbus.NewRedisBus(redis.Client(), eventbus.RedisOptions{
+		Stream:        redisCfg.Stream,
+		Instance:      redisCfg.Instance,
+		BatchSize:     redisCfg.BatchSize,
+		Block:         redisCfg.Block,
+		ClaimInterval: redisCfg.ClaimInterval,
+		ClaimMinIdle:  redisCfg.ClaimMinIdle,
+		MaxDeliveries: redisCfg.MaxDeliveries,
+		MaxLen:        redisCfg.MaxLen,
+		Retention:     redisCfg.Retention,
+	})
+}
+
+// newKafkaBus creates the event bus sharing events through Kafka
+func newKafkaBus(cfg *config.Config) *eventbus.KafkaBus {
+	kafkaCfg := cfg.EventBus.Kafka
+	return eventbus.NewKafkaBus(eventbus.KafkaOptions{
+		Brokers:           cfg.Kafka.Brokers,
+		TopicPrefix:       kafkaCfg.TopicPrefix,
+		Instance:          kafkaCfg.Instance,
+		Partitions:        kafkaCfg.Partitions,
+		ReplicationFactor: kafkaCfg.ReplicationFactor,
+		MaxDeliveries:     kafkaCfg.MaxDeliveries,
+		RetryDelay:        kafkaCfg.RetryDelay,
+		Retention:         kafkaCfg.Retention,
+	})
+}
@@ -0,0 +1,147 @@
+package employee
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/company"
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/logger"
+)
+
+// AvailabilityManager takes employees offline and back online as their
+// calendar windows close and open
+type AvailabilityManager struct {
+	repo      employee.Repository
+	calendars employee.CalendarRepository
+	companies company.Repository
+	uow       database.UnitOfWork
+	eventBus  eventbus.EventBus
+}
+
+// NewAvailabilityManager creates a new availability manager
+func NewAvailabilityManager(repo employee.Repository, calendars employee.CalendarRepository, companies company.Repository, uow database.UnitOfWork) *AvailabilityManager {
+	return &AvailabilityManager{
+		repo:      repo,
+		calendars: calendars,
+		companies: companies,
+		uow:       uow,
+		eventBus:  eventbus.GetEventBus(),
+	}
+}
+
+// Start runs Sync every interval until ctx is cancelled
+func (m *AvailabilityManager) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		m.sync(ctx, time.Now())
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				m.sync(ctx, now)
+			}
+		}
+	}()
+}
+
+// sync runs Sync and logs failures
+func (m *AvailabilityManager) sync(ctx context.Context, now time.Time) {
+	if err := m.Sync(ctx, now); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to sync employee availability: %v", err))
+	}
+}
+
+// Sync applies every calendar at the given time. Idle employees outside their
+// windows go offline; working employees finish their tasks first and go
+// offline on a later sync. Only employees taken offline by the calendar are
+// brought back online, so a manual offline status is left alone.
+func (m *AvailabilityManager) Sync(ctx context.Context, now time.Time) error {
+	calendars, err := m.calendars.ListCalendars(ctx)
+	if err != nil {
+		return err
+	}
+
+	locations := make(map[uuid.UUID]*time.Location)
+	for _, cal := range calendars {
+		loc, ok := locations[cal.CompanyID]
+		if !ok {
+			loc = m.companyLocation(ctx, cal.CompanyID)
+			locations[cal.CompanyID] = loc
+		}
+
+		if err := m.apply(ctx, cal, cal.IsAvailableAt(now, loc)); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to apply calendar of employee %s: %v", cal.EmployeeID, err))
+		}
+	}
+
+	return nil
+}
+
+// apply moves one employee online or offline to match its calendar
+func (m *AvailabilityManager) apply(ctx context.Context, cal *employee.Calendar, available bool) error {
+	return m.inTransaction(ctx, func(ctx context.Context) error {
+		emp, err := m.repo.LockByID(ctx, cal.EmployeeID)
+		if err != nil {
+			return err
+		}
+
+		var eventType eventbus.EventType
+		switch {
+		case !available && emp.Status == employee.StatusIdle:
+			emp.SetOffline()
+			cal.AutoOffline = true
+			eventType = eventbus.EventEmployeeOffline
+		case available && emp.Status == employee.StatusOffline && cal.AutoOffline:
+			emp.SetOnline()
+			cal.AutoOffline = false
+			eventType = eventbus.EventEmployeeOnline
+		default:
+			return nil
+		}
+
+		if err := m.repo.Update(ctx, emp); err != nil {
+			return err
+		}
+		cal.UpdatedAt = time.Now()
+		if err := m.calendars.SaveCalendar(ctx, cal); err != nil {
+			return err
+		}
+
+		event, err := eventbus.NewEvent(eventType, "availability_manager", emp, eventbus.Metadata{
+			CompanyID: emp.CompanyID.String(),
+			Version:   1,
+		})
+		if err != nil {
+			return err
+		}
+		return m.eventBus.Publish(ctx, event)
+	})
+}
+
+// companyLocation returns the timezone of a company, defaulting to UTC
+func (m *AvailabilityManager) companyLocation(ctx context.Context, companyID uuid.UUID) *time.Location {
+	if m.companies == nil {
+		return time.UTC
+	}
+	c, err := m.companies.GetByID(ctx, companyID)
+	if err != nil {
+		return time.UTC
+	}
+	return c.Location()
+}
+
+// inTransaction runs fn in a unit of work, or directly when none is configured
+func (m *AvailabilityManager) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if m.uow == nil {
+		return fn(ctx)
+	}
+	return m.uow.Do(ctx, fn)
+}
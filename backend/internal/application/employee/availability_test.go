@@ -0,0 +1,140 @@
+package employee
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/infrastructure/config"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&config.LogConfig{Level: "error", Format: "console"})
+	os.Exit(m.Run())
+}
+
+// fakeRepository overrides the employee.Repository methods used by the availability manager
+type fakeRepository struct {
+	employee.Repository
+	employees map[uuid.UUID]*employee.Employee
+}
+
+func (r *fakeRepository) LockByID(_ context.Context, id uuid.UUID) (*employee.Employee, error) {
+	if e, ok := r.employees[id]; ok {
+		return e, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeRepository) Update(_ context.Context, e *employee.Employee) error {
+	r.employees[e.ID] = e
+	return nil
+}
+
+// fakeCalendarRepository is an in-memory employee.CalendarRepository
+type fakeCalendarRepository struct {
+	calendars map[uuid.UUID]*employee.Calendar
+}
+
+func (r *fakeCalendarRepository) GetCalendar(_ context.Context, employeeID uuid.UUID) (*employee.Calendar, error) {
+	if c, ok := r.calendars[employeeID]; ok {
+		return c, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeCalendarRepository) SaveCalendar(_ context.Context, c *employee.Calendar) error {
+	r.calendars[c.EmployeeID] = c
+	return nil
+}
+
+func (r *fakeCalendarRepository) ListCalendarsByCompany(ctx context.Context, companyID uuid.UUID) ([]*employee.Calendar, error) {
+	var result []*employee.Calendar
+	for _, c := range r.calendars {
+		if c.CompanyID == companyID {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeCalendarRepository) ListCalendars(context.Context) ([]*employee.Calendar, error) {
+	var result []*employee.Calendar
+	for _, c := range r.calendars {
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+func newNightShift(emp *employee.Employee) *employee.Calendar {
+	cal := employee.NewCalendar(emp.CompanyID, emp.ID)
+	cal.Windows = []employee.AvailabilityWindow{{Start: "22:00", End: "06:00"}}
+	return cal
+}
+
+func newTestAvailabilityManager(emps []*employee.Employee, cals []*employee.Calendar) (*AvailabilityManager, *eventbus.MemoryBus) {
+	repo := &fakeRepository{employees: make(map[uuid.UUID]*employee.Employee)}
+	for _, e := range emps {
+		repo.employees[e.ID] = e
+	}
+	calendars := &fakeCalendarRepository{calendars: make(map[uuid.UUID]*employee.Calendar)}
+	for _, c := range cals {
+		calendars.calendars[c.EmployeeID] = c
+	}
+
+	m := NewAvailabilityManager(repo, calendars, nil, nil)
+	bus := eventbus.NewEventBus()
+	m.eventBus = bus
+	return m, bus
+}
+
+func TestAvailabilityManager_NightShift(t *testing.T) {
+	emp := employee.NewEmployee(uuid.New(), "Night Owl", "Writer")
+	cal := newNightShift(emp)
+	m, bus := newTestAvailabilityManager([]*employee.Employee{emp}, []*employee.Calendar{cal})
+
+	noon := time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, m.Sync(context.Background(), noon))
+	assert.Equal(t, employee.StatusOffline, emp.Status)
+	assert.True(t, cal.AutoOffline)
+	assert.Len(t, bus.GetHistoryByType(eventbus.EventEmployeeOffline, 10), 1)
+
+	// Syncing again within the same closed window is a no-op
+	require.NoError(t, m.Sync(context.Background(), noon.Add(time.Hour)))
+	assert.Len(t, bus.GetHistoryByType(eventbus.EventEmployeeOffline, 10), 1)
+
+	midnight := time.Date(2024, 1, 9, 0, 30, 0, 0, time.UTC)
+	require.NoError(t, m.Sync(context.Background(), midnight))
+	assert.Equal(t, employee.StatusIdle, emp.Status)
+	assert.False(t, cal.AutoOffline)
+	assert.Len(t, bus.GetHistoryByType(eventbus.EventEmployeeOnline, 10), 1)
+}
+
+func TestAvailabilityManager_LeavesWorkingAndManualOfflineAlone(t *testing.T) {
+	companyID := uuid.New()
+	working := employee.NewEmployee(companyID, "Busy", "Writer")
+	working.AssignTask(uuid.New())
+	manual := employee.NewEmployee(companyID, "Manual", "Writer")
+	manual.SetOffline()
+
+	m, bus := newTestAvailabilityManager(
+		[]*employee.Employee{working, manual},
+		[]*employee.Calendar{newNightShift(working), newNightShift(manual)},
+	)
+
+	require.NoError(t, m.Sync(context.Background(), time.Date(2024, 1, 8, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, employee.StatusWorking, working.Status, "working employees finish their tasks first")
+
+	require.NoError(t, m.Sync(context.Background(), time.Date(2024, 1, 8, 23, 0, 0, 0, time.UTC)))
+	assert.Equal(t, employee.StatusOffline, manual.Status, "manual offline is not overridden")
+	assert.Empty(t, bus.GetHistory(0))
+}
@@ -2,20 +2,44 @@ package employee
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/internal/infrastructure/eventbus"
 	"unlimited-corp/pkg/errors"
 )
 
 // Service handles employee business logic
 type Service struct {
-	repo employee.Repository
+	repo      employee.Repository
+	calendars employee.CalendarRepository
+	eventBus  eventbus.EventBus
+	uow       database.UnitOfWork
 }
 
 // NewService creates a new employee service
-func NewService(repo employee.Repository) *Service {
-	return &Service{repo: repo}
+func NewService(repo employee.Repository, calendars employee.CalendarRepository) *Service {
+	return &Service{
+		repo:      repo,
+		calendars: calendars,
+		eventBus:  eventbus.GetEventBus(),
+	}
+}
+
+// SetUnitOfWork makes status changes write their events in the same
+// transaction as the employee
+func (s *Service) SetUnitOfWork(uow database.UnitOfWork) {
+	s.uow = uow
+}
+
+// inTransaction runs fn in a unit of work, or directly when none is configured
+func (s *Service) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.uow == nil {
+		return fn(ctx)
+	}
+	return s.uow.Do(ctx, fn)
 }
 
 // CreateInput represents the input for creating an employee
@@ -171,11 +195,121 @@ func (s *Service) SetStatus(ctx context.Context, id, companyID uuid.UUID, status
 		return nil, errors.ErrNotFound
 	}
 
+	previous := emp.Status
 	emp.SetStatus(employee.Status(status))
 
-	if err := s.repo.Update(ctx, emp); err != nil {
-		return nil, errors.Wrap(err, "failed to update employee status")
+	err = s.inTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Update(ctx, emp); err != nil {
+			return errors.Wrap(err, "failed to update employee status")
+		}
+		if eventType, ok := statusEventType(previous, emp.Status); ok {
+			return s.publish(ctx, eventType, emp)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return emp, nil
+}
+
+// GetCalendar retrieves the calendar of an employee; employees without one get an empty calendar
+func (s *Service) GetCalendar(ctx context.Context, employeeID, companyID uuid.UUID) (*employee.Calendar, error) {
+	emp, err := s.getCompanyEmployee(ctx, employeeID, companyID)
+	if err != nil {
+		return nil, err
+	}
+
+	cal, err := s.calendars.GetCalendar(ctx, emp.ID)
+	if errors.IsNotFound(err) {
+		return employee.NewCalendar(emp.CompanyID, emp.ID), nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get calendar")
+	}
+
+	return cal, nil
+}
+
+// UpdateCalendarInput represents the input for replacing an employee calendar
+type UpdateCalendarInput struct {
+	EmployeeID uuid.UUID                     `json:"-"`
+	CompanyID  uuid.UUID                     `json:"-"`
+	Timezone   string                        `json:"timezone"`
+	Windows    []employee.AvailabilityWindow `json:"windows"`
+	Blackouts  []employee.Blackout           `json:"blackouts"`
+}
+
+// UpdateCalendar replaces the working windows and blackouts of an employee
+func (s *Service) UpdateCalendar(ctx context.Context, input *UpdateCalendarInput) (*employee.Calendar, error) {
+	cal, err := s.GetCalendar(ctx, input.EmployeeID, input.CompanyID)
+	if err != nil {
+		return nil, err
+	}
+
+	cal.Timezone = input.Timezone
+	cal.Windows = input.Windows
+	cal.Blackouts = input.Blackouts
+	if cal.Windows == nil {
+		cal.Windows = []employee.AvailabilityWindow{}
+	}
+	if cal.Blackouts == nil {
+		cal.Blackouts = []employee.Blackout{}
+	}
+	if err := cal.Validate(); err != nil {
+		return nil, errors.New(400, err.Error())
+	}
+	cal.UpdatedAt = time.Now()
+
+	if err := s.calendars.SaveCalendar(ctx, cal); err != nil {
+		return nil, errors.Wrap(err, "failed to save calendar")
 	}
 
+	return cal, nil
+}
+
+// getCompanyEmployee retrieves an employee and checks it belongs to the company
+func (s *Service) getCompanyEmployee(ctx context.Context, id, companyID uuid.UUID) (*employee.Employee, error) {
+	emp, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if emp.CompanyID != companyID {
+		return nil, errors.ErrNotFound
+	}
 	return emp, nil
 }
+
+// statusEventType returns the event announcing a status change, if any
+func statusEventType(previous, current employee.Status) (eventbus.EventType, bool) {
+	if previous == current {
+		return "", false
+	}
+	switch {
+	case current == employee.StatusOffline:
+		return eventbus.EventEmployeeOffline, true
+	case previous == employee.StatusOffline:
+		return eventbus.EventEmployeeOnline, true
+	case current == employee.StatusIdle:
+		return eventbus.EventEmployeeIdle, true
+	case current == employee.StatusWorking:
+		return eventbus.EventEmployeeBusy, true
+	}
+	return "", false
+}
+
+// publish announces an employee event; the employee itself is the payload
+func (s *Service) publish(ctx context.Context, eventType eventbus.EventType, emp *employee.Employee) error {
+	event, err := eventbus.NewEvent(eventType, "employee_service", emp, eventbus.Metadata{
+		CompanyID: emp.CompanyID.String(),
+		Version:   1,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode employee event")
+	}
+	if err := s.eventBus.Publish(ctx, event); err != nil {
+		return errors.Wrap(err, "failed to publish employee event")
+	}
+	return nil
+}
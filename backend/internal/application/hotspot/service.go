@@ -0,0 +1,161 @@
+package hotspot
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/hotspot"
+	"unlimited-corp/internal/infrastructure/hotspotsource"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+)
+
+// defaultLimit is how many topics a snapshot keeps when no limit is configured
+const defaultLimit = 20
+
+// SourceProvider returns the configured sources of a platform; implemented
+// by hotspotsource.Registry
+type SourceProvider interface {
+	Sources(platform string) []hotspot.Source
+}
+
+// Service analyzes hotspots: it fetches a platform's sources and the
+// company's latest upload, merges their items into topics and scores them
+// against the previous snapshot
+type Service struct {
+	repo    hotspot.Repository
+	sources SourceProvider
+	limit   int
+}
+
+// NewService creates a new hotspot service; limit is how many topics a
+// snapshot keeps
+func NewService(repo hotspot.Repository, sources SourceProvider, limit int) *Service {
+	if limit <= 0 {
+		limit = defaultLimit
+	}
+	return &Service{repo: repo, sources: sources, limit: limit}
+}
+
+// UploadInput is a CSV of hotspots uploaded for a platform
+type UploadInput struct {
+	CompanyID  uuid.UUID
+	UploadedBy *uuid.UUID
+	Platform   string
+	Filename   string
+	Content    []byte
+}
+
+// Analyze fetches the sources of a platform, scores their topics against
+// the previous snapshot and stores the result as the new snapshot. Sources
+// that fail are skipped; the analysis fails only if all of them do.
+func (s *Service) Analyze(ctx context.Context, companyID uuid.UUID, platform, category string) (*hotspot.Snapshot, error) {
+	if platform == "" {
+		return nil, errors.New(http.StatusBadRequest, "platform is required")
+	}
+	sources, err := s.platformSources(ctx, companyID, platform)
+	if err != nil {
+		return nil, err
+	}
+	if len(sources) == 0 {
+		return nil, errors.New(http.StatusBadRequest, fmt.Sprintf("no hotspot sources for platform %q", platform))
+	}
+
+	items, err := fetch(ctx, sources)
+	if err != nil {
+		return nil, err
+	}
+
+	previous, err := s.repo.GetLatestSnapshot(ctx, companyID, platform, category)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	topics := hotspot.Score(hotspot.Merge(items), previous, s.limit)
+
+	snapshot := hotspot.NewSnapshot(companyID, platform, category, topics)
+	if err := s.repo.CreateSnapshot(ctx, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// Latest returns the newest snapshot of a company's platform and category
+func (s *Service) Latest(ctx context.Context, companyID uuid.UUID, platform, category string) (*hotspot.Snapshot, error) {
+	if platform == "" {
+		return nil, errors.New(http.StatusBadRequest, "platform is required")
+	}
+	return s.repo.GetLatestSnapshot(ctx, companyID, platform, category)
+}
+
+// Upload stores a CSV of hotspots, which becomes a source of the platform's
+// analyses until the next upload
+func (s *Service) Upload(ctx context.Context, input *UploadInput) (*hotspot.Upload, error) {
+	if input.Platform == "" {
+		return nil, errors.New(http.StatusBadRequest, "platform is required")
+	}
+	items, err := hotspotsource.ParseCSV(input.Content, input.Filename)
+	if err != nil {
+		return nil, errors.New(http.StatusBadRequest, fmt.Sprintf("invalid CSV: %v", err))
+	}
+	if len(items) == 0 {
+		return nil, errors.New(http.StatusBadRequest, "the CSV has no hotspots")
+	}
+
+	upload := hotspot.NewUpload(input.CompanyID, input.Platform, input.Filename, input.Content, len(items), input.UploadedBy)
+	if err := s.repo.CreateUpload(ctx, upload); err != nil {
+		return nil, err
+	}
+	return upload, nil
+}
+
+// platformSources returns the configured sources of a platform followed by
+// the company's latest upload for it
+func (s *Service) platformSources(ctx context.Context, companyID uuid.UUID, platform string) ([]hotspot.Source, error) {
+	var sources []hotspot.Source
+	if s.sources != nil {
+		sources = append(sources, s.sources.Sources(platform)...)
+	}
+	upload, err := s.repo.GetLatestUpload(ctx, companyID, platform)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	if upload != nil {
+		sources = append(sources, hotspotsource.NewUploadSource("upload:"+upload.Filename, upload.Content))
+	}
+	return sources, nil
+}
+
+// fetch fetches all sources concurrently and returns their items in source
+// order, logging the sources that failed
+func fetch(ctx context.Context, sources []hotspot.Source) ([]hotspot.Item, error) {
+	results := make([][]hotspot.Item, len(sources))
+	errs := make([]error, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func(i int, source hotspot.Source) {
+			defer wg.Done()
+			results[i], errs[i] = source.Fetch(ctx)
+		}(i, source)
+	}
+	wg.Wait()
+
+	var items []hotspot.Item
+	var failed []string
+	for i, source := range sources {
+		if errs[i] != nil {
+			logger.Warn(fmt.Sprintf("Failed to fetch hotspot source %s: %v", source.Name(), errs[i]))
+			failed = append(failed, fmt.Sprintf("%s: %v", source.Name(), errs[i]))
+			continue
+		}
+		items = append(items, results[i]...)
+	}
+	if len(failed) == len(sources) {
+		return nil, errors.New(http.StatusBadGateway, "all hotspot sources failed: "+strings.Join(failed, "; "))
+	}
+	return items, nil
+}
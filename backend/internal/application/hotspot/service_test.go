@@ -0,0 +1,153 @@
+package hotspot
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"unlimited-corp/internal/domain/hotspot"
+	"unlimited-corp/internal/infrastructure/config"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&config.LogConfig{Level: "error", Format: "console"})
+	os.Exit(m.Run())
+}
+
+// fakeRepository keeps snapshots and uploads in insertion order
+type fakeRepository struct {
+	snapshots []*hotspot.Snapshot
+	uploads   []*hotspot.Upload
+}
+
+func (r *fakeRepository) CreateSnapshot(_ context.Context, s *hotspot.Snapshot) error {
+	r.snapshots = append(r.snapshots, s)
+	return nil
+}
+
+func (r *fakeRepository) GetLatestSnapshot(_ context.Context, companyID uuid.UUID, platform, category string) (*hotspot.Snapshot, error) {
+	for i := len(r.snapshots) - 1; i >= 0; i-- {
+		s := r.snapshots[i]
+		if s.CompanyID == companyID && s.Platform == platform && s.Category == category {
+			return s, nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeRepository) CreateUpload(_ context.Context, u *hotspot.Upload) error {
+	r.uploads = append(r.uploads, u)
+	return nil
+}
+
+func (r *fakeRepository) GetLatestUpload(_ context.Context, companyID uuid.UUID, platform string) (*hotspot.Upload, error) {
+	for i := len(r.uploads) - 1; i >= 0; i-- {
+		u := r.uploads[i]
+		if u.CompanyID == companyID && u.Platform == platform {
+			return u, nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+// fakeSource returns fixed items or an error
+type fakeSource struct {
+	name  string
+	items []hotspot.Item
+	err   error
+}
+
+func (s *fakeSource) Name() string { return s.name }
+
+func (s *fakeSource) Fetch(context.Context) ([]hotspot.Item, error) {
+	return s.items, s.err
+}
+
+// fakeSources serves the same sources for every platform
+type fakeSources []hotspot.Source
+
+func (f fakeSources) Sources(string) []hotspot.Source { return f }
+
+func TestService_Analyze(t *testing.T) {
+	ctx := context.Background()
+	companyID := uuid.New()
+	repo := &fakeRepository{}
+	weibo := &fakeSource{name: "weibo", items: []hotspot.Item{
+		{Title: "AI Agents", Heat: 1000, Source: "weibo"},
+		{Title: "Remote work", Heat: 500, Source: "weibo"},
+	}}
+	broken := &fakeSource{name: "broken", err: fmt.Errorf("timeout")}
+	svc := NewService(repo, fakeSources{weibo, broken}, 10)
+
+	first, err := svc.Analyze(ctx, companyID, "xiaohongshu", "tech")
+	require.NoError(t, err)
+	require.Len(t, first.Topics, 2)
+	assert.Equal(t, "AI Agents", first.Topics[0].Title)
+	assert.Equal(t, hotspot.TrendNew, first.Topics[0].Trend)
+
+	// An upload joins the configured sources and raises the heat of the topic it repeats
+	_, err = svc.Upload(ctx, &UploadInput{
+		CompanyID: companyID,
+		Platform:  "xiaohongshu",
+		Filename:  "mine.csv",
+		Content:   []byte("title,heat\nai agents,800\nCity walks,300\n"),
+	})
+	require.NoError(t, err)
+
+	second, err := svc.Analyze(ctx, companyID, "xiaohongshu", "tech")
+	require.NoError(t, err)
+	require.Len(t, second.Topics, 3)
+	top := second.Topics[0]
+	assert.Equal(t, "AI Agents", top.Title)
+	assert.Equal(t, []string{"weibo", "upload:mine.csv"}, top.Sources)
+	assert.Equal(t, hotspot.TrendRising, top.Trend)
+	assert.Equal(t, 200, top.Delta)
+
+	latest, err := svc.Latest(ctx, companyID, "xiaohongshu", "tech")
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, latest.ID)
+}
+
+func TestService_AnalyzeFailures(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeRepository{}
+
+	_, err := NewService(repo, fakeSources{}, 0).Analyze(ctx, uuid.New(), "douyin", "")
+	assert.True(t, errors.IsBadRequest(err))
+
+	broken := &fakeSource{name: "broken", err: fmt.Errorf("timeout")}
+	_, err = NewService(repo, fakeSources{broken}, 0).Analyze(ctx, uuid.New(), "douyin", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "broken: timeout")
+	assert.Empty(t, repo.snapshots)
+}
+
+func TestService_UploadRejectsBadCSV(t *testing.T) {
+	svc := NewService(&fakeRepository{}, nil, 0)
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{name: "no title column", content: "name,heat\nAI,10\n"},
+		{name: "no rows", content: "title,heat\n"},
+		{name: "bad heat", content: "title,heat\nAI,hot\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Upload(context.Background(), &UploadInput{
+				CompanyID: uuid.New(),
+				Platform:  "douyin",
+				Filename:  "topics.csv",
+				Content:   []byte(tt.content),
+			})
+			assert.True(t, errors.IsBadRequest(err))
+		})
+	}
+}
@@ -12,10 +12,16 @@ import (
 	"github.com/google/uuid"
 )
 
+// consumerName 通知服务按事件 ID 去重时使用的消费者名称
+const consumerName = "notifications"
+
 // NotificationService 通知服务
+// 每个实例只推送连接到自己的 WebSocket 客户端，因此需要收到所有事件：
+// 使用广播订阅而非共享的消费者组，并在进程内按事件 ID 去重
 type NotificationService struct {
 	hub      *websocket.Hub
-	eventBus *eventbus.EventBus
+	eventBus eventbus.EventBus
+	dedup    eventbus.DedupStore
 }
 
 // NewNotificationService 创建通知服务
@@ -23,32 +29,42 @@ func NewNotificationService(hub *websocket.Hub) *NotificationService {
 	return &NotificationService{
 		hub:      hub,
 		eventBus: eventbus.GetEventBus(),
+		dedup:    eventbus.NewMemoryDedupStore(0),
 	}
 }
 
 // Start 启动通知服务，订阅事件
 func (s *NotificationService) Start() {
 	// 订阅任务事件
-	s.eventBus.Subscribe(eventbus.EventTaskCreated, s.handleTaskCreated)
-	s.eventBus.Subscribe(eventbus.EventTaskAssigned, s.handleTaskAssigned)
-	s.eventBus.Subscribe(eventbus.EventTaskStarted, s.handleTaskStarted)
-	s.eventBus.Subscribe(eventbus.EventTaskCompleted, s.handleTaskCompleted)
-	s.eventBus.Subscribe(eventbus.EventTaskFailed, s.handleTaskFailed)
-	s.eventBus.Subscribe(eventbus.EventTaskProgress, s.handleTaskProgress)
+	s.subscribe(eventbus.EventTaskCreated, s.handleTaskCreated)
+	s.subscribe(eventbus.EventTaskAssigned, s.handleTaskAssigned)
+	s.subscribe(eventbus.EventTaskStarted, s.handleTaskStarted)
+	s.subscribe(eventbus.EventTaskCompleted, s.handleTaskCompleted)
+	s.subscribe(eventbus.EventTaskFailed, s.handleTaskFailed)
+	s.subscribe(eventbus.EventTaskProgress, s.handleTaskProgress)
+	s.subscribe(eventbus.EventTaskApprovalRequested, s.handleTaskApproval)
+	s.subscribe(eventbus.EventTaskApprovalDecided, s.handleTaskApproval)
 
 	// 订阅员工事件
-	s.eventBus.Subscribe(eventbus.EventEmployeeOnline, s.handleEmployeeOnline)
-	s.eventBus.Subscribe(eventbus.EventEmployeeOffline, s.handleEmployeeOffline)
-	s.eventBus.Subscribe(eventbus.EventEmployeeBusy, s.handleEmployeeBusy)
-	s.eventBus.Subscribe(eventbus.EventEmployeeIdle, s.handleEmployeeIdle)
+	s.subscribe(eventbus.EventEmployeeOnline, s.handleEmployeeOnline)
+	s.subscribe(eventbus.EventEmployeeOffline, s.handleEmployeeOffline)
+	s.subscribe(eventbus.EventEmployeeBusy, s.handleEmployeeBusy)
+	s.subscribe(eventbus.EventEmployeeIdle, s.handleEmployeeIdle)
 
 	// 订阅聊天事件
-	s.eventBus.Subscribe(eventbus.EventChatMessage, s.handleChatMessage)
-	s.eventBus.Subscribe(eventbus.EventChatResponse, s.handleChatResponse)
+	s.subscribe(eventbus.EventChatMessage, s.handleChatMessage)
+	s.subscribe(eventbus.EventChatResponse, s.handleChatResponse)
 
 	logger.Info("Notification service started")
 }
 
+// subscribe 订阅本实例的广播事件，重复投递的事件只推送一次
+func (s *NotificationService) subscribe(eventType eventbus.EventType, handler eventbus.Handler) {
+	s.eventBus.Subscribe(eventType, func(ctx context.Context, event *eventbus.Event) error {
+		return eventbus.Deduplicate(ctx, s.dedup, consumerName, event, handler)
+	})
+}
+
 // handleTaskCreated 处理任务创建事件
 func (s *NotificationService) handleTaskCreated(ctx context.Context, event *eventbus.Event) error {
 	msg := s.createWebSocketMessage(websocket.MessageTypeTaskCreated, event.Payload)
@@ -91,6 +107,13 @@ func (s *NotificationService) handleTaskProgress(ctx context.Context, event *eve
 	return nil
 }
 
+// handleTaskApproval 处理任务审批请求与审批结果事件
+func (s *NotificationService) handleTaskApproval(ctx context.Context, event *eventbus.Event) error {
+	msg := s.createWebSocketMessage(websocket.MessageTypeTaskApproval, event.Payload)
+	s.hub.SendToCompany(event.Metadata.CompanyID, msg)
+	return nil
+}
+
 // handleEmployeeOnline 处理员工上线事件
 func (s *NotificationService) handleEmployeeOnline(ctx context.Context, event *eventbus.Event) error {
 	msg := s.createWebSocketMessage(websocket.MessageTypeEmployeeOnline, event.Payload)
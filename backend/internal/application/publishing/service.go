@@ -0,0 +1,155 @@
+package publishing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/publishing"
+	"unlimited-corp/internal/infrastructure/publisher"
+	"unlimited-corp/pkg/errors"
+)
+
+// PublisherRegistry returns the publishers of the configured platforms;
+// implemented by publisher.Registry
+type PublisherRegistry interface {
+	Publisher(platform string) (publishing.Publisher, bool)
+	Publishers() []publishing.Publisher
+	DefaultPlatforms() []string
+}
+
+// Service publishes content with the credentials companies stored for each platform
+type Service struct {
+	repo       publishing.CredentialRepository
+	publishers PublisherRegistry
+}
+
+// NewService creates a new publishing service
+func NewService(repo publishing.CredentialRepository, publishers PublisherRegistry) *Service {
+	return &Service{repo: repo, publishers: publishers}
+}
+
+// Platform is a platform content can be published to
+type Platform struct {
+	Name        string                 `json:"name"`
+	Constraints publishing.Constraints `json:"constraints"`
+	Default     bool                   `json:"default"`
+	// CredentialKeys are the names of the company's stored credentials, without their values
+	CredentialKeys []string `json:"credential_keys"`
+}
+
+// CredentialsInfo is a company's credentials for a platform without their values
+type CredentialsInfo struct {
+	*publishing.Credentials
+	Keys []string `json:"keys"`
+}
+
+// ListPlatforms lists the configured platforms with the company's credentials
+func (s *Service) ListPlatforms(ctx context.Context, companyID uuid.UUID) ([]*Platform, error) {
+	stored, err := s.repo.ListCredentials(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string][]string, len(stored))
+	for _, creds := range stored {
+		keys[creds.Platform] = creds.Keys()
+	}
+
+	platforms := make([]*Platform, 0, len(s.publishers.Publishers()))
+	for _, p := range s.publishers.Publishers() {
+		platforms = append(platforms, &Platform{
+			Name:           p.Platform(),
+			Constraints:    p.Constraints(),
+			Default:        containsString(s.publishers.DefaultPlatforms(), p.Platform()),
+			CredentialKeys: keys[p.Platform()],
+		})
+	}
+	return platforms, nil
+}
+
+// SaveCredentials creates or replaces a company's credentials for a platform
+func (s *Service) SaveCredentials(ctx context.Context, companyID uuid.UUID, platform string, values map[string]string) (*CredentialsInfo, error) {
+	if _, err := s.publisher(platform); err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, errors.New(http.StatusBadRequest, "credentials are required")
+	}
+	if webhookURL, ok := values[publisher.CredentialWebhookURL]; ok {
+		if err := publisher.CheckWebhookURL(ctx, webhookURL); err != nil {
+			return nil, errors.New(http.StatusBadRequest, fmt.Sprintf("%s %v", publisher.CredentialWebhookURL, err))
+		}
+	}
+
+	creds := publishing.NewCredentials(companyID, platform, values)
+	if err := s.repo.SaveCredentials(ctx, creds); err != nil {
+		return nil, err
+	}
+	return &CredentialsInfo{Credentials: creds, Keys: creds.Keys()}, nil
+}
+
+// DeleteCredentials removes a company's credentials for a platform
+func (s *Service) DeleteCredentials(ctx context.Context, companyID uuid.UUID, platform string) error {
+	return s.repo.DeleteCredentials(ctx, companyID, platform)
+}
+
+// Publish publishes a post to a platform, or saves it as a draft there, with
+// the company's credentials
+func (s *Service) Publish(ctx context.Context, companyID uuid.UUID, platform string, post *publishing.Post, mode publishing.Mode) (*publishing.Result, error) {
+	p, err := s.publisher(platform)
+	if err != nil {
+		return nil, err
+	}
+	creds, err := s.credentials(ctx, companyID, platform)
+	if err != nil {
+		return nil, err
+	}
+	return p.Publish(ctx, creds, post, mode)
+}
+
+// Unpublish takes down a post the company published to a platform
+func (s *Service) Unpublish(ctx context.Context, companyID uuid.UUID, platform, postID string) error {
+	p, err := s.publisher(platform)
+	if err != nil {
+		return err
+	}
+	creds, err := s.credentials(ctx, companyID, platform)
+	if err != nil {
+		return err
+	}
+	return p.Unpublish(ctx, creds, postID)
+}
+
+// DefaultPlatforms returns the platforms content goes to when none are given
+func (s *Service) DefaultPlatforms() []string {
+	return s.publishers.DefaultPlatforms()
+}
+
+// publisher returns the publisher of a configured platform
+func (s *Service) publisher(platform string) (publishing.Publisher, error) {
+	p, ok := s.publishers.Publisher(platform)
+	if !ok {
+		return nil, errors.New(http.StatusBadRequest, fmt.Sprintf("platform %q is not configured for publishing", platform))
+	}
+	return p, nil
+}
+
+// credentials returns the company's credentials for a platform, or nil if it has none
+func (s *Service) credentials(ctx context.Context, companyID uuid.UUID, platform string) (*publishing.Credentials, error) {
+	creds, err := s.repo.GetCredentials(ctx, companyID, platform)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	return creds, err
+}
+
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
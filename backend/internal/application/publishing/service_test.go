@@ -0,0 +1,120 @@
+package publishing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"unlimited-corp/internal/domain/publishing"
+	"unlimited-corp/internal/infrastructure/config"
+	"unlimited-corp/internal/infrastructure/publisher"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCredentials keeps credentials by company and platform
+type fakeCredentials struct {
+	creds map[string]*publishing.Credentials
+}
+
+func newFakeCredentials() *fakeCredentials {
+	return &fakeCredentials{creds: make(map[string]*publishing.Credentials)}
+}
+
+func (r *fakeCredentials) SaveCredentials(_ context.Context, c *publishing.Credentials) error {
+	r.creds[c.CompanyID.String()+c.Platform] = c
+	return nil
+}
+
+func (r *fakeCredentials) GetCredentials(_ context.Context, companyID uuid.UUID, platform string) (*publishing.Credentials, error) {
+	c, ok := r.creds[companyID.String()+platform]
+	if !ok {
+		return nil, errors.ErrNotFound
+	}
+	return c, nil
+}
+
+func (r *fakeCredentials) ListCredentials(_ context.Context, companyID uuid.UUID) ([]*publishing.Credentials, error) {
+	var list []*publishing.Credentials
+	for _, c := range r.creds {
+		if c.CompanyID == companyID {
+			list = append(list, c)
+		}
+	}
+	return list, nil
+}
+
+func (r *fakeCredentials) DeleteCredentials(_ context.Context, companyID uuid.UUID, platform string) error {
+	delete(r.creds, companyID.String()+platform)
+	return nil
+}
+
+func newTestService(t *testing.T, webhookURL string) *Service {
+	registry, err := publisher.NewRegistry(config.PublishingConfig{
+		DefaultPlatforms: []string{"xiaohongshu"},
+		Platforms: []config.PublishingPlatformConfig{
+			{Name: "xiaohongshu", Driver: config.PublishDriverStub},
+			{Name: "weibo", Driver: config.PublishDriverWebhook, URL: webhookURL},
+		},
+	})
+	require.NoError(t, err)
+	return NewService(newFakeCredentials(), registry)
+}
+
+func TestService_PublishWithCompanyCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer weibo-token" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		_, _ = w.Write([]byte(`{"post_id":"5012","url":"https://weibo.com/5012"}`))
+	}))
+	defer server.Close()
+
+	svc := newTestService(t, server.URL)
+	ctx := context.Background()
+	companyID := uuid.New()
+	post := &publishing.Post{Body: "正文"}
+
+	_, err := svc.Publish(ctx, companyID, "weibo", post, publishing.ModePublish)
+	assert.ErrorContains(t, err, "status 401", "a company without credentials is not authorized")
+
+	info, err := svc.SaveCredentials(ctx, companyID, "weibo", map[string]string{publisher.CredentialToken: "weibo-token"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"token"}, info.Keys)
+
+	result, err := svc.Publish(ctx, companyID, "weibo", post, publishing.ModePublish)
+	require.NoError(t, err)
+	assert.Equal(t, "5012", result.PostID)
+	assert.Equal(t, "https://weibo.com/5012", result.URL)
+
+	platforms, err := svc.ListPlatforms(ctx, companyID)
+	require.NoError(t, err)
+	require.Len(t, platforms, 2)
+	assert.True(t, platforms[0].Default)
+	assert.Equal(t, []string{"token"}, platforms[1].CredentialKeys)
+}
+
+func TestService_RejectsUnknownPlatformsAndBadCredentials(t *testing.T) {
+	svc := newTestService(t, "")
+	ctx := context.Background()
+
+	_, err := svc.Publish(ctx, uuid.New(), "myspace", &publishing.Post{Body: "x"}, publishing.ModePublish)
+	assert.True(t, errors.IsBadRequest(err))
+
+	_, err = svc.SaveCredentials(ctx, uuid.New(), "weibo", map[string]string{publisher.CredentialWebhookURL: "ftp://example.com"})
+	assert.True(t, errors.IsBadRequest(err))
+
+	// Company webhooks may not reach the server's own network
+	for _, webhookURL := range []string{"http://127.0.0.1:8080/hook", "http://10.0.0.5/hook", "http://169.254.169.254/latest/meta-data"} {
+		_, err = svc.SaveCredentials(ctx, uuid.New(), "weibo", map[string]string{publisher.CredentialWebhookURL: webhookURL})
+		assert.True(t, errors.IsBadRequest(err), webhookURL)
+	}
+
+	_, err = svc.SaveCredentials(ctx, uuid.New(), "weibo", nil)
+	assert.True(t, errors.IsBadRequest(err))
+}
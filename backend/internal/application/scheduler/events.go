@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// eventScheduleTimeout bounds the scheduling work triggered by a single event
+const eventScheduleTimeout = 10 * time.Second
+
+// schedulerConsumer is the name the scheduler deduplicates its events under
+const schedulerConsumer = "scheduler"
+
+// schedulingPayload holds the fields the scheduler reads from task and employee events
+type schedulingPayload struct {
+	ID                 uuid.UUID  `json:"id"`
+	CompanyID          uuid.UUID  `json:"company_id"`
+	AssignedEmployeeID *uuid.UUID `json:"assigned_employee_id"`
+}
+
+// Start subscribes the scheduler to the events that can make a task schedulable
+func (s *TaskScheduler) Start() {
+	s.subscriptions = append(s.subscriptions,
+		s.eventBus.SubscribeAs(schedulerConsumer, eventbus.EventTaskCreated, s.handleTaskCreated),
+		s.eventBus.SubscribeAs(schedulerConsumer, eventbus.EventTaskRetried, s.handleTaskCreated),
+		s.eventBus.SubscribeAs(schedulerConsumer, eventbus.EventTaskCompleted, s.handleTaskFinished),
+		s.eventBus.SubscribeAs(schedulerConsumer, eventbus.EventTaskFailed, s.handleTaskFinished),
+		s.eventBus.SubscribeAs(schedulerConsumer, eventbus.EventTaskCancelled, s.handleTaskFinished),
+		s.eventBus.SubscribeAs(schedulerConsumer, eventbus.EventEmployeeIdle, s.handleEmployeeAvailable),
+		s.eventBus.SubscribeAs(schedulerConsumer, eventbus.EventEmployeeOnline, s.handleEmployeeAvailable),
+	)
+
+	logger.Info("Task scheduler subscribed to scheduling events")
+}
+
+// Stop removes the scheduler's event subscriptions
+func (s *TaskScheduler) Stop() {
+	for _, sub := range s.subscriptions {
+		s.eventBus.Unsubscribe(sub)
+	}
+	s.subscriptions = nil
+}
+
+// handleTaskCreated dispatches a newly created or retried task right away, in fair order
+func (s *TaskScheduler) handleTaskCreated(ctx context.Context, event *eventbus.Event) error {
+	payload, err := decodeSchedulingPayload(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := eventContext(ctx)
+	defer cancel()
+
+	_, err = s.ProcessPendingTasks(ctx, payload.CompanyID)
+	return err
+}
+
+// handleTaskFinished frees the employee's capacity and schedules waiting tasks
+func (s *TaskScheduler) handleTaskFinished(ctx context.Context, event *eventbus.Event) error {
+	payload, err := decodeSchedulingPayload(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := eventContext(ctx)
+	defer cancel()
+
+	if payload.AssignedEmployeeID != nil {
+		// Only release assignments that are still active, so redelivered events are harmless
+		assignment, err := s.employeeRepo.GetLatestAssignmentByTask(ctx, payload.ID)
+		if err == nil && assignment.IsActive() {
+			success := event.Type == eventbus.EventTaskCompleted
+			if err := s.ReleaseEmployee(ctx, *payload.AssignedEmployeeID, payload.ID, success); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err = s.ProcessPendingTasks(ctx, payload.CompanyID)
+	return err
+}
+
+// handleEmployeeAvailable schedules waiting tasks when an employee can take work
+func (s *TaskScheduler) handleEmployeeAvailable(ctx context.Context, event *eventbus.Event) error {
+	payload, err := decodeSchedulingPayload(event)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := eventContext(ctx)
+	defer cancel()
+
+	_, err = s.ProcessPendingTasks(ctx, payload.CompanyID)
+	return err
+}
+
+// publishAssigned announces a task assignment
+func (s *TaskScheduler) publishAssigned(ctx context.Context, t *task.Task, decision *SchedulingDecision) error {
+	payload := map[string]interface{}{
+		"id":                   t.ID,
+		"company_id":           t.CompanyID,
+		"title":                t.Title,
+		"status":               t.Status,
+		"assigned_employee_id": t.AssignedEmployeeID,
+		"strategy":             decision.Strategy,
+		"reason":               decision.Reason,
+	}
+
+	event, err := eventbus.NewEvent(eventbus.EventTaskAssigned, "scheduler", payload, eventbus.Metadata{
+		CompanyID: t.CompanyID.String(),
+		Version:   1,
+	})
+	if err != nil {
+		return err
+	}
+	return s.eventBus.Publish(ctx, event)
+}
+
+// publishTask announces a task event; the task itself is the payload
+func (s *TaskScheduler) publishTask(ctx context.Context, eventType eventbus.EventType, t *task.Task) error {
+	event, err := eventbus.NewEvent(eventType, "scheduler", t, eventbus.Metadata{
+		CompanyID: t.CompanyID.String(),
+		Version:   1,
+	})
+	if err != nil {
+		return err
+	}
+	return s.eventBus.Publish(ctx, event)
+}
+
+// decodeSchedulingPayload reads the task or employee carried by an event
+func decodeSchedulingPayload(event *eventbus.Event) (*schedulingPayload, error) {
+	var payload schedulingPayload
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return nil, err
+	}
+	if payload.CompanyID == uuid.Nil && event.Metadata.CompanyID != "" {
+		if companyID, err := uuid.Parse(event.Metadata.CompanyID); err == nil {
+			payload.CompanyID = companyID
+		}
+	}
+	return &payload, nil
+}
+
+// eventContext detaches event handling from the publisher's request lifetime
+func eventContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(ctx), eventScheduleTimeout)
+}
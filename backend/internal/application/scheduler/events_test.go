@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/eventbus"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newEventDrivenScheduler(taskRepo *fakeTaskRepository, employeeRepo *fakeEmployeeRepository) (*TaskScheduler, *eventbus.MemoryBus) {
+	bus := eventbus.NewEventBus()
+	s := NewTaskScheduler(taskRepo, taskRepo, employeeRepo, nil, nil, nil)
+	s.eventBus = bus
+	s.Start()
+	return s, bus
+}
+
+func publish(t *testing.T, bus *eventbus.MemoryBus, eventType eventbus.EventType, payload interface{}, companyID uuid.UUID) {
+	event, err := eventbus.NewEvent(eventType, "test", payload, eventbus.Metadata{CompanyID: companyID.String()})
+	require.NoError(t, err)
+	require.NoError(t, bus.Publish(context.Background(), event))
+}
+
+func waitForAssignments(t *testing.T, bus *eventbus.MemoryBus, n int) {
+	assert.Eventually(t, func() bool {
+		return len(bus.GetHistoryByType(eventbus.EventTaskAssigned, n)) == n
+	}, 500*time.Millisecond, 5*time.Millisecond, "task must be assigned within 500ms of the event")
+}
+
+func TestTaskScheduler_SchedulesOnTaskCreated(t *testing.T) {
+	companyID := uuid.New()
+	emp := employee.NewEmployee(companyID, "Solo", "Writer")
+	newTask := task.NewTask(companyID, "Write", "", task.PriorityMedium)
+	taskID := newTask.ID
+
+	s, bus := newEventDrivenScheduler(newFakeTaskRepository(newTask), newFakeEmployeeRepository(emp))
+	defer s.Stop()
+
+	publish(t, bus, eventbus.EventTaskCreated, newTask, companyID)
+	waitForAssignments(t, bus, 1)
+
+	assigned := bus.GetHistoryByType(eventbus.EventTaskAssigned, 1)[0]
+	payload, err := decodeSchedulingPayload(assigned)
+	require.NoError(t, err)
+	assert.Equal(t, taskID, payload.ID)
+	require.NotNil(t, payload.AssignedEmployeeID)
+	assert.Equal(t, emp.ID, *payload.AssignedEmployeeID)
+}
+
+func TestTaskScheduler_SchedulesWaitingTaskOnCompletion(t *testing.T) {
+	companyID := uuid.New()
+	emp := employee.NewEmployee(companyID, "Solo", "Writer")
+	first := task.NewTask(companyID, "First", "", task.PriorityMedium)
+	second := task.NewTask(companyID, "Second", "", task.PriorityMedium)
+
+	taskRepo := newFakeTaskRepository(first, second)
+	employeeRepo := newFakeEmployeeRepository(emp)
+	s, bus := newEventDrivenScheduler(taskRepo, employeeRepo)
+	defer s.Stop()
+
+	require.NoError(t, s.Schedule(context.Background(), first))
+	require.Equal(t, task.StatusPending, second.Status)
+
+	first.Complete(nil)
+	publish(t, bus, eventbus.EventTaskCompleted, first, companyID)
+	waitForAssignments(t, bus, 2)
+
+	assert.Equal(t, 1, emp.TotalTasks)
+}
+
+func TestTaskScheduler_SchedulesOnEmployeeOnline(t *testing.T) {
+	companyID := uuid.New()
+	emp := employee.NewEmployee(companyID, "Solo", "Writer")
+	emp.SetOffline()
+	newTask := task.NewTask(companyID, "Write", "", task.PriorityMedium)
+
+	employeeRepo := newFakeEmployeeRepository(emp)
+	s, bus := newEventDrivenScheduler(newFakeTaskRepository(newTask), employeeRepo)
+	defer s.Stop()
+
+	require.NoError(t, s.Schedule(context.Background(), newTask))
+	require.Equal(t, task.StatusPending, newTask.Status)
+
+	emp.SetStatus(employee.StatusIdle)
+	publish(t, bus, eventbus.EventEmployeeOnline, emp, companyID)
+	waitForAssignments(t, bus, 1)
+}
@@ -0,0 +1,187 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+)
+
+// CandidateEvaluation explains how a single employee was judged for a task
+type CandidateEvaluation struct {
+	EmployeeID     uuid.UUID       `json:"employee_id"`
+	EmployeeName   string          `json:"employee_name"`
+	Status         employee.Status `json:"status"`
+	ActiveTasks    int             `json:"active_tasks"`
+	Capacity       int             `json:"capacity"`
+	Eligible       bool            `json:"eligible"`
+	ExcludedReason string          `json:"excluded_reason,omitempty"`
+	Rank           int             `json:"rank,omitempty"`
+	Score          float64         `json:"score"`
+	Factors        []FactorScore   `json:"factors,omitempty"`
+	Reason         string          `json:"reason,omitempty"`
+}
+
+// SchedulingDecision is the ranked outcome of evaluating employees for a task
+type SchedulingDecision struct {
+	TaskID             uuid.UUID             `json:"task_id"`
+	SelectedEmployeeID *uuid.UUID            `json:"selected_employee_id,omitempty"`
+	Reason             string                `json:"reason"`
+	Candidates         []CandidateEvaluation `json:"candidates"`
+	Strategy           string                `json:"strategy"`
+	EvaluatedAt        time.Time             `json:"evaluated_at"`
+
+	strategy Strategy
+}
+
+// Preview ranks candidate employees for a task without assigning anything
+func (s *TaskScheduler) Preview(ctx context.Context, t *task.Task) (*SchedulingDecision, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	employees, err := s.employeeRepo.GetByCompanyID(ctx, t.CompanyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list employees: %w", err)
+	}
+
+	return s.evaluate(ctx, t, employees)
+}
+
+// evaluate scores the given employees for a task and picks the best eligible one
+func (s *TaskScheduler) evaluate(ctx context.Context, t *task.Task, employees []*employee.Employee) (*SchedulingDecision, error) {
+	activeCounts, err := s.employeeRepo.CountActiveAssignments(ctx, t.CompanyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count active assignments: %w", err)
+	}
+
+	calendars, err := s.loadCalendars(ctx, t.CompanyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load calendars: %w", err)
+	}
+
+	c := s.loadCompany(ctx, t.CompanyID)
+	limits := planLimitsOf(c)
+	location := locationOf(c)
+	strategy := s.resolveStrategy(c, t)
+	now := time.Now()
+
+	evaluations := make([]CandidateEvaluation, 0, len(employees))
+	candidates := make([]Candidate, 0, len(employees))
+	for _, emp := range employees {
+		active := activeCounts[emp.ID]
+		capacity := s.capacityOf(emp, limits)
+
+		eval := CandidateEvaluation{
+			EmployeeID:   emp.ID,
+			EmployeeName: emp.Name,
+			Status:       emp.Status,
+			ActiveTasks:  active,
+			Capacity:     capacity,
+		}
+
+		if reason := exclusionReason(emp, active, capacity, calendars[emp.ID], now, location); reason != "" {
+			eval.ExcludedReason = reason
+		} else {
+			eval.Eligible = true
+			skills, err := s.employeeRepo.GetSkills(ctx, emp.ID)
+			candidates = append(candidates, Candidate{
+				Employee:    emp,
+				ActiveTasks: active,
+				Capacity:    capacity,
+				Skills:      skills,
+				SkillsKnown: err == nil,
+			})
+		}
+		evaluations = append(evaluations, eval)
+	}
+
+	scores := strategy.Score(ctx, t, candidates)
+	scoreByEmployee := make(map[uuid.UUID]SchedulingScore, len(scores))
+	for _, sc := range scores {
+		scoreByEmployee[sc.EmployeeID] = sc
+	}
+
+	for i := range evaluations {
+		if sc, ok := scoreByEmployee[evaluations[i].EmployeeID]; ok {
+			evaluations[i].Score = sc.Score
+			evaluations[i].Factors = sc.Factors
+			evaluations[i].Reason = sc.Reason
+		}
+	}
+
+	// Eligible candidates first, by score descending
+	sort.SliceStable(evaluations, func(i, j int) bool {
+		if evaluations[i].Eligible != evaluations[j].Eligible {
+			return evaluations[i].Eligible
+		}
+		return evaluations[i].Score > evaluations[j].Score
+	})
+
+	decision := &SchedulingDecision{
+		TaskID:      t.ID,
+		Candidates:  evaluations,
+		Strategy:    strategy.Name(),
+		EvaluatedAt: now,
+		strategy:    strategy,
+	}
+
+	for i := range evaluations {
+		if !evaluations[i].Eligible {
+			break
+		}
+		evaluations[i].Rank = i + 1
+	}
+
+	if len(candidates) == 0 {
+		decision.Reason = "no employee has free capacity"
+		return decision, nil
+	}
+
+	best := evaluations[0]
+	decision.SelectedEmployeeID = &best.EmployeeID
+	decision.Reason = fmt.Sprintf("%s ranked first by %s strategy: %s", best.EmployeeName, strategy.Name(), best.Reason)
+	return decision, nil
+}
+
+// exclusionReason explains why an employee cannot take the task, or returns "" if it can
+func exclusionReason(emp *employee.Employee, active, capacity int, cal *employee.Calendar, now time.Time, location *time.Location) string {
+	switch emp.Status {
+	case employee.StatusOffline:
+		return "employee is offline"
+	case employee.StatusError:
+		return "employee is in error state"
+	}
+	if cal != nil {
+		if reason := cal.UnavailableReason(now, location); reason != "" {
+			return reason
+		}
+	}
+	if !emp.HasCapacity(active, capacity) {
+		return fmt.Sprintf("at capacity (%d/%d active tasks)", active, capacity)
+	}
+	return ""
+}
+
+// GetDecision returns the stored explanation of a task's most recent assignment
+func (s *TaskScheduler) GetDecision(ctx context.Context, taskID uuid.UUID) (*SchedulingDecision, error) {
+	assignment, err := s.employeeRepo.GetLatestAssignmentByTask(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if len(assignment.Decision) == 0 {
+		return nil, errors.ErrNotFound
+	}
+
+	var decision SchedulingDecision
+	if err := json.Unmarshal(assignment.Decision, &decision); err != nil {
+		return nil, errors.Wrap(err, "failed to decode scheduling decision")
+	}
+	return &decision, nil
+}
@@ -0,0 +1,382 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"unlimited-corp/internal/domain/company"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// dispatchBatchSize bounds the pending tasks loaded per company in one dispatch
+const dispatchBatchSize = 100
+
+// fairQueue keeps the virtual clock of weighted fair queuing across companies.
+// It uses start-time fair queuing: serving a company moves its finish tag
+// 1/weight past its start tag, and the company with the smallest start tag
+// is served next. A backlogged company therefore gets slots in proportion to
+// its weight, and a company that was idle banks no credit for later.
+type fairQueue struct {
+	mu      sync.Mutex
+	virtual float64
+	finish  map[uuid.UUID]float64
+	waits   map[uuid.UUID]*waitStats
+}
+
+// waitStats accumulates how long a company's tasks waited before dispatch
+type waitStats struct {
+	dispatched int64
+	total      time.Duration
+	last       time.Duration
+}
+
+func newFairQueue() *fairQueue {
+	return &fairQueue{
+		finish: make(map[uuid.UUID]float64),
+		waits:  make(map[uuid.UUID]*waitStats),
+	}
+}
+
+// startTag returns the virtual time at which the company's next task starts
+func (q *fairQueue) startTag(companyID uuid.UUID) float64 {
+	if finish := q.finish[companyID]; finish > q.virtual {
+		return finish
+	}
+	return q.virtual
+}
+
+// next returns the index of the backlog to serve next
+func (q *fairQueue) next(backlogs []*backlog) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	best := 0
+	for i := 1; i < len(backlogs); i++ {
+		tag, bestTag := q.startTag(backlogs[i].companyID), q.startTag(backlogs[best].companyID)
+		if tag < bestTag || (tag == bestTag && backlogs[i].queue.OldestCreatedAt.Before(backlogs[best].queue.OldestCreatedAt)) {
+			best = i
+		}
+	}
+	return best
+}
+
+// restore replaces the virtual clock with the one shared by all instances
+func (q *fairQueue) restore(fair *task.FairShare) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.virtual = fair.Virtual
+	q.finish = make(map[uuid.UUID]float64, len(fair.Finish))
+	for companyID, finish := range fair.Finish {
+		q.finish[companyID] = finish
+	}
+}
+
+// snapshot returns a copy of the virtual clock to share with other instances
+func (q *fairQueue) snapshot() *task.FairShare {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	fair := &task.FairShare{Virtual: q.virtual, Finish: make(map[uuid.UUID]float64, len(q.finish))}
+	for companyID, finish := range q.finish {
+		fair.Finish[companyID] = finish
+	}
+	return fair
+}
+
+// charge accounts a dispatched task to its company
+func (q *fairQueue) charge(companyID uuid.UUID, weight int, wait time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	start := q.startTag(companyID)
+	q.virtual = start
+	q.finish[companyID] = start + 1/float64(weight)
+
+	stats, ok := q.waits[companyID]
+	if !ok {
+		stats = &waitStats{}
+		q.waits[companyID] = stats
+	}
+	stats.dispatched++
+	stats.total += wait
+	stats.last = wait
+}
+
+// waitsOf returns a copy of the wait statistics of a company
+func (q *fairQueue) waitsOf(companyID uuid.UUID) waitStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if stats, ok := q.waits[companyID]; ok {
+		return *stats
+	}
+	return waitStats{}
+}
+
+// companiesSeen lists the companies that have had tasks dispatched
+func (q *fairQueue) companiesSeen() []uuid.UUID {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ids := make([]uuid.UUID, 0, len(q.waits))
+	for id := range q.waits {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// backlog is one company's pending tasks during a dispatch
+type backlog struct {
+	companyID uuid.UUID
+	weight    int
+	queue     *task.Queue
+	tasks     []*task.Task
+	loaded    bool
+}
+
+// pop returns the company's next pending task, or nil when none is left
+func (b *backlog) pop(ctx context.Context, repo task.Repository) (*task.Task, error) {
+	if !b.loaded {
+		tasks, err := repo.ListPending(ctx, b.companyID, dispatchBatchSize)
+		if err != nil {
+			return nil, err
+		}
+		b.tasks, b.loaded = tasks, true
+	}
+	if len(b.tasks) == 0 {
+		return nil, nil
+	}
+
+	t := b.tasks[0]
+	b.tasks = b.tasks[1:]
+	return t, nil
+}
+
+// SetExecutionSlots sets how many tasks may run at once across all companies.
+// Zero or less means unlimited.
+func (s *TaskScheduler) SetExecutionSlots(slots int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slots = slots
+}
+
+// SetDispatchRepository makes dispatches take turns with other scheduler
+// instances and share their fair share clock through the repository
+func (s *TaskScheduler) SetDispatchRepository(repo task.DispatchRepository) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dispatchRepo = repo
+}
+
+// dispatchRepository returns the shared dispatch state, nil when dispatches
+// only take turns within this process
+func (s *TaskScheduler) dispatchRepository() task.DispatchRepository {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.dispatchRepo
+}
+
+// executionSlots returns the configured execution slots, zero when unlimited
+func (s *TaskScheduler) executionSlots() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.slots
+}
+
+// Dispatch hands the free execution slots to pending tasks of all companies
+// using weighted fair queuing, so one company's backlog cannot starve the rest
+func (s *TaskScheduler) Dispatch(ctx context.Context) (int, error) {
+	queues, err := s.taskRepo.ListQueues(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list task queues: %w", err)
+	}
+	return s.dispatch(ctx, queues)
+}
+
+// dispatch schedules the given companies' pending tasks in fair order until
+// the execution slots run out or no task can be placed
+func (s *TaskScheduler) dispatch(ctx context.Context, queues []*task.Queue) (int, error) {
+	// Slots are counted from the database, so dispatches take turns: within
+	// this process on dispatchMu, across instances on the dispatch state lock
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+
+	repo := s.dispatchRepository()
+	if repo == nil || s.uow == nil {
+		return s.dispatchFair(ctx, queues)
+	}
+
+	scheduled := 0
+	err := s.uow.Do(database.WithoutTx(ctx), func(txCtx context.Context) error {
+		fair, err := repo.LockDispatch(txCtx)
+		if err != nil {
+			return fmt.Errorf("failed to lock dispatch: %w", err)
+		}
+		s.fair.restore(fair)
+
+		// Each task is scheduled in its own transaction while the lock is held,
+		// so the next instance counts it as running
+		scheduled, err = s.dispatchFair(database.WithoutTx(txCtx), queues)
+		if err != nil {
+			return err
+		}
+		if err := repo.SaveFairShare(txCtx, s.fair.snapshot()); err != nil {
+			return fmt.Errorf("failed to save fair share: %w", err)
+		}
+		return nil
+	})
+	return scheduled, err
+}
+
+// dispatchFair runs one dispatch while holding the turn
+func (s *TaskScheduler) dispatchFair(ctx context.Context, queues []*task.Queue) (int, error) {
+	free := -1
+	if slots := s.executionSlots(); slots > 0 {
+		running, err := s.taskRepo.CountRunning(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("failed to count running tasks: %w", err)
+		}
+		free = slots - running
+		if free <= 0 {
+			return 0, nil
+		}
+	}
+
+	backlogs := make([]*backlog, 0, len(queues))
+	for _, q := range queues {
+		backlogs = append(backlogs, &backlog{
+			companyID: q.CompanyID,
+			weight:    fairShareWeightOf(s.loadCompany(ctx, q.CompanyID)),
+			queue:     q,
+		})
+	}
+
+	scheduled := 0
+	for free != 0 && len(backlogs) > 0 {
+		i := s.fair.next(backlogs)
+		b := backlogs[i]
+
+		t, err := b.pop(ctx, s.taskRepo)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Failed to list pending tasks of company %s: %v", b.companyID, err))
+		}
+		if t == nil {
+			backlogs = append(backlogs[:i], backlogs[i+1:]...)
+			continue
+		}
+
+		if err := s.Schedule(ctx, t); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to schedule task %s: %v", t.ID, err))
+			continue
+		}
+		if t.Status != task.StatusRunning {
+			// No employee can take this task yet; the company keeps its turn
+			continue
+		}
+
+		s.fair.charge(b.companyID, b.weight, time.Since(t.CreatedAt))
+		scheduled++
+		if free > 0 {
+			free--
+		}
+	}
+
+	return scheduled, nil
+}
+
+// fairShareWeightOf returns the fair share weight granted by the company's plan
+func fairShareWeightOf(c *company.Company) int {
+	if weight := planLimitsOf(c).FairShareWeight; weight > 0 {
+		return weight
+	}
+	return 1
+}
+
+// QueueStatus reports the shared execution slots and each company's queue
+type QueueStatus struct {
+	Slots     int             `json:"slots"` // 0 means unlimited
+	Running   int             `json:"running"`
+	Companies []*CompanyQueue `json:"companies"`
+}
+
+// CompanyQueue reports the queue depth and wait times of one company
+type CompanyQueue struct {
+	CompanyID         uuid.UUID    `json:"company_id"`
+	Plan              company.Plan `json:"plan"`
+	Weight            int          `json:"weight"`
+	Depth             int          `json:"depth"`
+	OldestWaitSeconds float64      `json:"oldest_wait_seconds"`
+	Dispatched        int64        `json:"dispatched"`
+	AvgWaitSeconds    float64      `json:"avg_wait_seconds"`
+	LastWaitSeconds   float64      `json:"last_wait_seconds"`
+}
+
+// QueueStatus reports per-company queue depth and wait times for operators.
+// Dispatch counts and wait averages cover tasks dispatched by this process.
+func (s *TaskScheduler) QueueStatus(ctx context.Context) (*QueueStatus, error) {
+	queues, err := s.taskRepo.ListQueues(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list task queues: %w", err)
+	}
+	running, err := s.taskRepo.CountRunning(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count running tasks: %w", err)
+	}
+
+	byCompany := make(map[uuid.UUID]*task.Queue, len(queues))
+	for _, q := range queues {
+		byCompany[q.CompanyID] = q
+	}
+	for _, id := range s.fair.companiesSeen() {
+		if _, ok := byCompany[id]; !ok {
+			byCompany[id] = &task.Queue{CompanyID: id}
+		}
+	}
+
+	now := time.Now()
+	status := &QueueStatus{
+		Slots:     s.executionSlots(),
+		Running:   running,
+		Companies: make([]*CompanyQueue, 0, len(byCompany)),
+	}
+	for id, q := range byCompany {
+		c := s.loadCompany(ctx, id)
+		plan := company.PlanFree
+		if c != nil {
+			plan = c.Plan()
+		}
+
+		waits := s.fair.waitsOf(id)
+		entry := &CompanyQueue{
+			CompanyID:         id,
+			Plan:              plan,
+			Weight:            fairShareWeightOf(c),
+			Depth:             q.Depth,
+			OldestWaitSeconds: q.OldestWait(now).Seconds(),
+			Dispatched:        waits.dispatched,
+			LastWaitSeconds:   waits.last.Seconds(),
+		}
+		if waits.dispatched > 0 {
+			entry.AvgWaitSeconds = (waits.total / time.Duration(waits.dispatched)).Seconds()
+		}
+		status.Companies = append(status.Companies, entry)
+	}
+
+	sort.Slice(status.Companies, func(i, j int) bool {
+		a, b := status.Companies[i], status.Companies[j]
+		if a.Depth != b.Depth {
+			return a.Depth > b.Depth
+		}
+		return a.CompanyID.String() < b.CompanyID.String()
+	})
+
+	return status, nil
+}
@@ -0,0 +1,220 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/company"
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/domain/task"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newBackloggedCompany creates a company on the plan with idle employees and
+// pending tasks created at the given time
+func newBackloggedCompany(plan company.Plan, employees, tasks int, createdAt time.Time) (*company.Company, []*employee.Employee, []*task.Task) {
+	c := company.NewCompany(uuid.New(), string(plan)+" company", "")
+	c.Settings = map[string]interface{}{"plan": string(plan)}
+
+	var emps []*employee.Employee
+	for i := 0; i < employees; i++ {
+		emps = append(emps, employee.NewEmployee(c.ID, fmt.Sprintf("Employee %d", i), "Writer"))
+	}
+
+	var pending []*task.Task
+	for i := 0; i < tasks; i++ {
+		t := task.NewTask(c.ID, fmt.Sprintf("Task %d", i), "", task.PriorityMedium)
+		t.CreatedAt = createdAt
+		pending = append(pending, t)
+	}
+	return c, emps, pending
+}
+
+func runningByCompany(repo *fakeTaskRepository) map[uuid.UUID]int {
+	running := make(map[uuid.UUID]int)
+	for _, t := range repo.tasks {
+		if t.Status == task.StatusRunning {
+			running[t.CompanyID]++
+		}
+	}
+	return running
+}
+
+func TestFairQueue_ServesInProportionToWeight(t *testing.T) {
+	q := newFairQueue()
+	heavy := &backlog{companyID: uuid.New(), weight: 3, queue: &task.Queue{}}
+	light := &backlog{companyID: uuid.New(), weight: 1, queue: &task.Queue{}}
+	backlogs := []*backlog{heavy, light}
+
+	served := make(map[uuid.UUID]int)
+	for i := 0; i < 8; i++ {
+		b := backlogs[q.next(backlogs)]
+		q.charge(b.companyID, b.weight, time.Second)
+		served[b.companyID]++
+	}
+
+	assert.Equal(t, 6, served[heavy.companyID])
+	assert.Equal(t, 2, served[light.companyID])
+	assert.Equal(t, int64(6), q.waitsOf(heavy.companyID).dispatched)
+}
+
+func TestFairQueue_IdleCompanyBanksNoCredit(t *testing.T) {
+	q := newFairQueue()
+	busy := &backlog{companyID: uuid.New(), weight: 1, queue: &task.Queue{}}
+	for i := 0; i < 10; i++ {
+		q.charge(busy.companyID, busy.weight, 0)
+	}
+
+	// A company arriving late starts at the current virtual time, not at zero
+	late := &backlog{companyID: uuid.New(), weight: 1, queue: &task.Queue{}}
+	backlogs := []*backlog{busy, late}
+
+	served := make(map[uuid.UUID]int)
+	for i := 0; i < 4; i++ {
+		b := backlogs[q.next(backlogs)]
+		q.charge(b.companyID, b.weight, 0)
+		served[b.companyID]++
+	}
+	assert.Equal(t, 2, served[busy.companyID])
+	assert.Equal(t, 2, served[late.companyID])
+}
+
+func TestTaskScheduler_Dispatch_SharesSlotsFairly(t *testing.T) {
+	old := time.Now().Add(-time.Hour)
+	recent := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name                 string
+		heavyPlan, lightPlan company.Plan
+		heavyTasks           int
+		lightTasks           int
+		slots                int
+		wantHeavy, wantLight int
+	}{
+		{"equal plans split the slots", company.PlanFree, company.PlanFree, 20, 5, 4, 2, 2},
+		{"a bigger plan gets a bigger share", company.PlanFree, company.PlanPro, 20, 5, 5, 1, 4},
+		{"unlimited slots run everything", company.PlanFree, company.PlanFree, 3, 3, 0, 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The heavy company queued first and has far more tasks
+			heavy, heavyEmps, heavyTasks := newBackloggedCompany(tt.heavyPlan, 20, tt.heavyTasks, old)
+			light, lightEmps, lightTasks := newBackloggedCompany(tt.lightPlan, 5, tt.lightTasks, recent)
+
+			taskRepo := newFakeTaskRepository(append(heavyTasks, lightTasks...)...)
+			employeeRepo := newFakeEmployeeRepository(append(heavyEmps, lightEmps...)...)
+			s := NewTaskScheduler(taskRepo, taskRepo, employeeRepo, nil, newFakeCompanyRepository(heavy, light), nil)
+			s.SetExecutionSlots(tt.slots)
+
+			scheduled, err := s.Dispatch(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantHeavy+tt.wantLight, scheduled)
+
+			running := runningByCompany(taskRepo)
+			assert.Equal(t, tt.wantHeavy, running[heavy.ID])
+			assert.Equal(t, tt.wantLight, running[light.ID])
+		})
+	}
+}
+
+func TestTaskScheduler_Dispatch_WaitsForFreeSlots(t *testing.T) {
+	c, emps, pending := newBackloggedCompany(company.PlanFree, 2, 2, time.Now())
+	taskRepo := newFakeTaskRepository(pending...)
+	s := NewTaskScheduler(taskRepo, taskRepo, newFakeEmployeeRepository(emps...), nil, newFakeCompanyRepository(c), nil)
+	s.SetExecutionSlots(1)
+
+	scheduled, err := s.ProcessPendingTasks(context.Background(), c.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, scheduled)
+
+	scheduled, err = s.ProcessPendingTasks(context.Background(), c.ID)
+	require.NoError(t, err)
+	assert.Zero(t, scheduled, "the only slot is taken")
+}
+
+// fakeDispatchRepository keeps the dispatch state shared by scheduler instances
+type fakeDispatchRepository struct {
+	fair  task.FairShare
+	locks int
+	saves int
+}
+
+func (r *fakeDispatchRepository) LockDispatch(_ context.Context) (*task.FairShare, error) {
+	r.locks++
+	fair := &task.FairShare{Virtual: r.fair.Virtual, Finish: make(map[uuid.UUID]float64)}
+	for companyID, finish := range r.fair.Finish {
+		fair.Finish[companyID] = finish
+	}
+	return fair, nil
+}
+
+func (r *fakeDispatchRepository) SaveFairShare(_ context.Context, fair *task.FairShare) error {
+	r.saves++
+	r.fair = *fair
+	return nil
+}
+
+func TestTaskScheduler_Dispatch_SharesFairShareAcrossInstances(t *testing.T) {
+	heavy, heavyEmps, heavyTasks := newBackloggedCompany(company.PlanFree, 5, 5, time.Now().Add(-time.Hour))
+	light, lightEmps, lightTasks := newBackloggedCompany(company.PlanFree, 5, 5, time.Now().Add(-time.Minute))
+
+	taskRepo := newFakeTaskRepository(append(heavyTasks, lightTasks...)...)
+	employeeRepo := newFakeEmployeeRepository(append(heavyEmps, lightEmps...)...)
+	companyRepo := newFakeCompanyRepository(heavy, light)
+	dispatchRepo := &fakeDispatchRepository{}
+	uow := &fakeUnitOfWork{}
+
+	first := NewTaskScheduler(taskRepo, taskRepo, employeeRepo, nil, companyRepo, uow)
+	first.SetDispatchRepository(dispatchRepo)
+	first.SetExecutionSlots(1)
+	second := NewTaskScheduler(taskRepo, taskRepo, employeeRepo, nil, companyRepo, uow)
+	second.SetDispatchRepository(dispatchRepo)
+	second.SetExecutionSlots(2)
+
+	scheduled, err := first.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, scheduled)
+	assert.Equal(t, 1, runningByCompany(taskRepo)[heavy.ID], "the company that queued first is served first")
+
+	// The other instance knows the heavy company was just served
+	scheduled, err = second.Dispatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, scheduled)
+	running := runningByCompany(taskRepo)
+	assert.Equal(t, 1, running[heavy.ID])
+	assert.Equal(t, 1, running[light.ID])
+
+	assert.Equal(t, 2, dispatchRepo.locks)
+	assert.Equal(t, 2, dispatchRepo.saves)
+}
+
+func TestTaskScheduler_QueueStatus(t *testing.T) {
+	c, emps, pending := newBackloggedCompany(company.PlanPro, 1, 3, time.Now().Add(-time.Minute))
+	taskRepo := newFakeTaskRepository(pending...)
+	s := NewTaskScheduler(taskRepo, taskRepo, newFakeEmployeeRepository(emps...), nil, newFakeCompanyRepository(c), nil)
+	s.SetExecutionSlots(1)
+
+	_, err := s.Dispatch(context.Background())
+	require.NoError(t, err)
+
+	status, err := s.QueueStatus(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, status.Slots)
+	assert.Equal(t, 1, status.Running)
+	require.Len(t, status.Companies, 1)
+
+	queue := status.Companies[0]
+	assert.Equal(t, c.ID, queue.CompanyID)
+	assert.Equal(t, company.PlanPro, queue.Plan)
+	assert.Equal(t, company.PlanPro.Limits().FairShareWeight, queue.Weight)
+	assert.Equal(t, 2, queue.Depth)
+	assert.GreaterOrEqual(t, queue.OldestWaitSeconds, 59.0)
+	assert.Equal(t, int64(1), queue.Dispatched)
+	assert.GreaterOrEqual(t, queue.AvgWaitSeconds, 59.0)
+}
@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// reapBatchSize bounds the leases recovered per reaper pass
+const reapBatchSize = 100
+
+// RecoveryPolicy decides what happens to tasks whose lease expired
+type RecoveryPolicy struct {
+	// LeaseTTL is how long a lease lives without a heartbeat
+	LeaseTTL time.Duration
+	// OnExpiry is the action for an expired task: requeued or failed
+	OnExpiry task.RecoveryAction
+	// MaxRequeues is how often a task may be requeued before it is failed
+	MaxRequeues int
+	// EmployeeStatus is the status given to the employee that lost the task: error or idle
+	EmployeeStatus employee.Status
+}
+
+// DefaultRecoveryPolicy requeues a task twice, then fails it; the employee goes to error
+func DefaultRecoveryPolicy() RecoveryPolicy {
+	return RecoveryPolicy{
+		LeaseTTL:       time.Minute,
+		OnExpiry:       task.RecoveryRequeued,
+		MaxRequeues:    2,
+		EmployeeStatus: employee.StatusError,
+	}
+}
+
+// SetRecoveryPolicy replaces the recovery policy
+func (s *TaskScheduler) SetRecoveryPolicy(policy RecoveryPolicy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	defaults := DefaultRecoveryPolicy()
+	if policy.LeaseTTL <= 0 {
+		policy.LeaseTTL = defaults.LeaseTTL
+	}
+	if policy.OnExpiry != task.RecoveryRequeued && policy.OnExpiry != task.RecoveryFailed {
+		policy.OnExpiry = defaults.OnExpiry
+	}
+	if policy.EmployeeStatus != employee.StatusError && policy.EmployeeStatus != employee.StatusIdle {
+		policy.EmployeeStatus = defaults.EmployeeStatus
+	}
+	s.recovery = policy
+}
+
+// Heartbeat renews the lease of a running task. It returns errors.ErrNotFound
+// once the lease is gone, telling the worker to stop working on the task.
+func (s *TaskScheduler) Heartbeat(ctx context.Context, taskID, employeeID uuid.UUID) error {
+	s.mu.RLock()
+	ttl := s.recovery.LeaseTTL
+	s.mu.RUnlock()
+
+	return s.leaseRepo.RenewLease(ctx, taskID, employeeID, time.Now().Add(ttl))
+}
+
+// ListRecoveries lists the lease recoveries of a task
+func (s *TaskScheduler) ListRecoveries(ctx context.Context, taskID uuid.UUID) ([]*task.Recovery, error) {
+	return s.leaseRepo.ListRecoveries(ctx, taskID)
+}
+
+// RecoverExpiredLeases requeues or fails the tasks whose lease expired before
+// now and frees their employees, recording each recovery
+func (s *TaskScheduler) RecoverExpiredLeases(ctx context.Context, now time.Time) (int, error) {
+	s.mu.RLock()
+	policy := s.recovery
+	s.mu.RUnlock()
+
+	var recovered []*task.Task
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		leases, err := s.leaseRepo.LockExpiredLeases(ctx, now, reapBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, lease := range leases {
+			t, err := s.recoverLease(ctx, lease, policy)
+			if err != nil {
+				return fmt.Errorf("failed to recover task %s: %w", lease.TaskID, err)
+			}
+			if t == nil {
+				continue
+			}
+			if t.Status == task.StatusFailed {
+				if err := s.publishTask(ctx, eventbus.EventTaskFailed, t); err != nil {
+					return fmt.Errorf("failed to publish failure of task %s: %w", t.ID, err)
+				}
+			}
+			recovered = append(recovered, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	companies := make(map[uuid.UUID]bool)
+	for _, t := range recovered {
+		companies[t.CompanyID] = true
+	}
+	for companyID := range companies {
+		if _, err := s.ProcessPendingTasks(ctx, companyID); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to reschedule company %s after recovery: %v", companyID, err))
+		}
+	}
+
+	return len(recovered), nil
+}
+
+// recoverLease handles one expired lease inside the reaper transaction
+func (s *TaskScheduler) recoverLease(ctx context.Context, lease *task.Lease, policy RecoveryPolicy) (*task.Task, error) {
+	if err := s.leaseRepo.ReleaseLease(ctx, lease.TaskID); err != nil {
+		return nil, err
+	}
+
+	t, err := s.taskRepo.GetByID(ctx, lease.TaskID)
+	if err != nil {
+		return nil, err
+	}
+	if t.Status != task.StatusRunning {
+		// The task finished without releasing its lease; nothing to recover
+		return nil, nil
+	}
+
+	// 1. Requeue or fail the task
+	previous, err := s.leaseRepo.ListRecoveries(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	action := policy.OnExpiry
+	reason := fmt.Sprintf("lease expired at %s without heartbeat", lease.ExpiresAt.Format(time.RFC3339))
+	if action == task.RecoveryRequeued && len(previous) >= policy.MaxRequeues {
+		action = task.RecoveryFailed
+		reason = fmt.Sprintf("%s; requeued %d times already", reason, len(previous))
+	}
+
+	if action == task.RecoveryRequeued {
+		t.Requeue()
+	} else {
+		t.Fail("worker stopped responding: " + reason)
+	}
+	if err := s.taskRepo.Update(ctx, t); err != nil {
+		return nil, err
+	}
+
+	// 2. Free the employee that lost the task
+	emp, err := s.employeeRepo.LockByID(ctx, lease.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.employeeRepo.ReleaseAssignment(ctx, emp.ID, t.ID); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	active, err := s.employeeRepo.GetActiveAssignments(ctx, emp.ID)
+	if err != nil {
+		return nil, err
+	}
+	remaining := make([]uuid.UUID, 0, len(active))
+	for _, a := range active {
+		remaining = append(remaining, a.TaskID)
+	}
+	emp.ReleaseTask(false, remaining)
+	if policy.EmployeeStatus == employee.StatusError {
+		emp.SetStatus(employee.StatusError)
+	}
+	if err := s.employeeRepo.Update(ctx, emp); err != nil {
+		return nil, err
+	}
+
+	// 3. Record the recovery
+	recovery := task.NewRecovery(lease, action, string(emp.Status), reason)
+	if err := s.leaseRepo.CreateRecovery(ctx, recovery); err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+// StartLeaseReaper periodically recovers tasks with expired leases until ctx is cancelled
+func (s *TaskScheduler) StartLeaseReaper(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				if _, err := s.RecoverExpiredLeases(ctx, now); err != nil {
+					logger.Warn(fmt.Sprintf("Failed to recover expired task leases: %v", err))
+				}
+			}
+		}
+	}()
+}
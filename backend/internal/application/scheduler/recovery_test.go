@@ -0,0 +1,88 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newRecoveryScheduler(t *testing.T, policy RecoveryPolicy) (*TaskScheduler, *fakeTaskRepository, *employee.Employee, *task.Task) {
+	companyID := uuid.New()
+	emp := employee.NewEmployee(companyID, "Solo", "Writer")
+	newTask := task.NewTask(companyID, "Write", "", task.PriorityMedium)
+
+	taskRepo := newFakeTaskRepository(newTask)
+	s := NewTaskScheduler(taskRepo, taskRepo, newFakeEmployeeRepository(emp), nil, nil, nil)
+	s.eventBus = eventbus.NewEventBus()
+	s.SetRecoveryPolicy(policy)
+
+	require.NoError(t, s.Schedule(context.Background(), newTask))
+	require.Equal(t, task.StatusRunning, newTask.Status)
+	require.Contains(t, taskRepo.leases, newTask.ID)
+	return s, taskRepo, emp, newTask
+}
+
+func TestTaskScheduler_RecoverExpiredLeases_Requeues(t *testing.T) {
+	s, taskRepo, emp, stuck := newRecoveryScheduler(t, DefaultRecoveryPolicy())
+
+	recovered, err := s.RecoverExpiredLeases(context.Background(), time.Now().Add(2*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+
+	requeued := taskRepo.tasks[stuck.ID]
+	assert.Equal(t, task.StatusPending, requeued.Status, "employee in error must not get the task back")
+	assert.Nil(t, requeued.AssignedEmployeeID)
+	assert.Equal(t, employee.StatusError, emp.Status)
+	assert.Nil(t, emp.CurrentTaskID)
+	assert.NotContains(t, taskRepo.leases, stuck.ID)
+
+	recoveries, err := s.ListRecoveries(context.Background(), stuck.ID)
+	require.NoError(t, err)
+	require.Len(t, recoveries, 1)
+	assert.Equal(t, task.RecoveryRequeued, recoveries[0].Action)
+	assert.Equal(t, string(employee.StatusError), recoveries[0].EmployeeStatus)
+}
+
+func TestTaskScheduler_RecoverExpiredLeases_FailsAfterMaxRequeues(t *testing.T) {
+	policy := DefaultRecoveryPolicy()
+	policy.MaxRequeues = 0
+	policy.EmployeeStatus = employee.StatusIdle
+	s, taskRepo, emp, stuck := newRecoveryScheduler(t, policy)
+
+	_, err := s.RecoverExpiredLeases(context.Background(), time.Now().Add(2*time.Minute))
+	require.NoError(t, err)
+
+	failed := taskRepo.tasks[stuck.ID]
+	assert.Equal(t, task.StatusFailed, failed.Status)
+	assert.NotEmpty(t, failed.ErrorMessage)
+	assert.Equal(t, employee.StatusIdle, emp.Status)
+	require.Len(t, taskRepo.recoveries, 1)
+	assert.Equal(t, task.RecoveryFailed, taskRepo.recoveries[0].Action)
+	assert.Len(t, s.eventBus.GetHistoryByType(eventbus.EventTaskFailed, 10), 1)
+}
+
+func TestTaskScheduler_Heartbeat_KeepsLeaseAlive(t *testing.T) {
+	s, taskRepo, emp, running := newRecoveryScheduler(t, DefaultRecoveryPolicy())
+	expiresAt := taskRepo.leases[running.ID].ExpiresAt
+
+	time.Sleep(time.Millisecond)
+	require.NoError(t, s.Heartbeat(context.Background(), running.ID, emp.ID))
+	assert.True(t, taskRepo.leases[running.ID].ExpiresAt.After(expiresAt))
+
+	recovered, err := s.RecoverExpiredLeases(context.Background(), expiresAt)
+	require.NoError(t, err)
+	assert.Zero(t, recovered)
+	assert.Equal(t, task.StatusRunning, taskRepo.tasks[running.ID].Status)
+
+	err = s.Heartbeat(context.Background(), running.ID, uuid.New())
+	assert.True(t, errors.IsNotFound(err), "only the leaseholder may renew")
+}
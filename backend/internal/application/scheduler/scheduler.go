@@ -2,13 +2,18 @@ package scheduler
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
-	"sort"
 	"sync"
 	"time"
 
+	"unlimited-corp/internal/domain/company"
 	"unlimited-corp/internal/domain/employee"
 	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
 
 	"github.com/google/uuid"
 )
@@ -16,17 +21,77 @@ import (
 // TaskScheduler handles task assignment to employees
 type TaskScheduler struct {
 	taskRepo     task.Repository
+	leaseRepo    task.LeaseRepository
 	employeeRepo employee.Repository
+	calendarRepo employee.CalendarRepository
+	companyRepo  company.Repository
+	uow          database.UnitOfWork
+	eventBus     eventbus.EventBus
+	strategies   map[string]Strategy
+	recovery     RecoveryPolicy
+	fair         *fairQueue
+	dispatchRepo task.DispatchRepository
+	slots        int
 	mu           sync.RWMutex
+	dispatchMu   sync.Mutex
 	isRunning    bool
+
+	subscriptions []*eventbus.Subscription
 }
 
-// NewTaskScheduler creates a new task scheduler
-func NewTaskScheduler(taskRepo task.Repository, employeeRepo employee.Repository) *TaskScheduler {
-	return &TaskScheduler{
+// NewTaskScheduler creates a new task scheduler with the built-in strategies
+func NewTaskScheduler(taskRepo task.Repository, leaseRepo task.LeaseRepository, employeeRepo employee.Repository, calendarRepo employee.CalendarRepository, companyRepo company.Repository, uow database.UnitOfWork) *TaskScheduler {
+	s := &TaskScheduler{
 		taskRepo:     taskRepo,
+		leaseRepo:    leaseRepo,
 		employeeRepo: employeeRepo,
+		calendarRepo: calendarRepo,
+		companyRepo:  companyRepo,
+		uow:          uow,
+		eventBus:     eventbus.GetEventBus(),
+		strategies:   make(map[string]Strategy),
+		recovery:     DefaultRecoveryPolicy(),
+		fair:         newFairQueue(),
 	}
+
+	s.RegisterStrategy(NewWeightedStrategy(DefaultWeights()))
+	s.RegisterStrategy(NewRoundRobinStrategy(employeeRepo))
+	s.RegisterStrategy(LeastLoadedStrategy{})
+	s.RegisterStrategy(CheapestModelStrategy{})
+	s.RegisterStrategy(BestSuccessRateStrategy{})
+
+	return s
+}
+
+// RegisterStrategy registers a scheduling strategy, replacing any with the same name
+func (s *TaskScheduler) RegisterStrategy(strategy Strategy) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.strategies[strategy.Name()] = strategy
+}
+
+// resolveStrategy picks the strategy configured for the company and task type
+func (s *TaskScheduler) resolveStrategy(c *company.Company, t *task.Task) Strategy {
+	settings := schedulingSettingsOf(c)
+
+	name := settings.Strategy
+	if taskType := t.Type(); taskType != "" {
+		if override, ok := settings.TaskTypes[taskType]; ok {
+			name = override
+		}
+	}
+
+	if name == "" || name == StrategyWeighted {
+		if settings.Weights != nil {
+			return NewWeightedStrategy(*settings.Weights)
+		}
+		name = StrategyWeighted
+	}
+
+	if strategy, ok := s.strategies[name]; ok {
+		return strategy
+	}
+	return s.strategies[StrategyWeighted]
 }
 
 // SchedulingScore represents the match score between an employee and a task
@@ -34,166 +99,225 @@ type SchedulingScore struct {
 	EmployeeID uuid.UUID
 	Score      float64
 	Reason     string
+	Factors    []FactorScore
+}
+
+// FactorScore is the contribution of a single scoring factor
+type FactorScore struct {
+	Name     string  `json:"name"`
+	Score    float64 `json:"score"`
+	Weight   float64 `json:"weight"`
+	Weighted float64 `json:"weighted"`
+	Detail   string  `json:"detail"`
 }
 
-// Schedule assigns a task to the most suitable employee
+// Schedule assigns a task to the most suitable employee. The task and the
+// candidate employees are locked with SKIP LOCKED semantics and the whole
+// assignment is written in one transaction, so concurrent schedulers never
+// hand the same capacity out twice.
 func (s *TaskScheduler) Schedule(ctx context.Context, t *task.Task) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// 1. Get all idle employees for the company
-	employees, err := s.employeeRepo.GetByStatus(ctx, t.CompanyID, employee.StatusIdle)
-	if err != nil {
-		return fmt.Errorf("failed to list employees: %w", err)
-	}
-
-	if len(employees) == 0 {
-		// No idle employees, task stays in pending
-		return nil
-	}
+	var assigned *task.Task
+	var decision *SchedulingDecision
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		// 1. Lock the task; another scheduler may already be handling it
+		locked, err := s.taskRepo.LockPending(ctx, t.ID)
+		if errors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to lock task: %w", err)
+		}
 
-	// 2. Calculate scores for each employee
-	scores := s.calculateScores(ctx, t, employees)
+		// 2. Lock employees that can take work and rank those with free capacity
+		employees, err := s.employeeRepo.LockSchedulable(ctx, locked.CompanyID)
+		if err != nil {
+			return fmt.Errorf("failed to lock employees: %w", err)
+		}
 
-	// 3. Sort by score descending
-	sort.Slice(scores, func(i, j int) bool {
-		return scores[i].Score > scores[j].Score
-	})
+		decision, err = s.evaluate(ctx, locked, employees)
+		if err != nil {
+			return err
+		}
 
-	// 4. Assign to the best matching employee
-	bestMatch := scores[0]
-	t.AssignedEmployeeID = &bestMatch.EmployeeID
-	t.Status = task.StatusRunning
-	now := time.Now()
-	t.StartedAt = &now
+		if decision.SelectedEmployeeID == nil {
+			// No employee has capacity, task stays in pending
+			return nil
+		}
 
-	// Update task in database
-	if err := s.taskRepo.Update(ctx, t); err != nil {
-		return fmt.Errorf("failed to update task: %w", err)
-	}
+		// 3. Assign to the best matching employee
+		employeeID := *decision.SelectedEmployeeID
+		locked.AssignedEmployeeID = &employeeID
+		locked.Status = task.StatusRunning
+		now := time.Now()
+		locked.StartedAt = &now
+		locked.UpdatedAt = now
 
-	// Update employee status
-	selectedEmployee, err := s.employeeRepo.GetByID(ctx, bestMatch.EmployeeID)
-	if err != nil {
-		return fmt.Errorf("failed to get employee: %w", err)
-	}
+		if err := s.taskRepo.Update(ctx, locked); err != nil {
+			return fmt.Errorf("failed to update task: %w", err)
+		}
 
-	selectedEmployee.AssignTask(t.ID)
-	if err := s.employeeRepo.Update(ctx, selectedEmployee); err != nil {
-		return fmt.Errorf("failed to update employee status: %w", err)
-	}
+		var selectedEmployee *employee.Employee
+		for _, emp := range employees {
+			if emp.ID == employeeID {
+				selectedEmployee = emp
+				break
+			}
+		}
 
-	return nil
-}
+		selectedEmployee.AssignTask(locked.ID)
+		if err := s.employeeRepo.Update(ctx, selectedEmployee); err != nil {
+			return fmt.Errorf("failed to update employee status: %w", err)
+		}
 
-// calculateScores calculates matching scores for employees
-func (s *TaskScheduler) calculateScores(ctx context.Context, t *task.Task, employees []*employee.Employee) []SchedulingScore {
-	scores := make([]SchedulingScore, len(employees))
+		// Record the assignment together with its decision explanation
+		assignment := employee.NewAssignment(locked.CompanyID, selectedEmployee.ID, locked.ID)
+		if explanation, err := json.Marshal(decision); err == nil {
+			assignment.Decision = explanation
+		}
+		if err := s.employeeRepo.CreateAssignment(ctx, assignment); err != nil {
+			return fmt.Errorf("failed to record assignment: %w", err)
+		}
 
-	for i, emp := range employees {
-		score := 0.0
-		reasons := make([]string, 0)
+		// Lease the task to the employee until its worker stops heartbeating
+		lease := task.NewLease(locked.CompanyID, locked.ID, selectedEmployee.ID, s.recovery.LeaseTTL)
+		if err := s.leaseRepo.AcquireLease(ctx, lease); err != nil {
+			return fmt.Errorf("failed to acquire task lease: %w", err)
+		}
 
-		// 1. Check if employee has required skills (weight: 40%)
-		skillMatch := s.calculateSkillMatch(ctx, t, emp)
-		score += skillMatch * 0.4
-		if skillMatch > 0 {
-			reasons = append(reasons, fmt.Sprintf("技能匹配度: %.0f%%", skillMatch))
+		// Announce the assignment with it, so it is never announced if it rolls back
+		if err := s.publishAssigned(ctx, locked, decision); err != nil {
+			return fmt.Errorf("failed to publish task assignment: %w", err)
 		}
 
-		// 2. Load balancing based on total tasks (weight: 30%)
-		loadScore := 100.0 / float64(emp.TotalTasks+1)
-		score += loadScore * 0.3
-		reasons = append(reasons, fmt.Sprintf("负载评分: %.1f", loadScore))
+		assigned = locked
+		return nil
+	})
+	if err != nil || assigned == nil {
+		return err
+	}
 
-		// 3. Success rate (weight: 20%)
-		successScore := emp.SuccessRate * 100
-		score += successScore * 0.2
-		reasons = append(reasons, fmt.Sprintf("成功率: %.0f%%", emp.SuccessRate*100))
+	*t = *assigned
+	return nil
+}
 
-		// 4. Priority bonus (weight: 10%)
-		priorityBonus := s.getPriorityBonus(t.Priority)
-		score += priorityBonus * 0.1
-		reasons = append(reasons, fmt.Sprintf("优先级加成: %.0f", priorityBonus))
+// inTransaction runs fn in a unit of work, or directly when none is configured
+func (s *TaskScheduler) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.uow == nil {
+		return fn(ctx)
+	}
+	return s.uow.Do(ctx, fn)
+}
 
-		scores[i] = SchedulingScore{
-			EmployeeID: emp.ID,
-			Score:      score,
-			Reason:     fmt.Sprintf("总分: %.1f", score),
-		}
+// loadCompany returns the company of a task, or nil if it cannot be loaded
+func (s *TaskScheduler) loadCompany(ctx context.Context, companyID uuid.UUID) *company.Company {
+	if s.companyRepo == nil {
+		return nil
 	}
 
-	return scores
+	c, err := s.companyRepo.GetByID(ctx, companyID)
+	if err != nil {
+		return nil
+	}
+	return c
 }
 
-// getPriorityBonus returns a numeric bonus based on task priority
-func (s *TaskScheduler) getPriorityBonus(priority task.TaskPriority) float64 {
-	switch priority {
-	case task.PriorityUrgent:
-		return 100.0
-	case task.PriorityHigh:
-		return 75.0
-	case task.PriorityMedium:
-		return 50.0
-	case task.PriorityLow:
-		return 25.0
-	default:
-		return 50.0
+// planLimitsOf returns the plan limits of a company, defaulting to the free plan
+func planLimitsOf(c *company.Company) company.PlanLimits {
+	if c == nil {
+		return company.PlanFree.Limits()
 	}
+	return c.Plan().Limits()
 }
 
-// calculateSkillMatch checks if employee has required skills
-func (s *TaskScheduler) calculateSkillMatch(ctx context.Context, _ *task.Task, emp *employee.Employee) float64 {
-	// Get employee skills
-	skills, err := s.employeeRepo.GetSkills(ctx, emp.ID)
-	if err != nil {
-		return 50.0 // Default score if can't get skills
+// loadCalendars returns the calendars of a company keyed by employee ID
+func (s *TaskScheduler) loadCalendars(ctx context.Context, companyID uuid.UUID) (map[uuid.UUID]*employee.Calendar, error) {
+	calendars := make(map[uuid.UUID]*employee.Calendar)
+	if s.calendarRepo == nil {
+		return calendars, nil
 	}
 
-	if len(skills) == 0 {
-		return 30.0 // Lower score for employees without skills
+	list, err := s.calendarRepo.ListCalendarsByCompany(ctx, companyID)
+	if err != nil {
+		return nil, err
 	}
-
-	// If task doesn't require specific skills, give base score
-	return 80.0
+	for _, cal := range list {
+		calendars[cal.EmployeeID] = cal
+	}
+	return calendars, nil
 }
 
-// ReleaseEmployee marks an employee as idle after task completion
-func (s *TaskScheduler) ReleaseEmployee(ctx context.Context, employeeID uuid.UUID, success bool) error {
-	emp, err := s.employeeRepo.GetByID(ctx, employeeID)
-	if err != nil {
-		return fmt.Errorf("failed to get employee: %w", err)
+// locationOf returns the timezone of a company, defaulting to UTC
+func locationOf(c *company.Company) *time.Location {
+	if c == nil {
+		return time.UTC
 	}
+	return c.Location()
+}
 
-	emp.CompleteTask(success)
-	return s.employeeRepo.Update(ctx, emp)
+// capacityOf resolves how many tasks an employee may hold at once
+func (s *TaskScheduler) capacityOf(emp *employee.Employee, limits company.PlanLimits) int {
+	if max := emp.MaxConcurrentTasks(); max > 0 {
+		return max
+	}
+	if limits.MaxConcurrentTasksPerEmployee > 0 {
+		return limits.MaxConcurrentTasksPerEmployee
+	}
+	return 1
 }
 
-// ProcessPendingTasks processes all pending tasks for a company
-func (s *TaskScheduler) ProcessPendingTasks(ctx context.Context, companyID uuid.UUID) (int, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// ReleaseEmployee releases a finished task from an employee; the employee
+// becomes idle once no other assignments remain active
+func (s *TaskScheduler) ReleaseEmployee(ctx context.Context, employeeID, taskID uuid.UUID, success bool) error {
+	return s.inTransaction(ctx, func(ctx context.Context) error {
+		emp, err := s.employeeRepo.LockByID(ctx, employeeID)
+		if err != nil {
+			return fmt.Errorf("failed to get employee: %w", err)
+		}
 
-	// Get pending tasks
-	tasks, err := s.taskRepo.ListByCompanyID(ctx, companyID, 100, 0)
-	if err != nil {
-		return 0, fmt.Errorf("failed to list tasks: %w", err)
-	}
+		if err := s.employeeRepo.ReleaseAssignment(ctx, employeeID, taskID); err != nil && err != errors.ErrNotFound {
+			return fmt.Errorf("failed to release assignment: %w", err)
+		}
+		if err := s.leaseRepo.ReleaseLease(ctx, taskID); err != nil {
+			return fmt.Errorf("failed to release task lease: %w", err)
+		}
 
-	scheduled := 0
-	for _, t := range tasks {
-		if t.Status == task.StatusPending {
-			if err := s.Schedule(ctx, t); err != nil {
-				continue // Log error and continue with next task
-			}
-			scheduled++
+		active, err := s.employeeRepo.GetActiveAssignments(ctx, employeeID)
+		if err != nil {
+			return fmt.Errorf("failed to get active assignments: %w", err)
+		}
+
+		remaining := make([]uuid.UUID, 0, len(active))
+		for _, a := range active {
+			remaining = append(remaining, a.TaskID)
 		}
+
+		emp.ReleaseTask(success, remaining)
+		return s.employeeRepo.Update(ctx, emp)
+	})
+}
+
+// ProcessPendingTasks schedules pending tasks after a change in a company.
+// With limited execution slots every company competes for the capacity that
+// was freed, so the whole queue is dispatched instead.
+func (s *TaskScheduler) ProcessPendingTasks(ctx context.Context, companyID uuid.UUID) (int, error) {
+	if s.executionSlots() > 0 {
+		return s.Dispatch(ctx)
 	}
+	return s.dispatch(ctx, []*task.Queue{{CompanyID: companyID}})
+}
 
-	return scheduled, nil
+// processAllPending dispatches pending tasks of every company that has any
+func (s *TaskScheduler) processAllPending(ctx context.Context) {
+	if _, err := s.Dispatch(ctx); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to dispatch pending tasks: %v", err))
+	}
 }
 
-// StartBackgroundScheduler starts the background scheduling loop
+// StartBackgroundScheduler starts the polling loop that backs up event-driven
+// scheduling, catching tasks whose events were lost or arrived with no capacity
 func (s *TaskScheduler) StartBackgroundScheduler(ctx context.Context, interval time.Duration) {
 	s.mu.Lock()
 	if s.isRunning {
@@ -215,8 +339,7 @@ func (s *TaskScheduler) StartBackgroundScheduler(ctx context.Context, interval t
 				s.mu.Unlock()
 				return
 			case <-ticker.C:
-				// Background task scheduling is handled here
-				// In a real implementation, you would iterate over all companies
+				s.processAllPending(ctx)
 			}
 		}
 	}()
@@ -0,0 +1,580 @@
+package scheduler
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/config"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&config.LogConfig{Level: "error", Format: "console"})
+	os.Exit(m.Run())
+}
+
+// fakeEmployeeRepository is an in-memory employee.Repository for scheduler tests
+type fakeEmployeeRepository struct {
+	employees   map[uuid.UUID]*employee.Employee
+	assignments []*employee.Assignment
+}
+
+func newFakeEmployeeRepository(employees ...*employee.Employee) *fakeEmployeeRepository {
+	repo := &fakeEmployeeRepository{employees: make(map[uuid.UUID]*employee.Employee)}
+	for _, e := range employees {
+		repo.employees[e.ID] = e
+	}
+	return repo
+}
+
+func (r *fakeEmployeeRepository) Create(_ context.Context, e *employee.Employee) error {
+	r.employees[e.ID] = e
+	return nil
+}
+
+func (r *fakeEmployeeRepository) GetByID(_ context.Context, id uuid.UUID) (*employee.Employee, error) {
+	if e, ok := r.employees[id]; ok {
+		return e, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeEmployeeRepository) GetByCompanyID(_ context.Context, companyID uuid.UUID) ([]*employee.Employee, error) {
+	var result []*employee.Employee
+	for _, e := range r.employees {
+		if e.CompanyID == companyID {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeEmployeeRepository) GetAvailable(ctx context.Context, companyID uuid.UUID) ([]*employee.Employee, error) {
+	return r.GetByStatus(ctx, companyID, employee.StatusIdle)
+}
+
+func (r *fakeEmployeeRepository) Update(_ context.Context, e *employee.Employee) error {
+	r.employees[e.ID] = e
+	return nil
+}
+
+func (r *fakeEmployeeRepository) Delete(_ context.Context, id uuid.UUID) error {
+	delete(r.employees, id)
+	return nil
+}
+
+func (r *fakeEmployeeRepository) AssignSkill(context.Context, uuid.UUID, uuid.UUID, float64) error {
+	return nil
+}
+
+func (r *fakeEmployeeRepository) RemoveSkill(context.Context, uuid.UUID, uuid.UUID) error {
+	return nil
+}
+
+func (r *fakeEmployeeRepository) GetSkills(context.Context, uuid.UUID) ([]*employee.EmployeeSkill, error) {
+	return nil, nil
+}
+
+func (r *fakeEmployeeRepository) GetByStatus(_ context.Context, companyID uuid.UUID, status employee.Status) ([]*employee.Employee, error) {
+	var result []*employee.Employee
+	for _, e := range r.employees {
+		if e.CompanyID == companyID && e.Status == status {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeEmployeeRepository) CountByCompany(ctx context.Context, companyID uuid.UUID) (int, error) {
+	employees, _ := r.GetByCompanyID(ctx, companyID)
+	return len(employees), nil
+}
+
+func (r *fakeEmployeeRepository) GetSchedulable(_ context.Context, companyID uuid.UUID) ([]*employee.Employee, error) {
+	var result []*employee.Employee
+	for _, e := range r.employees {
+		if e.CompanyID == companyID && (e.Status == employee.StatusIdle || e.Status == employee.StatusWorking) {
+			result = append(result, e)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeEmployeeRepository) LockSchedulable(ctx context.Context, companyID uuid.UUID) ([]*employee.Employee, error) {
+	return r.GetSchedulable(ctx, companyID)
+}
+
+func (r *fakeEmployeeRepository) LockByID(ctx context.Context, id uuid.UUID) (*employee.Employee, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r *fakeEmployeeRepository) CreateAssignment(_ context.Context, a *employee.Assignment) error {
+	r.assignments = append(r.assignments, a)
+	return nil
+}
+
+func (r *fakeEmployeeRepository) ReleaseAssignment(_ context.Context, employeeID, taskID uuid.UUID) error {
+	for _, a := range r.assignments {
+		if a.EmployeeID == employeeID && a.TaskID == taskID && a.IsActive() {
+			now := a.AssignedAt
+			a.ReleasedAt = &now
+			return nil
+		}
+	}
+	return errors.ErrNotFound
+}
+
+func (r *fakeEmployeeRepository) GetActiveAssignments(_ context.Context, employeeID uuid.UUID) ([]*employee.Assignment, error) {
+	var result []*employee.Assignment
+	for _, a := range r.assignments {
+		if a.EmployeeID == employeeID && a.IsActive() {
+			result = append(result, a)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeEmployeeRepository) GetLatestAssignmentByTask(_ context.Context, taskID uuid.UUID) (*employee.Assignment, error) {
+	for i := len(r.assignments) - 1; i >= 0; i-- {
+		if r.assignments[i].TaskID == taskID {
+			return r.assignments[i], nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeEmployeeRepository) GetLatestAssignmentByCompany(_ context.Context, companyID uuid.UUID) (*employee.Assignment, error) {
+	for i := len(r.assignments) - 1; i >= 0; i-- {
+		if r.assignments[i].CompanyID == companyID {
+			return r.assignments[i], nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeEmployeeRepository) CountActiveAssignments(_ context.Context, companyID uuid.UUID) (map[uuid.UUID]int, error) {
+	counts := make(map[uuid.UUID]int)
+	for _, a := range r.assignments {
+		if a.CompanyID == companyID && a.IsActive() {
+			counts[a.EmployeeID]++
+		}
+	}
+	return counts, nil
+}
+
+// fakeTaskRepository is an in-memory task.Repository for scheduler tests.
+// Reads return copies, as rows read from a database would be.
+type fakeTaskRepository struct {
+	mu         sync.Mutex
+	tasks      map[uuid.UUID]*task.Task
+	locked     map[uuid.UUID]bool // rows held by another transaction
+	leases     map[uuid.UUID]*task.Lease
+	recoveries []*task.Recovery
+}
+
+func newFakeTaskRepository(tasks ...*task.Task) *fakeTaskRepository {
+	repo := &fakeTaskRepository{
+		tasks:  make(map[uuid.UUID]*task.Task),
+		locked: make(map[uuid.UUID]bool),
+		leases: make(map[uuid.UUID]*task.Lease),
+	}
+	for _, t := range tasks {
+		repo.tasks[t.ID] = t
+	}
+	return repo
+}
+
+func (r *fakeTaskRepository) Create(_ context.Context, t *task.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks[t.ID] = t
+	return nil
+}
+
+func (r *fakeTaskRepository) GetByID(_ context.Context, id uuid.UUID) (*task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.tasks[id]; ok {
+		copied := *t
+		return &copied, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeTaskRepository) ListByCompanyID(_ context.Context, companyID uuid.UUID, _, _ int) ([]*task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*task.Task
+	for _, t := range r.tasks {
+		if t.CompanyID == companyID {
+			copied := *t
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeTaskRepository) Update(_ context.Context, t *task.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks[t.ID] = t
+	return nil
+}
+
+func (r *fakeTaskRepository) Delete(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.tasks, id)
+	return nil
+}
+
+func (r *fakeTaskRepository) ListPending(_ context.Context, companyID uuid.UUID, limit int) ([]*task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*task.Task
+	for _, t := range r.tasks {
+		if t.CompanyID == companyID && t.Status == task.StatusPending && len(result) < limit {
+			copied := *t
+			result = append(result, &copied)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeTaskRepository) ListQueues(context.Context) ([]*task.Queue, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	byCompany := make(map[uuid.UUID]*task.Queue)
+	var result []*task.Queue
+	for _, t := range r.tasks {
+		if t.Status != task.StatusPending {
+			continue
+		}
+		q, ok := byCompany[t.CompanyID]
+		if !ok {
+			q = &task.Queue{CompanyID: t.CompanyID, OldestCreatedAt: t.CreatedAt}
+			byCompany[t.CompanyID] = q
+			result = append(result, q)
+		}
+		q.Depth++
+		if t.CreatedAt.Before(q.OldestCreatedAt) {
+			q.OldestCreatedAt = t.CreatedAt
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeTaskRepository) CountRunning(context.Context) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	count := 0
+	for _, t := range r.tasks {
+		if t.Status == task.StatusRunning {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeTaskRepository) SetWorkflowRun(_ context.Context, id uuid.UUID, workflowID, runID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tasks[id]
+	if !ok {
+		return errors.ErrNotFound
+	}
+	t.BindWorkflow(workflowID, runID)
+	return nil
+}
+
+func (r *fakeTaskRepository) ListActiveWorkflowRuns(context.Context, string) ([]*task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return nil, nil
+}
+
+func (r *fakeTaskRepository) LockPending(_ context.Context, id uuid.UUID) (*task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tasks[id]
+	if !ok || r.locked[id] || t.Status != task.StatusPending {
+		return nil, errors.ErrNotFound
+	}
+	locked := *t
+	return &locked, nil
+}
+
+func (r *fakeTaskRepository) AcquireLease(_ context.Context, l *task.Lease) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.leases[l.TaskID] = l
+	return nil
+}
+
+func (r *fakeTaskRepository) RenewLease(_ context.Context, taskID, employeeID uuid.UUID, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.leases[taskID]
+	if !ok || l.EmployeeID != employeeID {
+		return errors.ErrNotFound
+	}
+	l.ExpiresAt = expiresAt
+	return nil
+}
+
+func (r *fakeTaskRepository) ReleaseLease(_ context.Context, taskID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.leases, taskID)
+	return nil
+}
+
+func (r *fakeTaskRepository) LockExpiredLeases(_ context.Context, now time.Time, limit int) ([]*task.Lease, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*task.Lease
+	for _, l := range r.leases {
+		if l.IsExpired(now) && len(result) < limit {
+			result = append(result, l)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeTaskRepository) CreateRecovery(_ context.Context, rec *task.Recovery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recoveries = append(r.recoveries, rec)
+	return nil
+}
+
+func (r *fakeTaskRepository) ListRecoveries(_ context.Context, taskID uuid.UUID) ([]*task.Recovery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var result []*task.Recovery
+	for _, rec := range r.recoveries {
+		if rec.TaskID == taskID {
+			result = append(result, rec)
+		}
+	}
+	return result, nil
+}
+
+// fakeCalendarRepository is an in-memory employee.CalendarRepository for scheduler tests
+type fakeCalendarRepository struct {
+	calendars map[uuid.UUID]*employee.Calendar
+}
+
+func newFakeCalendarRepository(calendars ...*employee.Calendar) *fakeCalendarRepository {
+	repo := &fakeCalendarRepository{calendars: make(map[uuid.UUID]*employee.Calendar)}
+	for _, c := range calendars {
+		repo.calendars[c.EmployeeID] = c
+	}
+	return repo
+}
+
+func (r *fakeCalendarRepository) GetCalendar(_ context.Context, employeeID uuid.UUID) (*employee.Calendar, error) {
+	if c, ok := r.calendars[employeeID]; ok {
+		return c, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeCalendarRepository) SaveCalendar(_ context.Context, c *employee.Calendar) error {
+	r.calendars[c.EmployeeID] = c
+	return nil
+}
+
+func (r *fakeCalendarRepository) ListCalendarsByCompany(_ context.Context, companyID uuid.UUID) ([]*employee.Calendar, error) {
+	var result []*employee.Calendar
+	for _, c := range r.calendars {
+		if c.CompanyID == companyID {
+			result = append(result, c)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeCalendarRepository) ListCalendars(context.Context) ([]*employee.Calendar, error) {
+	var result []*employee.Calendar
+	for _, c := range r.calendars {
+		result = append(result, c)
+	}
+	return result, nil
+}
+
+// fakeUnitOfWork counts units of work and runs them directly
+type fakeUnitOfWork struct {
+	calls int
+}
+
+func (u *fakeUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	u.calls++
+	return fn(ctx)
+}
+
+func TestTaskScheduler_Schedule_PrefersLessLoadedEmployee(t *testing.T) {
+	companyID := uuid.New()
+	busy := employee.NewEmployee(companyID, "Busy", "Writer")
+	busy.Settings = []byte(`{"max_concurrent_tasks": 2}`)
+	free := employee.NewEmployee(companyID, "Free", "Writer")
+	free.Settings = []byte(`{"max_concurrent_tasks": 2}`)
+	free.TotalTasks = 500 // a veteran employee must not be penalized forever
+
+	employeeRepo := newFakeEmployeeRepository(busy, free)
+	employeeRepo.assignments = append(employeeRepo.assignments, employee.NewAssignment(companyID, busy.ID, uuid.New()))
+	busy.SetStatus(employee.StatusWorking)
+
+	newTask := task.NewTask(companyID, "Write", "", task.PriorityMedium)
+	taskRepo := newFakeTaskRepository(newTask)
+	s := NewTaskScheduler(taskRepo, taskRepo, employeeRepo, nil, nil, nil)
+
+	require.NoError(t, s.Schedule(context.Background(), newTask))
+
+	require.NotNil(t, newTask.AssignedEmployeeID)
+	assert.Equal(t, free.ID, *newTask.AssignedEmployeeID)
+	assert.Equal(t, task.StatusRunning, newTask.Status)
+	assert.Equal(t, employee.StatusWorking, free.Status)
+
+	decision, err := s.GetDecision(context.Background(), newTask.ID)
+	require.NoError(t, err)
+	require.NotNil(t, decision.SelectedEmployeeID)
+	assert.Equal(t, free.ID, *decision.SelectedEmployeeID)
+}
+
+func TestTaskScheduler_Schedule_RespectsCapacity(t *testing.T) {
+	companyID := uuid.New()
+	emp := employee.NewEmployee(companyID, "Solo", "Writer")
+
+	first := task.NewTask(companyID, "First", "", task.PriorityMedium)
+	second := task.NewTask(companyID, "Second", "", task.PriorityMedium)
+	employeeRepo := newFakeEmployeeRepository(emp)
+	taskRepo := newFakeTaskRepository(first, second)
+	s := NewTaskScheduler(taskRepo, taskRepo, employeeRepo, nil, nil, nil)
+
+	require.NoError(t, s.Schedule(context.Background(), first))
+	require.NoError(t, s.Schedule(context.Background(), second))
+
+	assert.Equal(t, task.StatusRunning, first.Status)
+	assert.Equal(t, task.StatusPending, second.Status, "free plan allows one task per employee")
+
+	require.NoError(t, s.ReleaseEmployee(context.Background(), emp.ID, first.ID, true))
+	assert.Equal(t, employee.StatusIdle, emp.Status)
+
+	require.NoError(t, s.Schedule(context.Background(), second))
+	assert.Equal(t, task.StatusRunning, second.Status)
+}
+
+func TestTaskScheduler_Preview_ExplainsExclusions(t *testing.T) {
+	companyID := uuid.New()
+	idle := employee.NewEmployee(companyID, "Idle", "Writer")
+	offline := employee.NewEmployee(companyID, "Offline", "Writer")
+	offline.SetOffline()
+
+	newTask := task.NewTask(companyID, "Write", "", task.PriorityHigh)
+	employeeRepo := newFakeEmployeeRepository(idle, offline)
+	taskRepo := newFakeTaskRepository(newTask)
+	s := NewTaskScheduler(taskRepo, taskRepo, employeeRepo, nil, nil, nil)
+
+	decision, err := s.Preview(context.Background(), newTask)
+	require.NoError(t, err)
+
+	require.Len(t, decision.Candidates, 2)
+	assert.Equal(t, idle.ID, decision.Candidates[0].EmployeeID)
+	assert.True(t, decision.Candidates[0].Eligible)
+	assert.Equal(t, 1, decision.Candidates[0].Rank)
+	assert.Len(t, decision.Candidates[0].Factors, 4)
+
+	assert.Equal(t, offline.ID, decision.Candidates[1].EmployeeID)
+	assert.False(t, decision.Candidates[1].Eligible)
+	assert.NotEmpty(t, decision.Candidates[1].ExcludedReason)
+
+	// Preview must not assign anything
+	assert.Equal(t, task.StatusPending, newTask.Status)
+	assert.Empty(t, employeeRepo.assignments)
+}
+
+func TestTaskScheduler_Schedule_RunsInUnitOfWork(t *testing.T) {
+	companyID := uuid.New()
+	emp := employee.NewEmployee(companyID, "Solo", "Writer")
+	newTask := task.NewTask(companyID, "Write", "", task.PriorityMedium)
+
+	uow := &fakeUnitOfWork{}
+	employeeRepo := newFakeEmployeeRepository(emp)
+	taskRepo := newFakeTaskRepository(newTask)
+	s := NewTaskScheduler(taskRepo, taskRepo, employeeRepo, nil, nil, uow)
+
+	require.NoError(t, s.Schedule(context.Background(), newTask))
+	assert.Equal(t, 1, uow.calls)
+	assert.Equal(t, task.StatusRunning, newTask.Status)
+
+	require.NoError(t, s.ReleaseEmployee(context.Background(), emp.ID, newTask.ID, true))
+	assert.Equal(t, 2, uow.calls)
+}
+
+func TestTaskScheduler_Schedule_SkipsTaskLockedElsewhere(t *testing.T) {
+	companyID := uuid.New()
+	emp := employee.NewEmployee(companyID, "Solo", "Writer")
+	newTask := task.NewTask(companyID, "Write", "", task.PriorityMedium)
+
+	taskRepo := newFakeTaskRepository(newTask)
+	taskRepo.locked[newTask.ID] = true
+	employeeRepo := newFakeEmployeeRepository(emp)
+	s := NewTaskScheduler(taskRepo, taskRepo, employeeRepo, nil, nil, nil)
+
+	require.NoError(t, s.Schedule(context.Background(), newTask))
+	assert.Equal(t, task.StatusPending, newTask.Status)
+	assert.Equal(t, employee.StatusIdle, emp.Status)
+	assert.Empty(t, employeeRepo.assignments)
+}
+
+func TestTaskScheduler_ProcessPendingTasks(t *testing.T) {
+	companyID := uuid.New()
+	emp := employee.NewEmployee(companyID, "Solo", "Writer")
+	emp.Settings = []byte(`{"max_concurrent_tasks": 2}`)
+	first := task.NewTask(companyID, "First", "", task.PriorityMedium)
+	second := task.NewTask(companyID, "Second", "", task.PriorityMedium)
+	third := task.NewTask(companyID, "Third", "", task.PriorityMedium)
+
+	taskRepo := newFakeTaskRepository(first, second, third)
+	s := NewTaskScheduler(taskRepo, taskRepo, newFakeEmployeeRepository(emp), nil, nil, nil)
+
+	scheduled, err := s.ProcessPendingTasks(context.Background(), companyID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, scheduled)
+}
+
+func TestTaskScheduler_Schedule_HonorsCalendar(t *testing.T) {
+	companyID := uuid.New()
+	onLeave := employee.NewEmployee(companyID, "OnLeave", "Writer")
+	onLeave.SuccessRate = 1.0
+	onDuty := employee.NewEmployee(companyID, "OnDuty", "Writer")
+	onDuty.SuccessRate = 0.5
+
+	now := time.Now()
+	leave := employee.NewCalendar(companyID, onLeave.ID)
+	leave.Blackouts = []employee.Blackout{{Start: now.Add(-time.Hour), End: now.Add(time.Hour), Reason: "vacation"}}
+
+	newTask := task.NewTask(companyID, "Write", "", task.PriorityMedium)
+	taskRepo := newFakeTaskRepository(newTask)
+	s := NewTaskScheduler(taskRepo, taskRepo, newFakeEmployeeRepository(onLeave, onDuty), newFakeCalendarRepository(leave), nil, nil)
+
+	require.NoError(t, s.Schedule(context.Background(), newTask))
+	require.NotNil(t, newTask.AssignedEmployeeID)
+	assert.Equal(t, onDuty.ID, *newTask.AssignedEmployeeID)
+
+	decision, err := s.GetDecision(context.Background(), newTask.ID)
+	require.NoError(t, err)
+	require.Len(t, decision.Candidates, 2)
+	assert.Equal(t, "blackout: vacation", decision.Candidates[1].ExcludedReason)
+}
@@ -0,0 +1,378 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"unlimited-corp/internal/domain/company"
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Built-in strategy names
+const (
+	StrategyWeighted        = "weighted"
+	StrategyRoundRobin      = "round_robin"
+	StrategyLeastLoaded     = "least_loaded"
+	StrategyCheapestModel   = "cheapest_model"
+	StrategyBestSuccessRate = "best_success_rate"
+)
+
+// Candidate is an employee that still has free capacity
+type Candidate struct {
+	Employee    *employee.Employee
+	ActiveTasks int
+	Capacity    int
+	Skills      []*employee.EmployeeSkill
+	SkillsKnown bool // false when the employee's skills could not be loaded
+}
+
+// Strategy ranks the eligible candidates for a task; a higher score wins
+type Strategy interface {
+	Name() string
+	Score(ctx context.Context, t *task.Task, candidates []Candidate) []SchedulingScore
+}
+
+// AssignmentHistory returns the assignments already made in a company
+type AssignmentHistory interface {
+	GetLatestAssignmentByCompany(ctx context.Context, companyID uuid.UUID) (*employee.Assignment, error)
+}
+
+// Weights holds the factor weights of the weighted strategy
+type Weights struct {
+	SkillMatch  float64 `json:"skill_match"`
+	Load        float64 `json:"load"`
+	SuccessRate float64 `json:"success_rate"`
+	Priority    float64 `json:"priority"`
+}
+
+// DefaultWeights returns the default factor weights (40/30/20/10)
+func DefaultWeights() Weights {
+	return Weights{
+		SkillMatch:  0.4,
+		Load:        0.3,
+		SuccessRate: 0.2,
+		Priority:    0.1,
+	}
+}
+
+// normalize scales weights so they sum to 1, falling back to defaults if unusable
+func (w Weights) normalize() Weights {
+	if w.SkillMatch < 0 || w.Load < 0 || w.SuccessRate < 0 || w.Priority < 0 {
+		return DefaultWeights()
+	}
+	total := w.SkillMatch + w.Load + w.SuccessRate + w.Priority
+	if total <= 0 {
+		return DefaultWeights()
+	}
+	return Weights{
+		SkillMatch:  w.SkillMatch / total,
+		Load:        w.Load / total,
+		SuccessRate: w.SuccessRate / total,
+		Priority:    w.Priority / total,
+	}
+}
+
+// WeightedStrategy combines skill match, load, success rate and priority
+type WeightedStrategy struct {
+	weights Weights
+}
+
+// NewWeightedStrategy creates a weighted strategy with the given weights
+func NewWeightedStrategy(weights Weights) *WeightedStrategy {
+	return &WeightedStrategy{weights: weights.normalize()}
+}
+
+// Name returns the strategy name
+func (s *WeightedStrategy) Name() string {
+	return StrategyWeighted
+}
+
+// Score calculates matching scores for candidates
+func (s *WeightedStrategy) Score(_ context.Context, t *task.Task, candidates []Candidate) []SchedulingScore {
+	scores := make([]SchedulingScore, len(candidates))
+
+	for i, c := range candidates {
+		emp := c.Employee
+		skillMatch := skillMatchScore(c)
+		loadScore := loadScore(c.ActiveTasks, c.Capacity)
+		successScore := emp.SuccessRate * 100
+		priorityBonus := priorityBonus(t.Priority)
+
+		scores[i] = newSchedulingScore(emp.ID, []FactorScore{
+			newFactorScore("skill_match", skillMatch, s.weights.SkillMatch, fmt.Sprintf("技能匹配度: %.0f%%", skillMatch)),
+			newFactorScore("load", loadScore, s.weights.Load, fmt.Sprintf("负载评分: %.1f (%d/%d)", loadScore, c.ActiveTasks, c.Capacity)),
+			newFactorScore("success_rate", successScore, s.weights.SuccessRate, fmt.Sprintf("成功率: %.0f%%", successScore)),
+			newFactorScore("priority", priorityBonus, s.weights.Priority, fmt.Sprintf("优先级加成: %.0f", priorityBonus)),
+		})
+	}
+
+	return scores
+}
+
+// LeastLoadedStrategy prefers the employee with the largest free share of capacity
+type LeastLoadedStrategy struct{}
+
+// Name returns the strategy name
+func (LeastLoadedStrategy) Name() string {
+	return StrategyLeastLoaded
+}
+
+// Score scores candidates by free capacity
+func (LeastLoadedStrategy) Score(_ context.Context, _ *task.Task, candidates []Candidate) []SchedulingScore {
+	scores := make([]SchedulingScore, len(candidates))
+	for i, c := range candidates {
+		load := loadScore(c.ActiveTasks, c.Capacity)
+		scores[i] = newSchedulingScore(c.Employee.ID, []FactorScore{
+			newFactorScore("load", load, 1, fmt.Sprintf("负载评分: %.1f (%d/%d)", load, c.ActiveTasks, c.Capacity)),
+		})
+	}
+	return scores
+}
+
+// BestSuccessRateStrategy prefers the employee with the best track record
+type BestSuccessRateStrategy struct{}
+
+// Name returns the strategy name
+func (BestSuccessRateStrategy) Name() string {
+	return StrategyBestSuccessRate
+}
+
+// Score scores candidates by success rate, using load as a small tie breaker
+func (BestSuccessRateStrategy) Score(_ context.Context, _ *task.Task, candidates []Candidate) []SchedulingScore {
+	scores := make([]SchedulingScore, len(candidates))
+	for i, c := range candidates {
+		success := c.Employee.SuccessRate * 100
+		load := loadScore(c.ActiveTasks, c.Capacity)
+		scores[i] = newSchedulingScore(c.Employee.ID, []FactorScore{
+			newFactorScore("success_rate", success, 0.99, fmt.Sprintf("成功率: %.0f%%", success)),
+			newFactorScore("load", load, 0.01, fmt.Sprintf("负载评分: %.1f (%d/%d)", load, c.ActiveTasks, c.Capacity)),
+		})
+	}
+	return scores
+}
+
+// modelCosts is the relative price per 1K tokens of known models
+var modelCosts = map[string]float64{
+	"gpt-4o-mini":       0.15,
+	"gpt-3.5-turbo":     0.5,
+	"claude-3-haiku":    0.25,
+	"deepseek-chat":     0.14,
+	"qwen-turbo":        0.05,
+	"gpt-4o":            2.5,
+	"claude-3-5-sonnet": 3.0,
+	"gpt-4-turbo":       10.0,
+	"gpt-4":             30.0,
+}
+
+// defaultModelCost is used for employees whose model is unknown
+const defaultModelCost = 5.0
+
+// CheapestModelStrategy prefers employees configured with cheaper AI models
+type CheapestModelStrategy struct{}
+
+// Name returns the strategy name
+func (CheapestModelStrategy) Name() string {
+	return StrategyCheapestModel
+}
+
+// Score scores candidates inversely to the cost of their configured model
+func (CheapestModelStrategy) Score(_ context.Context, _ *task.Task, candidates []Candidate) []SchedulingScore {
+	scores := make([]SchedulingScore, len(candidates))
+	for i, c := range candidates {
+		model := employeeModel(c.Employee)
+		cost, ok := modelCosts[model]
+		if !ok {
+			cost = defaultModelCost
+		}
+		costScore := 100.0 / (1.0 + cost)
+		load := loadScore(c.ActiveTasks, c.Capacity)
+		scores[i] = newSchedulingScore(c.Employee.ID, []FactorScore{
+			newFactorScore("model_cost", costScore, 0.99, fmt.Sprintf("模型: %s (成本 %.2f/1K tokens)", model, cost)),
+			newFactorScore("load", load, 0.01, fmt.Sprintf("负载评分: %.1f (%d/%d)", load, c.ActiveTasks, c.Capacity)),
+		})
+	}
+	return scores
+}
+
+// RoundRobinStrategy rotates assignments through employees in a stable order.
+// The rotation continues from the company's latest recorded assignment, read
+// in the scheduling transaction, so every scheduler instance shares it.
+type RoundRobinStrategy struct {
+	history AssignmentHistory
+}
+
+// NewRoundRobinStrategy creates a round-robin strategy
+func NewRoundRobinStrategy(history AssignmentHistory) *RoundRobinStrategy {
+	return &RoundRobinStrategy{history: history}
+}
+
+// Name returns the strategy name
+func (s *RoundRobinStrategy) Name() string {
+	return StrategyRoundRobin
+}
+
+// Score gives the employee after the last assigned one the highest score
+func (s *RoundRobinStrategy) Score(ctx context.Context, t *task.Task, candidates []Candidate) []SchedulingScore {
+	last, hasLast := s.lastAssigned(ctx, t.CompanyID)
+
+	ordered := make([]uuid.UUID, len(candidates))
+	for i, c := range candidates {
+		ordered[i] = c.Employee.ID
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return ordered[i].String() < ordered[j].String()
+	})
+
+	// Find the first employee after the last assigned one
+	start := 0
+	if hasLast {
+		for i, id := range ordered {
+			if id.String() > last.String() {
+				start = i
+				break
+			}
+		}
+	}
+
+	position := make(map[uuid.UUID]int, len(ordered))
+	for i := range ordered {
+		position[ordered[(start+i)%len(ordered)]] = i
+	}
+
+	scores := make([]SchedulingScore, len(candidates))
+	for i, c := range candidates {
+		turn := 100.0 * float64(len(ordered)-position[c.Employee.ID]) / float64(len(ordered))
+		scores[i] = newSchedulingScore(c.Employee.ID, []FactorScore{
+			newFactorScore("turn", turn, 1, fmt.Sprintf("轮转顺位: %d", position[c.Employee.ID]+1)),
+		})
+	}
+	return scores
+}
+
+// lastAssigned returns the employee that last received work in a company
+func (s *RoundRobinStrategy) lastAssigned(ctx context.Context, companyID uuid.UUID) (uuid.UUID, bool) {
+	if s.history == nil {
+		return uuid.Nil, false
+	}
+
+	a, err := s.history.GetLatestAssignmentByCompany(ctx, companyID)
+	if err != nil {
+		if !errors.IsNotFound(err) {
+			logger.Warn(fmt.Sprintf("Failed to load the latest assignment of company %s: %v", companyID, err))
+		}
+		return uuid.Nil, false
+	}
+	return a.EmployeeID, true
+}
+
+// SchedulingSettings is the "scheduling" section of company settings
+type SchedulingSettings struct {
+	Strategy  string            `json:"strategy"`
+	Weights   *Weights          `json:"weights,omitempty"`
+	TaskTypes map[string]string `json:"task_types,omitempty"` // task type -> strategy name
+}
+
+// schedulingSettingsOf reads the scheduling settings of a company
+func schedulingSettingsOf(c *company.Company) SchedulingSettings {
+	var settings SchedulingSettings
+	if c == nil || c.Settings == nil {
+		return settings
+	}
+
+	raw, ok := c.Settings["scheduling"]
+	if !ok {
+		return settings
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return settings
+	}
+	_ = json.Unmarshal(data, &settings)
+	return settings
+}
+
+// newSchedulingScore sums factor contributions into a scheduling score
+func newSchedulingScore(employeeID uuid.UUID, factors []FactorScore) SchedulingScore {
+	score := 0.0
+	reasons := make([]string, 0, len(factors)+1)
+	for _, f := range factors {
+		score += f.Weighted
+		reasons = append(reasons, f.Detail)
+	}
+	reasons = append(reasons, fmt.Sprintf("总分: %.1f", score))
+
+	return SchedulingScore{
+		EmployeeID: employeeID,
+		Score:      score,
+		Reason:     strings.Join(reasons, "; "),
+		Factors:    factors,
+	}
+}
+
+// newFactorScore builds a factor score with its weighted contribution
+func newFactorScore(name string, score, weight float64, detail string) FactorScore {
+	return FactorScore{
+		Name:     name,
+		Score:    score,
+		Weight:   weight,
+		Weighted: score * weight,
+		Detail:   detail,
+	}
+}
+
+// loadScore scores the free share of an employee's capacity (0-100)
+func loadScore(active, capacity int) float64 {
+	if capacity <= 0 || active >= capacity {
+		return 0
+	}
+	return 100.0 * float64(capacity-active) / float64(capacity)
+}
+
+// priorityBonus returns a numeric bonus based on task priority
+func priorityBonus(priority task.TaskPriority) float64 {
+	switch priority {
+	case task.PriorityUrgent:
+		return 100.0
+	case task.PriorityHigh:
+		return 75.0
+	case task.PriorityMedium:
+		return 50.0
+	case task.PriorityLow:
+		return 25.0
+	default:
+		return 50.0
+	}
+}
+
+// skillMatchScore checks if employee has required skills
+func skillMatchScore(c Candidate) float64 {
+	if !c.SkillsKnown {
+		return 50.0 // Default score if can't get skills
+	}
+
+	if len(c.Skills) == 0 {
+		return 30.0 // Lower score for employees without skills
+	}
+
+	// If task doesn't require specific skills, give base score
+	return 80.0
+}
+
+// employeeModel returns the AI model configured in employee settings
+func employeeModel(emp *employee.Employee) string {
+	var settings struct {
+		Model string `json:"model"`
+	}
+	if len(emp.Settings) == 0 || json.Unmarshal(emp.Settings, &settings) != nil {
+		return ""
+	}
+	return settings.Model
+}
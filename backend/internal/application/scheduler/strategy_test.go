@@ -0,0 +1,198 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"unlimited-corp/internal/domain/company"
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCompanyRepository is an in-memory company.Repository for scheduler tests
+type fakeCompanyRepository struct {
+	companies map[uuid.UUID]*company.Company
+}
+
+func newFakeCompanyRepository(companies ...*company.Company) *fakeCompanyRepository {
+	repo := &fakeCompanyRepository{companies: make(map[uuid.UUID]*company.Company)}
+	for _, c := range companies {
+		repo.companies[c.ID] = c
+	}
+	return repo
+}
+
+func (r *fakeCompanyRepository) Create(_ context.Context, c *company.Company) error {
+	r.companies[c.ID] = c
+	return nil
+}
+
+func (r *fakeCompanyRepository) GetByID(_ context.Context, id uuid.UUID) (*company.Company, error) {
+	if c, ok := r.companies[id]; ok {
+		return c, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeCompanyRepository) GetByUserID(_ context.Context, userID uuid.UUID) (*company.Company, error) {
+	for _, c := range r.companies {
+		if c.UserID == userID {
+			return c, nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeCompanyRepository) Update(_ context.Context, c *company.Company) error {
+	r.companies[c.ID] = c
+	return nil
+}
+
+func (r *fakeCompanyRepository) Delete(_ context.Context, id uuid.UUID) error {
+	delete(r.companies, id)
+	return nil
+}
+
+func candidatesOf(employees ...*employee.Employee) []Candidate {
+	candidates := make([]Candidate, len(employees))
+	for i, e := range employees {
+		candidates[i] = Candidate{Employee: e, Capacity: 1}
+	}
+	return candidates
+}
+
+func TestWeights_Normalize(t *testing.T) {
+	w := Weights{SkillMatch: 2, Load: 1, SuccessRate: 1, Priority: 0}.normalize()
+	assert.InDelta(t, 0.5, w.SkillMatch, 1e-9)
+	assert.InDelta(t, 0.25, w.Load, 1e-9)
+	assert.InDelta(t, 0.25, w.SuccessRate, 1e-9)
+	assert.InDelta(t, 0.0, w.Priority, 1e-9)
+
+	assert.Equal(t, DefaultWeights(), Weights{}.normalize())
+	assert.Equal(t, DefaultWeights(), Weights{SkillMatch: -1, Load: 2}.normalize())
+}
+
+func TestLeastLoadedStrategy_Score(t *testing.T) {
+	companyID := uuid.New()
+	busy := employee.NewEmployee(companyID, "Busy", "Writer")
+	free := employee.NewEmployee(companyID, "Free", "Writer")
+
+	candidates := []Candidate{
+		{Employee: busy, ActiveTasks: 2, Capacity: 3},
+		{Employee: free, ActiveTasks: 0, Capacity: 3},
+	}
+	scores := LeastLoadedStrategy{}.Score(context.Background(), task.NewTask(companyID, "T", "", task.PriorityLow), candidates)
+
+	require.Len(t, scores, 2)
+	assert.Greater(t, scores[1].Score, scores[0].Score)
+}
+
+func TestBestSuccessRateStrategy_Score(t *testing.T) {
+	companyID := uuid.New()
+	rookie := employee.NewEmployee(companyID, "Rookie", "Writer")
+	rookie.SuccessRate = 0.5
+	veteran := employee.NewEmployee(companyID, "Veteran", "Writer")
+	veteran.SuccessRate = 0.95
+
+	scores := BestSuccessRateStrategy{}.Score(context.Background(), task.NewTask(companyID, "T", "", task.PriorityLow), candidatesOf(rookie, veteran))
+
+	require.Len(t, scores, 2)
+	assert.Greater(t, scores[1].Score, scores[0].Score)
+}
+
+func TestCheapestModelStrategy_Score(t *testing.T) {
+	companyID := uuid.New()
+	premium := employee.NewEmployee(companyID, "Premium", "Writer")
+	premium.Settings = []byte(`{"model": "gpt-4"}`)
+	budget := employee.NewEmployee(companyID, "Budget", "Writer")
+	budget.Settings = []byte(`{"model": "gpt-4o-mini"}`)
+	unknown := employee.NewEmployee(companyID, "Unknown", "Writer")
+
+	scores := CheapestModelStrategy{}.Score(context.Background(), task.NewTask(companyID, "T", "", task.PriorityLow), candidatesOf(premium, budget, unknown))
+
+	require.Len(t, scores, 3)
+	assert.Greater(t, scores[1].Score, scores[2].Score)
+	assert.Greater(t, scores[2].Score, scores[0].Score)
+}
+
+func TestRoundRobinStrategy_Rotates(t *testing.T) {
+	companyID := uuid.New()
+	first := employee.NewEmployee(companyID, "A", "Writer")
+	second := employee.NewEmployee(companyID, "B", "Writer")
+	candidates := candidatesOf(first, second)
+	tk := task.NewTask(companyID, "T", "", task.PriorityLow)
+
+	history := newFakeEmployeeRepository(first, second)
+	s := NewRoundRobinStrategy(history)
+	pick := func() uuid.UUID {
+		best := s.Score(context.Background(), tk, candidates)
+		winner := best[0]
+		for _, sc := range best[1:] {
+			if sc.Score > winner.Score {
+				winner = sc
+			}
+		}
+		require.NoError(t, history.CreateAssignment(context.Background(), employee.NewAssignment(companyID, winner.EmployeeID, tk.ID)))
+		return winner.EmployeeID
+	}
+
+	a, b, c := pick(), pick(), pick()
+	assert.NotEqual(t, a, b)
+	assert.Equal(t, a, c)
+}
+
+func TestTaskScheduler_ResolveStrategy(t *testing.T) {
+	c := company.NewCompany(uuid.New(), "Acme", "")
+	c.Settings["scheduling"] = map[string]interface{}{
+		"strategy": StrategyLeastLoaded,
+		"task_types": map[string]interface{}{
+			"content_creation": StrategyCheapestModel,
+		},
+	}
+	taskRepo := newFakeTaskRepository()
+	s := NewTaskScheduler(taskRepo, taskRepo, newFakeEmployeeRepository(), nil, newFakeCompanyRepository(c), nil)
+
+	plain := task.NewTask(c.ID, "Plain", "", task.PriorityMedium)
+	assert.Equal(t, StrategyLeastLoaded, s.resolveStrategy(c, plain).Name())
+
+	typed := task.NewTask(c.ID, "Typed", "", task.PriorityMedium)
+	typed.WorkflowDefinition = map[string]interface{}{"type": "content_creation"}
+	assert.Equal(t, StrategyCheapestModel, s.resolveStrategy(c, typed).Name())
+
+	assert.Equal(t, StrategyWeighted, s.resolveStrategy(nil, plain).Name())
+
+	c.Settings["scheduling"] = map[string]interface{}{"strategy": "does_not_exist"}
+	assert.Equal(t, StrategyWeighted, s.resolveStrategy(c, plain).Name())
+}
+
+func TestTaskScheduler_Schedule_UsesCompanyWeights(t *testing.T) {
+	c := company.NewCompany(uuid.New(), "Acme", "")
+	c.Settings["scheduling"] = map[string]interface{}{
+		"weights": map[string]interface{}{"success_rate": 1},
+	}
+
+	rookie := employee.NewEmployee(c.ID, "Rookie", "Writer")
+	rookie.SuccessRate = 0.4
+	veteran := employee.NewEmployee(c.ID, "Veteran", "Writer")
+	veteran.SuccessRate = 0.9
+
+	newTask := task.NewTask(c.ID, "Write", "", task.PriorityMedium)
+	taskRepo := newFakeTaskRepository(newTask)
+	s := NewTaskScheduler(taskRepo, taskRepo, newFakeEmployeeRepository(rookie, veteran), nil, newFakeCompanyRepository(c), nil)
+
+	decision, err := s.Preview(context.Background(), newTask)
+	require.NoError(t, err)
+	assert.Equal(t, StrategyWeighted, decision.Strategy)
+	require.NotNil(t, decision.SelectedEmployeeID)
+	assert.Equal(t, veteran.ID, *decision.SelectedEmployeeID)
+	for _, f := range decision.Candidates[0].Factors {
+		if f.Name != "success_rate" {
+			assert.Zero(t, f.Weight)
+		}
+	}
+}
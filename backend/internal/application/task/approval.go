@@ -0,0 +1,115 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+)
+
+// DecideApprovalInput is a reviewer's decision on a task approval
+type DecideApprovalInput struct {
+	// NodeID picks the approval; it can be omitted while only one is pending
+	NodeID  string                 `json:"node_id"`
+	Action  task.ApprovalAction    `json:"action" binding:"required,oneof=approve reject edit"`
+	Content map[string]interface{} `json:"content"`
+	Comment string                 `json:"comment"`
+}
+
+// SetApprovalRepository sets the repository the workflows record approvals in
+func (s *Service) SetApprovalRepository(approvals task.ApprovalRepository) {
+	s.approvals = approvals
+}
+
+// ListApprovals lists the approvals of a task of the company, pending and decided
+func (s *Service) ListApprovals(ctx context.Context, companyID, taskID uuid.UUID) ([]*task.Approval, error) {
+	if _, err := s.companyTask(ctx, companyID, taskID); err != nil {
+		return nil, err
+	}
+	if s.approvals == nil {
+		return []*task.Approval{}, nil
+	}
+	approvals, err := s.approvals.ListApprovals(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if approvals == nil {
+		approvals = []*task.Approval{}
+	}
+	return approvals, nil
+}
+
+// DecideApproval hands a reviewer's decision to the approval the workflow of
+// a task of the company waits for. The workflow records the decision once it
+// accepts it, so the returned approval shows the decision as submitted.
+func (s *Service) DecideApproval(ctx context.Context, companyID, taskID, reviewerID uuid.UUID, input *DecideApprovalInput) (*task.Approval, error) {
+	if !input.Action.IsValid() {
+		return nil, errors.New(http.StatusBadRequest, fmt.Sprintf("unknown approval action %q", input.Action))
+	}
+	if input.Action == task.ApprovalEdit && len(input.Content) == 0 {
+		return nil, errors.New(http.StatusBadRequest, "an edit decision needs the edited content")
+	}
+
+	t, err := s.companyTask(ctx, companyID, taskID)
+	if err != nil {
+		return nil, err
+	}
+	approval, err := s.pendingApproval(ctx, taskID, input.NodeID)
+	if err != nil {
+		return nil, err
+	}
+	if s.engine == nil || !t.HasWorkflow() {
+		return nil, errors.New(http.StatusConflict, "task has no workflow waiting for approval")
+	}
+
+	err = s.engine.Decide(ctx, t, task.ApprovalDecision{
+		NodeID:    approval.NodeID,
+		Action:    input.Action,
+		Content:   input.Content,
+		DecidedBy: reviewerID,
+		Comment:   input.Comment,
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to send approval to task workflow")
+	}
+
+	approval.Decide(input.Action, &reviewerID, input.Content, input.Comment)
+	return approval, nil
+}
+
+// pendingApproval returns the pending approval of a node, or the only
+// pending approval of the task when no node is given
+func (s *Service) pendingApproval(ctx context.Context, taskID uuid.UUID, nodeID string) (*task.Approval, error) {
+	if s.approvals == nil {
+		return nil, errors.New(http.StatusNotFound, "no approval is pending")
+	}
+	if nodeID != "" {
+		approval, err := s.approvals.GetPendingApproval(ctx, taskID, nodeID)
+		if errors.IsNotFound(err) {
+			return nil, errors.New(http.StatusNotFound, fmt.Sprintf("no approval is pending for node %s", nodeID))
+		}
+		return approval, err
+	}
+
+	approvals, err := s.approvals.ListApprovals(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	var pending []*task.Approval
+	for _, a := range approvals {
+		if a.IsPending() {
+			pending = append(pending, a)
+		}
+	}
+	switch len(pending) {
+	case 0:
+		return nil, errors.New(http.StatusNotFound, "no approval is pending")
+	case 1:
+		return pending[0], nil
+	default:
+		return nil, errors.New(http.StatusBadRequest, "several approvals are pending; node_id is required")
+	}
+}
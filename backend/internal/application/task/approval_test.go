@@ -0,0 +1,124 @@
+package task
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeApprovals stores approvals in memory
+type fakeApprovals struct {
+	task.ApprovalRepository
+	approvals []*task.Approval
+}
+
+func (r *fakeApprovals) GetPendingApproval(_ context.Context, taskID uuid.UUID, nodeID string) (*task.Approval, error) {
+	for _, a := range r.approvals {
+		if a.TaskID == taskID && a.NodeID == nodeID && a.IsPending() {
+			copied := *a
+			return &copied, nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeApprovals) ListApprovals(_ context.Context, taskID uuid.UUID) ([]*task.Approval, error) {
+	var approvals []*task.Approval
+	for _, a := range r.approvals {
+		if a.TaskID == taskID {
+			approvals = append(approvals, a)
+		}
+	}
+	return approvals, nil
+}
+
+func newPendingApproval(t *task.Task, nodeID string) *task.Approval {
+	content := map[string]interface{}{"title": "Draft"}
+	return task.NewApproval(t.CompanyID, t.ID, nodeID, nodeID, content, task.ApprovalReject, time.Now().Add(time.Hour))
+}
+
+func TestService_DecideApproval(t *testing.T) {
+	ctx := context.Background()
+	reviewer := uuid.New()
+
+	t.Run("signals the decision to the waiting node", func(t *testing.T) {
+		running := newWorkflowTask(task.StatusRunning)
+		running.BindWorkflow("task-1", "run-1")
+		engine := &fakeEngine{}
+		s := newTestService(newFakeRepository(running), engine)
+		s.SetApprovalRepository(&fakeApprovals{approvals: []*task.Approval{newPendingApproval(running, "review")}})
+
+		approval, err := s.DecideApproval(ctx, running.CompanyID, running.ID, reviewer, &DecideApprovalInput{
+			Action:  task.ApprovalEdit,
+			Content: map[string]interface{}{"title": "Better"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, task.ApprovalEdited, approval.Status)
+		assert.Equal(t, &reviewer, approval.DecidedBy)
+
+		require.Len(t, engine.decisions, 1)
+		assert.Equal(t, "review", engine.decisions[0].NodeID)
+		assert.Equal(t, reviewer, engine.decisions[0].DecidedBy)
+	})
+
+	t.Run("needs the node while several approvals are pending", func(t *testing.T) {
+		running := newWorkflowTask(task.StatusRunning)
+		running.BindWorkflow("task-1", "run-1")
+		engine := &fakeEngine{}
+		s := newTestService(newFakeRepository(running), engine)
+		s.SetApprovalRepository(&fakeApprovals{approvals: []*task.Approval{
+			newPendingApproval(running, "legal"), newPendingApproval(running, "brand"),
+		}})
+
+		_, err := s.DecideApproval(ctx, running.CompanyID, running.ID, reviewer, &DecideApprovalInput{Action: task.ApprovalApprove})
+		assert.True(t, errors.IsBadRequest(err))
+
+		_, err = s.DecideApproval(ctx, running.CompanyID, running.ID, reviewer, &DecideApprovalInput{NodeID: "brand", Action: task.ApprovalReject})
+		require.NoError(t, err)
+		assert.Equal(t, "brand", engine.decisions[0].NodeID)
+	})
+
+	t.Run("rejects decisions nothing waits for", func(t *testing.T) {
+		running := newWorkflowTask(task.StatusRunning)
+		running.BindWorkflow("task-1", "run-1")
+		engine := &fakeEngine{}
+		s := newTestService(newFakeRepository(running), engine)
+		s.SetApprovalRepository(&fakeApprovals{})
+
+		_, err := s.DecideApproval(ctx, running.CompanyID, running.ID, reviewer, &DecideApprovalInput{Action: task.ApprovalApprove})
+		assert.True(t, errors.IsNotFound(err))
+
+		_, err = s.DecideApproval(ctx, running.CompanyID, running.ID, reviewer, &DecideApprovalInput{Action: task.ApprovalEdit})
+		assert.True(t, errors.IsBadRequest(err), "an edit needs content")
+		assert.Empty(t, engine.calls)
+	})
+
+	t.Run("other companies' approvals are not found", func(t *testing.T) {
+		running := newWorkflowTask(task.StatusRunning)
+		running.BindWorkflow("task-1", "run-1")
+		engine := &fakeEngine{}
+		s := newTestService(newFakeRepository(running), engine)
+		s.SetApprovalRepository(&fakeApprovals{approvals: []*task.Approval{newPendingApproval(running, "review")}})
+		other := uuid.New()
+
+		_, err := s.ListApprovals(ctx, other, running.ID)
+		assert.True(t, errors.IsNotFound(err))
+		_, err = s.DecideApproval(ctx, other, running.ID, reviewer, &DecideApprovalInput{
+			Action:  task.ApprovalEdit,
+			Content: map[string]interface{}{"title": "Hijacked"},
+		})
+		assert.True(t, errors.IsNotFound(err))
+		assert.Empty(t, engine.calls)
+
+		approvals, err := s.ListApprovals(ctx, running.CompanyID, running.ID)
+		require.NoError(t, err)
+		assert.Len(t, approvals, 1)
+	})
+}
@@ -0,0 +1,154 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+)
+
+// RetryInput retries a failed task. The new run reuses the outputs of the
+// steps that succeeded, except FromStep and the steps after it.
+type RetryInput struct {
+	// FromStep is the step to start over from; by default the step the task failed at
+	FromStep string `json:"from_step"`
+	// StepInput, when set, is merged over the input of that step
+	StepInput map[string]interface{} `json:"step_input"`
+}
+
+// SetAttemptRepository sets the repository keeping the earlier runs of retried tasks
+func (s *Service) SetAttemptRepository(attempts task.AttemptRepository) {
+	s.attempts = attempts
+}
+
+// Retry keeps the failed run of a task of the company as an attempt and puts
+// the task back into the queue. Once assigned, its workflow takes over the
+// outputs of the attempt's succeeded steps.
+func (s *Service) Retry(ctx context.Context, companyID, id, retriedBy uuid.UUID, input *RetryInput) (*task.Task, error) {
+	if s.attempts == nil {
+		return nil, errors.New(http.StatusConflict, "task retries are unavailable")
+	}
+	t, err := s.companyTask(ctx, companyID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !t.CanTransitionTo(task.StatusPending) {
+		return nil, errors.New(http.StatusConflict, fmt.Sprintf("task cannot be retried while %s", t.Status))
+	}
+
+	outcome := &task.RunOutcome{}
+	if t.HasWorkflow() {
+		if s.engine == nil {
+			return nil, errors.New(http.StatusConflict, "task cannot be retried: the workflow engine is not connected")
+		}
+		outcomeCtx, cancel := context.WithTimeout(ctx, workflowStartTimeout)
+		outcome, err = s.engine.Outcome(outcomeCtx, t)
+		cancel()
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to get the outcome of the task workflow")
+		}
+	}
+
+	previous, err := s.attempts.ListAttempts(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	attempt := task.NewAttempt(t, len(previous)+1, outcome)
+	if err := s.retryFrom(t, attempt, input); err != nil {
+		return nil, err
+	}
+	if retriedBy != uuid.Nil {
+		attempt.RetriedBy = &retriedBy
+	}
+
+	err = s.inTransaction(ctx, func(ctx context.Context) error {
+		if err := s.attempts.CreateAttempt(ctx, attempt); err != nil {
+			return err
+		}
+		t.Retry()
+		if err := s.repo.Update(ctx, t); err != nil {
+			return errors.Wrap(err, "failed to update task status")
+		}
+		if err := s.repo.SetWorkflowRun(ctx, t.ID, "", ""); err != nil {
+			return errors.Wrap(err, "failed to clear task workflow")
+		}
+		return s.publish(ctx, eventbus.EventTaskRetried, t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// retryFrom sets the step the next run starts over from and its edited input
+func (s *Service) retryFrom(t *task.Task, attempt *task.Attempt, input *RetryInput) error {
+	if input == nil || (input.FromStep == "" && input.StepInput == nil) {
+		return nil
+	}
+	if !workflow.Declared(t.WorkflowDefinition) {
+		return errors.New(http.StatusBadRequest, "task has no workflow steps to retry from")
+	}
+	def, err := workflow.Parse(t.WorkflowDefinition)
+	if err != nil {
+		return errors.WrapWithCode(err, http.StatusBadRequest, err.Error())
+	}
+
+	from := input.FromStep
+	if from == "" {
+		from = attempt.FailedStep
+	}
+	if from == "" {
+		return errors.New(http.StatusBadRequest, "from_step is required: the task did not fail at a step")
+	}
+	if _, ok := def.Node(from); !ok {
+		return errors.New(http.StatusBadRequest, fmt.Sprintf("unknown step %q", from))
+	}
+
+	attempt.RetryFrom = from
+	attempt.RetryInput = input.StepInput
+	return nil
+}
+
+// ListAttempts lists the earlier runs of a task of the company, oldest first
+func (s *Service) ListAttempts(ctx context.Context, companyID, taskID uuid.UUID) ([]*task.Attempt, error) {
+	if _, err := s.companyTask(ctx, companyID, taskID); err != nil {
+		return nil, err
+	}
+	if s.attempts == nil {
+		return []*task.Attempt{}, nil
+	}
+	attempts, err := s.attempts.ListAttempts(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if attempts == nil {
+		attempts = []*task.Attempt{}
+	}
+	return attempts, nil
+}
+
+// resume returns what the new run of a retried task takes over from its last
+// attempt, or nil when the task was never retried or its run already started
+func (s *Service) resume(ctx context.Context, t *task.Task, def *workflow.Definition) (*task.Resume, error) {
+	if s.attempts == nil || t.HasWorkflow() {
+		return nil, nil
+	}
+	attempt, err := s.attempts.GetLatestAttempt(ctx, t.ID)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rerun := map[string]bool{}
+	if attempt.RetryFrom != "" {
+		rerun = def.Downstream(attempt.RetryFrom)
+	}
+	return attempt.Resume(rerun), nil
+}
@@ -0,0 +1,193 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAttempts stores attempts in memory
+type fakeAttempts struct {
+	attempts []*task.Attempt
+}
+
+func (r *fakeAttempts) CreateAttempt(_ context.Context, a *task.Attempt) error {
+	r.attempts = append(r.attempts, a)
+	return nil
+}
+
+func (r *fakeAttempts) GetLatestAttempt(_ context.Context, taskID uuid.UUID) (*task.Attempt, error) {
+	for i := len(r.attempts) - 1; i >= 0; i-- {
+		if r.attempts[i].TaskID == taskID {
+			return r.attempts[i], nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeAttempts) ListAttempts(_ context.Context, taskID uuid.UUID) ([]*task.Attempt, error) {
+	var attempts []*task.Attempt
+	for _, a := range r.attempts {
+		if a.TaskID == taskID {
+			attempts = append(attempts, a)
+		}
+	}
+	return attempts, nil
+}
+
+// newFailedPipelineTask creates a task whose draft -> review -> publish workflow failed at publish
+func newFailedPipelineTask() *task.Task {
+	t := task.NewTask(uuid.New(), "Write a post", "", task.PriorityMedium)
+	t.WorkflowDefinition = map[string]interface{}{
+		"version": 1,
+		"nodes": []interface{}{
+			map[string]interface{}{"id": "draft", "type": "skill", "skill_card_id": uuid.New().String()},
+			map[string]interface{}{"id": "review", "type": "skill", "skill_card_id": uuid.New().String()},
+			map[string]interface{}{"id": "publish", "type": "skill", "skill_card_id": uuid.New().String()},
+		},
+		"edges": []interface{}{
+			map[string]interface{}{"from": "draft", "to": "review"},
+			map[string]interface{}{"from": "review", "to": "publish"},
+		},
+	}
+	t.Start(uuid.New())
+	t.BindWorkflow("task-"+t.ID.String(), "run-1")
+	t.Fail("node publish failed: provider unavailable")
+	return t
+}
+
+func failedAtPublish() *task.RunOutcome {
+	return &task.RunOutcome{
+		Outputs: map[string]map[string]interface{}{
+			"draft":  {"content": "text"},
+			"review": {"approved": true},
+		},
+		FailedStep: "publish",
+	}
+}
+
+func TestService_Retry(t *testing.T) {
+	ctx := context.Background()
+	user := uuid.New()
+
+	t.Run("requeues the task and resumes from the failed step", func(t *testing.T) {
+		failed := newFailedPipelineTask()
+		repo := newFakeRepository(failed)
+		engine := &fakeEngine{outcome: failedAtPublish()}
+		attempts := &fakeAttempts{}
+		s := newTestService(repo, engine)
+		s.SetAttemptRepository(attempts)
+
+		retried, err := s.Retry(ctx, failed.CompanyID, failed.ID, user, &RetryInput{StepInput: map[string]interface{}{"channel": "weibo"}})
+		require.NoError(t, err)
+		assert.Equal(t, task.StatusPending, retried.Status)
+		assert.Empty(t, retried.ErrorMessage)
+		assert.False(t, repo.tasks[failed.ID].HasWorkflow())
+		assert.Len(t, s.eventBus.GetHistoryByType(eventbus.EventTaskRetried, 10), 1)
+
+		require.Len(t, attempts.attempts, 1)
+		attempt := attempts.attempts[0]
+		assert.Equal(t, 1, attempt.Number)
+		assert.Equal(t, task.StatusFailed, attempt.Status)
+		assert.Equal(t, "run-1", attempt.RunID)
+		assert.Equal(t, "publish", attempt.RetryFrom)
+		assert.Equal(t, &user, attempt.RetriedBy)
+
+		// Once assigned, the new run takes over the succeeded steps
+		assigned := repo.tasks[failed.ID]
+		assigned.Start(uuid.New())
+		require.NoError(t, s.StartWorkflow(ctx, assigned))
+		require.Len(t, engine.resumes, 1)
+		resume := engine.resumes[0]
+		require.NotNil(t, resume)
+		assert.Len(t, resume.Outputs, 2)
+		assert.Equal(t, "weibo", resume.StepInputs["publish"]["channel"])
+	})
+
+	t.Run("starts over from an earlier step", func(t *testing.T) {
+		failed := newFailedPipelineTask()
+		repo := newFakeRepository(failed)
+		engine := &fakeEngine{outcome: failedAtPublish()}
+		s := newTestService(repo, engine)
+		s.SetAttemptRepository(&fakeAttempts{})
+
+		_, err := s.Retry(ctx, failed.CompanyID, failed.ID, user, &RetryInput{FromStep: "review"})
+		require.NoError(t, err)
+
+		assigned := repo.tasks[failed.ID]
+		assigned.Start(uuid.New())
+		require.NoError(t, s.StartWorkflow(ctx, assigned))
+		assert.Equal(t, map[string]map[string]interface{}{"draft": {"content": "text"}}, engine.resumes[0].Outputs)
+	})
+
+	t.Run("rejects an unknown step", func(t *testing.T) {
+		failed := newFailedPipelineTask()
+		attempts := &fakeAttempts{}
+		s := newTestService(newFakeRepository(failed), &fakeEngine{outcome: failedAtPublish()})
+		s.SetAttemptRepository(attempts)
+
+		_, err := s.Retry(ctx, failed.CompanyID, failed.ID, user, &RetryInput{FromStep: "translate"})
+		assert.True(t, errors.IsBadRequest(err))
+		assert.Empty(t, attempts.attempts)
+	})
+
+	t.Run("only failed tasks are retried", func(t *testing.T) {
+		running := newWorkflowTask(task.StatusRunning)
+		engine := &fakeEngine{}
+		s := newTestService(newFakeRepository(running), engine)
+		s.SetAttemptRepository(&fakeAttempts{})
+
+		_, err := s.Retry(ctx, running.CompanyID, running.ID, user, &RetryInput{})
+		assert.True(t, errors.IsConflict(err))
+		assert.Empty(t, engine.calls)
+	})
+
+	t.Run("numbers attempts", func(t *testing.T) {
+		failed := newFailedPipelineTask()
+		repo := newFakeRepository(failed)
+		engine := &fakeEngine{outcome: failedAtPublish()}
+		attempts := &fakeAttempts{}
+		s := newTestService(repo, engine)
+		s.SetAttemptRepository(attempts)
+
+		_, err := s.Retry(ctx, failed.CompanyID, failed.ID, user, nil)
+		require.NoError(t, err)
+		again := repo.tasks[failed.ID]
+		again.Start(uuid.New())
+		require.NoError(t, s.StartWorkflow(ctx, again))
+		again.Fail("node publish failed again")
+
+		_, err = s.Retry(ctx, failed.CompanyID, failed.ID, user, nil)
+		require.NoError(t, err)
+		listed, err := s.ListAttempts(ctx, failed.CompanyID, failed.ID)
+		require.NoError(t, err)
+		require.Len(t, listed, 2)
+		assert.Equal(t, 2, listed[1].Number)
+		assert.Equal(t, "node publish failed again", listed[1].Error)
+	})
+
+	t.Run("other companies' tasks are not found", func(t *testing.T) {
+		failed := newFailedPipelineTask()
+		repo := newFakeRepository(failed)
+		engine := &fakeEngine{outcome: failedAtPublish()}
+		attempts := &fakeAttempts{}
+		s := newTestService(repo, engine)
+		s.SetAttemptRepository(attempts)
+		other := uuid.New()
+
+		_, err := s.Retry(ctx, other, failed.ID, user, nil)
+		assert.True(t, errors.IsNotFound(err))
+		_, err = s.ListAttempts(ctx, other, failed.ID)
+		assert.True(t, errors.IsNotFound(err))
+		assert.Empty(t, engine.calls)
+		assert.Empty(t, attempts.attempts)
+		assert.Equal(t, task.StatusFailed, repo.tasks[failed.ID].Status)
+	})
+}
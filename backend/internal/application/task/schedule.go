@@ -0,0 +1,258 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+)
+
+// defaultScheduleRunLimit bounds the run history returned for a schedule
+const defaultScheduleRunLimit = 50
+
+// ScheduleEngine fires task schedules, starting a task each time one fires
+type ScheduleEngine interface {
+	Create(ctx context.Context, s *task.Schedule) error
+	Pause(ctx context.Context, s *task.Schedule) error
+	Resume(ctx context.Context, s *task.Schedule) error
+	// Trigger starts a task right away, even while the schedule is paused
+	Trigger(ctx context.Context, s *task.Schedule) error
+	Delete(ctx context.Context, s *task.Schedule) error
+	// NextRuns returns the upcoming times the schedule fires
+	NextRuns(ctx context.Context, s *task.Schedule) ([]time.Time, error)
+}
+
+// CreateScheduleInput creates a schedule: a one-off start with RunAt, or a
+// recurring one with a cron expression evaluated in Timezone
+type CreateScheduleInput struct {
+	CompanyID      uuid.UUID         `json:"-"`
+	CreatedBy      uuid.UUID         `json:"-"`
+	Name           string            `json:"name" binding:"required"`
+	CronExpression string            `json:"cron_expression"`
+	RunAt          *time.Time        `json:"run_at"`
+	Timezone       string            `json:"timezone"`
+	Task           TaskTemplateInput `json:"task" binding:"required"`
+}
+
+// TaskTemplateInput is the task a schedule starts each time it fires
+type TaskTemplateInput struct {
+	Title              string                 `json:"title" binding:"required"`
+	Description        string                 `json:"description"`
+	Priority           task.TaskPriority      `json:"priority" binding:"omitempty,oneof=low medium high urgent"`
+	WorkflowDefinition map[string]interface{} `json:"workflow_definition"`
+	InputData          map[string]interface{} `json:"input_data"`
+}
+
+// ScheduleView is a schedule with the upcoming times it fires
+type ScheduleView struct {
+	*task.Schedule
+	NextRuns []time.Time `json:"next_runs,omitempty"`
+}
+
+// SetScheduleRepository sets the repository storing task schedules
+func (s *Service) SetScheduleRepository(schedules task.ScheduleRepository) {
+	s.schedules = schedules
+}
+
+// SetScheduleEngine sets the engine firing task schedules. Without one
+// schedules cannot be created or changed.
+func (s *Service) SetScheduleEngine(engine ScheduleEngine) {
+	s.scheduleEngine = engine
+}
+
+// CreateSchedule validates and stores a schedule, then registers it with the engine
+func (s *Service) CreateSchedule(ctx context.Context, input *CreateScheduleInput) (*task.Schedule, error) {
+	if err := s.requireScheduling(); err != nil {
+		return nil, err
+	}
+
+	kind := task.ScheduleCron
+	if input.RunAt != nil {
+		kind = task.ScheduleOnce
+	}
+	sch := task.NewSchedule(input.CompanyID, input.Name, kind, task.TaskTemplate{
+		Title:              input.Task.Title,
+		Description:        input.Task.Description,
+		Priority:           input.Task.Priority,
+		WorkflowDefinition: input.Task.WorkflowDefinition,
+		InputData:          input.Task.InputData,
+	})
+	sch.CronExpression = input.CronExpression
+	sch.RunAt = input.RunAt
+	if input.Timezone != "" {
+		sch.Timezone = input.Timezone
+	}
+	if input.CreatedBy != uuid.Nil {
+		sch.CreatedBy = &input.CreatedBy
+	}
+
+	if err := sch.Validate(time.Now()); err != nil {
+		return nil, errors.WrapWithCode(err, http.StatusBadRequest, err.Error())
+	}
+	if workflow.Declared(sch.Template.WorkflowDefinition) {
+		def, err := workflow.Parse(sch.Template.WorkflowDefinition)
+		if err != nil {
+			return nil, errors.WrapWithCode(err, http.StatusBadRequest, err.Error())
+		}
+		sch.Template.WorkflowDefinition = def.ToMap()
+	}
+
+	if err := s.schedules.CreateSchedule(ctx, sch); err != nil {
+		return nil, err
+	}
+	if err := s.scheduleEngine.Create(ctx, sch); err != nil {
+		if delErr := s.schedules.DeleteSchedule(ctx, sch.ID); delErr != nil {
+			logger.Warn(fmt.Sprintf("Failed to remove schedule %s after registration failed: %v", sch.ID, delErr))
+		}
+		return nil, errors.Wrap(err, "failed to register task schedule")
+	}
+	return sch, nil
+}
+
+// ListSchedules lists the schedules of a company
+func (s *Service) ListSchedules(ctx context.Context, companyID uuid.UUID) ([]*task.Schedule, error) {
+	if s.schedules == nil {
+		return []*task.Schedule{}, nil
+	}
+	schedules, err := s.schedules.ListSchedules(ctx, companyID)
+	if err != nil {
+		return nil, err
+	}
+	if schedules == nil {
+		schedules = []*task.Schedule{}
+	}
+	return schedules, nil
+}
+
+// GetSchedule returns a company's schedule with the upcoming times it fires
+func (s *Service) GetSchedule(ctx context.Context, companyID, id uuid.UUID) (*ScheduleView, error) {
+	sch, err := s.companySchedule(ctx, companyID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	view := &ScheduleView{Schedule: sch}
+	if s.scheduleEngine != nil && sch.Status == task.ScheduleActive {
+		// The upcoming runs are best effort; the stored schedule is shown regardless
+		if view.NextRuns, err = s.scheduleEngine.NextRuns(ctx, sch); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to get next runs of schedule %s: %v", sch.ID, err))
+		}
+	}
+	return view, nil
+}
+
+// PauseSchedule stops a schedule from firing until it is resumed
+func (s *Service) PauseSchedule(ctx context.Context, companyID, id uuid.UUID) (*task.Schedule, error) {
+	return s.changeSchedule(ctx, companyID, id, task.ScheduleActive, ScheduleEngine.Pause, (*task.Schedule).Pause)
+}
+
+// ResumeSchedule lets a paused schedule fire again
+func (s *Service) ResumeSchedule(ctx context.Context, companyID, id uuid.UUID) (*task.Schedule, error) {
+	return s.changeSchedule(ctx, companyID, id, task.SchedulePaused, ScheduleEngine.Resume, (*task.Schedule).Resume)
+}
+
+// changeSchedule tells the engine about a pause or resume before storing it
+func (s *Service) changeSchedule(
+	ctx context.Context,
+	companyID, id uuid.UUID,
+	from task.ScheduleStatus,
+	change func(ScheduleEngine, context.Context, *task.Schedule) error,
+	apply func(*task.Schedule),
+) (*task.Schedule, error) {
+	if err := s.requireScheduling(); err != nil {
+		return nil, err
+	}
+	sch, err := s.companySchedule(ctx, companyID, id)
+	if err != nil {
+		return nil, err
+	}
+	if sch.Status != from {
+		return nil, errors.New(http.StatusConflict, fmt.Sprintf("schedule is %s", sch.Status))
+	}
+
+	if err := change(s.scheduleEngine, ctx, sch); err != nil {
+		return nil, errors.Wrap(err, "failed to change task schedule")
+	}
+	apply(sch)
+	if err := s.schedules.UpdateSchedule(ctx, sch); err != nil {
+		return nil, err
+	}
+	return sch, nil
+}
+
+// TriggerSchedule starts the schedule's task right away
+func (s *Service) TriggerSchedule(ctx context.Context, companyID, id uuid.UUID) error {
+	if err := s.requireScheduling(); err != nil {
+		return err
+	}
+	sch, err := s.companySchedule(ctx, companyID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.scheduleEngine.Trigger(ctx, sch); err != nil {
+		return errors.Wrap(err, "failed to trigger task schedule")
+	}
+	return nil
+}
+
+// DeleteSchedule removes a schedule and its run history; tasks it started are kept
+func (s *Service) DeleteSchedule(ctx context.Context, companyID, id uuid.UUID) error {
+	if err := s.requireScheduling(); err != nil {
+		return err
+	}
+	sch, err := s.companySchedule(ctx, companyID, id)
+	if err != nil {
+		return err
+	}
+	if err := s.scheduleEngine.Delete(ctx, sch); err != nil {
+		return errors.Wrap(err, "failed to delete task schedule")
+	}
+	return s.schedules.DeleteSchedule(ctx, sch.ID)
+}
+
+// ListScheduleRuns lists the tasks a schedule started, newest first
+func (s *Service) ListScheduleRuns(ctx context.Context, companyID, id uuid.UUID, limit int) ([]*task.ScheduleRun, error) {
+	if _, err := s.companySchedule(ctx, companyID, id); err != nil {
+		return nil, err
+	}
+	if limit <= 0 || limit > defaultScheduleRunLimit {
+		limit = defaultScheduleRunLimit
+	}
+	runs, err := s.schedules.ListScheduleRuns(ctx, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	if runs == nil {
+		runs = []*task.ScheduleRun{}
+	}
+	return runs, nil
+}
+
+// companySchedule returns a schedule of the company; other companies' schedules are not found
+func (s *Service) companySchedule(ctx context.Context, companyID, id uuid.UUID) (*task.Schedule, error) {
+	if s.schedules == nil {
+		return nil, errors.ErrNotFound
+	}
+	sch, err := s.schedules.GetSchedule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if sch.CompanyID != companyID {
+		return nil, errors.ErrNotFound
+	}
+	return sch, nil
+}
+
+// requireScheduling fails when schedules cannot be registered
+func (s *Service) requireScheduling() error {
+	if s.schedules == nil || s.scheduleEngine == nil {
+		return errors.New(http.StatusConflict, "task scheduling is unavailable: the workflow engine is not connected")
+	}
+	return nil
+}
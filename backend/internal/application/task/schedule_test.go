@@ -0,0 +1,215 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSchedules stores schedules in memory
+type fakeSchedules struct {
+	task.ScheduleRepository
+	schedules map[uuid.UUID]*task.Schedule
+}
+
+func newFakeSchedules(schedules ...*task.Schedule) *fakeSchedules {
+	repo := &fakeSchedules{schedules: make(map[uuid.UUID]*task.Schedule)}
+	for _, s := range schedules {
+		repo.schedules[s.ID] = s
+	}
+	return repo
+}
+
+func (r *fakeSchedules) CreateSchedule(_ context.Context, s *task.Schedule) error {
+	r.schedules[s.ID] = s
+	return nil
+}
+
+func (r *fakeSchedules) GetSchedule(_ context.Context, id uuid.UUID) (*task.Schedule, error) {
+	if s, ok := r.schedules[id]; ok {
+		copied := *s
+		return &copied, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeSchedules) UpdateSchedule(_ context.Context, s *task.Schedule) error {
+	r.schedules[s.ID] = s
+	return nil
+}
+
+func (r *fakeSchedules) DeleteSchedule(_ context.Context, id uuid.UUID) error {
+	delete(r.schedules, id)
+	return nil
+}
+
+// fakeScheduleEngine records the schedule operations it is asked for
+type fakeScheduleEngine struct {
+	calls []string
+	err   error
+}
+
+func (e *fakeScheduleEngine) record(call string) error {
+	e.calls = append(e.calls, call)
+	return e.err
+}
+
+func (e *fakeScheduleEngine) Create(context.Context, *task.Schedule) error {
+	return e.record("create")
+}
+
+func (e *fakeScheduleEngine) Pause(context.Context, *task.Schedule) error {
+	return e.record("pause")
+}
+
+func (e *fakeScheduleEngine) Resume(context.Context, *task.Schedule) error {
+	return e.record("resume")
+}
+
+func (e *fakeScheduleEngine) Trigger(context.Context, *task.Schedule) error {
+	return e.record("trigger")
+}
+
+func (e *fakeScheduleEngine) Delete(context.Context, *task.Schedule) error {
+	return e.record("delete")
+}
+
+func (e *fakeScheduleEngine) NextRuns(context.Context, *task.Schedule) ([]time.Time, error) {
+	return []time.Time{time.Now().Add(time.Hour)}, e.record("next_runs")
+}
+
+func newScheduleService(repo *fakeSchedules, engine ScheduleEngine) *Service {
+	s := newTestService(newFakeRepository(), &fakeEngine{})
+	s.SetScheduleRepository(repo)
+	s.SetScheduleEngine(engine)
+	return s
+}
+
+func TestService_CreateSchedule(t *testing.T) {
+	ctx := context.Background()
+	companyID := uuid.New()
+
+	t.Run("registers a cron schedule", func(t *testing.T) {
+		repo, engine := newFakeSchedules(), &fakeScheduleEngine{}
+		s := newScheduleService(repo, engine)
+
+		sch, err := s.CreateSchedule(ctx, &CreateScheduleInput{
+			CompanyID:      companyID,
+			Name:           "Morning hotspots",
+			CronExpression: "0 8 * * *",
+			Timezone:       "Asia/Shanghai",
+			Task:           TaskTemplateInput{Title: "Track hotspots"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, task.ScheduleCron, sch.Kind)
+		assert.Equal(t, task.PriorityMedium, sch.Template.Priority)
+		assert.Contains(t, repo.schedules, sch.ID)
+		assert.Equal(t, []string{"create"}, engine.calls)
+	})
+
+	t.Run("a run_at makes a one-off schedule", func(t *testing.T) {
+		repo, engine := newFakeSchedules(), &fakeScheduleEngine{}
+		s := newScheduleService(repo, engine)
+		runAt := time.Now().Add(time.Hour)
+
+		sch, err := s.CreateSchedule(ctx, &CreateScheduleInput{
+			CompanyID: companyID,
+			Name:      "Friday post",
+			RunAt:     &runAt,
+			Task:      TaskTemplateInput{Title: "Publish"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, task.ScheduleOnce, sch.Kind)
+	})
+
+	t.Run("rejects an invalid schedule", func(t *testing.T) {
+		repo, engine := newFakeSchedules(), &fakeScheduleEngine{}
+		s := newScheduleService(repo, engine)
+
+		_, err := s.CreateSchedule(ctx, &CreateScheduleInput{
+			CompanyID:      companyID,
+			Name:           "Broken",
+			CronExpression: "whenever",
+			Task:           TaskTemplateInput{Title: "Track hotspots"},
+		})
+		assert.True(t, errors.IsBadRequest(err))
+		assert.Empty(t, repo.schedules)
+		assert.Empty(t, engine.calls)
+	})
+
+	t.Run("removes the schedule when registration fails", func(t *testing.T) {
+		repo, engine := newFakeSchedules(), &fakeScheduleEngine{err: fmt.Errorf("temporal unavailable")}
+		s := newScheduleService(repo, engine)
+
+		_, err := s.CreateSchedule(ctx, &CreateScheduleInput{
+			CompanyID:      companyID,
+			Name:           "Morning hotspots",
+			CronExpression: "0 8 * * *",
+			Task:           TaskTemplateInput{Title: "Track hotspots"},
+		})
+		assert.Error(t, err)
+		assert.Empty(t, repo.schedules)
+	})
+
+	t.Run("needs the engine", func(t *testing.T) {
+		s := newTestService(newFakeRepository(), &fakeEngine{})
+		s.SetScheduleRepository(newFakeSchedules())
+
+		_, err := s.CreateSchedule(ctx, &CreateScheduleInput{Name: "x", CronExpression: "0 8 * * *", Task: TaskTemplateInput{Title: "x"}})
+		assert.True(t, errors.IsConflict(err))
+	})
+}
+
+func TestService_PauseResumeSchedule(t *testing.T) {
+	ctx := context.Background()
+	companyID := uuid.New()
+	sch := task.NewSchedule(companyID, "Morning hotspots", task.ScheduleCron, task.TaskTemplate{Title: "Track hotspots"})
+	sch.CronExpression = "0 8 * * *"
+	repo, engine := newFakeSchedules(sch), &fakeScheduleEngine{}
+	s := newScheduleService(repo, engine)
+
+	paused, err := s.PauseSchedule(ctx, companyID, sch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, task.SchedulePaused, paused.Status)
+	assert.Equal(t, task.SchedulePaused, repo.schedules[sch.ID].Status)
+
+	_, err = s.PauseSchedule(ctx, companyID, sch.ID)
+	assert.True(t, errors.IsConflict(err), "a paused schedule cannot be paused again")
+
+	resumed, err := s.ResumeSchedule(ctx, companyID, sch.ID)
+	require.NoError(t, err)
+	assert.Equal(t, task.ScheduleActive, resumed.Status)
+	assert.Equal(t, []string{"pause", "resume"}, engine.calls)
+}
+
+func TestService_ScheduleBelongsToCompany(t *testing.T) {
+	ctx := context.Background()
+	sch := task.NewSchedule(uuid.New(), "Morning hotspots", task.ScheduleCron, task.TaskTemplate{Title: "Track hotspots"})
+	repo, engine := newFakeSchedules(sch), &fakeScheduleEngine{}
+	s := newScheduleService(repo, engine)
+	other := uuid.New()
+
+	_, err := s.GetSchedule(ctx, other, sch.ID)
+	assert.True(t, errors.IsNotFound(err))
+	assert.True(t, errors.IsNotFound(s.TriggerSchedule(ctx, other, sch.ID)))
+	assert.True(t, errors.IsNotFound(s.DeleteSchedule(ctx, other, sch.ID)))
+	assert.Empty(t, engine.calls)
+	assert.Contains(t, repo.schedules, sch.ID)
+
+	view, err := s.GetSchedule(ctx, sch.CompanyID, sch.ID)
+	require.NoError(t, err)
+	assert.Len(t, view.NextRuns, 1)
+
+	require.NoError(t, s.TriggerSchedule(ctx, sch.CompanyID, sch.ID))
+	require.NoError(t, s.DeleteSchedule(ctx, sch.CompanyID, sch.ID))
+	assert.Equal(t, []string{"next_runs", "trigger", "delete"}, engine.calls)
+	assert.NotContains(t, repo.schedules, sch.ID)
+}
@@ -2,19 +2,73 @@ package task
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/google/uuid"
 	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/internal/infrastructure/eventbus"
 	"unlimited-corp/pkg/errors"
 )
 
 type Service struct {
-	repo task.Repository
+	repo          task.Repository
+	eventBus      eventbus.EventBus
+	uow           database.UnitOfWork
+	engine        WorkflowEngine
+	approvals     task.ApprovalRepository
+	attempts      task.AttemptRepository
+	steps         task.StepRepository
+	subscriptions []*eventbus.Subscription
+
+	schedules      task.ScheduleRepository
+	scheduleEngine ScheduleEngine
 }
 
 func NewService(repo task.Repository) *Service {
-	return &Service{repo: repo}
+	return &Service{
+		repo:     repo,
+		eventBus: eventbus.GetEventBus(),
+	}
+}
+
+// SetUnitOfWork makes task changes write their events in the same
+// transaction as the task
+func (s *Service) SetUnitOfWork(uow database.UnitOfWork) {
+	s.uow = uow
+}
+
+// inTransaction runs fn in a unit of work, or directly when none is configured
+func (s *Service) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.uow == nil {
+		return fn(ctx)
+	}
+	return s.uow.Do(ctx, fn)
+}
+
+// statusEvents maps task statuses to the events announcing them
+var statusEvents = map[task.TaskStatus]eventbus.EventType{
+	task.StatusRunning:   eventbus.EventTaskStarted,
+	task.StatusCompleted: eventbus.EventTaskCompleted,
+	task.StatusFailed:    eventbus.EventTaskFailed,
+	task.StatusCancelled: eventbus.EventTaskCancelled,
+}
+
+// publish announces a task event; the task itself is the payload
+func (s *Service) publish(ctx context.Context, eventType eventbus.EventType, t *task.Task) error {
+	event, err := eventbus.NewEvent(eventType, "task_service", t, eventbus.Metadata{
+		CompanyID: t.CompanyID.String(),
+		Version:   1,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to encode task event")
+	}
+	if err := s.eventBus.Publish(ctx, event); err != nil {
+		return errors.Wrap(err, "failed to publish task event")
+	}
+	return nil
 }
 
 type CreateInput struct {
@@ -22,12 +76,34 @@ type CreateInput struct {
 	Title       string           `json:"title" binding:"required,min=2,max=500"`
 	Description string           `json:"description"`
 	Priority    task.TaskPriority `json:"priority" binding:"required,oneof=low medium high urgent"`
+	// WorkflowDefinition is validated when it declares nodes
+	WorkflowDefinition map[string]interface{} `json:"workflow_definition"`
+	InputData          map[string]interface{} `json:"input_data"`
 }
 
 func (s *Service) Create(ctx context.Context, input *CreateInput) (*task.Task, error) {
 	t := task.NewTask(input.CompanyID, input.Title, input.Description, input.Priority)
-	if err := s.repo.Create(ctx, t); err != nil {
-		return nil, errors.Wrap(err, "failed to create task")
+	if workflow.Declared(input.WorkflowDefinition) {
+		def, err := workflow.Parse(input.WorkflowDefinition)
+		if err != nil {
+			return nil, errors.WrapWithCode(err, http.StatusBadRequest, err.Error())
+		}
+		t.WorkflowDefinition = def.ToMap()
+	} else if input.WorkflowDefinition != nil {
+		t.WorkflowDefinition = input.WorkflowDefinition
+	}
+	if input.InputData != nil {
+		t.SetInputData(input.InputData)
+	}
+
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Create(ctx, t); err != nil {
+			return errors.Wrap(err, "failed to create task")
+		}
+		return s.publish(ctx, eventbus.EventTaskCreated, t)
+	})
+	if err != nil {
+		return nil, err
 	}
 	return t, nil
 }
@@ -36,6 +112,23 @@ func (s *Service) GetByID(ctx context.Context, id uuid.UUID) (*task.Task, error)
 	return s.repo.GetByID(ctx, id)
 }
 
+// GetCompanyTask returns a task of the company
+func (s *Service) GetCompanyTask(ctx context.Context, companyID, id uuid.UUID) (*task.Task, error) {
+	return s.companyTask(ctx, companyID, id)
+}
+
+// companyTask returns a task of the company; other companies' tasks are not found
+func (s *Service) companyTask(ctx context.Context, companyID, id uuid.UUID) (*task.Task, error) {
+	t, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.CompanyID != companyID {
+		return nil, errors.ErrNotFound
+	}
+	return t, nil
+}
+
 func (s *Service) ListByCompanyID(ctx context.Context, companyID uuid.UUID, limit, offset int) ([]*task.Task, error) {
 	if limit <= 0 {
 		limit = 20
@@ -66,12 +159,26 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, input *UpdateInput)
 	return t, nil
 }
 
-func (s *Service) UpdateStatus(ctx context.Context, id uuid.UUID, status task.TaskStatus) (*task.Task, error) {
-	t, err := s.repo.GetByID(ctx, id)
+// UpdateStatus sets the status of a company's task. Pausing, resuming and
+// cancelling go through the task's workflow; any other status of a task
+// with a workflow is the workflow's to set.
+func (s *Service) UpdateStatus(ctx context.Context, companyID, id uuid.UUID, status task.TaskStatus) (*task.Task, error) {
+	t, err := s.companyTask(ctx, companyID, id)
 	if err != nil {
 		return nil, err
 	}
 
+	switch {
+	case status == task.StatusPaused:
+		return s.Pause(ctx, companyID, id)
+	case status == task.StatusRunning && t.Status == task.StatusPaused:
+		return s.Resume(ctx, companyID, id)
+	case status == task.StatusCancelled:
+		return s.Cancel(ctx, companyID, id)
+	case t.HasWorkflow():
+		return nil, errors.New(http.StatusConflict, "the status of a task with a workflow is set by its workflow")
+	}
+
 	t.Status = status
 	t.UpdatedAt = time.Now()
 
@@ -85,8 +192,17 @@ func (s *Service) UpdateStatus(ctx context.Context, id uuid.UUID, status task.Ta
 		t.Progress = 100
 	}
 
-	if err := s.repo.Update(ctx, t); err != nil {
-		return nil, errors.Wrap(err, "failed to update task status")
+	err = s.inTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Update(ctx, t); err != nil {
+			return errors.Wrap(err, "failed to update task status")
+		}
+		if eventType, ok := statusEvents[status]; ok {
+			return s.publish(ctx, eventType, t)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 	return t, nil
 }
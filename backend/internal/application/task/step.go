@@ -0,0 +1,60 @@
+package task
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/task"
+)
+
+// StepsView is the recorded steps of one run of a task
+type StepsView struct {
+	Attempt int          `json:"attempt"`
+	Steps   []*task.Step `json:"steps"`
+}
+
+// SetStepRepository sets the repository workflow steps are recorded in
+func (s *Service) SetStepRepository(steps task.StepRepository) {
+	s.steps = steps
+}
+
+// ListSteps lists the steps of a run of a task of the company in workflow
+// order; attempt 0 is the current run
+func (s *Service) ListSteps(ctx context.Context, companyID, taskID uuid.UUID, attempt int) (*StepsView, error) {
+	if _, err := s.companyTask(ctx, companyID, taskID); err != nil {
+		return nil, err
+	}
+	if attempt == 0 {
+		current, err := s.currentAttempt(ctx, taskID)
+		if err != nil {
+			return nil, err
+		}
+		attempt = current
+	}
+
+	view := &StepsView{Attempt: attempt, Steps: []*task.Step{}}
+	if s.steps == nil {
+		return view, nil
+	}
+	steps, err := s.steps.ListSteps(ctx, taskID, attempt)
+	if err != nil {
+		return nil, err
+	}
+	if steps != nil {
+		view.Steps = steps
+	}
+	return view, nil
+}
+
+// currentAttempt returns the number of the task's current run: one more than
+// the attempts it was retried from
+func (s *Service) currentAttempt(ctx context.Context, taskID uuid.UUID) (int, error) {
+	if s.attempts == nil {
+		return 1, nil
+	}
+	attempts, err := s.attempts.ListAttempts(ctx, taskID)
+	if err != nil {
+		return 0, err
+	}
+	return len(attempts) + 1, nil
+}
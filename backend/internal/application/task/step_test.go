@@ -0,0 +1,85 @@
+package task
+
+import (
+	"context"
+	"testing"
+
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSteps stores steps in memory
+type fakeSteps struct {
+	task.StepRepository
+	steps []*task.Step
+}
+
+func (r *fakeSteps) ListSteps(_ context.Context, taskID uuid.UUID, attempt int) ([]*task.Step, error) {
+	var steps []*task.Step
+	for _, s := range r.steps {
+		if s.TaskID == taskID && s.Attempt == attempt {
+			steps = append(steps, s)
+		}
+	}
+	return steps, nil
+}
+
+func TestService_ListSteps(t *testing.T) {
+	ctx := context.Background()
+	failed := newFailedPipelineTask()
+	first := task.NewStep(failed.ID, 1, 1, "draft", "draft")
+	retried := task.NewStep(failed.ID, 2, 1, "draft", "draft")
+	steps := &fakeSteps{steps: []*task.Step{first, retried}}
+
+	t.Run("lists the current run", func(t *testing.T) {
+		s := newTestService(newFakeRepository(failed), &fakeEngine{})
+		s.SetStepRepository(steps)
+		s.SetAttemptRepository(&fakeAttempts{attempts: []*task.Attempt{task.NewAttempt(failed, 1, nil)}})
+
+		view, err := s.ListSteps(ctx, failed.CompanyID, failed.ID, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 2, view.Attempt)
+		assert.Equal(t, []*task.Step{retried}, view.Steps)
+
+		view, err = s.ListSteps(ctx, failed.CompanyID, failed.ID, 1)
+		require.NoError(t, err)
+		assert.Equal(t, []*task.Step{first}, view.Steps)
+	})
+
+	t.Run("a task never retried is on its first run", func(t *testing.T) {
+		s := newTestService(newFakeRepository(failed), &fakeEngine{})
+		s.SetStepRepository(steps)
+
+		view, err := s.ListSteps(ctx, failed.CompanyID, failed.ID, 0)
+		require.NoError(t, err)
+		assert.Equal(t, 1, view.Attempt)
+		assert.Len(t, view.Steps, 1)
+	})
+
+	t.Run("without a repository no steps are recorded", func(t *testing.T) {
+		s := newTestService(newFakeRepository(failed), &fakeEngine{})
+
+		view, err := s.ListSteps(ctx, failed.CompanyID, failed.ID, 0)
+		require.NoError(t, err)
+		assert.NotNil(t, view.Steps)
+		assert.Empty(t, view.Steps)
+	})
+
+	t.Run("unknown task", func(t *testing.T) {
+		s := newTestService(newFakeRepository(), &fakeEngine{})
+		_, err := s.ListSteps(ctx, failed.CompanyID, uuid.New(), 0)
+		assert.True(t, errors.IsNotFound(err))
+	})
+
+	t.Run("other companies' steps are not found", func(t *testing.T) {
+		s := newTestService(newFakeRepository(failed), &fakeEngine{})
+		s.SetStepRepository(steps)
+
+		_, err := s.ListSteps(ctx, uuid.New(), failed.ID, 0)
+		assert.True(t, errors.IsNotFound(err))
+	})
+}
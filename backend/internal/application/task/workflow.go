@@ -0,0 +1,181 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+)
+
+// workflowStartTimeout bounds starting the workflow of one assigned task
+const workflowStartTimeout = 10 * time.Second
+
+// workflowConsumer is the name the service deduplicates task assignments under
+const workflowConsumer = "task_workflows"
+
+// WorkflowEngine runs the workflows of tasks that declare a workflow definition
+type WorkflowEngine interface {
+	// Start starts the workflow of an assigned task and returns its run; a
+	// retried task's run takes over what resume holds. Starting a task whose
+	// workflow is already running returns that run.
+	Start(ctx context.Context, t *task.Task, def *workflow.Definition, resume *task.Resume) (workflowID, runID string, err error)
+	// Outcome returns how the steps of the task's finished workflow run ended
+	Outcome(ctx context.Context, t *task.Task) (*task.RunOutcome, error)
+	// Pause asks the workflow to stop before its next step
+	Pause(ctx context.Context, t *task.Task) error
+	// Resume lets a paused workflow continue
+	Resume(ctx context.Context, t *task.Task) error
+	// Cancel cancels the workflow, interrupting the step in flight
+	Cancel(ctx context.Context, t *task.Task) error
+	// Decide hands a reviewer's decision to the approval the workflow waits for
+	Decide(ctx context.Context, t *task.Task, d task.ApprovalDecision) error
+	// Progress returns the live pipeline of the workflow
+	Progress(ctx context.Context, t *task.Task) (*task.Pipeline, error)
+}
+
+// SetWorkflowEngine sets the engine running task workflows. Without an
+// engine tasks only change status in the database.
+func (s *Service) SetWorkflowEngine(engine WorkflowEngine) {
+	s.engine = engine
+}
+
+// Start subscribes the service to task assignments, starting the workflow of
+// every assigned task that declares one
+func (s *Service) Start() {
+	s.subscriptions = append(s.subscriptions,
+		s.eventBus.SubscribeAs(workflowConsumer, eventbus.EventTaskAssigned, s.handleTaskAssigned),
+	)
+}
+
+// Stop removes the service's event subscriptions
+func (s *Service) Stop() {
+	for _, sub := range s.subscriptions {
+		s.eventBus.Unsubscribe(sub)
+	}
+	s.subscriptions = nil
+}
+
+// handleTaskAssigned starts the workflow of a task the scheduler just assigned
+func (s *Service) handleTaskAssigned(ctx context.Context, event *eventbus.Event) error {
+	var payload struct {
+		ID uuid.UUID `json:"id"`
+	}
+	if err := json.Unmarshal(event.Payload, &payload); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, workflowStartTimeout)
+	defer cancel()
+
+	t, err := s.repo.GetByID(ctx, payload.ID)
+	if err != nil {
+		return err
+	}
+	if err := s.StartWorkflow(ctx, t); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to start workflow of task %s: %v", t.ID, err))
+		return err
+	}
+	return nil
+}
+
+// StartWorkflow starts the workflow of a running task and records its run on
+// the task. Tasks without a workflow definition are left alone.
+func (s *Service) StartWorkflow(ctx context.Context, t *task.Task) error {
+	if s.engine == nil || t.Status != task.StatusRunning || !workflow.Declared(t.WorkflowDefinition) {
+		return nil
+	}
+
+	def, err := workflow.Parse(t.WorkflowDefinition)
+	if err != nil {
+		return errors.WrapWithCode(err, http.StatusBadRequest, err.Error())
+	}
+
+	resume, err := s.resume(ctx, t, def)
+	if err != nil {
+		return errors.Wrap(err, "failed to get the attempt to resume")
+	}
+
+	workflowID, runID, err := s.engine.Start(ctx, t, def, resume)
+	if err != nil {
+		return errors.Wrap(err, "failed to start task workflow")
+	}
+	if err := s.repo.SetWorkflowRun(ctx, t.ID, workflowID, runID); err != nil {
+		return errors.Wrap(err, "failed to record task workflow")
+	}
+	t.BindWorkflow(workflowID, runID)
+	return nil
+}
+
+// Pipeline returns the live pipeline of the task's workflow, or nil when the
+// task has no workflow run
+func (s *Service) Pipeline(ctx context.Context, t *task.Task) (*task.Pipeline, error) {
+	if s.engine == nil || !t.HasWorkflow() {
+		return nil, nil
+	}
+	pipeline, err := s.engine.Progress(ctx, t)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to query task workflow")
+	}
+	return pipeline, nil
+}
+
+// Pause pauses a running task. Its workflow finishes the step in flight and
+// waits before starting the next one.
+func (s *Service) Pause(ctx context.Context, companyID, id uuid.UUID) (*task.Task, error) {
+	return s.control(ctx, companyID, id, task.StatusPaused, WorkflowEngine.Pause, (*task.Task).Pause, eventbus.EventTaskPaused)
+}
+
+// Resume lets a paused task continue with its next step
+func (s *Service) Resume(ctx context.Context, companyID, id uuid.UUID) (*task.Task, error) {
+	return s.control(ctx, companyID, id, task.StatusRunning, WorkflowEngine.Resume, (*task.Task).Resume, eventbus.EventTaskResumed)
+}
+
+// Cancel cancels a task and its workflow
+func (s *Service) Cancel(ctx context.Context, companyID, id uuid.UUID) (*task.Task, error) {
+	return s.control(ctx, companyID, id, task.StatusCancelled, WorkflowEngine.Cancel, (*task.Task).Cancel, eventbus.EventTaskCancelled)
+}
+
+// control moves a task of the company to a status, telling its workflow
+// first so the database never shows a state the workflow did not accept
+func (s *Service) control(
+	ctx context.Context,
+	companyID, id uuid.UUID,
+	status task.TaskStatus,
+	signal func(WorkflowEngine, context.Context, *task.Task) error,
+	apply func(*task.Task),
+	eventType eventbus.EventType,
+) (*task.Task, error) {
+	t, err := s.companyTask(ctx, companyID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !t.CanTransitionTo(status) {
+		return nil, errors.New(http.StatusConflict, fmt.Sprintf("task cannot move from %s to %s", t.Status, status))
+	}
+
+	if s.engine != nil && t.HasWorkflow() {
+		if err := signal(s.engine, ctx, t); err != nil {
+			return nil, errors.Wrap(err, fmt.Sprintf("failed to move task workflow to %s", status))
+		}
+	}
+
+	apply(t)
+	err = s.inTransaction(ctx, func(ctx context.Context) error {
+		if err := s.repo.Update(ctx, t); err != nil {
+			return errors.Wrap(err, "failed to update task status")
+		}
+		return s.publish(ctx, eventType, t)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
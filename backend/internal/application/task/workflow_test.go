@@ -0,0 +1,267 @@
+package task
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/config"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&config.LogConfig{Level: "error", Format: "console"})
+	os.Exit(m.Run())
+}
+
+// fakeRepository overrides the task.Repository methods used by the service
+type fakeRepository struct {
+	task.Repository
+	tasks map[uuid.UUID]*task.Task
+}
+
+func newFakeRepository(tasks ...*task.Task) *fakeRepository {
+	repo := &fakeRepository{tasks: make(map[uuid.UUID]*task.Task)}
+	for _, t := range tasks {
+		repo.tasks[t.ID] = t
+	}
+	return repo
+}
+
+func (r *fakeRepository) GetByID(_ context.Context, id uuid.UUID) (*task.Task, error) {
+	if t, ok := r.tasks[id]; ok {
+		copied := *t
+		return &copied, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeRepository) Update(_ context.Context, t *task.Task) error {
+	r.tasks[t.ID] = t
+	return nil
+}
+
+func (r *fakeRepository) SetWorkflowRun(_ context.Context, id uuid.UUID, workflowID, runID string) error {
+	t, ok := r.tasks[id]
+	if !ok {
+		return errors.ErrNotFound
+	}
+	t.BindWorkflow(workflowID, runID)
+	return nil
+}
+
+// fakeEngine records the workflow operations it is asked for
+type fakeEngine struct {
+	calls     []string
+	decisions []task.ApprovalDecision
+	resumes   []*task.Resume
+	outcome   *task.RunOutcome
+	err       error
+}
+
+func (e *fakeEngine) Start(_ context.Context, t *task.Task, def *workflow.Definition, resume *task.Resume) (string, string, error) {
+	e.calls = append(e.calls, "start")
+	e.resumes = append(e.resumes, resume)
+	return "task-" + t.ID.String(), "run-1", e.err
+}
+
+func (e *fakeEngine) Outcome(context.Context, *task.Task) (*task.RunOutcome, error) {
+	e.calls = append(e.calls, "outcome")
+	return e.outcome, e.err
+}
+
+func (e *fakeEngine) Pause(context.Context, *task.Task) error {
+	e.calls = append(e.calls, "pause")
+	return e.err
+}
+
+func (e *fakeEngine) Resume(context.Context, *task.Task) error {
+	e.calls = append(e.calls, "resume")
+	return e.err
+}
+
+func (e *fakeEngine) Cancel(context.Context, *task.Task) error {
+	e.calls = append(e.calls, "cancel")
+	return e.err
+}
+
+func (e *fakeEngine) Decide(_ context.Context, _ *task.Task, d task.ApprovalDecision) error {
+	e.calls = append(e.calls, "decide")
+	e.decisions = append(e.decisions, d)
+	return e.err
+}
+
+func (e *fakeEngine) Progress(context.Context, *task.Task) (*task.Pipeline, error) {
+	e.calls = append(e.calls, "progress")
+	return &task.Pipeline{CurrentStep: "draft", Percent: 50}, e.err
+}
+
+func newWorkflowTask(status task.TaskStatus) *task.Task {
+	t := task.NewTask(uuid.New(), "Write a post", "", task.PriorityMedium)
+	t.Status = status
+	t.WorkflowDefinition = map[string]interface{}{
+		"version": 1,
+		"nodes": []interface{}{
+			map[string]interface{}{"id": "draft", "type": "skill", "skill_card_id": uuid.New().String()},
+		},
+	}
+	return t
+}
+
+func newTestService(repo task.Repository, engine WorkflowEngine) *Service {
+	s := &Service{repo: repo, eventBus: eventbus.NewEventBus()}
+	s.SetWorkflowEngine(engine)
+	return s
+}
+
+func TestService_StartWorkflow(t *testing.T) {
+	running := newWorkflowTask(task.StatusRunning)
+	plain := task.NewTask(uuid.New(), "No workflow", "", task.PriorityLow)
+	plain.Status = task.StatusRunning
+	repo := newFakeRepository(running, plain)
+	engine := &fakeEngine{}
+	s := newTestService(repo, engine)
+
+	require.NoError(t, s.StartWorkflow(context.Background(), running))
+	assert.Equal(t, "task-"+running.ID.String(), repo.tasks[running.ID].TemporalWorkflowID)
+	assert.Equal(t, "run-1", repo.tasks[running.ID].TemporalRunID)
+
+	require.NoError(t, s.StartWorkflow(context.Background(), plain))
+	assert.Equal(t, []string{"start"}, engine.calls, "tasks without a definition start no workflow")
+
+	pipeline, err := s.Pipeline(context.Background(), repo.tasks[running.ID])
+	require.NoError(t, err)
+	assert.Equal(t, "draft", pipeline.CurrentStep)
+
+	pipeline, err = s.Pipeline(context.Background(), plain)
+	require.NoError(t, err)
+	assert.Nil(t, pipeline)
+}
+
+func TestService_GetCompanyTask(t *testing.T) {
+	running := newWorkflowTask(task.StatusRunning)
+	s := newTestService(newFakeRepository(running), &fakeEngine{})
+
+	found, err := s.GetCompanyTask(context.Background(), running.CompanyID, running.ID)
+	require.NoError(t, err)
+	assert.Equal(t, running.ID, found.ID)
+
+	_, err = s.GetCompanyTask(context.Background(), uuid.New(), running.ID)
+	assert.True(t, errors.IsNotFound(err), "other companies' tasks and their pipelines are not found")
+}
+
+func TestService_PauseResumeCancel(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("signals the workflow before changing the status", func(t *testing.T) {
+		running := newWorkflowTask(task.StatusRunning)
+		running.BindWorkflow("task-1", "run-1")
+		repo := newFakeRepository(running)
+		engine := &fakeEngine{}
+		s := newTestService(repo, engine)
+
+		paused, err := s.Pause(ctx, running.CompanyID, running.ID)
+		require.NoError(t, err)
+		assert.Equal(t, task.StatusPaused, paused.Status)
+
+		resumed, err := s.Resume(ctx, running.CompanyID, running.ID)
+		require.NoError(t, err)
+		assert.Equal(t, task.StatusRunning, resumed.Status)
+
+		cancelled, err := s.Cancel(ctx, running.CompanyID, running.ID)
+		require.NoError(t, err)
+		assert.Equal(t, task.StatusCancelled, cancelled.Status)
+
+		assert.Equal(t, []string{"pause", "resume", "cancel"}, engine.calls)
+		assert.Len(t, s.eventBus.GetHistoryByType(eventbus.EventTaskPaused, 10), 1)
+		assert.Len(t, s.eventBus.GetHistoryByType(eventbus.EventTaskResumed, 10), 1)
+		assert.Len(t, s.eventBus.GetHistoryByType(eventbus.EventTaskCancelled, 10), 1)
+	})
+
+	t.Run("keeps the status when the workflow rejects the signal", func(t *testing.T) {
+		running := newWorkflowTask(task.StatusRunning)
+		running.BindWorkflow("task-1", "run-1")
+		repo := newFakeRepository(running)
+		s := newTestService(repo, &fakeEngine{err: fmt.Errorf("workflow not found")})
+
+		_, err := s.Pause(ctx, running.CompanyID, running.ID)
+		require.Error(t, err)
+		assert.Equal(t, task.StatusRunning, repo.tasks[running.ID].Status)
+	})
+
+	t.Run("other companies' tasks are not found", func(t *testing.T) {
+		running := newWorkflowTask(task.StatusRunning)
+		running.BindWorkflow("task-1", "run-1")
+		repo := newFakeRepository(running)
+		engine := &fakeEngine{}
+		s := newTestService(repo, engine)
+		other := uuid.New()
+
+		for _, op := range []func(context.Context, uuid.UUID, uuid.UUID) (*task.Task, error){s.Pause, s.Resume, s.Cancel} {
+			_, err := op(ctx, other, running.ID)
+			assert.True(t, errors.IsNotFound(err))
+		}
+		assert.Empty(t, engine.calls)
+		assert.Equal(t, task.StatusRunning, repo.tasks[running.ID].Status)
+	})
+
+	t.Run("rejects invalid transitions", func(t *testing.T) {
+		pending := newWorkflowTask(task.StatusPending)
+		engine := &fakeEngine{}
+		s := newTestService(newFakeRepository(pending), engine)
+
+		_, err := s.Pause(ctx, pending.CompanyID, pending.ID)
+		assert.True(t, errors.IsConflict(err))
+		assert.Empty(t, engine.calls)
+	})
+
+	t.Run("tasks without a workflow only change status", func(t *testing.T) {
+		pending := newWorkflowTask(task.StatusPending)
+		engine := &fakeEngine{}
+		s := newTestService(newFakeRepository(pending), engine)
+
+		cancelled, err := s.UpdateStatus(ctx, pending.CompanyID, pending.ID, task.StatusCancelled)
+		require.NoError(t, err)
+		assert.Equal(t, task.StatusCancelled, cancelled.Status)
+		assert.Empty(t, engine.calls)
+	})
+
+	t.Run("status updates only reach the company's tasks", func(t *testing.T) {
+		running := newWorkflowTask(task.StatusRunning)
+		running.BindWorkflow("task-1", "run-1")
+		engine := &fakeEngine{}
+		s := newTestService(newFakeRepository(running), engine)
+
+		_, err := s.UpdateStatus(ctx, uuid.New(), running.ID, task.StatusPaused)
+		assert.True(t, errors.IsNotFound(err))
+		assert.Empty(t, engine.calls)
+
+		paused, err := s.UpdateStatus(ctx, running.CompanyID, running.ID, task.StatusPaused)
+		require.NoError(t, err)
+		assert.Equal(t, task.StatusPaused, paused.Status)
+		assert.Equal(t, []string{"pause"}, engine.calls)
+	})
+
+	t.Run("the workflow sets the other statuses of its task", func(t *testing.T) {
+		running := newWorkflowTask(task.StatusRunning)
+		running.BindWorkflow("task-1", "run-1")
+		repo := newFakeRepository(running)
+		s := newTestService(repo, &fakeEngine{})
+
+		for _, status := range []task.TaskStatus{task.StatusCompleted, task.StatusFailed, task.StatusPending} {
+			_, err := s.UpdateStatus(ctx, running.CompanyID, running.ID, status)
+			assert.True(t, errors.IsConflict(err), status)
+		}
+		assert.Equal(t, task.StatusRunning, repo.tasks[running.ID].Status)
+		assert.Empty(t, s.eventBus.GetHistoryByType(eventbus.EventTaskCompleted, 10))
+	})
+}
@@ -0,0 +1,301 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/google/uuid"
+	taskApp "unlimited-corp/internal/application/task"
+	"unlimited-corp/internal/domain/skillcard"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+)
+
+// defaultTemplateVersion is the version of a template created without one
+const defaultTemplateVersion = "1.0.0"
+
+// templateKeyPattern is what a template key, which tasks get as their type, looks like
+var templateKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,99}$`)
+
+// TaskCreator creates tasks; implemented by the task service
+type TaskCreator interface {
+	Create(ctx context.Context, input *taskApp.CreateInput) (*task.Task, error)
+}
+
+// SkillCardGetter looks up skill cards; implemented by the skill card repository
+type SkillCardGetter interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*skillcard.SkillCard, error)
+}
+
+// Service manages the workflow template catalog
+type Service struct {
+	repo       workflow.TemplateRepository
+	tasks      TaskCreator
+	skillCards SkillCardGetter
+}
+
+// NewService creates a new workflow template service
+func NewService(repo workflow.TemplateRepository, tasks TaskCreator, skillCards SkillCardGetter) *Service {
+	return &Service{repo: repo, tasks: tasks, skillCards: skillCards}
+}
+
+// VersionInput is a version of a template to publish
+type VersionInput struct {
+	Version string `json:"version"`
+	// Definition is decoded strictly: unknown fields are rejected
+	Definition       json.RawMessage       `json:"definition" binding:"required"`
+	ParamSchema      *workflow.ParamSchema `json:"param_schema"`
+	EstimatedSeconds int                   `json:"estimated_seconds"`
+	Changelog        string                `json:"changelog"`
+}
+
+// CreateTemplateInput creates a company's own template with its first version
+type CreateTemplateInput struct {
+	CompanyID   uuid.UUID `json:"-"`
+	CreatedBy   uuid.UUID `json:"-"`
+	Key         string    `json:"key" binding:"required"`
+	Name        string    `json:"name" binding:"required,min=2,max=200"`
+	Description string    `json:"description"`
+	Category    string    `json:"category"`
+	Thumbnail   string    `json:"thumbnail" binding:"omitempty,url,max=500"`
+	// IsPublic shares the template with the community
+	IsPublic bool `json:"is_public"`
+	VersionInput
+}
+
+// InstantiateInput creates a task from a template version
+type InstantiateInput struct {
+	CompanyID uuid.UUID `json:"-"`
+	// Version defaults to the latest
+	Version     string            `json:"version"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	Priority    task.TaskPriority `json:"priority" binding:"omitempty,oneof=low medium high urgent"`
+	// Params are validated against the version's parameter schema
+	Params map[string]interface{} `json:"params"`
+}
+
+// TemplateDetail is a template with all its versions
+type TemplateDetail struct {
+	*workflow.Template
+	Versions []*workflow.TemplateVersion `json:"versions"`
+}
+
+// ListTemplates lists the system templates, the company's own and the
+// public ones of other companies
+func (s *Service) ListTemplates(ctx context.Context, companyID uuid.UUID, filter workflow.TemplateFilter) ([]*workflow.Template, error) {
+	if filter.Source != "" && !workflow.IsValidSource(filter.Source) {
+		return nil, errors.New(http.StatusBadRequest, fmt.Sprintf("unknown source %q", filter.Source))
+	}
+	templates, err := s.repo.ListTemplates(ctx, companyID, filter)
+	if err != nil {
+		return nil, err
+	}
+	if templates == nil {
+		templates = []*workflow.Template{}
+	}
+	names := make(map[string]string)
+	for _, t := range templates {
+		s.describe(ctx, companyID, t, names)
+	}
+	return templates, nil
+}
+
+// GetTemplate returns a template the company can see with all its versions
+func (s *Service) GetTemplate(ctx context.Context, companyID, id uuid.UUID) (*TemplateDetail, error) {
+	t, err := s.visibleTemplate(ctx, companyID, id)
+	if err != nil {
+		return nil, err
+	}
+	s.describe(ctx, companyID, t, make(map[string]string))
+	versions, err := s.repo.ListVersions(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	if versions == nil {
+		versions = []*workflow.TemplateVersion{}
+	}
+	return &TemplateDetail{Template: t, Versions: versions}, nil
+}
+
+// GetVersion returns a version of a template the company can see
+func (s *Service) GetVersion(ctx context.Context, companyID, id uuid.UUID, version string) (*workflow.TemplateVersion, error) {
+	t, err := s.visibleTemplate(ctx, companyID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.version(ctx, t, version)
+}
+
+// CreateTemplate creates a company's own template with its first version
+func (s *Service) CreateTemplate(ctx context.Context, input *CreateTemplateInput) (*workflow.Template, error) {
+	if !templateKeyPattern.MatchString(input.Key) {
+		return nil, errors.New(http.StatusBadRequest, "key must be 2-100 lowercase letters, digits or underscores, starting with a letter")
+	}
+	category := input.Category
+	if category == "" {
+		category = workflow.CategoryCustom
+	}
+	if !workflow.IsValidCategory(category) {
+		return nil, errors.New(http.StatusBadRequest, "invalid category")
+	}
+
+	t := workflow.NewTemplate(input.CompanyID, input.Key, input.Name, input.Description, category)
+	t.Thumbnail = input.Thumbnail
+	t.IsPublic = input.IsPublic
+	if input.CreatedBy != uuid.Nil {
+		t.CreatedBy = &input.CreatedBy
+	}
+	if input.Version == "" {
+		input.Version = defaultTemplateVersion
+	}
+	v, err := newVersion(t, &input.VersionInput)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateTemplate(ctx, t, v); err != nil {
+		return nil, err
+	}
+	t.Latest = v
+	s.describe(ctx, input.CompanyID, t, make(map[string]string))
+	return t, nil
+}
+
+// PublishVersion publishes a new version of a company's own template. Its
+// version number must be higher than the latest one.
+func (s *Service) PublishVersion(ctx context.Context, companyID, id uuid.UUID, input *VersionInput) (*workflow.TemplateVersion, error) {
+	t, err := s.visibleTemplate(ctx, companyID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !t.IsOwnedBy(companyID) {
+		return nil, errors.New(http.StatusForbidden, "only the company owning a template can change it")
+	}
+	if input.Version == "" {
+		return nil, errors.New(http.StatusBadRequest, "version is required")
+	}
+
+	v, err := newVersion(t, input)
+	if err != nil {
+		return nil, err
+	}
+	if !v.Follows(t.Latest) {
+		return nil, errors.New(http.StatusConflict, fmt.Sprintf("version %s is not higher than the latest version %s", v.Version, t.Latest.Version))
+	}
+	if err := s.repo.CreateVersion(ctx, v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Instantiate creates a task running a version of a template with the given
+// parameters, filled in with their defaults, and counts it in the template's usage
+func (s *Service) Instantiate(ctx context.Context, id uuid.UUID, input *InstantiateInput) (*task.Task, error) {
+	t, err := s.visibleTemplate(ctx, input.CompanyID, id)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.version(ctx, t, input.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	def, params, err := v.Instantiate(input.Params)
+	if err != nil {
+		return nil, errors.WrapWithCode(err, http.StatusBadRequest, err.Error())
+	}
+
+	title := input.Title
+	if title == "" {
+		title = t.Name
+	}
+	priority := input.Priority
+	if priority == "" {
+		priority = task.PriorityMedium
+	}
+	created, err := s.tasks.Create(ctx, &taskApp.CreateInput{
+		CompanyID:          input.CompanyID,
+		Title:              title,
+		Description:        input.Description,
+		Priority:           priority,
+		WorkflowDefinition: def.ToMap(),
+		InputData:          params,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// The task is already created and running; a lost usage record only skews the statistics
+	if err := s.repo.RecordUse(ctx, v, created.ID); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to record the use of template %s by task %s: %v", t.ID, created.ID, err))
+	}
+	return created, nil
+}
+
+// describe fills in where a template comes from for the company and the
+// skill cards its latest version needs. names caches skill card names by
+// ID across templates; a card that cannot be found keeps an empty name.
+func (s *Service) describe(ctx context.Context, companyID uuid.UUID, t *workflow.Template, names map[string]string) {
+	t.Source = t.SourceFor(companyID)
+	t.RequiredSkillCards = []workflow.SkillCardRef{}
+	if t.Latest == nil || t.Latest.Definition == nil {
+		return
+	}
+	for _, id := range t.Latest.Definition.SkillCardIDs() {
+		name, ok := names[id]
+		if !ok {
+			if cardID, err := uuid.Parse(id); err == nil {
+				if card, err := s.skillCards.GetByID(ctx, cardID); err == nil && card != nil {
+					name = card.Name
+				}
+			}
+			names[id] = name
+		}
+		t.RequiredSkillCards = append(t.RequiredSkillCards, workflow.SkillCardRef{ID: id, Name: name})
+	}
+}
+
+// visibleTemplate returns a template the company can see; others are not found
+func (s *Service) visibleTemplate(ctx context.Context, companyID, id uuid.UUID) (*workflow.Template, error) {
+	t, err := s.repo.GetTemplate(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !t.IsVisibleTo(companyID) {
+		return nil, errors.ErrNotFound
+	}
+	return t, nil
+}
+
+// version returns the given version of a template, or its latest
+func (s *Service) version(ctx context.Context, t *workflow.Template, version string) (*workflow.TemplateVersion, error) {
+	if version == "" {
+		if t.Latest == nil {
+			return nil, errors.New(http.StatusConflict, "template has no published version")
+		}
+		return t.Latest, nil
+	}
+	semver, err := workflow.ParseSemVer(version)
+	if err != nil {
+		return nil, errors.WrapWithCode(err, http.StatusBadRequest, err.Error())
+	}
+	return s.repo.GetVersion(ctx, t.ID, semver.String())
+}
+
+// newVersion validates a version of a template
+func newVersion(t *workflow.Template, input *VersionInput) (*workflow.TemplateVersion, error) {
+	def, err := workflow.ParseJSON(input.Definition)
+	if err != nil {
+		return nil, errors.WrapWithCode(err, http.StatusBadRequest, err.Error())
+	}
+	v, err := workflow.NewTemplateVersion(t, input.Version, def, input.ParamSchema, input.EstimatedSeconds, input.Changelog)
+	if err != nil {
+		return nil, errors.WrapWithCode(err, http.StatusBadRequest, err.Error())
+	}
+	return v, nil
+}
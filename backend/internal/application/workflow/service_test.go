@@ -0,0 +1,249 @@
+package workflow
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	taskApp "unlimited-corp/internal/application/task"
+	"unlimited-corp/internal/domain/skillcard"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTemplates stores templates, versions and their uses in memory
+type fakeTemplates struct {
+	templates map[uuid.UUID]*workflow.Template
+	versions  map[uuid.UUID][]*workflow.TemplateVersion
+	uses      map[uuid.UUID]uuid.UUID
+}
+
+func newFakeTemplates() *fakeTemplates {
+	return &fakeTemplates{
+		templates: make(map[uuid.UUID]*workflow.Template),
+		versions:  make(map[uuid.UUID][]*workflow.TemplateVersion),
+		uses:      make(map[uuid.UUID]uuid.UUID),
+	}
+}
+
+func (r *fakeTemplates) CreateTemplate(ctx context.Context, t *workflow.Template, v *workflow.TemplateVersion) error {
+	r.templates[t.ID] = t
+	return r.CreateVersion(ctx, v)
+}
+
+func (r *fakeTemplates) GetTemplate(_ context.Context, id uuid.UUID) (*workflow.Template, error) {
+	t, ok := r.templates[id]
+	if !ok {
+		return nil, errors.ErrNotFound
+	}
+	copied := *t
+	if versions := r.versions[id]; len(versions) > 0 {
+		copied.Latest = versions[len(versions)-1]
+	}
+	return &copied, nil
+}
+
+func (r *fakeTemplates) ListTemplates(ctx context.Context, companyID uuid.UUID, _ workflow.TemplateFilter) ([]*workflow.Template, error) {
+	var templates []*workflow.Template
+	for id, t := range r.templates {
+		if t.IsVisibleTo(companyID) {
+			copied, _ := r.GetTemplate(ctx, id)
+			templates = append(templates, copied)
+		}
+	}
+	return templates, nil
+}
+
+func (r *fakeTemplates) CreateVersion(_ context.Context, v *workflow.TemplateVersion) error {
+	r.versions[v.TemplateID] = append(r.versions[v.TemplateID], v)
+	return nil
+}
+
+func (r *fakeTemplates) GetVersion(_ context.Context, templateID uuid.UUID, version string) (*workflow.TemplateVersion, error) {
+	for _, v := range r.versions[templateID] {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeTemplates) ListVersions(_ context.Context, templateID uuid.UUID) ([]*workflow.TemplateVersion, error) {
+	return r.versions[templateID], nil
+}
+
+func (r *fakeTemplates) RecordUse(_ context.Context, v *workflow.TemplateVersion, taskID uuid.UUID) error {
+	r.uses[taskID] = v.ID
+	return nil
+}
+
+// fakeSkillCards looks up skill cards by ID
+type fakeSkillCards map[uuid.UUID]*skillcard.SkillCard
+
+func (f fakeSkillCards) GetByID(_ context.Context, id uuid.UUID) (*skillcard.SkillCard, error) {
+	return f[id], nil
+}
+
+// fakeTasks records the tasks it is asked to create
+type fakeTasks struct {
+	created []*taskApp.CreateInput
+}
+
+func (f *fakeTasks) Create(_ context.Context, input *taskApp.CreateInput) (*task.Task, error) {
+	f.created = append(f.created, input)
+	t := task.NewTask(input.CompanyID, input.Title, input.Description, input.Priority)
+	t.WorkflowDefinition = input.WorkflowDefinition
+	t.SetInputData(input.InputData)
+	return t, nil
+}
+
+const digestDefinition = `{"version":1,"nodes":[{"id":"write","type":"skill","skill_card_id":"a0000001-0000-0000-0000-000000000002","inputs":{"product":"$.input.topic"}}]}`
+
+func digestInput(companyID uuid.UUID) *CreateTemplateInput {
+	return &CreateTemplateInput{
+		CompanyID: companyID,
+		Key:       "weekly_digest",
+		Name:      "Weekly digest",
+		VersionInput: VersionInput{
+			Definition: json.RawMessage(digestDefinition),
+			ParamSchema: &workflow.ParamSchema{
+				Type:     workflow.ParamObject,
+				Required: []string{"topic"},
+				Properties: map[string]*workflow.ParamProperty{
+					"topic": {Type: workflow.ParamString},
+					"tone":  {Type: workflow.ParamString, Default: "casual"},
+				},
+			},
+			EstimatedSeconds: 90,
+		},
+	}
+}
+
+func TestService_CreateTemplate(t *testing.T) {
+	ctx := context.Background()
+	companyID := uuid.New()
+
+	t.Run("creates version 1.0.0", func(t *testing.T) {
+		repo := newFakeTemplates()
+		s := NewService(repo, &fakeTasks{}, fakeSkillCards{})
+
+		tpl, err := s.CreateTemplate(ctx, digestInput(companyID))
+		require.NoError(t, err)
+		assert.Equal(t, workflow.CategoryCustom, tpl.Category)
+		require.NotNil(t, tpl.Latest)
+		assert.Equal(t, "1.0.0", tpl.Latest.Version)
+		assert.Equal(t, 1, tpl.Latest.NodeCount)
+		assert.Len(t, repo.versions[tpl.ID], 1)
+	})
+
+	t.Run("rejects invalid input", func(t *testing.T) {
+		tests := []struct {
+			name   string
+			modify func(*CreateTemplateInput)
+		}{
+			{"key", func(in *CreateTemplateInput) { in.Key = "Weekly Digest" }},
+			{"category", func(in *CreateTemplateInput) { in.Category = "sports" }},
+			{"version", func(in *CreateTemplateInput) { in.Version = "1.0" }},
+			{"definition", func(in *CreateTemplateInput) { in.Definition = json.RawMessage(`{"version":1,"steps":[]}`) }},
+			{"schema", func(in *CreateTemplateInput) { in.ParamSchema.Required = []string{"audience"} }},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				repo := newFakeTemplates()
+				input := digestInput(companyID)
+				tt.modify(input)
+
+				_, err := NewService(repo, &fakeTasks{}, fakeSkillCards{}).CreateTemplate(ctx, input)
+				assert.True(t, errors.IsBadRequest(err), "got %v", err)
+				assert.Empty(t, repo.templates)
+			})
+		}
+	})
+}
+
+func TestService_PublishVersion(t *testing.T) {
+	ctx := context.Background()
+	companyID := uuid.New()
+	repo := newFakeTemplates()
+	s := NewService(repo, &fakeTasks{}, fakeSkillCards{})
+	tpl, err := s.CreateTemplate(ctx, digestInput(companyID))
+	require.NoError(t, err)
+
+	v, err := s.PublishVersion(ctx, companyID, tpl.ID, &VersionInput{Version: "1.1.0", Definition: json.RawMessage(digestDefinition)})
+	require.NoError(t, err)
+	assert.Equal(t, "1.1.0", v.Version)
+
+	_, err = s.PublishVersion(ctx, companyID, tpl.ID, &VersionInput{Version: "1.0.5", Definition: json.RawMessage(digestDefinition)})
+	assert.True(t, errors.IsConflict(err), "versions only go up")
+
+	_, err = s.PublishVersion(ctx, uuid.New(), tpl.ID, &VersionInput{Version: "2.0.0", Definition: json.RawMessage(digestDefinition)})
+	assert.True(t, errors.IsNotFound(err), "other companies do not see the template")
+
+	system := &workflow.Template{ID: uuid.New(), Key: "auto_ops", IsSystem: true}
+	repo.templates[system.ID] = system
+	_, err = s.PublishVersion(ctx, companyID, system.ID, &VersionInput{Version: "2.0.0", Definition: json.RawMessage(digestDefinition)})
+	assert.True(t, errors.Is(err, errors.ErrForbidden))
+}
+
+func TestService_Instantiate(t *testing.T) {
+	ctx := context.Background()
+	companyID := uuid.New()
+	repo, tasks := newFakeTemplates(), &fakeTasks{}
+	s := NewService(repo, tasks, fakeSkillCards{})
+	tpl, err := s.CreateTemplate(ctx, digestInput(companyID))
+	require.NoError(t, err)
+
+	created, err := s.Instantiate(ctx, tpl.ID, &InstantiateInput{
+		CompanyID: companyID,
+		Params:    map[string]interface{}{"topic": "autumn outfits"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Weekly digest", created.Title)
+	assert.Equal(t, task.PriorityMedium, created.Priority)
+	assert.Equal(t, "weekly_digest", created.Type(), "the template key is the task type")
+	assert.Equal(t, map[string]interface{}{"topic": "autumn outfits", "tone": "casual"}, created.InputData)
+	assert.True(t, workflow.Declared(created.WorkflowDefinition))
+
+	_, err = s.Instantiate(ctx, tpl.ID, &InstantiateInput{CompanyID: companyID, Params: map[string]interface{}{"tone": "formal"}})
+	assert.True(t, errors.IsBadRequest(err), "parameters are validated")
+
+	_, err = s.Instantiate(ctx, tpl.ID, &InstantiateInput{CompanyID: companyID, Version: "9.0.0", Params: map[string]interface{}{"topic": "x"}})
+	assert.True(t, errors.IsNotFound(err))
+	assert.Len(t, tasks.created, 1)
+	assert.Equal(t, map[uuid.UUID]uuid.UUID{created.ID: tpl.Latest.ID}, repo.uses, "the task counts in the template's usage")
+}
+
+func TestService_ListTemplates(t *testing.T) {
+	ctx := context.Background()
+	companyID, otherID := uuid.New(), uuid.New()
+	cardID := uuid.MustParse("a0000001-0000-0000-0000-000000000002")
+	repo := newFakeTemplates()
+	s := NewService(repo, &fakeTasks{}, fakeSkillCards{cardID: {ID: cardID, Name: "Copywriting"}})
+
+	_, err := s.CreateTemplate(ctx, digestInput(companyID))
+	require.NoError(t, err)
+	shared := digestInput(otherID)
+	shared.Key, shared.Name, shared.IsPublic = "shared_digest", "Shared digest", true
+	_, err = s.CreateTemplate(ctx, shared)
+	require.NoError(t, err)
+	_, err = s.CreateTemplate(ctx, digestInput(uuid.New()))
+	require.NoError(t, err)
+
+	templates, err := s.ListTemplates(ctx, companyID, workflow.TemplateFilter{})
+	require.NoError(t, err)
+	require.Len(t, templates, 2, "private templates of other companies are hidden")
+	sources := map[string]string{}
+	for _, tpl := range templates {
+		sources[tpl.Key] = tpl.Source
+		assert.Equal(t, []workflow.SkillCardRef{{ID: cardID.String(), Name: "Copywriting"}}, tpl.RequiredSkillCards)
+	}
+	assert.Equal(t, map[string]string{"weekly_digest": workflow.SourceMine, "shared_digest": workflow.SourceCommunity}, sources)
+
+	_, err = s.ListTemplates(ctx, companyID, workflow.TemplateFilter{Source: "popular"})
+	assert.True(t, errors.IsBadRequest(err))
+}
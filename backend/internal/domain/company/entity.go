@@ -18,17 +18,30 @@ type Company struct {
 }
 
 func NewCompany(userID uuid.UUID, name, description string) *Company {
+	now := time.Now()
 	return &Company{
 		ID:          uuid.New(),
 		UserID:      userID,
 		Name:        name,
 		Description: description,
 		Settings:    make(map[string]interface{}),
-		CreatedAt:   time.Now(),
-		UpdatedAt:   time.Now(),
+		CreatedAt:   now,
+		UpdatedAt:   now,
 	}
 }
 
 func (c *Company) GetID() uuid.UUID {
 	return c.ID
 }
+
+// Location returns the company timezone from settings["timezone"], defaulting to UTC
+func (c *Company) Location() *time.Location {
+	if c.Settings != nil {
+		if name, ok := c.Settings["timezone"].(string); ok && name != "" {
+			if loc, err := time.LoadLocation(name); err == nil {
+				return loc
+			}
+		}
+	}
+	return time.UTC
+}
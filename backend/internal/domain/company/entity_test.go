@@ -2,6 +2,7 @@ package company
 
 import (
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -90,3 +91,14 @@ func TestCompany_TimestampsSet(t *testing.T) {
 	// They should be approximately equal (created at same time)
 	assert.WithinDuration(t, company.CreatedAt, company.UpdatedAt, 0)
 }
+
+func TestCompany_Location(t *testing.T) {
+	company := NewCompany(uuid.New(), "Test Company", "")
+	assert.Equal(t, time.UTC, company.Location())
+
+	company.Settings["timezone"] = "Asia/Shanghai"
+	assert.Equal(t, "Asia/Shanghai", company.Location().String())
+
+	company.Settings["timezone"] = "Not/AZone"
+	assert.Equal(t, time.UTC, company.Location())
+}
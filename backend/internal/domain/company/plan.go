@@ -0,0 +1,58 @@
+package company
+
+// Plan represents the subscription plan of a company
+type Plan string
+
+const (
+	PlanFree       Plan = "free"
+	PlanPro        Plan = "pro"
+	PlanEnterprise Plan = "enterprise"
+)
+
+// PlanLimits holds the resource limits granted by a plan
+type PlanLimits struct {
+	// MaxConcurrentTasksPerEmployee is the default number of tasks an employee may run at once
+	MaxConcurrentTasksPerEmployee int `json:"max_concurrent_tasks_per_employee"`
+	// FairShareWeight is the company's share of the execution slots shared by all companies
+	FairShareWeight int `json:"fair_share_weight"`
+}
+
+var planLimits = map[Plan]PlanLimits{
+	PlanFree: {
+		MaxConcurrentTasksPerEmployee: 1,
+		FairShareWeight:               1,
+	},
+	PlanPro: {
+		MaxConcurrentTasksPerEmployee: 3,
+		FairShareWeight:               4,
+	},
+	PlanEnterprise: {
+		MaxConcurrentTasksPerEmployee: 10,
+		FairShareWeight:               16,
+	},
+}
+
+// Limits returns the limits of the plan, falling back to the free plan
+func (p Plan) Limits() PlanLimits {
+	if limits, ok := planLimits[p]; ok {
+		return limits
+	}
+	return planLimits[PlanFree]
+}
+
+// IsValidPlan checks if the plan is valid
+func IsValidPlan(plan string) bool {
+	_, ok := planLimits[Plan(plan)]
+	return ok
+}
+
+// Plan returns the subscription plan stored in company settings
+func (c *Company) Plan() Plan {
+	if c.Settings == nil {
+		return PlanFree
+	}
+	if plan, ok := c.Settings["plan"].(string); ok && IsValidPlan(plan) {
+		return Plan(plan)
+	}
+	return PlanFree
+}
@@ -0,0 +1,40 @@
+package company
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompany_Plan(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings map[string]interface{}
+		expected Plan
+	}{
+		{"nil settings", nil, PlanFree},
+		{"no plan", map[string]interface{}{}, PlanFree},
+		{"pro plan", map[string]interface{}{"plan": "pro"}, PlanPro},
+		{"enterprise plan", map[string]interface{}{"plan": "enterprise"}, PlanEnterprise},
+		{"unknown plan", map[string]interface{}{"plan": "platinum"}, PlanFree},
+		{"wrong type", map[string]interface{}{"plan": 3}, PlanFree},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			company := NewCompany(uuid.New(), "Test", "")
+			company.Settings = tt.settings
+			assert.Equal(t, tt.expected, company.Plan())
+		})
+	}
+}
+
+func TestPlan_Limits(t *testing.T) {
+	assert.Equal(t, 1, PlanFree.Limits().MaxConcurrentTasksPerEmployee)
+	assert.Greater(t, PlanPro.Limits().MaxConcurrentTasksPerEmployee, PlanFree.Limits().MaxConcurrentTasksPerEmployee)
+	assert.Greater(t, PlanEnterprise.Limits().MaxConcurrentTasksPerEmployee, PlanPro.Limits().MaxConcurrentTasksPerEmployee)
+	assert.Equal(t, PlanFree.Limits(), Plan("unknown").Limits())
+	assert.Equal(t, 1, PlanFree.Limits().FairShareWeight)
+	assert.Greater(t, PlanEnterprise.Limits().FairShareWeight, PlanPro.Limits().FairShareWeight)
+}
@@ -0,0 +1,163 @@
+package employee
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AvailabilityWindow is a recurring daily working window in the calendar's timezone.
+// A window whose end is not after its start runs past midnight (e.g. a night
+// shift from 22:00 to 06:00); its weekdays refer to the day the window opens.
+type AvailabilityWindow struct {
+	Weekdays []time.Weekday `json:"weekdays,omitempty"` // 0=Sunday ... 6=Saturday; empty means every day
+	Start    string         `json:"start"`              // HH:MM
+	End      string         `json:"end"`                // HH:MM
+}
+
+// Blackout is a one-off period during which the employee is unavailable
+type Blackout struct {
+	Start  time.Time `json:"start"`
+	End    time.Time `json:"end"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Calendar holds the availability of an employee
+type Calendar struct {
+	EmployeeID  uuid.UUID            `json:"employee_id"`
+	CompanyID   uuid.UUID            `json:"company_id"`
+	Timezone    string               `json:"timezone,omitempty"` // overrides the company timezone when set
+	Windows     []AvailabilityWindow `json:"windows"`
+	Blackouts   []Blackout           `json:"blackouts"`
+	AutoOffline bool                 `json:"auto_offline"` // the employee was taken offline by the calendar
+	CreatedAt   time.Time            `json:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at"`
+}
+
+// NewCalendar creates an empty calendar; an employee without windows is always available
+func NewCalendar(companyID, employeeID uuid.UUID) *Calendar {
+	now := time.Now()
+	return &Calendar{
+		EmployeeID: employeeID,
+		CompanyID:  companyID,
+		Windows:    []AvailabilityWindow{},
+		Blackouts:  []Blackout{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+// Validate checks windows, blackouts and timezone
+func (c *Calendar) Validate() error {
+	if c.Timezone != "" {
+		if _, err := time.LoadLocation(c.Timezone); err != nil {
+			return fmt.Errorf("invalid timezone %q", c.Timezone)
+		}
+	}
+	for i, w := range c.Windows {
+		if _, err := parseClock(w.Start); err != nil {
+			return fmt.Errorf("window %d: invalid start: %w", i, err)
+		}
+		if _, err := parseClock(w.End); err != nil {
+			return fmt.Errorf("window %d: invalid end: %w", i, err)
+		}
+		for _, d := range w.Weekdays {
+			if d < time.Sunday || d > time.Saturday {
+				return fmt.Errorf("window %d: invalid weekday %d", i, d)
+			}
+		}
+	}
+	for i, b := range c.Blackouts {
+		if !b.End.After(b.Start) {
+			return fmt.Errorf("blackout %d: end must be after start", i)
+		}
+	}
+	return nil
+}
+
+// Location returns the calendar timezone, falling back to the given company location
+func (c *Calendar) Location(companyLocation *time.Location) *time.Location {
+	if c.Timezone != "" {
+		if loc, err := time.LoadLocation(c.Timezone); err == nil {
+			return loc
+		}
+	}
+	if companyLocation != nil {
+		return companyLocation
+	}
+	return time.UTC
+}
+
+// UnavailableReason explains why the employee is unavailable at the given time,
+// or returns "" if the employee is available
+func (c *Calendar) UnavailableReason(at time.Time, companyLocation *time.Location) string {
+	for _, b := range c.Blackouts {
+		if !at.Before(b.Start) && at.Before(b.End) {
+			if b.Reason != "" {
+				return "blackout: " + b.Reason
+			}
+			return "blackout period"
+		}
+	}
+
+	if len(c.Windows) == 0 {
+		return ""
+	}
+
+	local := at.In(c.Location(companyLocation))
+	for _, w := range c.Windows {
+		if w.contains(local) {
+			return ""
+		}
+	}
+	return "outside working hours"
+}
+
+// IsAvailableAt checks if the employee is available at the given time
+func (c *Calendar) IsAvailableAt(at time.Time, companyLocation *time.Location) bool {
+	return c.UnavailableReason(at, companyLocation) == ""
+}
+
+// contains checks if a local time falls within the window
+func (w AvailabilityWindow) contains(local time.Time) bool {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false
+	}
+
+	minute := local.Hour()*60 + local.Minute()
+	if start < end {
+		return w.onDay(local.Weekday()) && minute >= start && minute < end
+	}
+
+	// Overnight window: the evening part belongs to today, the morning part to yesterday
+	yesterday := (local.Weekday() + 6) % 7
+	return (w.onDay(local.Weekday()) && minute >= start) || (w.onDay(yesterday) && minute < end)
+}
+
+// onDay checks if the window opens on the given weekday
+func (w AvailabilityWindow) onDay(day time.Weekday) bool {
+	if len(w.Weekdays) == 0 {
+		return true
+	}
+	for _, d := range w.Weekdays {
+		if d == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses HH:MM into minutes after midnight
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,87 @@
+package employee
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalendar_NoWindowsAlwaysAvailable(t *testing.T) {
+	cal := NewCalendar(uuid.New(), uuid.New())
+	assert.True(t, cal.IsAvailableAt(time.Now(), time.UTC))
+}
+
+func TestCalendar_DayWindow(t *testing.T) {
+	cal := NewCalendar(uuid.New(), uuid.New())
+	cal.Windows = []AvailabilityWindow{{
+		Weekdays: []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		Start:    "09:00",
+		End:      "18:00",
+	}}
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+
+	// Monday 2024-01-08
+	assert.True(t, cal.IsAvailableAt(time.Date(2024, 1, 8, 9, 0, 0, 0, shanghai), shanghai))
+	assert.False(t, cal.IsAvailableAt(time.Date(2024, 1, 8, 18, 0, 0, 0, shanghai), shanghai))
+	assert.False(t, cal.IsAvailableAt(time.Date(2024, 1, 6, 10, 0, 0, 0, shanghai), shanghai), "saturday")
+
+	// 02:00 UTC is 10:00 in Shanghai
+	assert.True(t, cal.IsAvailableAt(time.Date(2024, 1, 8, 2, 0, 0, 0, time.UTC), shanghai))
+	assert.Equal(t, "outside working hours", cal.UnavailableReason(time.Date(2024, 1, 8, 2, 0, 0, 0, time.UTC), time.UTC))
+}
+
+func TestCalendar_NightShift(t *testing.T) {
+	cal := NewCalendar(uuid.New(), uuid.New())
+	cal.Windows = []AvailabilityWindow{{
+		Weekdays: []time.Weekday{time.Friday},
+		Start:    "22:00",
+		End:      "06:00",
+	}}
+
+	// Friday 2024-01-12 23:00 and Saturday 05:59 belong to the Friday shift
+	assert.True(t, cal.IsAvailableAt(time.Date(2024, 1, 12, 23, 0, 0, 0, time.UTC), time.UTC))
+	assert.True(t, cal.IsAvailableAt(time.Date(2024, 1, 13, 5, 59, 0, 0, time.UTC), time.UTC))
+	assert.False(t, cal.IsAvailableAt(time.Date(2024, 1, 13, 6, 0, 0, 0, time.UTC), time.UTC))
+	assert.False(t, cal.IsAvailableAt(time.Date(2024, 1, 12, 5, 0, 0, 0, time.UTC), time.UTC), "thursday's night is not covered")
+}
+
+func TestCalendar_Blackout(t *testing.T) {
+	cal := NewCalendar(uuid.New(), uuid.New())
+	start := time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC)
+	cal.Blackouts = []Blackout{{Start: start, End: start.Add(24 * time.Hour), Reason: "春节"}}
+
+	assert.Equal(t, "blackout: 春节", cal.UnavailableReason(start.Add(time.Hour), time.UTC))
+	assert.True(t, cal.IsAvailableAt(start.Add(24*time.Hour), time.UTC))
+}
+
+func TestCalendar_TimezoneOverride(t *testing.T) {
+	cal := NewCalendar(uuid.New(), uuid.New())
+	cal.Timezone = "America/New_York"
+	cal.Windows = []AvailabilityWindow{{Start: "09:00", End: "17:00"}}
+
+	// 14:00 UTC is 09:00 in New York (EST)
+	assert.True(t, cal.IsAvailableAt(time.Date(2024, 1, 8, 14, 0, 0, 0, time.UTC), time.UTC))
+}
+
+func TestCalendar_Validate(t *testing.T) {
+	cal := NewCalendar(uuid.New(), uuid.New())
+	cal.Windows = []AvailabilityWindow{{Start: "09:00", End: "18:00"}}
+	assert.NoError(t, cal.Validate())
+
+	cal.Windows = []AvailabilityWindow{{Start: "9am", End: "18:00"}}
+	assert.Error(t, cal.Validate())
+
+	cal.Windows = []AvailabilityWindow{{Weekdays: []time.Weekday{7}, Start: "09:00", End: "18:00"}}
+	assert.Error(t, cal.Validate())
+
+	cal.Windows = nil
+	cal.Timezone = "Mars/Olympus"
+	assert.Error(t, cal.Validate())
+
+	now := time.Now()
+	cal.Timezone = ""
+	cal.Blackouts = []Blackout{{Start: now, End: now}}
+	assert.Error(t, cal.Validate())
+}
@@ -43,6 +43,33 @@ type EmployeeSkill struct {
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
 
+// Assignment tracks a task actively held by an employee
+type Assignment struct {
+	ID         uuid.UUID       `json:"id" db:"id"`
+	CompanyID  uuid.UUID       `json:"company_id" db:"company_id"`
+	EmployeeID uuid.UUID       `json:"employee_id" db:"employee_id"`
+	TaskID     uuid.UUID       `json:"task_id" db:"task_id"`
+	Decision   json.RawMessage `json:"decision,omitempty" db:"decision"`
+	AssignedAt time.Time       `json:"assigned_at" db:"assigned_at"`
+	ReleasedAt *time.Time      `json:"released_at,omitempty" db:"released_at"`
+}
+
+// NewAssignment creates a new active assignment
+func NewAssignment(companyID, employeeID, taskID uuid.UUID) *Assignment {
+	return &Assignment{
+		ID:         uuid.New(),
+		CompanyID:  companyID,
+		EmployeeID: employeeID,
+		TaskID:     taskID,
+		AssignedAt: time.Now(),
+	}
+}
+
+// IsActive returns true if the assignment has not been released
+func (a *Assignment) IsActive() bool {
+	return a.ReleasedAt == nil
+}
+
 // NewEmployee creates a new employee
 func NewEmployee(companyID uuid.UUID, name, role string) *Employee {
 	return &Employee{
@@ -102,6 +129,40 @@ func (e *Employee) CompleteTask(success bool) {
 	e.UpdatedAt = time.Now()
 }
 
+// ReleaseTask records the result of a finished task and keeps the employee
+// working while other assignments are still active
+func (e *Employee) ReleaseTask(success bool, remaining []uuid.UUID) {
+	e.CompleteTask(success)
+	if len(remaining) > 0 {
+		current := remaining[0]
+		e.CurrentTaskID = &current
+		e.Status = StatusWorking
+	}
+}
+
+// MaxConcurrentTasks returns the concurrency override from settings, or 0 if unset
+func (e *Employee) MaxConcurrentTasks() int {
+	if len(e.Settings) == 0 {
+		return 0
+	}
+
+	var settings struct {
+		MaxConcurrentTasks int `json:"max_concurrent_tasks"`
+	}
+	if err := json.Unmarshal(e.Settings, &settings); err != nil || settings.MaxConcurrentTasks < 0 {
+		return 0
+	}
+	return settings.MaxConcurrentTasks
+}
+
+// HasCapacity checks if the employee can take another task
+func (e *Employee) HasCapacity(active, capacity int) bool {
+	if e.Status != StatusIdle && e.Status != StatusWorking {
+		return false
+	}
+	return active < capacity
+}
+
 // SetOffline sets the employee to offline status
 func (e *Employee) SetOffline() {
 	e.Status = StatusOffline
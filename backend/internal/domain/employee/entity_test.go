@@ -188,3 +188,73 @@ func TestStatus_Constants(t *testing.T) {
 	assert.Equal(t, Status("offline"), StatusOffline)
 	assert.Equal(t, Status("error"), StatusError)
 }
+
+func TestEmployee_ReleaseTask(t *testing.T) {
+	employee := NewEmployee(uuid.New(), "Test", "Role")
+	first, second := uuid.New(), uuid.New()
+	employee.AssignTask(first)
+	employee.AssignTask(second)
+
+	// Another assignment is still active
+	employee.ReleaseTask(true, []uuid.UUID{second})
+	assert.Equal(t, StatusWorking, employee.Status)
+	require.NotNil(t, employee.CurrentTaskID)
+	assert.Equal(t, second, *employee.CurrentTaskID)
+	assert.Equal(t, 1, employee.TotalTasks)
+
+	// Last assignment released
+	employee.ReleaseTask(true, nil)
+	assert.Equal(t, StatusIdle, employee.Status)
+	assert.Nil(t, employee.CurrentTaskID)
+	assert.Equal(t, 2, employee.TotalTasks)
+}
+
+func TestEmployee_MaxConcurrentTasks(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings string
+		expected int
+	}{
+		{"unset", `{}`, 0},
+		{"configured", `{"max_concurrent_tasks": 4}`, 4},
+		{"negative", `{"max_concurrent_tasks": -1}`, 0},
+		{"invalid json", `not json`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			employee := NewEmployee(uuid.New(), "Test", "Role")
+			employee.Settings = []byte(tt.settings)
+			assert.Equal(t, tt.expected, employee.MaxConcurrentTasks())
+		})
+	}
+}
+
+func TestEmployee_HasCapacity(t *testing.T) {
+	employee := NewEmployee(uuid.New(), "Test", "Role")
+
+	assert.True(t, employee.HasCapacity(0, 1))
+	assert.False(t, employee.HasCapacity(1, 1))
+
+	employee.SetStatus(StatusWorking)
+	assert.True(t, employee.HasCapacity(2, 3))
+
+	employee.SetStatus(StatusOffline)
+	assert.False(t, employee.HasCapacity(0, 3))
+
+	employee.SetStatus(StatusError)
+	assert.False(t, employee.HasCapacity(0, 3))
+}
+
+func TestNewAssignment(t *testing.T) {
+	companyID, employeeID, taskID := uuid.New(), uuid.New(), uuid.New()
+
+	assignment := NewAssignment(companyID, employeeID, taskID)
+
+	require.NotNil(t, assignment)
+	assert.NotEqual(t, uuid.Nil, assignment.ID)
+	assert.Equal(t, companyID, assignment.CompanyID)
+	assert.Equal(t, employeeID, assignment.EmployeeID)
+	assert.Equal(t, taskID, assignment.TaskID)
+	assert.True(t, assignment.IsActive())
+}
@@ -40,4 +40,47 @@ type Repository interface {
 
 	// CountByCompany counts the number of employees for a company
 	CountByCompany(ctx context.Context, companyID uuid.UUID) (int, error)
+
+	// GetSchedulable retrieves employees that are idle or working for a company
+	GetSchedulable(ctx context.Context, companyID uuid.UUID) ([]*Employee, error)
+
+	// LockSchedulable locks the idle or working employees of a company that are not
+	// locked by another transaction (SELECT ... FOR UPDATE SKIP LOCKED)
+	LockSchedulable(ctx context.Context, companyID uuid.UUID) ([]*Employee, error)
+
+	// LockByID retrieves an employee by ID and locks it until the transaction ends
+	LockByID(ctx context.Context, id uuid.UUID) (*Employee, error)
+
+	// CreateAssignment records a task assigned to an employee
+	CreateAssignment(ctx context.Context, assignment *Assignment) error
+
+	// ReleaseAssignment marks the active assignment of a task as released
+	ReleaseAssignment(ctx context.Context, employeeID, taskID uuid.UUID) error
+
+	// GetActiveAssignments retrieves the active assignments of an employee
+	GetActiveAssignments(ctx context.Context, employeeID uuid.UUID) ([]*Assignment, error)
+
+	// GetLatestAssignmentByTask retrieves the most recent assignment of a task
+	GetLatestAssignmentByTask(ctx context.Context, taskID uuid.UUID) (*Assignment, error)
+
+	// GetLatestAssignmentByCompany retrieves the most recent assignment in a company
+	GetLatestAssignmentByCompany(ctx context.Context, companyID uuid.UUID) (*Assignment, error)
+
+	// CountActiveAssignments counts active assignments per employee for a company
+	CountActiveAssignments(ctx context.Context, companyID uuid.UUID) (map[uuid.UUID]int, error)
+}
+
+// CalendarRepository defines the interface for employee calendar data access
+type CalendarRepository interface {
+	// GetCalendar retrieves the calendar of an employee
+	GetCalendar(ctx context.Context, employeeID uuid.UUID) (*Calendar, error)
+
+	// SaveCalendar creates or replaces the calendar of an employee
+	SaveCalendar(ctx context.Context, calendar *Calendar) error
+
+	// ListCalendarsByCompany retrieves the calendars of a company
+	ListCalendarsByCompany(ctx context.Context, companyID uuid.UUID) ([]*Calendar, error)
+
+	// ListCalendars retrieves all calendars
+	ListCalendars(ctx context.Context) ([]*Calendar, error)
 }
@@ -0,0 +1,108 @@
+package hotspot
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Trends of a topic against the previous snapshot
+const (
+	TrendNew     = "new"     // not in the previous snapshot
+	TrendRising  = "rising"  // at least 10% hotter
+	TrendStable  = "stable"  // within 10%
+	TrendFalling = "falling" // at least 10% cooler
+)
+
+// Source fetches the current items of one hotspot feed, e.g. an RSS feed,
+// a JSON endpoint or an uploaded CSV
+type Source interface {
+	// Name identifies the source in topics and errors
+	Name() string
+	// Fetch returns the source's items, hottest first when it has no heat values
+	Fetch(ctx context.Context) ([]Item, error)
+}
+
+// Item is one entry a source returned
+type Item struct {
+	Title       string     `json:"title"`
+	URL         string     `json:"url,omitempty"`
+	Summary     string     `json:"summary,omitempty"`
+	Heat        float64    `json:"heat,omitempty"`
+	PublishedAt *time.Time `json:"published_at,omitempty"`
+	Source      string     `json:"source"`
+}
+
+// Topic is a de-duplicated hotspot scored for heat and trend
+type Topic struct {
+	Key     string   `json:"key"`
+	Title   string   `json:"title"`
+	URL     string   `json:"url,omitempty"`
+	Summary string   `json:"summary,omitempty"`
+	Sources []string `json:"sources"`
+	Heat    int      `json:"heat"`
+	Trend   string   `json:"trend"`
+	// Delta is the change in heat since the previous snapshot
+	Delta int `json:"delta"`
+}
+
+// Snapshot is the scored topics of a company's platform and category at
+// one point in time; the next snapshot's trends are measured against it
+type Snapshot struct {
+	ID         uuid.UUID `json:"id"`
+	CompanyID  uuid.UUID `json:"company_id"`
+	Platform   string    `json:"platform"`
+	Category   string    `json:"category"`
+	Topics     []Topic   `json:"topics"`
+	CapturedAt time.Time `json:"captured_at"`
+}
+
+// NewSnapshot creates a snapshot of the given topics
+func NewSnapshot(companyID uuid.UUID, platform, category string, topics []Topic) *Snapshot {
+	return &Snapshot{
+		ID:         uuid.New(),
+		CompanyID:  companyID,
+		Platform:   platform,
+		Category:   category,
+		Topics:     topics,
+		CapturedAt: time.Now(),
+	}
+}
+
+// Topic returns the topic with the given key
+func (s *Snapshot) Topic(key string) (Topic, bool) {
+	for _, t := range s.Topics {
+		if t.Key == key {
+			return t, true
+		}
+	}
+	return Topic{}, false
+}
+
+// Upload is a CSV of hotspots a company uploaded for a platform. The latest
+// upload is one of the platform's sources.
+type Upload struct {
+	ID         uuid.UUID  `json:"id"`
+	CompanyID  uuid.UUID  `json:"company_id"`
+	Platform   string     `json:"platform"`
+	Filename   string     `json:"filename"`
+	Content    []byte     `json:"-"`
+	ItemCount  int        `json:"item_count"`
+	UploadedBy *uuid.UUID `json:"uploaded_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// NewUpload creates an upload of a CSV holding the given number of items
+func NewUpload(companyID uuid.UUID, platform, filename string, content []byte, itemCount int, uploadedBy *uuid.UUID) *Upload {
+	return &Upload{
+		ID:         uuid.New(),
+		CompanyID:  companyID,
+		Platform:   platform,
+		Filename:   filename,
+		Content:    content,
+		ItemCount:  itemCount,
+		UploadedBy: uploadedBy,
+		CreatedAt:  time.Now(),
+	}
+}
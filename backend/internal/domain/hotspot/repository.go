@@ -0,0 +1,22 @@
+package hotspot
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Repository stores hotspot snapshots and uploads
+type Repository interface {
+	// CreateSnapshot stores a snapshot
+	CreateSnapshot(ctx context.Context, snapshot *Snapshot) error
+	// GetLatestSnapshot returns the newest snapshot of a company's platform and category;
+	// returns errors.ErrNotFound if there is none
+	GetLatestSnapshot(ctx context.Context, companyID uuid.UUID, platform, category string) (*Snapshot, error)
+
+	// CreateUpload stores an uploaded CSV
+	CreateUpload(ctx context.Context, upload *Upload) error
+	// GetLatestUpload returns the newest upload of a company's platform;
+	// returns errors.ErrNotFound if there is none
+	GetLatestUpload(ctx context.Context, companyID uuid.UUID, platform string) (*Upload, error)
+}
@@ -0,0 +1,118 @@
+package hotspot
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Scoring parameters
+const (
+	// Items without a heat value score by rank: the first rankedHeat, each
+	// next one rankedStep less, down to rankedFloor
+	rankedHeat  = 1000
+	rankedStep  = 50
+	rankedFloor = 100
+	// sourceBonus is the share of heat a topic gains for every other source reporting it
+	sourceBonus = 0.2
+	// trendThreshold is the relative change in heat that makes a topic rising or falling
+	trendThreshold = 0.1
+)
+
+// Key normalizes a title so the same topic from different sources matches:
+// letters and digits only, lower-cased
+func Key(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Merge de-duplicates the items of several sources into topics. Items keep
+// the order of their source, which ranks those without a heat value. A topic
+// takes the heat of its hottest item plus a bonus for every other source
+// reporting it, and the title, URL and summary of its first item.
+func Merge(items []Item) []Topic {
+	ranks := make(map[string]int)
+	topics := make(map[string]*Topic)
+	heats := make(map[string]float64)
+	var order []string
+
+	for _, item := range items {
+		rank := ranks[item.Source]
+		ranks[item.Source]++
+		key := Key(item.Title)
+		if key == "" {
+			continue
+		}
+
+		heat := item.Heat
+		if heat <= 0 {
+			heat = math.Max(rankedFloor, rankedHeat-float64(rank*rankedStep))
+		}
+		t, ok := topics[key]
+		if !ok {
+			t = &Topic{Key: key, Title: strings.TrimSpace(item.Title), URL: item.URL, Summary: item.Summary}
+			topics[key] = t
+			order = append(order, key)
+		}
+		if !containsString(t.Sources, item.Source) {
+			t.Sources = append(t.Sources, item.Source)
+		}
+		heats[key] = math.Max(heats[key], heat)
+	}
+
+	merged := make([]Topic, 0, len(order))
+	for _, key := range order {
+		t := topics[key]
+		t.Heat = int(math.Round(heats[key] * (1 + sourceBonus*float64(len(t.Sources)-1))))
+		merged = append(merged, *t)
+	}
+	return merged
+}
+
+// Score sets the trend of every topic against the previous snapshot, which
+// may be nil, and returns at most limit topics, hottest first
+func Score(topics []Topic, previous *Snapshot, limit int) []Topic {
+	for i := range topics {
+		t := &topics[i]
+		before, ok := Topic{}, false
+		if previous != nil {
+			before, ok = previous.Topic(t.Key)
+		}
+		if !ok {
+			t.Trend = TrendNew
+			t.Delta = t.Heat
+			continue
+		}
+		t.Delta = t.Heat - before.Heat
+		change := float64(t.Delta) / math.Max(1, float64(before.Heat))
+		switch {
+		case change >= trendThreshold:
+			t.Trend = TrendRising
+		case change <= -trendThreshold:
+			t.Trend = TrendFalling
+		default:
+			t.Trend = TrendStable
+		}
+	}
+
+	sort.SliceStable(topics, func(i, j int) bool { return topics[i].Heat > topics[j].Heat })
+	if limit > 0 && len(topics) > limit {
+		topics = topics[:limit]
+	}
+	return topics
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
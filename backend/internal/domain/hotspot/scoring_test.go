@@ -0,0 +1,59 @@
+package hotspot
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKey(t *testing.T) {
+	assert.Equal(t, "ai工具推荐2024", Key(" AI 工具推荐，2024! "))
+	assert.Equal(t, Key("Go 1.23 released"), Key("go 1.23 Released."))
+	assert.Empty(t, Key("!!!"))
+}
+
+func TestMerge(t *testing.T) {
+	topics := Merge([]Item{
+		{Title: "AI 工具", URL: "https://a.example.com/1", Source: "feed"},
+		{Title: "数字化转型", Source: "feed"},
+		{Title: "ai工具!", Source: "api", Heat: 1500},
+		{Title: "效率提升", Source: "api", Heat: 300},
+		{Title: "AI 工具", Source: "feed"},
+		{Title: "???", Source: "api", Heat: 9000},
+	})
+
+	require.Len(t, topics, 3)
+	assert.Equal(t, "AI 工具", topics[0].Title, "the first item names the topic")
+	assert.Equal(t, "https://a.example.com/1", topics[0].URL)
+	assert.Equal(t, []string{"feed", "api"}, topics[0].Sources)
+	assert.Equal(t, 1800, topics[0].Heat, "the hottest item plus a fifth for the second source")
+	assert.Equal(t, 950, topics[1].Heat, "ranked second in its feed")
+	assert.Equal(t, 300, topics[2].Heat)
+}
+
+func TestScore(t *testing.T) {
+	previous := &Snapshot{Topics: []Topic{
+		{Key: "rising", Heat: 500},
+		{Key: "steady", Heat: 1000},
+		{Key: "cooling", Heat: 2000},
+	}}
+	topics := Score([]Topic{
+		{Key: "steady", Heat: 1050},
+		{Key: "rising", Heat: 800},
+		{Key: "cooling", Heat: 900},
+		{Key: "fresh", Heat: 1200},
+	}, previous, 3)
+
+	require.Len(t, topics, 3)
+	assert.Equal(t, []string{"fresh", "steady", "cooling"}, []string{topics[0].Key, topics[1].Key, topics[2].Key})
+	assert.Equal(t, TrendNew, topics[0].Trend)
+	assert.Equal(t, 1200, topics[0].Delta)
+	assert.Equal(t, TrendStable, topics[1].Trend)
+	assert.Equal(t, TrendFalling, topics[2].Trend)
+	assert.Equal(t, -1100, topics[2].Delta)
+
+	first := Score([]Topic{{Key: "rising", Heat: 800}}, nil, 0)
+	assert.Equal(t, TrendNew, first[0].Trend, "everything is new without a previous snapshot")
+	assert.Equal(t, TrendRising, Score([]Topic{{Key: "rising", Heat: 800}}, previous, 0)[0].Trend)
+}
@@ -0,0 +1,57 @@
+package publishing
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Constraints are the formats a platform accepts; zero limits are not checked
+type Constraints struct {
+	// RequireTitle rejects posts without a title
+	RequireTitle bool `json:"require_title,omitempty"`
+	// MaxTitleLength and MaxBodyLength count characters, not bytes
+	MaxTitleLength int `json:"max_title_length,omitempty"`
+	MaxBodyLength  int `json:"max_body_length,omitempty"`
+	MaxTags        int `json:"max_tags,omitempty"`
+	MinImages      int `json:"min_images,omitempty"`
+	MaxImages      int `json:"max_images,omitempty"`
+}
+
+// PlatformConstraints are the constraints of the platforms adapters know
+var PlatformConstraints = map[string]Constraints{
+	// 小红书图文笔记：标题 20 字，正文 1000 字，至少一张图
+	"xiaohongshu": {RequireTitle: true, MaxTitleLength: 20, MaxBodyLength: 1000, MaxTags: 10, MinImages: 1, MaxImages: 18},
+	// 抖音图文：标题 20 字，话题最多 5 个
+	"douyin": {MaxTitleLength: 20, MaxBodyLength: 1000, MaxTags: 5, MinImages: 1, MaxImages: 35},
+	// 微博：没有标题，正文 2000 字，最多 9 张图
+	"weibo": {MaxBodyLength: 2000, MaxImages: 9},
+	// 微信公众号图文：标题 64 字，需要封面图
+	"wechat_mp": {RequireTitle: true, MaxTitleLength: 64, MaxBodyLength: 20000, MinImages: 1},
+}
+
+// Check returns what about a post the platform does not accept
+func (c Constraints) Check(post *Post) []string {
+	var problems []string
+	if c.RequireTitle && post.Title == "" {
+		problems = append(problems, "a title is required")
+	}
+	if n := utf8.RuneCountInString(post.Title); c.MaxTitleLength > 0 && n > c.MaxTitleLength {
+		problems = append(problems, fmt.Sprintf("the title has %d characters, at most %d are allowed", n, c.MaxTitleLength))
+	}
+	if post.Body == "" && post.Title == "" {
+		problems = append(problems, "the post is empty")
+	}
+	if n := utf8.RuneCountInString(post.Body); c.MaxBodyLength > 0 && n > c.MaxBodyLength {
+		problems = append(problems, fmt.Sprintf("the body has %d characters, at most %d are allowed", n, c.MaxBodyLength))
+	}
+	if c.MaxTags > 0 && len(post.Tags) > c.MaxTags {
+		problems = append(problems, fmt.Sprintf("the post has %d tags, at most %d are allowed", len(post.Tags), c.MaxTags))
+	}
+	if len(post.Images) < c.MinImages {
+		problems = append(problems, fmt.Sprintf("the post has %d images, at least %d are required", len(post.Images), c.MinImages))
+	}
+	if c.MaxImages > 0 && len(post.Images) > c.MaxImages {
+		problems = append(problems, fmt.Sprintf("the post has %d images, at most %d are allowed", len(post.Images), c.MaxImages))
+	}
+	return problems
+}
@@ -0,0 +1,57 @@
+package publishing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConstraints_Check(t *testing.T) {
+	xiaohongshu := PlatformConstraints["xiaohongshu"]
+	tests := []struct {
+		name string
+		post Post
+		want []string
+	}{
+		{
+			name: "fits",
+			post: Post{Title: "五个效率工具", Body: "正文", Tags: []string{"效率"}, Images: []string{"a.png"}},
+		},
+		{
+			name: "title counted in characters",
+			post: Post{Title: strings.Repeat("字", 21), Body: "正文", Images: []string{"a.png"}},
+			want: []string{"the title has 21 characters, at most 20 are allowed"},
+		},
+		{
+			name: "missing title and images",
+			post: Post{Body: "正文"},
+			want: []string{"a title is required", "the post has 0 images, at least 1 are required"},
+		},
+		{
+			name: "too many tags",
+			post: Post{Title: "标题", Tags: strings.Split("a,b,c,d,e,f,g,h,i,j,k", ","), Images: []string{"a.png"}},
+			want: []string{"the post has 11 tags, at most 10 are allowed"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, xiaohongshu.Check(&tt.post))
+		})
+	}
+}
+
+func TestPostFromContent(t *testing.T) {
+	post := PostFromContent(map[string]interface{}{
+		"title":      " 标题 ",
+		"content":    "正文",
+		"tags":       []interface{}{"AI", "", "效率"},
+		"image_urls": []interface{}{"https://cdn.example.com/a.png"},
+	})
+	assert.Equal(t, &Post{
+		Title:  "标题",
+		Body:   "正文",
+		Tags:   []string{"AI", "效率"},
+		Images: []string{"https://cdn.example.com/a.png"},
+	}, post)
+}
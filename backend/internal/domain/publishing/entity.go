@@ -0,0 +1,149 @@
+package publishing
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Mode is whether a post goes live or is saved as a draft on the platform
+type Mode string
+
+const (
+	ModePublish Mode = "publish"
+	ModeDraft   Mode = "draft"
+)
+
+// IsValid checks if the mode is known
+func (m Mode) IsValid() bool {
+	return m == ModePublish || m == ModeDraft
+}
+
+// Post is the content published to a platform
+type Post struct {
+	Title  string   `json:"title"`
+	Body   string   `json:"body"`
+	Tags   []string `json:"tags,omitempty"`
+	Images []string `json:"images,omitempty"`
+}
+
+// PostFromContent reads a post from the output of a content skill: title,
+// content or body, tags and images or image_urls
+func PostFromContent(content map[string]interface{}) *Post {
+	post := &Post{Title: stringValue(content["title"])}
+	for _, key := range []string{"content", "body", "text"} {
+		if body := stringValue(content[key]); body != "" {
+			post.Body = body
+			break
+		}
+	}
+	post.Tags = stringList(content["tags"])
+	post.Images = stringList(content["images"])
+	if len(post.Images) == 0 {
+		post.Images = stringList(content["image_urls"])
+	}
+	return post
+}
+
+// Result is a post a platform accepted
+type Result struct {
+	Platform string `json:"platform"`
+	PostID   string `json:"post_id"`
+	URL      string `json:"url,omitempty"`
+	Mode     Mode   `json:"mode"`
+}
+
+// Credentials are what a company uses to publish to a platform, e.g. a
+// token, an app secret or its own webhook URL
+type Credentials struct {
+	ID        uuid.UUID         `json:"id"`
+	CompanyID uuid.UUID         `json:"company_id"`
+	Platform  string            `json:"platform"`
+	Values    map[string]string `json:"-"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// NewCredentials creates the credentials of a company for a platform
+func NewCredentials(companyID uuid.UUID, platform string, values map[string]string) *Credentials {
+	now := time.Now()
+	return &Credentials{
+		ID:        uuid.New(),
+		CompanyID: companyID,
+		Platform:  platform,
+		Values:    values,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Keys returns the names of the stored values, sorted, without the secrets
+func (c *Credentials) Keys() []string {
+	keys := make([]string, 0, len(c.Values))
+	for k := range c.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Get returns a stored value; missing credentials have none
+func (c *Credentials) Get(key string) string {
+	if c == nil {
+		return ""
+	}
+	return c.Values[key]
+}
+
+// Publisher publishes posts to one platform. Adapters check the platform's
+// format constraints before anything is sent.
+type Publisher interface {
+	// Platform is the name posts are published under, e.g. xiaohongshu
+	Platform() string
+	// Constraints are the formats the platform accepts
+	Constraints() Constraints
+	// Publish publishes a post or saves it as a draft; creds may be nil
+	Publish(ctx context.Context, creds *Credentials, post *Post, mode Mode) (*Result, error)
+	// Unpublish takes down a published post or deletes a draft
+	Unpublish(ctx context.Context, creds *Credentials, postID string) error
+}
+
+// ConstraintError is content a platform does not accept
+type ConstraintError struct {
+	Platform string
+	Problems []string
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("content does not fit %s: %s", e.Platform, strings.Join(e.Problems, "; "))
+}
+
+// stringValue returns v if it is a string
+func stringValue(v interface{}) string {
+	s, _ := v.(string)
+	return strings.TrimSpace(s)
+}
+
+// stringList returns the non-empty strings of a list, which is decoded JSON or a []string
+func stringList(v interface{}) []string {
+	var values []string
+	switch list := v.(type) {
+	case []string:
+		values = list
+	case []interface{}:
+		for _, item := range list {
+			values = append(values, stringValue(item))
+		}
+	}
+	var out []string
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value != "" {
+			out = append(out, value)
+		}
+	}
+	return out
+}
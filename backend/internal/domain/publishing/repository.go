@@ -0,0 +1,21 @@
+package publishing
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// CredentialRepository stores the publishing credentials of companies
+type CredentialRepository interface {
+	// SaveCredentials creates or replaces a company's credentials for a platform
+	SaveCredentials(ctx context.Context, creds *Credentials) error
+	// GetCredentials returns a company's credentials for a platform;
+	// returns errors.ErrNotFound if there are none
+	GetCredentials(ctx context.Context, companyID uuid.UUID, platform string) (*Credentials, error)
+	// ListCredentials lists a company's credentials ordered by platform
+	ListCredentials(ctx context.Context, companyID uuid.UUID) ([]*Credentials, error)
+	// DeleteCredentials removes a company's credentials for a platform;
+	// returns errors.ErrNotFound if there are none
+	DeleteCredentials(ctx context.Context, companyID uuid.UUID, platform string) error
+}
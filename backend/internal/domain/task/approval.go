@@ -0,0 +1,122 @@
+package task
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ApprovalAction is a reviewer's decision on content waiting for approval
+type ApprovalAction string
+
+const (
+	ApprovalApprove ApprovalAction = "approve"
+	ApprovalReject  ApprovalAction = "reject"
+	// ApprovalEdit approves the content with the reviewer's changes
+	ApprovalEdit ApprovalAction = "edit"
+)
+
+// IsValid checks if the action is known
+func (a ApprovalAction) IsValid() bool {
+	switch a {
+	case ApprovalApprove, ApprovalReject, ApprovalEdit:
+		return true
+	}
+	return false
+}
+
+// ApprovalDecision is a reviewer's decision on the approval a workflow node waits for
+type ApprovalDecision struct {
+	NodeID string
+	Action ApprovalAction
+	// Content holds the reviewer's changes of an edit decision
+	Content   map[string]interface{}
+	DecidedBy uuid.UUID
+	Comment   string
+}
+
+// ApprovalStatus is the state of an approval
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+	ApprovalEdited   ApprovalStatus = "edited"
+)
+
+// Approval is a human check of a workflow step's content. The workflow waits
+// until a reviewer decides, or applies the default action once it expires.
+type Approval struct {
+	ID        uuid.UUID      `json:"id" db:"id"`
+	TaskID    uuid.UUID      `json:"task_id" db:"task_id"`
+	CompanyID uuid.UUID      `json:"company_id" db:"company_id"`
+	NodeID    string         `json:"node_id" db:"node_id"`
+	NodeName  string         `json:"node_name" db:"node_name"`
+	Status    ApprovalStatus `json:"status" db:"status"`
+	// Content is what the reviewer is asked to approve
+	Content map[string]interface{} `json:"content"`
+	// EditedContent holds the reviewer's changes of an edit decision
+	EditedContent map[string]interface{} `json:"edited_content,omitempty"`
+	DefaultAction ApprovalAction         `json:"default_action" db:"default_action"`
+	// DecidedBy is the reviewer; nil when the default action was applied
+	DecidedBy *uuid.UUID `json:"decided_by,omitempty" db:"decided_by"`
+	Comment   string     `json:"comment,omitempty" db:"comment"`
+	TimedOut  bool       `json:"timed_out" db:"timed_out"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	DecidedAt *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// NewApproval creates a pending approval of a workflow node's content
+func NewApproval(companyID, taskID uuid.UUID, nodeID, nodeName string, content map[string]interface{}, defaultAction ApprovalAction, expiresAt time.Time) *Approval {
+	return &Approval{
+		ID:            uuid.New(),
+		TaskID:        taskID,
+		CompanyID:     companyID,
+		NodeID:        nodeID,
+		NodeName:      nodeName,
+		Status:        ApprovalPending,
+		Content:       content,
+		DefaultAction: defaultAction,
+		ExpiresAt:     expiresAt,
+		CreatedAt:     time.Now(),
+	}
+}
+
+// IsPending checks if the approval still waits for a decision
+func (a *Approval) IsPending() bool {
+	return a.Status == ApprovalPending
+}
+
+// Decide records a decision. A nil reviewer means the approval expired and
+// the default action was applied.
+func (a *Approval) Decide(action ApprovalAction, by *uuid.UUID, edited map[string]interface{}, comment string) {
+	now := time.Now()
+	switch action {
+	case ApprovalReject:
+		a.Status = ApprovalRejected
+	case ApprovalEdit:
+		a.Status = ApprovalEdited
+		a.EditedContent = edited
+	default:
+		a.Status = ApprovalApproved
+	}
+	a.DecidedBy = by
+	a.Comment = comment
+	a.TimedOut = by == nil
+	a.DecidedAt = &now
+}
+
+// ApprovedContent returns the content that continues through the workflow:
+// the reviewed content with the reviewer's edits applied
+func ApprovedContent(content, edited map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(content)+len(edited))
+	for k, v := range content {
+		merged[k] = v
+	}
+	for k, v := range edited {
+		merged[k] = v
+	}
+	return merged
+}
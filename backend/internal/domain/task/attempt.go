@@ -0,0 +1,92 @@
+package task
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// RunOutcome is how the steps of a task's workflow run ended
+type RunOutcome struct {
+	// Outputs holds the output of every step that succeeded
+	Outputs map[string]map[string]interface{} `json:"outputs"`
+	// FailedStep is the step the run failed at, if any
+	FailedStep string `json:"failed_step,omitempty"`
+}
+
+// Attempt is an earlier run of a task, kept when the task is retried. The
+// next run reuses the outputs of its steps up to RetryFrom.
+type Attempt struct {
+	ID         uuid.UUID  `json:"id"`
+	TaskID     uuid.UUID  `json:"task_id"`
+	CompanyID  uuid.UUID  `json:"company_id"`
+	Number     int        `json:"number"`
+	WorkflowID string     `json:"workflow_id,omitempty"`
+	RunID      string     `json:"run_id,omitempty"`
+	Status     TaskStatus `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	FailedStep string     `json:"failed_step,omitempty"`
+	// StepOutputs are the outputs of the steps that succeeded
+	StepOutputs map[string]map[string]interface{} `json:"step_outputs"`
+	// RetryFrom is the step the next run starts over from; RetryInput, when
+	// set, is merged over that step's input
+	RetryFrom  string                 `json:"retry_from,omitempty"`
+	RetryInput map[string]interface{} `json:"retry_input,omitempty"`
+	RetriedBy  *uuid.UUID             `json:"retried_by,omitempty"`
+	StartedAt  *time.Time             `json:"started_at,omitempty"`
+	EndedAt    *time.Time             `json:"ended_at,omitempty"`
+	RetriedAt  time.Time              `json:"retried_at"`
+}
+
+// NewAttempt records how the current run of a failed task ended, before the
+// task is retried
+func NewAttempt(t *Task, number int, outcome *RunOutcome) *Attempt {
+	if outcome == nil {
+		outcome = &RunOutcome{}
+	}
+	outputs := outcome.Outputs
+	if outputs == nil {
+		outputs = make(map[string]map[string]interface{})
+	}
+	return &Attempt{
+		ID:          uuid.New(),
+		TaskID:      t.ID,
+		CompanyID:   t.CompanyID,
+		Number:      number,
+		WorkflowID:  t.TemporalWorkflowID,
+		RunID:       t.TemporalRunID,
+		Status:      t.Status,
+		Error:       t.ErrorMessage,
+		FailedStep:  outcome.FailedStep,
+		StepOutputs: outputs,
+		RetryFrom:   outcome.FailedStep,
+		StartedAt:   t.StartedAt,
+		EndedAt:     t.CompletedAt,
+		RetriedAt:   time.Now(),
+	}
+}
+
+// Resume is what a new run of a retried task takes over from the attempt before it
+type Resume struct {
+	// Attempt is the number of the new run; the first run of a task is attempt 1
+	Attempt int `json:"attempt"`
+	// Outputs are the outputs of steps the run does not execute again
+	Outputs map[string]map[string]interface{} `json:"outputs,omitempty"`
+	// StepInputs are merged over the input the given steps resolve
+	StepInputs map[string]map[string]interface{} `json:"step_inputs,omitempty"`
+}
+
+// Resume returns what the next run takes over: the outputs of every step
+// except those it runs again, and the edited input of the retried step
+func (a *Attempt) Resume(rerun map[string]bool) *Resume {
+	resume := &Resume{Attempt: a.Number + 1, Outputs: make(map[string]map[string]interface{})}
+	for id, output := range a.StepOutputs {
+		if !rerun[id] {
+			resume.Outputs[id] = output
+		}
+	}
+	if a.RetryFrom != "" && a.RetryInput != nil {
+		resume.StepInputs = map[string]map[string]interface{}{a.RetryFrom: a.RetryInput}
+	}
+	return resume
+}
@@ -0,0 +1,38 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAttempt_Resume(t *testing.T) {
+	failed := NewTask(uuid.New(), "Test", "Desc", PriorityMedium)
+	failed.Start(uuid.New())
+	failed.BindWorkflow("task-1", "run-1")
+	failed.Fail("node publish failed")
+
+	attempt := NewAttempt(failed, 1, &RunOutcome{
+		Outputs: map[string]map[string]interface{}{
+			"draft":  {"content": "text"},
+			"review": {"score": 0.9},
+		},
+		FailedStep: "publish",
+	})
+	assert.Equal(t, StatusFailed, attempt.Status)
+	assert.Equal(t, "run-1", attempt.RunID)
+	assert.Equal(t, "node publish failed", attempt.Error)
+	assert.Equal(t, "publish", attempt.RetryFrom, "the next run starts over from the failed step")
+
+	resume := attempt.Resume(map[string]bool{"publish": true})
+	assert.Equal(t, 2, resume.Attempt)
+	assert.Len(t, resume.Outputs, 2)
+	assert.Nil(t, resume.StepInputs)
+
+	attempt.RetryFrom = "review"
+	attempt.RetryInput = map[string]interface{}{"strict": true}
+	resume = attempt.Resume(map[string]bool{"review": true, "publish": true})
+	assert.Equal(t, map[string]map[string]interface{}{"draft": {"content": "text"}}, resume.Outputs)
+	assert.Equal(t, true, resume.StepInputs["review"]["strict"])
+}
@@ -0,0 +1,18 @@
+package task
+
+// Compensation is how undoing the completed steps of a failed or cancelled
+// task ended
+type Compensation struct {
+	// Compensated are the steps that were undone
+	Compensated []string `json:"compensated"`
+	// Failed are the steps whose compensation failed; their effects remain
+	Failed []CompensationFailure `json:"failed"`
+	// PartiallyCompensated is set when any compensation failed
+	PartiallyCompensated bool `json:"partially_compensated"`
+}
+
+// CompensationFailure is a step that could not be undone
+type CompensationFailure struct {
+	StepID string `json:"step_id"`
+	Error  string `json:"error"`
+}
@@ -36,9 +36,12 @@ type Task struct {
 	Progress           int                    `json:"progress"`
 	WorkflowDefinition map[string]interface{} `json:"workflow_definition"`
 	AssignedEmployeeID *uuid.UUID             `json:"assigned_employee_id"`
+	TemporalWorkflowID string                 `json:"temporal_workflow_id,omitempty"`
+	TemporalRunID      string                 `json:"temporal_run_id,omitempty"`
 	InputData          map[string]interface{} `json:"input_data"`
 	OutputData         map[string]interface{} `json:"output_data"`
 	ErrorMessage       string                 `json:"error_message"`
+	Compensation       *Compensation          `json:"compensation,omitempty"`
 	StartedAt          *time.Time             `json:"started_at"`
 	CompletedAt        *time.Time             `json:"completed_at"`
 	CreatedAt          time.Time              `json:"created_at"`
@@ -120,6 +123,37 @@ func (t *Task) Resume() {
 	t.UpdatedAt = time.Now()
 }
 
+// Requeue puts the task back into the pending queue, clearing its assignment
+func (t *Task) Requeue() {
+	t.Status = StatusPending
+	t.AssignedEmployeeID = nil
+	t.StartedAt = nil
+	t.Progress = 0
+	t.UpdatedAt = time.Now()
+}
+
+// Retry puts a failed task back into the pending queue for a new run,
+// clearing the result and the workflow run of the previous one
+func (t *Task) Retry() {
+	t.Requeue()
+	t.OutputData = nil
+	t.ErrorMessage = ""
+	t.Compensation = nil
+	t.CompletedAt = nil
+	t.BindWorkflow("", "")
+}
+
+// BindWorkflow records the workflow run executing the task
+func (t *Task) BindWorkflow(workflowID, runID string) {
+	t.TemporalWorkflowID = workflowID
+	t.TemporalRunID = runID
+}
+
+// HasWorkflow reports whether a workflow run executes the task
+func (t *Task) HasWorkflow() bool {
+	return t.TemporalWorkflowID != ""
+}
+
 // IsActive returns true if task is currently running
 func (t *Task) IsActive() bool {
 	return t.Status == StatusRunning
@@ -134,8 +168,13 @@ func (t *Task) CanTransitionTo(status TaskStatus) bool {
 		return status == StatusCompleted || status == StatusFailed ||
 			status == StatusPaused || status == StatusCancelled
 	case StatusPaused:
-		return status == StatusRunning || status == StatusCancelled
-	case StatusCompleted, StatusFailed, StatusCancelled:
+		// The step in flight when the task was paused may still end the workflow
+		return status == StatusRunning || status == StatusCancelled ||
+			status == StatusCompleted || status == StatusFailed
+	case StatusFailed:
+		// A failed task can be retried
+		return status == StatusPending
+	case StatusCompleted, StatusCancelled:
 		return false // Terminal states
 	}
 	return false
@@ -152,6 +191,15 @@ func (t *Task) Duration() time.Duration {
 	return time.Since(*t.StartedAt)
 }
 
+// Type returns the task type declared in the workflow definition, if any
+func (t *Task) Type() string {
+	if t.WorkflowDefinition == nil {
+		return ""
+	}
+	taskType, _ := t.WorkflowDefinition["type"].(string)
+	return taskType
+}
+
 // SetInputData sets the input data
 func (t *Task) SetInputData(data map[string]interface{}) {
 	t.InputData = data
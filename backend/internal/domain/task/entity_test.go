@@ -92,6 +92,24 @@ func TestTask_Fail(t *testing.T) {
 	require.NotNil(t, task.CompletedAt)
 }
 
+func TestTask_Retry(t *testing.T) {
+	task := NewTask(uuid.New(), "Test", "Desc", PriorityMedium)
+	task.Start(uuid.New())
+	task.BindWorkflow("task-1", "run-1")
+	task.Fail("Something went wrong")
+	task.Compensation = &Compensation{Compensated: []string{"draft"}}
+
+	require.True(t, task.CanTransitionTo(StatusPending))
+	task.Retry()
+
+	assert.Equal(t, StatusPending, task.Status)
+	assert.Empty(t, task.ErrorMessage)
+	assert.Nil(t, task.Compensation)
+	assert.Nil(t, task.CompletedAt)
+	assert.Nil(t, task.AssignedEmployeeID)
+	assert.False(t, task.HasWorkflow(), "the next run gets a new workflow run")
+}
+
 func TestTask_Cancel(t *testing.T) {
 	task := NewTask(uuid.New(), "Test", "Desc", PriorityMedium)
 
@@ -152,8 +170,13 @@ func TestTask_CanTransitionTo(t *testing.T) {
 		{"running to failed", StatusRunning, StatusFailed, true},
 		{"running to paused", StatusRunning, StatusPaused, true},
 		{"paused to running", StatusPaused, StatusRunning, true},
+		{"paused to completed", StatusPaused, StatusCompleted, true},
+		{"paused to failed", StatusPaused, StatusFailed, true},
 		{"completed to running", StatusCompleted, StatusRunning, false},
 		{"failed to running", StatusFailed, StatusRunning, false},
+		{"failed to pending", StatusFailed, StatusPending, true},
+		{"completed to pending", StatusCompleted, StatusPending, false},
+		{"cancelled to pending", StatusCancelled, StatusPending, false},
 		{"cancelled to running", StatusCancelled, StatusRunning, false},
 	}
 
@@ -207,3 +230,11 @@ func TestTask_SetInputData(t *testing.T) {
 
 	assert.Equal(t, input, task.InputData)
 }
+
+func TestTask_Type(t *testing.T) {
+	task := NewTask(uuid.New(), "Test", "Desc", PriorityMedium)
+	assert.Equal(t, "", task.Type())
+
+	task.WorkflowDefinition = map[string]interface{}{"type": "hotspot_tracking"}
+	assert.Equal(t, "hotspot_tracking", task.Type())
+}
@@ -0,0 +1,72 @@
+package task
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Lease marks a running task as held by an employee's worker. The worker
+// renews it with heartbeats; a lease that expires means the worker is gone.
+type Lease struct {
+	TaskID     uuid.UUID `json:"task_id" db:"task_id"`
+	CompanyID  uuid.UUID `json:"company_id" db:"company_id"`
+	EmployeeID uuid.UUID `json:"employee_id" db:"employee_id"`
+	ExpiresAt  time.Time `json:"expires_at" db:"expires_at"`
+	RenewedAt  time.Time `json:"renewed_at" db:"renewed_at"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// NewLease creates a lease on a task for the given duration
+func NewLease(companyID, taskID, employeeID uuid.UUID, ttl time.Duration) *Lease {
+	now := time.Now()
+	return &Lease{
+		TaskID:     taskID,
+		CompanyID:  companyID,
+		EmployeeID: employeeID,
+		ExpiresAt:  now.Add(ttl),
+		RenewedAt:  now,
+		CreatedAt:  now,
+	}
+}
+
+// IsExpired checks if the lease has expired at the given time
+func (l *Lease) IsExpired(now time.Time) bool {
+	return !now.Before(l.ExpiresAt)
+}
+
+// RecoveryAction is what the reaper did with a task whose lease expired
+type RecoveryAction string
+
+const (
+	RecoveryRequeued RecoveryAction = "requeued"
+	RecoveryFailed   RecoveryAction = "failed"
+)
+
+// Recovery records a task recovered from an expired lease
+type Recovery struct {
+	ID             uuid.UUID      `json:"id" db:"id"`
+	TaskID         uuid.UUID      `json:"task_id" db:"task_id"`
+	CompanyID      uuid.UUID      `json:"company_id" db:"company_id"`
+	EmployeeID     uuid.UUID      `json:"employee_id" db:"employee_id"`
+	Action         RecoveryAction `json:"action" db:"action"`
+	EmployeeStatus string         `json:"employee_status" db:"employee_status"`
+	Reason         string         `json:"reason" db:"reason"`
+	LeaseExpiredAt time.Time      `json:"lease_expired_at" db:"lease_expired_at"`
+	RecoveredAt    time.Time      `json:"recovered_at" db:"recovered_at"`
+}
+
+// NewRecovery creates a recovery record for an expired lease
+func NewRecovery(lease *Lease, action RecoveryAction, employeeStatus, reason string) *Recovery {
+	return &Recovery{
+		ID:             uuid.New(),
+		TaskID:         lease.TaskID,
+		CompanyID:      lease.CompanyID,
+		EmployeeID:     lease.EmployeeID,
+		Action:         action,
+		EmployeeStatus: employeeStatus,
+		Reason:         reason,
+		LeaseExpiredAt: lease.ExpiresAt,
+		RecoveredAt:    time.Now(),
+	}
+}
@@ -0,0 +1,73 @@
+package task
+
+import "time"
+
+// Step statuses shown in a pipeline
+const (
+	StepPending   = "pending"
+	StepRunning   = "running"
+	StepCompleted = "completed"
+	StepFailed    = "failed"
+	StepSkipped   = "skipped"
+	StepCancelled = "cancelled"
+	// A completed step whose effects were undone after the workflow failed
+	StepCompensated = "compensated"
+	// A completed step whose effects could not be undone
+	StepCompensationFailed = "compensation_failed"
+)
+
+// Pipeline is the live view of a task's workflow: the steps, the one running
+// and how far the workflow got
+type Pipeline struct {
+	CurrentStep string         `json:"current_step,omitempty"`
+	Percent     int            `json:"percent"`
+	Paused      bool           `json:"paused"`
+	Steps       []PipelineStep `json:"steps"`
+}
+
+// PipelineStep is one step of a pipeline
+type PipelineStep struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Status      string     `json:"status"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// Items counts the items of a map step as they finish
+	Items *ItemProgress `json:"items,omitempty"`
+}
+
+// ItemProgress counts the items a map step fans out over
+type ItemProgress struct {
+	Total     int `json:"total"`
+	Succeeded int `json:"succeeded"`
+	Failed    int `json:"failed"`
+}
+
+// IsFinished reports whether the step will not change any more
+func (s *PipelineStep) IsFinished() bool {
+	switch s.Status {
+	case StepCompleted, StepFailed, StepSkipped, StepCancelled, StepCompensated, StepCompensationFailed:
+		return true
+	}
+	return false
+}
+
+// UpdatePercent recomputes how far the pipeline got. Finished steps count
+// whole; a running map step counts by the share of its items that finished.
+func (p *Pipeline) UpdatePercent() {
+	if len(p.Steps) == 0 {
+		return
+	}
+	done := 0
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		switch {
+		case s.IsFinished():
+			done += 100
+		case s.Items != nil && s.Items.Total > 0:
+			done += (s.Items.Succeeded + s.Items.Failed) * 100 / s.Items.Total
+		}
+	}
+	p.Percent = done / len(p.Steps)
+}
@@ -0,0 +1,22 @@
+package task
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipeline_UpdatePercent(t *testing.T) {
+	pipeline := Pipeline{Steps: []PipelineStep{
+		{ID: "fetch", Status: StepCompleted},
+		{ID: "variants", Status: StepRunning, Items: &ItemProgress{Total: 4, Succeeded: 2, Failed: 1}},
+		{ID: "publish", Status: StepPending},
+	}}
+	pipeline.UpdatePercent()
+	assert.Equal(t, 58, pipeline.Percent, "a running map step counts by its finished items")
+
+	pipeline.Steps[1].Status = StepCompleted
+	pipeline.Steps[2].Status = StepSkipped
+	pipeline.UpdatePercent()
+	assert.Equal(t, 100, pipeline.Percent)
+}
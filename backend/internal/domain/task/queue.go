@@ -0,0 +1,42 @@
+package task
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Queue summarizes the pending tasks of a company
+type Queue struct {
+	CompanyID       uuid.UUID `json:"company_id" db:"company_id"`
+	Depth           int       `json:"depth" db:"depth"`
+	OldestCreatedAt time.Time `json:"oldest_created_at" db:"oldest_created_at"`
+}
+
+// OldestWait returns how long the oldest pending task has been waiting at the given time
+func (q *Queue) OldestWait(now time.Time) time.Duration {
+	if q.Depth == 0 || q.OldestCreatedAt.IsZero() || now.Before(q.OldestCreatedAt) {
+		return 0
+	}
+	return now.Sub(q.OldestCreatedAt)
+}
+
+// FairShare is the virtual clock of weighted fair queuing across companies,
+// shared by all scheduler instances
+type FairShare struct {
+	Virtual float64
+	// Finish holds the finish tags past the virtual time; other companies
+	// start at the virtual time
+	Finish map[uuid.UUID]float64
+}
+
+// DispatchRepository lets one scheduler instance dispatch at a time
+type DispatchRepository interface {
+	// LockDispatch locks the dispatch state until the transaction in ctx
+	// ends, so other instances wait, and returns the fair share clock
+	LockDispatch(ctx context.Context) (*FairShare, error)
+	// SaveFairShare stores the fair share clock, dropping the finish tags
+	// it no longer needs
+	SaveFairShare(ctx context.Context, fair *FairShare) error
+}
@@ -2,6 +2,7 @@ package task
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -12,4 +13,73 @@ type Repository interface {
 	ListByCompanyID(ctx context.Context, companyID uuid.UUID, limit, offset int) ([]*Task, error)
 	Update(ctx context.Context, task *Task) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	ListPending(ctx context.Context, companyID uuid.UUID, limit int) ([]*Task, error)
+	// ListQueues summarizes the pending tasks of every company that has any
+	ListQueues(ctx context.Context) ([]*Queue, error)
+	CountRunning(ctx context.Context) (int, error)
+	// SetWorkflowRun records the workflow run executing a task without touching its other fields
+	SetWorkflowRun(ctx context.Context, id uuid.UUID, workflowID, runID string) error
+	// ListActiveWorkflowRuns lists the running and paused tasks whose workflow run ID starts with runIDPrefix
+	ListActiveWorkflowRuns(ctx context.Context, runIDPrefix string) ([]*Task, error)
+
+	// LockPending locks a pending task for assignment (SELECT ... FOR UPDATE SKIP LOCKED).
+	// Returns errors.ErrNotFound if the task is missing, no longer pending or locked elsewhere.
+	LockPending(ctx context.Context, id uuid.UUID) (*Task, error)
+}
+
+// LeaseRepository stores task leases and the recoveries of expired ones
+type LeaseRepository interface {
+	// AcquireLease creates or replaces the lease of a task
+	AcquireLease(ctx context.Context, lease *Lease) error
+	// RenewLease extends a task lease held by the employee; returns errors.ErrNotFound if it no longer exists
+	RenewLease(ctx context.Context, taskID, employeeID uuid.UUID, expiresAt time.Time) error
+	ReleaseLease(ctx context.Context, taskID uuid.UUID) error
+	// LockExpiredLeases locks leases expired before now (SELECT ... FOR UPDATE SKIP LOCKED)
+	LockExpiredLeases(ctx context.Context, now time.Time, limit int) ([]*Lease, error)
+
+	CreateRecovery(ctx context.Context, recovery *Recovery) error
+	ListRecoveries(ctx context.Context, taskID uuid.UUID) ([]*Recovery, error)
+}
+
+// ApprovalRepository stores the approvals of task workflows
+type ApprovalRepository interface {
+	CreateApproval(ctx context.Context, approval *Approval) error
+	// GetPendingApproval returns the approval a workflow node waits for; returns errors.ErrNotFound if there is none
+	GetPendingApproval(ctx context.Context, taskID uuid.UUID, nodeID string) (*Approval, error)
+	// DecideApproval records the decision of a pending approval; returns errors.ErrNotFound if it is no longer pending
+	DecideApproval(ctx context.Context, approval *Approval) error
+	ListApprovals(ctx context.Context, taskID uuid.UUID) ([]*Approval, error)
+}
+
+// AttemptRepository keeps the earlier runs of retried tasks
+type AttemptRepository interface {
+	CreateAttempt(ctx context.Context, attempt *Attempt) error
+	// GetLatestAttempt returns the attempt the task was last retried from; returns errors.ErrNotFound if it never was
+	GetLatestAttempt(ctx context.Context, taskID uuid.UUID) (*Attempt, error)
+	ListAttempts(ctx context.Context, taskID uuid.UUID) ([]*Attempt, error)
+}
+
+// ScheduleRepository stores task schedules and the tasks they started
+type ScheduleRepository interface {
+	CreateSchedule(ctx context.Context, schedule *Schedule) error
+	GetSchedule(ctx context.Context, id uuid.UUID) (*Schedule, error)
+	ListSchedules(ctx context.Context, companyID uuid.UUID) ([]*Schedule, error)
+	UpdateSchedule(ctx context.Context, schedule *Schedule) error
+	DeleteSchedule(ctx context.Context, id uuid.UUID) error
+
+	CreateScheduleRun(ctx context.Context, run *ScheduleRun) error
+	// GetScheduleRunByWorkflow returns the run a schedule workflow recorded; returns errors.ErrNotFound if there is none
+	GetScheduleRunByWorkflow(ctx context.Context, workflowID string) (*ScheduleRun, error)
+	// ListScheduleRuns lists the runs of a schedule with the current status of their tasks, newest first
+	ListScheduleRuns(ctx context.Context, scheduleID uuid.UUID, limit int) ([]*ScheduleRun, error)
+}
+
+// StepRepository stores the steps of task workflow runs
+type StepRepository interface {
+	// SaveStep creates the step or updates the one with the same task, attempt and node
+	SaveStep(ctx context.Context, step *Step) error
+	// GetStep returns a step of an attempt; returns errors.ErrNotFound if it was not recorded
+	GetStep(ctx context.Context, taskID uuid.UUID, attempt int, nodeID string) (*Step, error)
+	// ListSteps lists the steps of an attempt in workflow order
+	ListSteps(ctx context.Context, taskID uuid.UUID, attempt int) ([]*Step, error)
 }
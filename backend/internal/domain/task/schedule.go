@@ -0,0 +1,180 @@
+package task
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron"
+)
+
+// ScheduleKind tells how a schedule fires
+type ScheduleKind string
+
+const (
+	// ScheduleOnce starts one task at a given time
+	ScheduleOnce ScheduleKind = "once"
+	// ScheduleCron starts a task every time a cron expression matches
+	ScheduleCron ScheduleKind = "cron"
+)
+
+// ScheduleStatus is the state of a schedule
+type ScheduleStatus string
+
+const (
+	ScheduleActive ScheduleStatus = "active"
+	SchedulePaused ScheduleStatus = "paused"
+	// ScheduleFinished marks a one-off schedule that already started its task
+	ScheduleFinished ScheduleStatus = "finished"
+)
+
+// TaskTemplate describes the task a schedule starts each time it fires
+type TaskTemplate struct {
+	Title              string                 `json:"title"`
+	Description        string                 `json:"description,omitempty"`
+	Priority           TaskPriority           `json:"priority"`
+	WorkflowDefinition map[string]interface{} `json:"workflow_definition,omitempty"`
+	InputData          map[string]interface{} `json:"input_data,omitempty"`
+}
+
+// Schedule starts tasks from a template, once at a given time or on a cron
+// expression evaluated in the schedule's time zone
+type Schedule struct {
+	ID        uuid.UUID    `json:"id" db:"id"`
+	CompanyID uuid.UUID    `json:"company_id" db:"company_id"`
+	Name      string       `json:"name" db:"name"`
+	Kind      ScheduleKind `json:"kind" db:"kind"`
+	// CronExpression has five fields: minute, hour, day of month, month, day of week
+	CronExpression string         `json:"cron_expression,omitempty" db:"cron_expression"`
+	RunAt          *time.Time     `json:"run_at,omitempty" db:"run_at"`
+	Timezone       string         `json:"timezone" db:"timezone"`
+	Template       TaskTemplate   `json:"task_template"`
+	Status         ScheduleStatus `json:"status" db:"status"`
+	CreatedBy      *uuid.UUID     `json:"created_by,omitempty" db:"created_by"`
+	LastRunAt      *time.Time     `json:"last_run_at,omitempty" db:"last_run_at"`
+	CreatedAt      time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// NewSchedule creates an active schedule
+func NewSchedule(companyID uuid.UUID, name string, kind ScheduleKind, template TaskTemplate) *Schedule {
+	now := time.Now()
+	if template.Priority == "" {
+		template.Priority = PriorityMedium
+	}
+	return &Schedule{
+		ID:        uuid.New(),
+		CompanyID: companyID,
+		Name:      name,
+		Kind:      kind,
+		Timezone:  "UTC",
+		Template:  template,
+		Status:    ScheduleActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Validate checks the schedule fires at a well defined time in a known time zone
+func (s *Schedule) Validate(now time.Time) error {
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", s.Timezone)
+	}
+	if s.Template.Title == "" {
+		return fmt.Errorf("task title is required")
+	}
+
+	switch s.Kind {
+	case ScheduleOnce:
+		if s.RunAt == nil || s.CronExpression != "" {
+			return fmt.Errorf("a one-off schedule needs run_at and no cron expression")
+		}
+		if !s.RunAt.After(now) {
+			return fmt.Errorf("run_at must be in the future")
+		}
+	case ScheduleCron:
+		if s.RunAt != nil {
+			return fmt.Errorf("a cron schedule takes no run_at")
+		}
+		if _, err := cron.ParseStandard(s.CronExpression); err != nil {
+			return fmt.Errorf("invalid cron expression %q: %v", s.CronExpression, err)
+		}
+	default:
+		return fmt.Errorf("unknown schedule kind %q", s.Kind)
+	}
+	return nil
+}
+
+// Location returns the time zone the schedule fires in
+func (s *Schedule) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// IsPaused checks if the schedule is paused
+func (s *Schedule) IsPaused() bool {
+	return s.Status == SchedulePaused
+}
+
+// Pause stops the schedule from firing
+func (s *Schedule) Pause() {
+	s.Status = SchedulePaused
+	s.UpdatedAt = time.Now()
+}
+
+// Resume lets a paused schedule fire again
+func (s *Schedule) Resume() {
+	s.Status = ScheduleActive
+	s.UpdatedAt = time.Now()
+}
+
+// RecordRun notes that the schedule started a task; a one-off schedule is then finished
+func (s *Schedule) RecordRun(at time.Time) {
+	s.LastRunAt = &at
+	if s.Kind == ScheduleOnce {
+		s.Status = ScheduleFinished
+	}
+	s.UpdatedAt = time.Now()
+}
+
+// ScheduleRun records a task started by a schedule
+type ScheduleRun struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	ScheduleID uuid.UUID `json:"schedule_id" db:"schedule_id"`
+	CompanyID  uuid.UUID `json:"company_id" db:"company_id"`
+	TaskID     uuid.UUID `json:"task_id" db:"task_id"`
+	// WorkflowID is the Temporal workflow that started the task; it makes spawning idempotent
+	WorkflowID  string     `json:"workflow_id" db:"workflow_id"`
+	TaskStatus  TaskStatus `json:"task_status" db:"task_status"`
+	TriggeredAt time.Time  `json:"triggered_at" db:"triggered_at"`
+}
+
+// NewScheduleRun records a task started by a schedule's workflow
+func NewScheduleRun(s *Schedule, taskID uuid.UUID, workflowID string, triggeredAt time.Time) *ScheduleRun {
+	return &ScheduleRun{
+		ID:          uuid.New(),
+		ScheduleID:  s.ID,
+		CompanyID:   s.CompanyID,
+		TaskID:      taskID,
+		WorkflowID:  workflowID,
+		TaskStatus:  StatusPending,
+		TriggeredAt: triggeredAt,
+	}
+}
+
+// Spawn creates the task a schedule starts
+func (s *Schedule) Spawn() *Task {
+	t := NewTask(s.CompanyID, s.Template.Title, s.Template.Description, s.Template.Priority)
+	t.WorkflowDefinition = s.Template.WorkflowDefinition
+	if t.WorkflowDefinition == nil {
+		t.WorkflowDefinition = make(map[string]interface{})
+	}
+	t.InputData = s.Template.InputData
+	if t.InputData == nil {
+		t.InputData = make(map[string]interface{})
+	}
+	return t
+}
@@ -0,0 +1,84 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule_Validate(t *testing.T) {
+	now := time.Date(2025, 3, 7, 12, 0, 0, 0, time.UTC)
+	future := now.Add(8 * time.Hour)
+	past := now.Add(-time.Minute)
+
+	tests := []struct {
+		name     string
+		kind     ScheduleKind
+		cron     string
+		runAt    *time.Time
+		timezone string
+		title    string
+		wantErr  string
+	}{
+		{name: "every morning at eight", kind: ScheduleCron, cron: "0 8 * * *", timezone: "Asia/Shanghai", title: "Hotspots"},
+		{name: "friday evening", kind: ScheduleCron, cron: "0 20 * * 5", title: "Publish"},
+		{name: "one-off", kind: ScheduleOnce, runAt: &future, title: "Launch"},
+		{name: "bad cron", kind: ScheduleCron, cron: "every day", title: "Hotspots", wantErr: "invalid cron expression"},
+		{name: "cron with run_at", kind: ScheduleCron, cron: "0 8 * * *", runAt: &future, title: "Hotspots", wantErr: "no run_at"},
+		{name: "one-off in the past", kind: ScheduleOnce, runAt: &past, title: "Launch", wantErr: "in the future"},
+		{name: "one-off without run_at", kind: ScheduleOnce, title: "Launch", wantErr: "needs run_at"},
+		{name: "unknown timezone", kind: ScheduleCron, cron: "0 8 * * *", timezone: "Mars/Olympus", title: "Hotspots", wantErr: "unknown timezone"},
+		{name: "no title", kind: ScheduleCron, cron: "0 8 * * *", wantErr: "title is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSchedule(uuid.New(), tt.name, tt.kind, TaskTemplate{Title: tt.title})
+			s.CronExpression = tt.cron
+			s.RunAt = tt.runAt
+			if tt.timezone != "" {
+				s.Timezone = tt.timezone
+			}
+
+			err := s.Validate(now)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSchedule_RecordRun(t *testing.T) {
+	at := time.Now()
+
+	cron := NewSchedule(uuid.New(), "daily", ScheduleCron, TaskTemplate{Title: "Hotspots"})
+	cron.RecordRun(at)
+	assert.Equal(t, ScheduleActive, cron.Status)
+	assert.Equal(t, &at, cron.LastRunAt)
+
+	once := NewSchedule(uuid.New(), "launch", ScheduleOnce, TaskTemplate{Title: "Launch"})
+	once.RecordRun(at)
+	assert.Equal(t, ScheduleFinished, once.Status, "a one-off schedule fires once")
+}
+
+func TestSchedule_Spawn(t *testing.T) {
+	s := NewSchedule(uuid.New(), "daily", ScheduleCron, TaskTemplate{
+		Title:     "Hotspots",
+		InputData: map[string]interface{}{"platform": "weibo"},
+	})
+
+	spawned := s.Spawn()
+	assert.Equal(t, s.CompanyID, spawned.CompanyID)
+	assert.Equal(t, "Hotspots", spawned.Title)
+	assert.Equal(t, PriorityMedium, spawned.Priority)
+	assert.Equal(t, StatusPending, spawned.Status)
+	assert.Equal(t, "weibo", spawned.InputData["platform"])
+	assert.NotNil(t, spawned.WorkflowDefinition)
+	assert.NotEqual(t, spawned.ID, s.Spawn().ID, "every run starts a new task")
+}
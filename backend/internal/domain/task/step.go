@@ -0,0 +1,146 @@
+package task
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Step is one step of a task's workflow run. Every attempt of a task keeps
+// its own steps, keyed by the workflow node they ran.
+type Step struct {
+	ID          uuid.UUID              `json:"id"`
+	TaskID      uuid.UUID              `json:"task_id"`
+	Attempt     int                    `json:"attempt"`
+	NodeID      string                 `json:"node_id"`
+	Order       int                    `json:"order"`
+	Name        string                 `json:"name"`
+	SkillCardID *uuid.UUID             `json:"skill_card_id,omitempty"`
+	Status      string                 `json:"status"`
+	Input       map[string]interface{} `json:"input,omitempty"`
+	Output      map[string]interface{} `json:"output,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	ExecutedBy  *uuid.UUID             `json:"executed_by_employee_id,omitempty"`
+	// Reused marks a step whose output was taken over from an earlier attempt
+	Reused      bool       `json:"reused"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// NewStep creates a pending step of the given attempt of a task
+func NewStep(taskID uuid.UUID, attempt, order int, nodeID, name string) *Step {
+	now := time.Now()
+	return &Step{
+		ID:        uuid.New(),
+		TaskID:    taskID,
+		Attempt:   attempt,
+		NodeID:    nodeID,
+		Order:     order,
+		Name:      name,
+		Status:    StepPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// Start marks the step as running with the input it resolved
+func (s *Step) Start(input map[string]interface{}, executedBy *uuid.UUID, at time.Time) {
+	s.Status = StepRunning
+	s.Input = input
+	s.ExecutedBy = executedBy
+	s.StartedAt = &at
+	s.UpdatedAt = time.Now()
+}
+
+// Complete marks the step as completed with its output
+func (s *Step) Complete(output map[string]interface{}, at time.Time) {
+	s.Status = StepCompleted
+	s.Output = output
+	s.CompletedAt = &at
+	s.UpdatedAt = time.Now()
+}
+
+// Reuse completes a pending step with the output of an earlier attempt
+func (s *Step) Reuse(output map[string]interface{}, at time.Time) {
+	s.Complete(output, at)
+	s.Reused = true
+}
+
+// Fail marks the step as failed
+func (s *Step) Fail(errorMsg string, at time.Time) {
+	s.Status = StepFailed
+	s.Error = errorMsg
+	s.CompletedAt = &at
+	s.UpdatedAt = time.Now()
+}
+
+// Skip marks a step the workflow will not run
+func (s *Step) Skip() {
+	s.Status = StepSkipped
+	s.UpdatedAt = time.Now()
+}
+
+// Cancel marks the step as cancelled along with its workflow
+func (s *Step) Cancel(at time.Time) {
+	s.Status = StepCancelled
+	s.CompletedAt = &at
+	s.UpdatedAt = time.Now()
+}
+
+// Compensate marks a completed step whose effects were undone
+func (s *Step) Compensate(at time.Time) {
+	s.Status = StepCompensated
+	s.CompletedAt = &at
+	s.UpdatedAt = time.Now()
+}
+
+// FailCompensation marks a completed step whose effects could not be undone
+func (s *Step) FailCompensation(errorMsg string, at time.Time) {
+	s.Status = StepCompensationFailed
+	s.Error = errorMsg
+	s.CompletedAt = &at
+	s.UpdatedAt = time.Now()
+}
+
+// IsFinished reports whether the step's run will not change it any more;
+// a completed or failed step may still be compensated
+func (s *Step) IsFinished() bool {
+	switch s.Status {
+	case StepCompleted, StepFailed, StepSkipped, StepCancelled, StepCompensated, StepCompensationFailed:
+		return true
+	}
+	return false
+}
+
+// CanTransitionTo checks if status transition is valid. A pending step
+// completes at once when its output is reused; a completed step, or a
+// failed one that left effects behind such as a partial publish, is
+// compensated when its workflow fails or is cancelled.
+func (s *Step) CanTransitionTo(status string) bool {
+	switch s.Status {
+	case StepPending:
+		return status == StepRunning || status == StepCompleted ||
+			status == StepSkipped || status == StepCancelled
+	case StepRunning:
+		return status == StepCompleted || status == StepFailed || status == StepCancelled
+	case StepCompleted, StepFailed:
+		return status == StepCompensated || status == StepCompensationFailed
+	case StepSkipped, StepCancelled, StepCompensated, StepCompensationFailed:
+		return false // Terminal states
+	}
+	return false
+}
+
+// PipelineStep returns the step as shown in a pipeline
+func (s *Step) PipelineStep() PipelineStep {
+	return PipelineStep{
+		ID:          s.NodeID,
+		Name:        s.Name,
+		Status:      s.Status,
+		Error:       s.Error,
+		StartedAt:   s.StartedAt,
+		CompletedAt: s.CompletedAt,
+	}
+}
@@ -0,0 +1,83 @@
+package task
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStep_Lifecycle(t *testing.T) {
+	employeeID := uuid.New()
+	step := NewStep(uuid.New(), 1, 2, "review", "Review")
+	assert.Equal(t, StepPending, step.Status)
+	assert.False(t, step.IsFinished())
+
+	step.Start(map[string]interface{}{"content": "text"}, &employeeID, time.Now())
+	assert.Equal(t, StepRunning, step.Status)
+	assert.NotNil(t, step.StartedAt)
+	assert.Equal(t, &employeeID, step.ExecutedBy)
+
+	step.Fail("provider unavailable", time.Now())
+	assert.Equal(t, StepFailed, step.Status)
+	assert.True(t, step.IsFinished())
+	assert.NotNil(t, step.CompletedAt)
+
+	view := step.PipelineStep()
+	assert.Equal(t, "review", view.ID)
+	assert.Equal(t, "provider unavailable", view.Error)
+}
+
+func TestStep_Reuse(t *testing.T) {
+	step := NewStep(uuid.New(), 2, 1, "draft", "Draft")
+	step.Reuse(map[string]interface{}{"content": "text"}, time.Now())
+	assert.Equal(t, StepCompleted, step.Status)
+	assert.True(t, step.Reused)
+	assert.Nil(t, step.StartedAt)
+}
+
+func TestStep_Compensation(t *testing.T) {
+	step := NewStep(uuid.New(), 1, 1, "publish", "Publish")
+	step.Complete(map[string]interface{}{"url": "https://example.com/1"}, time.Now())
+
+	step.FailCompensation("platform unavailable", time.Now())
+	assert.Equal(t, StepCompensationFailed, step.Status)
+	assert.Equal(t, "platform unavailable", step.Error)
+	assert.True(t, step.IsFinished())
+	assert.Equal(t, "https://example.com/1", step.Output["url"], "the output shows what was left behind")
+}
+
+func TestStep_CanTransitionTo(t *testing.T) {
+	tests := []struct {
+		from     string
+		to       string
+		expected bool
+	}{
+		{StepPending, StepRunning, true},
+		{StepPending, StepCompleted, true},
+		{StepPending, StepSkipped, true},
+		{StepPending, StepFailed, false},
+		{StepRunning, StepCompleted, true},
+		{StepRunning, StepFailed, true},
+		{StepRunning, StepCancelled, true},
+		{StepRunning, StepSkipped, false},
+		{StepCompleted, StepRunning, false},
+		{StepFailed, StepRunning, false},
+		{StepSkipped, StepCompleted, false},
+		{StepCancelled, StepRunning, false},
+		{StepCompleted, StepCompensated, true},
+		{StepCompleted, StepCompensationFailed, true},
+		{StepFailed, StepCompensated, true},
+		{StepSkipped, StepCompensated, false},
+		{StepCompensated, StepCompleted, false},
+		{StepCompensationFailed, StepCompensated, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from+"->"+tt.to, func(t *testing.T) {
+			step := &Step{Status: tt.from}
+			assert.Equal(t, tt.expected, step.CanTransitionTo(tt.to))
+		})
+	}
+}
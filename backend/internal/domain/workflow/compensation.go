@@ -0,0 +1,90 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"unlimited-corp/internal/domain/task"
+
+	"github.com/google/uuid"
+)
+
+// Compensation undoes what a completed node did, e.g. unpublishing a post
+// or deleting a draft, when the run later fails or is cancelled.
+// Compensations run one at a time, the last completed node first.
+type Compensation struct {
+	SkillCardID string `json:"skill_card_id"`
+	// Inputs maps parameters to literals or expressions, which may also read
+	// the node's own output. By default the compensation receives the node's output.
+	Inputs map[string]interface{} `json:"inputs,omitempty"`
+}
+
+// CompensationResult is how the compensation of a node ended; Error is empty
+// when it succeeded
+type CompensationResult struct {
+	NodeID string
+	Error  string
+}
+
+// CompensationInputs resolves the parameters of a node's compensation once
+// the node completed
+func (r *Run) CompensationInputs(n *Node) map[string]interface{} {
+	if len(n.Compensate.Inputs) > 0 {
+		return r.scope.Resolve(n.Compensate.Inputs).(map[string]interface{})
+	}
+	params := make(map[string]interface{}, len(r.scope.Nodes[n.ID]))
+	for k, v := range r.scope.Nodes[n.ID] {
+		params[k] = v
+	}
+	return params
+}
+
+// DescribeCompensations summarizes how compensations ended for the error of
+// a task, naming the ones that failed first since their effects remain
+func DescribeCompensations(results []CompensationResult) string {
+	var failed, compensated []string
+	for _, r := range results {
+		if r.Error != "" {
+			failed = append(failed, fmt.Sprintf("compensation of %s failed: %s", r.NodeID, r.Error))
+		} else {
+			compensated = append(compensated, r.NodeID)
+		}
+	}
+	parts := failed
+	if len(compensated) > 0 {
+		parts = append(parts, "compensated "+strings.Join(compensated, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// SummarizeCompensations returns how compensations ended for the task, or
+// nil when there were none
+func SummarizeCompensations(results []CompensationResult) *task.Compensation {
+	if len(results) == 0 {
+		return nil
+	}
+	summary := &task.Compensation{Compensated: []string{}, Failed: []task.CompensationFailure{}}
+	for _, r := range results {
+		if r.Error != "" {
+			summary.Failed = append(summary.Failed, task.CompensationFailure{StepID: r.NodeID, Error: r.Error})
+		} else {
+			summary.Compensated = append(summary.Compensated, r.NodeID)
+		}
+	}
+	summary.PartiallyCompensated = len(summary.Failed) > 0
+	return summary
+}
+
+// checkCompensation returns the problems of a node's compensation
+func (n *Node) checkCompensation() []string {
+	if n.Compensate == nil {
+		return nil
+	}
+	if n.Type != NodeSkill && n.Type != NodeMap {
+		return []string{fmt.Sprintf("node %q: only skill and map nodes have a compensation", n.ID)}
+	}
+	if _, err := uuid.Parse(n.Compensate.SkillCardID); err != nil {
+		return []string{fmt.Sprintf("node %q needs a valid compensation skill_card_id", n.ID)}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package workflow
+
+import (
+	"testing"
+
+	"unlimited-corp/internal/domain/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_CompensationInputs(t *testing.T) {
+	def := &Definition{
+		Version: 1,
+		Nodes: []Node{
+			{ID: "draft", Type: NodeSkill, SkillCardID: skillA, Compensate: &Compensation{SkillCardID: skillB}},
+			{ID: "review", Type: NodeSkill, SkillCardID: skillA},
+			{ID: "publish", Type: NodeSkill, SkillCardID: skillA, Compensate: &Compensation{
+				SkillCardID: skillB,
+				Inputs:      map[string]interface{}{"post": "$.nodes.publish.output.id", "topic": "$.input.topic"},
+			}},
+			{ID: "notify", Type: NodeSkill, SkillCardID: skillA},
+		},
+		Edges: []Edge{{From: "draft", To: "review"}, {From: "draft", To: "publish"}, {From: "publish", To: "notify"}},
+	}
+	require.NoError(t, def.Validate())
+
+	run := NewRun(def, map[string]interface{}{"topic": "go"})
+	run.Next()
+	run.Complete("draft", map[string]interface{}{"draft_id": "d1"})
+	run.Next()
+	run.Complete("publish", map[string]interface{}{"id": "p1"})
+
+	draft, _ := def.Node("draft")
+	publish, _ := def.Node("publish")
+	assert.Equal(t, map[string]interface{}{"post": "p1", "topic": "go"}, run.CompensationInputs(publish))
+	assert.Equal(t, map[string]interface{}{"draft_id": "d1"}, run.CompensationInputs(draft), "by default a compensation receives the node's output")
+}
+
+func TestDescribeCompensations(t *testing.T) {
+	assert.Equal(t, "", DescribeCompensations(nil))
+	assert.Equal(t, "compensated publish, draft", DescribeCompensations([]CompensationResult{{NodeID: "publish"}, {NodeID: "draft"}}))
+	assert.Equal(t,
+		"compensation of publish failed: platform unavailable; compensated draft",
+		DescribeCompensations([]CompensationResult{{NodeID: "publish", Error: "platform unavailable"}, {NodeID: "draft"}}))
+}
+
+func TestSummarizeCompensations(t *testing.T) {
+	assert.Nil(t, SummarizeCompensations(nil))
+	assert.Equal(t, &task.Compensation{Compensated: []string{"publish", "draft"}, Failed: []task.CompensationFailure{}},
+		SummarizeCompensations([]CompensationResult{{NodeID: "publish"}, {NodeID: "draft"}}))
+	assert.Equal(t, &task.Compensation{
+		Compensated:          []string{"draft"},
+		Failed:               []task.CompensationFailure{{StepID: "publish", Error: "platform unavailable"}},
+		PartiallyCompensated: true,
+	}, SummarizeCompensations([]CompensationResult{{NodeID: "publish", Error: "platform unavailable"}, {NodeID: "draft"}}))
+}
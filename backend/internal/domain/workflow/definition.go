@@ -0,0 +1,434 @@
+package workflow
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// CurrentVersion is the newest definition format version
+const CurrentVersion = 1
+
+// NodeType identifies what a node does
+type NodeType string
+
+const (
+	// NodeSkill runs a skill card, optionally as a specific employee
+	NodeSkill NodeType = "skill"
+	// NodeApproval parks the workflow until a reviewer approves, rejects or
+	// edits the node's input, which becomes its output
+	NodeApproval NodeType = "approval"
+	// NodeMap fans out over a list, running a skill card or a child workflow
+	// per item, and reduces the outputs to its own
+	NodeMap NodeType = "map"
+	// NodePublish publishes the content of its input to the platforms named
+	// by its platforms or platform input, by default the configured default
+	// platforms, as a draft when publish_mode is "draft". Its output is the
+	// publication, which is taken down again when the run later fails or is
+	// cancelled.
+	NodePublish NodeType = "publish"
+	// NodeHotspots analyzes the hotspots of its platform input, optionally
+	// within a category, from the configured sources and the company's latest
+	// upload, scoring their trend against the previous analysis
+	NodeHotspots NodeType = "hotspots"
+)
+
+// Actions an approval node applies when nobody decides before its timeout
+const (
+	ApprovalDefaultApprove = "approve"
+	ApprovalDefaultReject  = "reject"
+)
+
+// Definition is a versioned workflow: nodes connected by edges. Nodes whose
+// predecessors are done run in parallel; a node with several incoming edges
+// waits for all of them (a join).
+type Definition struct {
+	Version int    `json:"version"`
+	Name    string `json:"name,omitempty"`
+	// Type is the task type the scheduler can match strategies on
+	Type  string `json:"type,omitempty"`
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges,omitempty"`
+	// Output maps result fields to expressions; by default the outputs of the
+	// nodes without outgoing edges are merged
+	Output map[string]interface{} `json:"output,omitempty"`
+}
+
+// Node is one step of a workflow
+type Node struct {
+	ID   string   `json:"id"`
+	Name string   `json:"name,omitempty"`
+	Type NodeType `json:"type"`
+	// SkillCardID is the skill card a skill node runs
+	SkillCardID string `json:"skill_card_id,omitempty"`
+	// EmployeeID runs the node as this employee instead of the task's assignee
+	EmployeeID string `json:"employee_id,omitempty"`
+	// Inputs maps parameter names to literals or expressions such as
+	// "$.input.topic" or "$.nodes.draft.output.content". Without inputs a node
+	// receives the workflow input merged with its predecessors' outputs.
+	Inputs map[string]interface{} `json:"inputs,omitempty"`
+	// Condition skips the node when it evaluates to false
+	Condition *Condition `json:"condition,omitempty"`
+	// TimeoutSeconds bounds a skill node's execution, or how long an
+	// approval node waits for a reviewer
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	MaxAttempts    int `json:"max_attempts,omitempty"`
+	// DefaultAction is what an approval node does once it times out:
+	// "approve" or "reject" (the default)
+	DefaultAction string `json:"default_action,omitempty"`
+	// Map configures a map node's fan-out
+	Map *MapSpec `json:"map,omitempty"`
+	// Compensate undoes the node once it completed and the run fails or is cancelled
+	Compensate *Compensation `json:"compensate,omitempty"`
+}
+
+// DisplayName returns the node name, falling back to its ID
+func (n *Node) DisplayName() string {
+	if n.Name != "" {
+		return n.Name
+	}
+	return n.ID
+}
+
+// Edge connects two nodes. An edge whose condition is false is not taken.
+type Edge struct {
+	From      string     `json:"from"`
+	To        string     `json:"to"`
+	Condition *Condition `json:"condition,omitempty"`
+}
+
+// ValidationError lists every problem found in a definition
+type ValidationError struct {
+	Problems []string `json:"problems"`
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid workflow definition: " + strings.Join(e.Problems, "; ")
+}
+
+// Declared reports whether a task's workflow definition map declares nodes.
+// Maps without nodes only annotate the task, e.g. with its type.
+func Declared(m map[string]interface{}) bool {
+	_, ok := m["nodes"]
+	return ok
+}
+
+// Parse decodes and validates a definition stored as a map
+func Parse(m map[string]interface{}) (*Definition, error) {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return nil, &ValidationError{Problems: []string{err.Error()}}
+	}
+	return ParseJSON(raw)
+}
+
+// ParseJSON decodes and validates a JSON definition. Unknown fields are rejected.
+func ParseJSON(raw []byte) (*Definition, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+
+	var def Definition
+	if err := decoder.Decode(&def); err != nil {
+		return nil, &ValidationError{Problems: []string{err.Error()}}
+	}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	return &def, nil
+}
+
+// ToMap converts the definition to the map stored on a task
+func (d *Definition) ToMap() map[string]interface{} {
+	raw, _ := json.Marshal(d)
+	var m map[string]interface{}
+	_ = json.Unmarshal(raw, &m)
+	return m
+}
+
+// Node returns the node with the given ID
+func (d *Definition) Node(id string) (*Node, bool) {
+	for i := range d.Nodes {
+		if d.Nodes[i].ID == id {
+			return &d.Nodes[i], true
+		}
+	}
+	return nil, false
+}
+
+// SkillCardIDs lists the skill cards the definition runs, its child
+// workflows' and compensations' included, each once in order of appearance
+func (d *Definition) SkillCardIDs() []string {
+	var ids []string
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, n := range d.Nodes {
+		add(n.SkillCardID)
+		if n.Map != nil && n.Map.Workflow != nil {
+			for _, id := range n.Map.Workflow.SkillCardIDs() {
+				add(id)
+			}
+		}
+		if n.Compensate != nil {
+			add(n.Compensate.SkillCardID)
+		}
+	}
+	return ids
+}
+
+// Validate checks the definition is well formed: a supported version, unique
+// nodes with their bindings, edges between known nodes, no cycles, and
+// expressions that only read data available when the node runs
+func (d *Definition) Validate() error {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	if d.Version < 1 || d.Version > CurrentVersion {
+		addf("unsupported version %d", d.Version)
+	}
+	if len(d.Nodes) == 0 {
+		addf("at least one node is required")
+	}
+
+	ids := make(map[string]bool, len(d.Nodes))
+	for i, n := range d.Nodes {
+		if n.ID == "" {
+			addf("node %d has no id", i)
+			continue
+		}
+		if ids[n.ID] {
+			addf("duplicate node id %q", n.ID)
+		}
+		ids[n.ID] = true
+
+		switch n.Type {
+		case NodeSkill:
+			if _, err := uuid.Parse(n.SkillCardID); err != nil {
+				addf("node %q needs a valid skill_card_id", n.ID)
+			}
+			if n.DefaultAction != "" {
+				addf("node %q: only approval nodes have a default_action", n.ID)
+			}
+		case NodeApproval:
+			if n.SkillCardID != "" || n.EmployeeID != "" || n.MaxAttempts != 0 {
+				addf("node %q: approval nodes take no skill_card_id, employee_id or max_attempts", n.ID)
+			}
+			switch n.DefaultAction {
+			case "", ApprovalDefaultApprove, ApprovalDefaultReject:
+			default:
+				addf("node %q has unknown default_action %q", n.ID, n.DefaultAction)
+			}
+		case NodeMap:
+			problems = append(problems, n.checkMap(d.Version)...)
+			if n.DefaultAction != "" {
+				addf("node %q: only approval nodes have a default_action", n.ID)
+			}
+		case NodePublish:
+			if n.SkillCardID != "" || n.EmployeeID != "" || n.DefaultAction != "" {
+				addf("node %q: publish nodes take no skill_card_id, employee_id or default_action", n.ID)
+			}
+		case NodeHotspots:
+			if n.SkillCardID != "" || n.EmployeeID != "" || n.DefaultAction != "" {
+				addf("node %q: hotspots nodes take no skill_card_id, employee_id or default_action", n.ID)
+			}
+		default:
+			addf("node %q has unknown type %q", n.ID, n.Type)
+		}
+		if n.Map != nil && n.Type != NodeMap {
+			addf("node %q: only map nodes have a map", n.ID)
+		}
+		problems = append(problems, n.checkCompensation()...)
+		if n.EmployeeID != "" {
+			if _, err := uuid.Parse(n.EmployeeID); err != nil {
+				addf("node %q has an invalid employee_id", n.ID)
+			}
+		}
+		if n.TimeoutSeconds < 0 || n.MaxAttempts < 0 {
+			addf("node %q has a negative timeout or attempt count", n.ID)
+		}
+	}
+
+	seen := make(map[[2]string]bool, len(d.Edges))
+	for _, e := range d.Edges {
+		switch {
+		case !ids[e.From] || !ids[e.To]:
+			addf("edge %s -> %s references an unknown node", e.From, e.To)
+			continue
+		case e.From == e.To:
+			addf("edge %s -> %s loops on itself", e.From, e.To)
+			continue
+		case seen[[2]string{e.From, e.To}]:
+			addf("duplicate edge %s -> %s", e.From, e.To)
+		}
+		seen[[2]string{e.From, e.To}] = true
+	}
+
+	if len(problems) == 0 {
+		if cycle := d.findCycle(); cycle != "" {
+			addf("edges form a cycle through node %q", cycle)
+		}
+	}
+
+	if len(problems) == 0 {
+		ancestors := d.ancestors()
+		for _, n := range d.Nodes {
+			for _, p := range checkExpressions(n.Inputs, ancestors[n.ID]) {
+				addf("node %q input: %s", n.ID, p)
+			}
+			for _, p := range n.Condition.check(ancestors[n.ID]) {
+				addf("node %q condition: %s", n.ID, p)
+			}
+			if n.Map != nil {
+				if p := checkPath(n.Map.Items, ancestors[n.ID]); p != "" {
+					addf("node %q map items: %s", n.ID, p)
+				}
+			}
+			if n.Compensate != nil {
+				// A compensation can read its own node as well
+				available := copySet(ancestors[n.ID])
+				available[n.ID] = true
+				for _, p := range checkExpressions(n.Compensate.Inputs, available) {
+					addf("node %q compensation input: %s", n.ID, p)
+				}
+			}
+		}
+		for _, e := range d.Edges {
+			// An edge condition can read its source node as well
+			available := copySet(ancestors[e.From])
+			available[e.From] = true
+			for _, p := range e.Condition.check(available) {
+				addf("edge %s -> %s condition: %s", e.From, e.To, p)
+			}
+		}
+		all := make(map[string]bool, len(ids))
+		for id := range ids {
+			all[id] = true
+		}
+		for _, p := range checkExpressions(d.Output, all) {
+			addf("output: %s", p)
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// successors returns the outgoing edges of every node
+func (d *Definition) successors() map[string][]Edge {
+	out := make(map[string][]Edge, len(d.Nodes))
+	for _, e := range d.Edges {
+		out[e.From] = append(out[e.From], e)
+	}
+	return out
+}
+
+// predecessors returns the incoming edges of every node
+func (d *Definition) predecessors() map[string][]Edge {
+	in := make(map[string][]Edge, len(d.Nodes))
+	for _, e := range d.Edges {
+		in[e.To] = append(in[e.To], e)
+	}
+	return in
+}
+
+// findCycle returns a node on a cycle, or "" when the graph is acyclic
+func (d *Definition) findCycle() string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(d.Nodes))
+	out := d.successors()
+
+	var visit func(id string) string
+	visit = func(id string) string {
+		state[id] = visiting
+		for _, e := range out[id] {
+			switch state[e.To] {
+			case visiting:
+				return e.To
+			case unvisited:
+				if found := visit(e.To); found != "" {
+					return found
+				}
+			}
+		}
+		state[id] = visited
+		return ""
+	}
+
+	for _, n := range d.Nodes {
+		if state[n.ID] == unvisited {
+			if found := visit(n.ID); found != "" {
+				return found
+			}
+		}
+	}
+	return ""
+}
+
+// ancestors returns, for every node, the nodes that always finish before it
+func (d *Definition) ancestors() map[string]map[string]bool {
+	in := d.predecessors()
+	result := make(map[string]map[string]bool, len(d.Nodes))
+
+	var collect func(id string) map[string]bool
+	collect = func(id string) map[string]bool {
+		if set, ok := result[id]; ok {
+			return set
+		}
+		set := make(map[string]bool)
+		for _, e := range in[id] {
+			set[e.From] = true
+			for a := range collect(e.From) {
+				set[a] = true
+			}
+		}
+		result[id] = set
+		return set
+	}
+
+	for _, n := range d.Nodes {
+		collect(n.ID)
+	}
+	return result
+}
+
+// Downstream returns the node and every node reachable from it
+func (d *Definition) Downstream(id string) map[string]bool {
+	out := d.successors()
+	set := make(map[string]bool)
+
+	var collect func(id string)
+	collect = func(id string) {
+		if set[id] {
+			return
+		}
+		set[id] = true
+		for _, e := range out[id] {
+			collect(e.To)
+		}
+	}
+	collect(id)
+	return set
+}
+
+func copySet(set map[string]bool) map[string]bool {
+	copied := make(map[string]bool, len(set)+1)
+	for k, v := range set {
+		copied[k] = v
+	}
+	return copied
+}
@@ -0,0 +1,198 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	skillA = "11111111-1111-1111-1111-111111111111"
+	skillB = "22222222-2222-2222-2222-222222222222"
+)
+
+// pipelineJSON drafts, then reviews and illustrates in parallel, then publishes
+const pipelineJSON = `{
+	"version": 1,
+	"name": "content_pipeline",
+	"type": "content",
+	"nodes": [
+		{"id": "draft", "type": "skill", "skill_card_id": "` + skillA + `", "inputs": {"topic": "$.input.topic"}},
+		{"id": "review", "type": "skill", "skill_card_id": "` + skillB + `"},
+		{"id": "illustrate", "type": "skill", "skill_card_id": "` + skillB + `",
+			"condition": {"path": "$.input.with_images", "op": "eq", "value": true}},
+		{"id": "publish", "type": "skill", "skill_card_id": "` + skillA + `",
+			"inputs": {"text": "$.nodes.draft.output.content", "score": "$.nodes.review.output.score"}}
+	],
+	"edges": [
+		{"from": "draft", "to": "review"},
+		{"from": "draft", "to": "illustrate"},
+		{"from": "review", "to": "publish", "condition": {"path": "$.nodes.review.output.score", "op": "gte", "value": 0.8}},
+		{"from": "illustrate", "to": "publish"}
+	],
+	"output": {"published": "$.nodes.publish.output.url"}
+}`
+
+func TestParseJSON_Valid(t *testing.T) {
+	def, err := ParseJSON([]byte(pipelineJSON))
+	require.NoError(t, err)
+	assert.Equal(t, "content", def.Type)
+	assert.Len(t, def.Nodes, 4)
+
+	node, ok := def.Node("publish")
+	require.True(t, ok)
+	assert.Equal(t, "publish", node.DisplayName())
+
+	reparsed, err := Parse(def.ToMap())
+	require.NoError(t, err)
+	assert.Equal(t, def, reparsed)
+}
+
+func TestDefinition_Validate(t *testing.T) {
+	skill := func(id string) Node { return Node{ID: id, Type: NodeSkill, SkillCardID: skillA} }
+
+	tests := []struct {
+		name    string
+		def     Definition
+		problem string
+	}{
+		{"unsupported version", Definition{Version: 2, Nodes: []Node{skill("a")}}, "unsupported version 2"},
+		{"no nodes", Definition{Version: 1}, "at least one node"},
+		{"duplicate node", Definition{Version: 1, Nodes: []Node{skill("a"), skill("a")}}, `duplicate node id "a"`},
+		{"unknown type", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: "teleport"}}}, "unknown type"},
+		{"missing skill card", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeSkill}}}, "valid skill_card_id"},
+		{"approval with skill card", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeApproval, SkillCardID: skillA}}}, "approval nodes take no skill_card_id"},
+		{"publish with skill card", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodePublish, SkillCardID: skillA}}}, "publish nodes take no skill_card_id"},
+		{"hotspots with skill card", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeHotspots, SkillCardID: skillA}}}, "hotspots nodes take no skill_card_id"},
+		{"unknown default action", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeApproval, DefaultAction: "shrug"}}}, `unknown default_action "shrug"`},
+		{"skill with default action", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeSkill, SkillCardID: skillA, DefaultAction: "approve"}}}, "only approval nodes"},
+		{"bad employee", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeSkill, SkillCardID: skillA, EmployeeID: "bob"}}}, "invalid employee_id"},
+		{"unknown edge node", Definition{Version: 1, Nodes: []Node{skill("a")}, Edges: []Edge{{From: "a", To: "b"}}}, "unknown node"},
+		{"cycle", Definition{Version: 1, Nodes: []Node{skill("a"), skill("b")}, Edges: []Edge{{From: "a", To: "b"}, {From: "b", To: "a"}}}, "cycle"},
+		{
+			"input reads a later node",
+			Definition{Version: 1, Nodes: []Node{
+				{ID: "a", Type: NodeSkill, SkillCardID: skillA, Inputs: map[string]interface{}{"x": "$.nodes.b.output.x"}},
+				skill("b"),
+			}, Edges: []Edge{{From: "a", To: "b"}}},
+			"has not run",
+		},
+		{
+			"bad path",
+			Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeSkill, SkillCardID: skillA, Inputs: map[string]interface{}{"x": "$.env.HOME"}}}},
+			"invalid path",
+		},
+		{
+			"unknown operator",
+			Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeSkill, SkillCardID: skillA, Condition: &Condition{Path: "$.input.x", Op: "like"}}}},
+			"unknown operator",
+		},
+		{"map without map", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeMap, SkillCardID: skillA}}}, `node "a" needs a map`},
+		{"map on skill node", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeSkill, SkillCardID: skillA, Map: &MapSpec{Items: "$.input.x"}}}}, "only map nodes have a map"},
+		{"map items literal", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeMap, SkillCardID: skillA, Map: &MapSpec{Items: "products"}}}}, "map items must be an expression"},
+		{
+			"map items read a later node",
+			Definition{Version: 1, Nodes: []Node{
+				{ID: "a", Type: NodeMap, SkillCardID: skillA, Map: &MapSpec{Items: "$.nodes.b.output.list"}},
+				skill("b"),
+			}, Edges: []Edge{{From: "a", To: "b"}}},
+			"map items: path",
+		},
+		{
+			"map with skill and workflow",
+			Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeMap, SkillCardID: skillA, Map: &MapSpec{Items: "$.input.x", Workflow: &Definition{Nodes: []Node{skill("c")}}}}}},
+			"not both",
+		},
+		{
+			"map workflow invalid",
+			Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeMap, Map: &MapSpec{Items: "$.input.x", Workflow: &Definition{}}}}},
+			`node "a" workflow: at least one node`,
+		},
+		{
+			"map workflow with approval",
+			Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeMap, Map: &MapSpec{Items: "$.input.x", Workflow: &Definition{Nodes: []Node{{ID: "ok", Type: NodeApproval}}}}}}},
+			"cannot wait for approvals",
+		},
+		{"map concurrency", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeMap, SkillCardID: skillA, Map: &MapSpec{Items: "$.input.x", Concurrency: 51}}}}, "concurrency must be between"},
+		{"map require count", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeMap, SkillCardID: skillA, Map: &MapSpec{Items: "$.input.x", Require: MapRequireCount}}}}, "positive require_count"},
+		{"map unknown require", Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeMap, SkillCardID: skillA, Map: &MapSpec{Items: "$.input.x", Require: "most"}}}}, `unknown map require "most"`},
+		{
+			"rank without judge",
+			Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeMap, SkillCardID: skillA, Map: &MapSpec{Items: "$.input.x", Reduce: &MapReduce{Strategy: MapReduceRank}}}}},
+			"valid judge_skill_card_id",
+		},
+		{
+			"unknown reduce",
+			Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeMap, SkillCardID: skillA, Map: &MapSpec{Items: "$.input.x", Reduce: &MapReduce{Strategy: "vote"}}}}},
+			`unknown reduce strategy "vote"`,
+		},
+		{
+			"compensation on approval node",
+			Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeApproval, Compensate: &Compensation{SkillCardID: skillB}}}},
+			"only skill and map nodes have a compensation",
+		},
+		{
+			"compensation on publish node",
+			Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodePublish, Compensate: &Compensation{SkillCardID: skillB}}}},
+			"only skill and map nodes have a compensation",
+		},
+		{
+			"compensation without skill card",
+			Definition{Version: 1, Nodes: []Node{{ID: "a", Type: NodeSkill, SkillCardID: skillA, Compensate: &Compensation{}}}},
+			"valid compensation skill_card_id",
+		},
+		{
+			"compensation reads a later node",
+			Definition{Version: 1, Nodes: []Node{
+				{ID: "a", Type: NodeSkill, SkillCardID: skillA, Compensate: &Compensation{SkillCardID: skillB, Inputs: map[string]interface{}{"x": "$.nodes.b.output.x"}}},
+				skill("b"),
+			}, Edges: []Edge{{From: "a", To: "b"}}},
+			`node "a" compensation input: path`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.def.Validate()
+			require.Error(t, err)
+
+			var validationErr *ValidationError
+			require.ErrorAs(t, err, &validationErr)
+			assert.Contains(t, err.Error(), tt.problem)
+		})
+	}
+}
+
+func TestParseJSON_RejectsUnknownFields(t *testing.T) {
+	_, err := ParseJSON([]byte(`{"version": 1, "nodes": [], "steps": []}`))
+	assert.Error(t, err)
+}
+
+func TestDeclared(t *testing.T) {
+	assert.True(t, Declared(map[string]interface{}{"nodes": []interface{}{}}))
+	assert.False(t, Declared(map[string]interface{}{"type": "content"}))
+	assert.False(t, Declared(nil))
+}
+
+func TestDefinition_Downstream(t *testing.T) {
+	def, err := ParseJSON([]byte(pipelineJSON))
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]bool{"review": true, "publish": true}, def.Downstream("review"))
+	assert.Equal(t, map[string]bool{"publish": true}, def.Downstream("publish"))
+	assert.Len(t, def.Downstream("draft"), 4)
+}
+
+func TestDefinition_SkillCardIDs(t *testing.T) {
+	def := &Definition{Version: 1, Nodes: []Node{
+		{ID: "draft", Type: NodeSkill, SkillCardID: "write", Compensate: &Compensation{SkillCardID: "retract"}},
+		{ID: "each", Type: NodeMap, Map: &MapSpec{Workflow: &Definition{Nodes: []Node{
+			{ID: "polish", Type: NodeSkill, SkillCardID: "polish"},
+			{ID: "again", Type: NodeSkill, SkillCardID: "write"},
+		}}}},
+		{ID: "review", Type: NodeApproval},
+	}}
+
+	assert.Equal(t, []string{"write", "retract", "polish"}, def.SkillCardIDs())
+}
@@ -0,0 +1,320 @@
+package workflow
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// expressionPrefix marks a string as a path into the workflow data
+const expressionPrefix = "$."
+
+// Operator compares the value at a condition path
+type Operator string
+
+const (
+	OpEq        Operator = "eq"
+	OpNeq       Operator = "neq"
+	OpGt        Operator = "gt"
+	OpGte       Operator = "gte"
+	OpLt        Operator = "lt"
+	OpLte       Operator = "lte"
+	OpExists    Operator = "exists"
+	OpNotExists Operator = "not_exists"
+	OpContains  Operator = "contains"
+	OpIn        Operator = "in"
+)
+
+var operators = map[Operator]bool{
+	OpEq: true, OpNeq: true, OpGt: true, OpGte: true, OpLt: true, OpLte: true,
+	OpExists: true, OpNotExists: true, OpContains: true, OpIn: true,
+}
+
+// Condition is either a comparison of the value at Path with Value, or a
+// combination of conditions with All, Any or Not
+type Condition struct {
+	Path  string      `json:"path,omitempty"`
+	Op    Operator    `json:"op,omitempty"`
+	Value interface{} `json:"value,omitempty"`
+	All   []Condition `json:"all,omitempty"`
+	Any   []Condition `json:"any,omitempty"`
+	Not   *Condition  `json:"not,omitempty"`
+}
+
+// Scope is the data expressions read: the workflow input and node outputs
+type Scope struct {
+	Input map[string]interface{}
+	Nodes map[string]map[string]interface{}
+}
+
+// Lookup returns the value at a path such as "$.input.topic" or
+// "$.nodes.draft.output.tags.0"
+func (s Scope) Lookup(path string) (interface{}, bool) {
+	segments, ok := splitPath(path)
+	if !ok {
+		return nil, false
+	}
+
+	var current interface{}
+	switch segments[0] {
+	case "input":
+		current, segments = s.Input, segments[1:]
+	case "nodes":
+		output, ok := s.Nodes[segments[1]]
+		if !ok {
+			return nil, false
+		}
+		current, segments = output, segments[3:]
+	}
+
+	for _, segment := range segments {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			next, ok := value[segment]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, false
+			}
+			current = value[index]
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// Resolve replaces expressions in a mapping value, recursing into maps and
+// lists. Expressions that find nothing resolve to nil.
+func (s Scope) Resolve(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, expressionPrefix) {
+			resolved, _ := s.Lookup(v)
+			return resolved
+		}
+		return v
+	case map[string]interface{}:
+		resolved := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved[key] = s.Resolve(item)
+		}
+		return resolved
+	case []interface{}:
+		resolved := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved[i] = s.Resolve(item)
+		}
+		return resolved
+	}
+	return value
+}
+
+// Evaluate reports whether the condition holds; a nil condition always holds
+func (c *Condition) Evaluate(scope Scope) bool {
+	if c == nil {
+		return true
+	}
+
+	switch {
+	case len(c.All) > 0:
+		for i := range c.All {
+			if !c.All[i].Evaluate(scope) {
+				return false
+			}
+		}
+		return true
+	case len(c.Any) > 0:
+		for i := range c.Any {
+			if c.Any[i].Evaluate(scope) {
+				return true
+			}
+		}
+		return false
+	case c.Not != nil:
+		return !c.Not.Evaluate(scope)
+	}
+
+	actual, found := scope.Lookup(c.Path)
+	switch c.Op {
+	case OpExists:
+		return found && actual != nil
+	case OpNotExists:
+		return !found || actual == nil
+	case OpEq:
+		return found && equal(actual, c.Value)
+	case OpNeq:
+		return !found || !equal(actual, c.Value)
+	case OpGt, OpGte, OpLt, OpLte:
+		a, ok1 := toFloat(actual)
+		b, ok2 := toFloat(c.Value)
+		if !found || !ok1 || !ok2 {
+			return false
+		}
+		switch c.Op {
+		case OpGt:
+			return a > b
+		case OpGte:
+			return a >= b
+		case OpLt:
+			return a < b
+		default:
+			return a <= b
+		}
+	case OpContains:
+		return found && contains(actual, c.Value)
+	case OpIn:
+		return found && contains(c.Value, actual)
+	}
+	return false
+}
+
+// check returns the problems of a condition given the nodes it may read
+func (c *Condition) check(available map[string]bool) []string {
+	if c == nil {
+		return nil
+	}
+
+	forms := 0
+	if c.Path != "" || c.Op != "" {
+		forms++
+	}
+	if len(c.All) > 0 {
+		forms++
+	}
+	if len(c.Any) > 0 {
+		forms++
+	}
+	if c.Not != nil {
+		forms++
+	}
+	if forms != 1 {
+		return []string{"use exactly one of path/op, all, any or not"}
+	}
+
+	var problems []string
+	switch {
+	case len(c.All) > 0:
+		for i := range c.All {
+			problems = append(problems, c.All[i].check(available)...)
+		}
+	case len(c.Any) > 0:
+		for i := range c.Any {
+			problems = append(problems, c.Any[i].check(available)...)
+		}
+	case c.Not != nil:
+		problems = append(problems, c.Not.check(available)...)
+	default:
+		if !operators[c.Op] {
+			problems = append(problems, fmt.Sprintf("unknown operator %q", c.Op))
+		}
+		if p := checkPath(c.Path, available); p != "" {
+			problems = append(problems, p)
+		}
+	}
+	return problems
+}
+
+// checkExpressions returns the problems of the expressions in a mapping
+func checkExpressions(value interface{}, available map[string]bool) []string {
+	var problems []string
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, expressionPrefix) {
+			if p := checkPath(v, available); p != "" {
+				problems = append(problems, p)
+			}
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			problems = append(problems, checkExpressions(item, available)...)
+		}
+	case []interface{}:
+		for _, item := range v {
+			problems = append(problems, checkExpressions(item, available)...)
+		}
+	}
+	return problems
+}
+
+// checkPath returns a problem with the path, or "" when it is valid and only
+// reads nodes that are available
+func checkPath(path string, available map[string]bool) string {
+	segments, ok := splitPath(path)
+	if !ok {
+		return fmt.Sprintf("invalid path %q, expected $.input... or $.nodes.<id>.output...", path)
+	}
+	if segments[0] == "nodes" && !available[segments[1]] {
+		return fmt.Sprintf("path %q reads node %q, which has not run at that point", path, segments[1])
+	}
+	return ""
+}
+
+// splitPath splits a path into segments, checking its root
+func splitPath(path string) ([]string, bool) {
+	if !strings.HasPrefix(path, expressionPrefix) {
+		return nil, false
+	}
+	segments := strings.Split(strings.TrimPrefix(path, expressionPrefix), ".")
+	for _, s := range segments {
+		if s == "" {
+			return nil, false
+		}
+	}
+
+	switch segments[0] {
+	case "input":
+		return segments, true
+	case "nodes":
+		return segments, len(segments) >= 3 && segments[2] == "output"
+	}
+	return nil, false
+}
+
+// equal compares two values, treating all numbers alike
+func equal(a, b interface{}) bool {
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			return x == y
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// contains reports whether a list holds the value or a string holds the substring
+func contains(container, value interface{}) bool {
+	switch c := container.(type) {
+	case string:
+		s, ok := value.(string)
+		return ok && strings.Contains(c, s)
+	case []interface{}:
+		for _, item := range c {
+			if equal(item, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// toFloat converts a numeric value to float64
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int32:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	}
+	return 0, false
+}
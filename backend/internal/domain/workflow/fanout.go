@@ -0,0 +1,316 @@
+package workflow
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// How many items of a map node must succeed
+const (
+	MapRequireAll   = "all"
+	MapRequireAny   = "any"
+	MapRequireCount = "count"
+)
+
+// How a map node combines the outputs of its items
+const (
+	// MapReduceConcat collects the outputs in item order
+	MapReduceConcat = "concat"
+	// MapReduceFirst picks the output of the first item that succeeded
+	MapReduceFirst = "first"
+	// MapReduceRank asks a judge skill card to pick the best output
+	MapReduceRank = "rank"
+)
+
+// Map node limits
+const (
+	DefaultMapConcurrency = 5
+	MaxMapConcurrency     = 50
+	MaxMapItems           = 1000
+)
+
+// MapSpec fans a map node out over a list: every item runs the node's skill
+// card, or the child workflow, with the node's inputs plus "item" and
+// "index". Once enough items are done the outputs are reduced to the node's output.
+type MapSpec struct {
+	// Items is an expression resolving to the list, e.g. "$.input.products"
+	Items string `json:"items"`
+	// Workflow is the child workflow run per item; it reads its item as
+	// "$.input.item". Without it the node's skill card runs per item.
+	Workflow *Definition `json:"workflow,omitempty"`
+	// Concurrency caps the items in flight; DefaultMapConcurrency by default
+	Concurrency int `json:"concurrency,omitempty"`
+	// Require is how many items must succeed: "all" (the default), "any",
+	// or "count" with RequireCount
+	Require      string     `json:"require,omitempty"`
+	RequireCount int        `json:"require_count,omitempty"`
+	Reduce       *MapReduce `json:"reduce,omitempty"`
+}
+
+// MapReduce configures how a map node combines the outputs of its items
+type MapReduce struct {
+	// Strategy is "concat" (the default), "first" or "rank"
+	Strategy string `json:"strategy,omitempty"`
+	// JudgeSkillCardID ranks the outputs; it gets them as "candidates"
+	// along with the criteria and answers with the "best" index
+	JudgeSkillCardID string `json:"judge_skill_card_id,omitempty"`
+	Criteria         string `json:"criteria,omitempty"`
+}
+
+// MapItemResult is how one item of a map node ended
+type MapItemResult struct {
+	Index  int
+	Output map[string]interface{}
+	Error  string
+	// Done is set once the item ran
+	Done bool
+}
+
+// Succeeded reports whether the item ran and produced an output
+func (r MapItemResult) Succeeded() bool {
+	return r.Done && r.Error == ""
+}
+
+// Limit returns how many items may be in flight at once
+func (m *MapSpec) Limit() int {
+	if m.Concurrency > 0 {
+		return m.Concurrency
+	}
+	return DefaultMapConcurrency
+}
+
+// Required returns how many of total items must succeed
+func (m *MapSpec) Required(total int) int {
+	switch m.Require {
+	case MapRequireAny:
+		if total == 0 {
+			return 0
+		}
+		return 1
+	case MapRequireCount:
+		if m.RequireCount < total {
+			return m.RequireCount
+		}
+		return total
+	}
+	return total
+}
+
+// Hopeless reports whether so many items failed that the requirement can
+// no longer be met; no further items need to run
+func (m *MapSpec) Hopeless(total, failed int) bool {
+	return failed > total-m.Required(total)
+}
+
+// Strategy returns the reduce strategy
+func (m *MapSpec) Strategy() string {
+	if m.Reduce == nil || m.Reduce.Strategy == "" {
+		return MapReduceConcat
+	}
+	return m.Reduce.Strategy
+}
+
+// NeedsJudge reports whether the outputs are ranked by a judge skill card
+func (m *MapSpec) NeedsJudge() bool {
+	return m.Strategy() == MapReduceRank
+}
+
+// ItemInput returns the input of one item: the node's inputs plus the item
+// and its index
+func ItemInput(params map[string]interface{}, item interface{}, index int) map[string]interface{} {
+	input := make(map[string]interface{}, len(params)+2)
+	for k, v := range params {
+		input[k] = v
+	}
+	input["item"] = item
+	input["index"] = index
+	return input
+}
+
+// JudgeInput returns the input of the judge skill card: the outputs of the
+// items that succeeded, in item order, and the criteria
+func (m *MapSpec) JudgeInput(results []MapItemResult) map[string]interface{} {
+	input := map[string]interface{}{"candidates": succeededOutputs(results)}
+	if m.Reduce != nil && m.Reduce.Criteria != "" {
+		input["criteria"] = m.Reduce.Criteria
+	}
+	return input
+}
+
+// Combine checks enough items succeeded and reduces their outputs to the
+// node's output: the outputs in "results", counts in "succeeded" and
+// "failed", the errors of failed items in "errors", and for the first and
+// rank strategies the chosen output in "result". judgement is the judge's
+// output for the rank strategy.
+func (m *MapSpec) Combine(results []MapItemResult, judgement map[string]interface{}) (map[string]interface{}, error) {
+	outputs := succeededOutputs(results)
+	var errs []interface{}
+	for _, r := range results {
+		if !r.Succeeded() {
+			message := r.Error
+			if !r.Done {
+				message = "not run"
+			}
+			errs = append(errs, map[string]interface{}{"index": r.Index, "error": message})
+		}
+	}
+
+	if required := m.Required(len(results)); len(outputs) < required {
+		message := fmt.Sprintf("%d of %d items succeeded, %d required", len(outputs), len(results), required)
+		if len(errs) > 0 {
+			first := errs[0].(map[string]interface{})
+			message = fmt.Sprintf("%s; item %d: %s", message, first["index"], first["error"])
+		}
+		return nil, fmt.Errorf("%s", message)
+	}
+
+	output := map[string]interface{}{
+		"results":   outputs,
+		"succeeded": len(outputs),
+		"failed":    len(results) - len(outputs),
+	}
+	if len(errs) > 0 {
+		output["errors"] = errs
+	}
+	if len(outputs) == 0 {
+		return output, nil
+	}
+
+	switch m.Strategy() {
+	case MapReduceFirst:
+		output["result"] = outputs[0]
+	case MapReduceRank:
+		output["result"] = outputs[pickBest(judgement, len(outputs))]
+		output["judgement"] = judgement
+	}
+	return output, nil
+}
+
+// succeededOutputs returns the outputs of the items that succeeded, in item order
+func succeededOutputs(results []MapItemResult) []interface{} {
+	outputs := make([]interface{}, 0, len(results))
+	for _, r := range results {
+		if r.Succeeded() {
+			outputs = append(outputs, r.Output)
+		}
+	}
+	return outputs
+}
+
+// pickBest reads the judge's choice among n candidates: its "best" index,
+// or the first index of its "ranking". Anything else picks the first candidate.
+func pickBest(judgement map[string]interface{}, n int) int {
+	candidates := []interface{}{judgement["best"]}
+	if ranking, ok := judgement["ranking"].([]interface{}); ok && len(ranking) > 0 {
+		candidates = append(candidates, ranking[0])
+	}
+	for _, c := range candidates {
+		if index, ok := toFloat(c); ok && index >= 0 && int(index) < n && index == float64(int(index)) {
+			return int(index)
+		}
+	}
+	return 0
+}
+
+// MapItems resolves the list a map node fans out over
+func (r *Run) MapItems(n *Node) ([]interface{}, error) {
+	value, ok := r.scope.Lookup(n.Map.Items)
+	if !ok || value == nil {
+		return nil, fmt.Errorf("items %s of node %s found nothing", n.Map.Items, n.ID)
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("items %s of node %s is not a list", n.Map.Items, n.ID)
+	}
+	if len(items) > MaxMapItems {
+		return nil, fmt.Errorf("node %s has %d items, at most %d are allowed", n.ID, len(items), MaxMapItems)
+	}
+	return items, nil
+}
+
+// checkMap returns the problems of a map node; version is the version of
+// the definition, which a child workflow without one inherits
+func (n *Node) checkMap(version int) []string {
+	var problems []string
+	addf := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	m := n.Map
+	if m == nil {
+		return []string{fmt.Sprintf("node %q needs a map", n.ID)}
+	}
+	if !strings.HasPrefix(m.Items, expressionPrefix) {
+		addf("node %q: map items must be an expression such as $.input.items", n.ID)
+	}
+
+	switch {
+	case m.Workflow != nil && n.SkillCardID != "":
+		addf("node %q: a map runs either a skill_card_id or a workflow per item, not both", n.ID)
+	case m.Workflow != nil:
+		child := *m.Workflow
+		if child.Version == 0 {
+			child.Version = version
+		}
+		if err := child.Validate(); err != nil {
+			for _, p := range err.(*ValidationError).Problems {
+				addf("node %q workflow: %s", n.ID, p)
+			}
+		}
+		if child.hasApproval() {
+			addf("node %q: a map's workflow cannot wait for approvals", n.ID)
+		}
+	default:
+		if _, err := uuid.Parse(n.SkillCardID); err != nil {
+			addf("node %q needs a valid skill_card_id or a workflow", n.ID)
+		}
+	}
+
+	if m.Concurrency < 0 || m.Concurrency > MaxMapConcurrency {
+		addf("node %q: map concurrency must be between 1 and %d", n.ID, MaxMapConcurrency)
+	}
+	switch m.Require {
+	case "", MapRequireAll, MapRequireAny:
+		if m.RequireCount != 0 {
+			addf("node %q: require_count needs require \"count\"", n.ID)
+		}
+	case MapRequireCount:
+		if m.RequireCount < 1 {
+			addf("node %q: require \"count\" needs a positive require_count", n.ID)
+		}
+	default:
+		addf("node %q has unknown map require %q", n.ID, m.Require)
+	}
+
+	if m.Reduce != nil {
+		switch m.Reduce.Strategy {
+		case "", MapReduceConcat, MapReduceFirst:
+			if m.Reduce.JudgeSkillCardID != "" {
+				addf("node %q: only the rank strategy has a judge", n.ID)
+			}
+		case MapReduceRank:
+			if _, err := uuid.Parse(m.Reduce.JudgeSkillCardID); err != nil {
+				addf("node %q: rank needs a valid judge_skill_card_id", n.ID)
+			}
+		default:
+			addf("node %q has unknown reduce strategy %q", n.ID, m.Reduce.Strategy)
+		}
+	}
+	return problems
+}
+
+// hasApproval reports whether the definition, or a map's workflow in it,
+// has an approval node
+func (d *Definition) hasApproval() bool {
+	for _, n := range d.Nodes {
+		if n.Type == NodeApproval {
+			return true
+		}
+		if n.Map != nil && n.Map.Workflow != nil && n.Map.Workflow.hasApproval() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// variantsJSON writes a caption per product, then publishes the best one
+const variantsJSON = `{
+	"version": 1,
+	"nodes": [
+		{"id": "captions", "type": "map", "skill_card_id": "` + skillA + `",
+			"inputs": {"tone": "$.input.tone"},
+			"map": {"items": "$.input.products", "concurrency": 2, "require": "count", "require_count": 2,
+				"reduce": {"strategy": "rank", "judge_skill_card_id": "` + skillB + `", "criteria": "catchy"}}},
+		{"id": "publish", "type": "skill", "skill_card_id": "` + skillA + `",
+			"inputs": {"caption": "$.nodes.captions.output.result.text"}}
+	],
+	"edges": [{"from": "captions", "to": "publish"}]
+}`
+
+func TestParseJSON_MapNode(t *testing.T) {
+	def, err := ParseJSON([]byte(variantsJSON))
+	require.NoError(t, err)
+
+	node, ok := def.Node("captions")
+	require.True(t, ok)
+	require.NotNil(t, node.Map)
+	assert.Equal(t, 2, node.Map.Limit())
+	assert.True(t, node.Map.NeedsJudge())
+
+	reparsed, err := Parse(def.ToMap())
+	require.NoError(t, err)
+	assert.Equal(t, def, reparsed)
+}
+
+func TestParseJSON_MapChildWorkflow(t *testing.T) {
+	raw := `{
+		"version": 1,
+		"nodes": [{"id": "each", "type": "map", "map": {"items": "$.input.topics", "workflow": {
+			"nodes": [
+				{"id": "draft", "type": "skill", "skill_card_id": "` + skillA + `", "inputs": {"topic": "$.input.item"}},
+				{"id": "polish", "type": "skill", "skill_card_id": "` + skillB + `"}
+			],
+			"edges": [{"from": "draft", "to": "polish"}]
+		}}}]
+	}`
+	def, err := ParseJSON([]byte(raw))
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMapConcurrency, def.Nodes[0].Map.Limit())
+}
+
+func TestRun_MapItems(t *testing.T) {
+	def, err := ParseJSON([]byte(variantsJSON))
+	require.NoError(t, err)
+	node, _ := def.Node("captions")
+
+	run := NewRun(def, map[string]interface{}{"products": []interface{}{"mug", "tee"}, "tone": "fun"})
+	require.Len(t, run.Next(), 1)
+	items, err := run.MapItems(node)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"mug", "tee"}, items)
+	assert.Equal(t, map[string]interface{}{"tone": "fun", "item": "tee", "index": 1}, ItemInput(run.Inputs(node), items[1], 1))
+
+	_, err = NewRun(def, map[string]interface{}{"products": "mug"}).MapItems(node)
+	assert.ErrorContains(t, err, "not a list")
+	_, err = NewRun(def, nil).MapItems(node)
+	assert.ErrorContains(t, err, "found nothing")
+}
+
+func TestMapSpec_Requirements(t *testing.T) {
+	all := &MapSpec{}
+	assert.Equal(t, 4, all.Required(4))
+	assert.False(t, all.Hopeless(4, 0))
+	assert.True(t, all.Hopeless(4, 1))
+
+	anyOf := &MapSpec{Require: MapRequireAny}
+	assert.Equal(t, 1, anyOf.Required(4))
+	assert.False(t, anyOf.Hopeless(4, 3))
+	assert.True(t, anyOf.Hopeless(4, 4))
+
+	count := &MapSpec{Require: MapRequireCount, RequireCount: 3}
+	assert.Equal(t, 3, count.Required(4))
+	assert.Equal(t, 2, count.Required(2), "never more than there are items")
+	assert.False(t, count.Hopeless(4, 1))
+	assert.True(t, count.Hopeless(4, 2))
+}
+
+func TestMapSpec_Combine(t *testing.T) {
+	results := []MapItemResult{
+		{Index: 0, Done: true, Output: map[string]interface{}{"text": "a"}},
+		{Index: 1, Done: true, Error: "model refused"},
+		{Index: 2, Done: true, Output: map[string]interface{}{"text": "c"}},
+	}
+
+	t.Run("concat", func(t *testing.T) {
+		_, err := (&MapSpec{}).Combine(results, nil)
+		assert.EqualError(t, err, "2 of 3 items succeeded, 3 required; item 1: model refused")
+
+		output, err := (&MapSpec{Require: MapRequireAny}).Combine(results, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{map[string]interface{}{"text": "a"}, map[string]interface{}{"text": "c"}}, output["results"])
+		assert.Equal(t, 2, output["succeeded"])
+		assert.Equal(t, 1, output["failed"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"index": 1, "error": "model refused"}}, output["errors"])
+		assert.NotContains(t, output, "result")
+	})
+
+	t.Run("first", func(t *testing.T) {
+		spec := &MapSpec{Require: MapRequireAny, Reduce: &MapReduce{Strategy: MapReduceFirst}}
+		output, err := spec.Combine(results, nil)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{"text": "a"}, output["result"])
+	})
+
+	t.Run("rank", func(t *testing.T) {
+		spec := &MapSpec{Require: MapRequireAny, Reduce: &MapReduce{Strategy: MapReduceRank, JudgeSkillCardID: skillB, Criteria: "short"}}
+		assert.Equal(t, map[string]interface{}{
+			"candidates": []interface{}{map[string]interface{}{"text": "a"}, map[string]interface{}{"text": "c"}},
+			"criteria":   "short",
+		}, spec.JudgeInput(results))
+
+		judgements := []struct {
+			judgement map[string]interface{}
+			want      string
+		}{
+			{map[string]interface{}{"best": float64(1)}, "c"},
+			{map[string]interface{}{"ranking": []interface{}{float64(1), float64(0)}}, "c"},
+			{map[string]interface{}{"best": float64(7)}, "a"},
+			{map[string]interface{}{"answer": "the second"}, "a"},
+		}
+		for _, j := range judgements {
+			output, err := spec.Combine(results, j.judgement)
+			require.NoError(t, err)
+			assert.Equal(t, map[string]interface{}{"text": j.want}, output["result"], "judgement %v", j.judgement)
+			assert.Equal(t, j.judgement, output["judgement"])
+		}
+	})
+
+	t.Run("items that did not run", func(t *testing.T) {
+		partial := append([]MapItemResult{}, results...)
+		partial[1] = MapItemResult{Index: 1}
+		_, err := (&MapSpec{}).Combine(partial, nil)
+		assert.EqualError(t, err, "2 of 3 items succeeded, 3 required; item 1: not run")
+	})
+}
@@ -0,0 +1,53 @@
+package workflow
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// TemplateFilter narrows a template listing
+type TemplateFilter struct {
+	Category string
+	// Source is "official" for system templates, "mine" for the company's own
+	// or "community" for the public templates of other companies
+	Source string
+}
+
+// Template sources a listing can be narrowed to
+const (
+	SourceOfficial  = "official"
+	SourceMine      = "mine"
+	SourceCommunity = "community"
+)
+
+// IsValidSource checks if a template source is valid
+func IsValidSource(source string) bool {
+	switch source {
+	case SourceOfficial, SourceMine, SourceCommunity:
+		return true
+	}
+	return false
+}
+
+// TemplateRepository stores workflow templates and their versions
+type TemplateRepository interface {
+	// CreateTemplate creates a template with its first version
+	CreateTemplate(ctx context.Context, template *Template, version *TemplateVersion) error
+	// GetTemplate returns a template with its usage and latest version; returns errors.ErrNotFound if it does not exist
+	GetTemplate(ctx context.Context, id uuid.UUID) (*Template, error)
+	// ListTemplates lists the system templates, the company's own and the
+	// public ones of other companies with their usage and latest versions
+	ListTemplates(ctx context.Context, companyID uuid.UUID, filter TemplateFilter) ([]*Template, error)
+
+	// CreateVersion publishes a version; returns a conflict error if the version number is taken
+	CreateVersion(ctx context.Context, version *TemplateVersion) error
+	// GetVersion returns a version of a template; returns errors.ErrNotFound if it does not exist
+	GetVersion(ctx context.Context, templateID uuid.UUID, version string) (*TemplateVersion, error)
+	// ListVersions lists the versions of a template, newest first
+	ListVersions(ctx context.Context, templateID uuid.UUID) ([]*TemplateVersion, error)
+
+	// RecordUse records that a task was created from a version, counting it
+	// in the template's usage and success rate
+	RecordUse(ctx context.Context, version *TemplateVersion, taskID uuid.UUID) error
+}
@@ -0,0 +1,202 @@
+package workflow
+
+// NodeStatus is the state of a node during a run
+type NodeStatus string
+
+const (
+	NodePending   NodeStatus = "pending"
+	NodeRunning   NodeStatus = "running"
+	NodeCompleted NodeStatus = "completed"
+	NodeFailed    NodeStatus = "failed"
+	NodeSkipped   NodeStatus = "skipped"
+)
+
+// isFinished reports whether the node will not change any more
+func (s NodeStatus) isFinished() bool {
+	return s == NodeCompleted || s == NodeFailed || s == NodeSkipped
+}
+
+// Run walks a definition. It holds no I/O and makes every decision in
+// definition order, so engines such as a Temporal workflow can drive it
+// deterministically: call Next, execute the returned nodes, report each
+// with Complete or Fail, and repeat until Done.
+type Run struct {
+	def      *Definition
+	scope    Scope
+	status   map[string]NodeStatus
+	errors   map[string]string
+	incoming map[string][]Edge
+	outgoing map[string][]Edge
+	failed   bool
+}
+
+// NewRun starts a run of the definition with the given workflow input
+func NewRun(def *Definition, input map[string]interface{}) *Run {
+	if input == nil {
+		input = make(map[string]interface{})
+	}
+
+	r := &Run{
+		def:      def,
+		scope:    Scope{Input: input, Nodes: make(map[string]map[string]interface{})},
+		status:   make(map[string]NodeStatus, len(def.Nodes)),
+		errors:   make(map[string]string),
+		incoming: def.predecessors(),
+		outgoing: def.successors(),
+	}
+	for _, n := range def.Nodes {
+		r.status[n.ID] = NodePending
+	}
+	return r
+}
+
+// Next marks the nodes that can start as running and returns them in
+// definition order. A node can start once every predecessor has finished
+// and at least one incoming edge was taken; nodes ruled out by their
+// edges or condition are skipped. Nothing starts after a failure.
+func (r *Run) Next() []*Node {
+	if r.failed {
+		return nil
+	}
+
+	var ready []*Node
+	for changed := true; changed; {
+		changed = false
+		for i := range r.def.Nodes {
+			n := &r.def.Nodes[i]
+			if r.status[n.ID] != NodePending {
+				continue
+			}
+
+			runnable, decided := r.evaluate(n)
+			if !decided {
+				continue
+			}
+			if runnable {
+				r.status[n.ID] = NodeRunning
+				ready = append(ready, n)
+			} else {
+				r.status[n.ID] = NodeSkipped
+			}
+			changed = true
+		}
+	}
+	return ready
+}
+
+// evaluate decides whether a pending node runs, once its predecessors finished
+func (r *Run) evaluate(n *Node) (runnable, decided bool) {
+	edges := r.incoming[n.ID]
+	taken := len(edges) == 0
+	for _, e := range edges {
+		status := r.status[e.From]
+		if !status.isFinished() {
+			return false, false
+		}
+		if status == NodeCompleted && e.Condition.Evaluate(r.scope) {
+			taken = true
+		}
+	}
+
+	return taken && n.Condition.Evaluate(r.scope), true
+}
+
+// Inputs resolves the parameters of a node against the data produced so far
+func (r *Run) Inputs(n *Node) map[string]interface{} {
+	if len(n.Inputs) > 0 {
+		return r.scope.Resolve(n.Inputs).(map[string]interface{})
+	}
+
+	params := make(map[string]interface{}, len(r.scope.Input))
+	for k, v := range r.scope.Input {
+		params[k] = v
+	}
+	for _, e := range r.incoming[n.ID] {
+		for k, v := range r.scope.Nodes[e.From] {
+			params[k] = v
+		}
+	}
+	return params
+}
+
+// Complete records the output of a running node
+func (r *Run) Complete(id string, output map[string]interface{}) {
+	if output == nil {
+		output = make(map[string]interface{})
+	}
+	r.status[id] = NodeCompleted
+	r.scope.Nodes[id] = output
+}
+
+// Fail records a failed node; the run starts no further nodes
+func (r *Run) Fail(id, message string) {
+	r.status[id] = NodeFailed
+	r.errors[id] = message
+	r.failed = true
+}
+
+// Done reports whether no node is running and none can start
+func (r *Run) Done() bool {
+	for _, status := range r.status {
+		if status == NodeRunning {
+			return false
+		}
+		if status == NodePending && !r.failed {
+			return false
+		}
+	}
+	return true
+}
+
+// Failed reports whether a node failed
+func (r *Run) Failed() bool {
+	return r.failed
+}
+
+// Status returns the status of a node
+func (r *Run) Status(id string) NodeStatus {
+	return r.status[id]
+}
+
+// Error returns the failure message of a node
+func (r *Run) Error(id string) string {
+	return r.errors[id]
+}
+
+// NodeOutput returns the output of a completed node
+func (r *Run) NodeOutput(id string) map[string]interface{} {
+	return r.scope.Nodes[id]
+}
+
+// Progress returns the percentage of nodes that finished
+func (r *Run) Progress() int {
+	if len(r.def.Nodes) == 0 {
+		return 100
+	}
+	finished := 0
+	for _, status := range r.status {
+		if status.isFinished() {
+			finished++
+		}
+	}
+	return finished * 100 / len(r.def.Nodes)
+}
+
+// Output returns the workflow result: the definition's output mapping, or
+// the merged outputs of completed nodes without outgoing edges
+func (r *Run) Output() map[string]interface{} {
+	if len(r.def.Output) > 0 {
+		return r.scope.Resolve(r.def.Output).(map[string]interface{})
+	}
+
+	output := make(map[string]interface{})
+	for _, n := range r.def.Nodes {
+		if len(r.outgoing[n.ID]) > 0 || r.status[n.ID] != NodeCompleted {
+			continue
+		}
+		for k, v := range r.scope.Nodes[n.ID] {
+			output[k] = v
+		}
+	}
+	return output
+}
@@ -0,0 +1,133 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func nodeIDs(nodes []*Node) []string {
+	ids := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		ids = append(ids, n.ID)
+	}
+	return ids
+}
+
+func TestRun_ParallelBranchesAndJoin(t *testing.T) {
+	def, err := ParseJSON([]byte(pipelineJSON))
+	require.NoError(t, err)
+
+	run := NewRun(def, map[string]interface{}{"topic": "go", "with_images": true})
+
+	ready := run.Next()
+	require.Equal(t, []string{"draft"}, nodeIDs(ready))
+	assert.Equal(t, map[string]interface{}{"topic": "go"}, run.Inputs(ready[0]))
+	assert.Empty(t, run.Next(), "nothing else can start while draft runs")
+
+	run.Complete("draft", map[string]interface{}{"content": "text"})
+	ready = run.Next()
+	assert.Equal(t, []string{"review", "illustrate"}, nodeIDs(ready), "branches start together")
+	assert.Equal(t, "text", run.Inputs(ready[0])["content"], "nodes without inputs receive their predecessors' outputs")
+
+	run.Complete("review", map[string]interface{}{"score": 0.9})
+	assert.Empty(t, run.Next(), "publish waits for both branches")
+
+	run.Complete("illustrate", map[string]interface{}{"image": "cat.png"})
+	ready = run.Next()
+	require.Equal(t, []string{"publish"}, nodeIDs(ready))
+	assert.Equal(t, map[string]interface{}{"text": "text", "score": 0.9}, run.Inputs(ready[0]))
+	assert.Equal(t, 75, run.Progress())
+
+	run.Complete("publish", map[string]interface{}{"url": "https://example.com/post"})
+	assert.True(t, run.Done())
+	assert.False(t, run.Failed())
+	assert.Equal(t, map[string]interface{}{"published": "https://example.com/post"}, run.Output())
+	assert.Equal(t, 100, run.Progress())
+}
+
+func TestRun_ConditionsSkipNodes(t *testing.T) {
+	def, err := ParseJSON([]byte(pipelineJSON))
+	require.NoError(t, err)
+
+	run := NewRun(def, map[string]interface{}{"topic": "go", "with_images": false})
+	run.Next()
+	run.Complete("draft", map[string]interface{}{"content": "text"})
+
+	assert.Equal(t, []string{"review"}, nodeIDs(run.Next()))
+	assert.Equal(t, NodeSkipped, run.Status("illustrate"))
+
+	// A low score does not take the review -> publish edge, and the skipped
+	// branch takes none, so publish is skipped too
+	run.Complete("review", map[string]interface{}{"score": 0.5})
+	assert.Empty(t, run.Next())
+	assert.Equal(t, NodeSkipped, run.Status("publish"))
+	assert.True(t, run.Done())
+}
+
+func TestRun_FailureStopsNewNodes(t *testing.T) {
+	def, err := ParseJSON([]byte(pipelineJSON))
+	require.NoError(t, err)
+
+	run := NewRun(def, map[string]interface{}{"with_images": true})
+	run.Next()
+	run.Complete("draft", nil)
+	run.Next()
+
+	run.Fail("review", "provider down")
+	assert.False(t, run.Done(), "illustrate is still running")
+	run.Complete("illustrate", nil)
+
+	assert.Empty(t, run.Next())
+	assert.True(t, run.Done())
+	assert.True(t, run.Failed())
+	assert.Equal(t, "provider down", run.Error("review"))
+	assert.Equal(t, NodePending, run.Status("publish"))
+}
+
+func TestRun_DefaultOutputMergesSinks(t *testing.T) {
+	def := &Definition{Version: 1, Nodes: []Node{
+		{ID: "a", Type: NodeSkill, SkillCardID: skillA},
+		{ID: "b", Type: NodeSkill, SkillCardID: skillB},
+	}}
+	require.NoError(t, def.Validate())
+
+	run := NewRun(def, nil)
+	assert.Len(t, run.Next(), 2)
+	run.Complete("a", map[string]interface{}{"x": 1.0})
+	run.Complete("b", map[string]interface{}{"y": 2.0})
+
+	assert.True(t, run.Done())
+	assert.Equal(t, map[string]interface{}{"x": 1.0, "y": 2.0}, run.Output())
+}
+
+func TestCondition_Evaluate(t *testing.T) {
+	scope := Scope{
+		Input: map[string]interface{}{"n": 3.0, "tags": []interface{}{"ai", "go"}, "title": "Hello"},
+		Nodes: map[string]map[string]interface{}{"a": {"ok": true}},
+	}
+
+	tests := []struct {
+		cond Condition
+		want bool
+	}{
+		{Condition{Path: "$.input.n", Op: OpEq, Value: 3}, true},
+		{Condition{Path: "$.input.n", Op: OpGt, Value: 5.0}, false},
+		{Condition{Path: "$.input.n", Op: OpLte, Value: 3.0}, true},
+		{Condition{Path: "$.input.tags", Op: OpContains, Value: "go"}, true},
+		{Condition{Path: "$.input.title", Op: OpContains, Value: "ell"}, true},
+		{Condition{Path: "$.input.title", Op: OpIn, Value: []interface{}{"Hi", "Hello"}}, true},
+		{Condition{Path: "$.input.missing", Op: OpExists}, false},
+		{Condition{Path: "$.input.missing", Op: OpNeq, Value: 1.0}, true},
+		{Condition{Path: "$.input.tags.1", Op: OpEq, Value: "go"}, true},
+		{Condition{Path: "$.nodes.a.output.ok", Op: OpEq, Value: true}, true},
+		{Condition{All: []Condition{{Path: "$.input.n", Op: OpGt, Value: 1.0}, {Path: "$.input.n", Op: OpLt, Value: 2.0}}}, false},
+		{Condition{Any: []Condition{{Path: "$.input.n", Op: OpGt, Value: 1.0}, {Path: "$.input.n", Op: OpLt, Value: 2.0}}}, true},
+		{Condition{Not: &Condition{Path: "$.input.n", Op: OpExists}}, false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, tt.cond.Evaluate(scope), "%+v", tt.cond)
+	}
+}
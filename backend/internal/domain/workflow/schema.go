@@ -0,0 +1,229 @@
+package workflow
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// Parameter types a ParamSchema property can have
+const (
+	ParamString  = "string"
+	ParamInteger = "integer"
+	ParamNumber  = "number"
+	ParamBoolean = "boolean"
+	ParamArray   = "array"
+	ParamObject  = "object"
+)
+
+var paramTypes = map[string]bool{
+	ParamString: true, ParamInteger: true, ParamNumber: true,
+	ParamBoolean: true, ParamArray: true, ParamObject: true,
+}
+
+// ParamSchema describes the parameters of a workflow template with the JSON
+// Schema subset the configuration form needs: an object of typed properties
+// with required names, enums, defaults and bounds
+type ParamSchema struct {
+	Type       string                    `json:"type"`
+	Required   []string                  `json:"required,omitempty"`
+	Properties map[string]*ParamProperty `json:"properties"`
+}
+
+// ParamProperty is one parameter of a ParamSchema
+type ParamProperty struct {
+	Type        string        `json:"type"`
+	Title       string        `json:"title,omitempty"`
+	Description string        `json:"description,omitempty"`
+	Enum        []interface{} `json:"enum,omitempty"`
+	Default     interface{}   `json:"default,omitempty"`
+	Minimum     *float64      `json:"minimum,omitempty"`
+	Maximum     *float64      `json:"maximum,omitempty"`
+	MinLength   *int          `json:"minLength,omitempty"`
+	MaxLength   *int          `json:"maxLength,omitempty"`
+	// Items is the type of the elements of an array parameter
+	Items *ParamProperty `json:"items,omitempty"`
+}
+
+// ParamsError lists every problem found in template parameters or their schema
+type ParamsError struct {
+	Problems []string `json:"problems"`
+}
+
+func (e *ParamsError) Error() string {
+	return "invalid parameters: " + strings.Join(e.Problems, "; ")
+}
+
+// Check validates the schema itself: known types, required parameters that
+// exist and defaults that satisfy their property
+func (s *ParamSchema) Check() error {
+	var problems []string
+	if s.Type != ParamObject {
+		problems = append(problems, `schema type must be "object"`)
+	}
+	for _, name := range s.names() {
+		p := s.Properties[name]
+		if p == nil {
+			problems = append(problems, fmt.Sprintf("parameter %q has no schema", name))
+			continue
+		}
+		problems = append(problems, p.check(name)...)
+	}
+	for _, name := range s.Required {
+		if _, ok := s.Properties[name]; !ok {
+			problems = append(problems, fmt.Sprintf("required parameter %q is not declared", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ParamsError{Problems: problems}
+	}
+	return nil
+}
+
+// Apply validates parameters against the schema and returns them with the
+// defaults of the missing ones filled in. Undeclared parameters are rejected.
+func (s *ParamSchema) Apply(params map[string]interface{}) (map[string]interface{}, error) {
+	var problems []string
+	result := make(map[string]interface{}, len(s.Properties))
+
+	for name, value := range params {
+		p, ok := s.Properties[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("unknown parameter %q", name))
+			continue
+		}
+		problems = append(problems, p.validate(name, value)...)
+		result[name] = value
+	}
+	for _, name := range s.names() {
+		if _, ok := result[name]; !ok && s.Properties[name].Default != nil {
+			result[name] = s.Properties[name].Default
+		}
+	}
+	for _, name := range s.Required {
+		if _, ok := result[name]; !ok {
+			problems = append(problems, fmt.Sprintf("parameter %q is required", name))
+		}
+	}
+
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return nil, &ParamsError{Problems: problems}
+	}
+	return result, nil
+}
+
+// names returns the declared parameter names in a stable order
+func (s *ParamSchema) names() []string {
+	names := make([]string, 0, len(s.Properties))
+	for name := range s.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// check validates the property's own schema
+func (p *ParamProperty) check(name string) []string {
+	var problems []string
+	if !paramTypes[p.Type] {
+		return []string{fmt.Sprintf("parameter %q has unknown type %q", name, p.Type)}
+	}
+	if p.Minimum != nil && p.Maximum != nil && *p.Minimum > *p.Maximum {
+		problems = append(problems, fmt.Sprintf("parameter %q has a minimum above its maximum", name))
+	}
+	if p.MinLength != nil && p.MaxLength != nil && *p.MinLength > *p.MaxLength {
+		problems = append(problems, fmt.Sprintf("parameter %q has a minLength above its maxLength", name))
+	}
+	if p.Items != nil {
+		if p.Type != ParamArray {
+			problems = append(problems, fmt.Sprintf("parameter %q: only arrays have items", name))
+		} else {
+			problems = append(problems, p.Items.check(name+"[]")...)
+		}
+	}
+	for _, v := range p.Enum {
+		if !p.hasType(v) {
+			problems = append(problems, fmt.Sprintf("parameter %q has an enum value of the wrong type", name))
+			break
+		}
+	}
+	if p.Default != nil {
+		for _, problem := range p.validate(name, p.Default) {
+			problems = append(problems, "default: "+problem)
+		}
+	}
+	return problems
+}
+
+// validate checks a value against the property
+func (p *ParamProperty) validate(name string, value interface{}) []string {
+	if !p.hasType(value) {
+		return []string{fmt.Sprintf("parameter %q must be of type %s", name, p.Type)}
+	}
+
+	var problems []string
+	if len(p.Enum) > 0 {
+		allowed := false
+		for _, v := range p.Enum {
+			if equal(v, value) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			problems = append(problems, fmt.Sprintf("parameter %q must be one of %v", name, p.Enum))
+		}
+	}
+	if n, ok := toFloat(value); ok {
+		if p.Minimum != nil && n < *p.Minimum {
+			problems = append(problems, fmt.Sprintf("parameter %q must be at least %v", name, *p.Minimum))
+		}
+		if p.Maximum != nil && n > *p.Maximum {
+			problems = append(problems, fmt.Sprintf("parameter %q must be at most %v", name, *p.Maximum))
+		}
+	}
+	if s, ok := value.(string); ok {
+		length := utf8.RuneCountInString(s)
+		if p.MinLength != nil && length < *p.MinLength {
+			problems = append(problems, fmt.Sprintf("parameter %q must be at least %d characters", name, *p.MinLength))
+		}
+		if p.MaxLength != nil && length > *p.MaxLength {
+			problems = append(problems, fmt.Sprintf("parameter %q must be at most %d characters", name, *p.MaxLength))
+		}
+	}
+	if items, ok := value.([]interface{}); ok && p.Items != nil {
+		for i, item := range items {
+			problems = append(problems, p.Items.validate(fmt.Sprintf("%s[%d]", name, i), item)...)
+		}
+	}
+	return problems
+}
+
+// hasType reports whether a decoded JSON value is of the property's type
+func (p *ParamProperty) hasType(value interface{}) bool {
+	switch p.Type {
+	case ParamString:
+		_, ok := value.(string)
+		return ok
+	case ParamInteger:
+		n, ok := toFloat(value)
+		return ok && n == math.Trunc(n)
+	case ParamNumber:
+		_, ok := toFloat(value)
+		return ok
+	case ParamBoolean:
+		_, ok := value.(bool)
+		return ok
+	case ParamArray:
+		_, ok := value.([]interface{})
+		return ok
+	case ParamObject:
+		_, ok := value.(map[string]interface{})
+		return ok
+	}
+	return false
+}
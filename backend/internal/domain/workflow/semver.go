@@ -0,0 +1,49 @@
+package workflow
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SemVer is a MAJOR.MINOR.PATCH template version
+type SemVer struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseSemVer parses a version such as "1.2.0"; a leading "v" is accepted
+func ParseSemVer(s string) (SemVer, error) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimSpace(s), "v"), ".")
+	if len(parts) != 3 {
+		return SemVer{}, fmt.Errorf("invalid version %q: want MAJOR.MINOR.PATCH", s)
+	}
+
+	var numbers [3]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil || n < 0 || (len(p) > 1 && p[0] == '0') {
+			return SemVer{}, fmt.Errorf("invalid version %q: want MAJOR.MINOR.PATCH", s)
+		}
+		numbers[i] = n
+	}
+	return SemVer{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// Compare returns -1, 0 or 1 as v is lower than, equal to or higher than other
+func (v SemVer) Compare(other SemVer) int {
+	for _, d := range [3]int{v.Major - other.Major, v.Minor - other.Minor, v.Patch - other.Patch} {
+		switch {
+		case d < 0:
+			return -1
+		case d > 0:
+			return 1
+		}
+	}
+	return 0
+}
+
+func (v SemVer) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
@@ -0,0 +1,188 @@
+package workflow
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Template categories
+const (
+	CategoryContent    = "content"
+	CategoryHotspot    = "hotspot"
+	CategoryOperations = "operations"
+	CategoryCustom     = "custom"
+)
+
+// IsValidCategory checks if a template category is valid
+func IsValidCategory(category string) bool {
+	switch category {
+	case CategoryContent, CategoryHotspot, CategoryOperations, CategoryCustom:
+		return true
+	}
+	return false
+}
+
+// Template is a reusable workflow in the catalog. System templates belong to
+// no company and are shared by all; a company's own templates are theirs,
+// and shared with the community when public. The workflow itself lives in
+// the template's versions.
+type Template struct {
+	ID          uuid.UUID  `json:"id"`
+	CompanyID   *uuid.UUID `json:"company_id,omitempty"`
+	Key         string     `json:"key"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Category    string     `json:"category"`
+	Thumbnail   string     `json:"thumbnail,omitempty"`
+	IsSystem    bool       `json:"is_system"`
+	IsPublic    bool       `json:"is_public"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	// Source is where the template comes from for the company viewing it:
+	// SourceOfficial, SourceMine or SourceCommunity
+	Source string `json:"source,omitempty"`
+	// UsageCount is how many tasks were created from the template
+	UsageCount int `json:"usage_count"`
+	// SuccessRate is the share of those tasks that completed among the ones
+	// that completed or failed; nil before any of them finished
+	SuccessRate *float64 `json:"success_rate"`
+	// RequiredSkillCards are the skill cards the latest version runs
+	RequiredSkillCards []SkillCardRef `json:"required_skill_cards"`
+	// Latest is the newest version, when loaded
+	Latest *TemplateVersion `json:"latest,omitempty"`
+}
+
+// SkillCardRef names a skill card a template needs
+type SkillCardRef struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// TemplateVersion is one published version of a template. Versions never
+// change; a new workflow or parameter schema is a new version.
+type TemplateVersion struct {
+	ID          uuid.UUID    `json:"id"`
+	TemplateID  uuid.UUID    `json:"template_id"`
+	Version     string       `json:"version"`
+	Definition  *Definition  `json:"definition"`
+	ParamSchema *ParamSchema `json:"param_schema"`
+	NodeCount   int          `json:"node_count"`
+	// EstimatedSeconds is how long a run usually takes
+	EstimatedSeconds int       `json:"estimated_seconds"`
+	Changelog        string    `json:"changelog,omitempty"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// NewTemplate creates a company's own template
+func NewTemplate(companyID uuid.UUID, key, name, description, category string) *Template {
+	now := time.Now()
+	return &Template{
+		ID:          uuid.New(),
+		CompanyID:   &companyID,
+		Key:         key,
+		Name:        name,
+		Description: description,
+		Category:    category,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+}
+
+// NewTemplateVersion creates a version of a template after validating its
+// version number, definition and parameter schema. A definition without a
+// name or type takes the template's name and key.
+func NewTemplateVersion(t *Template, version string, def *Definition, schema *ParamSchema, estimatedSeconds int, changelog string) (*TemplateVersion, error) {
+	semver, err := ParseSemVer(version)
+	if err != nil {
+		return nil, err
+	}
+	if def == nil {
+		return nil, fmt.Errorf("a template version needs a definition")
+	}
+	if err := def.Validate(); err != nil {
+		return nil, err
+	}
+	if schema == nil {
+		schema = &ParamSchema{Type: ParamObject, Properties: map[string]*ParamProperty{}}
+	}
+	if err := schema.Check(); err != nil {
+		return nil, err
+	}
+	if estimatedSeconds < 0 {
+		return nil, fmt.Errorf("estimated_seconds cannot be negative")
+	}
+
+	if def.Name == "" {
+		def.Name = t.Name
+	}
+	if def.Type == "" {
+		def.Type = t.Key
+	}
+	return &TemplateVersion{
+		ID:               uuid.New(),
+		TemplateID:       t.ID,
+		Version:          semver.String(),
+		Definition:       def,
+		ParamSchema:      schema,
+		NodeCount:        len(def.Nodes),
+		EstimatedSeconds: estimatedSeconds,
+		Changelog:        changelog,
+		CreatedAt:        time.Now(),
+	}, nil
+}
+
+// IsVisibleTo reports whether a company can see and use the template
+func (t *Template) IsVisibleTo(companyID uuid.UUID) bool {
+	return t.IsSystem || t.IsPublic || (t.CompanyID != nil && *t.CompanyID == companyID)
+}
+
+// SourceFor returns where the template comes from for a company
+func (t *Template) SourceFor(companyID uuid.UUID) string {
+	switch {
+	case t.IsSystem:
+		return SourceOfficial
+	case t.IsOwnedBy(companyID):
+		return SourceMine
+	default:
+		return SourceCommunity
+	}
+}
+
+// IsOwnedBy reports whether a company can publish versions of the template
+func (t *Template) IsOwnedBy(companyID uuid.UUID) bool {
+	return !t.IsSystem && t.CompanyID != nil && *t.CompanyID == companyID
+}
+
+// Follows reports whether the version can be published after latest: its
+// version number must be higher
+func (v *TemplateVersion) Follows(latest *TemplateVersion) bool {
+	if latest == nil {
+		return true
+	}
+	next, err := ParseSemVer(v.Version)
+	if err != nil {
+		return false
+	}
+	current, err := ParseSemVer(latest.Version)
+	if err != nil {
+		return true
+	}
+	return next.Compare(current) > 0
+}
+
+// Instantiate validates parameters against the version's schema and returns
+// the definition and input a task runs the version with
+func (v *TemplateVersion) Instantiate(params map[string]interface{}) (*Definition, map[string]interface{}, error) {
+	schema := v.ParamSchema
+	if schema == nil {
+		schema = &ParamSchema{Type: ParamObject}
+	}
+	input, err := schema.Apply(params)
+	if err != nil {
+		return nil, nil, err
+	}
+	return v.Definition, input, nil
+}
@@ -0,0 +1,141 @@
+package workflow
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSemVer(t *testing.T) {
+	v, err := ParseSemVer("v1.10.2")
+	require.NoError(t, err)
+	assert.Equal(t, SemVer{Major: 1, Minor: 10, Patch: 2}, v)
+	assert.Equal(t, "1.10.2", v.String())
+
+	for _, invalid := range []string{"", "1.2", "1.2.x", "1.02.0", "-1.0.0", "1.0.0-beta"} {
+		_, err := ParseSemVer(invalid)
+		assert.Error(t, err, invalid)
+	}
+
+	assert.Equal(t, 1, SemVer{1, 10, 0}.Compare(SemVer{1, 9, 3}))
+	assert.Equal(t, -1, SemVer{1, 0, 0}.Compare(SemVer{2, 0, 0}))
+	assert.Equal(t, 0, SemVer{1, 2, 3}.Compare(SemVer{1, 2, 3}))
+}
+
+func platformSchema() *ParamSchema {
+	one, ten := 1.0, 10.0
+	return &ParamSchema{
+		Type:     ParamObject,
+		Required: []string{"platform"},
+		Properties: map[string]*ParamProperty{
+			"platform": {Type: ParamString, Enum: []interface{}{"xiaohongshu", "douyin", "weibo"}},
+			"count":    {Type: ParamInteger, Default: 1.0, Minimum: &one, Maximum: &ten},
+			"keywords": {Type: ParamArray, Items: &ParamProperty{Type: ParamString}},
+		},
+	}
+}
+
+func TestParamSchema_Apply(t *testing.T) {
+	schema := platformSchema()
+	require.NoError(t, schema.Check())
+
+	params, err := schema.Apply(map[string]interface{}{"platform": "weibo"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"platform": "weibo", "count": 1.0}, params, "defaults are filled in")
+
+	tests := []struct {
+		name    string
+		params  map[string]interface{}
+		problem string
+	}{
+		{"missing required", map[string]interface{}{}, `"platform" is required`},
+		{"not in enum", map[string]interface{}{"platform": "twitter"}, "must be one of"},
+		{"not an integer", map[string]interface{}{"platform": "weibo", "count": 2.5}, "of type integer"},
+		{"above maximum", map[string]interface{}{"platform": "weibo", "count": 11.0}, "at most 10"},
+		{"wrong item type", map[string]interface{}{"platform": "weibo", "keywords": []interface{}{"go", 1.0}}, `"keywords[1]"`},
+		{"unknown", map[string]interface{}{"platform": "weibo", "colour": "red"}, `unknown parameter "colour"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := schema.Apply(tt.params)
+			var perr *ParamsError
+			require.ErrorAs(t, err, &perr)
+			assert.Contains(t, perr.Error(), tt.problem)
+		})
+	}
+}
+
+func TestParamSchema_Check(t *testing.T) {
+	five, one := 5.0, 1.0
+	schema := &ParamSchema{
+		Type:     ParamObject,
+		Required: []string{"topic"},
+		Properties: map[string]*ParamProperty{
+			"count": {Type: ParamInteger, Minimum: &five, Maximum: &one, Default: "many"},
+			"mood":  {Type: "colour"},
+		},
+	}
+
+	err := schema.Check()
+	var perr *ParamsError
+	require.ErrorAs(t, err, &perr)
+	problems := perr.Error()
+	assert.Contains(t, problems, "minimum above its maximum")
+	assert.Contains(t, problems, `default: parameter "count" must be of type integer`)
+	assert.Contains(t, problems, `unknown type "colour"`)
+	assert.Contains(t, problems, `required parameter "topic" is not declared`)
+}
+
+func TestTemplateVersion(t *testing.T) {
+	tpl := NewTemplate(uuid.New(), "weekly_digest", "Weekly digest", "", CategoryContent)
+	def := &Definition{
+		Version: 1,
+		Nodes:   []Node{{ID: "write", Type: NodeSkill, SkillCardID: uuid.New().String()}},
+	}
+
+	v1, err := NewTemplateVersion(tpl, "1.0.0", def, platformSchema(), 120, "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, v1.NodeCount)
+	assert.Equal(t, "Weekly digest", v1.Definition.Name)
+	assert.Equal(t, "weekly_digest", v1.Definition.Type, "tasks of the template get its key as type")
+
+	_, err = NewTemplateVersion(tpl, "one", def, nil, 0, "")
+	assert.Error(t, err)
+	_, err = NewTemplateVersion(tpl, "1.0.0", &Definition{Version: 1}, nil, 0, "")
+	assert.Error(t, err, "the definition is validated")
+
+	v2, err := NewTemplateVersion(tpl, "1.10.0", def, nil, 0, "")
+	require.NoError(t, err)
+	assert.True(t, v2.Follows(v1))
+	assert.False(t, v1.Follows(v2))
+	assert.False(t, v1.Follows(v1))
+	assert.True(t, v1.Follows(nil))
+
+	got, input, err := v1.Instantiate(map[string]interface{}{"platform": "douyin"})
+	require.NoError(t, err)
+	assert.Same(t, v1.Definition, got)
+	assert.Equal(t, 1.0, input["count"])
+}
+
+func TestTemplate_Visibility(t *testing.T) {
+	company := uuid.New()
+	own := NewTemplate(company, "mine", "Mine", "", CategoryCustom)
+	system := &Template{ID: uuid.New(), IsSystem: true}
+
+	assert.True(t, own.IsVisibleTo(company))
+	assert.False(t, own.IsVisibleTo(uuid.New()))
+	assert.True(t, own.IsOwnedBy(company))
+	assert.True(t, system.IsVisibleTo(company))
+	assert.False(t, system.IsOwnedBy(company), "system templates are read-only")
+
+	shared := NewTemplate(uuid.New(), "shared", "Shared", "", CategoryCustom)
+	shared.IsPublic = true
+	assert.True(t, shared.IsVisibleTo(company))
+	assert.False(t, shared.IsOwnedBy(company), "community templates are read-only")
+
+	assert.Equal(t, SourceMine, own.SourceFor(company))
+	assert.Equal(t, SourceOfficial, system.SourceFor(company))
+	assert.Equal(t, SourceCommunity, shared.SourceFor(company))
+}
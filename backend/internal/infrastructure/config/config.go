@@ -10,14 +10,20 @@ import (
 
 // Config 应用配置
 type Config struct {
-	App      AppConfig      `mapstructure:"app"`
-	Database DatabaseConfig `mapstructure:"database"`
-	Redis    RedisConfig    `mapstructure:"redis"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Log      LogConfig      `mapstructure:"log"`
-	Temporal TemporalConfig `mapstructure:"temporal"`
-	Kafka    KafkaConfig    `mapstructure:"kafka"`
-	MinIO    MinIOConfig    `mapstructure:"minio"`
+	App        AppConfig        `mapstructure:"app"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	Redis      RedisConfig      `mapstructure:"redis"`
+	JWT        JWTConfig        `mapstructure:"jwt"`
+	Log        LogConfig        `mapstructure:"log"`
+	Temporal   TemporalConfig   `mapstructure:"temporal"`
+	Workflow   WorkflowConfig   `mapstructure:"workflow"`
+	Kafka      KafkaConfig      `mapstructure:"kafka"`
+	MinIO      MinIOConfig      `mapstructure:"minio"`
+	Scheduler  SchedulerConfig  `mapstructure:"scheduler"`
+	Ops        OpsConfig        `mapstructure:"ops"`
+	Hotspot    HotspotConfig    `mapstructure:"hotspot"`
+	Publishing PublishingConfig `mapstructure:"publishing"`
+	EventBus   EventBusConfig   `mapstructure:"eventbus"`
 }
 
 // AppConfig 应用配置
@@ -82,11 +88,70 @@ type TemporalConfig struct {
 	TaskQueue string `mapstructure:"task_queue"`
 }
 
+// 工作流引擎
+const (
+	WorkflowEngineTemporal  = "temporal"  // 由 Temporal 集群执行，需单独运行 worker
+	WorkflowEngineInProcess = "inprocess" // 在服务进程内执行，步骤检查点写入数据库，适合单机部署
+)
+
+// WorkflowConfig 工作流执行配置
+type WorkflowConfig struct {
+	Engine string `mapstructure:"engine"` // temporal（默认）或 inprocess
+}
+
 // KafkaConfig Kafka配置
 type KafkaConfig struct {
 	Brokers []string `mapstructure:"brokers"`
 }
 
+// 事件总线驱动
+const (
+	EventBusDriverMemory = "memory" // 进程内投递，只有发布事件的进程能收到
+	EventBusDriverRedis  = "redis"  // 通过 Redis Stream 投递，API 服务与 Worker 等多个进程共享事件
+	EventBusDriverKafka  = "kafka"  // 通过 Kafka 投递，每类事件一个 topic，按公司 ID 分区保证顺序，适合大规模部署
+)
+
+// EventBusConfig 事件总线配置
+type EventBusConfig struct {
+	Driver string              `mapstructure:"driver"` // memory、redis 或 kafka，默认 memory
+	Outbox OutboxConfig        `mapstructure:"outbox"`
+	Redis  EventBusRedisConfig `mapstructure:"redis"`
+	Kafka  EventBusKafkaConfig `mapstructure:"kafka"`
+}
+
+// OutboxConfig 事件发件箱配置：事件与业务数据在同一事务中写入发件箱，由服务进程中的中继按顺序投递
+type OutboxConfig struct {
+	Enabled      bool          `mapstructure:"enabled"`       // 关闭时事件在事务提交后直接投递，进程崩溃会丢失
+	PollInterval time.Duration `mapstructure:"poll_interval"` // 中继轮询间隔，也是失败重试的初始退避，默认 1 秒
+	BatchSize    int           `mapstructure:"batch_size"`    // 每批投递的事件数，默认 100
+	MaxAttempts  int           `mapstructure:"max_attempts"`  // 单个事件最多投递次数，默认 10
+	Retention    time.Duration `mapstructure:"retention"`     // 已投递事件与消费记录的保留时间，默认 7 天
+}
+
+// EventBusRedisConfig Redis Stream 事件总线配置，复用 redis 连接
+type EventBusRedisConfig struct {
+	Stream        string        `mapstructure:"stream"`         // Stream 键名，默认 unlimited:events
+	Instance      string        `mapstructure:"instance"`       // 实例名，各进程必须不同，默认主机名
+	BatchSize     int64         `mapstructure:"batch_size"`     // 每次读取或认领的消息数，默认 100
+	Block         time.Duration `mapstructure:"block"`          // 读取新消息的阻塞时间，默认 5 秒
+	ClaimInterval time.Duration `mapstructure:"claim_interval"` // 认领未确认消息的间隔，默认 30 秒
+	ClaimMinIdle  time.Duration `mapstructure:"claim_min_idle"` // 消息未确认超过该时间后重新投递，默认 1 分钟
+	MaxDeliveries int64         `mapstructure:"max_deliveries"` // 单条消息最多投递次数，超过后丢弃并记录错误日志，默认 10
+	MaxLen        int64         `mapstructure:"max_len"`        // Stream 长度上限（近似），0 表示不限制
+	Retention     time.Duration `mapstructure:"retention"`      // 消息在 Stream 中的保留时间，默认 24 小时
+}
+
+// EventBusKafkaConfig Kafka 事件总线配置，broker 地址使用 kafka.brokers
+type EventBusKafkaConfig struct {
+	TopicPrefix       string        `mapstructure:"topic_prefix"`       // topic 前缀，后接事件类别（task、employee 等），默认 unlimited.events.
+	Instance          string        `mapstructure:"instance"`           // 实例名，各进程必须不同，默认主机名
+	Partitions        int           `mapstructure:"partitions"`         // 自动创建 topic 的分区数，默认 12
+	ReplicationFactor int           `mapstructure:"replication_factor"` // 自动创建 topic 的副本数，默认 1
+	MaxDeliveries     int           `mapstructure:"max_deliveries"`     // 单条消息最多投递次数，超过后丢弃并记录错误日志，默认 10
+	RetryDelay        time.Duration `mapstructure:"retry_delay"`        // 投递失败后的初始退避，每次失败翻倍，默认 1 秒
+	Retention         time.Duration `mapstructure:"retention"`          // 自动创建 topic 的消息保留时间，默认 7 天
+}
+
 // MinIOConfig MinIO配置
 type MinIOConfig struct {
 	Endpoint  string `mapstructure:"endpoint"`
@@ -96,6 +161,82 @@ type MinIOConfig struct {
 	UseSSL    bool   `mapstructure:"use_ssl"`
 }
 
+// SchedulerConfig 任务调度配置
+type SchedulerConfig struct {
+	PollInterval   time.Duration  `mapstructure:"poll_interval"`   // 兜底轮询间隔，事件驱动调度之外的安全网
+	ExecutionSlots int            `mapstructure:"execution_slots"` // 所有公司共享的并发执行槽位，按套餐权重公平分配；0 表示不限
+	Recovery       RecoveryConfig `mapstructure:"recovery"`
+}
+
+// RecoveryConfig 卡死任务恢复配置
+type RecoveryConfig struct {
+	LeaseTTL       time.Duration `mapstructure:"lease_ttl"`       // 租约有效期，超时未心跳视为执行者失联
+	ReaperInterval time.Duration `mapstructure:"reaper_interval"` // 过期租约扫描间隔
+	OnExpiry       string        `mapstructure:"on_expiry"`       // 租约过期后的处理：requeued（重新排队）或 failed（直接失败）
+	MaxRequeues    int           `mapstructure:"max_requeues"`    // 最多重新排队次数，超过后任务失败
+	EmployeeStatus string        `mapstructure:"employee_status"` // 失联员工的状态：error 或 idle
+}
+
+// OpsConfig 运维接口配置
+type OpsConfig struct {
+	Token string `mapstructure:"token"` // 运维接口令牌，为空时关闭运维接口
+}
+
+// 热点数据源类型
+const (
+	HotspotSourceRSS  = "rss"  // RSS 2.0 或 Atom 订阅
+	HotspotSourceJSON = "json" // 返回 JSON 的接口，按字段映射读取话题
+	HotspotSourceCSV  = "csv"  // CSV 文件，表头含 title，可选 url、summary、heat、published_at
+)
+
+// HotspotConfig 热点追踪配置
+type HotspotConfig struct {
+	Limit   int                   `mapstructure:"limit"` // 每次分析保留的话题数，默认 20
+	Sources []HotspotSourceConfig `mapstructure:"sources"`
+}
+
+// HotspotSourceConfig 热点数据源
+type HotspotSourceConfig struct {
+	Name      string              `mapstructure:"name"`
+	Type      string              `mapstructure:"type"`      // rss、json 或 csv
+	URL       string              `mapstructure:"url"`       // http(s) 地址或本地文件路径
+	Platforms []string            `mapstructure:"platforms"` // 适用的平台，为空表示所有平台
+	Headers   map[string]string   `mapstructure:"headers"`   // 请求头，如鉴权令牌
+	Timeout   time.Duration       `mapstructure:"timeout"`   // 请求超时，默认 10 秒
+	Mapping   HotspotFieldMapping `mapstructure:"mapping"`   // json 类型的字段映射
+}
+
+// HotspotFieldMapping JSON 数据源的字段映射，路径以点分隔，如 data.list
+type HotspotFieldMapping struct {
+	Items       string `mapstructure:"items"`        // 话题列表的路径，为空表示根节点即列表
+	Title       string `mapstructure:"title"`        // 默认 title
+	URL         string `mapstructure:"url"`          // 默认 url
+	Summary     string `mapstructure:"summary"`      // 默认 summary
+	Heat        string `mapstructure:"heat"`         // 默认 heat
+	PublishedAt string `mapstructure:"published_at"` // 默认 published_at
+}
+
+// 发布驱动
+const (
+	PublishDriverStub    = "stub"    // 本地桩，内存中生成帖子 ID 和链接，用于联调
+	PublishDriverWebhook = "webhook" // 以 JSON POST 到 webhook，由对接方完成真实发布
+)
+
+// PublishingConfig 内容发布配置
+type PublishingConfig struct {
+	DefaultPlatforms []string                   `mapstructure:"default_platforms"` // 自动运营未指定平台时发布到的平台
+	Platforms        []PublishingPlatformConfig `mapstructure:"platforms"`         // 可发布的平台，未配置的平台不可发布
+	EncryptionKey    string                     `mapstructure:"encryption_key"`    // 公司凭证加密存储的密钥，为空时凭证不可用
+}
+
+// PublishingPlatformConfig 发布平台
+type PublishingPlatformConfig struct {
+	Name    string        `mapstructure:"name"`    // 平台，内置 xiaohongshu、douyin、weibo、wechat_mp 的格式限制
+	Driver  string        `mapstructure:"driver"`  // stub 或 webhook
+	URL     string        `mapstructure:"url"`     // webhook 地址，公司凭证中的 webhook_url 优先
+	Timeout time.Duration `mapstructure:"timeout"` // webhook 请求超时，默认 10 秒
+}
+
 var globalConfig *Config
 
 // Load 加载配置
@@ -151,6 +292,16 @@ func overrideFromEnv(config *Config) {
 		config.Redis.Password = redisPassword
 	}
 
+	// 运维接口令牌
+	if opsToken := os.Getenv("OPS_TOKEN"); opsToken != "" {
+		config.Ops.Token = opsToken
+	}
+
+	// 发布凭证加密密钥
+	if key := os.Getenv("PUBLISHING_ENCRYPTION_KEY"); key != "" {
+		config.Publishing.EncryptionKey = key
+	}
+
 	// MinIO配置
 	if minioEndpoint := os.Getenv("MINIO_ENDPOINT"); minioEndpoint != "" {
 		config.MinIO.Endpoint = minioEndpoint
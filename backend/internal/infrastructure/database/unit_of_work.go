@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Querier 是 *sqlx.DB 与 *sqlx.Tx 共有的查询接口
+type Querier interface {
+	sqlx.ExtContext
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// UnitOfWork 在同一个事务中执行多个仓储操作
+type UnitOfWork interface {
+	// Do 在事务中执行 fn；fn 收到的 ctx 携带事务，仓储使用该 ctx 即加入同一事务
+	Do(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+// WithTx 返回携带事务的 context
+func WithTx(ctx context.Context, tx *sqlx.Tx) context.Context {
+	return context.WithValue(ctx, txKey{}, tx)
+}
+
+// TxFromContext 取出 context 中的事务
+func TxFromContext(ctx context.Context) (*sqlx.Tx, bool) {
+	tx, ok := ctx.Value(txKey{}).(*sqlx.Tx)
+	return tx, ok && tx != nil
+}
+
+// WithoutTx 返回不再携带事务的 context，用于事务提交后继续使用原 context
+func WithoutTx(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, txKey{}, (*sqlx.Tx)(nil))
+	return context.WithValue(ctx, afterCommitKey{}, (*afterCommitHooks)(nil))
+}
+
+type afterCommitKey struct{}
+
+// afterCommitHooks 收集事务提交后要执行的函数
+type afterCommitHooks struct {
+	mu  sync.Mutex
+	fns []func()
+}
+
+// AfterCommit 在 ctx 所在事务提交后执行 fn，事务回滚时不执行；ctx 不在事务中时立即执行
+func AfterCommit(ctx context.Context, fn func()) {
+	hooks, ok := ctx.Value(afterCommitKey{}).(*afterCommitHooks)
+	if !ok || hooks == nil {
+		fn()
+		return
+	}
+	hooks.mu.Lock()
+	defer hooks.mu.Unlock()
+	hooks.fns = append(hooks.fns, fn)
+}
+
+// Conn 返回 context 中的事务，没有事务时返回 db 本身
+func Conn(ctx context.Context, db *sqlx.DB) Querier {
+	if tx, ok := TxFromContext(ctx); ok {
+		return tx
+	}
+	return db
+}
+
+// TxManager 基于 sqlx 的 UnitOfWork 实现
+type TxManager struct {
+	db *sqlx.DB
+}
+
+// NewTxManager 创建事务管理器
+func NewTxManager(db *sqlx.DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// Do 在事务中执行 fn；若 ctx 已在事务中则直接加入该事务
+func (m *TxManager) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := TxFromContext(ctx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	hooks := &afterCommitHooks{}
+	txCtx := context.WithValue(WithTx(ctx, tx), afterCommitKey{}, hooks)
+	if err := fn(txCtx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return fmt.Errorf("tx error: %v, rollback error: %v", err, rbErr)
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	hooks.mu.Lock()
+	fns := hooks.fns
+	hooks.mu.Unlock()
+	for _, fn := range fns {
+		fn()
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/pkg/logger"
+)
+
+// brokerBus is what the buses delivering events through a broker share.
+// Subscriptions are grouped by the consumer group reading them: Subscribe
+// and SubscribeAll go to a group of this process, so each process receives
+// every event; each named consumer of SubscribeAs is a group shared by the
+// processes subscribing under the name, so each event is handled by one of
+// them.
+type brokerBus struct {
+	mu      sync.RWMutex
+	local   *MemoryBus
+	groups  map[string]*MemoryBus
+	outbox  Outbox
+	dedup   DedupStore
+	ctx     context.Context
+	cancel  context.CancelFunc
+	running sync.WaitGroup
+
+	// send adds an event to the broker
+	send func(ctx context.Context, event *Event) error
+	// startGroup starts reading a consumer group's messages until ctx is
+	// cancelled, tracking its goroutines with running
+	startGroup func(ctx context.Context, name string, group *MemoryBus)
+}
+
+func newBrokerBus() brokerBus {
+	return brokerBus{
+		local:  NewEventBus(),
+		groups: make(map[string]*MemoryBus),
+		dedup:  NewMemoryDedupStore(defaultDedupCapacity),
+	}
+}
+
+// Publish publishes an event. With an outbox the event is written to it in
+// ctx's transaction and sent to the broker by the outbox relay; without one
+// it is sent once ctx's transaction commits.
+func (b *brokerBus) Publish(ctx context.Context, event *Event) error {
+	prepare(event)
+
+	b.mu.RLock()
+	outbox := b.outbox
+	b.mu.RUnlock()
+	if outbox != nil {
+		return outbox.Add(ctx, event)
+	}
+
+	database.AfterCommit(ctx, func() {
+		if err := b.send(database.WithoutTx(ctx), event); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to publish event %s (%s): %v", event.ID, event.Type, err))
+		}
+	})
+	return nil
+}
+
+// Subscribe subscribes this process to events of a specific type
+func (b *brokerBus) Subscribe(eventType EventType, handler Handler) *Subscription {
+	return b.local.Subscribe(eventType, handler)
+}
+
+// SubscribeAll subscribes this process to all events
+func (b *brokerBus) SubscribeAll(handler Handler) *Subscription {
+	return b.local.SubscribeAll(handler)
+}
+
+// SubscribeAs subscribes a named consumer to events of a specific type; the
+// processes subscribing under the name share its consumer group
+func (b *brokerBus) SubscribeAs(consumer string, eventType EventType, handler Handler) *Subscription {
+	b.mu.Lock()
+	group, ok := b.groups[consumer]
+	if !ok {
+		group = NewEventBus()
+		b.groups[consumer] = group
+		if b.ctx != nil {
+			b.startGroup(b.ctx, consumer, group)
+		}
+	}
+	b.mu.Unlock()
+
+	return group.Subscribe(eventType, func(ctx context.Context, event *Event) error {
+		b.mu.RLock()
+		store := b.dedup
+		b.mu.RUnlock()
+		return Deduplicate(ctx, store, consumer, event, handler)
+	})
+}
+
+// Unsubscribe removes a subscription
+func (b *brokerBus) Unsubscribe(sub *Subscription) {
+	b.local.Unsubscribe(sub)
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, group := range b.groups {
+		group.Unsubscribe(sub)
+	}
+}
+
+// SetOutbox makes Publish write events to an outbox instead of the broker
+func (b *brokerBus) SetOutbox(outbox Outbox) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.outbox = outbox
+}
+
+// SetDedupStore sets the store SubscribeAs deduplicates events with
+func (b *brokerBus) SetDedupStore(store DedupStore) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.dedup = store
+}
+
+// GetHistory returns the events recently read by this process
+func (b *brokerBus) GetHistory(limit int) []*Event {
+	return b.local.GetHistory(limit)
+}
+
+// GetHistoryByType returns the events of a type recently read by this process
+func (b *brokerBus) GetHistoryByType(eventType EventType, limit int) []*Event {
+	return b.local.GetHistoryByType(eventType, limit)
+}
+
+// start starts reading this process's group, named local, and the group of
+// every named consumer. It returns the context consumers run in, or false if
+// the bus already runs.
+func (b *brokerBus) start(ctx context.Context, local string) (context.Context, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ctx != nil {
+		return nil, false
+	}
+	b.ctx, b.cancel = context.WithCancel(ctx)
+
+	b.startGroup(b.ctx, local, b.local)
+	for name, group := range b.groups {
+		b.startGroup(b.ctx, name, group)
+	}
+	return b.ctx, true
+}
+
+// stop cancels the consumers and waits for them, returning false if the bus
+// was not started
+func (b *brokerBus) stop() bool {
+	b.mu.Lock()
+	cancel := b.cancel
+	b.mu.Unlock()
+	if cancel == nil {
+		return false
+	}
+	cancel()
+	b.running.Wait()
+	return true
+}
+
+// sleep waits for d, returning false if ctx is cancelled first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
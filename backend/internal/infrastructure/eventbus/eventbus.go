@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"unlimited-corp/internal/infrastructure/database"
 )
 
 // EventType represents the type of event
@@ -21,6 +22,12 @@ const (
 	EventTaskFailed    EventType = "task.failed"
 	EventTaskCancelled EventType = "task.cancelled"
 	EventTaskProgress  EventType = "task.progress"
+	EventTaskPaused    EventType = "task.paused"
+	EventTaskResumed   EventType = "task.resumed"
+	EventTaskRetried   EventType = "task.retried"
+
+	EventTaskApprovalRequested EventType = "task.approval_requested"
+	EventTaskApprovalDecided   EventType = "task.approval_decided"
 
 	// Employee events
 	EventEmployeeCreated EventType = "employee.created"
@@ -79,25 +86,62 @@ type Subscription struct {
 	Handler   Handler
 }
 
-// EventBus is an in-memory event bus implementation
-type EventBus struct {
+// EventBus delivers domain events to their subscribers. MemoryBus delivers
+// them within the process, RedisBus and KafkaBus through a broker to every
+// process sharing it.
+type EventBus interface {
+	// Publish publishes an event; see the implementations for when it is delivered
+	Publish(ctx context.Context, event *Event) error
+	// Deliver hands an event to the bus's transport right away, returning an
+	// error if it could not; the outbox relay delivers events with it
+	Deliver(ctx context.Context, event *Event) error
+
+	// Subscribe subscribes to events of a specific type; every process
+	// receives the events its subscriptions match
+	Subscribe(eventType EventType, handler Handler) *Subscription
+	// SubscribeAll subscribes to all events
+	SubscribeAll(handler Handler) *Subscription
+	// SubscribeAs subscribes a named consumer to events of a specific type.
+	// The consumer handles each event ID once, however often it is delivered
+	// and however many processes subscribe under its name.
+	SubscribeAs(consumer string, eventType EventType, handler Handler) *Subscription
+	// Unsubscribe removes a subscription
+	Unsubscribe(sub *Subscription)
+
+	// SetOutbox makes Publish write events to an outbox instead of delivering them
+	SetOutbox(outbox Outbox)
+	// SetDedupStore sets the store SubscribeAs deduplicates events with
+	SetDedupStore(store DedupStore)
+
+	// GetHistory returns the events recently delivered to this process
+	GetHistory(limit int) []*Event
+	// GetHistoryByType returns the events of a type recently delivered to this process
+	GetHistoryByType(eventType EventType, limit int) []*Event
+}
+
+// MemoryBus is an in-memory event bus implementation
+type MemoryBus struct {
 	mu            sync.RWMutex
 	subscriptions map[EventType][]*Subscription
 	eventHistory  []*Event
 	maxHistory    int
+
+	outbox Outbox
+	dedup  DedupStore
 }
 
-// NewEventBus creates a new event bus
-func NewEventBus() *EventBus {
-	return &EventBus{
+// NewEventBus creates a new in-memory event bus
+func NewEventBus() *MemoryBus {
+	return &MemoryBus{
 		subscriptions: make(map[EventType][]*Subscription),
 		eventHistory:  make([]*Event, 0),
 		maxHistory:    1000,
+		dedup:         NewMemoryDedupStore(defaultDedupCapacity),
 	}
 }
 
 // Subscribe subscribes to events of a specific type
-func (eb *EventBus) Subscribe(eventType EventType, handler Handler) *Subscription {
+func (eb *MemoryBus) Subscribe(eventType EventType, handler Handler) *Subscription {
 	eb.mu.Lock()
 	defer eb.mu.Unlock()
 
@@ -112,12 +156,12 @@ func (eb *EventBus) Subscribe(eventType EventType, handler Handler) *Subscriptio
 }
 
 // SubscribeAll subscribes to all events
-func (eb *EventBus) SubscribeAll(handler Handler) *Subscription {
+func (eb *MemoryBus) SubscribeAll(handler Handler) *Subscription {
 	return eb.Subscribe("*", handler)
 }
 
 // Unsubscribe removes a subscription
-func (eb *EventBus) Unsubscribe(sub *Subscription) {
+func (eb *MemoryBus) Unsubscribe(sub *Subscription) {
 	eb.mu.Lock()
 	defer eb.mu.Unlock()
 
@@ -130,76 +174,68 @@ func (eb *EventBus) Unsubscribe(sub *Subscription) {
 	}
 }
 
-// Publish publishes an event to all subscribers
-func (eb *EventBus) Publish(ctx context.Context, event *Event) error {
-	eb.mu.RLock()
-	defer eb.mu.RUnlock()
+// Publish publishes an event to all subscribers. With an outbox the event
+// is written to it in ctx's transaction and delivered by the outbox relay;
+// without one it is delivered once ctx's transaction commits.
+func (eb *MemoryBus) Publish(ctx context.Context, event *Event) error {
+	prepare(event)
 
-	// Add event ID and timestamp if not set
-	if event.ID == "" {
-		event.ID = uuid.New().String()
-	}
-	if event.Timestamp.IsZero() {
-		event.Timestamp = time.Now()
+	eb.mu.RLock()
+	outbox := eb.outbox
+	eb.mu.RUnlock()
+	if outbox != nil {
+		return outbox.Add(ctx, event)
 	}
 
-	// Store in history
-	eb.storeEvent(event)
-
-	// Get handlers for this event type
-	handlers := eb.subscriptions[event.Type]
+	database.AfterCommit(ctx, func() {
+		eb.dispatch(database.WithoutTx(ctx), event)
+	})
+	return nil
+}
 
-	// Also get handlers subscribed to all events
-	allHandlers := eb.subscriptions["*"]
-	handlers = append(handlers, allHandlers...)
+// dispatch delivers an event to its subscribers without waiting for them
+func (eb *MemoryBus) dispatch(ctx context.Context, event *Event) {
+	eb.mu.Lock()
+	eb.storeEvent(event)
+	eb.mu.Unlock()
 
-	// Execute handlers asynchronously
-	for _, sub := range handlers {
+	// Execute handlers asynchronously; their errors are dropped, an outbox
+	// relay uses Deliver to see them
+	for _, h := range eb.handlers(event.Type) {
 		go func(h Handler) {
-			if err := h(ctx, event); err != nil {
-				// Log error but don't fail
-				// In production, you'd use a proper logger
-				_ = err
-			}
-		}(sub.Handler)
+			_ = h(ctx, event)
+		}(h)
 	}
-
-	return nil
 }
 
-// PublishSync publishes an event and waits for all handlers to complete
-func (eb *EventBus) PublishSync(ctx context.Context, event *Event) error {
-	eb.mu.RLock()
-	defer eb.mu.RUnlock()
-
-	// Add event ID and timestamp if not set
-	if event.ID == "" {
-		event.ID = uuid.New().String()
-	}
-	if event.Timestamp.IsZero() {
-		event.Timestamp = time.Now()
-	}
+// PublishSync publishes an event and waits for all handlers to complete,
+// bypassing the outbox
+func (eb *MemoryBus) PublishSync(ctx context.Context, event *Event) error {
+	prepare(event)
+	return eb.Deliver(ctx, event)
+}
 
-	// Store in history
+// Deliver hands an event to its subscribers, waiting for all of them, and
+// returns the first handler error. The outbox relay delivers events with it.
+func (eb *MemoryBus) Deliver(ctx context.Context, event *Event) error {
+	eb.mu.Lock()
 	eb.storeEvent(event)
+	eb.mu.Unlock()
 
-	// Get handlers
-	handlers := eb.subscriptions[event.Type]
-	allHandlers := eb.subscriptions["*"]
-	handlers = append(handlers, allHandlers...)
+	handlers := eb.handlers(event.Type)
 
-	// Execute handlers synchronously
+	// Execute handlers concurrently and wait for them
 	var wg sync.WaitGroup
 	errors := make(chan error, len(handlers))
 
-	for _, sub := range handlers {
+	for _, h := range handlers {
 		wg.Add(1)
 		go func(h Handler) {
 			defer wg.Done()
 			if err := h(ctx, event); err != nil {
 				errors <- err
 			}
-		}(sub.Handler)
+		}(h)
 	}
 
 	wg.Wait()
@@ -215,8 +251,34 @@ func (eb *EventBus) PublishSync(ctx context.Context, event *Event) error {
 	return nil
 }
 
+// handlers returns the handlers of an event type followed by those
+// subscribed to all events
+func (eb *MemoryBus) handlers(eventType EventType) []Handler {
+	eb.mu.RLock()
+	defer eb.mu.RUnlock()
+
+	var handlers []Handler
+	for _, sub := range eb.subscriptions[eventType] {
+		handlers = append(handlers, sub.Handler)
+	}
+	for _, sub := range eb.subscriptions["*"] {
+		handlers = append(handlers, sub.Handler)
+	}
+	return handlers
+}
+
+// prepare sets the ID and timestamp of an event if not set
+func prepare(event *Event) {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+}
+
 // storeEvent stores an event in history
-func (eb *EventBus) storeEvent(event *Event) {
+func (eb *MemoryBus) storeEvent(event *Event) {
 	eb.eventHistory = append(eb.eventHistory, event)
 
 	// Trim history if needed
@@ -226,7 +288,7 @@ func (eb *EventBus) storeEvent(event *Event) {
 }
 
 // GetHistory returns recent events
-func (eb *EventBus) GetHistory(limit int) []*Event {
+func (eb *MemoryBus) GetHistory(limit int) []*Event {
 	eb.mu.RLock()
 	defer eb.mu.RUnlock()
 
@@ -241,7 +303,7 @@ func (eb *EventBus) GetHistory(limit int) []*Event {
 }
 
 // GetHistoryByType returns recent events of a specific type
-func (eb *EventBus) GetHistoryByType(eventType EventType, limit int) []*Event {
+func (eb *MemoryBus) GetHistoryByType(eventType EventType, limit int) []*Event {
 	eb.mu.RLock()
 	defer eb.mu.RUnlock()
 
@@ -272,13 +334,26 @@ func NewEvent(eventType EventType, source string, payload interface{}, metadata
 }
 
 // Global event bus instance
-var globalEventBus *EventBus
-var once sync.Once
-
-// GetEventBus returns the global event bus instance
-func GetEventBus() *EventBus {
-	once.Do(func() {
+var globalEventBus EventBus
+var globalMu sync.Mutex
+
+// GetEventBus returns the global event bus instance, an in-memory bus unless
+// SetEventBus replaced it
+func GetEventBus() EventBus {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	if globalEventBus == nil {
 		globalEventBus = NewEventBus()
-	})
+	}
 	return globalEventBus
 }
+
+// SetEventBus replaces the global event bus; call it before creating the
+// services that publish or subscribe
+func SetEventBus(bus EventBus) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	globalEventBus = bus
+}
+
+var _ EventBus = (*MemoryBus)(nil)
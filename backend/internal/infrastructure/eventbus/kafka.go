@@ -0,0 +1,372 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"unlimited-corp/pkg/logger"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaBus defaults
+const (
+	defaultKafkaTopicPrefix       = "unlimited.events."
+	defaultKafkaPartitions        = 12
+	defaultKafkaReplicationFactor = 1
+	defaultKafkaMaxDeliveries     = 10
+	defaultKafkaRetryDelay        = time.Second
+	defaultKafkaRetention         = 7 * 24 * time.Hour
+
+	// maxKafkaRetryDelay caps the backoff between deliveries of a message
+	maxKafkaRetryDelay = time.Minute
+	// kafkaBatchTimeout is how long the writer waits to batch messages; low
+	// since Deliver waits for its message to be written
+	kafkaBatchTimeout = 10 * time.Millisecond
+	// kafkaRequestTimeout bounds creating topics and removing groups
+	kafkaRequestTimeout = 10 * time.Second
+)
+
+// KafkaSchemaVersion is the version of the envelope events are written to
+// Kafka in. Readers skip messages of a later version, so a change to the
+// envelope that older readers cannot decode must increase it.
+const KafkaSchemaVersion = 1
+
+// Kafka message headers, so tools can route and filter messages without
+// decoding them
+const (
+	kafkaHeaderType   = "event_type"
+	kafkaHeaderSchema = "schema_version"
+)
+
+// eventFamilies are the families of event types; each has its own topic
+var eventFamilies = []string{"task", "employee", "skillcard", "workflow", "chat", "system"}
+
+// EventFamily returns the family of an event type, the part before its
+// first dot: "task" for task.created
+func EventFamily(eventType EventType) string {
+	family, _, _ := strings.Cut(string(eventType), ".")
+	return family
+}
+
+// KafkaOptions configures a KafkaBus; zero values fall back to the defaults
+type KafkaOptions struct {
+	// Brokers are the addresses of the Kafka brokers
+	Brokers []string
+	// TopicPrefix prefixes the topic of each event family
+	TopicPrefix string
+	// Instance names this process: it names the consumer group its own
+	// subscriptions read from. It must differ between processes; the host
+	// name by default.
+	Instance string
+	// Partitions and ReplicationFactor are used when creating topics
+	Partitions        int
+	ReplicationFactor int
+	// MaxDeliveries is how often a message is handed to a consumer group's
+	// handlers before it is dropped
+	MaxDeliveries int
+	// RetryDelay is the backoff after a first failed delivery; it doubles
+	// with every further failure
+	RetryDelay time.Duration
+	// Retention is how long messages stay in the topics created by the bus
+	Retention time.Duration
+}
+
+// kafkaWriter writes messages to Kafka; implemented by *kafka.Writer
+type kafkaWriter interface {
+	WriteMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// kafkaReader reads the messages of a consumer group; implemented by
+// *kafka.Reader
+type kafkaReader interface {
+	FetchMessage(ctx context.Context) (kafka.Message, error)
+	CommitMessages(ctx context.Context, msgs ...kafka.Message) error
+	Close() error
+}
+
+// kafkaEnvelope is the JSON value of a Kafka message
+type kafkaEnvelope struct {
+	Schema int    `json:"schema"`
+	Event  *Event `json:"event"`
+}
+
+// KafkaBus delivers events through Kafka, with a topic per event family.
+// Messages are keyed by company ID, so the events of a company stay in one
+// partition and are handled in the order they were published.
+//
+// Each named consumer of SubscribeAs, such as the scheduler or a webhook
+// dispatcher, is a consumer group: the processes subscribing under the name
+// share its partitions. Subscribe and SubscribeAll read from a group of
+// their own process, so each process receives every event; the notification
+// service pushes to the websocket clients connected to its process this way.
+// Every group reads the topics of all families. A failed message is handed
+// to its group's handlers again, holding back the rest of its partition,
+// until MaxDeliveries; then it is dropped and the group moves on.
+type KafkaBus struct {
+	brokerBus
+	opts      KafkaOptions
+	client    *kafka.Client
+	writer    kafkaWriter
+	newReader func(groupID string) kafkaReader
+}
+
+// NewKafkaBus creates an event bus on Kafka brokers; it consumes events
+// once started
+func NewKafkaBus(opts KafkaOptions) *KafkaBus {
+	if opts.TopicPrefix == "" {
+		opts.TopicPrefix = defaultKafkaTopicPrefix
+	}
+	if opts.Instance == "" {
+		opts.Instance, _ = os.Hostname()
+	}
+	if opts.Partitions <= 0 {
+		opts.Partitions = defaultKafkaPartitions
+	}
+	if opts.ReplicationFactor <= 0 {
+		opts.ReplicationFactor = defaultKafkaReplicationFactor
+	}
+	if opts.MaxDeliveries <= 0 {
+		opts.MaxDeliveries = defaultKafkaMaxDeliveries
+	}
+	if opts.RetryDelay <= 0 {
+		opts.RetryDelay = defaultKafkaRetryDelay
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = defaultKafkaRetention
+	}
+
+	b := &KafkaBus{
+		brokerBus: newBrokerBus(),
+		opts:      opts,
+		client:    &kafka.Client{Addr: kafka.TCP(opts.Brokers...)},
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(opts.Brokers...),
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchTimeout: kafkaBatchTimeout,
+		},
+	}
+	b.newReader = func(groupID string) kafkaReader {
+		return kafka.NewReader(kafka.ReaderConfig{
+			Brokers:     opts.Brokers,
+			GroupID:     groupID,
+			GroupTopics: b.topics(),
+			StartOffset: kafka.LastOffset,
+		})
+	}
+	b.send = b.Deliver
+	b.startGroup = b.startConsumer
+	return b
+}
+
+// Topic returns the topic events of a type are written to
+func (b *KafkaBus) Topic(eventType EventType) string {
+	return b.opts.TopicPrefix + EventFamily(eventType)
+}
+
+// topics returns the topics of all event families
+func (b *KafkaBus) topics() []string {
+	topics := make([]string, len(eventFamilies))
+	for i, family := range eventFamilies {
+		topics[i] = b.opts.TopicPrefix + family
+	}
+	return topics
+}
+
+// localGroup is the consumer group of this process's own subscriptions
+func (b *KafkaBus) localGroup() string {
+	return "instance:" + b.opts.Instance
+}
+
+// Deliver writes an event to its family's topic, waiting until the brokers
+// acknowledge it
+func (b *KafkaBus) Deliver(ctx context.Context, event *Event) error {
+	msg, err := b.encodeMessage(event)
+	if err != nil {
+		return err
+	}
+	if err := b.writer.WriteMessages(ctx, msg); err != nil {
+		return fmt.Errorf("failed to write event to kafka: %w", err)
+	}
+	return nil
+}
+
+// Start creates the topics that do not exist and consumes events until ctx
+// is cancelled or Stop is called. Processes that only publish need not
+// start the bus.
+func (b *KafkaBus) Start(ctx context.Context) {
+	if err := b.createTopics(ctx); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to create event topics: %v", err))
+	}
+	b.start(ctx, b.localGroup())
+}
+
+// Stop stops consuming, removes this process's own consumer group and
+// flushes the messages being written; the groups of named consumers stay
+// for the other processes and the next start
+func (b *KafkaBus) Stop() {
+	if b.stop() {
+		ctx, done := context.WithTimeout(context.Background(), kafkaRequestTimeout)
+		defer done()
+		if _, err := b.client.DeleteGroups(ctx, &kafka.DeleteGroupsRequest{GroupIDs: []string{b.localGroup()}}); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to remove event consumer group %s: %v", b.localGroup(), err))
+		}
+	}
+	if err := b.writer.Close(); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to flush event writer: %v", err))
+	}
+}
+
+// createTopics creates the topic of each event family unless it exists
+func (b *KafkaBus) createTopics(ctx context.Context) error {
+	retention := strconv.FormatInt(b.opts.Retention.Milliseconds(), 10)
+	var configs []kafka.TopicConfig
+	for _, topic := range b.topics() {
+		configs = append(configs, kafka.TopicConfig{
+			Topic:             topic,
+			NumPartitions:     b.opts.Partitions,
+			ReplicationFactor: b.opts.ReplicationFactor,
+			ConfigEntries:     []kafka.ConfigEntry{{ConfigName: "retention.ms", ConfigValue: retention}},
+		})
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, kafkaRequestTimeout)
+	defer cancel()
+	resp, err := b.client.CreateTopics(ctx, &kafka.CreateTopicsRequest{Topics: configs})
+	if err != nil {
+		return err
+	}
+	for topic, err := range resp.Errors {
+		if err != nil && !errors.Is(err, kafka.TopicAlreadyExists) {
+			return fmt.Errorf("topic %s: %w", topic, err)
+		}
+	}
+	return nil
+}
+
+// startConsumer starts reading a consumer group's messages
+func (b *KafkaBus) startConsumer(ctx context.Context, name string, group *MemoryBus) {
+	reader := b.newReader(name)
+	b.running.Add(1)
+	go func() {
+		defer b.running.Done()
+		defer reader.Close()
+		b.consume(ctx, name, group, reader)
+	}()
+}
+
+// consume handles a group's messages one by one, committing each once it
+// is handled or dropped
+func (b *KafkaBus) consume(ctx context.Context, name string, group *MemoryBus, reader kafkaReader) {
+	for {
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logger.Warn(fmt.Sprintf("Failed to read events for consumer group %s: %v", name, err))
+			if !sleep(ctx, b.opts.RetryDelay) {
+				return
+			}
+			continue
+		}
+
+		if !b.handle(ctx, name, group, msg) {
+			return
+		}
+		if err := reader.CommitMessages(ctx, msg); err != nil && ctx.Err() == nil {
+			logger.Warn(fmt.Sprintf("Failed to commit event message %s/%d@%d for consumer group %s: %v",
+				msg.Topic, msg.Partition, msg.Offset, name, err))
+		}
+	}
+}
+
+// handle hands a message to a group's handlers until they all succeed or it
+// was delivered MaxDeliveries times, returning false if ctx was cancelled
+// first. Messages that cannot be decoded are dropped, since delivering them
+// again cannot help.
+func (b *KafkaBus) handle(ctx context.Context, name string, group *MemoryBus, msg kafka.Message) bool {
+	event, err := decodeKafkaMessage(msg)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Dropping invalid event message %s/%d@%d: %v", msg.Topic, msg.Partition, msg.Offset, err))
+		return true
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := group.Deliver(ctx, event)
+		if err == nil {
+			return true
+		}
+		if attempt >= b.opts.MaxDeliveries {
+			logger.Error(fmt.Sprintf("Dropping event %s (%s) for consumer group %s after %d deliveries: %v",
+				event.ID, event.Type, name, attempt, err))
+			return true
+		}
+		logger.Warn(fmt.Sprintf("Consumer group %s failed to handle event %s (%s), it will be delivered again: %v",
+			name, event.ID, event.Type, err))
+		if !sleep(ctx, b.retryDelay(attempt)) {
+			return false
+		}
+	}
+}
+
+// retryDelay doubles RetryDelay with every failed delivery
+func (b *KafkaBus) retryDelay(attempts int) time.Duration {
+	delay := b.opts.RetryDelay
+	for i := 1; i < attempts && delay < maxKafkaRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxKafkaRetryDelay {
+		delay = maxKafkaRetryDelay
+	}
+	return delay
+}
+
+// encodeMessage wraps an event in the versioned envelope, keyed by its
+// company so a company's events share a partition. Events without a
+// company are spread over the partitions.
+func (b *KafkaBus) encodeMessage(event *Event) (kafka.Message, error) {
+	value, err := json.Marshal(kafkaEnvelope{Schema: KafkaSchemaVersion, Event: event})
+	if err != nil {
+		return kafka.Message{}, fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	msg := kafka.Message{
+		Topic: b.Topic(event.Type),
+		Value: value,
+		Headers: []kafka.Header{
+			{Key: kafkaHeaderType, Value: []byte(event.Type)},
+			{Key: kafkaHeaderSchema, Value: []byte(strconv.Itoa(KafkaSchemaVersion))},
+		},
+	}
+	if event.Metadata.CompanyID != "" {
+		msg.Key = []byte(event.Metadata.CompanyID)
+	}
+	return msg, nil
+}
+
+// decodeKafkaMessage reads the event of a message, rejecting envelopes of a
+// schema this version does not know
+func decodeKafkaMessage(msg kafka.Message) (*Event, error) {
+	var envelope kafkaEnvelope
+	if err := json.Unmarshal(msg.Value, &envelope); err != nil {
+		return nil, err
+	}
+	if envelope.Schema < 1 || envelope.Schema > KafkaSchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d", envelope.Schema)
+	}
+	if envelope.Event == nil {
+		return nil, fmt.Errorf("message has no event")
+	}
+	return envelope.Event, nil
+}
+
+var _ EventBus = (*KafkaBus)(nil)
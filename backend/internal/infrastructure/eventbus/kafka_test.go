@@ -0,0 +1,221 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKafka stands in for the brokers: written messages are read by every
+// consumer group
+type fakeKafka struct {
+	mu      sync.Mutex
+	written []kafka.Message
+	readers map[string]*fakeReader
+}
+
+func newFakeKafka() *fakeKafka {
+	return &fakeKafka{readers: make(map[string]*fakeReader)}
+}
+
+func (k *fakeKafka) WriteMessages(_ context.Context, msgs ...kafka.Message) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for _, msg := range msgs {
+		msg.Offset = int64(len(k.written))
+		k.written = append(k.written, msg)
+		for _, r := range k.readers {
+			r.messages <- msg
+		}
+	}
+	return nil
+}
+
+func (k *fakeKafka) Close() error { return nil }
+
+func (k *fakeKafka) reader(groupID string) kafkaReader {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	r := &fakeReader{messages: make(chan kafka.Message, 100)}
+	k.readers[groupID] = r
+	return r
+}
+
+func (k *fakeKafka) committed(groupID string) []int64 {
+	k.mu.Lock()
+	r := k.readers[groupID]
+	k.mu.Unlock()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]int64(nil), r.committed...)
+}
+
+type fakeReader struct {
+	messages  chan kafka.Message
+	mu        sync.Mutex
+	committed []int64
+}
+
+func (r *fakeReader) FetchMessage(ctx context.Context) (kafka.Message, error) {
+	select {
+	case msg := <-r.messages:
+		return msg, nil
+	case <-ctx.Done():
+		return kafka.Message{}, ctx.Err()
+	}
+}
+
+func (r *fakeReader) CommitMessages(_ context.Context, msgs ...kafka.Message) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, msg := range msgs {
+		r.committed = append(r.committed, msg.Offset)
+	}
+	return nil
+}
+
+func (r *fakeReader) Close() error { return nil }
+
+// newKafkaBus creates a bus writing to and reading from a fake broker
+func newKafkaBus(k *fakeKafka) *KafkaBus {
+	bus := NewKafkaBus(KafkaOptions{Instance: "api-1", MaxDeliveries: 3, RetryDelay: time.Millisecond})
+	bus.writer = k
+	bus.newReader = k.reader
+	return bus
+}
+
+func TestEventFamily(t *testing.T) {
+	assert.Equal(t, "task", EventFamily(EventTaskApprovalRequested))
+	assert.Equal(t, "skillcard", EventFamily(EventSkillCardAssigned))
+	assert.Equal(t, "system", EventFamily(EventSystemError))
+
+	bus := NewKafkaBus(KafkaOptions{TopicPrefix: "prod.events."})
+	assert.Equal(t, "prod.events.employee", bus.Topic(EventEmployeeOnline))
+	assert.Len(t, bus.topics(), 6)
+}
+
+func TestKafkaBus_DeliverKeysByCompany(t *testing.T) {
+	k := newFakeKafka()
+	bus := newKafkaBus(k)
+
+	event, err := NewEvent(EventTaskCompleted, "temporal_worker", map[string]string{"id": "task-1"}, Metadata{CompanyID: "company-1", Version: 1})
+	require.NoError(t, err)
+	require.NoError(t, bus.Deliver(context.Background(), event))
+	require.NoError(t, bus.Deliver(context.Background(), &Event{ID: "evt-2", Type: EventSystemNotification}))
+
+	require.Len(t, k.written, 2)
+	msg := k.written[0]
+	assert.Equal(t, "unlimited.events.task", msg.Topic)
+	assert.Equal(t, []byte("company-1"), msg.Key)
+	assert.Contains(t, msg.Headers, kafka.Header{Key: kafkaHeaderType, Value: []byte("task.completed")})
+	assert.Contains(t, msg.Headers, kafka.Header{Key: kafkaHeaderSchema, Value: []byte("1")})
+	assert.JSONEq(t, `{"schema":1,"event":{"id":"`+event.ID+`","type":"task.completed","source":"temporal_worker",
+		"payload":{"id":"task-1"},"metadata":{"company_id":"company-1","version":1},
+		"timestamp":"`+event.Timestamp.Format(time.RFC3339Nano)+`"}}`, string(msg.Value))
+
+	decoded, err := decodeKafkaMessage(msg)
+	require.NoError(t, err)
+	assert.Equal(t, event.ID, decoded.ID)
+	assert.Equal(t, "company-1", decoded.Metadata.CompanyID)
+
+	// Events without a company are spread over the partitions
+	assert.Nil(t, k.written[1].Key)
+}
+
+func TestDecodeKafkaMessage_RejectsUnknownSchema(t *testing.T) {
+	_, err := decodeKafkaMessage(kafka.Message{Value: []byte(`{"schema":2,"event":{"id":"evt-1"}}`)})
+	assert.EqualError(t, err, "unsupported schema version 2")
+
+	_, err = decodeKafkaMessage(kafka.Message{Value: []byte(`{"id":"evt-1"}`)})
+	assert.Error(t, err)
+}
+
+func TestKafkaBus_GroupsReceiveEvents(t *testing.T) {
+	k := newFakeKafka()
+	bus := newKafkaBus(k)
+
+	var local, scheduled atomic.Int32
+	bus.Subscribe(EventTaskCreated, func(ctx context.Context, event *Event) error {
+		local.Add(1)
+		return nil
+	})
+	bus.SubscribeAs("scheduler", EventTaskCreated, func(ctx context.Context, event *Event) error {
+		scheduled.Add(1)
+		return nil
+	})
+	bus.start(context.Background(), bus.localGroup())
+	t.Cleanup(func() { bus.stop() })
+
+	require.NoError(t, bus.Deliver(context.Background(), &Event{ID: "evt-1", Type: EventTaskCreated}))
+	require.NoError(t, bus.Deliver(context.Background(), &Event{ID: "evt-2", Type: EventEmployeeOnline}))
+
+	require.Eventually(t, func() bool {
+		return len(k.committed("instance:api-1")) == 2 && len(k.committed("scheduler")) == 2
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, int32(1), local.Load())
+	assert.Equal(t, int32(1), scheduled.Load())
+}
+
+func TestKafkaBus_RetriesInOrderThenDrops(t *testing.T) {
+	k := newFakeKafka()
+	bus := newKafkaBus(k)
+
+	var mu sync.Mutex
+	var handled []string
+	attempts := map[string]int{}
+	bus.SubscribeAs("webhooks", EventTaskCompleted, func(ctx context.Context, event *Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[event.ID]++
+		switch {
+		case event.ID == "evt-1" && attempts[event.ID] == 1:
+			return fmt.Errorf("endpoint down")
+		case event.ID == "evt-2":
+			return fmt.Errorf("endpoint rejects event")
+		}
+		handled = append(handled, event.ID)
+		return nil
+	})
+	bus.start(context.Background(), bus.localGroup())
+	t.Cleanup(func() { bus.stop() })
+
+	for i := 1; i <= 3; i++ {
+		require.NoError(t, bus.Deliver(context.Background(), &Event{ID: fmt.Sprintf("evt-%d", i), Type: EventTaskCompleted}))
+	}
+
+	require.Eventually(t, func() bool { return len(k.committed("webhooks")) == 3 }, time.Second, 5*time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	// evt-1 is handled on its second delivery, before evt-3; evt-2 is dropped
+	assert.Equal(t, []string{"evt-1", "evt-3"}, handled)
+	assert.Equal(t, 3, attempts["evt-2"])
+	assert.Equal(t, []int64{0, 1, 2}, k.committed("webhooks"))
+}
+
+func TestKafkaBus_RetryDelayBacksOff(t *testing.T) {
+	bus := NewKafkaBus(KafkaOptions{RetryDelay: time.Second})
+
+	assert.Equal(t, time.Second, bus.retryDelay(1))
+	assert.Equal(t, 4*time.Second, bus.retryDelay(3))
+	assert.Equal(t, maxKafkaRetryDelay, bus.retryDelay(20))
+}
+
+func TestKafkaBus_DropsUndecodableMessages(t *testing.T) {
+	bus := newKafkaBus(newFakeKafka())
+	var handled atomic.Int32
+	bus.SubscribeAll(func(ctx context.Context, event *Event) error {
+		handled.Add(1)
+		return nil
+	})
+
+	msg := kafka.Message{Value: []byte(`not json`)}
+	assert.True(t, bus.handle(context.Background(), "instance:api-1", bus.local, msg))
+	assert.Equal(t, int32(0), handled.Load())
+}
@@ -0,0 +1,130 @@
+package eventbus
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// Outbox durably stores published events so they are delivered only when
+// the transaction that wrote them commits; implemented by outbox.Relay
+type Outbox interface {
+	// Add stores an event in ctx's transaction
+	Add(ctx context.Context, event *Event) error
+}
+
+// SetOutbox makes Publish write events to an outbox instead of delivering
+// them; the outbox relay delivers them with Deliver
+func (eb *MemoryBus) SetOutbox(outbox Outbox) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	eb.outbox = outbox
+}
+
+// DedupStore remembers which events each consumer has handled, so events
+// delivered more than once are handled once
+type DedupStore interface {
+	// Claim records that a consumer handles an event, returning false if it
+	// already has
+	Claim(ctx context.Context, consumer, eventID string) (bool, error)
+	// Release forgets a claim whose handling failed, so a redelivery is handled
+	Release(ctx context.Context, consumer, eventID string) error
+}
+
+// SetDedupStore sets the store SubscribeAs deduplicates events with
+func (eb *MemoryBus) SetDedupStore(store DedupStore) {
+	eb.mu.Lock()
+	defer eb.mu.Unlock()
+	eb.dedup = store
+}
+
+// SubscribeAs subscribes a named consumer to events of a specific type. The
+// consumer handles each event ID once, however often the event is delivered.
+func (eb *MemoryBus) SubscribeAs(consumer string, eventType EventType, handler Handler) *Subscription {
+	return eb.Subscribe(eventType, func(ctx context.Context, event *Event) error {
+		eb.mu.RLock()
+		store := eb.dedup
+		eb.mu.RUnlock()
+		return Deduplicate(ctx, store, consumer, event, handler)
+	})
+}
+
+// Deduplicate runs handler unless the consumer already handled the event.
+// A store that cannot be reached does not stop the event from being handled:
+// a duplicate is better than a lost event.
+func Deduplicate(ctx context.Context, store DedupStore, consumer string, event *Event, handler Handler) error {
+	if store == nil || event.ID == "" {
+		return handler(ctx, event)
+	}
+
+	claimed, err := store.Claim(ctx, consumer, event.ID)
+	if err != nil {
+		return handler(ctx, event)
+	}
+	if !claimed {
+		return nil
+	}
+
+	if err := handler(ctx, event); err != nil {
+		_ = store.Release(ctx, consumer, event.ID)
+		return err
+	}
+	return nil
+}
+
+// defaultDedupCapacity is how many handled events the default store remembers
+const defaultDedupCapacity = 10000
+
+// MemoryDedupStore remembers the most recently handled events in memory
+type MemoryDedupStore struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	handled  map[string]*list.Element
+}
+
+// NewMemoryDedupStore creates a store remembering up to capacity events
+func NewMemoryDedupStore(capacity int) *MemoryDedupStore {
+	if capacity <= 0 {
+		capacity = defaultDedupCapacity
+	}
+	return &MemoryDedupStore{
+		capacity: capacity,
+		order:    list.New(),
+		handled:  make(map[string]*list.Element),
+	}
+}
+
+// Claim records that a consumer handles an event, evicting the oldest
+// record when full
+func (s *MemoryDedupStore) Claim(_ context.Context, consumer, eventID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := consumer + "/" + eventID
+	if _, ok := s.handled[key]; ok {
+		return false, nil
+	}
+	s.handled[key] = s.order.PushBack(key)
+	if s.order.Len() > s.capacity {
+		oldest := s.order.Front()
+		s.order.Remove(oldest)
+		delete(s.handled, oldest.Value.(string))
+	}
+	return true, nil
+}
+
+// Release forgets a claim
+func (s *MemoryDedupStore) Release(_ context.Context, consumer, eventID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := consumer + "/" + eventID
+	if elem, ok := s.handled[key]; ok {
+		s.order.Remove(elem)
+		delete(s.handled, key)
+	}
+	return nil
+}
+
+var _ DedupStore = (*MemoryDedupStore)(nil)
@@ -0,0 +1,112 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOutbox keeps the events added to it
+type fakeOutbox struct {
+	events []*Event
+}
+
+func (o *fakeOutbox) Add(_ context.Context, event *Event) error {
+	o.events = append(o.events, event)
+	return nil
+}
+
+func TestEventBus_PublishWritesToOutbox(t *testing.T) {
+	eb := NewEventBus()
+	outbox := &fakeOutbox{}
+	eb.SetOutbox(outbox)
+
+	var received atomic.Int32
+	eb.Subscribe(EventTaskCreated, func(ctx context.Context, event *Event) error {
+		received.Add(1)
+		return nil
+	})
+
+	event := &Event{Type: EventTaskCreated, Source: "test", Payload: json.RawMessage(`{}`)}
+	require.NoError(t, eb.Publish(context.Background(), event))
+
+	// The event waits in the outbox until the relay delivers it
+	require.Len(t, outbox.events, 1)
+	assert.NotEmpty(t, outbox.events[0].ID)
+	assert.Equal(t, int32(0), received.Load())
+	assert.Empty(t, eb.GetHistory(0))
+
+	require.NoError(t, eb.Deliver(context.Background(), outbox.events[0]))
+	assert.Equal(t, int32(1), received.Load())
+	assert.Len(t, eb.GetHistory(0), 1)
+}
+
+func TestEventBus_DeliverReturnsHandlerError(t *testing.T) {
+	eb := NewEventBus()
+	eb.Subscribe(EventTaskCreated, func(ctx context.Context, event *Event) error {
+		return fmt.Errorf("database unavailable")
+	})
+
+	err := eb.Deliver(context.Background(), &Event{ID: "evt-1", Type: EventTaskCreated})
+	assert.EqualError(t, err, "database unavailable")
+}
+
+func TestEventBus_SubscribeAsDeduplicates(t *testing.T) {
+	eb := NewEventBus()
+	ctx := context.Background()
+
+	var handled atomic.Int32
+	fail := true
+	eb.SubscribeAs("notifications", EventTaskCreated, func(ctx context.Context, event *Event) error {
+		if fail {
+			return fmt.Errorf("websocket hub closed")
+		}
+		handled.Add(1)
+		return nil
+	})
+	var other atomic.Int32
+	eb.SubscribeAs("scheduler", EventTaskCreated, func(ctx context.Context, event *Event) error {
+		other.Add(1)
+		return nil
+	})
+
+	event := &Event{ID: "evt-1", Type: EventTaskCreated}
+
+	// A failed handling is released, so the redelivery is handled
+	require.Error(t, eb.Deliver(ctx, event))
+	fail = false
+	require.NoError(t, eb.Deliver(ctx, event))
+	require.NoError(t, eb.Deliver(ctx, event))
+
+	assert.Equal(t, int32(1), handled.Load())
+	assert.Equal(t, int32(1), other.Load())
+
+	// Another event is handled
+	require.NoError(t, eb.Deliver(ctx, &Event{ID: "evt-2", Type: EventTaskCreated}))
+	assert.Equal(t, int32(2), handled.Load())
+}
+
+func TestMemoryDedupStore_EvictsOldest(t *testing.T) {
+	store := NewMemoryDedupStore(2)
+	ctx := context.Background()
+
+	for _, id := range []string{"a", "b", "c"} {
+		claimed, err := store.Claim(ctx, "scheduler", id)
+		require.NoError(t, err)
+		assert.True(t, claimed)
+	}
+
+	claimed, _ := store.Claim(ctx, "scheduler", "c")
+	assert.False(t, claimed)
+	claimed, _ = store.Claim(ctx, "notifications", "c")
+	assert.True(t, claimed, "consumers are deduplicated separately")
+
+	// "a" was evicted
+	claimed, _ = store.Claim(ctx, "scheduler", "a")
+	assert.True(t, claimed)
+}
@@ -0,0 +1,344 @@
+package eventbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"unlimited-corp/pkg/logger"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBus defaults
+const (
+	defaultRedisStream        = "unlimited:events"
+	defaultRedisBatchSize     = 100
+	defaultRedisBlock         = 5 * time.Second
+	defaultRedisClaimInterval = 30 * time.Second
+	defaultRedisClaimMinIdle  = time.Minute
+	defaultRedisMaxDeliveries = 10
+	defaultRedisRetention     = 24 * time.Hour
+
+	// redisRetryDelay is how long a consumer waits after Redis fails
+	redisRetryDelay = time.Second
+)
+
+// RedisOptions configures a RedisBus; zero values fall back to the defaults
+type RedisOptions struct {
+	// Stream is the key of the stream all events are added to
+	Stream string
+	// Instance names this process: it is the consumer name in every group and
+	// names the group its own subscriptions read from. It must differ between
+	// processes; the host name by default.
+	Instance string
+	// BatchSize is how many messages a consumer reads or reclaims at once
+	BatchSize int64
+	// Block is how long a read waits for new messages
+	Block time.Duration
+	// ClaimInterval is how often pending messages are reclaimed
+	ClaimInterval time.Duration
+	// ClaimMinIdle is how long a message stays unacknowledged before it is
+	// reclaimed and delivered again, to this or another process
+	ClaimMinIdle time.Duration
+	// MaxDeliveries is how often a message is delivered before it is dropped
+	MaxDeliveries int64
+	// MaxLen caps the stream at about this many messages; 0 leaves it uncapped
+	MaxLen int64
+	// Retention is how long messages stay in the stream
+	Retention time.Duration
+}
+
+// RedisBus delivers events through a Redis stream, so events published by
+// one process reach the subscribers of every process sharing the stream.
+//
+// Each named consumer of SubscribeAs is a consumer group: the processes
+// subscribing under the name share its messages, so each event is handled
+// by one of them. Subscribe and SubscribeAll read from a group of their own
+// process, so each process receives every event. A message is acknowledged
+// once all of its group's handlers in the process succeed; otherwise it stays
+// pending and is delivered again after ClaimMinIdle, to the first process
+// that reclaims it, until MaxDeliveries.
+type RedisBus struct {
+	brokerBus
+	client *redis.Client
+	opts   RedisOptions
+}
+
+// NewRedisBus creates an event bus on a Redis connection; it consumes
+// events once started
+func NewRedisBus(client *redis.Client, opts RedisOptions) *RedisBus {
+	if opts.Stream == "" {
+		opts.Stream = defaultRedisStream
+	}
+	if opts.Instance == "" {
+		opts.Instance, _ = os.Hostname()
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultRedisBatchSize
+	}
+	if opts.Block <= 0 {
+		opts.Block = defaultRedisBlock
+	}
+	if opts.ClaimInterval <= 0 {
+		opts.ClaimInterval = defaultRedisClaimInterval
+	}
+	if opts.ClaimMinIdle <= 0 {
+		opts.ClaimMinIdle = defaultRedisClaimMinIdle
+	}
+	if opts.MaxDeliveries <= 0 {
+		opts.MaxDeliveries = defaultRedisMaxDeliveries
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = defaultRedisRetention
+	}
+	b := &RedisBus{brokerBus: newBrokerBus(), client: client, opts: opts}
+	b.send = b.Deliver
+	b.startGroup = b.startConsumer
+	return b
+}
+
+// localGroup is the consumer group of this process's own subscriptions
+func (b *RedisBus) localGroup() string {
+	return "instance:" + b.opts.Instance
+}
+
+// Deliver adds an event to the stream, trimming messages beyond MaxLen
+func (b *RedisBus) Deliver(ctx context.Context, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	args := &redis.XAddArgs{
+		Stream: b.opts.Stream,
+		Values: map[string]interface{}{"type": string(event.Type), "event": data},
+	}
+	if b.opts.MaxLen > 0 {
+		args.MaxLen = b.opts.MaxLen
+		args.Approx = true
+	}
+	if err := b.client.XAdd(ctx, args).Err(); err != nil {
+		return fmt.Errorf("failed to add event to stream: %w", err)
+	}
+	return nil
+}
+
+// Start consumes events until ctx is cancelled or Stop is called: this
+// process's own group and every named consumer read new messages, reclaim
+// pending ones, and the stream is trimmed to the retention. Processes that
+// only publish need not start the bus.
+func (b *RedisBus) Start(ctx context.Context) {
+	ctx, ok := b.start(ctx, b.localGroup())
+	if !ok {
+		return
+	}
+
+	b.running.Add(1)
+	go func() {
+		defer b.running.Done()
+		b.trimLoop(ctx)
+	}()
+}
+
+// Stop stops consuming and removes this process's own group; the groups of
+// named consumers stay for the other processes and the next start
+func (b *RedisBus) Stop() {
+	if !b.stop() {
+		return
+	}
+
+	ctx, done := context.WithTimeout(context.Background(), 5*time.Second)
+	defer done()
+	if err := b.client.XGroupDestroy(ctx, b.opts.Stream, b.localGroup()).Err(); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to remove event consumer group %s: %v", b.localGroup(), err))
+	}
+}
+
+// startConsumer creates a consumer group if needed and starts reading and
+// reclaiming its messages
+func (b *RedisBus) startConsumer(ctx context.Context, name string, group *MemoryBus) {
+	b.running.Add(2)
+	go func() {
+		defer b.running.Done()
+		if !b.createGroup(ctx, name) {
+			return
+		}
+		b.consume(ctx, name, group)
+	}()
+	go func() {
+		defer b.running.Done()
+		b.reclaimLoop(ctx, name, group)
+	}()
+}
+
+// createGroup creates a consumer group reading new messages, retrying until
+// it exists or ctx is cancelled
+func (b *RedisBus) createGroup(ctx context.Context, name string) bool {
+	for {
+		err := b.client.XGroupCreateMkStream(ctx, b.opts.Stream, name, "$").Err()
+		if err == nil || strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return true
+		}
+		logger.Warn(fmt.Sprintf("Failed to create event consumer group %s: %v", name, err))
+		if !sleep(ctx, redisRetryDelay) {
+			return false
+		}
+	}
+}
+
+// consume reads new messages of a group and handles them
+func (b *RedisBus) consume(ctx context.Context, name string, group *MemoryBus) {
+	for ctx.Err() == nil {
+		streams, err := b.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+			Group:    name,
+			Consumer: b.opts.Instance,
+			Streams:  []string{b.opts.Stream, ">"},
+			Count:    b.opts.BatchSize,
+			Block:    b.opts.Block,
+		}).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			if strings.HasPrefix(err.Error(), "NOGROUP") && !b.createGroup(ctx, name) {
+				return
+			}
+			logger.Warn(fmt.Sprintf("Failed to read events for consumer group %s: %v", name, err))
+			sleep(ctx, redisRetryDelay)
+			continue
+		}
+
+		for _, stream := range streams {
+			for _, msg := range stream.Messages {
+				b.handle(ctx, name, group, msg)
+			}
+		}
+	}
+}
+
+// reclaimLoop periodically reclaims a group's messages left unacknowledged
+// longer than ClaimMinIdle, by this process or one that stopped
+func (b *RedisBus) reclaimLoop(ctx context.Context, name string, group *MemoryBus) {
+	ticker := time.NewTicker(b.opts.ClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := b.reclaim(ctx, name, group); err != nil && ctx.Err() == nil {
+				logger.Warn(fmt.Sprintf("Failed to reclaim events for consumer group %s: %v", name, err))
+			}
+		}
+	}
+}
+
+// reclaim delivers a group's idle pending messages again, dropping those
+// delivered MaxDeliveries times
+func (b *RedisBus) reclaim(ctx context.Context, name string, group *MemoryBus) error {
+	pending, err := b.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: b.opts.Stream,
+		Group:  name,
+		Idle:   b.opts.ClaimMinIdle,
+		Start:  "-",
+		End:    "+",
+		Count:  b.opts.BatchSize,
+	}).Result()
+	if err != nil {
+		return err
+	}
+
+	for _, p := range pending {
+		if p.RetryCount >= b.opts.MaxDeliveries {
+			logger.Error(fmt.Sprintf("Dropping event message %s for consumer group %s after %d deliveries", p.ID, name, p.RetryCount))
+			b.ack(ctx, name, p.ID)
+			continue
+		}
+
+		msgs, err := b.client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   b.opts.Stream,
+			Group:    name,
+			Consumer: b.opts.Instance,
+			MinIdle:  b.opts.ClaimMinIdle,
+			Messages: []string{p.ID},
+		}).Result()
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			b.handle(ctx, name, group, msg)
+		}
+	}
+	return nil
+}
+
+// handle hands a message to a group's handlers and acknowledges it once
+// they all succeed. Messages that cannot be decoded are acknowledged and
+// dropped, since delivering them again cannot help.
+func (b *RedisBus) handle(ctx context.Context, name string, group *MemoryBus, msg redis.XMessage) {
+	event, err := decodeMessage(msg)
+	if err != nil {
+		logger.Error(fmt.Sprintf("Dropping invalid event message %s: %v", msg.ID, err))
+		b.ack(ctx, name, msg.ID)
+		return
+	}
+
+	if err := group.Deliver(ctx, event); err != nil {
+		logger.Warn(fmt.Sprintf("Consumer group %s failed to handle event %s (%s), it will be delivered again: %v",
+			name, event.ID, event.Type, err))
+		return
+	}
+	b.ack(ctx, name, msg.ID)
+}
+
+// ack acknowledges a message of a group
+func (b *RedisBus) ack(ctx context.Context, name, id string) {
+	if err := b.client.XAck(ctx, b.opts.Stream, name, id).Err(); err != nil && ctx.Err() == nil {
+		logger.Warn(fmt.Sprintf("Failed to acknowledge event message %s for consumer group %s: %v", id, name, err))
+	}
+}
+
+// trimLoop periodically removes messages older than the retention
+func (b *RedisBus) trimLoop(ctx context.Context) {
+	ticker := time.NewTicker(b.opts.ClaimInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := b.trim(ctx, now); err != nil && ctx.Err() == nil {
+				logger.Warn(fmt.Sprintf("Failed to trim the event stream: %v", err))
+			}
+		}
+	}
+}
+
+// trim removes the messages added before now minus the retention
+func (b *RedisBus) trim(ctx context.Context, now time.Time) error {
+	minID := fmt.Sprintf("%d-0", now.Add(-b.opts.Retention).UnixMilli())
+	return b.client.XTrimMinIDApprox(ctx, b.opts.Stream, minID, 0).Err()
+}
+
+// decodeMessage reads the event of a stream message
+func decodeMessage(msg redis.XMessage) (*Event, error) {
+	data, ok := msg.Values["event"].(string)
+	if !ok {
+		return nil, fmt.Errorf("message has no event")
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return nil, err
+	}
+	return &event, nil
+}
+
+var _ EventBus = (*RedisBus)(nil)
@@ -0,0 +1,195 @@
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"unlimited-corp/internal/infrastructure/config"
+	"unlimited-corp/pkg/logger"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&config.LogConfig{Level: "error", Format: "console"})
+	os.Exit(m.Run())
+}
+
+// newRedisBuses creates buses of different instances sharing one stream
+func newRedisBuses(t *testing.T, instances ...string) []*RedisBus {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+
+	var buses []*RedisBus
+	for _, instance := range instances {
+		bus := NewRedisBus(client, RedisOptions{
+			Instance:      instance,
+			Block:         20 * time.Millisecond,
+			ClaimInterval: time.Hour,
+			ClaimMinIdle:  10 * time.Millisecond,
+			MaxDeliveries: 3,
+		})
+		buses = append(buses, bus)
+	}
+	return buses
+}
+
+// startBuses starts buses and waits until the given number of consumer
+// groups exist, so no message is published before its group reads
+func startBuses(t *testing.T, groups int, buses ...*RedisBus) {
+	t.Helper()
+	for _, bus := range buses {
+		bus.Start(context.Background())
+		t.Cleanup(bus.Stop)
+	}
+	require.Eventually(t, func() bool {
+		infos, err := buses[0].client.XInfoGroups(context.Background(), buses[0].opts.Stream).Result()
+		return err == nil && len(infos) == groups
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestRedisBus_SubscribersOfEveryInstanceReceiveEvents(t *testing.T) {
+	buses := newRedisBuses(t, "api-1", "api-2")
+
+	var received [2]atomic.Int32
+	for i, bus := range buses {
+		i := i
+		bus.Subscribe(EventTaskCompleted, func(ctx context.Context, event *Event) error {
+			received[i].Add(1)
+			return nil
+		})
+	}
+	startBuses(t, 2, buses...)
+
+	// A worker publishes without consuming
+	worker := NewRedisBus(buses[0].client, RedisOptions{Instance: "worker"})
+	event, err := NewEvent(EventTaskCompleted, "temporal_worker", map[string]string{"id": "task-1"}, Metadata{Version: 1})
+	require.NoError(t, err)
+	require.NoError(t, worker.Publish(context.Background(), event))
+
+	require.Eventually(t, func() bool {
+		return received[0].Load() == 1 && received[1].Load() == 1
+	}, time.Second, 5*time.Millisecond)
+	history := buses[0].GetHistoryByType(EventTaskCompleted, 10)
+	require.Len(t, history, 1)
+	assert.Equal(t, event.ID, history[0].ID)
+}
+
+func TestRedisBus_NamedConsumerHandlesEachEventOnce(t *testing.T) {
+	buses := newRedisBuses(t, "api-1", "api-2")
+
+	var handled atomic.Int32
+	for _, bus := range buses {
+		bus.SubscribeAs("scheduler", EventTaskCreated, func(ctx context.Context, event *Event) error {
+			handled.Add(1)
+			return nil
+		})
+	}
+	startBuses(t, 3, buses...)
+
+	for i := 0; i < 10; i++ {
+		require.NoError(t, buses[i%2].Deliver(context.Background(), &Event{ID: fmt.Sprintf("evt-%d", i), Type: EventTaskCreated}))
+	}
+
+	require.Eventually(t, func() bool { return handled.Load() == 10 }, time.Second, 5*time.Millisecond)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(10), handled.Load())
+}
+
+func TestRedisBus_ReclaimsFailedMessages(t *testing.T) {
+	buses := newRedisBuses(t, "api-1")
+	bus := buses[0]
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	bus.SubscribeAs("notifications", EventTaskFailed, func(ctx context.Context, event *Event) error {
+		if attempts.Add(1) == 1 {
+			return fmt.Errorf("websocket hub closed")
+		}
+		return nil
+	})
+	startBuses(t, 2, bus)
+
+	require.NoError(t, bus.Deliver(ctx, &Event{ID: "evt-1", Type: EventTaskFailed}))
+	require.Eventually(t, func() bool { return attempts.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	// The failed message stays pending until it is reclaimed
+	pending, err := bus.client.XPending(ctx, bus.opts.Stream, "notifications").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pending.Count)
+
+	time.Sleep(20 * time.Millisecond)
+	require.NoError(t, bus.reclaim(ctx, "notifications", bus.groups["notifications"]))
+	assert.Equal(t, int32(2), attempts.Load())
+
+	pending, err = bus.client.XPending(ctx, bus.opts.Stream, "notifications").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pending.Count)
+}
+
+func TestRedisBus_DropsMessagesAfterMaxDeliveries(t *testing.T) {
+	buses := newRedisBuses(t, "api-1")
+	bus := buses[0]
+	ctx := context.Background()
+
+	var attempts atomic.Int32
+	bus.SubscribeAs("webhooks", EventTaskCompleted, func(ctx context.Context, event *Event) error {
+		attempts.Add(1)
+		return fmt.Errorf("endpoint down")
+	})
+	startBuses(t, 2, bus)
+
+	require.NoError(t, bus.Deliver(ctx, &Event{ID: "evt-1", Type: EventTaskCompleted}))
+	require.Eventually(t, func() bool { return attempts.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	group := bus.groups["webhooks"]
+	for i := 0; i < 3; i++ {
+		time.Sleep(20 * time.Millisecond)
+		require.NoError(t, bus.reclaim(ctx, "webhooks", group))
+	}
+	assert.Equal(t, int32(3), attempts.Load())
+
+	pending, err := bus.client.XPending(ctx, bus.opts.Stream, "webhooks").Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pending.Count)
+}
+
+func TestRedisBus_PublishWritesToOutbox(t *testing.T) {
+	buses := newRedisBuses(t, "api-1")
+	bus := buses[0]
+	outbox := &fakeOutbox{}
+	bus.SetOutbox(outbox)
+
+	require.NoError(t, bus.Publish(context.Background(), &Event{Type: EventTaskCreated}))
+	require.Len(t, outbox.events, 1)
+
+	length, err := bus.client.XLen(context.Background(), bus.opts.Stream).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), length)
+}
+
+func TestRedisBus_TrimsToRetention(t *testing.T) {
+	buses := newRedisBuses(t, "api-1")
+	bus := buses[0]
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, bus.Deliver(ctx, &Event{ID: fmt.Sprintf("evt-%d", i), Type: EventTaskCreated}))
+	}
+
+	require.NoError(t, bus.trim(ctx, time.Now()))
+	length, _ := bus.client.XLen(ctx, bus.opts.Stream).Result()
+	assert.Equal(t, int64(3), length)
+
+	require.NoError(t, bus.trim(ctx, time.Now().Add(bus.opts.Retention+time.Minute)))
+	length, _ = bus.client.XLen(ctx, bus.opts.Stream).Result()
+	assert.Equal(t, int64(0), length)
+}
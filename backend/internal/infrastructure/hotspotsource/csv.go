@@ -0,0 +1,116 @@
+package hotspotsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"unlimited-corp/internal/domain/hotspot"
+	"unlimited-corp/internal/infrastructure/config"
+)
+
+// utf8BOM starts CSVs saved by spreadsheet programs
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// csvDateLayouts are the date formats of the published_at column
+var csvDateLayouts = []string{"2006-01-02 15:04:05", "2006-01-02", "2006-01-02T15:04:05Z07:00"}
+
+// CSVSource reads the items of a CSV file or an uploaded CSV
+type CSVSource struct {
+	name     string
+	location location
+	data     []byte
+}
+
+// NewCSVSource creates a source reading a CSV file
+func NewCSVSource(cfg config.HotspotSourceConfig) *CSVSource {
+	return &CSVSource{name: cfg.Name, location: newLocation(cfg)}
+}
+
+// NewUploadSource creates a source of an uploaded CSV
+func NewUploadSource(name string, data []byte) *CSVSource {
+	return &CSVSource{name: name, data: data}
+}
+
+// Name returns the source's name
+func (s *CSVSource) Name() string {
+	return s.name
+}
+
+// Fetch reads the CSV
+func (s *CSVSource) Fetch(ctx context.Context) ([]hotspot.Item, error) {
+	data := s.data
+	if data == nil {
+		var err error
+		if data, err = s.location.read(ctx); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", s.name, err)
+		}
+	}
+	items, err := ParseCSV(data, s.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.name, err)
+	}
+	return items, nil
+}
+
+// ParseCSV reads the rows of a CSV with a header row. The title column is
+// required; url, summary, heat and published_at are optional. Rows without
+// a title are left out.
+func ParseCSV(data []byte, source string) ([]hotspot.Item, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("the CSV is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["title"]; !ok {
+		return nil, fmt.Errorf("the CSV has no title column")
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var items []hotspot.Item
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		item := hotspot.Item{
+			Title:       field(record, "title"),
+			URL:         field(record, "url"),
+			Summary:     field(record, "summary"),
+			PublishedAt: parseDate(field(record, "published_at"), csvDateLayouts),
+			Source:      source,
+		}
+		if item.Title == "" {
+			continue
+		}
+		if heat := field(record, "heat"); heat != "" {
+			if item.Heat, err = strconv.ParseFloat(heat, 64); err != nil {
+				return nil, fmt.Errorf("line %d: heat %q is not a number", line, heat)
+			}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
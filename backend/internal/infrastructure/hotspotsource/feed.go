@@ -0,0 +1,128 @@
+package hotspotsource
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"unlimited-corp/internal/domain/hotspot"
+	"unlimited-corp/internal/infrastructure/config"
+)
+
+// feedDateLayouts are the date formats RSS and Atom feeds use
+var feedDateLayouts = []string{time.RFC1123Z, time.RFC1123, time.RFC3339, "Mon, 2 Jan 2006 15:04:05 -0700"}
+
+// FeedSource reads the entries of an RSS 2.0 or Atom feed, in feed order
+type FeedSource struct {
+	name     string
+	location location
+}
+
+// NewFeedSource creates a feed source
+func NewFeedSource(cfg config.HotspotSourceConfig) *FeedSource {
+	return &FeedSource{name: cfg.Name, location: newLocation(cfg)}
+}
+
+// Name returns the source's name
+func (s *FeedSource) Name() string {
+	return s.name
+}
+
+// Fetch reads the feed
+func (s *FeedSource) Fetch(ctx context.Context) ([]hotspot.Item, error) {
+	data, err := s.location.read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read feed %s: %w", s.name, err)
+	}
+	items, err := ParseFeed(data, s.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse feed %s: %w", s.name, err)
+	}
+	return items, nil
+}
+
+// feed is an RSS 2.0 or an Atom document
+type feed struct {
+	XMLName xml.Name
+	Channel struct {
+		Items []struct {
+			Title       string `xml:"title"`
+			Link        string `xml:"link"`
+			Description string `xml:"description"`
+			PubDate     string `xml:"pubDate"`
+		} `xml:"item"`
+	} `xml:"channel"`
+	Entries []struct {
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Summary   string `xml:"summary"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+	} `xml:"entry"`
+}
+
+// ParseFeed parses an RSS 2.0 or Atom feed into items of the named source
+func ParseFeed(data []byte, source string) ([]hotspot.Item, error) {
+	var f feed
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	if err := decoder.Decode(&f); err != nil {
+		return nil, err
+	}
+
+	var items []hotspot.Item
+	switch f.XMLName.Local {
+	case "rss":
+		for _, e := range f.Channel.Items {
+			items = append(items, hotspot.Item{
+				Title:       strings.TrimSpace(e.Title),
+				URL:         strings.TrimSpace(e.Link),
+				Summary:     strings.TrimSpace(e.Description),
+				PublishedAt: parseDate(e.PubDate, feedDateLayouts),
+				Source:      source,
+			})
+		}
+	case "feed":
+		for _, e := range f.Entries {
+			item := hotspot.Item{
+				Title:       strings.TrimSpace(e.Title),
+				Summary:     strings.TrimSpace(e.Summary),
+				PublishedAt: parseDate(e.Published, feedDateLayouts),
+				Source:      source,
+			}
+			if item.PublishedAt == nil {
+				item.PublishedAt = parseDate(e.Updated, feedDateLayouts)
+			}
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					item.URL = l.Href
+					break
+				}
+			}
+			items = append(items, item)
+		}
+	default:
+		return nil, fmt.Errorf("<%s> is neither an RSS nor an Atom feed", f.XMLName.Local)
+	}
+	return items, nil
+}
+
+// parseDate parses a date in the first layout that fits, or returns nil
+func parseDate(value string, layouts []string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package hotspotsource
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"unlimited-corp/internal/domain/hotspot"
+	"unlimited-corp/internal/infrastructure/config"
+)
+
+// jsonDateLayouts are the date formats JSON endpoints use, besides Unix seconds
+var jsonDateLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// JSONSource reads the items of a JSON endpoint through a field mapping
+type JSONSource struct {
+	name     string
+	location location
+	mapping  config.HotspotFieldMapping
+}
+
+// NewJSONSource creates a JSON source; unmapped item fields take their default names
+func NewJSONSource(cfg config.HotspotSourceConfig) *JSONSource {
+	mapping := cfg.Mapping
+	defaults := []struct {
+		field *string
+		name  string
+	}{
+		{&mapping.Title, "title"},
+		{&mapping.URL, "url"},
+		{&mapping.Summary, "summary"},
+		{&mapping.Heat, "heat"},
+		{&mapping.PublishedAt, "published_at"},
+	}
+	for _, d := range defaults {
+		if *d.field == "" {
+			*d.field = d.name
+		}
+	}
+	return &JSONSource{name: cfg.Name, location: newLocation(cfg), mapping: mapping}
+}
+
+// Name returns the source's name
+func (s *JSONSource) Name() string {
+	return s.name
+}
+
+// Fetch reads the endpoint
+func (s *JSONSource) Fetch(ctx context.Context) ([]hotspot.Item, error) {
+	data, err := s.location.read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", s.name, err)
+	}
+	items, err := ParseJSON(data, s.mapping, s.name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", s.name, err)
+	}
+	return items, nil
+}
+
+// ParseJSON reads the items of a JSON document through a field mapping.
+// Items without a title are left out.
+func ParseJSON(data []byte, mapping config.HotspotFieldMapping, source string) ([]hotspot.Item, error) {
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	list, ok := lookup(doc, mapping.Items).([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%q is not a list", mapping.Items)
+	}
+
+	var items []hotspot.Item
+	for i, entry := range list {
+		item := hotspot.Item{
+			Title:   text(lookup(entry, mapping.Title)),
+			URL:     text(lookup(entry, mapping.URL)),
+			Summary: text(lookup(entry, mapping.Summary)),
+			Source:  source,
+		}
+		if item.Title == "" {
+			continue
+		}
+		heat, err := number(lookup(entry, mapping.Heat))
+		if err != nil {
+			return nil, fmt.Errorf("item %d: heat: %w", i, err)
+		}
+		item.Heat = heat
+		switch published := lookup(entry, mapping.PublishedAt).(type) {
+		case float64:
+			t := time.Unix(int64(published), 0)
+			item.PublishedAt = &t
+		case string:
+			item.PublishedAt = parseDate(published, jsonDateLayouts)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// lookup follows a dotted path of object keys; an empty path is the value itself
+func lookup(value interface{}, path string) interface{} {
+	if path == "" {
+		return value
+	}
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = object[key]
+	}
+	return value
+}
+
+// text returns a string or number field as text
+func text(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return ""
+}
+
+// number returns a numeric or numeric string field; a missing one is 0
+func number(value interface{}) (float64, error) {
+	switch v := value.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return v, nil
+	case string:
+		if strings.TrimSpace(v) == "" {
+			return 0, nil
+		}
+		return strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return 0, fmt.Errorf("%v is not a number", value)
+}
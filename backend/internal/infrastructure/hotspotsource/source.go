@@ -0,0 +1,126 @@
+// Package hotspotsource implements the hotspot sources: RSS and Atom feeds,
+// JSON endpoints read through a field mapping, and CSV files or uploads.
+// Feeds and endpoints are fetched over http(s) or read from a local file.
+package hotspotsource
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"unlimited-corp/internal/domain/hotspot"
+	"unlimited-corp/internal/infrastructure/config"
+)
+
+// defaultTimeout bounds fetching a source without a timeout of its own
+const defaultTimeout = 10 * time.Second
+
+// maxBodySize is the most a source may return
+const maxBodySize = 10 << 20
+
+// location is where a source reads from: an http(s) URL or a local file
+type location struct {
+	url     string
+	headers map[string]string
+	client  *http.Client
+}
+
+func newLocation(cfg config.HotspotSourceConfig) location {
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return location{url: cfg.URL, headers: cfg.Headers, client: &http.Client{Timeout: timeout}}
+}
+
+// read returns the content at the location
+func (l location) read(ctx context.Context) ([]byte, error) {
+	if !strings.HasPrefix(l.url, "http://") && !strings.HasPrefix(l.url, "https://") {
+		f, err := os.Open(strings.TrimPrefix(l.url, "file://"))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return io.ReadAll(io.LimitReader(f, maxBodySize))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, l.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range l.headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxBodySize))
+}
+
+// New creates the source a configuration describes
+func New(cfg config.HotspotSourceConfig) (hotspot.Source, error) {
+	if cfg.Name == "" {
+		return nil, fmt.Errorf("hotspot source needs a name")
+	}
+	if cfg.URL == "" {
+		return nil, fmt.Errorf("hotspot source %s needs a url", cfg.Name)
+	}
+	switch cfg.Type {
+	case config.HotspotSourceRSS:
+		return NewFeedSource(cfg), nil
+	case config.HotspotSourceJSON:
+		return NewJSONSource(cfg), nil
+	case config.HotspotSourceCSV:
+		return NewCSVSource(cfg), nil
+	}
+	return nil, fmt.Errorf("hotspot source %s has unknown type %q", cfg.Name, cfg.Type)
+}
+
+// Registry holds the configured sources by the platforms they serve
+type Registry struct {
+	sources   []hotspot.Source
+	platforms [][]string
+}
+
+// NewRegistry creates the sources of the configuration
+func NewRegistry(cfg config.HotspotConfig) (*Registry, error) {
+	r := &Registry{}
+	for _, sc := range cfg.Sources {
+		source, err := New(sc)
+		if err != nil {
+			return nil, err
+		}
+		r.sources = append(r.sources, source)
+		r.platforms = append(r.platforms, sc.Platforms)
+	}
+	return r, nil
+}
+
+// Sources returns the sources serving a platform
+func (r *Registry) Sources(platform string) []hotspot.Source {
+	var sources []hotspot.Source
+	for i, source := range r.sources {
+		if len(r.platforms[i]) == 0 || containsString(r.platforms[i], platform) {
+			sources = append(sources, source)
+		}
+	}
+	return sources
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package hotspotsource
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/infrastructure/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeedSource(t *testing.T) {
+	t.Run("rss", func(t *testing.T) {
+		items, err := NewFeedSource(config.HotspotSourceConfig{Name: "tech", URL: "testdata/feed.rss"}).Fetch(context.Background())
+		require.NoError(t, err)
+		require.Len(t, items, 3)
+		assert.Equal(t, "Go 1.23 发布", items[0].Title)
+		assert.Equal(t, "https://news.example.com/go-1-23", items[0].URL)
+		assert.Equal(t, "迭代器与新的标准库包", items[0].Summary)
+		assert.Equal(t, "tech", items[0].Source)
+		require.NotNil(t, items[1].PublishedAt)
+		assert.True(t, items[1].PublishedAt.Equal(time.Date(2024, 8, 14, 0, 30, 0, 0, time.UTC)))
+		assert.Nil(t, items[2].PublishedAt)
+	})
+
+	t.Run("atom", func(t *testing.T) {
+		items, err := NewFeedSource(config.HotspotSourceConfig{Name: "blog", URL: "file://testdata/feed.atom"}).Fetch(context.Background())
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		assert.Equal(t, "https://blog.example.com/ai-tools", items[0].URL, "the alternate link")
+		require.NotNil(t, items[0].PublishedAt, "updated stands in for published")
+		assert.Equal(t, "https://blog.example.com/remote", items[1].URL)
+	})
+
+	t.Run("not a feed", func(t *testing.T) {
+		_, err := ParseFeed([]byte(`<html><body></body></html>`), "page")
+		assert.ErrorContains(t, err, "neither an RSS nor an Atom feed")
+	})
+}
+
+func TestJSONSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		http.ServeFile(w, r, "testdata/hot.json")
+	}))
+	defer server.Close()
+
+	cfg := config.HotspotSourceConfig{
+		Name:    "weibo",
+		Type:    config.HotspotSourceJSON,
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+		Mapping: config.HotspotFieldMapping{Items: "data.list", Title: "word", Heat: "num", URL: "link", PublishedAt: "onboard_time"},
+	}
+	source, err := New(cfg)
+	require.NoError(t, err)
+	items, err := source.Fetch(context.Background())
+	require.NoError(t, err)
+
+	require.Len(t, items, 3, "items without a title are left out")
+	assert.Equal(t, "AI 工具推荐", items[0].Title)
+	assert.Equal(t, 152000.0, items[0].Heat)
+	assert.Equal(t, "https://hot.example.com/1", items[0].URL)
+	assert.Equal(t, int64(1723600000), items[0].PublishedAt.Unix())
+	assert.Equal(t, 98000.0, items[1].Heat, "numeric strings are numbers")
+	require.NotNil(t, items[2].PublishedAt)
+
+	cfg.Headers = nil
+	_, err = NewJSONSource(cfg).Fetch(context.Background())
+	assert.ErrorContains(t, err, "unexpected status 401")
+
+	_, err = ParseJSON([]byte(`{"data":{}}`), config.HotspotFieldMapping{Items: "data.list"}, "weibo")
+	assert.ErrorContains(t, err, `"data.list" is not a list`)
+}
+
+func TestCSVSource(t *testing.T) {
+	items, err := NewCSVSource(config.HotspotSourceConfig{Name: "manual", URL: "testdata/topics.csv"}).Fetch(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "开学季", items[0].Title, "the byte order mark is not part of the header")
+	assert.Equal(t, 120000.0, items[0].Heat)
+	assert.Equal(t, "返校好物", items[0].Summary)
+	require.NotNil(t, items[0].PublishedAt)
+	assert.Equal(t, "秋季穿搭, 新品", items[1].Title)
+
+	_, err = ParseCSV([]byte("topic,heat\nAI,1\n"), "upload")
+	assert.ErrorContains(t, err, "no title column")
+	_, err = ParseCSV([]byte("title,heat\nAI,hot\n"), "upload")
+	assert.ErrorContains(t, err, `line 2: heat "hot" is not a number`)
+
+	items, err = NewUploadSource("upload", []byte("Title\nAI\n")).Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "upload", items[0].Source)
+}
+
+func TestRegistry(t *testing.T) {
+	registry, err := NewRegistry(config.HotspotConfig{Sources: []config.HotspotSourceConfig{
+		{Name: "tech", Type: config.HotspotSourceRSS, URL: "testdata/feed.rss"},
+		{Name: "weibo", Type: config.HotspotSourceJSON, URL: "https://hot.example.com", Platforms: []string{"weibo"}},
+	}})
+	require.NoError(t, err)
+	assert.Len(t, registry.Sources("weibo"), 2)
+	require.Len(t, registry.Sources("douyin"), 1)
+	assert.Equal(t, "tech", registry.Sources("douyin")[0].Name())
+
+	_, err = NewRegistry(config.HotspotConfig{Sources: []config.HotspotSourceConfig{{Name: "x", Type: "xml", URL: "x.xml"}}})
+	assert.ErrorContains(t, err, `unknown type "xml"`)
+	_, err = NewRegistry(config.HotspotConfig{Sources: []config.HotspotSourceConfig{{Name: "x", Type: "rss"}}})
+	assert.ErrorContains(t, err, "needs a url")
+}
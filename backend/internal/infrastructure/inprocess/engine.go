@@ -0,0 +1,361 @@
+// Package inprocess runs task workflows inside the server process, for
+// installs without a Temporal cluster. Runs are goroutines driving the same
+// definition.Run as the Temporal interpreter; their steps are checkpointed in
+// the database, so runs interrupted by a restart resume from the last
+// finished step.
+package inprocess
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/temporal"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// RunIDPrefix starts the run IDs of in-process workflows, telling them apart
+// from Temporal runs
+const RunIDPrefix = "inprocess-"
+
+// Activities performs the side effects of a workflow run; implemented by
+// temporal.Activities, whose methods are called directly here instead of as
+// Temporal activities
+type Activities interface {
+	ExecuteSkillActivity(ctx context.Context, input temporal.SkillExecutionInput) (*temporal.SkillExecutionResult, error)
+	UpdateTaskStatusActivity(ctx context.Context, input temporal.TaskStatusInput) error
+	RequestApprovalActivity(ctx context.Context, input temporal.ApprovalRequestInput) error
+	RecordApprovalActivity(ctx context.Context, input temporal.ApprovalDecisionInput) error
+	RecordStepActivity(ctx context.Context, input temporal.StepRecordInput) error
+	PublishContentActivity(ctx context.Context, input temporal.PublishInput) (map[string]interface{}, error)
+	UnpublishContentActivity(ctx context.Context, input temporal.UnpublishInput) error
+	AnalyzeHotspotsActivity(ctx context.Context, input temporal.HotspotAnalysisInput) (*temporal.SkillExecutionResult, error)
+}
+
+// RetryPolicy is how often and how fast failed skill executions and
+// bookkeeping calls are retried, mirroring the Temporal activity options
+type RetryPolicy struct {
+	InitialInterval time.Duration
+	MaximumInterval time.Duration
+	// BookkeepingAttempts bounds the attempts of recording steps, approvals and task status
+	BookkeepingAttempts int
+}
+
+// DefaultRetryPolicy matches the retry policy of the Temporal engine
+var DefaultRetryPolicy = RetryPolicy{
+	InitialInterval:     time.Second,
+	MaximumInterval:     time.Minute,
+	BookkeepingAttempts: 5,
+}
+
+// WorkflowEngine runs task workflows in goroutines of the server process,
+// one run per task
+type WorkflowEngine struct {
+	activities Activities
+	tasks      task.Repository
+	steps      task.StepRepository
+	attempts   task.AttemptRepository
+	retry      RetryPolicy
+
+	// ctx is cancelled by Stop, which interrupts runs without ending their tasks
+	ctx  context.Context
+	stop context.CancelFunc
+
+	mu   sync.Mutex
+	runs map[uuid.UUID]*run
+	wg   sync.WaitGroup
+}
+
+// NewWorkflowEngine creates an in-process workflow engine. Steps are
+// checkpointed through the activities; the repositories are read to report
+// the outcome of finished runs and to recover interrupted ones.
+func NewWorkflowEngine(activities Activities, tasks task.Repository, steps task.StepRepository, attempts task.AttemptRepository) *WorkflowEngine {
+	ctx, stop := context.WithCancel(context.Background())
+	return &WorkflowEngine{
+		activities: activities,
+		tasks:      tasks,
+		steps:      steps,
+		attempts:   attempts,
+		retry:      DefaultRetryPolicy,
+		ctx:        ctx,
+		stop:       stop,
+		runs:       make(map[uuid.UUID]*run),
+	}
+}
+
+// SetRetryPolicy overrides the default retry policy
+func (e *WorkflowEngine) SetRetryPolicy(policy RetryPolicy) {
+	e.retry = policy
+}
+
+// Start starts a run of the task's workflow, taking over what an earlier
+// attempt did when resume is set. A run already going for the task is
+// returned instead of starting another one.
+func (e *WorkflowEngine) Start(ctx context.Context, t *task.Task, def *definition.Definition, resume *task.Resume) (string, string, error) {
+	r := e.newRun(t, def, RunIDPrefix+uuid.New().String())
+	if resume != nil {
+		r.attempt = resume.Attempt
+		r.reused = resume.Outputs
+		r.stepInputs = resume.StepInputs
+	}
+	r = e.launch(r)
+	return r.workflowID, r.runID, nil
+}
+
+// Recover restarts the runs of running and paused tasks that were
+// interrupted when the process stopped. Steps that finished are taken over
+// from their checkpoints; steps that were in flight run again with the input
+// they were started with.
+func (e *WorkflowEngine) Recover(ctx context.Context) (int, error) {
+	tasks, err := e.tasks.ListActiveWorkflowRuns(ctx, RunIDPrefix)
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for _, t := range tasks {
+		if err := e.recover(ctx, t); err != nil {
+			logger.Warn(fmt.Sprintf("Failed to recover workflow of task %s: %v", t.ID, err))
+			continue
+		}
+		recovered++
+	}
+	return recovered, nil
+}
+
+// recover restarts the interrupted run of a task from its checkpoints
+func (e *WorkflowEngine) recover(ctx context.Context, t *task.Task) error {
+	def, err := definition.Parse(t.WorkflowDefinition)
+	if err != nil {
+		return err
+	}
+	attempt, err := e.currentAttempt(ctx, t.ID)
+	if err != nil {
+		return err
+	}
+	steps, err := e.steps.ListSteps(ctx, t.ID, attempt)
+	if err != nil {
+		return err
+	}
+
+	r := e.newRun(t, def, t.TemporalRunID)
+	r.attempt = attempt
+	r.paused = t.Status == task.StatusPaused
+	r.reused = make(map[string]map[string]interface{})
+	r.stepInputs = make(map[string]map[string]interface{})
+	for _, s := range steps {
+		switch s.Status {
+		case task.StepCompleted:
+			r.reused[s.NodeID] = s.Output
+		case task.StepRunning:
+			r.stepInputs[s.NodeID] = s.Input
+		}
+	}
+	e.launch(r)
+	return nil
+}
+
+// newRun prepares a run of the task's workflow
+func (e *WorkflowEngine) newRun(t *task.Task, def *definition.Definition, runID string) *run {
+	r := &run{
+		engine:     e,
+		taskID:     t.ID,
+		companyID:  t.CompanyID.String(),
+		workflowID: temporal.TaskWorkflowID(t),
+		runID:      runID,
+		def:        def,
+		input:      t.InputData,
+		attempt:    1,
+		resumed:    make(chan struct{}),
+		approvals:  make(map[string]chan task.ApprovalDecision),
+		done:       make(chan struct{}),
+	}
+	if t.AssignedEmployeeID != nil {
+		r.employeeID = t.AssignedEmployeeID.String()
+	}
+	for _, node := range def.Nodes {
+		r.pipeline.Steps = append(r.pipeline.Steps, task.PipelineStep{
+			ID:     node.ID,
+			Name:   node.DisplayName(),
+			Status: task.StepPending,
+		})
+	}
+	return r
+}
+
+// launch starts the run unless the task already has one going, which is
+// returned instead
+func (e *WorkflowEngine) launch(r *run) *run {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if existing, ok := e.runs[r.taskID]; ok {
+		return existing
+	}
+
+	r.ctx, r.cancel = context.WithCancel(e.ctx)
+	e.runs[r.taskID] = r
+	e.wg.Add(1)
+	go func() {
+		defer e.wg.Done()
+		defer e.forget(r)
+		r.execute()
+	}()
+	return r
+}
+
+// forget removes a finished run
+func (e *WorkflowEngine) forget(r *run) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.runs[r.taskID] == r {
+		delete(e.runs, r.taskID)
+	}
+}
+
+// run returns the run of the task's current workflow
+func (e *WorkflowEngine) run(t *task.Task) (*run, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	r, ok := e.runs[t.ID]
+	if !ok || (t.TemporalRunID != "" && r.runID != t.TemporalRunID) {
+		return nil, fmt.Errorf("workflow of task %s is not running in this process", t.ID)
+	}
+	return r, nil
+}
+
+// Stop interrupts every run and waits for them to return. Interrupted runs
+// leave their tasks and in-flight steps as they are, for Recover to pick up.
+func (e *WorkflowEngine) Stop() {
+	e.stop()
+	e.wg.Wait()
+}
+
+// Outcome returns how the steps of the task's last run ended, from their
+// checkpoints. A step whose compensation undid it leaves no output to reuse.
+func (e *WorkflowEngine) Outcome(ctx context.Context, t *task.Task) (*task.RunOutcome, error) {
+	if !strings.HasPrefix(t.TemporalRunID, RunIDPrefix) {
+		return nil, fmt.Errorf("task %s did not run in the in-process engine", t.ID)
+	}
+	attempt, err := e.currentAttempt(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := e.steps.ListSteps(ctx, t.ID, attempt)
+	if err != nil {
+		return nil, err
+	}
+
+	outcome := &task.RunOutcome{Outputs: make(map[string]map[string]interface{})}
+	for _, s := range steps {
+		switch s.Status {
+		case task.StepCompleted, task.StepCompensationFailed:
+			outcome.Outputs[s.NodeID] = s.Output
+		case task.StepFailed:
+			if outcome.FailedStep == "" {
+				outcome.FailedStep = s.NodeID
+			}
+		}
+	}
+	return outcome, nil
+}
+
+// Pause makes the task's run stop before its next step
+func (e *WorkflowEngine) Pause(ctx context.Context, t *task.Task) error {
+	r, err := e.run(t)
+	if err != nil {
+		return err
+	}
+	r.pause()
+	return nil
+}
+
+// Resume lets the task's paused run continue
+func (e *WorkflowEngine) Resume(ctx context.Context, t *task.Task) error {
+	r, err := e.run(t)
+	if err != nil {
+		return err
+	}
+	r.resume()
+	return nil
+}
+
+// Cancel cancels the task's run, interrupting the steps in flight
+func (e *WorkflowEngine) Cancel(ctx context.Context, t *task.Task) error {
+	r, err := e.run(t)
+	if err != nil {
+		return err
+	}
+	r.cancelByUser()
+	return nil
+}
+
+// Decide hands a reviewer's decision to the approval the task's run waits
+// for. Decisions for nodes that are not waiting are dropped.
+func (e *WorkflowEngine) Decide(ctx context.Context, t *task.Task, d task.ApprovalDecision) error {
+	r, err := e.run(t)
+	if err != nil {
+		return err
+	}
+	if !r.decide(d) {
+		logger.Warn(fmt.Sprintf("Dropping approval of task %s for node %s that is not waiting", t.ID, d.NodeID))
+	}
+	return nil
+}
+
+// Progress returns the live pipeline of the task's run. A run that finished
+// or is not in this process is reported from its checkpoints.
+func (e *WorkflowEngine) Progress(ctx context.Context, t *task.Task) (*task.Pipeline, error) {
+	if r, err := e.run(t); err == nil {
+		return r.snapshot(), nil
+	}
+
+	def, err := definition.Parse(t.WorkflowDefinition)
+	if err != nil {
+		return nil, err
+	}
+	attempt, err := e.currentAttempt(ctx, t.ID)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := e.steps.ListSteps(ctx, t.ID, attempt)
+	if err != nil {
+		return nil, err
+	}
+	recorded := make(map[string]*task.Step, len(steps))
+	for _, s := range steps {
+		recorded[s.NodeID] = s
+	}
+
+	pipeline := &task.Pipeline{Paused: t.Status == task.StatusPaused}
+	for _, node := range def.Nodes {
+		step := task.PipelineStep{ID: node.ID, Name: node.DisplayName(), Status: task.StepPending}
+		if s, ok := recorded[node.ID]; ok {
+			step = s.PipelineStep()
+		}
+		if step.Status == task.StepRunning && pipeline.CurrentStep == "" {
+			pipeline.CurrentStep = step.ID
+		}
+		pipeline.Steps = append(pipeline.Steps, step)
+	}
+	pipeline.UpdatePercent()
+	return pipeline, nil
+}
+
+// currentAttempt returns the number of the task's current run: one more
+// than the attempts it was retried from
+func (e *WorkflowEngine) currentAttempt(ctx context.Context, taskID uuid.UUID) (int, error) {
+	if e.attempts == nil {
+		return 1, nil
+	}
+	attempts, err := e.attempts.ListAttempts(ctx, taskID)
+	if err != nil {
+		return 0, err
+	}
+	return len(attempts) + 1, nil
+}
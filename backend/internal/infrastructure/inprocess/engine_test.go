@@ -0,0 +1,578 @@
+package inprocess
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/config"
+	"unlimited-corp/internal/infrastructure/temporal"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktemporal "go.temporal.io/sdk/temporal"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&config.LogConfig{Level: "error", Format: "console"})
+	m.Run()
+}
+
+// fakeActivities scripts skill executions and keeps the steps and task
+// statuses a run records. It is also the step repository the engine reads.
+type fakeActivities struct {
+	task.StepRepository
+
+	mu       sync.Mutex
+	outputs  map[string]map[string]interface{}
+	failures map[string][]error
+	blockers map[string]chan struct{}
+	// held are the nodes whose recorded start waits to be released, for at
+	// most two seconds
+	held      map[string]chan struct{}
+	calls     map[string][]map[string]interface{}
+	order     []string
+	steps     map[string]*task.Step
+	statuses  []temporal.TaskStatusInput
+	approvals []temporal.ApprovalDecisionInput
+	// down are the platforms publishing fails on
+	down        map[string]bool
+	published   []temporal.PublishInput
+	unpublished []string
+	analyzed    []temporal.HotspotAnalysisInput
+}
+
+func newFakeActivities() *fakeActivities {
+	return &fakeActivities{
+		outputs:  make(map[string]map[string]interface{}),
+		failures: make(map[string][]error),
+		blockers: make(map[string]chan struct{}),
+		held:     make(map[string]chan struct{}),
+		calls:    make(map[string][]map[string]interface{}),
+		steps:    make(map[string]*task.Step),
+		down:     make(map[string]bool),
+	}
+}
+
+func (a *fakeActivities) ExecuteSkillActivity(ctx context.Context, input temporal.SkillExecutionInput) (*temporal.SkillExecutionResult, error) {
+	a.mu.Lock()
+	a.calls[input.SkillCardID] = append(a.calls[input.SkillCardID], input.Parameters)
+	a.order = append(a.order, input.SkillCardID)
+	blocker := a.blockers[input.SkillCardID]
+	var err error
+	if failures := a.failures[input.SkillCardID]; len(failures) > 0 {
+		err, a.failures[input.SkillCardID] = failures[0], failures[1:]
+	}
+	output := a.outputs[input.SkillCardID]
+	a.mu.Unlock()
+
+	if blocker != nil {
+		select {
+		case <-blocker:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &temporal.SkillExecutionResult{Success: true, Output: output}, nil
+}
+
+func (a *fakeActivities) UpdateTaskStatusActivity(_ context.Context, input temporal.TaskStatusInput) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.statuses = append(a.statuses, input)
+	return nil
+}
+
+func (a *fakeActivities) RequestApprovalActivity(context.Context, temporal.ApprovalRequestInput) error {
+	return nil
+}
+
+func (a *fakeActivities) RecordApprovalActivity(_ context.Context, input temporal.ApprovalDecisionInput) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.approvals = append(a.approvals, input)
+	return nil
+}
+
+func (a *fakeActivities) RecordStepActivity(_ context.Context, input temporal.StepRecordInput) error {
+	a.mu.Lock()
+	release := a.held[input.NodeID]
+	a.mu.Unlock()
+	if input.Status == task.StepRunning && release != nil {
+		select {
+		case <-release:
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	taskID, _ := uuid.Parse(input.TaskID)
+	step, ok := a.steps[input.NodeID]
+	if !ok {
+		step = task.NewStep(taskID, input.Attempt, input.Order, input.NodeID, input.Name)
+		a.steps[input.NodeID] = step
+	}
+	step.Status = input.Status
+	step.Error = input.Error
+	step.Reused = input.Reused
+	if input.Input != nil {
+		step.Input = input.Input
+	}
+	if input.Output != nil {
+		step.Output = input.Output
+	}
+	return nil
+}
+
+func (a *fakeActivities) PublishContentActivity(_ context.Context, input temporal.PublishInput) (map[string]interface{}, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.published = append(a.published, input)
+	statuses := make(map[string]string, len(input.Platforms))
+	posts := make(map[string]interface{}, len(input.Platforms))
+	for _, platform := range input.Platforms {
+		if a.down[platform] {
+			statuses[platform] = "failed"
+			continue
+		}
+		statuses[platform] = "success"
+		posts[platform] = map[string]interface{}{"postId": "post-" + platform}
+	}
+	return map[string]interface{}{"platforms": input.Platforms, "status": statuses, "posts": posts}, nil
+}
+
+func (a *fakeActivities) UnpublishContentActivity(_ context.Context, input temporal.UnpublishInput) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.unpublished = append(a.unpublished, input.Platforms...)
+	return nil
+}
+
+func (a *fakeActivities) AnalyzeHotspotsActivity(_ context.Context, input temporal.HotspotAnalysisInput) (*temporal.SkillExecutionResult, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.analyzed = append(a.analyzed, input)
+	return &temporal.SkillExecutionResult{Success: true, Output: map[string]interface{}{
+		"platform": input.Platform,
+		"summary":  "AI Agents",
+		"keywords": []string{"AI Agents"},
+	}}, nil
+}
+
+func (a *fakeActivities) ListSteps(_ context.Context, _ uuid.UUID, _ int) ([]*task.Step, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	steps := make([]*task.Step, 0, len(a.steps))
+	for _, s := range a.steps {
+		copied := *s
+		steps = append(steps, &copied)
+	}
+	return steps, nil
+}
+
+func (a *fakeActivities) block(skill string) chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	release := make(chan struct{})
+	a.blockers[skill] = release
+	return release
+}
+
+func (a *fakeActivities) holdStart(nodeID string) chan struct{} {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	release := make(chan struct{})
+	a.held[nodeID] = release
+	return release
+}
+
+func (a *fakeActivities) callCount(skill string) int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return len(a.calls[skill])
+}
+
+func (a *fakeActivities) stepStatus(nodeID string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s, ok := a.steps[nodeID]; ok {
+		return s.Status
+	}
+	return ""
+}
+
+// finalStatus returns the status the run ended its task with
+func (a *fakeActivities) finalStatus() (temporal.TaskStatusInput, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, s := range a.statuses {
+		if s.Status != "" {
+			return s, true
+		}
+	}
+	return temporal.TaskStatusInput{}, false
+}
+
+// fakeTasks lists the tasks recovery restarts
+type fakeTasks struct {
+	task.Repository
+	active []*task.Task
+}
+
+func (r *fakeTasks) ListActiveWorkflowRuns(context.Context, string) ([]*task.Task, error) {
+	return r.active, nil
+}
+
+func newTestEngine(activities *fakeActivities, tasks *fakeTasks) *WorkflowEngine {
+	if tasks == nil {
+		tasks = &fakeTasks{}
+	}
+	e := NewWorkflowEngine(activities, tasks, activities, nil)
+	e.SetRetryPolicy(RetryPolicy{
+		InitialInterval:     time.Millisecond,
+		MaximumInterval:     5 * time.Millisecond,
+		BookkeepingAttempts: 2,
+	})
+	return e
+}
+
+func newRunningTask() *task.Task {
+	t := task.NewTask(uuid.New(), "Write a post", "", task.PriorityMedium)
+	t.Status = task.StatusRunning
+	t.InputData = map[string]interface{}{"topic": "AI"}
+	return t
+}
+
+// draftAndPublish is a two-step definition: publish posts what draft wrote
+func draftAndPublish(draft, publish string) *definition.Definition {
+	return &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft,
+				Inputs: map[string]interface{}{"topic": "$.input.topic"}},
+			{ID: "publish", Type: definition.NodeSkill, SkillCardID: publish,
+				Inputs: map[string]interface{}{"text": "$.nodes.draft.output.content"}},
+		},
+		Edges: []definition.Edge{{From: "draft", To: "publish"}},
+	}
+}
+
+func waitForEnd(t *testing.T, activities *fakeActivities) temporal.TaskStatusInput {
+	t.Helper()
+	var final temporal.TaskStatusInput
+	require.Eventually(t, func() bool {
+		var ok bool
+		final, ok = activities.finalStatus()
+		return ok
+	}, 5*time.Second, 5*time.Millisecond)
+	return final
+}
+
+func TestWorkflowEngine_RunsDefinition(t *testing.T) {
+	draft, publish := uuid.NewString(), uuid.NewString()
+	activities := newFakeActivities()
+	activities.outputs[draft] = map[string]interface{}{"content": "hello"}
+	activities.outputs[publish] = map[string]interface{}{"url": "https://example.com/1"}
+	e := newTestEngine(activities, nil)
+	defer e.Stop()
+
+	tk := newRunningTask()
+	def := draftAndPublish(draft, publish)
+	tk.WorkflowDefinition = def.ToMap()
+	workflowID, runID, err := e.Start(context.Background(), tk, def, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "task-"+tk.ID.String(), workflowID)
+	assert.Contains(t, runID, RunIDPrefix)
+
+	final := waitForEnd(t, activities)
+	assert.Equal(t, string(task.StatusCompleted), final.Status)
+	assert.Equal(t, "https://example.com/1", final.Output["url"])
+	assert.Equal(t, "hello", activities.calls[publish][0]["text"])
+	assert.Equal(t, task.StepCompleted, activities.stepStatus("draft"))
+	assert.Equal(t, task.StepCompleted, activities.stepStatus("publish"))
+	assert.Equal(t, 50, activities.statuses[0].Progress)
+
+	tk.TemporalRunID = runID
+	outcome, err := e.Outcome(context.Background(), tk)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", outcome.Outputs["draft"]["content"])
+	assert.Empty(t, outcome.FailedStep)
+
+	pipeline, err := e.Progress(context.Background(), tk)
+	require.NoError(t, err)
+	assert.Equal(t, 100, pipeline.Percent)
+}
+
+func TestWorkflowEngine_RetriesFailedSkills(t *testing.T) {
+	t.Run("succeeds within the node's attempts", func(t *testing.T) {
+		draft, publish := uuid.NewString(), uuid.NewString()
+		activities := newFakeActivities()
+		activities.failures[draft] = []error{errors.New("rate limited"), errors.New("rate limited")}
+		e := newTestEngine(activities, nil)
+		defer e.Stop()
+
+		_, _, err := e.Start(context.Background(), newRunningTask(), draftAndPublish(draft, publish), nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, string(task.StatusCompleted), waitForEnd(t, activities).Status)
+		assert.Equal(t, 3, activities.callCount(draft))
+	})
+
+	t.Run("fails the task once attempts run out", func(t *testing.T) {
+		draft, publish := uuid.NewString(), uuid.NewString()
+		activities := newFakeActivities()
+		activities.failures[draft] = []error{errors.New("rate limited"), errors.New("rate limited")}
+		e := newTestEngine(activities, nil)
+		defer e.Stop()
+
+		def := draftAndPublish(draft, publish)
+		def.Nodes[0].MaxAttempts = 2
+		tk := newRunningTask()
+		_, runID, err := e.Start(context.Background(), tk, def, nil)
+		require.NoError(t, err)
+
+		final := waitForEnd(t, activities)
+		assert.Equal(t, string(task.StatusFailed), final.Status)
+		assert.Equal(t, "node draft failed: rate limited", final.Error)
+		assert.Equal(t, 2, activities.callCount(draft))
+		assert.Zero(t, activities.callCount(publish))
+
+		tk.TemporalRunID = runID
+		outcome, err := e.Outcome(context.Background(), tk)
+		require.NoError(t, err)
+		assert.Equal(t, "draft", outcome.FailedStep)
+	})
+
+	t.Run("does not retry non-retryable errors", func(t *testing.T) {
+		draft, publish := uuid.NewString(), uuid.NewString()
+		activities := newFakeActivities()
+		activities.failures[draft] = []error{sdktemporal.NewNonRetryableApplicationError("skill card not found", "NotFound", nil)}
+		e := newTestEngine(activities, nil)
+		defer e.Stop()
+
+		_, _, err := e.Start(context.Background(), newRunningTask(), draftAndPublish(draft, publish), nil)
+		require.NoError(t, err)
+
+		assert.Equal(t, string(task.StatusFailed), waitForEnd(t, activities).Status)
+		assert.Equal(t, 1, activities.callCount(draft))
+	})
+}
+
+func TestWorkflowEngine_TimesOutSteps(t *testing.T) {
+	draft, publish := uuid.NewString(), uuid.NewString()
+	activities := newFakeActivities()
+	activities.block(draft)
+	e := newTestEngine(activities, nil)
+	defer e.Stop()
+
+	def := draftAndPublish(draft, publish)
+	def.Nodes[0].TimeoutSeconds = 1
+	def.Nodes[0].MaxAttempts = 1
+	_, _, err := e.Start(context.Background(), newRunningTask(), def, nil)
+	require.NoError(t, err)
+
+	final := waitForEnd(t, activities)
+	assert.Equal(t, string(task.StatusFailed), final.Status)
+	assert.Contains(t, final.Error, "deadline exceeded")
+}
+
+func TestWorkflowEngine_PauseResume(t *testing.T) {
+	draft, publish := uuid.NewString(), uuid.NewString()
+	activities := newFakeActivities()
+	release := activities.block(draft)
+	e := newTestEngine(activities, nil)
+	defer e.Stop()
+
+	tk := newRunningTask()
+	_, runID, err := e.Start(context.Background(), tk, draftAndPublish(draft, publish), nil)
+	require.NoError(t, err)
+	tk.TemporalRunID = runID
+	require.Eventually(t, func() bool { return activities.callCount(draft) == 1 }, time.Second, time.Millisecond)
+
+	// The step in flight finishes, but the next one waits for resume
+	require.NoError(t, e.Pause(context.Background(), tk))
+	close(release)
+	require.Eventually(t, func() bool { return activities.stepStatus("draft") == task.StepCompleted }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Zero(t, activities.callCount(publish))
+
+	pipeline, err := e.Progress(context.Background(), tk)
+	require.NoError(t, err)
+	assert.True(t, pipeline.Paused)
+	assert.Equal(t, 50, pipeline.Percent)
+
+	require.NoError(t, e.Resume(context.Background(), tk))
+	assert.Equal(t, string(task.StatusCompleted), waitForEnd(t, activities).Status)
+	assert.Equal(t, 1, activities.callCount(publish))
+}
+
+func TestWorkflowEngine_Cancel(t *testing.T) {
+	draft, publish := uuid.NewString(), uuid.NewString()
+	activities := newFakeActivities()
+	activities.block(draft)
+	e := newTestEngine(activities, nil)
+	defer e.Stop()
+
+	tk := newRunningTask()
+	_, runID, err := e.Start(context.Background(), tk, draftAndPublish(draft, publish), nil)
+	require.NoError(t, err)
+	tk.TemporalRunID = runID
+	require.Eventually(t, func() bool { return activities.callCount(draft) == 1 }, time.Second, time.Millisecond)
+
+	require.NoError(t, e.Cancel(context.Background(), tk))
+
+	assert.Equal(t, string(task.StatusCancelled), waitForEnd(t, activities).Status)
+	assert.Equal(t, task.StepCancelled, activities.stepStatus("draft"))
+	assert.Zero(t, activities.callCount(publish))
+	assert.Error(t, e.Pause(context.Background(), tk), "a finished run takes no more signals")
+}
+
+func TestWorkflowEngine_Approval(t *testing.T) {
+	draft := uuid.NewString()
+	activities := newFakeActivities()
+	activities.outputs[draft] = map[string]interface{}{"content": "hello"}
+	e := newTestEngine(activities, nil)
+	defer e.Stop()
+
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft},
+			{ID: "review", Type: definition.NodeApproval},
+		},
+		Edges: []definition.Edge{{From: "draft", To: "review"}},
+	}
+	tk := newRunningTask()
+	_, runID, err := e.Start(context.Background(), tk, def, nil)
+	require.NoError(t, err)
+	tk.TemporalRunID = runID
+	require.Eventually(t, func() bool { return activities.stepStatus("review") == task.StepRunning }, time.Second, time.Millisecond)
+
+	reviewer := uuid.New()
+	require.NoError(t, e.Decide(context.Background(), tk, task.ApprovalDecision{
+		NodeID:    "review",
+		Action:    task.ApprovalEdit,
+		Content:   map[string]interface{}{"content": "hello, edited"},
+		DecidedBy: reviewer,
+	}))
+
+	final := waitForEnd(t, activities)
+	assert.Equal(t, string(task.StatusCompleted), final.Status)
+	assert.Equal(t, "hello, edited", final.Output["content"])
+	require.Len(t, activities.approvals, 1)
+	assert.Equal(t, reviewer.String(), activities.approvals[0].Decision.DecidedBy)
+}
+
+func TestWorkflowEngine_RecordsStartsWithoutWaiting(t *testing.T) {
+	left, right := uuid.NewString(), uuid.NewString()
+	activities := newFakeActivities()
+	release := activities.holdStart("left")
+	e := newTestEngine(activities, nil)
+	defer e.Stop()
+
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "left", Type: definition.NodeSkill, SkillCardID: left},
+			{ID: "right", Type: definition.NodeSkill, SkillCardID: right},
+		},
+	}
+	_, _, err := e.Start(context.Background(), newRunningTask(), def, nil)
+	require.NoError(t, err)
+
+	// Both nodes run while the left one's start is still being recorded
+	require.Eventually(t, func() bool {
+		return activities.callCount(left) == 1 && activities.callCount(right) == 1
+	}, time.Second, time.Millisecond)
+	assert.Empty(t, activities.stepStatus("left"))
+
+	close(release)
+	assert.Equal(t, string(task.StatusCompleted), waitForEnd(t, activities).Status)
+	assert.Equal(t, task.StepCompleted, activities.stepStatus("left"), "the outcome is recorded after the start")
+}
+
+func TestWorkflowEngine_HotspotsNode(t *testing.T) {
+	suggest := uuid.NewString()
+	activities := newFakeActivities()
+	e := newTestEngine(activities, nil)
+	defer e.Stop()
+
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "analyze", Type: definition.NodeHotspots},
+			{ID: "suggest", Type: definition.NodeSkill, SkillCardID: suggest, Inputs: map[string]interface{}{"product": "$.nodes.analyze.output.summary"}},
+		},
+		Edges: []definition.Edge{{From: "analyze", To: "suggest"}},
+	}
+	require.NoError(t, def.Validate())
+	tk := newRunningTask()
+	tk.InputData = map[string]interface{}{"platform": "weibo", "category": "tech"}
+	_, _, err := e.Start(context.Background(), tk, def, nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, string(task.StatusCompleted), waitForEnd(t, activities).Status)
+	require.Len(t, activities.analyzed, 1)
+	assert.Equal(t, temporal.HotspotAnalysisInput{CompanyID: tk.CompanyID.String(), Platform: "weibo", Category: "tech"}, activities.analyzed[0])
+	assert.Equal(t, "AI Agents", activities.calls[suggest][0]["product"])
+	assert.Equal(t, task.StepCompleted, activities.stepStatus("analyze"))
+}
+
+func TestWorkflowEngine_Recover(t *testing.T) {
+	draft, publish := uuid.NewString(), uuid.NewString()
+	activities := newFakeActivities()
+
+	tk := newRunningTask()
+	tk.WorkflowDefinition = draftAndPublish(draft, publish).ToMap()
+	tk.TemporalWorkflowID = "task-" + tk.ID.String()
+	tk.TemporalRunID = RunIDPrefix + "interrupted"
+
+	// The process stopped while publish was in flight
+	done := task.NewStep(tk.ID, 1, 1, "draft", "draft")
+	done.Status = task.StepCompleted
+	done.Output = map[string]interface{}{"content": "hello"}
+	inFlight := task.NewStep(tk.ID, 1, 2, "publish", "publish")
+	inFlight.Status = task.StepRunning
+	inFlight.Input = map[string]interface{}{"text": "hello", "channel": "edited"}
+	activities.steps["draft"] = done
+	activities.steps["publish"] = inFlight
+
+	e := newTestEngine(activities, &fakeTasks{active: []*task.Task{tk}})
+	defer e.Stop()
+
+	recovered, err := e.Recover(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, recovered)
+
+	assert.Equal(t, string(task.StatusCompleted), waitForEnd(t, activities).Status)
+	assert.Zero(t, activities.callCount(draft))
+	require.Equal(t, 1, activities.callCount(publish))
+	assert.Equal(t, "edited", activities.calls[publish][0]["channel"])
+}
+
+func TestWorkflowEngine_StopLeavesRunsForRecovery(t *testing.T) {
+	draft, publish := uuid.NewString(), uuid.NewString()
+	activities := newFakeActivities()
+	activities.block(draft)
+	e := newTestEngine(activities, nil)
+
+	_, _, err := e.Start(context.Background(), newRunningTask(), draftAndPublish(draft, publish), nil)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return activities.callCount(draft) == 1 }, time.Second, time.Millisecond)
+
+	e.Stop()
+
+	_, ended := activities.finalStatus()
+	assert.False(t, ended, "an interrupted run does not end its task")
+	assert.Equal(t, task.StepRunning, activities.stepStatus("draft"))
+}
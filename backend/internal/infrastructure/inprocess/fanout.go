@@ -0,0 +1,126 @@
+package inprocess
+
+import (
+	"context"
+	"fmt"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+)
+
+// executeMap fans a map node out over its items: each item runs the node's
+// skill card, or its workflow as a child run, with at most the node's
+// concurrency in flight. No item starts while the run is paused. Once enough
+// items failed that the node cannot succeed, the items in flight are
+// cancelled. It returns the reduced output.
+func (r *run) executeMap(node *definition.Node, params map[string]interface{}, items []interface{}) (map[string]interface{}, error) {
+	spec := node.Map
+	ctx, cancelItems := context.WithCancel(r.ctx)
+	defer cancelItems()
+
+	results := make([]definition.MapItemResult, len(items))
+	for i := range results {
+		results[i].Index = i
+	}
+	counts := task.ItemProgress{Total: len(items)}
+	finished := make(chan definition.MapItemResult)
+	next, running := 0, 0
+	r.itemProgress(node.ID, counts)
+
+	for {
+		for running < spec.Limit() && next < len(items) && !spec.Hopeless(len(items), counts.Failed) {
+			if err := r.wait(); err != nil {
+				break
+			}
+			i := next
+			next++
+			running++
+			go func(i int) {
+				output, err := r.executeItem(ctx, node, definition.ItemInput(params, items[i], i))
+				result := definition.MapItemResult{Index: i, Output: output, Done: true}
+				if err != nil {
+					result.Output, result.Error = nil, err.Error()
+				}
+				finished <- result
+			}(i)
+		}
+		if running == 0 {
+			break
+		}
+		result := <-finished
+		running--
+		results[result.Index] = result
+		if result.Succeeded() {
+			counts.Succeeded++
+		} else {
+			counts.Failed++
+		}
+		r.itemProgress(node.ID, counts)
+		if spec.Hopeless(len(items), counts.Failed) {
+			cancelItems()
+		}
+	}
+	if r.ctx.Err() != nil {
+		return nil, errCancelled
+	}
+
+	var judgement map[string]interface{}
+	if spec.NeedsJudge() && counts.Succeeded > 0 && counts.Succeeded >= spec.Required(len(items)) {
+		judged, err := r.executeSkill(r.ctx, node, spec.Reduce.JudgeSkillCardID, spec.JudgeInput(results))
+		if err != nil {
+			return nil, fmt.Errorf("judge failed: %w", err)
+		}
+		judgement = judged
+	}
+	return spec.Combine(results, judgement)
+}
+
+// executeItem runs one item of a map node: its skill card, or its workflow
+// as a child run
+func (r *run) executeItem(ctx context.Context, node *definition.Node, input map[string]interface{}) (map[string]interface{}, error) {
+	if node.Map.Workflow == nil {
+		return r.executeSkill(ctx, node, node.SkillCardID, input)
+	}
+
+	def := *node.Map.Workflow
+	if def.Version == 0 {
+		def.Version = r.def.Version
+	}
+	child := &run{
+		engine:     r.engine,
+		parent:     r,
+		taskID:     r.taskID,
+		companyID:  r.companyID,
+		employeeID: r.nodeEmployee(node),
+		workflowID: r.workflowID,
+		runID:      r.runID,
+		attempt:    r.attempt,
+		def:        &def,
+		input:      input,
+		ctx:        ctx,
+		approvals:  make(map[string]chan task.ApprovalDecision),
+		done:       make(chan struct{}),
+	}
+	child.execute()
+	return child.output, child.err
+}
+
+// itemProgress shows the item counts of a running map step on the pipeline
+// and reports the progress they make
+func (r *run) itemProgress(nodeID string, items task.ItemProgress) {
+	if r.parent != nil {
+		return
+	}
+	r.mu.Lock()
+	for i := range r.pipeline.Steps {
+		if r.pipeline.Steps[i].ID == nodeID {
+			r.pipeline.Steps[i].Items = &items
+		}
+	}
+	r.pipeline.UpdatePercent()
+	r.mu.Unlock()
+
+	if r.ctx.Err() == nil {
+		r.report()
+	}
+}
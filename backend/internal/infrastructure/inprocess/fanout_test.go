@@ -0,0 +1,196 @@
+package inprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/temporal"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// itemActivities writes "<item>!" for every item, fails on the item "bad",
+// judges by picking the candidate at index 1, and tracks how many items run
+// at once
+type itemActivities struct {
+	*fakeActivities
+	judge   string
+	running int
+	peak    int
+}
+
+func (a *itemActivities) ExecuteSkillActivity(ctx context.Context, input temporal.SkillExecutionInput) (*temporal.SkillExecutionResult, error) {
+	a.mu.Lock()
+	a.calls[input.SkillCardID] = append(a.calls[input.SkillCardID], input.Parameters)
+	if input.SkillCardID == a.judge {
+		a.mu.Unlock()
+		return &temporal.SkillExecutionResult{Success: true, Output: map[string]interface{}{"best": 1}}, nil
+	}
+	a.running++
+	if a.running > a.peak {
+		a.peak = a.running
+	}
+	a.mu.Unlock()
+	defer func() {
+		a.mu.Lock()
+		a.running--
+		a.mu.Unlock()
+	}()
+
+	select {
+	case <-time.After(5 * time.Millisecond):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	item := input.Parameters["item"]
+	if text, ok := input.Parameters["text"]; ok {
+		item = text
+	}
+	if item == "bad" {
+		return nil, errors.New("model refused")
+	}
+	return &temporal.SkillExecutionResult{Success: true, Output: map[string]interface{}{"text": fmt.Sprintf("%v!", item)}}, nil
+}
+
+func startMap(t *testing.T, activities *itemActivities, def *definition.Definition, input map[string]interface{}) (*WorkflowEngine, temporal.TaskStatusInput) {
+	t.Helper()
+	require.NoError(t, def.Validate())
+	e := NewWorkflowEngine(activities, &fakeTasks{}, activities, nil)
+	e.SetRetryPolicy(RetryPolicy{InitialInterval: time.Millisecond, MaximumInterval: 5 * time.Millisecond, BookkeepingAttempts: 2})
+
+	tk := newRunningTask()
+	tk.InputData = input
+	_, _, err := e.Start(context.Background(), tk, def, nil)
+	require.NoError(t, err)
+	return e, waitForEnd(t, activities.fakeActivities)
+}
+
+func TestWorkflowEngine_MapRanksVariants(t *testing.T) {
+	caption, judge, publish := uuid.NewString(), uuid.NewString(), uuid.NewString()
+	activities := &itemActivities{fakeActivities: newFakeActivities(), judge: judge}
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "captions", Type: definition.NodeMap, SkillCardID: caption, MaxAttempts: 1,
+				Inputs: map[string]interface{}{"tone": "$.input.tone"},
+				Map: &definition.MapSpec{
+					Items:        "$.input.products",
+					Concurrency:  2,
+					Require:      definition.MapRequireCount,
+					RequireCount: 3,
+					Reduce:       &definition.MapReduce{Strategy: definition.MapReduceRank, JudgeSkillCardID: judge},
+				}},
+			{ID: "publish", Type: definition.NodeSkill, SkillCardID: publish, MaxAttempts: 1,
+				Inputs: map[string]interface{}{"text": "$.nodes.captions.output.result.text"}},
+		},
+		Edges: []definition.Edge{{From: "captions", To: "publish"}},
+	}
+
+	e, final := startMap(t, activities, def, map[string]interface{}{
+		"products": []interface{}{"mug", "bad", "tee", "cap", "pen"},
+		"tone":     "fun",
+	})
+	defer e.Stop()
+
+	assert.Equal(t, string(task.StatusCompleted), final.Status)
+	assert.Equal(t, "tee!!", final.Output["text"], "publish got the variant the judge picked")
+	assert.Equal(t, 5, activities.callCount(caption))
+	assert.Equal(t, 2, activities.peak, "no more items in flight than the concurrency")
+	assert.Equal(t, "fun", activities.calls[caption][0]["tone"])
+	assert.Len(t, activities.calls[judge][0]["candidates"], 4)
+	assert.Equal(t, task.StepCompleted, activities.stepStatus("captions"))
+
+	// Items report progress while the map step runs
+	var reported []int
+	activities.mu.Lock()
+	for _, s := range activities.statuses {
+		reported = append(reported, s.Progress)
+	}
+	activities.mu.Unlock()
+	require.Greater(t, len(reported), 5)
+	assert.Equal(t, []int{10, 20, 30, 40, 50}, reported[:5], "each of five items is a tenth of two steps")
+}
+
+func TestWorkflowEngine_MapChildWorkflows(t *testing.T) {
+	draft, polish := uuid.NewString(), uuid.NewString()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "posts", Type: definition.NodeMap, MaxAttempts: 1, Map: &definition.MapSpec{
+				Items:   "$.input.topics",
+				Require: definition.MapRequireAny,
+				Workflow: &definition.Definition{
+					Nodes: []definition.Node{
+						{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft, MaxAttempts: 1},
+						{ID: "polish", Type: definition.NodeSkill, SkillCardID: polish, MaxAttempts: 1,
+							Inputs: map[string]interface{}{"text": "$.nodes.draft.output.text"}},
+					},
+					Edges: []definition.Edge{{From: "draft", To: "polish"}},
+				},
+			}},
+		},
+	}
+
+	t.Run("tolerates failed items", func(t *testing.T) {
+		activities := &itemActivities{fakeActivities: newFakeActivities()}
+		e, final := startMap(t, activities, def, map[string]interface{}{"topics": []interface{}{"go", "bad"}})
+		defer e.Stop()
+
+		assert.Equal(t, string(task.StatusCompleted), final.Status)
+		assert.Equal(t, []interface{}{map[string]interface{}{"text": "go!!"}}, final.Output["results"])
+		assert.Equal(t, []interface{}{map[string]interface{}{"index": 1, "error": "node draft failed: model refused"}}, final.Output["errors"])
+		assert.Len(t, activities.steps, 1, "child runs leave the steps to the parent")
+	})
+
+	t.Run("fails when no item succeeds", func(t *testing.T) {
+		activities := &itemActivities{fakeActivities: newFakeActivities()}
+		e, final := startMap(t, activities, def, map[string]interface{}{"topics": []interface{}{"bad"}})
+		defer e.Stop()
+
+		assert.Equal(t, string(task.StatusFailed), final.Status)
+		assert.Equal(t, "node posts failed: 0 of 1 items succeeded, 1 required; item 0: node draft failed: model refused", final.Error)
+	})
+}
+
+func TestWorkflowEngine_MapStopsWhenHopeless(t *testing.T) {
+	caption := uuid.NewString()
+	activities := &itemActivities{fakeActivities: newFakeActivities()}
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "captions", Type: definition.NodeMap, SkillCardID: caption, MaxAttempts: 1,
+				Map: &definition.MapSpec{Items: "$.input.products", Concurrency: 1}},
+		},
+	}
+
+	e, final := startMap(t, activities, def, map[string]interface{}{"products": []interface{}{"bad", "mug", "tee"}})
+	defer e.Stop()
+
+	assert.Equal(t, string(task.StatusFailed), final.Status)
+	assert.Contains(t, final.Error, "item 0: model refused")
+	assert.Equal(t, 1, activities.callCount(caption), "no item starts once the map cannot succeed")
+}
+
+func TestWorkflowEngine_MapItemsMustBeAList(t *testing.T) {
+	activities := &itemActivities{fakeActivities: newFakeActivities()}
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "captions", Type: definition.NodeMap, SkillCardID: uuid.NewString(),
+				Map: &definition.MapSpec{Items: "$.input.products"}},
+		},
+	}
+
+	e, final := startMap(t, activities, def, map[string]interface{}{"products": "mug"})
+	defer e.Stop()
+
+	assert.Equal(t, string(task.StatusFailed), final.Status)
+	assert.Contains(t, final.Error, "is not a list")
+}
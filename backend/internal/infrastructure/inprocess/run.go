@@ -0,0 +1,701 @@
+package inprocess
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/temporal"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Node defaults, the same as the Temporal engine's
+const (
+	defaultNodeTimeout     = 5 * time.Minute
+	defaultNodeMaxAttempts = 3
+	defaultApprovalTimeout = 24 * time.Hour
+)
+
+// bookkeepingTimeout bounds one call recording a step, approval or task status
+const bookkeepingTimeout = time.Minute
+
+// errCancelled is the error of steps interrupted by cancelling their run
+var errCancelled = errors.New("workflow cancelled")
+
+// run is one run of a task's workflow. Only its execute goroutine touches
+// the definition.Run; the mutex guards what the engine's callers reach.
+type run struct {
+	engine *WorkflowEngine
+	// parent is set on the run of a map node's workflow for one item, which
+	// leaves the task and its steps to the parent and keeps its outcome
+	parent *run
+	output map[string]interface{}
+	err    error
+
+	taskID     uuid.UUID
+	companyID  string
+	employeeID string
+	workflowID string
+	runID      string
+	attempt    int
+
+	def        *definition.Definition
+	input      map[string]interface{}
+	reused     map[string]map[string]interface{}
+	stepInputs map[string]map[string]interface{}
+	// compensations undo the completed nodes when the run fails or is cancelled
+	compensations []compensation
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu        sync.Mutex
+	paused    bool
+	resumed   chan struct{}
+	cancelled bool
+	approvals map[string]chan task.ApprovalDecision
+	pipeline  task.Pipeline
+	reported  int
+	// undone are how the nodes undone as they failed ended, such as a
+	// partial publication taken down again
+	undone []definition.CompensationResult
+}
+
+// nodeResult is how the execution of a node ended
+type nodeResult struct {
+	node      *definition.Node
+	startedAt time.Time
+	output    map[string]interface{}
+	err       error
+	// started is closed once the node's start is recorded
+	started <-chan struct{}
+}
+
+// execute runs every node whose predecessors are done, in parallel, until
+// the graph is exhausted, a node fails or the run is cancelled. A failed or
+// cancelled run compensates its completed nodes; an interrupted one does not,
+// since it runs again once recovered.
+func (r *run) execute() {
+	defer close(r.done)
+	if r.parent == nil {
+		logger.Info(fmt.Sprintf("Starting in-process workflow of task %s (attempt %d)", r.taskID, r.attempt))
+	}
+
+	run := definition.NewRun(r.def, r.input)
+	results := make(chan nodeResult)
+	running := 0
+
+	for {
+		// While paused no node starts; waiting fails once the run is cancelled
+		if err := r.wait(); err != nil {
+			break
+		}
+		// Reused nodes complete at once, which can make their successors ready
+		for ready := run.Next(); len(ready) > 0; ready = run.Next() {
+			for _, node := range ready {
+				if output, ok := r.reused[node.ID]; ok {
+					run.Complete(node.ID, output)
+					r.record(node.ID, temporal.StepRecordInput{Status: task.StepCompleted, Output: output, Reused: true})
+					r.addCompensation(run, node)
+					continue
+				}
+
+				params := run.Inputs(node)
+				for k, v := range r.stepInputs[node.ID] {
+					params[k] = v
+				}
+				started := r.recordStart(node.ID, temporal.StepRecordInput{
+					Status:      task.StepRunning,
+					SkillCardID: node.SkillCardID,
+					EmployeeID:  r.nodeEmployee(node),
+					Input:       params,
+				})
+				running++
+
+				var items []interface{}
+				var itemsErr error
+				if node.Type == definition.NodeMap {
+					items, itemsErr = run.MapItems(node)
+				}
+				go func(node *definition.Node) {
+					startedAt := time.Now()
+					result := nodeResult{node: node, startedAt: startedAt, err: itemsErr, started: started}
+					if itemsErr == nil {
+						result.output, result.err = r.executeNode(node, params, items)
+					}
+					results <- result
+				}(node)
+			}
+		}
+		for _, node := range r.def.Nodes {
+			if run.Status(node.ID) == definition.NodeSkipped {
+				r.record(node.ID, temporal.StepRecordInput{Status: task.StepSkipped})
+			}
+		}
+
+		if running == 0 {
+			break
+		}
+		r.settle(run, <-results)
+		running--
+		if !run.Failed() && r.ctx.Err() == nil {
+			r.report()
+		}
+	}
+
+	// Steps still in flight were interrupted; wait for them to return
+	for ; running > 0; running-- {
+		r.settle(run, <-results)
+	}
+
+	var outcomes []definition.CompensationResult
+	if !r.interrupted() && (r.ctx.Err() != nil || run.Failed()) {
+		outcomes = r.compensate()
+	}
+	compensated := definition.DescribeCompensations(outcomes)
+	compensation := r.compensation(outcomes)
+
+	if r.parent != nil {
+		r.output, r.err = r.childOutcome(run, compensated)
+		return
+	}
+	switch {
+	case r.interrupted():
+		logger.Info(fmt.Sprintf("In-process workflow of task %s interrupted by shutdown", r.taskID))
+	case r.ctx.Err() != nil:
+		r.finish(task.StatusCancelled, nil, "", compensation)
+		logger.Info(fmt.Sprintf("In-process workflow of task %s cancelled", r.taskID))
+	case run.Failed():
+		r.finish(task.StatusFailed, nil, withCompensations(failure(r.def, run), compensated), compensation)
+	default:
+		r.finish(task.StatusCompleted, run.Output(), "", nil)
+		logger.Info(fmt.Sprintf("In-process workflow of task %s completed", r.taskID))
+	}
+}
+
+// childOutcome returns the output of the run of a map node's workflow, or
+// why it did not complete along with how its compensations ended
+func (r *run) childOutcome(run *definition.Run, compensated string) (map[string]interface{}, error) {
+	switch {
+	case r.ctx.Err() != nil:
+		return nil, errCancelled
+	case run.Failed():
+		return nil, errors.New(withCompensations(failure(r.def, run), compensated))
+	}
+	return run.Output(), nil
+}
+
+// failure describes the first node of a run that failed
+func failure(def *definition.Definition, run *definition.Run) string {
+	for _, node := range def.Nodes {
+		if run.Status(node.ID) == definition.NodeFailed {
+			return fmt.Sprintf("node %s failed: %s", node.ID, run.Error(node.ID))
+		}
+	}
+	return ""
+}
+
+// settle records how a node ended, after its start. Steps of an interrupted
+// run are left running, to run again once the run is recovered.
+func (r *run) settle(run *definition.Run, result nodeResult) {
+	<-result.started
+	if result.err == nil {
+		run.Complete(result.node.ID, result.output)
+		r.record(result.node.ID, temporal.StepRecordInput{Status: task.StepCompleted, Output: result.output})
+		r.addCompensation(run, result.node)
+		return
+	}
+
+	run.Fail(result.node.ID, result.err.Error())
+	switch {
+	case r.interrupted():
+	case r.ctx.Err() != nil:
+		r.record(result.node.ID, temporal.StepRecordInput{Status: task.StepCancelled, Error: errCancelled.Error()})
+	default:
+		r.record(result.node.ID, temporal.StepRecordInput{Status: task.StepFailed, Error: result.err.Error()})
+	}
+}
+
+// executeNode runs a skill node, fans a map node out over its items, asks
+// for the approval of an approval node's input or publishes a publish
+// node's content, and returns its output
+func (r *run) executeNode(node *definition.Node, params map[string]interface{}, items []interface{}) (map[string]interface{}, error) {
+	switch node.Type {
+	case definition.NodeApproval:
+		return r.approve(node, params)
+	case definition.NodeMap:
+		return r.executeMap(node, params, items)
+	case definition.NodePublish:
+		return r.publish(node, params)
+	case definition.NodeHotspots:
+		return r.analyzeHotspots(node, params)
+	}
+	return r.executeSkill(r.ctx, node, node.SkillCardID, params)
+}
+
+// executeSkill runs a skill card for a node with the node's timeout and
+// attempts, and returns its output
+func (r *run) executeSkill(ctx context.Context, node *definition.Node, skillCardID string, params map[string]interface{}) (map[string]interface{}, error) {
+	var result *temporal.SkillExecutionResult
+	err := r.withNodeRetries(ctx, node, func(ctx context.Context) error {
+		var err error
+		result, err = r.engine.activities.ExecuteSkillActivity(ctx, temporal.SkillExecutionInput{
+			TaskID:      r.taskID.String(),
+			SkillCardID: skillCardID,
+			EmployeeID:  r.nodeEmployee(node),
+			Parameters:  params,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("skill failed: %s", result.Error)
+	}
+	return result.Output, nil
+}
+
+// analyzeHotspots analyzes the hotspots of a node's platform and category
+// with the node's timeout and attempts, and returns the analysis
+func (r *run) analyzeHotspots(node *definition.Node, params map[string]interface{}) (map[string]interface{}, error) {
+	platform, _ := params["platform"].(string)
+	category, _ := params["category"].(string)
+	var result *temporal.SkillExecutionResult
+	err := r.withNodeRetries(r.ctx, node, func(ctx context.Context) error {
+		var err error
+		result, err = r.engine.activities.AnalyzeHotspotsActivity(ctx, temporal.HotspotAnalysisInput{
+			CompanyID: r.companyID,
+			Platform:  platform,
+			Category:  category,
+		})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.Output, nil
+}
+
+// publish publishes a publish node's content with the node's timeout and
+// attempts. Content that went out on only some platforms is taken down
+// again, recorded with the compensations, and fails the node. The output is
+// the publication.
+func (r *run) publish(node *definition.Node, params map[string]interface{}) (map[string]interface{}, error) {
+	input := temporal.NewPublishInput(r.companyID, params)
+	var publication map[string]interface{}
+	err := r.withNodeRetries(r.ctx, node, func(ctx context.Context) error {
+		var err error
+		publication, err = r.engine.activities.PublishContentActivity(ctx, input)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	published, err := temporal.PublishOutcome(publication, input.Platforms)
+	if err != nil && len(published) > 0 {
+		undone := r.unpublish(context.WithoutCancel(r.ctx), published, publication)
+		r.undid(node.ID, undone)
+		return nil, fmt.Errorf("%v; %s", err, temporal.DescribeUnpublish(undone, published))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return publication, nil
+}
+
+// unpublish takes a publication down from the platforms it went out on
+func (r *run) unpublish(ctx context.Context, platforms []string, publication map[string]interface{}) error {
+	return r.engine.bookkeep(ctx, func(ctx context.Context) error {
+		return r.engine.activities.UnpublishContentActivity(ctx, temporal.UnpublishInput{
+			CompanyID:   r.companyID,
+			Platforms:   platforms,
+			Publication: publication,
+		})
+	})
+}
+
+// withNodeRetries calls fn with the node's timeout until it succeeds or the
+// node's attempts run out
+func (r *run) withNodeRetries(ctx context.Context, node *definition.Node, fn func(ctx context.Context) error) error {
+	timeout := defaultNodeTimeout
+	if node.TimeoutSeconds > 0 {
+		timeout = time.Duration(node.TimeoutSeconds) * time.Second
+	}
+	attempts := defaultNodeMaxAttempts
+	if node.MaxAttempts > 0 {
+		attempts = node.MaxAttempts
+	}
+	return r.engine.withRetries(ctx, attempts, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return fn(ctx)
+	})
+}
+
+// approve records a pending approval of the node's content and waits for a
+// reviewer, or for the timeout to apply the node's default action. The
+// approved content is the output; a rejection fails the node.
+func (r *run) approve(node *definition.Node, content map[string]interface{}) (map[string]interface{}, error) {
+	timeout := defaultApprovalTimeout
+	if node.TimeoutSeconds > 0 {
+		timeout = time.Duration(node.TimeoutSeconds) * time.Second
+	}
+	defaultAction := node.DefaultAction
+	if defaultAction == "" {
+		defaultAction = definition.ApprovalDefaultReject
+	}
+
+	decisions := make(chan task.ApprovalDecision, 1)
+	r.mu.Lock()
+	r.approvals[node.ID] = decisions
+	r.mu.Unlock()
+	defer func() {
+		r.mu.Lock()
+		delete(r.approvals, node.ID)
+		r.mu.Unlock()
+	}()
+
+	err := r.engine.bookkeep(r.ctx, func(ctx context.Context) error {
+		return r.engine.activities.RequestApprovalActivity(ctx, temporal.ApprovalRequestInput{
+			TaskID:        r.taskID.String(),
+			CompanyID:     r.companyID,
+			NodeID:        node.ID,
+			NodeName:      node.DisplayName(),
+			Content:       content,
+			DefaultAction: defaultAction,
+			ExpiresAt:     time.Now().Add(timeout),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	decision := temporal.ApprovalDecision{NodeID: node.ID}
+	timedOut := false
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case d := <-decisions:
+		decision.Action = string(d.Action)
+		decision.Content = d.Content
+		decision.DecidedBy = d.DecidedBy.String()
+		decision.Comment = d.Comment
+	case <-timer.C:
+		timedOut = true
+		decision.Action = defaultAction
+	case <-r.ctx.Done():
+		return nil, errCancelled
+	}
+
+	err = r.engine.bookkeep(r.ctx, func(ctx context.Context) error {
+		return r.engine.activities.RecordApprovalActivity(ctx, temporal.ApprovalDecisionInput{
+			TaskID:   r.taskID.String(),
+			Decision: decision,
+			TimedOut: timedOut,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	switch task.ApprovalAction(decision.Action) {
+	case task.ApprovalReject:
+		return nil, errors.New(rejectionMessage(decision, timedOut))
+	case task.ApprovalEdit:
+		return task.ApprovedContent(content, decision.Content), nil
+	}
+	return task.ApprovedContent(content, nil), nil
+}
+
+// rejectionMessage describes why an approval failed its step
+func rejectionMessage(decision temporal.ApprovalDecision, timedOut bool) string {
+	message := "rejected by reviewer"
+	if timedOut {
+		message = "rejected after the approval timed out"
+	}
+	if decision.Comment != "" {
+		message = fmt.Sprintf("%s: %s", message, decision.Comment)
+	}
+	return message
+}
+
+// nodeEmployee returns the employee a node runs as: its own or the task's
+func (r *run) nodeEmployee(node *definition.Node) string {
+	if node.EmployeeID != "" {
+		return node.EmployeeID
+	}
+	return r.employeeID
+}
+
+// record checkpoints a step of the run and updates the live pipeline. A
+// step already in the given status is left alone. A failed checkpoint does
+// not fail the run.
+func (r *run) record(nodeID string, input temporal.StepRecordInput) {
+	if input, ok := r.track(nodeID, input); ok {
+		r.save(input)
+	}
+}
+
+// recordStart records a step as running like record, but checkpoints it in
+// the background so that steps ready together start together. The returned
+// channel is closed once the checkpoint is done.
+func (r *run) recordStart(nodeID string, input temporal.StepRecordInput) <-chan struct{} {
+	saved := make(chan struct{})
+	input, ok := r.track(nodeID, input)
+	if !ok {
+		close(saved)
+		return saved
+	}
+	go func() {
+		defer close(saved)
+		r.save(input)
+	}()
+	return saved
+}
+
+// track updates a step in the live pipeline and returns the checkpoint to
+// record, unless the step is already in the given status
+func (r *run) track(nodeID string, input temporal.StepRecordInput) (temporal.StepRecordInput, bool) {
+	if r.parent != nil {
+		return input, false
+	}
+	r.mu.Lock()
+	var step *task.PipelineStep
+	for i := range r.pipeline.Steps {
+		if r.pipeline.Steps[i].ID == nodeID {
+			step = &r.pipeline.Steps[i]
+			input.Order = i + 1
+		}
+	}
+	if step == nil || step.Status == input.Status {
+		r.mu.Unlock()
+		return input, false
+	}
+	now := time.Now()
+	step.Status = input.Status
+	step.Error = input.Error
+	switch {
+	case input.Status == task.StepRunning:
+		step.StartedAt = &now
+	case step.IsFinished() && input.Status != task.StepSkipped:
+		step.CompletedAt = &now
+	}
+	r.pipeline.UpdatePercent()
+	input.Name = step.Name
+	r.mu.Unlock()
+
+	input.TaskID = r.taskID.String()
+	input.Attempt = r.attempt
+	input.NodeID = nodeID
+	input.At = now
+	return input, true
+}
+
+// save checkpoints a step. Outcomes of a cancelled run are still recorded.
+func (r *run) save(input temporal.StepRecordInput) {
+	err := r.engine.bookkeep(context.WithoutCancel(r.ctx), func(ctx context.Context) error {
+		return r.engine.activities.RecordStepActivity(ctx, input)
+	})
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to record step %s of task %s: %v", input.NodeID, r.taskID, err))
+	}
+}
+
+// report records the percent complete on the task unless it did not change
+// since the last report. A failed report does not fail the run.
+func (r *run) report() {
+	if r.parent != nil {
+		return
+	}
+	r.mu.Lock()
+	percent := r.pipeline.Percent
+	if percent == r.reported {
+		r.mu.Unlock()
+		return
+	}
+	r.reported = percent
+	r.mu.Unlock()
+
+	err := r.engine.bookkeep(r.ctx, func(ctx context.Context) error {
+		return r.engine.activities.UpdateTaskStatusActivity(ctx, temporal.TaskStatusInput{
+			TaskID:   r.taskID.String(),
+			Progress: percent,
+		})
+	})
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to report progress of task %s: %v", r.taskID, err))
+	}
+}
+
+// finish records how the run ended on the task, along with how its
+// compensations ended
+func (r *run) finish(status task.TaskStatus, output map[string]interface{}, message string, compensation *task.Compensation) {
+	err := r.engine.bookkeep(context.WithoutCancel(r.ctx), func(ctx context.Context) error {
+		return r.engine.activities.UpdateTaskStatusActivity(ctx, temporal.TaskStatusInput{
+			TaskID:       r.taskID.String(),
+			Status:       string(status),
+			Progress:     100,
+			Output:       output,
+			Error:        message,
+			Compensation: compensation,
+		})
+	})
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to update status of task %s: %v", r.taskID, err))
+	}
+}
+
+// wait blocks while the task's run is paused. It fails when the run is
+// cancelled or interrupted.
+func (r *run) wait() error {
+	root := r.root()
+	for {
+		root.mu.Lock()
+		paused, resumed := root.paused, root.resumed
+		root.mu.Unlock()
+		if err := r.ctx.Err(); err != nil {
+			return err
+		}
+		if !paused {
+			return nil
+		}
+		select {
+		case <-resumed:
+		case <-r.ctx.Done():
+		}
+	}
+}
+
+// pause makes the run stop before its next step; a step already running is
+// never interrupted
+func (r *run) pause() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.paused {
+		r.paused = true
+		r.resumed = make(chan struct{})
+	}
+}
+
+// resume lets a paused run continue
+func (r *run) resume() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.paused {
+		r.paused = false
+		close(r.resumed)
+	}
+}
+
+// cancelByUser cancels the run, which ends its task as cancelled
+func (r *run) cancelByUser() {
+	r.mu.Lock()
+	r.cancelled = true
+	r.mu.Unlock()
+	r.cancel()
+}
+
+// interrupted reports whether the run was stopped by the engine shutting
+// down rather than cancelled
+func (r *run) interrupted() bool {
+	root := r.root()
+	root.mu.Lock()
+	defer root.mu.Unlock()
+	return root.ctx.Err() != nil && !root.cancelled
+}
+
+// root returns the run of the task's workflow a child run belongs to
+func (r *run) root() *run {
+	for r.parent != nil {
+		r = r.parent
+	}
+	return r
+}
+
+// decide hands a decision to the approval of its node, reporting whether
+// the node was waiting for one
+func (r *run) decide(d task.ApprovalDecision) bool {
+	r.mu.Lock()
+	decisions, ok := r.approvals[d.NodeID]
+	r.mu.Unlock()
+	if !ok {
+		return false
+	}
+	select {
+	case decisions <- d:
+		return true
+	default:
+		return false
+	}
+}
+
+// snapshot returns a copy of the live pipeline
+func (r *run) snapshot() *task.Pipeline {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	snapshot := r.pipeline
+	snapshot.Steps = append([]task.PipelineStep(nil), r.pipeline.Steps...)
+	snapshot.Paused = r.paused
+	snapshot.CurrentStep = ""
+	for _, s := range snapshot.Steps {
+		if s.Status == task.StepRunning {
+			snapshot.CurrentStep = s.ID
+			break
+		}
+	}
+	return &snapshot
+}
+
+// withRetries calls fn until it succeeds, fails with a non-retryable error,
+// the context ends or attempts run out, backing off exponentially in between
+func (e *WorkflowEngine) withRetries(ctx context.Context, attempts int, fn func(ctx context.Context) error) error {
+	interval := e.retry.InitialInterval
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return errCancelled
+		}
+		if attempt >= attempts || !retryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return errCancelled
+		}
+		interval *= 2
+		if interval > e.retry.MaximumInterval {
+			interval = e.retry.MaximumInterval
+		}
+	}
+}
+
+// bookkeep records something of a run, retrying with the bookkeeping policy
+func (e *WorkflowEngine) bookkeep(ctx context.Context, fn func(ctx context.Context) error) error {
+	return e.withRetries(ctx, e.retry.BookkeepingAttempts, func(ctx context.Context) error {
+		ctx, cancel := context.WithTimeout(ctx, bookkeepingTimeout)
+		defer cancel()
+		return fn(ctx)
+	})
+}
+
+// retryable reports whether an error may go away on retry. Activities mark
+// the errors that will not as non-retryable.
+func retryable(err error) bool {
+	var nonRetryable interface{ NonRetryable() bool }
+	return !errors.As(err, &nonRetryable) || !nonRetryable.NonRetryable()
+}
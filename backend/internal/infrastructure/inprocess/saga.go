@@ -0,0 +1,96 @@
+package inprocess
+
+import (
+	"context"
+	"fmt"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/temporal"
+	"unlimited-corp/pkg/logger"
+)
+
+// compensation undoes a completed node
+type compensation struct {
+	node *definition.Node
+	undo func(ctx context.Context) error
+}
+
+// addCompensation registers the compensation of a completed node, with
+// inputs resolved from its output. A publish node is undone by taking its
+// publication down.
+func (r *run) addCompensation(run *definition.Run, node *definition.Node) {
+	switch {
+	case node.Type == definition.NodePublish:
+		publication := run.NodeOutput(node.ID)
+		published, _ := temporal.PublishOutcome(publication, nil)
+		if len(published) == 0 {
+			return
+		}
+		r.compensations = append(r.compensations, compensation{node: node, undo: func(ctx context.Context) error {
+			return r.unpublish(ctx, published, publication)
+		}})
+	case node.Compensate != nil:
+		params := run.CompensationInputs(node)
+		r.compensations = append(r.compensations, compensation{node: node, undo: func(ctx context.Context) error {
+			_, err := r.executeSkill(ctx, node, node.Compensate.SkillCardID, params)
+			return err
+		}})
+	}
+}
+
+// undid records how undoing a node as it failed ended
+func (r *run) undid(nodeID string, err error) {
+	outcome := definition.CompensationResult{NodeID: nodeID}
+	if err != nil {
+		outcome.Error = err.Error()
+	}
+	r.mu.Lock()
+	r.undone = append(r.undone, outcome)
+	r.mu.Unlock()
+}
+
+// compensate undoes the completed nodes one at a time, the last completed
+// first, even once the run is cancelled. A failed compensation does not stop
+// the others. Each outcome is recorded on its step. It returns the outcomes,
+// none when there was nothing to undo.
+func (r *run) compensate() []definition.CompensationResult {
+	if len(r.compensations) == 0 {
+		return nil
+	}
+	ctx := context.WithoutCancel(r.ctx)
+	outcomes := make([]definition.CompensationResult, 0, len(r.compensations))
+	for i := len(r.compensations) - 1; i >= 0; i-- {
+		c := r.compensations[i]
+		outcome := definition.CompensationResult{NodeID: c.node.ID}
+		if err := c.undo(ctx); err != nil {
+			outcome.Error = err.Error()
+			logger.Warn(fmt.Sprintf("Failed to compensate step %s of task %s: %v", c.node.ID, r.taskID, err))
+			r.record(c.node.ID, temporal.StepRecordInput{Status: task.StepCompensationFailed, Error: outcome.Error})
+		} else {
+			r.record(c.node.ID, temporal.StepRecordInput{Status: task.StepCompensated})
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	r.compensations = nil
+	return outcomes
+}
+
+// compensation summarizes how the compensations of the run ended, along
+// with the nodes undone as they failed
+func (r *run) compensation(outcomes []definition.CompensationResult) *task.Compensation {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return definition.SummarizeCompensations(append(outcomes, r.undone...))
+}
+
+// withCompensations appends the summary of the compensations to the error of a run
+func withCompensations(message, compensations string) string {
+	switch {
+	case compensations == "":
+		return message
+	case message == "":
+		return compensations
+	}
+	return message + "; " + compensations
+}
@@ -0,0 +1,179 @@
+package inprocess
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// uploadAndPublish drafts, uploads and publishes; the draft and the upload
+// have compensations
+func uploadAndPublish(draft, deleteDraft, upload, remove, publish string) *definition.Definition {
+	return &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft, MaxAttempts: 1,
+				Compensate: &definition.Compensation{SkillCardID: deleteDraft,
+					Inputs: map[string]interface{}{"id": "$.nodes.draft.output.id"}}},
+			{ID: "upload", Type: definition.NodeSkill, SkillCardID: upload, MaxAttempts: 1,
+				Compensate: &definition.Compensation{SkillCardID: remove}},
+			{ID: "publish", Type: definition.NodeSkill, SkillCardID: publish, MaxAttempts: 1},
+		},
+		Edges: []definition.Edge{{From: "draft", To: "upload"}, {From: "upload", To: "publish"}},
+	}
+}
+
+func TestWorkflowEngine_Compensation(t *testing.T) {
+	draft, deleteDraft, upload, remove, publish := uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString(), uuid.NewString()
+	setup := func() *fakeActivities {
+		activities := newFakeActivities()
+		activities.outputs[draft] = map[string]interface{}{"id": "d1"}
+		activities.outputs[upload] = map[string]interface{}{"url": "https://cdn.example.com/1.png"}
+		return activities
+	}
+
+	t.Run("undoes the completed nodes, the last first", func(t *testing.T) {
+		activities := setup()
+		activities.failures[publish] = []error{errors.New("platform unavailable")}
+		e := newTestEngine(activities, nil)
+		defer e.Stop()
+
+		tk := newRunningTask()
+		_, runID, err := e.Start(context.Background(), tk, uploadAndPublish(draft, deleteDraft, upload, remove, publish), nil)
+		require.NoError(t, err)
+
+		final := waitForEnd(t, activities)
+		assert.Equal(t, string(task.StatusFailed), final.Status)
+		assert.Equal(t, "node publish failed: platform unavailable; compensated upload, draft", final.Error)
+		assert.Equal(t, &task.Compensation{Compensated: []string{"upload", "draft"}, Failed: []task.CompensationFailure{}}, final.Compensation)
+		assert.Equal(t, []string{draft, upload, publish, remove, deleteDraft}, activities.order)
+		assert.Equal(t, "d1", activities.calls[deleteDraft][0]["id"])
+		assert.Equal(t, "https://cdn.example.com/1.png", activities.calls[remove][0]["url"], "the node's output by default")
+		assert.Equal(t, task.StepCompensated, activities.stepStatus("draft"))
+		assert.Equal(t, task.StepCompensated, activities.stepStatus("upload"))
+
+		tk.TemporalRunID = runID
+		outcome, err := e.Outcome(context.Background(), tk)
+		require.NoError(t, err)
+		assert.Empty(t, outcome.Outputs, "undone steps run again on retry")
+		assert.Equal(t, "publish", outcome.FailedStep)
+	})
+
+	t.Run("a failed compensation does not stop the others", func(t *testing.T) {
+		activities := setup()
+		activities.failures[publish] = []error{errors.New("platform unavailable")}
+		activities.failures[remove] = []error{errors.New("cdn unavailable")}
+		e := newTestEngine(activities, nil)
+		defer e.Stop()
+
+		_, _, err := e.Start(context.Background(), newRunningTask(), uploadAndPublish(draft, deleteDraft, upload, remove, publish), nil)
+		require.NoError(t, err)
+
+		final := waitForEnd(t, activities)
+		assert.Equal(t, "node publish failed: platform unavailable; compensation of upload failed: cdn unavailable; compensated draft", final.Error)
+		require.NotNil(t, final.Compensation)
+		assert.True(t, final.Compensation.PartiallyCompensated)
+		assert.Equal(t, []task.CompensationFailure{{StepID: "upload", Error: "cdn unavailable"}}, final.Compensation.Failed)
+		assert.Equal(t, task.StepCompensationFailed, activities.stepStatus("upload"))
+		assert.Equal(t, task.StepCompensated, activities.stepStatus("draft"))
+	})
+
+	t.Run("cancel undoes what completed", func(t *testing.T) {
+		activities := setup()
+		activities.block(upload)
+		e := newTestEngine(activities, nil)
+		defer e.Stop()
+
+		tk := newRunningTask()
+		_, runID, err := e.Start(context.Background(), tk, uploadAndPublish(draft, deleteDraft, upload, remove, publish), nil)
+		require.NoError(t, err)
+		tk.TemporalRunID = runID
+		require.Eventually(t, func() bool { return activities.callCount(upload) == 1 }, time.Second, time.Millisecond)
+
+		require.NoError(t, e.Cancel(context.Background(), tk))
+
+		final := waitForEnd(t, activities)
+		assert.Equal(t, string(task.StatusCancelled), final.Status)
+		require.NotNil(t, final.Compensation)
+		assert.Equal(t, []string{"draft"}, final.Compensation.Compensated)
+		assert.Equal(t, []string{draft, upload, deleteDraft}, activities.order)
+		assert.Equal(t, task.StepCompensated, activities.stepStatus("draft"))
+		assert.Equal(t, task.StepCancelled, activities.stepStatus("upload"))
+	})
+}
+
+func TestWorkflowEngine_PublishNode(t *testing.T) {
+	draft, check := uuid.NewString(), uuid.NewString()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft},
+			{ID: "publish", Type: definition.NodePublish},
+			{ID: "check", Type: definition.NodeSkill, SkillCardID: check, MaxAttempts: 1},
+		},
+		Edges: []definition.Edge{{From: "draft", To: "publish"}, {From: "publish", To: "check"}},
+	}
+	require.NoError(t, def.Validate())
+	setup := func() *fakeActivities {
+		activities := newFakeActivities()
+		activities.outputs[draft] = map[string]interface{}{"title": "标题", "content": "正文"}
+		return activities
+	}
+	start := func(e *WorkflowEngine) {
+		tk := newRunningTask()
+		tk.InputData = map[string]interface{}{"platforms": []interface{}{"xiaohongshu", "weibo"}, "publish_mode": "draft"}
+		_, _, err := e.Start(context.Background(), tk, def, nil)
+		require.NoError(t, err)
+	}
+
+	t.Run("publishes the content of its input", func(t *testing.T) {
+		activities := setup()
+		e := newTestEngine(activities, nil)
+		defer e.Stop()
+		start(e)
+
+		assert.Equal(t, string(task.StatusCompleted), waitForEnd(t, activities).Status)
+		require.Len(t, activities.published, 1)
+		published := activities.published[0]
+		assert.Equal(t, []string{"xiaohongshu", "weibo"}, published.Platforms)
+		assert.Equal(t, "draft", published.Mode)
+		assert.Equal(t, "正文", published.Content["content"])
+		assert.Empty(t, activities.unpublished)
+	})
+
+	t.Run("a later failure takes the publication down", func(t *testing.T) {
+		activities := setup()
+		activities.failures[check] = []error{errors.New("check failed")}
+		e := newTestEngine(activities, nil)
+		defer e.Stop()
+		start(e)
+
+		final := waitForEnd(t, activities)
+		assert.Equal(t, "node check failed: check failed; compensated publish", final.Error)
+		assert.Equal(t, []string{"xiaohongshu", "weibo"}, activities.unpublished)
+		assert.Equal(t, task.StepCompensated, activities.stepStatus("publish"))
+	})
+
+	t.Run("a partial publication is taken down and fails the node", func(t *testing.T) {
+		activities := setup()
+		activities.down["weibo"] = true
+		e := newTestEngine(activities, nil)
+		defer e.Stop()
+		start(e)
+
+		final := waitForEnd(t, activities)
+		assert.Equal(t, "node publish failed: failed to publish to weibo; taken down again from xiaohongshu", final.Error)
+		require.NotNil(t, final.Compensation)
+		assert.Equal(t, []string{"publish"}, final.Compensation.Compensated)
+		assert.Equal(t, []string{"xiaohongshu"}, activities.unpublished)
+		assert.Equal(t, task.StepFailed, activities.stepStatus("publish"))
+	})
+}
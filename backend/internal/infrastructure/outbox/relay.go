@@ -0,0 +1,221 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/logger"
+)
+
+// Relay defaults
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+	defaultMaxAttempts  = 10
+	defaultRetention    = 7 * 24 * time.Hour
+
+	// maxRetryDelay caps the backoff between delivery attempts
+	maxRetryDelay = 5 * time.Minute
+)
+
+// Record is an event waiting in the outbox
+type Record struct {
+	ID        int64           `db:"id"`
+	EventID   string          `db:"event_id"`
+	EventType string          `db:"event_type"`
+	Event     json.RawMessage `db:"event"`
+	Attempts  int             `db:"attempts"`
+	RetryAt   *time.Time      `db:"retry_at"`
+	CreatedAt time.Time       `db:"created_at"`
+}
+
+// Store persists outbox records; implemented by persistence.OutboxRepository
+type Store interface {
+	// Append writes an event in ctx's transaction
+	Append(ctx context.Context, event *eventbus.Event) error
+	// Pending locks and returns the oldest undelivered records, in the order
+	// they were written, skipping those that used up their attempts
+	Pending(ctx context.Context, limit, maxAttempts int) ([]*Record, error)
+	// MarkPublished records that a record was delivered
+	MarkPublished(ctx context.Context, id int64) error
+	// MarkFailed records a failed delivery attempt and when to retry it
+	MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	// Purge deletes records delivered before a time and consumer claims
+	// older than it, returning how many outbox records were deleted
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Deliverer hands an event to its subscribers and reports whether they all
+// handled it; implemented by eventbus.EventBus
+type Deliverer interface {
+	Deliver(ctx context.Context, event *eventbus.Event) error
+}
+
+// Options configures a relay; zero values fall back to the defaults
+type Options struct {
+	PollInterval time.Duration
+	BatchSize    int
+	MaxAttempts  int
+	Retention    time.Duration
+}
+
+// Relay moves events from the outbox to the bus. Events are written by
+// Add in the transaction of the change they announce and delivered in the
+// order they were written, at least once: an event whose delivery fails
+// holds back the events after it until it is delivered or runs out of
+// attempts. Consumers deduplicate redeliveries by event ID.
+type Relay struct {
+	store Store
+	uow   database.UnitOfWork
+	bus   Deliverer
+	opts  Options
+	nudge chan struct{}
+}
+
+// NewRelay creates an outbox relay
+func NewRelay(store Store, uow database.UnitOfWork, bus Deliverer, opts Options) *Relay {
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = defaultPollInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultBatchSize
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.Retention <= 0 {
+		opts.Retention = defaultRetention
+	}
+	return &Relay{
+		store: store,
+		uow:   uow,
+		bus:   bus,
+		opts:  opts,
+		nudge: make(chan struct{}, 1),
+	}
+}
+
+// Add writes an event to the outbox in ctx's transaction and wakes the
+// relay once the transaction commits
+func (r *Relay) Add(ctx context.Context, event *eventbus.Event) error {
+	if err := r.store.Append(ctx, event); err != nil {
+		return err
+	}
+	database.AfterCommit(ctx, r.Notify)
+	return nil
+}
+
+// Notify wakes the relay without waiting for its next poll
+func (r *Relay) Notify() {
+	select {
+	case r.nudge <- struct{}{}:
+	default:
+	}
+}
+
+// Flush delivers one batch of pending events in order and returns how many
+// were delivered. It stops at the first event that fails, recording the
+// attempt so the event is retried first once its backoff has passed, and at
+// an event still backing off.
+func (r *Relay) Flush(ctx context.Context) (int, error) {
+	delivered := 0
+	var deliveryErr error
+	err := r.uow.Do(ctx, func(txCtx context.Context) error {
+		records, err := r.store.Pending(txCtx, r.opts.BatchSize, r.opts.MaxAttempts)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		for _, record := range records {
+			if record.RetryAt != nil && record.RetryAt.After(now) {
+				return nil
+			}
+			// Handlers run outside the relay's transaction
+			if err := r.deliver(ctx, record); err != nil {
+				deliveryErr = fmt.Errorf("failed to deliver event %s (%s): %w", record.EventID, record.EventType, err)
+				if record.Attempts+1 >= r.opts.MaxAttempts {
+					logger.Error(fmt.Sprintf("Giving up on outbox event %s (%s) after %d attempts: %v",
+						record.EventID, record.EventType, record.Attempts+1, err))
+				}
+				return r.store.MarkFailed(txCtx, record.ID, err.Error(), now.Add(r.retryDelay(record.Attempts+1)))
+			}
+			if err := r.store.MarkPublished(txCtx, record.ID); err != nil {
+				return err
+			}
+			delivered++
+		}
+		return nil
+	})
+	if err != nil {
+		return delivered, err
+	}
+	return delivered, deliveryErr
+}
+
+// retryDelay doubles the poll interval with every failed attempt
+func (r *Relay) retryDelay(attempts int) time.Duration {
+	delay := r.opts.PollInterval
+	for i := 1; i < attempts && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryDelay {
+		delay = maxRetryDelay
+	}
+	return delay
+}
+
+// deliver decodes a record and hands its event to the bus
+func (r *Relay) deliver(ctx context.Context, record *Record) error {
+	var event eventbus.Event
+	if err := json.Unmarshal(record.Event, &event); err != nil {
+		return fmt.Errorf("invalid event: %w", err)
+	}
+	return r.bus.Deliver(ctx, &event)
+}
+
+// Start runs the relay until ctx is cancelled: it flushes when notified and
+// on every poll, and purges delivered events older than the retention
+func (r *Relay) Start(ctx context.Context) {
+	go func() {
+		poll := time.NewTicker(r.opts.PollInterval)
+		defer poll.Stop()
+		purge := time.NewTicker(time.Hour)
+		defer purge.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-r.nudge:
+				r.drain(ctx)
+			case <-poll.C:
+				r.drain(ctx)
+			case now := <-purge.C:
+				if _, err := r.store.Purge(ctx, now.Add(-r.opts.Retention)); err != nil {
+					logger.Warn(fmt.Sprintf("Failed to purge the event outbox: %v", err))
+				}
+			}
+		}
+	}()
+}
+
+// drain flushes full batches until the outbox is empty or a delivery fails
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivered, err := r.Flush(ctx)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Failed to relay outbox events: %v", err))
+			return
+		}
+		if delivered < r.opts.BatchSize {
+			return
+		}
+	}
+}
+
+var _ eventbus.Outbox = (*Relay)(nil)
@@ -0,0 +1,201 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/infrastructure/config"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&config.LogConfig{Level: "error", Format: "console"})
+	os.Exit(m.Run())
+}
+
+// fakeStore keeps outbox records in memory
+type fakeStore struct {
+	records   []*Record
+	published map[int64]bool
+	purged    time.Time
+}
+
+func newFakeStore() *fakeStore {
+	return &fakeStore{published: make(map[int64]bool)}
+}
+
+func (s *fakeStore) Append(_ context.Context, event *eventbus.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	s.records = append(s.records, &Record{
+		ID:        int64(len(s.records) + 1),
+		EventID:   event.ID,
+		EventType: string(event.Type),
+		Event:     data,
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+func (s *fakeStore) Pending(_ context.Context, limit, maxAttempts int) ([]*Record, error) {
+	var pending []*Record
+	for _, r := range s.records {
+		if !s.published[r.ID] && r.Attempts < maxAttempts && len(pending) < limit {
+			pending = append(pending, r)
+		}
+	}
+	return pending, nil
+}
+
+func (s *fakeStore) MarkPublished(_ context.Context, id int64) error {
+	s.published[id] = true
+	return nil
+}
+
+func (s *fakeStore) MarkFailed(_ context.Context, id int64, _ string, retryAt time.Time) error {
+	r := s.records[id-1]
+	r.Attempts++
+	r.RetryAt = &retryAt
+	return nil
+}
+
+func (s *fakeStore) Purge(_ context.Context, before time.Time) (int64, error) {
+	s.purged = before
+	return 0, nil
+}
+
+// directUnitOfWork runs units of work without a transaction
+type directUnitOfWork struct{}
+
+func (directUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+// recordingBus records delivered event IDs, failing those in fail
+type recordingBus struct {
+	delivered []string
+	fail      map[string]bool
+}
+
+func (b *recordingBus) Deliver(_ context.Context, event *eventbus.Event) error {
+	if b.fail[event.ID] {
+		return fmt.Errorf("handler failed")
+	}
+	b.delivered = append(b.delivered, event.ID)
+	return nil
+}
+
+func addEvents(t *testing.T, relay *Relay, ids ...string) {
+	t.Helper()
+	for _, id := range ids {
+		require.NoError(t, relay.Add(context.Background(), &eventbus.Event{ID: id, Type: eventbus.EventTaskCreated}))
+	}
+}
+
+func TestRelay_FlushDeliversInOrder(t *testing.T) {
+	store := newFakeStore()
+	bus := &recordingBus{}
+	relay := NewRelay(store, directUnitOfWork{}, bus, Options{BatchSize: 2})
+
+	addEvents(t, relay, "a", "b", "c")
+
+	delivered, err := relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	delivered, err = relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+
+	assert.Equal(t, []string{"a", "b", "c"}, bus.delivered)
+}
+
+func TestRelay_FailedEventHoldsBackLaterEvents(t *testing.T) {
+	store := newFakeStore()
+	bus := &recordingBus{fail: map[string]bool{"b": true}}
+	relay := NewRelay(store, directUnitOfWork{}, bus, Options{PollInterval: time.Minute})
+
+	addEvents(t, relay, "a", "b", "c")
+
+	delivered, err := relay.Flush(context.Background())
+	require.Error(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, []string{"a"}, bus.delivered)
+	assert.Equal(t, 1, store.records[1].Attempts)
+
+	// The event backs off before its retry, still holding back "c"
+	delivered, err = relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered)
+
+	bus.fail = nil
+	past := time.Now().Add(-time.Second)
+	store.records[1].RetryAt = &past
+	delivered, err = relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Equal(t, []string{"a", "b", "c"}, bus.delivered)
+}
+
+func TestRelay_GivesUpAfterMaxAttempts(t *testing.T) {
+	store := newFakeStore()
+	bus := &recordingBus{fail: map[string]bool{"a": true}}
+	relay := NewRelay(store, directUnitOfWork{}, bus, Options{MaxAttempts: 2})
+
+	addEvents(t, relay, "a", "b")
+
+	for i := 0; i < 2; i++ {
+		_, err := relay.Flush(context.Background())
+		require.Error(t, err)
+		store.records[0].RetryAt = nil
+	}
+
+	delivered, err := relay.Flush(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, []string{"b"}, bus.delivered)
+}
+
+func TestRelay_RetryDelayBacksOff(t *testing.T) {
+	relay := NewRelay(newFakeStore(), directUnitOfWork{}, &recordingBus{}, Options{PollInterval: time.Second})
+
+	assert.Equal(t, time.Second, relay.retryDelay(1))
+	assert.Equal(t, 4*time.Second, relay.retryDelay(3))
+	assert.Equal(t, maxRetryDelay, relay.retryDelay(20))
+}
+
+func TestRelay_StartDeliversWhenNotified(t *testing.T) {
+	store := newFakeStore()
+	bus := eventbus.NewEventBus()
+	relay := NewRelay(store, directUnitOfWork{}, bus, Options{PollInterval: time.Hour})
+	bus.SetOutbox(relay)
+
+	received := make(chan string, 1)
+	bus.Subscribe(eventbus.EventTaskCreated, func(ctx context.Context, event *eventbus.Event) error {
+		received <- event.ID
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	relay.Start(ctx)
+
+	event := &eventbus.Event{ID: "evt-1", Type: eventbus.EventTaskCreated}
+	require.NoError(t, bus.Publish(ctx, event))
+
+	select {
+	case id := <-received:
+		assert.Equal(t, "evt-1", id)
+	case <-time.After(time.Second):
+		t.Fatal("event was not relayed")
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"unlimited-corp/internal/domain/chat"
+	"unlimited-corp/internal/infrastructure/database"
 	"unlimited-corp/pkg/errors"
 )
 
@@ -19,12 +20,17 @@ func NewChatRepository(db *sqlx.DB) *ChatRepository {
 	return &ChatRepository{db: db}
 }
 
+// conn returns the transaction carried by ctx, or the database itself
+func (r *ChatRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
 func (r *ChatRepository) CreateSession(ctx context.Context, session *chat.ChatSession) error {
 	query := `
 		INSERT INTO chat_sessions (id, company_id, title, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		session.ID, session.CompanyID, session.Title, session.CreatedAt, session.UpdatedAt,
 	)
 	if err != nil {
@@ -39,7 +45,7 @@ func (r *ChatRepository) GetSessionByID(ctx context.Context, id uuid.UUID) (*cha
 		FROM chat_sessions WHERE id = $1
 	`
 	var session chat.ChatSession
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.conn(ctx).QueryRowContext(ctx, query, id).Scan(
 		&session.ID, &session.CompanyID, &session.Title, &session.CreatedAt, &session.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -58,7 +64,7 @@ func (r *ChatRepository) ListSessionsByCompanyID(ctx context.Context, companyID
 		ORDER BY updated_at DESC
 		LIMIT $2 OFFSET $3
 	`
-	rows, err := r.db.QueryContext(ctx, query, companyID, limit, offset)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, companyID, limit, offset)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list chat sessions")
 	}
@@ -78,7 +84,7 @@ func (r *ChatRepository) ListSessionsByCompanyID(ctx context.Context, companyID
 
 func (r *ChatRepository) DeleteSession(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM chat_sessions WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return errors.Wrap(err, "failed to delete chat session")
 	}
@@ -97,7 +103,7 @@ func (r *ChatRepository) CreateMessage(ctx context.Context, message *chat.ChatMe
 		INSERT INTO chat_messages (id, session_id, role, content, actions, created_at)
 		VALUES ($1, $2, $3, $4, $5, $6)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		message.ID, message.SessionID, message.Role, message.Content, actions, message.CreatedAt,
 	)
 	if err != nil {
@@ -113,7 +119,7 @@ func (r *ChatRepository) ListMessagesBySessionID(ctx context.Context, sessionID
 		ORDER BY created_at ASC
 		LIMIT $2 OFFSET $3
 	`
-	rows, err := r.db.QueryContext(ctx, query, sessionID, limit, offset)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, sessionID, limit, offset)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list chat messages")
 	}
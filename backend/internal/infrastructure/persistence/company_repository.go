@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"unlimited-corp/internal/domain/company"
+	"unlimited-corp/internal/infrastructure/database"
 	"unlimited-corp/pkg/errors"
 )
 
@@ -19,6 +20,11 @@ func NewCompanyRepository(db *sqlx.DB) *CompanyRepository {
 	return &CompanyRepository{db: db}
 }
 
+// conn returns the transaction carried by ctx, or the database itself
+func (r *CompanyRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
 func (r *CompanyRepository) Create(ctx context.Context, c *company.Company) error {
 	settings, err := json.Marshal(c.Settings)
 	if err != nil {
@@ -29,7 +35,7 @@ func (r *CompanyRepository) Create(ctx context.Context, c *company.Company) erro
 		INSERT INTO companies (id, user_id, name, description, logo_url, settings, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
-	_, err = r.db.ExecContext(ctx, query,
+	_, err = r.conn(ctx).ExecContext(ctx, query,
 		c.ID, c.UserID, c.Name, c.Description, c.LogoURL, settings, c.CreatedAt, c.UpdatedAt,
 	)
 	if err != nil {
@@ -45,7 +51,7 @@ func (r *CompanyRepository) GetByID(ctx context.Context, id uuid.UUID) (*company
 	`
 	var c company.Company
 	var settings []byte
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	err := r.conn(ctx).QueryRowContext(ctx, query, id).Scan(
 		&c.ID, &c.UserID, &c.Name, &c.Description, &c.LogoURL, &settings, &c.CreatedAt, &c.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -68,7 +74,7 @@ func (r *CompanyRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (
 	`
 	var c company.Company
 	var settings []byte
-	err := r.db.QueryRowContext(ctx, query, userID).Scan(
+	err := r.conn(ctx).QueryRowContext(ctx, query, userID).Scan(
 		&c.ID, &c.UserID, &c.Name, &c.Description, &c.LogoURL, &settings, &c.CreatedAt, &c.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -95,7 +101,7 @@ func (r *CompanyRepository) Update(ctx context.Context, c *company.Company) erro
 		SET name = $1, description = $2, logo_url = $3, settings = $4, updated_at = $5
 		WHERE id = $6
 	`
-	result, err := r.db.ExecContext(ctx, query,
+	result, err := r.conn(ctx).ExecContext(ctx, query,
 		c.Name, c.Description, c.LogoURL, settings, c.UpdatedAt, c.ID,
 	)
 	if err != nil {
@@ -114,7 +120,7 @@ func (r *CompanyRepository) Update(ctx context.Context, c *company.Company) erro
 
 func (r *CompanyRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM companies WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return errors.Wrap(err, "failed to delete company")
 	}
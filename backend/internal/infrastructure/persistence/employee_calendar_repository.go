@@ -0,0 +1,114 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/pkg/errors"
+)
+
+const calendarColumns = `
+	employee_id, company_id, COALESCE(timezone, ''), windows, blackouts, auto_offline, created_at, updated_at
+`
+
+// GetCalendar retrieves the calendar of an employee
+func (r *EmployeeRepository) GetCalendar(ctx context.Context, employeeID uuid.UUID) (*employee.Calendar, error) {
+	query := `SELECT ` + calendarColumns + ` FROM employee_calendars WHERE employee_id = $1`
+
+	cal, err := scanCalendar(r.conn(ctx).QueryRowContext(ctx, query, employeeID))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get calendar")
+	}
+
+	return cal, nil
+}
+
+// SaveCalendar creates or replaces the calendar of an employee
+func (r *EmployeeRepository) SaveCalendar(ctx context.Context, cal *employee.Calendar) error {
+	windows, _ := json.Marshal(cal.Windows)
+	blackouts, _ := json.Marshal(cal.Blackouts)
+
+	query := `
+		INSERT INTO employee_calendars (
+			employee_id, company_id, timezone, windows, blackouts, auto_offline, created_at, updated_at
+		) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7, $8)
+		ON CONFLICT (employee_id) DO UPDATE SET
+			timezone = EXCLUDED.timezone,
+			windows = EXCLUDED.windows,
+			blackouts = EXCLUDED.blackouts,
+			auto_offline = EXCLUDED.auto_offline,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		cal.EmployeeID, cal.CompanyID, cal.Timezone, windows, blackouts, cal.AutoOffline,
+		cal.CreatedAt, cal.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to save calendar")
+	}
+
+	return nil
+}
+
+// ListCalendarsByCompany retrieves the calendars of a company
+func (r *EmployeeRepository) ListCalendarsByCompany(ctx context.Context, companyID uuid.UUID) ([]*employee.Calendar, error) {
+	query := `SELECT ` + calendarColumns + ` FROM employee_calendars WHERE company_id = $1`
+	return r.listCalendars(ctx, query, companyID)
+}
+
+// ListCalendars retrieves all calendars
+func (r *EmployeeRepository) ListCalendars(ctx context.Context) ([]*employee.Calendar, error) {
+	query := `SELECT ` + calendarColumns + ` FROM employee_calendars ORDER BY company_id`
+	return r.listCalendars(ctx, query)
+}
+
+// listCalendars runs a calendar query and scans all rows
+func (r *EmployeeRepository) listCalendars(ctx context.Context, query string, args ...interface{}) ([]*employee.Calendar, error) {
+	rows, err := r.conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list calendars")
+	}
+	defer rows.Close()
+
+	var calendars []*employee.Calendar
+	for rows.Next() {
+		cal, err := scanCalendar(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan calendar")
+		}
+		calendars = append(calendars, cal)
+	}
+
+	return calendars, nil
+}
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCalendar scans a calendar row selected with calendarColumns
+func scanCalendar(row rowScanner) (*employee.Calendar, error) {
+	var cal employee.Calendar
+	var windows, blackouts []byte
+	if err := row.Scan(
+		&cal.EmployeeID, &cal.CompanyID, &cal.Timezone, &windows, &blackouts, &cal.AutoOffline,
+		&cal.CreatedAt, &cal.UpdatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(windows, &cal.Windows)
+	json.Unmarshal(blackouts, &cal.Blackouts)
+	return &cal, nil
+}
+
+// Ensure implementation matches interface
+var _ employee.CalendarRepository = (*EmployeeRepository)(nil)
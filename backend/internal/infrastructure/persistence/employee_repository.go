@@ -21,6 +21,11 @@ func NewEmployeeRepository(db *database.DB) *EmployeeRepository {
 	return &EmployeeRepository{db: db}
 }
 
+// conn returns the transaction carried by ctx, or the database itself
+func (r *EmployeeRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db.DB)
+}
+
 // Create creates a new employee
 func (r *EmployeeRepository) Create(ctx context.Context, e *employee.Employee) error {
 	query := `
@@ -31,7 +36,7 @@ func (r *EmployeeRepository) Create(ctx context.Context, e *employee.Employee) e
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		e.ID, e.CompanyID, e.Name, e.Role, e.AvatarURL, e.Personality,
 		e.Status, e.CurrentTaskID, e.TotalTasks, e.SuccessRate, e.Settings,
 		e.CreatedAt, e.UpdatedAt,
@@ -48,7 +53,7 @@ func (r *EmployeeRepository) GetByID(ctx context.Context, id uuid.UUID) (*employ
 	query := `SELECT * FROM employees WHERE id = $1`
 
 	var e employee.Employee
-	if err := r.db.GetContext(ctx, &e, query, id); err != nil {
+	if err := r.conn(ctx).GetContext(ctx, &e, query, id); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, errors.ErrNotFound
 		}
@@ -63,7 +68,7 @@ func (r *EmployeeRepository) GetByCompanyID(ctx context.Context, companyID uuid.
 	query := `SELECT * FROM employees WHERE company_id = $1 ORDER BY name ASC`
 
 	var employees []*employee.Employee
-	if err := r.db.SelectContext(ctx, &employees, query, companyID); err != nil {
+	if err := r.conn(ctx).SelectContext(ctx, &employees, query, companyID); err != nil {
 		return nil, errors.Wrap(err, "failed to get employees by company")
 	}
 
@@ -75,7 +80,7 @@ func (r *EmployeeRepository) GetAvailable(ctx context.Context, companyID uuid.UU
 	query := `SELECT * FROM employees WHERE company_id = $1 AND status = 'idle' ORDER BY success_rate DESC`
 
 	var employees []*employee.Employee
-	if err := r.db.SelectContext(ctx, &employees, query, companyID); err != nil {
+	if err := r.conn(ctx).SelectContext(ctx, &employees, query, companyID); err != nil {
 		return nil, errors.Wrap(err, "failed to get available employees")
 	}
 
@@ -92,7 +97,7 @@ func (r *EmployeeRepository) Update(ctx context.Context, e *employee.Employee) e
 		WHERE id = $11
 	`
 
-	result, err := r.db.ExecContext(ctx, query,
+	result, err := r.conn(ctx).ExecContext(ctx, query,
 		e.Name, e.Role, e.AvatarURL, e.Personality,
 		e.Status, e.CurrentTaskID, e.TotalTasks, e.SuccessRate,
 		e.Settings, time.Now(), e.ID,
@@ -117,7 +122,7 @@ func (r *EmployeeRepository) Update(ctx context.Context, e *employee.Employee) e
 func (r *EmployeeRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM employees WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return errors.Wrap(err, "failed to delete employee")
 	}
@@ -142,7 +147,7 @@ func (r *EmployeeRepository) AssignSkill(ctx context.Context, employeeID, skillC
 		ON CONFLICT (employee_id, skill_card_id) DO UPDATE SET proficiency = $4
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		uuid.New(), employeeID, skillCardID, proficiency, time.Now(),
 	)
 	if err != nil {
@@ -156,7 +161,7 @@ func (r *EmployeeRepository) AssignSkill(ctx context.Context, employeeID, skillC
 func (r *EmployeeRepository) RemoveSkill(ctx context.Context, employeeID, skillCardID uuid.UUID) error {
 	query := `DELETE FROM employee_skills WHERE employee_id = $1 AND skill_card_id = $2`
 
-	_, err := r.db.ExecContext(ctx, query, employeeID, skillCardID)
+	_, err := r.conn(ctx).ExecContext(ctx, query, employeeID, skillCardID)
 	if err != nil {
 		return errors.Wrap(err, "failed to remove skill")
 	}
@@ -169,7 +174,7 @@ func (r *EmployeeRepository) GetSkills(ctx context.Context, employeeID uuid.UUID
 	query := `SELECT * FROM employee_skills WHERE employee_id = $1`
 
 	var skills []*employee.EmployeeSkill
-	if err := r.db.SelectContext(ctx, &skills, query, employeeID); err != nil {
+	if err := r.conn(ctx).SelectContext(ctx, &skills, query, employeeID); err != nil {
 		return nil, errors.Wrap(err, "failed to get employee skills")
 	}
 
@@ -181,7 +186,7 @@ func (r *EmployeeRepository) GetByStatus(ctx context.Context, companyID uuid.UUI
 	query := `SELECT * FROM employees WHERE company_id = $1 AND status = $2 ORDER BY name ASC`
 
 	var employees []*employee.Employee
-	if err := r.db.SelectContext(ctx, &employees, query, companyID, status); err != nil {
+	if err := r.conn(ctx).SelectContext(ctx, &employees, query, companyID, status); err != nil {
 		return nil, errors.Wrap(err, "failed to get employees by status")
 	}
 
@@ -193,12 +198,179 @@ func (r *EmployeeRepository) CountByCompany(ctx context.Context, companyID uuid.
 	query := `SELECT COUNT(*) FROM employees WHERE company_id = $1`
 
 	var count int
-	if err := r.db.GetContext(ctx, &count, query, companyID); err != nil {
+	if err := r.conn(ctx).GetContext(ctx, &count, query, companyID); err != nil {
 		return 0, errors.Wrap(err, "failed to count employees")
 	}
 
 	return count, nil
 }
 
+// GetSchedulable retrieves employees that are idle or working for a company
+func (r *EmployeeRepository) GetSchedulable(ctx context.Context, companyID uuid.UUID) ([]*employee.Employee, error) {
+	query := `SELECT * FROM employees WHERE company_id = $1 AND status IN ('idle', 'working') ORDER BY name ASC`
+
+	var employees []*employee.Employee
+	if err := r.conn(ctx).SelectContext(ctx, &employees, query, companyID); err != nil {
+		return nil, errors.Wrap(err, "failed to get schedulable employees")
+	}
+
+	return employees, nil
+}
+
+// LockSchedulable locks the idle or working employees of a company, skipping rows locked elsewhere
+func (r *EmployeeRepository) LockSchedulable(ctx context.Context, companyID uuid.UUID) ([]*employee.Employee, error) {
+	query := `
+		SELECT * FROM employees
+		WHERE company_id = $1 AND status IN ('idle', 'working')
+		ORDER BY name ASC
+		FOR UPDATE SKIP LOCKED
+	`
+
+	var employees []*employee.Employee
+	if err := r.conn(ctx).SelectContext(ctx, &employees, query, companyID); err != nil {
+		return nil, errors.Wrap(err, "failed to lock schedulable employees")
+	}
+
+	return employees, nil
+}
+
+// LockByID retrieves an employee by ID and locks it until the transaction ends
+func (r *EmployeeRepository) LockByID(ctx context.Context, id uuid.UUID) (*employee.Employee, error) {
+	query := `SELECT * FROM employees WHERE id = $1 FOR UPDATE`
+
+	var e employee.Employee
+	if err := r.conn(ctx).GetContext(ctx, &e, query, id); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound
+		}
+		return nil, errors.Wrap(err, "failed to lock employee")
+	}
+
+	return &e, nil
+}
+
+// CreateAssignment records a task assigned to an employee
+func (r *EmployeeRepository) CreateAssignment(ctx context.Context, a *employee.Assignment) error {
+	query := `
+		INSERT INTO employee_task_assignments (id, company_id, employee_id, task_id, decision, assigned_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+
+	_, err := r.conn(ctx).ExecContext(ctx, query, a.ID, a.CompanyID, a.EmployeeID, a.TaskID, a.Decision, a.AssignedAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to create assignment")
+	}
+
+	return nil
+}
+
+// ReleaseAssignment marks the active assignment of a task as released
+func (r *EmployeeRepository) ReleaseAssignment(ctx context.Context, employeeID, taskID uuid.UUID) error {
+	query := `
+		UPDATE employee_task_assignments SET released_at = $1
+		WHERE employee_id = $2 AND task_id = $3 AND released_at IS NULL
+	`
+
+	result, err := r.conn(ctx).ExecContext(ctx, query, time.Now(), employeeID, taskID)
+	if err != nil {
+		return errors.Wrap(err, "failed to release assignment")
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "failed to get rows affected")
+	}
+
+	if rowsAffected == 0 {
+		return errors.ErrNotFound
+	}
+
+	return nil
+}
+
+// GetActiveAssignments retrieves the active assignments of an employee
+func (r *EmployeeRepository) GetActiveAssignments(ctx context.Context, employeeID uuid.UUID) ([]*employee.Assignment, error) {
+	query := `
+		SELECT id, company_id, employee_id, task_id, decision, assigned_at, released_at
+		FROM employee_task_assignments
+		WHERE employee_id = $1 AND released_at IS NULL
+		ORDER BY assigned_at ASC
+	`
+
+	var assignments []*employee.Assignment
+	if err := r.conn(ctx).SelectContext(ctx, &assignments, query, employeeID); err != nil {
+		return nil, errors.Wrap(err, "failed to get active assignments")
+	}
+
+	return assignments, nil
+}
+
+// GetLatestAssignmentByTask retrieves the most recent assignment of a task
+func (r *EmployeeRepository) GetLatestAssignmentByTask(ctx context.Context, taskID uuid.UUID) (*employee.Assignment, error) {
+	query := `
+		SELECT id, company_id, employee_id, task_id, decision, assigned_at, released_at
+		FROM employee_task_assignments
+		WHERE task_id = $1
+		ORDER BY assigned_at DESC
+		LIMIT 1
+	`
+
+	var a employee.Assignment
+	if err := r.conn(ctx).GetContext(ctx, &a, query, taskID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound
+		}
+		return nil, errors.Wrap(err, "failed to get assignment")
+	}
+
+	return &a, nil
+}
+
+// GetLatestAssignmentByCompany retrieves the most recent assignment in a company
+func (r *EmployeeRepository) GetLatestAssignmentByCompany(ctx context.Context, companyID uuid.UUID) (*employee.Assignment, error) {
+	query := `
+		SELECT id, company_id, employee_id, task_id, decision, assigned_at, released_at
+		FROM employee_task_assignments
+		WHERE company_id = $1
+		ORDER BY assigned_at DESC
+		LIMIT 1
+	`
+
+	var a employee.Assignment
+	if err := r.conn(ctx).GetContext(ctx, &a, query, companyID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.ErrNotFound
+		}
+		return nil, errors.Wrap(err, "failed to get assignment")
+	}
+
+	return &a, nil
+}
+
+// CountActiveAssignments counts active assignments per employee for a company
+func (r *EmployeeRepository) CountActiveAssignments(ctx context.Context, companyID uuid.UUID) (map[uuid.UUID]int, error) {
+	query := `
+		SELECT employee_id, COUNT(*) AS active
+		FROM employee_task_assignments
+		WHERE company_id = $1 AND released_at IS NULL
+		GROUP BY employee_id
+	`
+
+	var rows []struct {
+		EmployeeID uuid.UUID `db:"employee_id"`
+		Active     int       `db:"active"`
+	}
+	if err := r.conn(ctx).SelectContext(ctx, &rows, query, companyID); err != nil {
+		return nil, errors.Wrap(err, "failed to count active assignments")
+	}
+
+	counts := make(map[uuid.UUID]int, len(rows))
+	for _, row := range rows {
+		counts[row.EmployeeID] = row.Active
+	}
+
+	return counts, nil
+}
+
 // Ensure implementation matches interface
 var _ employee.Repository = (*EmployeeRepository)(nil)
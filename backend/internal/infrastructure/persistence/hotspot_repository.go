@@ -0,0 +1,111 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"unlimited-corp/internal/domain/hotspot"
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/pkg/errors"
+)
+
+// HotspotRepository stores hotspot snapshots and uploads
+type HotspotRepository struct {
+	db *sqlx.DB
+}
+
+// NewHotspotRepository creates a new hotspot repository
+func NewHotspotRepository(db *sqlx.DB) *HotspotRepository {
+	return &HotspotRepository{db: db}
+}
+
+// conn returns the transaction carried by ctx, or the database itself
+func (r *HotspotRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// CreateSnapshot stores a snapshot
+func (r *HotspotRepository) CreateSnapshot(ctx context.Context, s *hotspot.Snapshot) error {
+	topics, err := json.Marshal(s.Topics)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode hotspot topics")
+	}
+	query := `
+		INSERT INTO hotspot_snapshots (id, company_id, platform, category, topics, captured_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err = r.conn(ctx).ExecContext(ctx, query, s.ID, s.CompanyID, s.Platform, s.Category, topics, s.CapturedAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to create hotspot snapshot")
+	}
+	return nil
+}
+
+// GetLatestSnapshot returns the newest snapshot of a company's platform and category
+func (r *HotspotRepository) GetLatestSnapshot(ctx context.Context, companyID uuid.UUID, platform, category string) (*hotspot.Snapshot, error) {
+	query := `
+		SELECT id, company_id, platform, category, topics, captured_at
+		FROM hotspot_snapshots
+		WHERE company_id = $1 AND platform = $2 AND category = $3
+		ORDER BY captured_at DESC
+		LIMIT 1
+	`
+	var s hotspot.Snapshot
+	var topics []byte
+	err := r.conn(ctx).QueryRowContext(ctx, query, companyID, platform, category).Scan(
+		&s.ID, &s.CompanyID, &s.Platform, &s.Category, &topics, &s.CapturedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get hotspot snapshot")
+	}
+	if err := json.Unmarshal(topics, &s.Topics); err != nil {
+		return nil, errors.Wrap(err, "failed to decode hotspot topics")
+	}
+	return &s, nil
+}
+
+// CreateUpload stores an uploaded CSV
+func (r *HotspotRepository) CreateUpload(ctx context.Context, u *hotspot.Upload) error {
+	query := `
+		INSERT INTO hotspot_uploads (id, company_id, platform, filename, content, item_count, uploaded_by, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		u.ID, u.CompanyID, u.Platform, u.Filename, u.Content, u.ItemCount, u.UploadedBy, u.CreatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create hotspot upload")
+	}
+	return nil
+}
+
+// GetLatestUpload returns the newest upload of a company's platform
+func (r *HotspotRepository) GetLatestUpload(ctx context.Context, companyID uuid.UUID, platform string) (*hotspot.Upload, error) {
+	query := `
+		SELECT id, company_id, platform, filename, content, item_count, uploaded_by, created_at
+		FROM hotspot_uploads
+		WHERE company_id = $1 AND platform = $2
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+	var u hotspot.Upload
+	err := r.conn(ctx).QueryRowContext(ctx, query, companyID, platform).Scan(
+		&u.ID, &u.CompanyID, &u.Platform, &u.Filename, &u.Content, &u.ItemCount, &u.UploadedBy, &u.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get hotspot upload")
+	}
+	return &u, nil
+}
+
+// Ensure implementation matches interface
+var _ hotspot.Repository = (*HotspotRepository)(nil)
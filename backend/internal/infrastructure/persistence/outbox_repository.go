@@ -0,0 +1,136 @@
+package persistence
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/internal/infrastructure/outbox"
+	"unlimited-corp/pkg/errors"
+)
+
+// OutboxRepository stores the event outbox and the events each consumer has
+// handled
+type OutboxRepository struct {
+	db *sqlx.DB
+}
+
+// NewOutboxRepository creates a new outbox repository
+func NewOutboxRepository(db *sqlx.DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+// conn returns the transaction carried by ctx, or the database itself
+func (r *OutboxRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+// Append writes an event to the outbox
+func (r *OutboxRepository) Append(ctx context.Context, event *eventbus.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode outbox event")
+	}
+	query := `
+		INSERT INTO outbox_events (event_id, event_type, event, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (event_id) DO NOTHING
+	`
+	_, err = r.conn(ctx).ExecContext(ctx, query, event.ID, string(event.Type), data, time.Now())
+	if err != nil {
+		return errors.Wrap(err, "failed to append outbox event")
+	}
+	return nil
+}
+
+// Pending locks and returns the oldest undelivered events in order. Relays
+// block on each other's locks rather than skipping them, so events are never
+// delivered out of order.
+func (r *OutboxRepository) Pending(ctx context.Context, limit, maxAttempts int) ([]*outbox.Record, error) {
+	query := `
+		SELECT id, event_id, event_type, event, attempts, retry_at, created_at
+		FROM outbox_events
+		WHERE published_at IS NULL AND attempts < $1
+		ORDER BY id
+		LIMIT $2
+		FOR UPDATE
+	`
+	var records []*outbox.Record
+	if err := r.conn(ctx).SelectContext(ctx, &records, query, maxAttempts, limit); err != nil {
+		return nil, errors.Wrap(err, "failed to list pending outbox events")
+	}
+	return records, nil
+}
+
+// MarkPublished records that an event was delivered
+func (r *OutboxRepository) MarkPublished(ctx context.Context, id int64) error {
+	query := `UPDATE outbox_events SET published_at = $2, last_error = '' WHERE id = $1`
+	if _, err := r.conn(ctx).ExecContext(ctx, query, id, time.Now()); err != nil {
+		return errors.Wrap(err, "failed to mark outbox event published")
+	}
+	return nil
+}
+
+// MarkFailed records a failed delivery attempt
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	query := `
+		UPDATE outbox_events
+		SET attempts = attempts + 1, last_error = $2, retry_at = $3
+		WHERE id = $1
+	`
+	if _, err := r.conn(ctx).ExecContext(ctx, query, id, reason, retryAt); err != nil {
+		return errors.Wrap(err, "failed to record outbox delivery failure")
+	}
+	return nil
+}
+
+// Purge deletes events delivered before a time and consumer claims older than it
+func (r *OutboxRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM outbox_events WHERE published_at < $1`, before)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to purge outbox events")
+	}
+	purged, err := result.RowsAffected()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to purge outbox events")
+	}
+	if _, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM consumed_events WHERE consumed_at < $1`, before); err != nil {
+		return purged, errors.Wrap(err, "failed to purge consumed events")
+	}
+	return purged, nil
+}
+
+// Claim records that a consumer handles an event, returning false if it already has
+func (r *OutboxRepository) Claim(ctx context.Context, consumer, eventID string) (bool, error) {
+	query := `
+		INSERT INTO consumed_events (consumer, event_id, consumed_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (consumer, event_id) DO NOTHING
+	`
+	result, err := r.conn(ctx).ExecContext(ctx, query, consumer, eventID, time.Now())
+	if err != nil {
+		return false, errors.Wrap(err, "failed to claim event")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, errors.Wrap(err, "failed to claim event")
+	}
+	return rowsAffected == 1, nil
+}
+
+// Release forgets a consumer's claim on an event
+func (r *OutboxRepository) Release(ctx context.Context, consumer, eventID string) error {
+	query := `DELETE FROM consumed_events WHERE consumer = $1 AND event_id = $2`
+	if _, err := r.conn(ctx).ExecContext(ctx, query, consumer, eventID); err != nil {
+		return errors.Wrap(err, "failed to release event")
+	}
+	return nil
+}
+
+var (
+	_ outbox.Store        = (*OutboxRepository)(nil)
+	_ eventbus.DedupStore = (*OutboxRepository)(nil)
+)
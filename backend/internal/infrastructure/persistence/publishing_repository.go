@@ -0,0 +1,177 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"unlimited-corp/internal/domain/publishing"
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/secret"
+)
+
+// errCredentialsUnavailable is returned for credentials while no secret box is set
+var errCredentialsUnavailable = errors.New(http.StatusServiceUnavailable,
+	"publishing credentials are unavailable: PUBLISHING_ENCRYPTION_KEY is not configured")
+
+// PublishingRepository stores the publishing credentials of companies sealed
+// by its secret box, as a JSON string; stored JSON objects are credentials
+// saved in plaintext before encryption. Without a secret box credentials are
+// neither saved nor read.
+type PublishingRepository struct {
+	db  *sqlx.DB
+	box *secret.Box
+}
+
+// NewPublishingRepository creates a new publishing repository
+func NewPublishingRepository(db *sqlx.DB) *PublishingRepository {
+	return &PublishingRepository{db: db}
+}
+
+// SetSecretBox sets the secret box sealing the credentials
+func (r *PublishingRepository) SetSecretBox(box *secret.Box) {
+	r.box = box
+}
+
+// conn returns the transaction carried by ctx, or the database itself
+func (r *PublishingRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+const credentialColumns = `id, company_id, platform, credentials, created_at, updated_at`
+
+// SaveCredentials creates or replaces a company's credentials for a platform.
+// Replaced credentials keep their ID and creation time, which are set on creds.
+func (r *PublishingRepository) SaveCredentials(ctx context.Context, creds *publishing.Credentials) error {
+	if r.box == nil {
+		return errCredentialsUnavailable
+	}
+	values, err := r.encode(creds.Values)
+	if err != nil {
+		return errors.Wrap(err, "failed to encode publishing credentials")
+	}
+	query := `
+		INSERT INTO publishing_credentials (id, company_id, platform, credentials, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (company_id, platform) DO UPDATE SET
+			credentials = EXCLUDED.credentials,
+			updated_at = EXCLUDED.updated_at
+		RETURNING id, created_at
+	`
+	err = r.conn(ctx).QueryRowContext(ctx, query,
+		creds.ID, creds.CompanyID, creds.Platform, values, creds.CreatedAt, creds.UpdatedAt,
+	).Scan(&creds.ID, &creds.CreatedAt)
+	if err != nil {
+		return errors.Wrap(err, "failed to save publishing credentials")
+	}
+	return nil
+}
+
+// GetCredentials returns a company's credentials for a platform
+func (r *PublishingRepository) GetCredentials(ctx context.Context, companyID uuid.UUID, platform string) (*publishing.Credentials, error) {
+	query := `SELECT ` + credentialColumns + ` FROM publishing_credentials WHERE company_id = $1 AND platform = $2`
+	creds, err := r.scanCredentials(r.conn(ctx).QueryRowContext(ctx, query, companyID, platform))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err == errCredentialsUnavailable {
+		return nil, err
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get publishing credentials")
+	}
+	return creds, nil
+}
+
+// ListCredentials lists a company's credentials ordered by platform
+func (r *PublishingRepository) ListCredentials(ctx context.Context, companyID uuid.UUID) ([]*publishing.Credentials, error) {
+	query := `SELECT ` + credentialColumns + ` FROM publishing_credentials WHERE company_id = $1 ORDER BY platform`
+	rows, err := r.conn(ctx).QueryContext(ctx, query, companyID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list publishing credentials")
+	}
+	defer rows.Close()
+
+	var list []*publishing.Credentials
+	for rows.Next() {
+		creds, err := r.scanCredentials(rows)
+		if err == errCredentialsUnavailable {
+			return nil, err
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan publishing credentials")
+		}
+		list = append(list, creds)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, errors.Wrap(err, "failed to list publishing credentials")
+	}
+	return list, nil
+}
+
+// DeleteCredentials removes a company's credentials for a platform
+func (r *PublishingRepository) DeleteCredentials(ctx context.Context, companyID uuid.UUID, platform string) error {
+	result, err := r.conn(ctx).ExecContext(ctx,
+		`DELETE FROM publishing_credentials WHERE company_id = $1 AND platform = $2`, companyID, platform)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete publishing credentials")
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(err, "failed to get rows affected")
+	}
+	if rowsAffected == 0 {
+		return errors.ErrNotFound
+	}
+	return nil
+}
+
+// encode returns the JSON the credential values are stored as
+func (r *PublishingRepository) encode(values map[string]string) ([]byte, error) {
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := r.box.Seal(plaintext)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sealed)
+}
+
+// decode reads credential values stored by encode
+func (r *PublishingRepository) decode(data []byte, values *map[string]string) error {
+	if r.box == nil {
+		return errCredentialsUnavailable
+	}
+	var sealed string
+	if err := json.Unmarshal(data, &sealed); err != nil {
+		// Credentials saved in plaintext
+		return json.Unmarshal(data, values)
+	}
+	plaintext, err := r.box.Open(sealed)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(plaintext, values)
+}
+
+// scanCredentials scans a row of credentialColumns
+func (r *PublishingRepository) scanCredentials(row rowScanner) (*publishing.Credentials, error) {
+	var creds publishing.Credentials
+	var values []byte
+	if err := row.Scan(&creds.ID, &creds.CompanyID, &creds.Platform, &values, &creds.CreatedAt, &creds.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := r.decode(values, &creds.Values); err != nil {
+		return nil, err
+	}
+	return &creds, nil
+}
+
+// Ensure implementation matches interface
+var _ publishing.CredentialRepository = (*PublishingRepository)(nil)
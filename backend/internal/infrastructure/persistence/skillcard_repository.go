@@ -21,6 +21,11 @@ func NewSkillCardRepository(db *database.DB) *SkillCardRepository {
 	return &SkillCardRepository{db: db}
 }
 
+// conn returns the transaction carried by ctx, or the database itself
+func (r *SkillCardRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db.DB)
+}
+
 // Create creates a new skill card
 func (r *SkillCardRepository) Create(ctx context.Context, s *skillcard.SkillCard) error {
 	query := `
@@ -32,7 +37,7 @@ func (r *SkillCardRepository) Create(ctx context.Context, s *skillcard.SkillCard
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		s.ID, s.CompanyID, s.Name, s.Description, s.Category, s.Icon,
 		s.KernelType, s.KernelConfig, s.InputSchema, s.OutputSchema,
 		s.IsSystem, s.IsPublic, s.Version, s.UsageCount, s.SuccessRate,
@@ -56,7 +61,7 @@ func (r *SkillCardRepository) GetByID(ctx context.Context, id uuid.UUID) (*skill
 	`
 
 	var s skillcard.SkillCard
-	if err := r.db.GetContext(ctx, &s, query, id); err != nil {
+	if err := r.conn(ctx).GetContext(ctx, &s, query, id); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -81,7 +86,7 @@ func (r *SkillCardRepository) GetByCompanyID(ctx context.Context, companyID uuid
 	`
 
 	var cards []*skillcard.SkillCard
-	if err := r.db.SelectContext(ctx, &cards, query, companyID); err != nil {
+	if err := r.conn(ctx).SelectContext(ctx, &cards, query, companyID); err != nil {
 		return nil, fmt.Errorf("failed to get skill cards by company: %w", err)
 	}
 
@@ -99,7 +104,7 @@ func (r *SkillCardRepository) GetSystemCards(ctx context.Context) ([]*skillcard.
 	`
 
 	var cards []*skillcard.SkillCard
-	if err := r.db.SelectContext(ctx, &cards, query); err != nil {
+	if err := r.conn(ctx).SelectContext(ctx, &cards, query); err != nil {
 		return nil, fmt.Errorf("failed to get system skill cards: %w", err)
 	}
 
@@ -117,7 +122,7 @@ func (r *SkillCardRepository) GetPublicCards(ctx context.Context) ([]*skillcard.
 	`
 
 	var cards []*skillcard.SkillCard
-	if err := r.db.SelectContext(ctx, &cards, query); err != nil {
+	if err := r.conn(ctx).SelectContext(ctx, &cards, query); err != nil {
 		return nil, fmt.Errorf("failed to get public skill cards: %w", err)
 	}
 
@@ -134,7 +139,7 @@ func (r *SkillCardRepository) Update(ctx context.Context, s *skillcard.SkillCard
 		WHERE id = $12 AND is_system = false
 	`
 
-	result, err := r.db.ExecContext(ctx, query,
+	result, err := r.conn(ctx).ExecContext(ctx, query,
 		s.Name, s.Description, s.Category, s.Icon,
 		s.KernelType, s.KernelConfig, s.InputSchema, s.OutputSchema,
 		s.IsPublic, s.Version, time.Now(), s.ID,
@@ -159,7 +164,7 @@ func (r *SkillCardRepository) Update(ctx context.Context, s *skillcard.SkillCard
 func (r *SkillCardRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM skill_cards WHERE id = $1 AND is_system = false`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete skill card: %w", err)
 	}
@@ -190,7 +195,7 @@ func (r *SkillCardRepository) GetByCategory(ctx context.Context, companyID uuid.
 	`
 
 	var cards []*skillcard.SkillCard
-	if err := r.db.SelectContext(ctx, &cards, query, category, companyID); err != nil {
+	if err := r.conn(ctx).SelectContext(ctx, &cards, query, category, companyID); err != nil {
 		return nil, fmt.Errorf("failed to get skill cards by category: %w", err)
 	}
 
@@ -212,7 +217,7 @@ func (r *SkillCardRepository) Search(ctx context.Context, companyID uuid.UUID, s
 
 	pattern := "%" + searchQuery + "%"
 	var cards []*skillcard.SkillCard
-	if err := r.db.SelectContext(ctx, &cards, query, companyID, pattern); err != nil {
+	if err := r.conn(ctx).SelectContext(ctx, &cards, query, companyID, pattern); err != nil {
 		return nil, fmt.Errorf("failed to search skill cards: %w", err)
 	}
 
@@ -239,7 +244,7 @@ func (r *SkillCardRepository) IncrementUsage(ctx context.Context, id uuid.UUID,
 		`
 	}
 
-	_, err := r.db.ExecContext(ctx, query, id, time.Now())
+	_, err := r.conn(ctx).ExecContext(ctx, query, id, time.Now())
 	if err != nil {
 		return fmt.Errorf("failed to increment usage: %w", err)
 	}
@@ -0,0 +1,120 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+)
+
+const approvalColumns = `
+	id, task_id, company_id, node_id, COALESCE(node_name, ''), status, content, edited_content,
+	default_action, decided_by, COALESCE(comment, ''), COALESCE(timed_out, false), expires_at, decided_at, created_at
+`
+
+// CreateApproval records a pending approval
+func (r *TaskRepository) CreateApproval(ctx context.Context, a *task.Approval) error {
+	content, _ := json.Marshal(a.Content)
+
+	query := `
+		INSERT INTO task_approvals (id, task_id, company_id, node_id, node_name, status, content,
+			default_action, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		a.ID, a.TaskID, a.CompanyID, a.NodeID, a.NodeName, a.Status, content,
+		a.DefaultAction, a.ExpiresAt, a.CreatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create task approval")
+	}
+	return nil
+}
+
+// GetPendingApproval returns the approval a workflow node waits for
+func (r *TaskRepository) GetPendingApproval(ctx context.Context, taskID uuid.UUID, nodeID string) (*task.Approval, error) {
+	query := `SELECT ` + approvalColumns + ` FROM task_approvals
+		WHERE task_id = $1 AND node_id = $2 AND status = 'pending'`
+
+	a, err := scanApproval(r.conn(ctx).QueryRowContext(ctx, query, taskID, nodeID))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task approval")
+	}
+	return a, nil
+}
+
+// DecideApproval records the decision of a pending approval
+func (r *TaskRepository) DecideApproval(ctx context.Context, a *task.Approval) error {
+	var edited []byte
+	if a.EditedContent != nil {
+		edited, _ = json.Marshal(a.EditedContent)
+	}
+
+	query := `
+		UPDATE task_approvals SET status = $1, edited_content = $2, decided_by = $3, comment = $4,
+			timed_out = $5, decided_at = $6
+		WHERE id = $7 AND status = 'pending'
+	`
+	result, err := r.conn(ctx).ExecContext(ctx, query,
+		a.Status, edited, a.DecidedBy, a.Comment, a.TimedOut, a.DecidedAt, a.ID,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to decide task approval")
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.ErrNotFound
+	}
+	return nil
+}
+
+// ListApprovals lists the approvals of a task, oldest first
+func (r *TaskRepository) ListApprovals(ctx context.Context, taskID uuid.UUID) ([]*task.Approval, error) {
+	query := `SELECT ` + approvalColumns + ` FROM task_approvals
+		WHERE task_id = $1 ORDER BY created_at ASC`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, taskID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list task approvals")
+	}
+	defer rows.Close()
+
+	var approvals []*task.Approval
+	for rows.Next() {
+		a, err := scanApproval(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan task approval")
+		}
+		approvals = append(approvals, a)
+	}
+	return approvals, rows.Err()
+}
+
+// scanApproval scans an approval row selected with approvalColumns
+func scanApproval(row rowScanner) (*task.Approval, error) {
+	var a task.Approval
+	var content, edited []byte
+	err := row.Scan(
+		&a.ID, &a.TaskID, &a.CompanyID, &a.NodeID, &a.NodeName, &a.Status, &content, &edited,
+		&a.DefaultAction, &a.DecidedBy, &a.Comment, &a.TimedOut, &a.ExpiresAt, &a.DecidedAt, &a.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(content, &a.Content)
+	if len(edited) > 0 {
+		json.Unmarshal(edited, &a.EditedContent)
+	}
+	return &a, nil
+}
+
+// Ensure implementation matches interface
+var _ task.ApprovalRepository = (*TaskRepository)(nil)
@@ -0,0 +1,102 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+)
+
+const attemptColumns = `
+	id, task_id, company_id, number, COALESCE(temporal_workflow_id, ''), COALESCE(temporal_run_id, ''),
+	status, COALESCE(error_message, ''), COALESCE(failed_step, ''), step_outputs, COALESCE(retry_from, ''),
+	retry_input, retried_by, started_at, ended_at, retried_at
+`
+
+// CreateAttempt records an earlier run of a retried task
+func (r *TaskRepository) CreateAttempt(ctx context.Context, a *task.Attempt) error {
+	outputs, _ := json.Marshal(a.StepOutputs)
+	var retryInput []byte
+	if a.RetryInput != nil {
+		retryInput, _ = json.Marshal(a.RetryInput)
+	}
+
+	query := `
+		INSERT INTO task_attempts (id, task_id, company_id, number, temporal_workflow_id, temporal_run_id,
+			status, error_message, failed_step, step_outputs, retry_from, retry_input, retried_by,
+			started_at, ended_at, retried_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		a.ID, a.TaskID, a.CompanyID, a.Number, a.WorkflowID, a.RunID,
+		a.Status, a.Error, a.FailedStep, outputs, a.RetryFrom, retryInput, a.RetriedBy,
+		a.StartedAt, a.EndedAt, a.RetriedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create task attempt")
+	}
+	return nil
+}
+
+// GetLatestAttempt returns the attempt the task was last retried from
+func (r *TaskRepository) GetLatestAttempt(ctx context.Context, taskID uuid.UUID) (*task.Attempt, error) {
+	query := `SELECT ` + attemptColumns + ` FROM task_attempts
+		WHERE task_id = $1 ORDER BY number DESC LIMIT 1`
+
+	a, err := scanAttempt(r.conn(ctx).QueryRowContext(ctx, query, taskID))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task attempt")
+	}
+	return a, nil
+}
+
+// ListAttempts lists the earlier runs of a task, oldest first
+func (r *TaskRepository) ListAttempts(ctx context.Context, taskID uuid.UUID) ([]*task.Attempt, error) {
+	query := `SELECT ` + attemptColumns + ` FROM task_attempts
+		WHERE task_id = $1 ORDER BY number ASC`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, taskID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list task attempts")
+	}
+	defer rows.Close()
+
+	var attempts []*task.Attempt
+	for rows.Next() {
+		a, err := scanAttempt(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan task attempt")
+		}
+		attempts = append(attempts, a)
+	}
+	return attempts, rows.Err()
+}
+
+// scanAttempt scans an attempt row selected with attemptColumns
+func scanAttempt(row rowScanner) (*task.Attempt, error) {
+	var a task.Attempt
+	var outputs, retryInput []byte
+	err := row.Scan(
+		&a.ID, &a.TaskID, &a.CompanyID, &a.Number, &a.WorkflowID, &a.RunID,
+		&a.Status, &a.Error, &a.FailedStep, &outputs, &a.RetryFrom,
+		&retryInput, &a.RetriedBy, &a.StartedAt, &a.EndedAt, &a.RetriedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(outputs, &a.StepOutputs)
+	if len(retryInput) > 0 {
+		json.Unmarshal(retryInput, &a.RetryInput)
+	}
+	return &a, nil
+}
+
+// Ensure implementation matches interface
+var _ task.AttemptRepository = (*TaskRepository)(nil)
@@ -0,0 +1,75 @@
+package persistence
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+)
+
+// LockDispatch locks the dispatch state row and loads the fair share clock
+func (r *TaskRepository) LockDispatch(ctx context.Context) (*task.FairShare, error) {
+	if _, err := r.conn(ctx).ExecContext(ctx, `INSERT INTO dispatch_state (id) VALUES (1) ON CONFLICT (id) DO NOTHING`); err != nil {
+		return nil, errors.Wrap(err, "failed to create dispatch state")
+	}
+
+	fair := &task.FairShare{Finish: make(map[uuid.UUID]float64)}
+	query := `SELECT virtual_time FROM dispatch_state WHERE id = 1 FOR UPDATE`
+	if err := r.conn(ctx).GetContext(ctx, &fair.Virtual, query); err != nil {
+		return nil, errors.Wrap(err, "failed to lock dispatch state")
+	}
+
+	rows, err := r.conn(ctx).QueryContext(ctx, `SELECT company_id, finish_tag FROM fair_share_tags WHERE finish_tag > $1`, fair.Virtual)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load fair share tags")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var companyID uuid.UUID
+		var finish float64
+		if err := rows.Scan(&companyID, &finish); err != nil {
+			return nil, errors.Wrap(err, "failed to scan fair share tag")
+		}
+		fair.Finish[companyID] = finish
+	}
+	return fair, rows.Err()
+}
+
+// SaveFairShare stores the virtual time and the finish tags past it
+func (r *TaskRepository) SaveFairShare(ctx context.Context, fair *task.FairShare) error {
+	query := `UPDATE dispatch_state SET virtual_time = $1, updated_at = CURRENT_TIMESTAMP WHERE id = 1`
+	if _, err := r.conn(ctx).ExecContext(ctx, query, fair.Virtual); err != nil {
+		return errors.Wrap(err, "failed to save dispatch state")
+	}
+
+	// Companies whose tags fell behind start at the virtual time anyway
+	if _, err := r.conn(ctx).ExecContext(ctx, `DELETE FROM fair_share_tags WHERE finish_tag <= $1`, fair.Virtual); err != nil {
+		return errors.Wrap(err, "failed to prune fair share tags")
+	}
+
+	var companyIDs []string
+	var finishes []float64
+	for companyID, finish := range fair.Finish {
+		if finish > fair.Virtual {
+			companyIDs = append(companyIDs, companyID.String())
+			finishes = append(finishes, finish)
+		}
+	}
+	if len(companyIDs) == 0 {
+		return nil
+	}
+	query = `
+		INSERT INTO fair_share_tags (company_id, finish_tag)
+		SELECT * FROM unnest($1::uuid[], $2::double precision[])
+		ON CONFLICT (company_id) DO UPDATE SET finish_tag = EXCLUDED.finish_tag
+	`
+	if _, err := r.conn(ctx).ExecContext(ctx, query, pq.Array(companyIDs), pq.Array(finishes)); err != nil {
+		return errors.Wrap(err, "failed to save fair share tags")
+	}
+	return nil
+}
+
+// Ensure implementation matches interface
+var _ task.DispatchRepository = (*TaskRepository)(nil)
@@ -0,0 +1,109 @@
+package persistence
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+)
+
+// AcquireLease creates or replaces the lease of a task
+func (r *TaskRepository) AcquireLease(ctx context.Context, l *task.Lease) error {
+	query := `
+		INSERT INTO task_leases (task_id, company_id, employee_id, expires_at, renewed_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (task_id) DO UPDATE SET
+			employee_id = EXCLUDED.employee_id,
+			expires_at = EXCLUDED.expires_at,
+			renewed_at = EXCLUDED.renewed_at
+	`
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		l.TaskID, l.CompanyID, l.EmployeeID, l.ExpiresAt, l.RenewedAt, l.CreatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to acquire task lease")
+	}
+	return nil
+}
+
+// RenewLease extends a task lease held by the employee
+func (r *TaskRepository) RenewLease(ctx context.Context, taskID, employeeID uuid.UUID, expiresAt time.Time) error {
+	query := `
+		UPDATE task_leases SET expires_at = $1, renewed_at = $2
+		WHERE task_id = $3 AND employee_id = $4
+	`
+	result, err := r.conn(ctx).ExecContext(ctx, query, expiresAt, time.Now(), taskID, employeeID)
+	if err != nil {
+		return errors.Wrap(err, "failed to renew task lease")
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.ErrNotFound
+	}
+	return nil
+}
+
+// ReleaseLease removes the lease of a task
+func (r *TaskRepository) ReleaseLease(ctx context.Context, taskID uuid.UUID) error {
+	query := `DELETE FROM task_leases WHERE task_id = $1`
+	if _, err := r.conn(ctx).ExecContext(ctx, query, taskID); err != nil {
+		return errors.Wrap(err, "failed to release task lease")
+	}
+	return nil
+}
+
+// LockExpiredLeases locks leases expired before now, skipping rows locked elsewhere
+func (r *TaskRepository) LockExpiredLeases(ctx context.Context, now time.Time, limit int) ([]*task.Lease, error) {
+	query := `
+		SELECT task_id, company_id, employee_id, expires_at, renewed_at, created_at
+		FROM task_leases
+		WHERE expires_at <= $1
+		ORDER BY expires_at ASC
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`
+	var leases []*task.Lease
+	if err := r.conn(ctx).SelectContext(ctx, &leases, query, now, limit); err != nil {
+		return nil, errors.Wrap(err, "failed to lock expired task leases")
+	}
+	return leases, nil
+}
+
+// CreateRecovery records a task recovered from an expired lease
+func (r *TaskRepository) CreateRecovery(ctx context.Context, rec *task.Recovery) error {
+	query := `
+		INSERT INTO task_recoveries (id, task_id, company_id, employee_id, action, employee_status,
+			reason, lease_expired_at, recovered_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		rec.ID, rec.TaskID, rec.CompanyID, rec.EmployeeID, rec.Action, rec.EmployeeStatus,
+		rec.Reason, rec.LeaseExpiredAt, rec.RecoveredAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create task recovery")
+	}
+	return nil
+}
+
+// ListRecoveries lists the recoveries of a task, oldest first
+func (r *TaskRepository) ListRecoveries(ctx context.Context, taskID uuid.UUID) ([]*task.Recovery, error) {
+	query := `
+		SELECT id, task_id, company_id, employee_id, action, employee_status,
+			COALESCE(reason, '') AS reason, lease_expired_at, recovered_at
+		FROM task_recoveries
+		WHERE task_id = $1
+		ORDER BY recovered_at ASC
+	`
+	var recoveries []*task.Recovery
+	if err := r.conn(ctx).SelectContext(ctx, &recoveries, query, taskID); err != nil {
+		return nil, errors.Wrap(err, "failed to list task recoveries")
+	}
+	return recoveries, nil
+}
+
+// Ensure implementation matches interface
+var _ task.LeaseRepository = (*TaskRepository)(nil)
@@ -8,6 +8,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/database"
 	"unlimited-corp/pkg/errors"
 )
 
@@ -19,21 +20,27 @@ func NewTaskRepository(db *sqlx.DB) *TaskRepository {
 	return &TaskRepository{db: db}
 }
 
+// conn returns the transaction carried by ctx, or the database itself
+func (r *TaskRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
 func (r *TaskRepository) Create(ctx context.Context, t *task.Task) error {
 	workflow, _ := json.Marshal(t.WorkflowDefinition)
 	inputData, _ := json.Marshal(t.InputData)
 	outputData, _ := json.Marshal(t.OutputData)
+	compensation, _ := json.Marshal(t.Compensation)
 
 	query := `
 		INSERT INTO tasks (id, company_id, title, description, priority, status, progress,
 			workflow_definition, assigned_employee_id, input_data, output_data, error_message,
-			started_at, completed_at, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+			compensation, started_at, completed_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
 	`
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		t.ID, t.CompanyID, t.Title, t.Description, t.Priority, t.Status, t.Progress,
 		workflow, t.AssignedEmployeeID, inputData, outputData, t.ErrorMessage,
-		t.StartedAt, t.CompletedAt, t.CreatedAt, t.UpdatedAt,
+		compensation, t.StartedAt, t.CompletedAt, t.CreatedAt, t.UpdatedAt,
 	)
 	if err != nil {
 		return errors.Wrap(err, "failed to create task")
@@ -44,15 +51,15 @@ func (r *TaskRepository) Create(ctx context.Context, t *task.Task) error {
 func (r *TaskRepository) GetByID(ctx context.Context, id uuid.UUID) (*task.Task, error) {
 	query := `
 		SELECT id, company_id, title, description, priority, status, progress,
-			workflow_definition, assigned_employee_id, input_data, output_data, error_message,
-			started_at, completed_at, created_at, updated_at
+			workflow_definition, assigned_employee_id, COALESCE(temporal_workflow_id, ''), COALESCE(temporal_run_id, ''),
+			input_data, output_data, error_message, compensation, started_at, completed_at, created_at, updated_at
 		FROM tasks WHERE id = $1
 	`
 	var t task.Task
-	var workflow, inputData, outputData []byte
-	err := r.db.QueryRowContext(ctx, query, id).Scan(
+	var workflow, inputData, outputData, compensation []byte
+	err := r.conn(ctx).QueryRowContext(ctx, query, id).Scan(
 		&t.ID, &t.CompanyID, &t.Title, &t.Description, &t.Priority, &t.Status, &t.Progress,
-		&workflow, &t.AssignedEmployeeID, &inputData, &outputData, &t.ErrorMessage,
+		&workflow, &t.AssignedEmployeeID, &t.TemporalWorkflowID, &t.TemporalRunID, &inputData, &outputData, &t.ErrorMessage, &compensation,
 		&t.StartedAt, &t.CompletedAt, &t.CreatedAt, &t.UpdatedAt,
 	)
 	if err == sql.ErrNoRows {
@@ -65,19 +72,49 @@ func (r *TaskRepository) GetByID(ctx context.Context, id uuid.UUID) (*task.Task,
 	json.Unmarshal(workflow, &t.WorkflowDefinition)
 	json.Unmarshal(inputData, &t.InputData)
 	json.Unmarshal(outputData, &t.OutputData)
+	json.Unmarshal(compensation, &t.Compensation)
+	return &t, nil
+}
+
+func (r *TaskRepository) LockPending(ctx context.Context, id uuid.UUID) (*task.Task, error) {
+	query := `
+		SELECT id, company_id, title, description, priority, status, progress,
+			workflow_definition, assigned_employee_id, COALESCE(temporal_workflow_id, ''), COALESCE(temporal_run_id, ''),
+			input_data, output_data, error_message, compensation, started_at, completed_at, created_at, updated_at
+		FROM tasks WHERE id = $1 AND status = 'pending'
+		FOR UPDATE SKIP LOCKED
+	`
+	var t task.Task
+	var workflow, inputData, outputData, compensation []byte
+	err := r.conn(ctx).QueryRowContext(ctx, query, id).Scan(
+		&t.ID, &t.CompanyID, &t.Title, &t.Description, &t.Priority, &t.Status, &t.Progress,
+		&workflow, &t.AssignedEmployeeID, &t.TemporalWorkflowID, &t.TemporalRunID, &inputData, &outputData, &t.ErrorMessage, &compensation,
+		&t.StartedAt, &t.CompletedAt, &t.CreatedAt, &t.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to lock task")
+	}
+
+	json.Unmarshal(workflow, &t.WorkflowDefinition)
+	json.Unmarshal(inputData, &t.InputData)
+	json.Unmarshal(outputData, &t.OutputData)
+	json.Unmarshal(compensation, &t.Compensation)
 	return &t, nil
 }
 
 func (r *TaskRepository) ListByCompanyID(ctx context.Context, companyID uuid.UUID, limit, offset int) ([]*task.Task, error) {
 	query := `
 		SELECT id, company_id, title, description, priority, status, progress,
-			workflow_definition, assigned_employee_id, input_data, output_data, error_message,
-			started_at, completed_at, created_at, updated_at
+			workflow_definition, assigned_employee_id, COALESCE(temporal_workflow_id, ''), COALESCE(temporal_run_id, ''),
+			input_data, output_data, error_message, compensation, started_at, completed_at, created_at, updated_at
 		FROM tasks WHERE company_id = $1
 		ORDER BY created_at DESC
 		LIMIT $2 OFFSET $3
 	`
-	rows, err := r.db.QueryContext(ctx, query, companyID, limit, offset)
+	rows, err := r.conn(ctx).QueryContext(ctx, query, companyID, limit, offset)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to list tasks")
 	}
@@ -86,10 +123,49 @@ func (r *TaskRepository) ListByCompanyID(ctx context.Context, companyID uuid.UUI
 	var tasks []*task.Task
 	for rows.Next() {
 		var t task.Task
-		var workflow, inputData, outputData []byte
+		var workflow, inputData, outputData, compensation []byte
+		err := rows.Scan(
+			&t.ID, &t.CompanyID, &t.Title, &t.Description, &t.Priority, &t.Status, &t.Progress,
+			&workflow, &t.AssignedEmployeeID, &t.TemporalWorkflowID, &t.TemporalRunID, &inputData, &outputData, &t.ErrorMessage, &compensation,
+			&t.StartedAt, &t.CompletedAt, &t.CreatedAt, &t.UpdatedAt,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan task")
+		}
+		json.Unmarshal(workflow, &t.WorkflowDefinition)
+		json.Unmarshal(inputData, &t.InputData)
+		json.Unmarshal(outputData, &t.OutputData)
+		json.Unmarshal(compensation, &t.Compensation)
+		tasks = append(tasks, &t)
+	}
+	return tasks, nil
+}
+
+// ListPending lists pending tasks, most urgent and oldest first
+func (r *TaskRepository) ListPending(ctx context.Context, companyID uuid.UUID, limit int) ([]*task.Task, error) {
+	query := `
+		SELECT id, company_id, title, description, priority, status, progress,
+			workflow_definition, assigned_employee_id, COALESCE(temporal_workflow_id, ''), COALESCE(temporal_run_id, ''),
+			input_data, output_data, error_message, compensation, started_at, completed_at, created_at, updated_at
+		FROM tasks WHERE company_id = $1 AND status = 'pending'
+		ORDER BY CASE priority
+			WHEN 'urgent' THEN 0 WHEN 'high' THEN 1 WHEN 'medium' THEN 2 ELSE 3 END,
+			created_at ASC
+		LIMIT $2
+	`
+	rows, err := r.conn(ctx).QueryContext(ctx, query, companyID, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list pending tasks")
+	}
+	defer rows.Close()
+
+	var tasks []*task.Task
+	for rows.Next() {
+		var t task.Task
+		var workflow, inputData, outputData, compensation []byte
 		err := rows.Scan(
 			&t.ID, &t.CompanyID, &t.Title, &t.Description, &t.Priority, &t.Status, &t.Progress,
-			&workflow, &t.AssignedEmployeeID, &inputData, &outputData, &t.ErrorMessage,
+			&workflow, &t.AssignedEmployeeID, &t.TemporalWorkflowID, &t.TemporalRunID, &inputData, &outputData, &t.ErrorMessage, &compensation,
 			&t.StartedAt, &t.CompletedAt, &t.CreatedAt, &t.UpdatedAt,
 		)
 		if err != nil {
@@ -98,26 +174,108 @@ func (r *TaskRepository) ListByCompanyID(ctx context.Context, companyID uuid.UUI
 		json.Unmarshal(workflow, &t.WorkflowDefinition)
 		json.Unmarshal(inputData, &t.InputData)
 		json.Unmarshal(outputData, &t.OutputData)
+		json.Unmarshal(compensation, &t.Compensation)
 		tasks = append(tasks, &t)
 	}
 	return tasks, nil
 }
 
+// SetWorkflowRun records the workflow run executing a task
+func (r *TaskRepository) SetWorkflowRun(ctx context.Context, id uuid.UUID, workflowID, runID string) error {
+	query := `
+		UPDATE tasks SET temporal_workflow_id = $1, temporal_run_id = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3
+	`
+	result, err := r.conn(ctx).ExecContext(ctx, query, workflowID, runID, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to set task workflow run")
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.ErrNotFound
+	}
+	return nil
+}
+
+// ListActiveWorkflowRuns lists the running and paused tasks whose workflow run ID starts with runIDPrefix
+func (r *TaskRepository) ListActiveWorkflowRuns(ctx context.Context, runIDPrefix string) ([]*task.Task, error) {
+	query := `
+		SELECT id, company_id, title, description, priority, status, progress,
+			workflow_definition, assigned_employee_id, COALESCE(temporal_workflow_id, ''), COALESCE(temporal_run_id, ''),
+			input_data, output_data, error_message, compensation, started_at, completed_at, created_at, updated_at
+		FROM tasks WHERE status IN ('running', 'paused') AND temporal_run_id LIKE $1 || '%'
+		ORDER BY started_at ASC
+	`
+	rows, err := r.conn(ctx).QueryContext(ctx, query, runIDPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list active workflow runs")
+	}
+	defer rows.Close()
+
+	var tasks []*task.Task
+	for rows.Next() {
+		var t task.Task
+		var workflow, inputData, outputData, compensation []byte
+		err := rows.Scan(
+			&t.ID, &t.CompanyID, &t.Title, &t.Description, &t.Priority, &t.Status, &t.Progress,
+			&workflow, &t.AssignedEmployeeID, &t.TemporalWorkflowID, &t.TemporalRunID, &inputData, &outputData, &t.ErrorMessage, &compensation,
+			&t.StartedAt, &t.CompletedAt, &t.CreatedAt, &t.UpdatedAt,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan task")
+		}
+		json.Unmarshal(workflow, &t.WorkflowDefinition)
+		json.Unmarshal(inputData, &t.InputData)
+		json.Unmarshal(outputData, &t.OutputData)
+		json.Unmarshal(compensation, &t.Compensation)
+		tasks = append(tasks, &t)
+	}
+	return tasks, rows.Err()
+}
+
+// ListQueues summarizes the pending tasks of every company that has any
+func (r *TaskRepository) ListQueues(ctx context.Context) ([]*task.Queue, error) {
+	query := `
+		SELECT company_id, COUNT(*) AS depth, MIN(created_at) AS oldest_created_at
+		FROM tasks WHERE status = 'pending'
+		GROUP BY company_id
+	`
+
+	var queues []*task.Queue
+	if err := r.conn(ctx).SelectContext(ctx, &queues, query); err != nil {
+		return nil, errors.Wrap(err, "failed to list task queues")
+	}
+	return queues, nil
+}
+
+// CountRunning counts the running tasks of all companies
+func (r *TaskRepository) CountRunning(ctx context.Context) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM tasks WHERE status = 'running'`
+	if err := r.conn(ctx).GetContext(ctx, &count, query); err != nil {
+		return 0, errors.Wrap(err, "failed to count running tasks")
+	}
+	return count, nil
+}
+
 func (r *TaskRepository) Update(ctx context.Context, t *task.Task) error {
 	workflow, _ := json.Marshal(t.WorkflowDefinition)
 	inputData, _ := json.Marshal(t.InputData)
 	outputData, _ := json.Marshal(t.OutputData)
+	compensation, _ := json.Marshal(t.Compensation)
 
 	query := `
 		UPDATE tasks
 		SET title = $1, description = $2, priority = $3, status = $4, progress = $5,
 			workflow_definition = $6, assigned_employee_id = $7, input_data = $8,
-			output_data = $9, error_message = $10, started_at = $11, completed_at = $12, updated_at = $13
-		WHERE id = $14
+			output_data = $9, error_message = $10, compensation = $11,
+			started_at = $12, completed_at = $13, updated_at = $14
+		WHERE id = $15
 	`
-	result, err := r.db.ExecContext(ctx, query,
+	result, err := r.conn(ctx).ExecContext(ctx, query,
 		t.Title, t.Description, t.Priority, t.Status, t.Progress,
-		workflow, t.AssignedEmployeeID, inputData, outputData, t.ErrorMessage,
+		workflow, t.AssignedEmployeeID, inputData, outputData, t.ErrorMessage, compensation,
 		t.StartedAt, t.CompletedAt, t.UpdatedAt, t.ID,
 	)
 	if err != nil {
@@ -133,7 +291,7 @@ func (r *TaskRepository) Update(ctx context.Context, t *task.Task) error {
 
 func (r *TaskRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM tasks WHERE id = $1`
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return errors.Wrap(err, "failed to delete task")
 	}
@@ -0,0 +1,168 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+)
+
+const scheduleColumns = `
+	id, company_id, name, kind, COALESCE(cron_expression, ''), run_at, timezone, task_template,
+	status, created_by, last_run_at, created_at, updated_at
+`
+
+// CreateSchedule creates a task schedule
+func (r *TaskRepository) CreateSchedule(ctx context.Context, s *task.Schedule) error {
+	template, _ := json.Marshal(s.Template)
+
+	query := `
+		INSERT INTO task_schedules (id, company_id, name, kind, cron_expression, run_at, timezone,
+			task_template, status, created_by, last_run_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+	`
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		s.ID, s.CompanyID, s.Name, s.Kind, s.CronExpression, s.RunAt, s.Timezone,
+		template, s.Status, s.CreatedBy, s.LastRunAt, s.CreatedAt, s.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create task schedule")
+	}
+	return nil
+}
+
+// GetSchedule retrieves a task schedule by ID
+func (r *TaskRepository) GetSchedule(ctx context.Context, id uuid.UUID) (*task.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM task_schedules WHERE id = $1`
+
+	s, err := scanSchedule(r.conn(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task schedule")
+	}
+	return s, nil
+}
+
+// ListSchedules lists the task schedules of a company, newest first
+func (r *TaskRepository) ListSchedules(ctx context.Context, companyID uuid.UUID) ([]*task.Schedule, error) {
+	query := `SELECT ` + scheduleColumns + ` FROM task_schedules
+		WHERE company_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, companyID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list task schedules")
+	}
+	defer rows.Close()
+
+	var schedules []*task.Schedule
+	for rows.Next() {
+		s, err := scanSchedule(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan task schedule")
+		}
+		schedules = append(schedules, s)
+	}
+	return schedules, rows.Err()
+}
+
+// UpdateSchedule updates the state of a task schedule
+func (r *TaskRepository) UpdateSchedule(ctx context.Context, s *task.Schedule) error {
+	query := `
+		UPDATE task_schedules SET status = $1, last_run_at = $2, updated_at = $3
+		WHERE id = $4
+	`
+	result, err := r.conn(ctx).ExecContext(ctx, query, s.Status, s.LastRunAt, s.UpdatedAt, s.ID)
+	if err != nil {
+		return errors.Wrap(err, "failed to update task schedule")
+	}
+
+	rows, _ := result.RowsAffected()
+	if rows == 0 {
+		return errors.ErrNotFound
+	}
+	return nil
+}
+
+// DeleteSchedule deletes a task schedule and its run history
+func (r *TaskRepository) DeleteSchedule(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM task_schedules WHERE id = $1`
+	if _, err := r.conn(ctx).ExecContext(ctx, query, id); err != nil {
+		return errors.Wrap(err, "failed to delete task schedule")
+	}
+	return nil
+}
+
+// CreateScheduleRun records a task started by a schedule
+func (r *TaskRepository) CreateScheduleRun(ctx context.Context, run *task.ScheduleRun) error {
+	query := `
+		INSERT INTO task_schedule_runs (id, schedule_id, company_id, task_id, workflow_id, triggered_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		run.ID, run.ScheduleID, run.CompanyID, run.TaskID, run.WorkflowID, run.TriggeredAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to create task schedule run")
+	}
+	return nil
+}
+
+// GetScheduleRunByWorkflow returns the run a schedule workflow recorded
+func (r *TaskRepository) GetScheduleRunByWorkflow(ctx context.Context, workflowID string) (*task.ScheduleRun, error) {
+	query := `
+		SELECT sr.id, sr.schedule_id, sr.company_id, sr.task_id, sr.workflow_id, t.status AS task_status, sr.triggered_at
+		FROM task_schedule_runs sr
+		JOIN tasks t ON t.id = sr.task_id
+		WHERE sr.workflow_id = $1
+	`
+	var run task.ScheduleRun
+	err := r.conn(ctx).GetContext(ctx, &run, query, workflowID)
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task schedule run")
+	}
+	return &run, nil
+}
+
+// ListScheduleRuns lists the runs of a schedule with the current status of their tasks, newest first
+func (r *TaskRepository) ListScheduleRuns(ctx context.Context, scheduleID uuid.UUID, limit int) ([]*task.ScheduleRun, error) {
+	query := `
+		SELECT sr.id, sr.schedule_id, sr.company_id, sr.task_id, sr.workflow_id, t.status AS task_status, sr.triggered_at
+		FROM task_schedule_runs sr
+		JOIN tasks t ON t.id = sr.task_id
+		WHERE sr.schedule_id = $1
+		ORDER BY sr.triggered_at DESC
+		LIMIT $2
+	`
+	var runs []*task.ScheduleRun
+	if err := r.conn(ctx).SelectContext(ctx, &runs, query, scheduleID, limit); err != nil {
+		return nil, errors.Wrap(err, "failed to list task schedule runs")
+	}
+	return runs, nil
+}
+
+// scanSchedule scans a schedule row selected with scheduleColumns
+func scanSchedule(row rowScanner) (*task.Schedule, error) {
+	var s task.Schedule
+	var template []byte
+	err := row.Scan(
+		&s.ID, &s.CompanyID, &s.Name, &s.Kind, &s.CronExpression, &s.RunAt, &s.Timezone, &template,
+		&s.Status, &s.CreatedBy, &s.LastRunAt, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(template, &s.Template)
+	return &s, nil
+}
+
+// Ensure implementation matches interface
+var _ task.ScheduleRepository = (*TaskRepository)(nil)
@@ -0,0 +1,116 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/pkg/errors"
+)
+
+const stepColumns = `
+	id, task_id, attempt, node_id, step_order, name, skill_card_id, status,
+	input_data, output_data, COALESCE(error_message, ''), executed_by_employee_id, reused,
+	started_at, completed_at, created_at, updated_at
+`
+
+// SaveStep creates the step or updates the one with the same task, attempt and node
+func (r *TaskRepository) SaveStep(ctx context.Context, s *task.Step) error {
+	var input, output []byte
+	if s.Input != nil {
+		input, _ = json.Marshal(s.Input)
+	}
+	if s.Output != nil {
+		output, _ = json.Marshal(s.Output)
+	}
+
+	query := `
+		INSERT INTO task_steps (id, task_id, attempt, node_id, step_order, name, skill_card_id, status,
+			input_data, output_data, error_message, executed_by_employee_id, reused,
+			started_at, completed_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+		ON CONFLICT (task_id, attempt, node_id) DO UPDATE SET
+			status = EXCLUDED.status,
+			input_data = COALESCE(EXCLUDED.input_data, task_steps.input_data),
+			output_data = COALESCE(EXCLUDED.output_data, task_steps.output_data),
+			error_message = EXCLUDED.error_message,
+			executed_by_employee_id = COALESCE(EXCLUDED.executed_by_employee_id, task_steps.executed_by_employee_id),
+			reused = EXCLUDED.reused,
+			started_at = COALESCE(EXCLUDED.started_at, task_steps.started_at),
+			completed_at = EXCLUDED.completed_at,
+			updated_at = EXCLUDED.updated_at
+	`
+	_, err := r.conn(ctx).ExecContext(ctx, query,
+		s.ID, s.TaskID, s.Attempt, s.NodeID, s.Order, s.Name, s.SkillCardID, s.Status,
+		input, output, s.Error, s.ExecutedBy, s.Reused,
+		s.StartedAt, s.CompletedAt, s.CreatedAt, s.UpdatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "failed to save task step")
+	}
+	return nil
+}
+
+// GetStep returns a step of an attempt
+func (r *TaskRepository) GetStep(ctx context.Context, taskID uuid.UUID, attempt int, nodeID string) (*task.Step, error) {
+	query := `SELECT ` + stepColumns + ` FROM task_steps
+		WHERE task_id = $1 AND attempt = $2 AND node_id = $3`
+
+	s, err := scanStep(r.conn(ctx).QueryRowContext(ctx, query, taskID, attempt, nodeID))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get task step")
+	}
+	return s, nil
+}
+
+// ListSteps lists the steps of an attempt in workflow order
+func (r *TaskRepository) ListSteps(ctx context.Context, taskID uuid.UUID, attempt int) ([]*task.Step, error) {
+	query := `SELECT ` + stepColumns + ` FROM task_steps
+		WHERE task_id = $1 AND attempt = $2 ORDER BY step_order ASC`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, taskID, attempt)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list task steps")
+	}
+	defer rows.Close()
+
+	var steps []*task.Step
+	for rows.Next() {
+		s, err := scanStep(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan task step")
+		}
+		steps = append(steps, s)
+	}
+	return steps, rows.Err()
+}
+
+// scanStep scans a step row selected with stepColumns
+func scanStep(row rowScanner) (*task.Step, error) {
+	var s task.Step
+	var input, output []byte
+	err := row.Scan(
+		&s.ID, &s.TaskID, &s.Attempt, &s.NodeID, &s.Order, &s.Name, &s.SkillCardID, &s.Status,
+		&input, &output, &s.Error, &s.ExecutedBy, &s.Reused,
+		&s.StartedAt, &s.CompletedAt, &s.CreatedAt, &s.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(input) > 0 {
+		json.Unmarshal(input, &s.Input)
+	}
+	if len(output) > 0 {
+		json.Unmarshal(output, &s.Output)
+	}
+	return &s, nil
+}
+
+// Ensure implementation matches interface
+var _ task.StepRepository = (*TaskRepository)(nil)
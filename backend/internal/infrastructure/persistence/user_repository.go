@@ -20,6 +20,11 @@ func NewUserRepository(db *database.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
+// conn returns the transaction carried by ctx, or the database itself
+func (r *UserRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db.DB)
+}
+
 // Create 创建用户
 func (r *UserRepository) Create(ctx context.Context, u *user.User) error {
 	query := `
@@ -27,7 +32,7 @@ func (r *UserRepository) Create(ctx context.Context, u *user.User) error {
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 	`
 
-	_, err := r.db.ExecContext(ctx, query,
+	_, err := r.conn(ctx).ExecContext(ctx, query,
 		u.ID, u.Email, u.PasswordHash, u.Nickname, u.AvatarURL, u.Status, u.CreatedAt, u.UpdatedAt,
 	)
 	if err != nil {
@@ -42,7 +47,7 @@ func (r *UserRepository) FindByID(ctx context.Context, id uuid.UUID) (*user.User
 	query := `SELECT * FROM users WHERE id = $1`
 
 	var u user.User
-	if err := r.db.GetContext(ctx, &u, query, id); err != nil {
+	if err := r.conn(ctx).GetContext(ctx, &u, query, id); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -57,7 +62,7 @@ func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*user.U
 	query := `SELECT * FROM users WHERE email = $1`
 
 	var u user.User
-	if err := r.db.GetContext(ctx, &u, query, email); err != nil {
+	if err := r.conn(ctx).GetContext(ctx, &u, query, email); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
 		}
@@ -75,7 +80,7 @@ func (r *UserRepository) Update(ctx context.Context, u *user.User) error {
 		WHERE id = $7
 	`
 
-	result, err := r.db.ExecContext(ctx, query,
+	result, err := r.conn(ctx).ExecContext(ctx, query,
 		u.Email, u.PasswordHash, u.Nickname, u.AvatarURL, u.Status, u.UpdatedAt, u.ID,
 	)
 	if err != nil {
@@ -98,7 +103,7 @@ func (r *UserRepository) Update(ctx context.Context, u *user.User) error {
 func (r *UserRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM users WHERE id = $1`
 
-	result, err := r.db.ExecContext(ctx, query, id)
+	result, err := r.conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete user: %w", err)
 	}
@@ -120,7 +125,7 @@ func (r *UserRepository) ExistsByEmail(ctx context.Context, email string) (bool,
 	query := `SELECT EXISTS(SELECT 1 FROM users WHERE email = $1)`
 
 	var exists bool
-	if err := r.db.GetContext(ctx, &exists, query, email); err != nil {
+	if err := r.conn(ctx).GetContext(ctx, &exists, query, email); err != nil {
 		return false, fmt.Errorf("failed to check email exists: %w", err)
 	}
 
@@ -0,0 +1,271 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/pkg/errors"
+)
+
+// uniqueViolation is the PostgreSQL error code of a unique constraint violation
+const uniqueViolation = "23505"
+
+// WorkflowTemplateRepository stores workflow templates and their versions
+type WorkflowTemplateRepository struct {
+	db *sqlx.DB
+}
+
+// NewWorkflowTemplateRepository creates a new workflow template repository
+func NewWorkflowTemplateRepository(db *sqlx.DB) *WorkflowTemplateRepository {
+	return &WorkflowTemplateRepository{db: db}
+}
+
+// conn returns the transaction carried by ctx, or the database itself
+func (r *WorkflowTemplateRepository) conn(ctx context.Context) database.Querier {
+	return database.Conn(ctx, r.db)
+}
+
+const templateColumns = `
+	t.id, t.company_id, t.key, t.name, COALESCE(t.description, ''), t.category,
+	COALESCE(t.thumbnail, ''), t.is_system, t.is_public, t.created_by, t.created_at, t.updated_at,
+	u.used, u.completed, u.finished
+`
+
+const versionColumns = `
+	v.id, v.template_id, v.version, v.definition, v.param_schema, v.node_count,
+	v.estimated_seconds, COALESCE(v.changelog, ''), v.created_at
+`
+
+// latestVersionJoin joins every template with its highest version
+const latestVersionJoin = `
+	LEFT JOIN LATERAL (
+		SELECT * FROM workflow_template_versions
+		WHERE template_id = t.id
+		ORDER BY major DESC, minor DESC, patch DESC
+		LIMIT 1
+	) v ON true
+`
+
+// usageJoin joins every template with how many tasks were created from it,
+// and how many of those completed and finished
+const usageJoin = `
+	LEFT JOIN LATERAL (
+		SELECT COUNT(*) AS used,
+			COUNT(*) FILTER (WHERE tk.status = 'completed') AS completed,
+			COUNT(*) FILTER (WHERE tk.status IN ('completed', 'failed')) AS finished
+		FROM workflow_template_uses tu
+		JOIN tasks tk ON tk.id = tu.task_id
+		WHERE tu.template_id = t.id
+	) u ON true
+`
+
+// CreateTemplate creates a template with its first version in one transaction
+func (r *WorkflowTemplateRepository) CreateTemplate(ctx context.Context, t *workflow.Template, v *workflow.TemplateVersion) error {
+	return database.NewTxManager(r.db).Do(ctx, func(ctx context.Context) error {
+		query := `
+			INSERT INTO workflow_templates (id, company_id, key, name, description, category, thumbnail,
+				is_system, is_public, created_by, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`
+		_, err := r.conn(ctx).ExecContext(ctx, query,
+			t.ID, t.CompanyID, t.Key, t.Name, t.Description, t.Category, t.Thumbnail,
+			t.IsSystem, t.IsPublic, t.CreatedBy, t.CreatedAt, t.UpdatedAt,
+		)
+		if err != nil {
+			return errors.Wrap(err, "failed to create workflow template")
+		}
+		return r.CreateVersion(ctx, v)
+	})
+}
+
+// GetTemplate returns a template with its usage and latest version
+func (r *WorkflowTemplateRepository) GetTemplate(ctx context.Context, id uuid.UUID) (*workflow.Template, error) {
+	query := `SELECT ` + templateColumns + `, ` + versionColumns + `
+		FROM workflow_templates t ` + usageJoin + latestVersionJoin + `
+		WHERE t.id = $1`
+
+	t, err := scanTemplate(r.conn(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get workflow template")
+	}
+	return t, nil
+}
+
+// ListTemplates lists the system templates, the company's own and the public
+// ones of other companies with their usage and latest versions
+func (r *WorkflowTemplateRepository) ListTemplates(ctx context.Context, companyID uuid.UUID, filter workflow.TemplateFilter) ([]*workflow.Template, error) {
+	query := `SELECT ` + templateColumns + `, ` + versionColumns + `
+		FROM workflow_templates t ` + usageJoin + latestVersionJoin + `
+		WHERE (t.is_system = true OR t.company_id = $1 OR t.is_public = true)
+		  AND ($2 = '' OR t.category = $2)
+		  AND ($3 = ''
+		    OR ($3 = 'official' AND t.is_system = true)
+		    OR ($3 = 'mine' AND t.is_system = false AND t.company_id = $1)
+		    OR ($3 = 'community' AND t.is_system = false AND t.company_id <> $1))
+		ORDER BY t.is_system DESC, (t.company_id = $1) DESC, t.name ASC`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, companyID, filter.Category, filter.Source)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list workflow templates")
+	}
+	defer rows.Close()
+
+	var templates []*workflow.Template
+	for rows.Next() {
+		t, err := scanTemplate(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan workflow template")
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+// CreateVersion publishes a version of a template
+func (r *WorkflowTemplateRepository) CreateVersion(ctx context.Context, v *workflow.TemplateVersion) error {
+	semver, err := workflow.ParseSemVer(v.Version)
+	if err != nil {
+		return errors.WrapWithCode(err, http.StatusBadRequest, err.Error())
+	}
+	definition, _ := json.Marshal(v.Definition)
+	schema, _ := json.Marshal(v.ParamSchema)
+
+	query := `
+		INSERT INTO workflow_template_versions (id, template_id, version, major, minor, patch,
+			definition, param_schema, node_count, estimated_seconds, changelog, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+	`
+	_, err = r.conn(ctx).ExecContext(ctx, query,
+		v.ID, v.TemplateID, v.Version, semver.Major, semver.Minor, semver.Patch,
+		definition, schema, v.NodeCount, v.EstimatedSeconds, v.Changelog, v.CreatedAt,
+	)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+		return errors.New(http.StatusConflict, "version "+v.Version+" already exists")
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to create workflow template version")
+	}
+	return nil
+}
+
+// GetVersion returns a version of a template
+func (r *WorkflowTemplateRepository) GetVersion(ctx context.Context, templateID uuid.UUID, version string) (*workflow.TemplateVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM workflow_template_versions v
+		WHERE v.template_id = $1 AND v.version = $2`
+
+	v, err := scanTemplateVersion(r.conn(ctx).QueryRowContext(ctx, query, templateID, version))
+	if err == sql.ErrNoRows {
+		return nil, errors.ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get workflow template version")
+	}
+	return v, nil
+}
+
+// ListVersions lists the versions of a template, newest first
+func (r *WorkflowTemplateRepository) ListVersions(ctx context.Context, templateID uuid.UUID) ([]*workflow.TemplateVersion, error) {
+	query := `SELECT ` + versionColumns + ` FROM workflow_template_versions v
+		WHERE v.template_id = $1 ORDER BY v.major DESC, v.minor DESC, v.patch DESC`
+
+	rows, err := r.conn(ctx).QueryContext(ctx, query, templateID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list workflow template versions")
+	}
+	defer rows.Close()
+
+	var versions []*workflow.TemplateVersion
+	for rows.Next() {
+		v, err := scanTemplateVersion(rows)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to scan workflow template version")
+		}
+		versions = append(versions, v)
+	}
+	return versions, rows.Err()
+}
+
+// RecordUse records that a task was created from a version of a template
+func (r *WorkflowTemplateRepository) RecordUse(ctx context.Context, v *workflow.TemplateVersion, taskID uuid.UUID) error {
+	query := `
+		INSERT INTO workflow_template_uses (task_id, template_id, version_id)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (task_id) DO NOTHING
+	`
+	if _, err := r.conn(ctx).ExecContext(ctx, query, taskID, v.TemplateID, v.ID); err != nil {
+		return errors.Wrap(err, "failed to record workflow template use")
+	}
+	return nil
+}
+
+// scanTemplate scans a template row selected with templateColumns and the
+// versionColumns of its latest version
+func scanTemplate(row rowScanner) (*workflow.Template, error) {
+	var t workflow.Template
+	var completed, finished int
+	var versionID, versionTemplateID sql.NullString
+	var version, changelog sql.NullString
+	var definition, schema []byte
+	var nodeCount, estimated sql.NullInt64
+	var versionCreatedAt sql.NullTime
+	err := row.Scan(
+		&t.ID, &t.CompanyID, &t.Key, &t.Name, &t.Description, &t.Category,
+		&t.Thumbnail, &t.IsSystem, &t.IsPublic, &t.CreatedBy, &t.CreatedAt, &t.UpdatedAt,
+		&t.UsageCount, &completed, &finished,
+		&versionID, &versionTemplateID, &version, &definition, &schema, &nodeCount,
+		&estimated, &changelog, &versionCreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if finished > 0 {
+		rate := float64(completed) / float64(finished)
+		t.SuccessRate = &rate
+	}
+
+	if versionID.Valid {
+		v := &workflow.TemplateVersion{
+			TemplateID:       t.ID,
+			Version:          version.String,
+			NodeCount:        int(nodeCount.Int64),
+			EstimatedSeconds: int(estimated.Int64),
+			Changelog:        changelog.String,
+			CreatedAt:        versionCreatedAt.Time,
+		}
+		v.ID, _ = uuid.Parse(versionID.String)
+		json.Unmarshal(definition, &v.Definition)
+		json.Unmarshal(schema, &v.ParamSchema)
+		t.Latest = v
+	}
+	return &t, nil
+}
+
+// scanTemplateVersion scans a version row selected with versionColumns
+func scanTemplateVersion(row rowScanner) (*workflow.TemplateVersion, error) {
+	var v workflow.TemplateVersion
+	var definition, schema []byte
+	err := row.Scan(
+		&v.ID, &v.TemplateID, &v.Version, &definition, &schema, &v.NodeCount,
+		&v.EstimatedSeconds, &v.Changelog, &v.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	json.Unmarshal(definition, &v.Definition)
+	json.Unmarshal(schema, &v.ParamSchema)
+	return &v, nil
+}
+
+// Ensure implementation matches interface
+var _ workflow.TemplateRepository = (*WorkflowTemplateRepository)(nil)
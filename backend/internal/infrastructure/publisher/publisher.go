@@ -0,0 +1,127 @@
+// Package publisher adapts publishing drivers to platforms: an adapter
+// checks a platform's format constraints and hands the post to its driver,
+// which is a webhook or a local stub.
+package publisher
+
+import (
+	"context"
+	"fmt"
+
+	"unlimited-corp/internal/domain/publishing"
+	"unlimited-corp/internal/infrastructure/config"
+)
+
+// Keys of the credential values drivers read
+const (
+	CredentialWebhookURL = "webhook_url" // overrides the configured webhook URL
+	CredentialToken      = "token"       // sent as a bearer token
+	CredentialSecret     = "secret"      // signs webhook requests
+)
+
+// Driver sends posts somewhere; adapters check them first
+type Driver interface {
+	Publish(ctx context.Context, platform string, creds *publishing.Credentials, post *publishing.Post, mode publishing.Mode) (*publishing.Result, error)
+	Unpublish(ctx context.Context, platform string, creds *publishing.Credentials, postID string) error
+}
+
+// Adapter publishes to one platform through a driver, rejecting posts that
+// do not fit the platform
+type Adapter struct {
+	platform    string
+	constraints publishing.Constraints
+	driver      Driver
+}
+
+// NewAdapter creates the adapter of a platform
+func NewAdapter(platform string, constraints publishing.Constraints, driver Driver) *Adapter {
+	return &Adapter{platform: platform, constraints: constraints, driver: driver}
+}
+
+// Platform returns the platform's name
+func (a *Adapter) Platform() string {
+	return a.platform
+}
+
+// Constraints returns the formats the platform accepts
+func (a *Adapter) Constraints() publishing.Constraints {
+	return a.constraints
+}
+
+// Publish checks the post against the platform's constraints and publishes it
+func (a *Adapter) Publish(ctx context.Context, creds *publishing.Credentials, post *publishing.Post, mode publishing.Mode) (*publishing.Result, error) {
+	if problems := a.constraints.Check(post); len(problems) > 0 {
+		return nil, &publishing.ConstraintError{Platform: a.platform, Problems: problems}
+	}
+	if !mode.IsValid() {
+		return nil, fmt.Errorf("unknown publish mode %q", mode)
+	}
+	result, err := a.driver.Publish(ctx, a.platform, creds, post, mode)
+	if err != nil {
+		return nil, err
+	}
+	result.Platform = a.platform
+	result.Mode = mode
+	return result, nil
+}
+
+// Unpublish takes down a post
+func (a *Adapter) Unpublish(ctx context.Context, creds *publishing.Credentials, postID string) error {
+	return a.driver.Unpublish(ctx, a.platform, creds, postID)
+}
+
+// Registry holds the adapters of the configured platforms
+type Registry struct {
+	publishers       []publishing.Publisher
+	byPlatform       map[string]publishing.Publisher
+	defaultPlatforms []string
+}
+
+// NewRegistry creates an adapter for every configured platform. The
+// platforms using the stub driver share one stub.
+func NewRegistry(cfg config.PublishingConfig) (*Registry, error) {
+	r := &Registry{byPlatform: make(map[string]publishing.Publisher)}
+	stub := NewStub()
+	for _, p := range cfg.Platforms {
+		if p.Name == "" {
+			return nil, fmt.Errorf("publishing platform has no name")
+		}
+		if _, ok := r.byPlatform[p.Name]; ok {
+			return nil, fmt.Errorf("publishing platform %s is configured twice", p.Name)
+		}
+		var driver Driver
+		switch p.Driver {
+		case config.PublishDriverStub:
+			driver = stub
+		case config.PublishDriverWebhook:
+			driver = NewWebhook(p.URL, p.Timeout)
+		default:
+			return nil, fmt.Errorf("publishing platform %s: unknown driver %q", p.Name, p.Driver)
+		}
+		adapter := NewAdapter(p.Name, publishing.PlatformConstraints[p.Name], driver)
+		r.publishers = append(r.publishers, adapter)
+		r.byPlatform[p.Name] = adapter
+	}
+	for _, platform := range cfg.DefaultPlatforms {
+		if _, ok := r.byPlatform[platform]; !ok {
+			return nil, fmt.Errorf("default publishing platform %s is not configured", platform)
+		}
+	}
+	r.defaultPlatforms = cfg.DefaultPlatforms
+	return r, nil
+}
+
+// Publisher returns the adapter of a platform
+func (r *Registry) Publisher(platform string) (publishing.Publisher, bool) {
+	p, ok := r.byPlatform[platform]
+	return p, ok
+}
+
+// Publishers returns the adapters in configuration order
+func (r *Registry) Publishers() []publishing.Publisher {
+	return r.publishers
+}
+
+// DefaultPlatforms returns the platforms content goes to when none are given
+func (r *Registry) DefaultPlatforms() []string {
+	return r.defaultPlatforms
+}
@@ -0,0 +1,205 @@
+package publisher
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"unlimited-corp/internal/domain/publishing"
+	"unlimited-corp/internal/infrastructure/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// post fits every platform with built-in constraints
+var post = &publishing.Post{
+	Title:  "五个效率工具",
+	Body:   "正文",
+	Tags:   []string{"效率"},
+	Images: []string{"https://cdn.example.com/a.png"},
+}
+
+func TestAdapter_RejectsContentThatDoesNotFit(t *testing.T) {
+	stub := NewStub()
+	adapter := NewAdapter("xiaohongshu", publishing.PlatformConstraints["xiaohongshu"], stub)
+
+	_, err := adapter.Publish(context.Background(), nil, &publishing.Post{Body: "正文"}, publishing.ModePublish)
+	var constraintErr *publishing.ConstraintError
+	require.ErrorAs(t, err, &constraintErr)
+	assert.Equal(t, "xiaohongshu", constraintErr.Platform)
+	assert.Len(t, constraintErr.Problems, 2)
+
+	_, err = adapter.Publish(context.Background(), nil, post, publishing.Mode("schedule"))
+	assert.EqualError(t, err, `unknown publish mode "schedule"`)
+}
+
+func TestStub(t *testing.T) {
+	stub := NewStub()
+	adapter := NewAdapter("douyin", publishing.PlatformConstraints["douyin"], stub)
+	ctx := context.Background()
+
+	result, err := adapter.Publish(ctx, nil, post, publishing.ModeDraft)
+	require.NoError(t, err)
+	assert.Equal(t, "douyin", result.Platform)
+	assert.Equal(t, publishing.ModeDraft, result.Mode)
+	assert.Equal(t, "stub://douyin/posts/"+result.PostID, result.URL)
+
+	held, ok := stub.Post(result.PostID)
+	require.True(t, ok)
+	assert.Equal(t, publishing.ModeDraft, held.Mode)
+
+	require.NoError(t, adapter.Unpublish(ctx, nil, result.PostID))
+	_, ok = stub.Post(result.PostID)
+	assert.False(t, ok)
+	assert.Error(t, adapter.Unpublish(ctx, nil, result.PostID))
+}
+
+func TestWebhook(t *testing.T) {
+	var received []webhookRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.Equal(t, "Bearer company-token", r.Header.Get("Authorization"))
+		assert.Equal(t, Sign("s3cret", body), r.Header.Get("X-Signature"))
+
+		var req webhookRequest
+		require.NoError(t, json.Unmarshal(body, &req))
+		received = append(received, req)
+		if req.Action == "publish" {
+			_, _ = w.Write([]byte(`{"post_id":"note-1","url":"https://www.xiaohongshu.com/explore/note-1"}`))
+		}
+	}))
+	defer server.Close()
+
+	creds := publishing.NewCredentials(uuid.New(), "xiaohongshu", map[string]string{
+		CredentialWebhookURL: server.URL,
+		CredentialToken:      "company-token",
+		CredentialSecret:     "s3cret",
+	})
+	webhook := NewWebhook("", 0)
+	// The test server listens on loopback, which company webhooks may not reach
+	webhook.companyClient = server.Client()
+	adapter := NewAdapter("xiaohongshu", publishing.PlatformConstraints["xiaohongshu"], webhook)
+	ctx := context.Background()
+
+	result, err := adapter.Publish(ctx, creds, post, publishing.ModePublish)
+	require.NoError(t, err)
+	assert.Equal(t, &publishing.Result{
+		Platform: "xiaohongshu",
+		PostID:   "note-1",
+		URL:      "https://www.xiaohongshu.com/explore/note-1",
+		Mode:     publishing.ModePublish,
+	}, result)
+
+	require.NoError(t, adapter.Unpublish(ctx, creds, "note-1"))
+	require.Len(t, received, 2)
+	assert.Equal(t, "publish", received[0].Action)
+	assert.Equal(t, post.Title, received[0].Post.Title)
+	assert.Equal(t, webhookRequest{Action: "unpublish", Platform: "xiaohongshu", PostID: "note-1"}, received[1])
+}
+
+func TestWebhook_Failures(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "rate limited", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+	ctx := context.Background()
+
+	_, err := NewWebhook("", 0).Publish(ctx, "weibo", nil, post, publishing.ModePublish)
+	assert.EqualError(t, err, "no webhook URL is configured for weibo")
+
+	_, err = NewWebhook(server.URL, 0).Publish(ctx, "weibo", nil, post, publishing.ModePublish)
+	assert.EqualError(t, err, "webhook returned status 429: rate limited")
+}
+
+func TestWebhook_CompanyURLsReachPublicAddressesOnly(t *testing.T) {
+	var called bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	creds := publishing.NewCredentials(uuid.New(), "weibo", map[string]string{CredentialWebhookURL: server.URL})
+	_, err := NewWebhook("", 0).Publish(context.Background(), "weibo", creds, post, publishing.ModePublish)
+	assert.ErrorContains(t, err, "is not public")
+	assert.False(t, called)
+}
+
+func TestIsPublicIP(t *testing.T) {
+	for _, addr := range []string{"8.8.8.8", "2001:4860:4860::8888"} {
+		assert.True(t, IsPublicIP(net.ParseIP(addr)), addr)
+	}
+	for _, addr := range []string{
+		"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254",
+		"100.100.100.200", "0.0.0.0", "::1", "fe80::1", "fd00:ec2::254", "::ffff:127.0.0.1",
+	} {
+		assert.False(t, IsPublicIP(net.ParseIP(addr)), addr)
+	}
+}
+
+func TestCheckWebhookURL(t *testing.T) {
+	ctx := context.Background()
+	assert.NoError(t, CheckWebhookURL(ctx, "https://93.184.215.14/hooks/publish"))
+	assert.EqualError(t, CheckWebhookURL(ctx, "ftp://example.com"), "must be an http(s) URL")
+	assert.EqualError(t, CheckWebhookURL(ctx, "http://169.254.169.254/latest/meta-data"), "host 169.254.169.254 is not a public address")
+	assert.EqualError(t, CheckWebhookURL(ctx, "http://[::1]:8080/hook"), "host ::1 is not a public address")
+	assert.EqualError(t, CheckWebhookURL(ctx, "http://localhost/hook"), "host localhost is not a public address")
+}
+
+func TestNewRegistry(t *testing.T) {
+	registry, err := NewRegistry(config.PublishingConfig{
+		DefaultPlatforms: []string{"xiaohongshu"},
+		Platforms: []config.PublishingPlatformConfig{
+			{Name: "xiaohongshu", Driver: config.PublishDriverStub},
+			{Name: "blog", Driver: config.PublishDriverWebhook, URL: "https://blog.example.com/hooks/publish"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"xiaohongshu"}, registry.DefaultPlatforms())
+	require.Len(t, registry.Publishers(), 2)
+
+	xiaohongshu, ok := registry.Publisher("xiaohongshu")
+	require.True(t, ok)
+	assert.Equal(t, 20, xiaohongshu.Constraints().MaxTitleLength)
+	blog, ok := registry.Publisher("blog")
+	require.True(t, ok)
+	assert.Equal(t, publishing.Constraints{}, blog.Constraints(), "unknown platforms have no constraints")
+	_, ok = registry.Publisher("douyin")
+	assert.False(t, ok)
+
+	tests := []struct {
+		name string
+		cfg  config.PublishingConfig
+		want string
+	}{
+		{
+			name: "unknown driver",
+			cfg:  config.PublishingConfig{Platforms: []config.PublishingPlatformConfig{{Name: "weibo", Driver: "carrier-pigeon"}}},
+			want: `publishing platform weibo: unknown driver "carrier-pigeon"`,
+		},
+		{
+			name: "duplicate",
+			cfg: config.PublishingConfig{Platforms: []config.PublishingPlatformConfig{
+				{Name: "weibo", Driver: config.PublishDriverStub},
+				{Name: "weibo", Driver: config.PublishDriverStub},
+			}},
+			want: "publishing platform weibo is configured twice",
+		},
+		{
+			name: "default not configured",
+			cfg:  config.PublishingConfig{DefaultPlatforms: []string{"weibo"}},
+			want: "default publishing platform weibo is not configured",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRegistry(tt.cfg)
+			assert.EqualError(t, err, tt.want)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"unlimited-corp/internal/domain/publishing"
+
+	"github.com/google/uuid"
+)
+
+// Stub keeps published posts in memory, so the publishing flow can run
+// without any platform. Posts get stub:// URLs.
+type Stub struct {
+	mu    sync.Mutex
+	posts map[string]StubPost
+}
+
+// StubPost is a post the stub holds
+type StubPost struct {
+	Platform    string
+	Post        publishing.Post
+	Mode        publishing.Mode
+	PublishedAt time.Time
+}
+
+// NewStub creates an empty stub
+func NewStub() *Stub {
+	return &Stub{posts: make(map[string]StubPost)}
+}
+
+// Publish stores the post under a new ID
+func (s *Stub) Publish(_ context.Context, platform string, _ *publishing.Credentials, post *publishing.Post, mode publishing.Mode) (*publishing.Result, error) {
+	id := uuid.New().String()
+	s.mu.Lock()
+	s.posts[id] = StubPost{Platform: platform, Post: *post, Mode: mode, PublishedAt: time.Now()}
+	s.mu.Unlock()
+	return &publishing.Result{PostID: id, URL: fmt.Sprintf("stub://%s/posts/%s", platform, id)}, nil
+}
+
+// Unpublish removes a post
+func (s *Stub) Unpublish(_ context.Context, platform string, _ *publishing.Credentials, postID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p, ok := s.posts[postID]; !ok || p.Platform != platform {
+		return fmt.Errorf("post %s is not on %s", postID, platform)
+	}
+	delete(s.posts, postID)
+	return nil
+}
+
+// Post returns a post the stub holds
+func (s *Stub) Post(postID string) (StubPost, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p, ok := s.posts[postID]
+	return p, ok
+}
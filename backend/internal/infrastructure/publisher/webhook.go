@@ -0,0 +1,130 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"unlimited-corp/internal/domain/publishing"
+)
+
+// defaultWebhookTimeout is used when the platform configures no timeout
+const defaultWebhookTimeout = 10 * time.Second
+
+// maxWebhookResponseSize is the largest webhook response read
+const maxWebhookResponseSize = 1 << 20
+
+// Webhook posts publish and unpublish requests as JSON to a URL, where a
+// service of the company's own does the actual publishing. Requests carry
+// the company's token as a bearer token and, when the credentials have a
+// secret, an X-Signature header: sha256= followed by the hex HMAC-SHA256
+// of the body.
+//
+// The configured URL is trusted and may be internal; a company's own
+// webhook_url may only reach public addresses.
+type Webhook struct {
+	url           string
+	client        *http.Client
+	companyClient *http.Client
+}
+
+// NewWebhook creates a webhook driver; url may be empty when every company
+// sets its own webhook_url
+func NewWebhook(url string, timeout time.Duration) *Webhook {
+	if timeout <= 0 {
+		timeout = defaultWebhookTimeout
+	}
+	return &Webhook{url: url, client: &http.Client{Timeout: timeout}, companyClient: newPublicClient(timeout)}
+}
+
+// webhookRequest is the body of a webhook request
+type webhookRequest struct {
+	Action   string           `json:"action"` // publish or unpublish
+	Platform string           `json:"platform"`
+	Mode     publishing.Mode  `json:"mode,omitempty"`
+	Post     *publishing.Post `json:"post,omitempty"`
+	PostID   string           `json:"post_id,omitempty"`
+}
+
+// webhookResponse is what a webhook answers a publish request with
+type webhookResponse struct {
+	PostID string `json:"post_id"`
+	URL    string `json:"url"`
+}
+
+// Publish asks the webhook to publish a post
+func (w *Webhook) Publish(ctx context.Context, platform string, creds *publishing.Credentials, post *publishing.Post, mode publishing.Mode) (*publishing.Result, error) {
+	body, err := w.send(ctx, creds, webhookRequest{Action: "publish", Platform: platform, Mode: mode, Post: post})
+	if err != nil {
+		return nil, err
+	}
+	var resp webhookResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("invalid webhook response: %w", err)
+	}
+	if resp.PostID == "" {
+		return nil, fmt.Errorf("the webhook response has no post_id")
+	}
+	return &publishing.Result{PostID: resp.PostID, URL: resp.URL}, nil
+}
+
+// Unpublish asks the webhook to take down a post
+func (w *Webhook) Unpublish(ctx context.Context, platform string, creds *publishing.Credentials, postID string) error {
+	_, err := w.send(ctx, creds, webhookRequest{Action: "unpublish", Platform: platform, PostID: postID})
+	return err
+}
+
+// send posts a request and returns the body of a 2xx response
+func (w *Webhook) send(ctx context.Context, creds *publishing.Credentials, request webhookRequest) ([]byte, error) {
+	url, client := creds.Get(CredentialWebhookURL), w.companyClient
+	if url == "" {
+		url, client = w.url, w.client
+	}
+	if url == "" {
+		return nil, fmt.Errorf("no webhook URL is configured for %s", request.Platform)
+	}
+
+	payload, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := creds.Get(CredentialToken); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if secret := creds.Get(CredentialSecret); secret != "" {
+		req.Header.Set("X-Signature", Sign(secret, payload))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxWebhookResponseSize))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return body, nil
+}
+
+// Sign returns the X-Signature of a webhook request body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
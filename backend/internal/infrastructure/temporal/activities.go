@@ -2,8 +2,24 @@ package temporal
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+
+	"unlimited-corp/internal/application/executor"
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/domain/hotspot"
+	"unlimited-corp/internal/domain/publishing"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/database"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.temporal.io/sdk/activity"
+	"go.temporal.io/sdk/temporal"
 )
 
 // SkillExecutionInput represents input for skill execution activity
@@ -25,16 +41,22 @@ type SkillExecutionResult struct {
 
 // TaskStatusInput represents input for task status update activity
 type TaskStatusInput struct {
-	TaskID   string                 `json:"taskId"`
-	Status   string                 `json:"status"`
-	Progress int                    `json:"progress"`
-	Output   map[string]interface{} `json:"output,omitempty"`
+	TaskID     string                 `json:"taskId"`
+	EmployeeID string                 `json:"employeeId,omitempty"`
+	Status     string                 `json:"status"`
+	Progress   int                    `json:"progress"`
+	Output     map[string]interface{} `json:"output,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	// Compensation is how undoing the completed steps of a failed or
+	// cancelled task ended
+	Compensation *task.Compensation `json:"compensation,omitempty"`
 }
 
 // HotspotAnalysisInput represents input for hotspot analysis activity
 type HotspotAnalysisInput struct {
-	Platform string `json:"platform"`
-	Category string `json:"category"`
+	CompanyID string `json:"companyId"`
+	Platform  string `json:"platform"`
+	Category  string `json:"category"`
 }
 
 // ContentSuggestionInput represents input for content suggestion activity
@@ -44,81 +66,722 @@ type ContentSuggestionInput struct {
 
 // PublishInput represents input for content publish activity
 type PublishInput struct {
+	CompanyID string                 `json:"companyId"`
 	Content   map[string]interface{} `json:"content"`
-	Platforms []string               `json:"platforms"`
+	// Platforms default to the configured default platforms
+	Platforms []string `json:"platforms,omitempty"`
+	// Mode is publish (default) or draft
+	Mode string `json:"mode,omitempty"`
+}
+
+// UnpublishInput represents input for content unpublish activity, which
+// takes back content from the platforms it went out on
+type UnpublishInput struct {
+	CompanyID   string                 `json:"companyId"`
+	Platforms   []string               `json:"platforms"`
+	Publication map[string]interface{} `json:"publication"`
+}
+
+// ApprovalRequestInput represents input for approval request activity
+type ApprovalRequestInput struct {
+	TaskID        string                 `json:"taskId"`
+	CompanyID     string                 `json:"companyId"`
+	NodeID        string                 `json:"nodeId"`
+	NodeName      string                 `json:"nodeName"`
+	Content       map[string]interface{} `json:"content"`
+	DefaultAction string                 `json:"defaultAction"`
+	ExpiresAt     time.Time              `json:"expiresAt"`
+}
+
+// ApprovalDecisionInput represents input for approval record activity
+type ApprovalDecisionInput struct {
+	TaskID   string           `json:"taskId"`
+	Decision ApprovalDecision `json:"decision"`
+	TimedOut bool             `json:"timedOut"`
+}
+
+// SpawnScheduledTaskInput represents input for scheduled task spawn activity
+type SpawnScheduledTaskInput struct {
+	ScheduleID  string    `json:"scheduleId"`
+	WorkflowID  string    `json:"workflowId"`
+	TriggeredAt time.Time `json:"triggeredAt"`
+}
+
+// StepRecordInput represents input for step record activity
+type StepRecordInput struct {
+	TaskID      string                 `json:"taskId"`
+	Attempt     int                    `json:"attempt"`
+	NodeID      string                 `json:"nodeId"`
+	Order       int                    `json:"order"`
+	Name        string                 `json:"name"`
+	SkillCardID string                 `json:"skillCardId,omitempty"`
+	EmployeeID  string                 `json:"employeeId,omitempty"`
+	Status      string                 `json:"status"`
+	Input       map[string]interface{} `json:"input,omitempty"`
+	Output      map[string]interface{} `json:"output,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	Reused      bool                   `json:"reused,omitempty"`
+	At          time.Time              `json:"at"`
 }
 
-// Activities holds references to services needed for activity execution
+// HotspotAnalyzer scores a platform's hotspots against the previous
+// snapshot; implemented by the hotspot service
+type HotspotAnalyzer interface {
+	Analyze(ctx context.Context, companyID uuid.UUID, platform, category string) (*hotspot.Snapshot, error)
+}
+
+// ContentPublisher publishes content with a company's platform credentials;
+// implemented by the publishing service
+type ContentPublisher interface {
+	Publish(ctx context.Context, companyID uuid.UUID, platform string, post *publishing.Post, mode publishing.Mode) (*publishing.Result, error)
+	Unpublish(ctx context.Context, companyID uuid.UUID, platform, postID string) error
+	DefaultPlatforms() []string
+}
+
+// SkillExecutor runs skill cards; implemented by executor.SkillExecutor
+type SkillExecutor interface {
+	Execute(ctx context.Context, execCtx *executor.ExecutionContext) (*executor.ExecutionResult, error)
+}
+
+// defaultHeartbeatInterval is used when the activity has no heartbeat timeout
+const defaultHeartbeatInterval = 10 * time.Second
+
+// Activities holds the services activities run against. Register it with
+// RegisterActivities so every activity method is registered.
 type Activities struct {
-	// SkillExecutor handles skill card execution
-	// TaskService handles task updates
-	// These will be injected when activities are registered
+	executor  SkillExecutor
+	tasks     task.Repository
+	employees employee.Repository
+	eventBus  eventbus.EventBus
+
+	leases   task.LeaseRepository
+	leaseTTL time.Duration
+
+	approvals task.ApprovalRepository
+	steps     task.StepRepository
+
+	schedules task.ScheduleRepository
+	uow       database.UnitOfWork
+
+	hotspots  HotspotAnalyzer
+	publisher ContentPublisher
+
+	// heartbeatInterval overrides the interval derived from the heartbeat timeout
+	heartbeatInterval time.Duration
 }
 
 // NewActivities creates a new Activities instance
-func NewActivities() *Activities {
-	return &Activities{}
+func NewActivities(skillExecutor SkillExecutor, tasks task.Repository, employees employee.Repository, eventBus eventbus.EventBus) *Activities {
+	return &Activities{
+		executor:  skillExecutor,
+		tasks:     tasks,
+		employees: employees,
+		eventBus:  eventBus,
+	}
+}
+
+// activityRegistry is implemented by workers and the SDK test environments
+type activityRegistry interface {
+	RegisterActivityWithOptions(a interface{}, options activity.RegisterOptions)
+}
+
+// RegisterActivities registers the activity methods of a, skipping its setters
+func RegisterActivities(registry activityRegistry, a *Activities) {
+	registry.RegisterActivityWithOptions(a, activity.RegisterOptions{SkipInvalidStructFunctions: true})
+}
+
+// SetLeases makes activity heartbeats also renew the scheduler's task lease,
+// so long-running executions are not recovered as stuck
+func (a *Activities) SetLeases(leases task.LeaseRepository, ttl time.Duration) {
+	a.leases = leases
+	a.leaseTTL = ttl
+}
+
+// SetApprovals sets the repository recording the approvals workflows wait for
+func (a *Activities) SetApprovals(approvals task.ApprovalRepository) {
+	a.approvals = approvals
+}
+
+// SetSteps sets the repository recording the steps of workflow runs
+func (a *Activities) SetSteps(steps task.StepRepository) {
+	a.steps = steps
+}
+
+// SetSchedules sets the repository of task schedules and the unit of work
+// a scheduled task is started in
+func (a *Activities) SetSchedules(schedules task.ScheduleRepository, uow database.UnitOfWork) {
+	a.schedules = schedules
+	a.uow = uow
+}
+
+// SetUnitOfWork makes activities write task changes and the events
+// announcing them in one transaction
+func (a *Activities) SetUnitOfWork(uow database.UnitOfWork) {
+	a.uow = uow
+}
+
+// inTransaction runs fn in the unit of work, or directly when none is configured
+func (a *Activities) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if a.uow == nil {
+		return fn(ctx)
+	}
+	return a.uow.Do(ctx, fn)
 }
 
-// ExecuteSkillActivity executes a skill card
-func ExecuteSkillActivity(ctx context.Context, input SkillExecutionInput) (*SkillExecutionResult, error) {
+// SetHotspots sets the analyzer of the hotspot activity
+func (a *Activities) SetHotspots(hotspots HotspotAnalyzer) {
+	a.hotspots = hotspots
+}
+
+// SetPublisher sets the publisher of the content publish activities
+func (a *Activities) SetPublisher(publisher ContentPublisher) {
+	a.publisher = publisher
+}
+
+// ExecuteSkillActivity executes a skill card with the SkillExecutor
+func (a *Activities) ExecuteSkillActivity(ctx context.Context, input SkillExecutionInput) (*SkillExecutionResult, error) {
 	start := time.Now()
 
-	// TODO: Integrate with actual SkillExecutor service
-	// For now, return mock result
-	result := &SkillExecutionResult{
-		Success: true,
-		Output: map[string]interface{}{
-			"title":   "AI生成的内容标题",
-			"content": "这是AI根据您的要求生成的内容。在实际实现中，这里会调用OpenAI或其他AI API来生成真实内容。",
-			"tags":    []string{"AI生成", "自动化", "内容创作"},
-		},
-		TokensUsed:    150,
+	taskID, err := uuid.Parse(input.TaskID)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("invalid task id", "InvalidInput", err)
+	}
+	skillCardID, err := uuid.Parse(input.SkillCardID)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("invalid skill card id", "InvalidInput", err)
+	}
+	employeeID, err := a.resolveEmployee(ctx, taskID, input.EmployeeID)
+	if err != nil {
+		return nil, err
+	}
+
+	stop := a.keepAlive(ctx, taskID, employeeID)
+	defer stop()
+
+	execResult, err := a.executor.Execute(ctx, &executor.ExecutionContext{
+		TaskID:      taskID,
+		EmployeeID:  employeeID,
+		SkillCardID: skillCardID,
+		Input:       input.Parameters,
+	})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return nil, temporal.NewNonRetryableApplicationError("skill card not found", "NotFound", err)
+		}
+		return nil, err
+	}
+
+	return &SkillExecutionResult{
+		Success:       execResult.Success,
+		Output:        decodeOutput(execResult.Output),
+		Error:         execResult.Error,
+		TokensUsed:    execResult.TokensUsed,
 		ExecutionTime: time.Since(start).Milliseconds(),
+	}, nil
+}
+
+// resolveEmployee parses the employee of the input, falling back to the task's assignee
+func (a *Activities) resolveEmployee(ctx context.Context, taskID uuid.UUID, employeeID string) (uuid.UUID, error) {
+	if employeeID != "" {
+		id, err := uuid.Parse(employeeID)
+		if err != nil {
+			return uuid.Nil, temporal.NewNonRetryableApplicationError("invalid employee id", "InvalidInput", err)
+		}
+		return id, nil
 	}
 
-	return result, nil
+	t, err := a.tasks.GetByID(ctx, taskID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return uuid.Nil, temporal.NewNonRetryableApplicationError("task not found", "NotFound", err)
+		}
+		return uuid.Nil, err
+	}
+	if t.AssignedEmployeeID == nil {
+		return uuid.Nil, nil
+	}
+	return *t.AssignedEmployeeID, nil
 }
 
-// UpdateTaskStatusActivity updates task status in the database
-func UpdateTaskStatusActivity(ctx context.Context, input TaskStatusInput) error {
-	// TODO: Integrate with TaskService to update task status
-	// For now, just log the update
-	fmt.Printf("Updating task %s: status=%s, progress=%d\n", input.TaskID, input.Status, input.Progress)
-	return nil
+// keepAlive heartbeats the activity, and renews the task lease, until
+// stopped. Called outside an activity, as by the in-process engine, it only
+// renews the lease.
+func (a *Activities) keepAlive(ctx context.Context, taskID, employeeID uuid.UUID) func() {
+	inActivity := activity.IsActivity(ctx)
+	if !inActivity && (a.leases == nil || employeeID == uuid.Nil) {
+		return func() {}
+	}
+
+	interval := a.heartbeatInterval
+	if interval <= 0 {
+		interval = defaultHeartbeatInterval
+		if inActivity && activity.GetInfo(ctx).HeartbeatTimeout > 0 {
+			interval = activity.GetInfo(ctx).HeartbeatTimeout / 3
+		}
+	}
+
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if inActivity {
+					activity.RecordHeartbeat(ctx, taskID.String())
+				}
+				a.renewLease(ctx, taskID, employeeID)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// renewLease extends the scheduler's lease on the task while it executes
+func (a *Activities) renewLease(ctx context.Context, taskID, employeeID uuid.UUID) {
+	if a.leases == nil || employeeID == uuid.Nil {
+		return
+	}
+	if err := a.leases.RenewLease(ctx, taskID, employeeID, time.Now().Add(a.leaseTTL)); err != nil {
+		logger.Warn(fmt.Sprintf("Failed to renew lease of task %s: %v", taskID, err))
+	}
+}
+
+// decodeOutput turns executor output into a map, wrapping non-object values
+func decodeOutput(raw json.RawMessage) map[string]interface{} {
+	if len(raw) == 0 {
+		return map[string]interface{}{}
+	}
+
+	var output map[string]interface{}
+	if err := json.Unmarshal(raw, &output); err == nil {
+		return output
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return map[string]interface{}{"result": string(raw)}
+	}
+	return map[string]interface{}{"result": value}
+}
+
+// UpdateTaskStatusActivity moves the task to the given status and progress,
+// announces the change and frees the employee once the task is finished.
+// Repeating an update the task already reflects is a no-op, so retries are safe.
+func (a *Activities) UpdateTaskStatusActivity(ctx context.Context, input TaskStatusInput) error {
+	taskID, err := uuid.Parse(input.TaskID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid task id", "InvalidInput", err)
+	}
+
+	t, err := a.tasks.GetByID(ctx, taskID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return temporal.NewNonRetryableApplicationError("task not found", "NotFound", err)
+		}
+		return err
+	}
+
+	status := task.TaskStatus(input.Status)
+	eventType, err := applyStatus(t, status, input)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError(err.Error(), "InvalidTransition", nil)
+	}
+	if eventType == "" {
+		if !compensatedAfterCancel(t, status, input) {
+			return nil
+		}
+		// Cancelling through the API ends the task before its workflow compensates
+		t.Compensation = input.Compensation
+		return a.tasks.Update(ctx, t)
+	}
+
+	return a.inTransaction(ctx, func(ctx context.Context) error {
+		if err := a.tasks.Update(ctx, t); err != nil {
+			return err
+		}
+
+		if status == task.StatusCompleted || status == task.StatusFailed || status == task.StatusCancelled {
+			if err := a.releaseEmployee(ctx, t, status == task.StatusCompleted); err != nil {
+				return err
+			}
+		}
+
+		return a.publish(ctx, eventType, t)
+	})
+}
+
+// applyStatus applies a status update to the task and returns the event
+// announcing it, or an empty event type when the task already reflects it
+func applyStatus(t *task.Task, status task.TaskStatus, input TaskStatusInput) (eventbus.EventType, error) {
+	if status == "" || status == t.Status {
+		// A step in flight when the task was paused still reports its progress
+		active := t.Status == task.StatusRunning || t.Status == task.StatusPaused
+		if !active || input.Progress == t.Progress {
+			return "", nil
+		}
+		t.UpdateProgress(input.Progress)
+		return eventbus.EventTaskProgress, nil
+	}
+
+	if !t.CanTransitionTo(status) {
+		return "", fmt.Errorf("task %s cannot move from %s to %s", t.ID, t.Status, status)
+	}
+
+	switch status {
+	case task.StatusRunning:
+		if t.Status == task.StatusPaused {
+			t.Resume()
+			t.UpdateProgress(input.Progress)
+			return eventbus.EventTaskResumed, nil
+		}
+		employeeID, _ := uuid.Parse(input.EmployeeID)
+		if employeeID == uuid.Nil && t.AssignedEmployeeID != nil {
+			employeeID = *t.AssignedEmployeeID
+		}
+		t.Start(employeeID)
+		t.UpdateProgress(input.Progress)
+		return eventbus.EventTaskStarted, nil
+	case task.StatusCompleted:
+		t.Complete(input.Output)
+		return eventbus.EventTaskCompleted, nil
+	case task.StatusFailed:
+		t.Fail(input.Error)
+		t.Compensation = input.Compensation
+		return eventbus.EventTaskFailed, nil
+	case task.StatusCancelled:
+		t.Cancel()
+		t.Compensation = input.Compensation
+		return eventbus.EventTaskCancelled, nil
+	case task.StatusPaused:
+		t.Pause()
+		return eventbus.EventTaskPaused, nil
+	}
+	return "", fmt.Errorf("unsupported task status: %s", status)
+}
+
+// compensatedAfterCancel reports whether a status update brings how the
+// compensations of an already cancelled task ended
+func compensatedAfterCancel(t *task.Task, status task.TaskStatus, input TaskStatusInput) bool {
+	return status == task.StatusCancelled && t.Status == task.StatusCancelled &&
+		input.Compensation != nil && t.Compensation == nil
+}
+
+// releaseEmployee ends the employee's assignment to a finished task. Released
+// assignments are skipped, so the scheduler releasing it too is harmless.
+func (a *Activities) releaseEmployee(ctx context.Context, t *task.Task, success bool) error {
+	if a.leases != nil {
+		if err := a.leases.ReleaseLease(ctx, t.ID); err != nil {
+			return err
+		}
+	}
+	if t.AssignedEmployeeID == nil {
+		return nil
+	}
+
+	assignment, err := a.employees.GetLatestAssignmentByTask(ctx, t.ID)
+	if errors.IsNotFound(err) || (err == nil && !assignment.IsActive()) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	employeeID := *t.AssignedEmployeeID
+	if err := a.employees.ReleaseAssignment(ctx, employeeID, t.ID); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	active, err := a.employees.GetActiveAssignments(ctx, employeeID)
+	if err != nil {
+		return err
+	}
+	remaining := make([]uuid.UUID, 0, len(active))
+	for _, as := range active {
+		remaining = append(remaining, as.TaskID)
+	}
+
+	emp, err := a.employees.GetByID(ctx, employeeID)
+	if err != nil {
+		return err
+	}
+	emp.ReleaseTask(success, remaining)
+	return a.employees.Update(ctx, emp)
+}
+
+// publish announces a task event; the task itself is the payload
+func (a *Activities) publish(ctx context.Context, eventType eventbus.EventType, t *task.Task) error {
+	if a.eventBus == nil {
+		return nil
+	}
+	event, err := eventbus.NewEvent(eventType, "temporal_worker", t, eventbus.Metadata{
+		CompanyID: t.CompanyID.String(),
+		Version:   1,
+	})
+	if err != nil {
+		return err
+	}
+	return a.eventBus.Publish(ctx, event)
+}
+
+// RequestApprovalActivity records a pending approval and notifies the
+// company. A retry reuses the approval already recorded for the node.
+func (a *Activities) RequestApprovalActivity(ctx context.Context, input ApprovalRequestInput) error {
+	if a.approvals == nil {
+		return temporal.NewNonRetryableApplicationError("approvals are not configured", "InvalidInput", nil)
+	}
+	taskID, err := uuid.Parse(input.TaskID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid task id", "InvalidInput", err)
+	}
+	companyID, err := uuid.Parse(input.CompanyID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid company id", "InvalidInput", err)
+	}
+
+	return a.inTransaction(ctx, func(ctx context.Context) error {
+		approval, err := a.approvals.GetPendingApproval(ctx, taskID, input.NodeID)
+		if errors.IsNotFound(err) {
+			approval = task.NewApproval(companyID, taskID, input.NodeID, input.NodeName, input.Content,
+				task.ApprovalAction(input.DefaultAction), input.ExpiresAt)
+			err = a.approvals.CreateApproval(ctx, approval)
+		}
+		if err != nil {
+			return err
+		}
+
+		return a.publishApproval(ctx, eventbus.EventTaskApprovalRequested, approval)
+	})
+}
+
+// RecordApprovalActivity records the decision of a pending approval: who
+// decided and the content they approved. An approval already decided by an
+// earlier attempt is left alone.
+func (a *Activities) RecordApprovalActivity(ctx context.Context, input ApprovalDecisionInput) error {
+	if a.approvals == nil {
+		return temporal.NewNonRetryableApplicationError("approvals are not configured", "InvalidInput", nil)
+	}
+	taskID, err := uuid.Parse(input.TaskID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid task id", "InvalidInput", err)
+	}
+
+	approval, err := a.approvals.GetPendingApproval(ctx, taskID, input.Decision.NodeID)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var decidedBy *uuid.UUID
+	if !input.TimedOut {
+		id, err := uuid.Parse(input.Decision.DecidedBy)
+		if err != nil {
+			return temporal.NewNonRetryableApplicationError("invalid reviewer id", "InvalidInput", err)
+		}
+		decidedBy = &id
+	}
+
+	approval.Decide(task.ApprovalAction(input.Decision.Action), decidedBy, input.Decision.Content, input.Decision.Comment)
+	return a.inTransaction(ctx, func(ctx context.Context) error {
+		if err := a.approvals.DecideApproval(ctx, approval); err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+		return a.publishApproval(ctx, eventbus.EventTaskApprovalDecided, approval)
+	})
+}
+
+// publishApproval announces an approval event; the approval is the payload
+func (a *Activities) publishApproval(ctx context.Context, eventType eventbus.EventType, approval *task.Approval) error {
+	if a.eventBus == nil {
+		return nil
+	}
+	event, err := eventbus.NewEvent(eventType, "temporal_worker", approval, eventbus.Metadata{
+		CompanyID: approval.CompanyID.String(),
+		Version:   1,
+	})
+	if err != nil {
+		return err
+	}
+	return a.eventBus.Publish(ctx, event)
+}
+
+// RecordStepActivity records a step of a workflow run as it starts and ends.
+// Steps are not recorded without a repository, and a retry that finds the
+// step already in the given status leaves it alone.
+func (a *Activities) RecordStepActivity(ctx context.Context, input StepRecordInput) error {
+	if a.steps == nil {
+		return nil
+	}
+	taskID, err := uuid.Parse(input.TaskID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid task id", "InvalidInput", err)
+	}
+	attempt := input.Attempt
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	step, err := a.steps.GetStep(ctx, taskID, attempt, input.NodeID)
+	if errors.IsNotFound(err) {
+		step = task.NewStep(taskID, attempt, input.Order, input.NodeID, input.Name)
+		if id, err := uuid.Parse(input.SkillCardID); err == nil {
+			step.SkillCardID = &id
+		}
+	} else if err != nil {
+		return err
+	}
+	if step.Status == input.Status {
+		return nil
+	}
+	if !step.CanTransitionTo(input.Status) {
+		return temporal.NewNonRetryableApplicationError(
+			fmt.Sprintf("step %s cannot go from %s to %s", input.NodeID, step.Status, input.Status), "InvalidInput", nil)
+	}
+
+	switch input.Status {
+	case task.StepRunning:
+		var executedBy *uuid.UUID
+		if id, err := uuid.Parse(input.EmployeeID); err == nil {
+			executedBy = &id
+		}
+		step.Start(input.Input, executedBy, input.At)
+	case task.StepCompleted:
+		if input.Reused {
+			step.Reuse(input.Output, input.At)
+		} else {
+			step.Complete(input.Output, input.At)
+		}
+	case task.StepFailed:
+		step.Fail(input.Error, input.At)
+	case task.StepSkipped:
+		step.Skip()
+	case task.StepCancelled:
+		step.Cancel(input.At)
+	case task.StepCompensated:
+		step.Compensate(input.At)
+	case task.StepCompensationFailed:
+		step.FailCompensation(input.Error, input.At)
+	}
+	return a.steps.SaveStep(ctx, step)
 }
 
-// AnalyzeHotspotsActivity analyzes platform hotspots
-func AnalyzeHotspotsActivity(ctx context.Context, input HotspotAnalysisInput) (*SkillExecutionResult, error) {
+// SpawnScheduledTaskActivity creates the task of a schedule that fired and
+// records the run. A retry of the same workflow returns the task already created.
+func (a *Activities) SpawnScheduledTaskActivity(ctx context.Context, input SpawnScheduledTaskInput) (string, error) {
+	if a.schedules == nil || a.uow == nil {
+		return "", temporal.NewNonRetryableApplicationError("schedules are not configured", "InvalidInput", nil)
+	}
+	scheduleID, err := uuid.Parse(input.ScheduleID)
+	if err != nil {
+		return "", temporal.NewNonRetryableApplicationError("invalid schedule id", "InvalidInput", err)
+	}
+
+	run, err := a.schedules.GetScheduleRunByWorkflow(ctx, input.WorkflowID)
+	if err == nil {
+		return run.TaskID.String(), nil
+	}
+	if !errors.IsNotFound(err) {
+		return "", err
+	}
+
+	sch, err := a.schedules.GetSchedule(ctx, scheduleID)
+	if errors.IsNotFound(err) {
+		return "", temporal.NewNonRetryableApplicationError("schedule not found", "NotFound", err)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	t := sch.Spawn()
+	err = a.uow.Do(ctx, func(ctx context.Context) error {
+		if err := a.tasks.Create(ctx, t); err != nil {
+			return err
+		}
+		if err := a.schedules.CreateScheduleRun(ctx, task.NewScheduleRun(sch, t.ID, input.WorkflowID, input.TriggeredAt)); err != nil {
+			return err
+		}
+		sch.RecordRun(input.TriggeredAt)
+		if err := a.schedules.UpdateSchedule(ctx, sch); err != nil {
+			return err
+		}
+		return a.publish(ctx, eventbus.EventTaskCreated, t)
+	})
+	if err != nil {
+		return "", err
+	}
+	return t.ID.String(), nil
+}
+
+// AnalyzeHotspotsActivity analyzes the hotspots of a platform from its
+// configured sources and the company's latest upload. The topics' titles
+// are also output as keywords and a summary, the inputs of content skills.
+func (a *Activities) AnalyzeHotspotsActivity(ctx context.Context, input HotspotAnalysisInput) (*SkillExecutionResult, error) {
 	start := time.Now()
 
-	// TODO: Integrate with actual hotspot analysis service
-	result := &SkillExecutionResult{
+	if a.hotspots == nil {
+		return nil, temporal.NewNonRetryableApplicationError("hotspot sources are not configured", "InvalidInput", nil)
+	}
+	companyID, err := uuid.Parse(input.CompanyID)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("invalid company id", "InvalidInput", err)
+	}
+	snapshot, err := a.hotspots.Analyze(ctx, companyID, input.Platform, input.Category)
+	if err != nil {
+		// Missing platforms or sources do not fix themselves on retry
+		if errors.IsBadRequest(err) {
+			return nil, temporal.NewNonRetryableApplicationError(err.Error(), "InvalidInput", err)
+		}
+		return nil, err
+	}
+
+	hotTopics := make([]map[string]interface{}, 0, len(snapshot.Topics))
+	recommendations := make([]string, 0)
+	keywords := make([]string, 0, len(snapshot.Topics))
+	for _, topic := range snapshot.Topics {
+		keywords = append(keywords, topic.Title)
+		hotTopics = append(hotTopics, map[string]interface{}{
+			"topic":   topic.Title,
+			"heat":    topic.Heat,
+			"trend":   topic.Trend,
+			"delta":   topic.Delta,
+			"url":     topic.URL,
+			"sources": topic.Sources,
+		})
+		if topic.Trend == hotspot.TrendNew || topic.Trend == hotspot.TrendRising {
+			recommendations = append(recommendations, fmt.Sprintf("建议关注「%s」", topic.Title))
+		}
+	}
+
+	return &SkillExecutionResult{
 		Success: true,
 		Output: map[string]interface{}{
-			"hotTopics": []map[string]interface{}{
-				{"topic": "AI技术", "heat": 9500, "trend": "rising"},
-				{"topic": "数字化转型", "heat": 8200, "trend": "stable"},
-				{"topic": "效率提升", "heat": 7800, "trend": "rising"},
-			},
-			"recommendations": []string{
-				"建议关注AI应用场景",
-				"可以结合效率工具主题",
-				"数字化转型案例分享",
-			},
-			"platform":   input.Platform,
-			"category":   input.Category,
-			"analyzedAt": time.Now().Format(time.RFC3339),
+			"hotTopics":       hotTopics,
+			"recommendations": recommendations,
+			"summary":         strings.Join(keywords, "、"),
+			"keywords":        keywords,
+			"platform":        input.Platform,
+			"category":        input.Category,
+			"snapshotId":      snapshot.ID.String(),
+			"analyzedAt":      snapshot.CapturedAt.Format(time.RFC3339),
 		},
-		TokensUsed:    200,
 		ExecutionTime: time.Since(start).Milliseconds(),
-	}
-
-	return result, nil
+	}, nil
 }
 
 // GenerateContentSuggestionsActivity generates content suggestions based on hotspots
-func GenerateContentSuggestionsActivity(ctx context.Context, input ContentSuggestionInput) (*SkillExecutionResult, error) {
+func (a *Activities) GenerateContentSuggestionsActivity(ctx context.Context, input ContentSuggestionInput) (*SkillExecutionResult, error) {
 	start := time.Now()
 
 	// TODO: Integrate with AI service for content suggestions
@@ -148,19 +811,118 @@ func GenerateContentSuggestionsActivity(ctx context.Context, input ContentSugges
 	return result, nil
 }
 
-// PublishContentActivity publishes content to platforms
-func PublishContentActivity(ctx context.Context, input PublishInput) (map[string]interface{}, error) {
-	// TODO: Integrate with platform publishing APIs
+// PublishContentActivity publishes content to platforms, one after the
+// other. A platform that fails is reported in the result rather than
+// failing the activity, since retrying would publish again to the platforms
+// that succeeded.
+func (a *Activities) PublishContentActivity(ctx context.Context, input PublishInput) (map[string]interface{}, error) {
+	if a.publisher == nil {
+		return nil, temporal.NewNonRetryableApplicationError("publishers are not configured", "InvalidInput", nil)
+	}
+	companyID, err := uuid.Parse(input.CompanyID)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError("invalid company id", "InvalidInput", err)
+	}
+	platforms := input.Platforms
+	if len(platforms) == 0 {
+		platforms = a.publisher.DefaultPlatforms()
+	}
+	if len(platforms) == 0 {
+		return nil, temporal.NewNonRetryableApplicationError("no platforms to publish to", "InvalidInput", nil)
+	}
+	mode := publishing.ModePublish
+	if input.Mode != "" {
+		mode = publishing.Mode(input.Mode)
+	}
+	if !mode.IsValid() {
+		return nil, temporal.NewNonRetryableApplicationError(fmt.Sprintf("unknown publish mode %q", input.Mode), "InvalidInput", nil)
+	}
+
+	post := publishing.PostFromContent(input.Content)
+	statuses := make(map[string]string, len(platforms))
+	posts := make(map[string]interface{}, len(platforms))
+	failures := make(map[string]string)
+	for _, platform := range platforms {
+		published, err := a.publisher.Publish(ctx, companyID, platform, post, mode)
+		if err != nil {
+			logger.Warn(fmt.Sprintf("Failed to publish to %s: %v", platform, err))
+			statuses[platform] = "failed"
+			failures[platform] = err.Error()
+			continue
+		}
+		statuses[platform] = "success"
+		posts[platform] = map[string]interface{}{
+			"postId": published.PostID,
+			"url":    published.URL,
+			"mode":   string(published.Mode),
+		}
+	}
+
 	result := map[string]interface{}{
-		"published":   true,
-		"platforms":   input.Platforms,
+		"published":   len(failures) == 0,
+		"mode":        string(mode),
+		"platforms":   platforms,
 		"publishedAt": time.Now().Format(time.RFC3339),
-		"status":      map[string]string{},
+		"status":      statuses,
+		"posts":       posts,
+	}
+	if len(failures) > 0 {
+		result["errors"] = failures
+	}
+	return result, nil
+}
+
+// UnpublishContentActivity takes published content back down, compensating
+// a publish whose workflow did not complete. The platforms already done are
+// kept in the heartbeat details, so a retry only takes down the rest.
+func (a *Activities) UnpublishContentActivity(ctx context.Context, input UnpublishInput) error {
+	if a.publisher == nil {
+		return temporal.NewNonRetryableApplicationError("publishers are not configured", "InvalidInput", nil)
+	}
+	companyID, err := uuid.Parse(input.CompanyID)
+	if err != nil {
+		return temporal.NewNonRetryableApplicationError("invalid company id", "InvalidInput", err)
 	}
 
+	var done []string
+	inActivity := activity.IsActivity(ctx)
+	if inActivity && activity.HasHeartbeatDetails(ctx) {
+		_ = activity.GetHeartbeatDetails(ctx, &done)
+	}
+	posts, _ := input.Publication["posts"].(map[string]interface{})
+	var failed []string
 	for _, platform := range input.Platforms {
-		result["status"].(map[string]string)[platform] = "success"
+		if containsString(done, platform) {
+			continue
+		}
+		post, _ := posts[platform].(map[string]interface{})
+		postID, _ := post["postId"].(string)
+		if postID == "" {
+			failed = append(failed, fmt.Sprintf("%s: no post id", platform))
+			continue
+		}
+		if err := a.publisher.Unpublish(ctx, companyID, platform, postID); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", platform, err))
+			continue
+		}
+		logger.Info(fmt.Sprintf("Unpublished post %s from %s", postID, platform))
+		done = append(done, platform)
+		if inActivity {
+			activity.RecordHeartbeat(ctx, done)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to unpublish from %s", strings.Join(failed, "; "))
 	}
+	return nil
+}
 
-	return result, nil
+// containsString reports whether values contains value
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
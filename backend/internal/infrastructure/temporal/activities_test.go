@@ -0,0 +1,436 @@
+package temporal
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/application/executor"
+	"unlimited-corp/internal/domain/employee"
+	"unlimited-corp/internal/domain/hotspot"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/config"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestMain(m *testing.M) {
+	_ = logger.Init(&config.LogConfig{Level: "error", Format: "console"})
+	os.Exit(m.Run())
+}
+
+// fakeSkillExecutor returns a fixed output after an optional delay
+type fakeSkillExecutor struct {
+	output json.RawMessage
+	delay  time.Duration
+	err    error
+	calls  []*executor.ExecutionContext
+}
+
+func (e *fakeSkillExecutor) Execute(_ context.Context, execCtx *executor.ExecutionContext) (*executor.ExecutionResult, error) {
+	e.calls = append(e.calls, execCtx)
+	time.Sleep(e.delay)
+	if e.err != nil {
+		return &executor.ExecutionResult{Success: false, Error: e.err.Error()}, e.err
+	}
+	return &executor.ExecutionResult{Success: true, Output: e.output, TokensUsed: 42}, nil
+}
+
+// fakeTaskRepository keeps tasks in memory; unused methods panic through the nil interface
+type fakeTaskRepository struct {
+	task.Repository
+	mu     sync.Mutex
+	tasks  map[uuid.UUID]*task.Task
+	leases map[uuid.UUID]time.Time
+}
+
+func newFakeTaskRepository(tasks ...*task.Task) *fakeTaskRepository {
+	repo := &fakeTaskRepository{tasks: make(map[uuid.UUID]*task.Task), leases: make(map[uuid.UUID]time.Time)}
+	for _, t := range tasks {
+		repo.tasks[t.ID] = t
+	}
+	return repo
+}
+
+func (r *fakeTaskRepository) GetByID(_ context.Context, id uuid.UUID) (*task.Task, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.tasks[id]; ok {
+		copied := *t
+		return &copied, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeTaskRepository) Create(_ context.Context, t *task.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks[t.ID] = t
+	return nil
+}
+
+func (r *fakeTaskRepository) Update(_ context.Context, t *task.Task) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tasks[t.ID] = t
+	return nil
+}
+
+func (r *fakeTaskRepository) AcquireLease(context.Context, *task.Lease) error { return nil }
+
+func (r *fakeTaskRepository) RenewLease(_ context.Context, taskID, _ uuid.UUID, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.leases[taskID] = expiresAt
+	return nil
+}
+
+func (r *fakeTaskRepository) ReleaseLease(_ context.Context, taskID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.leases, taskID)
+	return nil
+}
+
+func (r *fakeTaskRepository) LockExpiredLeases(context.Context, time.Time, int) ([]*task.Lease, error) {
+	return nil, nil
+}
+
+func (r *fakeTaskRepository) CreateRecovery(context.Context, *task.Recovery) error { return nil }
+
+func (r *fakeTaskRepository) ListRecoveries(context.Context, uuid.UUID) ([]*task.Recovery, error) {
+	return nil, nil
+}
+
+// fakeEmployeeRepository keeps employees and assignments in memory
+type fakeEmployeeRepository struct {
+	employee.Repository
+	employees   map[uuid.UUID]*employee.Employee
+	assignments []*employee.Assignment
+}
+
+func (r *fakeEmployeeRepository) GetByID(_ context.Context, id uuid.UUID) (*employee.Employee, error) {
+	if e, ok := r.employees[id]; ok {
+		return e, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeEmployeeRepository) Update(_ context.Context, e *employee.Employee) error {
+	r.employees[e.ID] = e
+	return nil
+}
+
+func (r *fakeEmployeeRepository) GetLatestAssignmentByTask(_ context.Context, taskID uuid.UUID) (*employee.Assignment, error) {
+	for i := len(r.assignments) - 1; i >= 0; i-- {
+		if r.assignments[i].TaskID == taskID {
+			return r.assignments[i], nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeEmployeeRepository) ReleaseAssignment(_ context.Context, employeeID, taskID uuid.UUID) error {
+	for _, a := range r.assignments {
+		if a.EmployeeID == employeeID && a.TaskID == taskID && a.IsActive() {
+			now := time.Now()
+			a.ReleasedAt = &now
+			return nil
+		}
+	}
+	return errors.ErrNotFound
+}
+
+func (r *fakeEmployeeRepository) GetActiveAssignments(_ context.Context, employeeID uuid.UUID) ([]*employee.Assignment, error) {
+	var active []*employee.Assignment
+	for _, a := range r.assignments {
+		if a.EmployeeID == employeeID && a.IsActive() {
+			active = append(active, a)
+		}
+	}
+	return active, nil
+}
+
+// newRunningTask creates a task running on a busy employee with an active assignment
+func newRunningTask() (*task.Task, *fakeEmployeeRepository) {
+	companyID := uuid.New()
+	emp := employee.NewEmployee(companyID, "Writer", "Writer")
+	t := task.NewTask(companyID, "Write a post", "", task.PriorityMedium)
+	t.Start(emp.ID)
+	emp.AssignTask(t.ID)
+
+	employees := &fakeEmployeeRepository{
+		employees:   map[uuid.UUID]*employee.Employee{emp.ID: emp},
+		assignments: []*employee.Assignment{employee.NewAssignment(companyID, emp.ID, t.ID)},
+	}
+	return t, employees
+}
+
+func TestActivities_ExecuteSkill(t *testing.T) {
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	skills := &fakeSkillExecutor{output: json.RawMessage(`{"content":"hello"}`), delay: 50 * time.Millisecond}
+
+	a := NewActivities(skills, tasks, employees, eventbus.NewEventBus())
+	a.SetLeases(tasks, time.Minute)
+	a.heartbeatInterval = 5 * time.Millisecond
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	RegisterActivities(env, a)
+
+	value, err := env.ExecuteActivity(a.ExecuteSkillActivity, SkillExecutionInput{
+		TaskID:      running.ID.String(),
+		SkillCardID: uuid.New().String(),
+		Parameters:  map[string]interface{}{"topic": "go"},
+	})
+	require.NoError(t, err)
+
+	var result SkillExecutionResult
+	require.NoError(t, value.Get(&result))
+	assert.True(t, result.Success)
+	assert.Equal(t, "hello", result.Output["content"])
+	assert.Equal(t, 42, result.TokensUsed)
+
+	require.Len(t, skills.calls, 1)
+	assert.Equal(t, *running.AssignedEmployeeID, skills.calls[0].EmployeeID, "employee falls back to the task's assignee")
+	assert.Contains(t, tasks.leases, running.ID, "heartbeats renew the task lease")
+}
+
+func TestActivities_ExecuteSkill_InvalidInput(t *testing.T) {
+	a := NewActivities(&fakeSkillExecutor{}, newFakeTaskRepository(), &fakeEmployeeRepository{}, nil)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	RegisterActivities(env, a)
+
+	_, err := env.ExecuteActivity(a.ExecuteSkillActivity, SkillExecutionInput{TaskID: "not-a-uuid"})
+	assert.Error(t, err)
+}
+
+// fakeHotspotAnalyzer returns a snapshot of fixed topics
+type fakeHotspotAnalyzer struct {
+	topics []hotspot.Topic
+	err    error
+}
+
+func (f *fakeHotspotAnalyzer) Analyze(_ context.Context, companyID uuid.UUID, platform, category string) (*hotspot.Snapshot, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return hotspot.NewSnapshot(companyID, platform, category, f.topics), nil
+}
+
+func TestActivities_AnalyzeHotspots(t *testing.T) {
+	a := NewActivities(&fakeSkillExecutor{}, newFakeTaskRepository(), &fakeEmployeeRepository{}, nil)
+	a.SetHotspots(&fakeHotspotAnalyzer{topics: []hotspot.Topic{
+		{Key: "aiagents", Title: "AI Agents", Heat: 1200, Trend: hotspot.TrendRising, Delta: 200, Sources: []string{"weibo"}},
+		{Key: "remotework", Title: "Remote work", Heat: 500, Trend: hotspot.TrendFalling, Delta: -100, Sources: []string{"weibo"}},
+	}})
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	RegisterActivities(env, a)
+
+	value, err := env.ExecuteActivity(a.AnalyzeHotspotsActivity, HotspotAnalysisInput{
+		CompanyID: uuid.New().String(),
+		Platform:  "xiaohongshu",
+		Category:  "tech",
+	})
+	require.NoError(t, err)
+	var result SkillExecutionResult
+	require.NoError(t, value.Get(&result))
+
+	topics := result.Output["hotTopics"].([]interface{})
+	require.Len(t, topics, 2)
+	assert.Equal(t, "AI Agents", topics[0].(map[string]interface{})["topic"])
+	assert.Equal(t, "rising", topics[0].(map[string]interface{})["trend"])
+	assert.Equal(t, []interface{}{"建议关注「AI Agents」"}, result.Output["recommendations"])
+	assert.Equal(t, "AI Agents、Remote work", result.Output["summary"])
+	assert.Equal(t, []interface{}{"AI Agents", "Remote work"}, result.Output["keywords"])
+	assert.Equal(t, "xiaohongshu", result.Output["platform"])
+}
+
+func TestActivities_AnalyzeHotspots_NotRetried(t *testing.T) {
+	tests := []struct {
+		name     string
+		analyzer HotspotAnalyzer
+	}{
+		{name: "not configured"},
+		{name: "no sources", analyzer: &fakeHotspotAnalyzer{err: errors.New(400, "no hotspot sources")}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewActivities(&fakeSkillExecutor{}, newFakeTaskRepository(), &fakeEmployeeRepository{}, nil)
+			if tt.analyzer != nil {
+				a.SetHotspots(tt.analyzer)
+			}
+
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestActivityEnvironment()
+			RegisterActivities(env, a)
+
+			_, err := env.ExecuteActivity(a.AnalyzeHotspotsActivity, HotspotAnalysisInput{
+				CompanyID: uuid.New().String(),
+				Platform:  "douyin",
+			})
+			require.Error(t, err)
+			var appErr *temporal.ApplicationError
+			require.ErrorAs(t, err, &appErr)
+			assert.True(t, appErr.NonRetryable())
+		})
+	}
+}
+
+func TestHotspotTrackingWorkflow_FinishesTask(t *testing.T) {
+	tests := []struct {
+		name     string
+		analyzer HotspotAnalyzer
+		status   string
+		want     task.TaskStatus
+	}{
+		{name: "completed", analyzer: &fakeHotspotAnalyzer{topics: []hotspot.Topic{{Key: "aiagents", Title: "AI Agents", Heat: 1200}}}, status: "completed", want: task.StatusCompleted},
+		{name: "analysis failed", status: "failed", want: task.StatusFailed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running, employees := newRunningTask()
+			tasks := newFakeTaskRepository(running)
+			a := NewActivities(&fakeSkillExecutor{}, tasks, employees, eventbus.NewEventBus())
+			if tt.analyzer != nil {
+				a.SetHotspots(tt.analyzer)
+			}
+
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			env.RegisterWorkflow(HotspotTrackingWorkflow)
+			RegisterActivities(env, a)
+
+			env.ExecuteWorkflow(HotspotTrackingWorkflow, WorkflowInput{
+				TaskID:     running.ID.String(),
+				CompanyID:  running.CompanyID.String(),
+				Parameters: map[string]interface{}{"platform": "weibo"},
+			})
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+
+			var result WorkflowResult
+			require.NoError(t, env.GetWorkflowResult(&result))
+			assert.Equal(t, tt.status, result.Status)
+			assert.Equal(t, tt.want, tasks.tasks[running.ID].Status)
+		})
+	}
+}
+
+func TestActivities_UpdateTaskStatus_Completed(t *testing.T) {
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	bus := eventbus.NewEventBus()
+	a := NewActivities(&fakeSkillExecutor{}, tasks, employees, bus)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	RegisterActivities(env, a)
+
+	input := TaskStatusInput{
+		TaskID:   running.ID.String(),
+		Status:   string(task.StatusCompleted),
+		Progress: 100,
+		Output:   map[string]interface{}{"content": "done"},
+	}
+	_, err := env.ExecuteActivity(a.UpdateTaskStatusActivity, input)
+	require.NoError(t, err)
+
+	updated := tasks.tasks[running.ID]
+	assert.Equal(t, task.StatusCompleted, updated.Status)
+	assert.Equal(t, "done", updated.OutputData["content"])
+
+	emp := employees.employees[*running.AssignedEmployeeID]
+	assert.Equal(t, employee.StatusIdle, emp.Status)
+	assert.False(t, employees.assignments[0].IsActive())
+	assert.Len(t, bus.GetHistoryByType(eventbus.EventTaskCompleted, 10), 1)
+
+	// A retried update is a no-op
+	_, err = env.ExecuteActivity(a.UpdateTaskStatusActivity, input)
+	require.NoError(t, err)
+	assert.Len(t, bus.GetHistoryByType(eventbus.EventTaskCompleted, 10), 1)
+}
+
+func TestActivities_UpdateTaskStatus_Progress(t *testing.T) {
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	bus := eventbus.NewEventBus()
+	a := NewActivities(&fakeSkillExecutor{}, tasks, employees, bus)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	RegisterActivities(env, a)
+
+	_, err := env.ExecuteActivity(a.UpdateTaskStatusActivity, TaskStatusInput{TaskID: running.ID.String(), Progress: 40})
+	require.NoError(t, err)
+	assert.Equal(t, 40, tasks.tasks[running.ID].Progress)
+	assert.Equal(t, task.StatusRunning, tasks.tasks[running.ID].Status)
+	assert.Len(t, bus.GetHistoryByType(eventbus.EventTaskProgress, 10), 1)
+}
+
+func TestActivities_UpdateTaskStatus_CompensationAfterCancel(t *testing.T) {
+	running, employees := newRunningTask()
+	running.Cancel()
+	tasks := newFakeTaskRepository(running)
+	bus := eventbus.NewEventBus()
+	a := NewActivities(&fakeSkillExecutor{}, tasks, employees, bus)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestActivityEnvironment()
+	RegisterActivities(env, a)
+
+	compensation := &task.Compensation{Compensated: []string{"draft"}, Failed: []task.CompensationFailure{}}
+	_, err := env.ExecuteActivity(a.UpdateTaskStatusActivity, TaskStatusInput{
+		TaskID:       running.ID.String(),
+		Status:       string(task.StatusCancelled),
+		Progress:     100,
+		Compensation: compensation,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, compensation, tasks.tasks[running.ID].Compensation, "the task was cancelled before its workflow compensated")
+	assert.Empty(t, bus.GetHistoryByType(eventbus.EventTaskCancelled, 10), "the cancellation was announced already")
+}
+
+func TestContentCreationWorkflow_ProducesExecutorOutput(t *testing.T) {
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	skills := &fakeSkillExecutor{output: json.RawMessage(`{"content":"real output"}`)}
+	a := NewActivities(skills, tasks, employees, eventbus.NewEventBus())
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(ContentCreationWorkflow)
+	RegisterActivities(env, a)
+
+	env.ExecuteWorkflow(ContentCreationWorkflow, WorkflowInput{
+		TaskID:      running.ID.String(),
+		CompanyID:   running.CompanyID.String(),
+		EmployeeID:  running.AssignedEmployeeID.String(),
+		SkillCardID: uuid.New().String(),
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result WorkflowResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	assert.Equal(t, "completed", result.Status)
+	assert.Equal(t, "real output", result.Output["content"])
+
+	assert.Equal(t, task.StatusCompleted, tasks.tasks[running.ID].Status)
+	assert.Equal(t, "real output", tasks.tasks[running.ID].OutputData["content"])
+}
@@ -0,0 +1,167 @@
+package temporal
+
+import (
+	"fmt"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// SignalApproval delivers a reviewer's decision to the approval waiting for it
+const SignalApproval = "approval"
+
+// Approval defaults used when a node or workflow input sets none
+const (
+	defaultApprovalTimeout = 24 * time.Hour
+	defaultApprovalAction  = definition.ApprovalDefaultReject
+)
+
+// ApprovalDecision is the payload of SignalApproval
+type ApprovalDecision struct {
+	NodeID string `json:"nodeId"`
+	Action string `json:"action"`
+	// Content holds the reviewer's changes of an edit decision
+	Content   map[string]interface{} `json:"content,omitempty"`
+	DecidedBy string                 `json:"decidedBy,omitempty"`
+	Comment   string                 `json:"comment,omitempty"`
+}
+
+// ApprovalPolicy configures how long an approval waits and what happens when
+// nobody decides in time
+type ApprovalPolicy struct {
+	TimeoutSeconds int    `json:"timeoutSeconds,omitempty"`
+	DefaultAction  string `json:"defaultAction,omitempty"`
+}
+
+// timeout returns the configured wait, falling back to defaultApprovalTimeout
+func (p ApprovalPolicy) timeout() time.Duration {
+	if p.TimeoutSeconds > 0 {
+		return time.Duration(p.TimeoutSeconds) * time.Second
+	}
+	return defaultApprovalTimeout
+}
+
+// defaultAction returns the configured action, falling back to defaultApprovalAction
+func (p ApprovalPolicy) defaultAction() string {
+	if p.DefaultAction != "" {
+		return p.DefaultAction
+	}
+	return defaultApprovalAction
+}
+
+// approvalDesk routes approval signals to the approvals a workflow waits for.
+// Decisions for nodes that are not waiting are dropped.
+type approvalDesk struct {
+	taskID    string
+	companyID string
+	waiting   map[string]workflow.Channel
+}
+
+// newApprovalDesk starts listening for approval signals
+func newApprovalDesk(ctx workflow.Context, taskID, companyID string) *approvalDesk {
+	d := &approvalDesk{taskID: taskID, companyID: companyID, waiting: make(map[string]workflow.Channel)}
+	signals := workflow.GetSignalChannel(ctx, SignalApproval)
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		for ctx.Err() == nil {
+			selector := workflow.NewSelector(ctx)
+			selector.AddReceive(signals, func(c workflow.ReceiveChannel, _ bool) {
+				var decision ApprovalDecision
+				c.Receive(ctx, &decision)
+				decisions, ok := d.waiting[decision.NodeID]
+				if !ok || !decisions.SendAsync(decision) {
+					workflow.GetLogger(ctx).Warn("Dropping approval for a node that is not waiting", "nodeId", decision.NodeID)
+				}
+			})
+			selector.AddReceive(ctx.Done(), func(workflow.ReceiveChannel, bool) {})
+			selector.Select(ctx)
+		}
+	})
+	return d
+}
+
+// request asks for approval of a node's content and waits in the background
+// for a reviewer, or for the timeout to apply the policy's default action.
+// The returned future settles like a skill activity, with the approved
+// content as output; a rejection settles it with an error.
+func (d *approvalDesk) request(ctx workflow.Context, nodeID, nodeName string, content map[string]interface{}, policy ApprovalPolicy) workflow.Future {
+	var a *Activities
+	future, settable := workflow.NewFuture(ctx)
+	decisions := workflow.NewBufferedChannel(ctx, 1)
+	d.waiting[nodeID] = decisions
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		defer delete(d.waiting, nodeID)
+
+		timeout := policy.timeout()
+		err := workflow.ExecuteActivity(ctx, a.RequestApprovalActivity, ApprovalRequestInput{
+			TaskID:        d.taskID,
+			CompanyID:     d.companyID,
+			NodeID:        nodeID,
+			NodeName:      nodeName,
+			Content:       content,
+			DefaultAction: policy.defaultAction(),
+			ExpiresAt:     workflow.Now(ctx).Add(timeout),
+		}).Get(ctx, nil)
+		if err != nil {
+			settable.SetError(err)
+			return
+		}
+
+		var decision ApprovalDecision
+		timedOut := false
+		timerCtx, cancelTimer := workflow.WithCancel(ctx)
+		selector := workflow.NewSelector(ctx)
+		selector.AddReceive(decisions, func(c workflow.ReceiveChannel, _ bool) {
+			c.Receive(ctx, &decision)
+		})
+		selector.AddFuture(workflow.NewTimer(timerCtx, timeout), func(f workflow.Future) {
+			if f.Get(ctx, nil) == nil {
+				timedOut = true
+				decision = ApprovalDecision{NodeID: nodeID, Action: policy.defaultAction()}
+			}
+		})
+		selector.Select(ctx)
+		cancelTimer()
+		if ctx.Err() != nil {
+			settable.SetError(temporal.NewCanceledError())
+			return
+		}
+
+		err = workflow.ExecuteActivity(ctx, a.RecordApprovalActivity, ApprovalDecisionInput{
+			TaskID:   d.taskID,
+			Decision: decision,
+			TimedOut: timedOut,
+		}).Get(ctx, nil)
+		if err != nil {
+			settable.SetError(err)
+			return
+		}
+
+		switch task.ApprovalAction(decision.Action) {
+		case task.ApprovalReject:
+			settable.SetError(temporal.NewApplicationError(rejectionMessage(decision, timedOut), "ApprovalRejected"))
+		case task.ApprovalEdit:
+			settable.Set(SkillExecutionResult{Success: true, Output: task.ApprovedContent(content, decision.Content)}, nil)
+		default:
+			settable.Set(SkillExecutionResult{Success: true, Output: task.ApprovedContent(content, nil)}, nil)
+		}
+	})
+	return future
+}
+
+// rejectionMessage describes why an approval failed its step
+func rejectionMessage(decision ApprovalDecision, timedOut bool) string {
+	message := "rejected by reviewer"
+	if timedOut {
+		message = "rejected after the approval timed out"
+	}
+	if decision.Comment != "" {
+		message = fmt.Sprintf("%s: %s", message, decision.Comment)
+	}
+	return message
+}
@@ -0,0 +1,226 @@
+package temporal
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// fakeApprovalRepository keeps approvals in memory
+type fakeApprovalRepository struct {
+	task.ApprovalRepository
+	mu        sync.Mutex
+	approvals []*task.Approval
+}
+
+func (r *fakeApprovalRepository) CreateApproval(_ context.Context, a *task.Approval) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.approvals = append(r.approvals, a)
+	return nil
+}
+
+func (r *fakeApprovalRepository) GetPendingApproval(_ context.Context, taskID uuid.UUID, nodeID string) (*task.Approval, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.approvals {
+		if a.TaskID == taskID && a.NodeID == nodeID && a.IsPending() {
+			copied := *a
+			return &copied, nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeApprovalRepository) DecideApproval(_ context.Context, decided *task.Approval) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, a := range r.approvals {
+		if a.ID == decided.ID && a.IsPending() {
+			r.approvals[i] = decided
+			return nil
+		}
+	}
+	return errors.ErrNotFound
+}
+
+func TestDefinitionWorkflow_Approval(t *testing.T) {
+	draft, publish := uuid.New(), uuid.New()
+	reviewer := uuid.New()
+	newDefinition := func(defaultAction string) *definition.Definition {
+		return &definition.Definition{
+			Version: 1,
+			Nodes: []definition.Node{
+				{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft.String()},
+				{ID: "review", Type: definition.NodeApproval, TimeoutSeconds: 3600, DefaultAction: defaultAction},
+				{ID: "publish", Type: definition.NodeSkill, SkillCardID: publish.String()},
+			},
+			Edges: []definition.Edge{{From: "draft", To: "review"}, {From: "review", To: "publish"}},
+		}
+	}
+
+	tests := []struct {
+		name          string
+		defaultAction string
+		decision      *ApprovalDecision
+		wantStatus    task.TaskStatus
+		wantApproval  task.ApprovalStatus
+		wantPublished string
+	}{
+		{
+			name:          "approve",
+			decision:      &ApprovalDecision{NodeID: "review", Action: "approve", DecidedBy: reviewer.String()},
+			wantStatus:    task.StatusCompleted,
+			wantApproval:  task.ApprovalApproved,
+			wantPublished: "draft text",
+		},
+		{
+			name: "edit",
+			decision: &ApprovalDecision{NodeID: "review", Action: "edit", DecidedBy: reviewer.String(),
+				Content: map[string]interface{}{"content": "edited text"}},
+			wantStatus:    task.StatusCompleted,
+			wantApproval:  task.ApprovalEdited,
+			wantPublished: "edited text",
+		},
+		{
+			name:         "reject",
+			decision:     &ApprovalDecision{NodeID: "review", Action: "reject", DecidedBy: reviewer.String(), Comment: "off brand"},
+			wantStatus:   task.StatusFailed,
+			wantApproval: task.ApprovalRejected,
+		},
+		{
+			name:          "timeout applies the default action",
+			defaultAction: definition.ApprovalDefaultApprove,
+			wantStatus:    task.StatusCompleted,
+			wantApproval:  task.ApprovalApproved,
+			wantPublished: "draft text",
+		},
+		{
+			name:         "timeout rejects by default",
+			wantStatus:   task.StatusFailed,
+			wantApproval: task.ApprovalRejected,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running, employees := newRunningTask()
+			tasks := newFakeTaskRepository(running)
+			skills := &scriptedExecutor{
+				outputs: map[uuid.UUID]string{draft: `{"content":"draft text"}`, publish: `{"url":"https://example.com/p/1"}`},
+				params:  make(map[uuid.UUID]map[string]interface{}),
+			}
+			approvals := &fakeApprovalRepository{}
+			bus := eventbus.NewEventBus()
+			a := NewActivities(skills, tasks, employees, bus)
+			a.SetApprovals(approvals)
+
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			env.RegisterWorkflow(DefinitionWorkflow)
+			RegisterActivities(env, a)
+
+			// A decision for a node that is not waiting is dropped
+			env.RegisterDelayedCallback(func() {
+				env.SignalWorkflow(SignalApproval, ApprovalDecision{NodeID: "publish", Action: "reject"})
+			}, 5*time.Minute)
+			if tt.decision != nil {
+				env.RegisterDelayedCallback(func() { env.SignalWorkflow(SignalApproval, *tt.decision) }, 10*time.Minute)
+			}
+
+			env.ExecuteWorkflow(DefinitionWorkflow, DefinitionWorkflowInput{
+				TaskID:     running.ID.String(),
+				CompanyID:  running.CompanyID.String(),
+				EmployeeID: running.AssignedEmployeeID.String(),
+				Definition: newDefinition(tt.defaultAction),
+			})
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+			assert.Equal(t, tt.wantStatus, tasks.tasks[running.ID].Status)
+
+			require.Len(t, approvals.approvals, 1)
+			approval := approvals.approvals[0]
+			assert.Equal(t, "review", approval.NodeID)
+			assert.Equal(t, "draft text", approval.Content["content"])
+			assert.Equal(t, tt.wantApproval, approval.Status)
+			if tt.decision != nil {
+				assert.Equal(t, &reviewer, approval.DecidedBy)
+				assert.False(t, approval.TimedOut)
+			} else {
+				assert.Nil(t, approval.DecidedBy)
+				assert.True(t, approval.TimedOut)
+			}
+
+			if tt.wantPublished != "" {
+				assert.Equal(t, tt.wantPublished, skills.params[publish]["content"])
+			} else {
+				assert.NotContains(t, skills.params, publish, "nothing is published after a rejection")
+				var result WorkflowResult
+				require.NoError(t, env.GetWorkflowResult(&result))
+				assert.Contains(t, result.Error, "rejected")
+			}
+
+			assert.Len(t, bus.GetHistoryByType(eventbus.EventTaskApprovalRequested, 10), 1)
+			assert.Len(t, bus.GetHistoryByType(eventbus.EventTaskApprovalDecided, 10), 1)
+		})
+	}
+}
+
+func TestAutoOpsWorkflow_PublishesApprovedContent(t *testing.T) {
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	skills := &fakeSkillExecutor{output: []byte(`{"title":"draft"}`)}
+	approvals := &fakeApprovalRepository{}
+	publisher := newFakePublisher("xiaohongshu")
+	a := NewActivities(skills, tasks, employees, eventbus.NewEventBus())
+	a.SetApprovals(approvals)
+	a.SetPublisher(publisher)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(AutoOpsWorkflow)
+	RegisterActivities(env, a)
+
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalApproval, ApprovalDecision{
+			NodeID:    "step_2",
+			Action:    "edit",
+			Content:   map[string]interface{}{"title": "reviewed"},
+			DecidedBy: uuid.New().String(),
+		})
+	}, time.Minute)
+
+	env.ExecuteWorkflow(AutoOpsWorkflow, WorkflowInput{
+		TaskID:      running.ID.String(),
+		CompanyID:   running.CompanyID.String(),
+		EmployeeID:  running.AssignedEmployeeID.String(),
+		SkillCardID: uuid.New().String(),
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result WorkflowResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	assert.Equal(t, "completed", result.Status)
+	assert.Equal(t, "reviewed", result.Output["title"])
+	require.Len(t, result.StepsResults, 3)
+	assert.Equal(t, "completed", result.StepsResults[2].Status)
+	assert.Equal(t, task.ApprovalEdited, approvals.approvals[0].Status)
+
+	require.Len(t, publisher.published, 1, "content goes to the default platform")
+	assert.Equal(t, "xiaohongshu", publisher.published[0].platform)
+	assert.Equal(t, "reviewed", publisher.published[0].post.Title)
+	posts := result.StepsResults[2].Output["posts"].(map[string]interface{})
+	assert.Equal(t, "post-1", posts["xiaohongshu"].(map[string]interface{})["postId"])
+}
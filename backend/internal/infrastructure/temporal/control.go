@@ -0,0 +1,72 @@
+package temporal
+
+import (
+	"unlimited-corp/internal/domain/task"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Signals controlling a running task workflow
+const (
+	SignalPause  = "pause"
+	SignalResume = "resume"
+)
+
+// pauseGate tracks the pause and resume signals of a workflow. Workflows
+// wait on it between steps; a step already running is never interrupted.
+type pauseGate struct {
+	paused bool
+}
+
+// newPauseGate starts listening for pause and resume signals
+func newPauseGate(ctx workflow.Context) *pauseGate {
+	g := &pauseGate{}
+	pause := workflow.GetSignalChannel(ctx, SignalPause)
+	resume := workflow.GetSignalChannel(ctx, SignalResume)
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		for ctx.Err() == nil {
+			selector := workflow.NewSelector(ctx)
+			selector.AddReceive(pause, func(c workflow.ReceiveChannel, _ bool) {
+				c.Receive(ctx, nil)
+				g.paused = true
+			})
+			selector.AddReceive(resume, func(c workflow.ReceiveChannel, _ bool) {
+				c.Receive(ctx, nil)
+				g.paused = false
+			})
+			selector.AddReceive(ctx.Done(), func(workflow.ReceiveChannel, bool) {})
+			selector.Select(ctx)
+		}
+	})
+	return g
+}
+
+// wait blocks while the workflow is paused. It fails when the workflow is cancelled.
+func (g *pauseGate) wait(ctx workflow.Context) error {
+	return workflow.Await(ctx, func() bool { return !g.paused })
+}
+
+// endTask records a workflow that stopped early on the task: cancelled when
+// the workflow was cancelled, failed otherwise. It returns the status recorded.
+func endTask(ctx workflow.Context, taskID string, err error) string {
+	return stopTask(ctx, taskID, temporal.IsCanceledError(err), err.Error(), nil)
+}
+
+// stopTask records a workflow that stopped early on the task along with how
+// its compensations ended: cancelled, or failed with the message. It returns
+// the status recorded.
+func stopTask(ctx workflow.Context, taskID string, cancelled bool, message string, compensation *task.Compensation) string {
+	input := TaskStatusInput{TaskID: taskID, Status: "failed", Progress: 100, Error: message, Compensation: compensation}
+	if cancelled {
+		// The workflow context is cancelled; record the outcome on a fresh one
+		disconnected, cancel := workflow.NewDisconnectedContext(ctx)
+		defer cancel()
+		input.Status, input.Error = "cancelled", ""
+		updateTask(disconnected, input)
+		return "cancelled"
+	}
+	updateTask(ctx, input)
+	return "failed"
+}
@@ -0,0 +1,116 @@
+package temporal
+
+import (
+	"context"
+	"fmt"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+)
+
+// WorkflowEngine runs task workflows on Temporal, one DefinitionWorkflow per task
+type WorkflowEngine struct {
+	client *TemporalClient
+}
+
+// NewWorkflowEngine creates a workflow engine using the given client
+func NewWorkflowEngine(client *TemporalClient) *WorkflowEngine {
+	return &WorkflowEngine{client: client}
+}
+
+// TaskWorkflowID returns the workflow ID of a task. It is derived from the
+// task so a task never runs two workflows at once.
+func TaskWorkflowID(t *task.Task) string {
+	return "task-" + t.ID.String()
+}
+
+// Start starts the definition workflow of the task, taking over what an
+// earlier attempt did when resume is set. A workflow already running for the
+// task is returned instead of starting another one.
+func (e *WorkflowEngine) Start(ctx context.Context, t *task.Task, def *definition.Definition, resume *task.Resume) (string, string, error) {
+	input := DefinitionWorkflowInput{
+		TaskID:     t.ID.String(),
+		CompanyID:  t.CompanyID.String(),
+		Definition: def,
+		Input:      t.InputData,
+		Attempt:    1,
+	}
+	if resume != nil {
+		input.Attempt = resume.Attempt
+		input.Reused = resume.Outputs
+		input.StepInputs = resume.StepInputs
+	}
+	if t.AssignedEmployeeID != nil {
+		input.EmployeeID = t.AssignedEmployeeID.String()
+	}
+
+	run, err := e.client.StartWorkflow(ctx, TaskWorkflowID(t), DefinitionWorkflow, input)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to start workflow: %w", err)
+	}
+	return run.GetID(), run.GetRunID(), nil
+}
+
+// Outcome returns how the steps of the task's finished workflow run ended.
+// A step whose compensation undid it leaves no output to reuse.
+func (e *WorkflowEngine) Outcome(ctx context.Context, t *task.Task) (*task.RunOutcome, error) {
+	var result WorkflowResult
+	if err := e.client.GetWorkflowResult(ctx, t.TemporalWorkflowID, t.TemporalRunID, &result); err != nil {
+		return nil, fmt.Errorf("failed to get workflow result: %w", err)
+	}
+
+	outcome := &task.RunOutcome{Outputs: make(map[string]map[string]interface{})}
+	for _, step := range result.StepsResults {
+		switch step.Status {
+		case "completed":
+			outcome.Outputs[step.StepID] = step.Output
+		case task.StepCompensated:
+			delete(outcome.Outputs, step.StepID)
+		case "failed":
+			if outcome.FailedStep == "" {
+				outcome.FailedStep = step.StepID
+			}
+		}
+	}
+	return outcome, nil
+}
+
+// Pause signals the task workflow to pause before its next step
+func (e *WorkflowEngine) Pause(ctx context.Context, t *task.Task) error {
+	return e.client.SignalWorkflow(ctx, t.TemporalWorkflowID, t.TemporalRunID, SignalPause, nil)
+}
+
+// Resume signals a paused task workflow to continue
+func (e *WorkflowEngine) Resume(ctx context.Context, t *task.Task) error {
+	return e.client.SignalWorkflow(ctx, t.TemporalWorkflowID, t.TemporalRunID, SignalResume, nil)
+}
+
+// Cancel cancels the task workflow
+func (e *WorkflowEngine) Cancel(ctx context.Context, t *task.Task) error {
+	return e.client.CancelWorkflow(ctx, t.TemporalWorkflowID, t.TemporalRunID)
+}
+
+// Decide signals a reviewer's decision to the approval the task workflow waits for
+func (e *WorkflowEngine) Decide(ctx context.Context, t *task.Task, d task.ApprovalDecision) error {
+	return e.client.SignalWorkflow(ctx, t.TemporalWorkflowID, t.TemporalRunID, SignalApproval, ApprovalDecision{
+		NodeID:    d.NodeID,
+		Action:    string(d.Action),
+		Content:   d.Content,
+		DecidedBy: d.DecidedBy.String(),
+		Comment:   d.Comment,
+	})
+}
+
+// Progress queries the live pipeline of the task workflow
+func (e *WorkflowEngine) Progress(ctx context.Context, t *task.Task) (*task.Pipeline, error) {
+	response, err := e.client.GetClient().QueryWorkflow(ctx, t.TemporalWorkflowID, t.TemporalRunID, QueryProgress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query workflow progress: %w", err)
+	}
+
+	var pipeline task.Pipeline
+	if err := response.Get(&pipeline); err != nil {
+		return nil, fmt.Errorf("failed to decode workflow progress: %w", err)
+	}
+	return &pipeline, nil
+}
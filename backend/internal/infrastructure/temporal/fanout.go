@@ -0,0 +1,172 @@
+package temporal
+
+import (
+	"errors"
+	"fmt"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// executeMap fans a map node out over its items: each item runs the node's
+// skill card as an activity, or its workflow as a child DefinitionWorkflow,
+// with at most the node's concurrency in flight. No item starts while the
+// workflow is paused. Once enough items failed that the node cannot succeed,
+// the items in flight are cancelled. The returned future resolves to a
+// SkillExecutionResult with the reduced output.
+func executeMap(ctx workflow.Context, input DefinitionWorkflowInput, gate *pauseGate, progress *progressTracker, run *definition.Run, node *definition.Node, params map[string]interface{}) workflow.Future {
+	future, settable := workflow.NewFuture(ctx)
+
+	items, err := run.MapItems(node)
+	if err != nil {
+		settable.SetError(temporal.NewNonRetryableApplicationError(err.Error(), "InvalidInput", nil))
+		return future
+	}
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		result, err := fanOut(ctx, input, gate, progress, node, params, items)
+		if err != nil {
+			settable.SetError(err)
+			return
+		}
+		settable.SetValue(*result)
+	})
+	return future
+}
+
+// fanOut runs the items of a map node and reduces their outputs
+func fanOut(ctx workflow.Context, input DefinitionWorkflowInput, gate *pauseGate, progress *progressTracker, node *definition.Node, params map[string]interface{}, items []interface{}) (*SkillExecutionResult, error) {
+	spec := node.Map
+	itemsCtx, cancelItems := workflow.WithCancel(ctx)
+	defer cancelItems()
+
+	results := make([]definition.MapItemResult, len(items))
+	for i := range results {
+		results[i].Index = i
+	}
+	counts := task.ItemProgress{Total: len(items)}
+	tokens := 0
+	selector := workflow.NewSelector(ctx)
+	next, running := 0, 0
+	progress.items(ctx, node.ID, counts)
+
+	for {
+		for running < spec.Limit() && next < len(items) && !spec.Hopeless(len(items), counts.Failed) {
+			if err := gate.wait(ctx); err != nil {
+				break
+			}
+			i := next
+			next++
+			itemInput := definition.ItemInput(params, items[i], i)
+
+			var future workflow.Future
+			if spec.Workflow != nil {
+				future = executeChild(itemsCtx, input, node, itemInput)
+			} else {
+				future = executeSkill(itemsCtx, input, node, node.SkillCardID, itemInput)
+			}
+			running++
+			selector.AddFuture(future, func(f workflow.Future) {
+				running--
+				results[i].Done = true
+				output, used, err := itemOutput(ctx, f, spec.Workflow != nil)
+				tokens += used
+				if err != nil {
+					results[i].Error = errorMessage(err)
+					counts.Failed++
+					return
+				}
+				results[i].Output = output
+				counts.Succeeded++
+			})
+		}
+		if running == 0 {
+			break
+		}
+		selector.Select(ctx)
+		progress.items(ctx, node.ID, counts)
+		if spec.Hopeless(len(items), counts.Failed) {
+			cancelItems()
+		}
+	}
+	if ctx.Err() != nil {
+		return nil, temporal.NewCanceledError()
+	}
+
+	var judgement map[string]interface{}
+	if spec.NeedsJudge() && counts.Succeeded > 0 && counts.Succeeded >= spec.Required(len(items)) {
+		var judged SkillExecutionResult
+		err := executeSkill(ctx, input, node, spec.Reduce.JudgeSkillCardID, spec.JudgeInput(results)).Get(ctx, &judged)
+		if err == nil && !judged.Success {
+			err = fmt.Errorf("judge failed: %s", judged.Error)
+		}
+		if err != nil {
+			return nil, err
+		}
+		tokens += judged.TokensUsed
+		judgement = judged.Output
+	}
+
+	output, err := spec.Combine(results, judgement)
+	if err != nil {
+		return nil, temporal.NewNonRetryableApplicationError(err.Error(), "MapFailed", nil)
+	}
+	return &SkillExecutionResult{Success: true, Output: output, TokensUsed: tokens}, nil
+}
+
+// executeChild starts the child workflow of a map node for one item
+func executeChild(ctx workflow.Context, input DefinitionWorkflowInput, node *definition.Node, itemInput map[string]interface{}) workflow.Future {
+	child := *node.Map.Workflow
+	if child.Version == 0 {
+		child.Version = input.Definition.Version
+	}
+	return workflow.ExecuteChildWorkflow(ctx, DefinitionWorkflow, DefinitionWorkflowInput{
+		TaskID:     input.TaskID,
+		CompanyID:  input.CompanyID,
+		EmployeeID: nodeEmployee(input, node),
+		Definition: &child,
+		Input:      itemInput,
+		Attempt:    input.Attempt,
+		Child:      true,
+	})
+}
+
+// itemOutput returns the output of a finished item and the tokens it used
+func itemOutput(ctx workflow.Context, f workflow.Future, child bool) (map[string]interface{}, int, error) {
+	if child {
+		var result WorkflowResult
+		if err := f.Get(ctx, &result); err != nil {
+			return nil, 0, err
+		}
+		tokens := 0
+		for _, step := range result.StepsResults {
+			tokens += step.TokensUsed
+		}
+		return result.Output, tokens, nil
+	}
+
+	var result SkillExecutionResult
+	if err := f.Get(ctx, &result); err != nil {
+		return nil, 0, err
+	}
+	if !result.Success {
+		return nil, result.TokensUsed, fmt.Errorf("skill failed: %s", result.Error)
+	}
+	return result.Output, result.TokensUsed, nil
+}
+
+// errorMessage returns the message of a failed activity or child workflow
+// without the wrappers around it
+func errorMessage(err error) string {
+	var appErr *temporal.ApplicationError
+	if errors.As(err, &appErr) {
+		return appErr.Message()
+	}
+	if temporal.IsCanceledError(err) {
+		return "cancelled"
+	}
+	return err.Error()
+}
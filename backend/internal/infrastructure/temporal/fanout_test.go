@@ -0,0 +1,197 @@
+package temporal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"unlimited-corp/internal/application/executor"
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/eventbus"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// itemExecutor writes "<item>!" for every item, fails on the item "bad",
+// and judges by picking the candidate at index 1
+type itemExecutor struct {
+	mu    sync.Mutex
+	judge uuid.UUID
+	calls []map[string]interface{}
+}
+
+func (e *itemExecutor) Execute(_ context.Context, execCtx *executor.ExecutionContext) (*executor.ExecutionResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = append(e.calls, execCtx.Input)
+	if execCtx.SkillCardID == e.judge {
+		return &executor.ExecutionResult{Success: true, Output: json.RawMessage(`{"best":1}`)}, nil
+	}
+	item := execCtx.Input["item"]
+	if text, ok := execCtx.Input["text"]; ok {
+		item = text
+	}
+	if item == "bad" {
+		return nil, errors.New("model refused")
+	}
+	output, _ := json.Marshal(map[string]interface{}{"text": fmt.Sprintf("%v!", item), "tokens": 1})
+	return &executor.ExecutionResult{Success: true, Output: output, TokensUsed: 10}, nil
+}
+
+func runMapWorkflow(t *testing.T, def *definition.Definition, skills *itemExecutor, input map[string]interface{}) (*WorkflowResult, *fakeTaskRepository, *task.Task, *eventbus.MemoryBus) {
+	t.Helper()
+	require.NoError(t, def.Validate())
+
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	bus := eventbus.NewEventBus()
+	a := NewActivities(skills, tasks, employees, bus)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(DefinitionWorkflow)
+	RegisterActivities(env, a)
+
+	env.ExecuteWorkflow(DefinitionWorkflow, DefinitionWorkflowInput{
+		TaskID:     running.ID.String(),
+		CompanyID:  running.CompanyID.String(),
+		EmployeeID: running.AssignedEmployeeID.String(),
+		Definition: def,
+		Input:      input,
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result WorkflowResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	return &result, tasks, running, bus
+}
+
+func TestDefinitionWorkflow_MapRanksVariants(t *testing.T) {
+	caption, judge, publish := uuid.New(), uuid.New(), uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "captions", Type: definition.NodeMap, SkillCardID: caption.String(), MaxAttempts: 1,
+				Inputs: map[string]interface{}{"tone": "$.input.tone"},
+				Map: &definition.MapSpec{
+					Items:        "$.input.products",
+					Concurrency:  2,
+					Require:      definition.MapRequireCount,
+					RequireCount: 2,
+					Reduce:       &definition.MapReduce{Strategy: definition.MapReduceRank, JudgeSkillCardID: judge.String(), Criteria: "catchy"},
+				}},
+			{ID: "publish", Type: definition.NodeSkill, SkillCardID: publish.String(),
+				Inputs: map[string]interface{}{"text": "$.nodes.captions.output.result.text"}},
+		},
+		Edges: []definition.Edge{{From: "captions", To: "publish"}},
+	}
+	skills := &itemExecutor{judge: judge}
+
+	result, tasks, running, bus := runMapWorkflow(t, def, skills, map[string]interface{}{
+		"products": []interface{}{"mug", "bad", "tee"},
+		"tone":     "fun",
+	})
+	assert.Equal(t, "completed", result.Status)
+	assert.Equal(t, "tee!!", result.Output["text"], "publish got the variant the judge picked")
+	assert.Equal(t, task.StatusCompleted, tasks.tasks[running.ID].Status)
+
+	var judged map[string]interface{}
+	for _, call := range skills.calls {
+		if _, ok := call["candidates"]; ok {
+			judged = call
+		}
+	}
+	require.NotNil(t, judged)
+	assert.Equal(t, "catchy", judged["criteria"])
+	assert.Len(t, judged["candidates"], 2)
+
+	var captions StepResult
+	for _, step := range result.StepsResults {
+		if step.StepID == "captions" {
+			captions = step
+		}
+	}
+	assert.Equal(t, "completed", captions.Status)
+	assert.EqualValues(t, 2, captions.Output["succeeded"])
+	assert.EqualValues(t, 1, captions.Output["failed"])
+	assert.Equal(t, 20, captions.TokensUsed)
+
+	// Items report progress while the map step runs
+	var reported []int
+	for _, event := range bus.GetHistoryByType(eventbus.EventTaskProgress, 20) {
+		var payload task.Task
+		require.NoError(t, json.Unmarshal(event.Payload, &payload))
+		reported = append(reported, payload.Progress)
+	}
+	assert.Contains(t, reported, 16, "one of three items of the first of two steps")
+	assert.Contains(t, reported, 50)
+}
+
+func TestDefinitionWorkflow_MapChildWorkflows(t *testing.T) {
+	draft, polish := uuid.New(), uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "posts", Type: definition.NodeMap, MaxAttempts: 1, Map: &definition.MapSpec{
+				Items: "$.input.topics",
+				Workflow: &definition.Definition{
+					Nodes: []definition.Node{
+						{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft.String(), MaxAttempts: 1},
+						{ID: "polish", Type: definition.NodeSkill, SkillCardID: polish.String(), MaxAttempts: 1,
+							Inputs: map[string]interface{}{"text": "$.nodes.draft.output.text"}},
+					},
+					Edges: []definition.Edge{{From: "draft", To: "polish"}},
+				},
+			}},
+		},
+	}
+
+	t.Run("completes", func(t *testing.T) {
+		result, tasks, running, _ := runMapWorkflow(t, def, &itemExecutor{}, map[string]interface{}{
+			"topics": []interface{}{"go", "rust"},
+		})
+		assert.Equal(t, "completed", result.Status)
+		assert.Equal(t, []interface{}{
+			map[string]interface{}{"text": "go!!", "tokens": float64(1)},
+			map[string]interface{}{"text": "rust!!", "tokens": float64(1)},
+		}, result.Output["results"])
+		assert.Equal(t, task.StatusCompleted, tasks.tasks[running.ID].Status)
+	})
+
+	t.Run("fails with the child's error", func(t *testing.T) {
+		result, tasks, running, _ := runMapWorkflow(t, def, &itemExecutor{}, map[string]interface{}{
+			"topics": []interface{}{"go", "bad"},
+		})
+		assert.Equal(t, "failed", result.Status)
+		assert.Contains(t, result.Error, "item 1: node draft failed")
+		assert.Contains(t, result.Error, "model refused")
+		assert.Equal(t, task.StatusFailed, tasks.tasks[running.ID].Status)
+	})
+}
+
+func TestDefinitionWorkflow_MapStopsWhenHopeless(t *testing.T) {
+	caption := uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "captions", Type: definition.NodeMap, SkillCardID: caption.String(), MaxAttempts: 1,
+				Map: &definition.MapSpec{Items: "$.input.products", Concurrency: 1}},
+		},
+	}
+	skills := &itemExecutor{}
+
+	result, _, _, _ := runMapWorkflow(t, def, skills, map[string]interface{}{
+		"products": []interface{}{"bad", "mug", "tee"},
+	})
+	assert.Equal(t, "failed", result.Status)
+	assert.Contains(t, result.Error, "0 of 3 items succeeded, 3 required; item 0: model refused")
+	assert.Len(t, skills.calls, 1, "no item starts once the map cannot succeed")
+}
@@ -0,0 +1,338 @@
+package temporal
+
+import (
+	"fmt"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// Default activity settings of definition nodes
+const (
+	defaultNodeTimeout     = 5 * time.Minute
+	defaultNodeMaxAttempts = 3
+)
+
+// DefinitionWorkflowInput is the input of DefinitionWorkflow. The definition
+// travels with the input so a run never changes when the stored one does.
+type DefinitionWorkflowInput struct {
+	TaskID     string                 `json:"taskId"`
+	CompanyID  string                 `json:"companyId"`
+	EmployeeID string                 `json:"employeeId"`
+	Definition *definition.Definition `json:"definition"`
+	Input      map[string]interface{} `json:"input"`
+	// Reused holds node outputs taken over from an earlier attempt of the
+	// task; those nodes do not run again
+	Reused map[string]map[string]interface{} `json:"reused,omitempty"`
+	// StepInputs are merged over the resolved input of the given nodes
+	StepInputs map[string]map[string]interface{} `json:"stepInputs,omitempty"`
+	// Attempt is the number of this run of the task, which its steps are recorded under
+	Attempt int `json:"attempt,omitempty"`
+	// Child marks the run of a map node's workflow for one item. A child
+	// leaves the task and its steps to the parent and fails with an error.
+	Child bool `json:"child,omitempty"`
+}
+
+// DefinitionWorkflow interprets a workflow definition: it runs every node
+// whose predecessors are done, in parallel, until the graph is exhausted or
+// a node fails. All scheduling decisions come from definition.Run, which is
+// deterministic, so any definition replays safely. When the run fails or is
+// cancelled, the completed nodes with a compensation are undone, the last
+// completed first.
+func DefinitionWorkflow(ctx workflow.Context, input DefinitionWorkflowInput) (*WorkflowResult, error) {
+	logger := workflow.GetLogger(ctx)
+	logger.Info("Starting definition workflow", "taskId", input.TaskID, "definition", input.Definition.Name)
+
+	result := &WorkflowResult{
+		TaskID:       input.TaskID,
+		Status:       "running",
+		StepsResults: make([]StepResult, 0, len(input.Definition.Nodes)),
+		StartedAt:    workflow.Now(ctx),
+	}
+
+	// Bookkeeping activities such as finishTask use these options; nodes set their own
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	})
+
+	run := definition.NewRun(input.Definition, input.Input)
+	selector := workflow.NewSelector(ctx)
+	gate := newPauseGate(ctx)
+	approvals := newApprovalDesk(ctx, input.TaskID, input.CompanyID)
+	steps := make([]task.PipelineStep, 0, len(input.Definition.Nodes))
+	for _, node := range input.Definition.Nodes {
+		steps = append(steps, task.PipelineStep{ID: node.ID, Name: node.DisplayName()})
+	}
+	trackedTaskID := input.TaskID
+	if input.Child {
+		trackedTaskID = ""
+	}
+	progress := newProgressTracker(ctx, trackedTaskID, input.Attempt, gate, steps...)
+	compensations := &saga{}
+	running := 0
+
+	for {
+		// While paused no node starts; waiting fails once the workflow is cancelled
+		if err := gate.wait(ctx); err != nil {
+			break
+		}
+		// Reused nodes complete at once, which can make their successors ready
+		for ready := run.Next(); len(ready) > 0; ready = run.Next() {
+			for _, node := range ready {
+				if output, ok := input.Reused[node.ID]; ok {
+					run.Complete(node.ID, output)
+					step := StepResult{
+						StepID:   node.ID,
+						StepName: node.DisplayName(),
+						Status:   "completed",
+						Output:   output,
+						Reused:   true,
+					}
+					result.StepsResults = append(result.StepsResults, step)
+					progress.record(ctx, step)
+					addCompensation(compensations, input, run, node)
+					continue
+				}
+
+				params := run.Inputs(node)
+				for k, v := range input.StepInputs[node.ID] {
+					params[k] = v
+				}
+				var future workflow.Future
+				if node.Type == definition.NodeMap {
+					future = executeMap(ctx, input, gate, progress, run, node, params)
+				} else {
+					future = executeNode(ctx, input, approvals, compensations, node, params)
+				}
+				progress.start(ctx, node.ID, stepRun{
+					SkillCardID: node.SkillCardID,
+					EmployeeID:  nodeEmployee(input, node),
+					Input:       params,
+				})
+				running++
+
+				node, startedAt := node, workflow.Now(ctx)
+				selector.AddFuture(future, func(f workflow.Future) {
+					running--
+					step := StepResult{
+						StepID:      node.ID,
+						StepName:    node.DisplayName(),
+						StartedAt:   startedAt,
+						CompletedAt: workflow.Now(ctx),
+					}
+
+					var skillResult SkillExecutionResult
+					err := f.Get(ctx, &skillResult)
+					if err == nil && !skillResult.Success {
+						err = fmt.Errorf("skill failed: %s", skillResult.Error)
+					}
+					if err != nil {
+						run.Fail(node.ID, err.Error())
+						step.Status = "failed"
+						if temporal.IsCanceledError(err) {
+							step.Status = "cancelled"
+						}
+						step.Error = err.Error()
+					} else {
+						run.Complete(node.ID, skillResult.Output)
+						step.Status = "completed"
+						step.Output = skillResult.Output
+						step.TokensUsed = skillResult.TokensUsed
+						addCompensation(compensations, input, run, node)
+					}
+					result.StepsResults = append(result.StepsResults, step)
+					progress.record(ctx, step)
+				})
+			}
+		}
+		for _, node := range input.Definition.Nodes {
+			if run.Status(node.ID) == definition.NodeSkipped {
+				progress.record(ctx, StepResult{StepID: node.ID, Status: task.StepSkipped})
+			}
+		}
+
+		if running == 0 {
+			break
+		}
+		selector.Select(ctx)
+		if !run.Failed() && ctx.Err() == nil {
+			progress.report(ctx)
+		}
+	}
+
+	for _, node := range input.Definition.Nodes {
+		if run.Status(node.ID) == definition.NodeSkipped {
+			step := StepResult{
+				StepID:   node.ID,
+				StepName: node.DisplayName(),
+				Status:   "skipped",
+			}
+			result.StepsResults = append(result.StepsResults, step)
+		}
+	}
+
+	var outcomes []definition.CompensationResult
+	if ctx.Err() != nil || run.Failed() {
+		outcomes = compensations.compensate(ctx, progress, result)
+	}
+	compensated := definition.DescribeCompensations(outcomes)
+
+	result.CompletedAt = workflow.Now(ctx)
+	if input.Child {
+		return childResult(ctx, run, result, compensated)
+	}
+	if ctx.Err() != nil {
+		cancelled := temporal.NewCanceledError()
+		result.Error = compensated
+		result.Status = stopTask(ctx, input.TaskID, true, "", result.Compensation)
+		logger.Info("Definition workflow cancelled", "taskId", input.TaskID)
+		return result, cancelled
+	}
+	if run.Failed() {
+		result.Error = withCompensations(failure(result), compensated)
+		result.Status = stopTask(ctx, input.TaskID, false, result.Error, result.Compensation)
+		return result, nil
+	}
+
+	result.Status = "completed"
+	result.Output = run.Output()
+	finishTask(ctx, input.TaskID, "completed", result.Output, "")
+
+	logger.Info("Definition workflow completed", "taskId", input.TaskID)
+	return result, nil
+}
+
+// childResult ends the run of a map node's workflow, leaving the task to
+// the parent: a child that did not complete fails with an error, which
+// includes how its compensations ended
+func childResult(ctx workflow.Context, run *definition.Run, result *WorkflowResult, compensated string) (*WorkflowResult, error) {
+	if ctx.Err() != nil {
+		result.Status = "cancelled"
+		result.Error = compensated
+		return result, temporal.NewCanceledError()
+	}
+	if run.Failed() {
+		message := withCompensations(failure(result), compensated)
+		return nil, temporal.NewNonRetryableApplicationError(message, "ChildWorkflowFailed", nil)
+	}
+	result.Status = "completed"
+	result.Output = run.Output()
+	return result, nil
+}
+
+// failure describes the first step of a run that failed
+func failure(result *WorkflowResult) string {
+	for _, step := range result.StepsResults {
+		if step.Status == "failed" {
+			return fmt.Sprintf("node %s failed: %s", step.StepID, step.Error)
+		}
+	}
+	return ""
+}
+
+// addCompensation registers the compensation of a completed node, with
+// inputs resolved from its output
+func addCompensation(s *saga, input DefinitionWorkflowInput, run *definition.Run, node *definition.Node) {
+	if node.Type == definition.NodePublish {
+		addUnpublish(s, input, node, run.NodeOutput(node.ID))
+		return
+	}
+	if node.Compensate == nil {
+		return
+	}
+	params := run.CompensationInputs(node)
+	s.add(node.ID, node.DisplayName(), func(ctx workflow.Context) error {
+		var undone SkillExecutionResult
+		if err := executeSkill(ctx, input, node, node.Compensate.SkillCardID, params).Get(ctx, &undone); err != nil {
+			return err
+		}
+		if !undone.Success {
+			return fmt.Errorf("skill failed: %s", undone.Error)
+		}
+		return nil
+	})
+}
+
+// executeNode starts the activity of a node, or asks for the approval of an
+// approval node's input
+func executeNode(ctx workflow.Context, input DefinitionWorkflowInput, approvals *approvalDesk, s *saga, node *definition.Node, params map[string]interface{}) workflow.Future {
+	switch node.Type {
+	case definition.NodeApproval:
+		return approvals.request(ctx, node.ID, node.DisplayName(), params, ApprovalPolicy{
+			TimeoutSeconds: node.TimeoutSeconds,
+			DefaultAction:  node.DefaultAction,
+		})
+	case definition.NodePublish:
+		return executePublish(ctx, input, s, node, params)
+	case definition.NodeHotspots:
+		return executeHotspots(ctx, input, node, params)
+	}
+	return executeSkill(ctx, input, node, node.SkillCardID, params)
+}
+
+// executeSkill starts the activity running a skill card for a node, with
+// the node's timeout and attempts
+func executeSkill(ctx workflow.Context, input DefinitionWorkflowInput, node *definition.Node, skillCardID string, params map[string]interface{}) workflow.Future {
+	var a *Activities
+	return workflow.ExecuteActivity(nodeContext(ctx, node), a.ExecuteSkillActivity, SkillExecutionInput{
+		TaskID:      input.TaskID,
+		SkillCardID: skillCardID,
+		EmployeeID:  nodeEmployee(input, node),
+		Parameters:  params,
+	})
+}
+
+// executeHotspots starts the activity analyzing the hotspots of a node's
+// platform and category, with the node's timeout and attempts
+func executeHotspots(ctx workflow.Context, input DefinitionWorkflowInput, node *definition.Node, params map[string]interface{}) workflow.Future {
+	var a *Activities
+	platform, _ := params["platform"].(string)
+	category, _ := params["category"].(string)
+	return workflow.ExecuteActivity(nodeContext(ctx, node), a.AnalyzeHotspotsActivity, HotspotAnalysisInput{
+		CompanyID: input.CompanyID,
+		Platform:  platform,
+		Category:  category,
+	})
+}
+
+// nodeContext returns ctx with the activity options of a node: its timeout
+// and attempts
+func nodeContext(ctx workflow.Context, node *definition.Node) workflow.Context {
+	timeout := defaultNodeTimeout
+	if node.TimeoutSeconds > 0 {
+		timeout = time.Duration(node.TimeoutSeconds) * time.Second
+	}
+	attempts := int32(defaultNodeMaxAttempts)
+	if node.MaxAttempts > 0 {
+		attempts = int32(node.MaxAttempts)
+	}
+
+	return workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: timeout,
+		HeartbeatTimeout:    30 * time.Second,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    attempts,
+		},
+	})
+}
+
+// nodeEmployee returns the employee a node runs as: its own or the task's
+func nodeEmployee(input DefinitionWorkflowInput, node *definition.Node) string {
+	if node.EmployeeID != "" {
+		return node.EmployeeID
+	}
+	return input.EmployeeID
+}
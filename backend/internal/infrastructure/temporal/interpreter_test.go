@@ -0,0 +1,370 @@
+package temporal
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/application/executor"
+	"unlimited-corp/internal/domain/hotspot"
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/eventbus"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// scriptedExecutor returns a fixed output per skill card and records the parameters it got
+type scriptedExecutor struct {
+	mu      sync.Mutex
+	outputs map[uuid.UUID]string
+	failing map[uuid.UUID]bool
+	params  map[uuid.UUID]map[string]interface{}
+}
+
+func (e *scriptedExecutor) Execute(_ context.Context, execCtx *executor.ExecutionContext) (*executor.ExecutionResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.params[execCtx.SkillCardID] = execCtx.Input
+	if e.failing[execCtx.SkillCardID] {
+		return nil, errors.New("provider unavailable")
+	}
+	return &executor.ExecutionResult{Success: true, Output: json.RawMessage(e.outputs[execCtx.SkillCardID])}, nil
+}
+
+func TestDefinitionWorkflow(t *testing.T) {
+	draft, review, publish := uuid.New(), uuid.New(), uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft.String(),
+				Inputs: map[string]interface{}{"topic": "$.input.topic"}},
+			{ID: "review", Type: definition.NodeSkill, SkillCardID: review.String()},
+			{ID: "illustrate", Type: definition.NodeSkill, SkillCardID: review.String(),
+				Condition: &definition.Condition{Path: "$.input.with_images", Op: definition.OpEq, Value: true}},
+			{ID: "publish", Type: definition.NodeSkill, SkillCardID: publish.String(),
+				Inputs: map[string]interface{}{"text": "$.nodes.draft.output.content"}},
+		},
+		Edges: []definition.Edge{
+			{From: "draft", To: "review"},
+			{From: "draft", To: "illustrate"},
+			{From: "review", To: "publish"},
+			{From: "illustrate", To: "publish"},
+		},
+	}
+	require.NoError(t, def.Validate())
+
+	tests := []struct {
+		name       string
+		failing    uuid.UUID
+		wantStatus string
+	}{
+		{"completes", uuid.Nil, "completed"},
+		{"fails when a node fails", publish, "failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			running, employees := newRunningTask()
+			tasks := newFakeTaskRepository(running)
+			skills := &scriptedExecutor{
+				outputs: map[uuid.UUID]string{
+					draft:   `{"content":"draft text"}`,
+					review:  `{"approved":true}`,
+					publish: `{"url":"https://example.com/p/1"}`,
+				},
+				failing: map[uuid.UUID]bool{tt.failing: true},
+				params:  make(map[uuid.UUID]map[string]interface{}),
+			}
+			a := NewActivities(skills, tasks, employees, eventbus.NewEventBus())
+
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestWorkflowEnvironment()
+			env.RegisterWorkflow(DefinitionWorkflow)
+			RegisterActivities(env, a)
+
+			env.ExecuteWorkflow(DefinitionWorkflow, DefinitionWorkflowInput{
+				TaskID:     running.ID.String(),
+				CompanyID:  running.CompanyID.String(),
+				EmployeeID: running.AssignedEmployeeID.String(),
+				Definition: def,
+				Input:      map[string]interface{}{"topic": "go", "with_images": false},
+			})
+			require.True(t, env.IsWorkflowCompleted())
+			require.NoError(t, env.GetWorkflowError())
+
+			var result WorkflowResult
+			require.NoError(t, env.GetWorkflowResult(&result))
+			assert.Equal(t, tt.wantStatus, result.Status)
+
+			statuses := make(map[string]string)
+			for _, step := range result.StepsResults {
+				statuses[step.StepID] = step.Status
+			}
+			assert.Equal(t, "skipped", statuses["illustrate"])
+			assert.Equal(t, "go", skills.params[draft]["topic"])
+			assert.Equal(t, "draft text", skills.params[publish]["text"])
+			assert.Equal(t, tt.wantStatus, string(tasks.tasks[running.ID].Status))
+
+			if tt.wantStatus == "completed" {
+				assert.Equal(t, "https://example.com/p/1", result.Output["url"])
+			} else {
+				assert.Contains(t, result.Error, "node publish failed")
+			}
+		})
+	}
+}
+
+func TestDefinitionWorkflow_HotspotsNode(t *testing.T) {
+	suggest := uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "analyze", Type: definition.NodeHotspots},
+			{ID: "suggest", Type: definition.NodeSkill, SkillCardID: suggest.String(),
+				Inputs: map[string]interface{}{"product": "$.nodes.analyze.output.summary", "keywords": "$.nodes.analyze.output.keywords"}},
+		},
+		Edges: []definition.Edge{{From: "analyze", To: "suggest"}},
+	}
+	require.NoError(t, def.Validate())
+
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	skills := &scriptedExecutor{
+		outputs: map[uuid.UUID]string{suggest: `{"ideas":["AI Agents 上手指南"]}`},
+		params:  make(map[uuid.UUID]map[string]interface{}),
+	}
+	a := NewActivities(skills, tasks, employees, eventbus.NewEventBus())
+	a.SetHotspots(&fakeHotspotAnalyzer{topics: []hotspot.Topic{
+		{Key: "aiagents", Title: "AI Agents", Heat: 1200, Trend: hotspot.TrendNew},
+		{Key: "remotework", Title: "Remote work", Heat: 500, Trend: hotspot.TrendFalling},
+	}})
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(DefinitionWorkflow)
+	RegisterActivities(env, a)
+
+	env.ExecuteWorkflow(DefinitionWorkflow, DefinitionWorkflowInput{
+		TaskID:     running.ID.String(),
+		CompanyID:  running.CompanyID.String(),
+		EmployeeID: running.AssignedEmployeeID.String(),
+		Definition: def,
+		Input:      map[string]interface{}{"platform": "weibo", "category": "tech"},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result WorkflowResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	assert.Equal(t, "completed", result.Status)
+	analysis := result.StepsResults[0].Output
+	assert.Equal(t, "weibo", analysis["platform"])
+	assert.Equal(t, "tech", analysis["category"])
+	assert.Equal(t, []interface{}{"建议关注「AI Agents」"}, analysis["recommendations"])
+	assert.Equal(t, "AI Agents、Remote work", skills.params[suggest]["product"])
+	assert.Equal(t, []interface{}{"AI Agents", "Remote work"}, skills.params[suggest]["keywords"])
+	assert.Equal(t, task.StatusCompleted, tasks.tasks[running.ID].Status)
+}
+
+func TestDefinitionWorkflow_PauseAndCancel(t *testing.T) {
+	draft, review := uuid.New(), uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft.String()},
+			{ID: "review", Type: definition.NodeSkill, SkillCardID: review.String()},
+		},
+		Edges: []definition.Edge{{From: "draft", To: "review"}},
+	}
+
+	setup := func() (*testsuite.TestWorkflowEnvironment, *fakeTaskRepository, *scriptedExecutor, DefinitionWorkflowInput) {
+		running, employees := newRunningTask()
+		tasks := newFakeTaskRepository(running)
+		skills := &scriptedExecutor{
+			outputs: map[uuid.UUID]string{draft: `{"ok":true}`, review: `{"ok":true}`},
+			params:  make(map[uuid.UUID]map[string]interface{}),
+		}
+		a := NewActivities(skills, tasks, employees, eventbus.NewEventBus())
+
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterWorkflow(DefinitionWorkflow)
+		RegisterActivities(env, a)
+
+		input := DefinitionWorkflowInput{
+			TaskID:     running.ID.String(),
+			CompanyID:  running.CompanyID.String(),
+			EmployeeID: running.AssignedEmployeeID.String(),
+			Definition: def,
+		}
+		return env, tasks, skills, input
+	}
+
+	t.Run("resume continues with the next step", func(t *testing.T) {
+		env, tasks, _, input := setup()
+		env.RegisterDelayedCallback(func() { env.SignalWorkflow(SignalPause, nil) }, 0)
+		env.RegisterDelayedCallback(func() { env.SignalWorkflow(SignalResume, nil) }, time.Hour)
+
+		start := env.Now()
+		env.ExecuteWorkflow(DefinitionWorkflow, input)
+		require.True(t, env.IsWorkflowCompleted())
+		require.NoError(t, env.GetWorkflowError())
+
+		var result WorkflowResult
+		require.NoError(t, env.GetWorkflowResult(&result))
+		assert.Equal(t, "completed", result.Status)
+		require.Len(t, result.StepsResults, 2)
+		// The first step was in flight when the pause arrived; the second waits for the resume
+		assert.True(t, result.StepsResults[0].StartedAt.Before(start.Add(time.Hour)))
+		assert.False(t, result.StepsResults[1].StartedAt.Before(start.Add(time.Hour)), "no step starts while paused")
+		assert.Equal(t, task.StatusCompleted, tasks.tasks[uuid.MustParse(input.TaskID)].Status)
+	})
+
+	t.Run("cancel while paused", func(t *testing.T) {
+		env, tasks, skills, input := setup()
+		env.RegisterDelayedCallback(func() { env.SignalWorkflow(SignalPause, nil) }, 0)
+		env.RegisterDelayedCallback(env.CancelWorkflow, time.Minute)
+
+		env.ExecuteWorkflow(DefinitionWorkflow, input)
+		require.True(t, env.IsWorkflowCompleted())
+		assert.True(t, temporal.IsCanceledError(env.GetWorkflowError()))
+
+		assert.Contains(t, skills.params, draft)
+		assert.NotContains(t, skills.params, review, "no step starts after the pause")
+		assert.Equal(t, task.StatusCancelled, tasks.tasks[uuid.MustParse(input.TaskID)].Status)
+	})
+}
+
+func TestDefinitionWorkflow_Progress(t *testing.T) {
+	draft, review := uuid.New(), uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "draft", Name: "Draft", Type: definition.NodeSkill, SkillCardID: draft.String()},
+			{ID: "review", Type: definition.NodeSkill, SkillCardID: review.String()},
+		},
+		Edges: []definition.Edge{{From: "draft", To: "review"}},
+	}
+
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	skills := &scriptedExecutor{
+		outputs: map[uuid.UUID]string{draft: `{"ok":true}`, review: `{"ok":true}`},
+		params:  make(map[uuid.UUID]map[string]interface{}),
+	}
+	bus := eventbus.NewEventBus()
+	a := NewActivities(skills, tasks, employees, bus)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(DefinitionWorkflow)
+	RegisterActivities(env, a)
+
+	// Pause after the first step and look at the pipeline while paused
+	var paused task.Pipeline
+	env.RegisterDelayedCallback(func() { env.SignalWorkflow(SignalPause, nil) }, 0)
+	env.RegisterDelayedCallback(func() {
+		value, err := env.QueryWorkflow(QueryProgress)
+		require.NoError(t, err)
+		require.NoError(t, value.Get(&paused))
+	}, 30*time.Minute)
+	env.RegisterDelayedCallback(func() { env.SignalWorkflow(SignalResume, nil) }, time.Hour)
+
+	env.ExecuteWorkflow(DefinitionWorkflow, DefinitionWorkflowInput{
+		TaskID:     running.ID.String(),
+		CompanyID:  running.CompanyID.String(),
+		EmployeeID: running.AssignedEmployeeID.String(),
+		Definition: def,
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	assert.True(t, paused.Paused)
+	assert.Equal(t, 50, paused.Percent)
+	assert.Empty(t, paused.CurrentStep)
+	require.Len(t, paused.Steps, 2)
+	assert.Equal(t, "Draft", paused.Steps[0].Name)
+	assert.Equal(t, task.StepCompleted, paused.Steps[0].Status)
+	assert.Equal(t, task.StepPending, paused.Steps[1].Status)
+
+	value, err := env.QueryWorkflow(QueryProgress)
+	require.NoError(t, err)
+	var final task.Pipeline
+	require.NoError(t, value.Get(&final))
+	assert.Equal(t, 100, final.Percent)
+	assert.False(t, final.Paused)
+
+	// Every step reported its progress on the task
+	var reported []int
+	for _, event := range bus.GetHistoryByType(eventbus.EventTaskProgress, 10) {
+		var payload task.Task
+		require.NoError(t, json.Unmarshal(event.Payload, &payload))
+		reported = append(reported, payload.Progress)
+	}
+	assert.ElementsMatch(t, []int{50, 100}, reported)
+	assert.Equal(t, 100, tasks.tasks[running.ID].Progress)
+}
+
+func TestDefinitionWorkflow_ResumesAnEarlierAttempt(t *testing.T) {
+	draft, review, publish := uuid.New(), uuid.New(), uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft.String()},
+			{ID: "review", Type: definition.NodeSkill, SkillCardID: review.String()},
+			{ID: "publish", Type: definition.NodeSkill, SkillCardID: publish.String(),
+				Inputs: map[string]interface{}{"text": "$.nodes.draft.output.content", "channel": "weibo"}},
+		},
+		Edges: []definition.Edge{{From: "draft", To: "review"}, {From: "review", To: "publish"}},
+	}
+
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	skills := &scriptedExecutor{
+		outputs: map[uuid.UUID]string{publish: `{"url":"https://example.com/p/1"}`},
+		params:  make(map[uuid.UUID]map[string]interface{}),
+	}
+	a := NewActivities(skills, tasks, employees, eventbus.NewEventBus())
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(DefinitionWorkflow)
+	RegisterActivities(env, a)
+
+	env.ExecuteWorkflow(DefinitionWorkflow, DefinitionWorkflowInput{
+		TaskID:     running.ID.String(),
+		CompanyID:  running.CompanyID.String(),
+		EmployeeID: running.AssignedEmployeeID.String(),
+		Definition: def,
+		Reused: map[string]map[string]interface{}{
+			"draft":  {"content": "draft text"},
+			"review": {"approved": true},
+		},
+		StepInputs: map[string]map[string]interface{}{"publish": {"channel": "xiaohongshu"}},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result WorkflowResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	assert.Equal(t, "completed", result.Status)
+	assert.Equal(t, "https://example.com/p/1", result.Output["url"])
+
+	assert.NotContains(t, skills.params, draft, "succeeded steps do not run again")
+	assert.NotContains(t, skills.params, review)
+	assert.Equal(t, "draft text", skills.params[publish]["text"], "later steps read the reused outputs")
+	assert.Equal(t, "xiaohongshu", skills.params[publish]["channel"], "the edited input replaces the resolved one")
+
+	require.Len(t, result.StepsResults, 3)
+	assert.True(t, result.StepsResults[0].Reused)
+	assert.False(t, result.StepsResults[2].Reused)
+	assert.Equal(t, task.StatusCompleted, tasks.tasks[running.ID].Status)
+}
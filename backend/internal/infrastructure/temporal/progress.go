@@ -0,0 +1,213 @@
+package temporal
+
+import (
+	"unlimited-corp/internal/domain/task"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// QueryProgress is the query returning the live task.Pipeline of a workflow
+const QueryProgress = "progress"
+
+// progressTracker keeps the pipeline of a workflow, answers progress queries,
+// records every step of the attempt as it starts and ends, and reports the
+// progress on the task after every step
+type progressTracker struct {
+	taskID   string
+	attempt  int
+	gate     *pauseGate
+	pipeline task.Pipeline
+	reported int
+	// starting are the recordings of step starts still in flight, which
+	// the steps' outcomes wait for
+	starting map[string]startRecord
+}
+
+// startRecord is the recording of a step's start, on a context disconnected
+// from the workflow's so that cancelling the workflow does not abandon it
+type startRecord struct {
+	future workflow.Future
+	cancel workflow.CancelFunc
+}
+
+// stepRun is what a starting step runs
+type stepRun struct {
+	SkillCardID string
+	EmployeeID  string
+	Input       map[string]interface{}
+}
+
+// newProgressTracker registers the progress query for the given attempt of a
+// task's workflow with the given steps
+func newProgressTracker(ctx workflow.Context, taskID string, attempt int, gate *pauseGate, steps ...task.PipelineStep) *progressTracker {
+	p := &progressTracker{taskID: taskID, attempt: attempt, gate: gate, starting: make(map[string]startRecord)}
+	for _, s := range steps {
+		s.Status = task.StepPending
+		p.pipeline.Steps = append(p.pipeline.Steps, s)
+	}
+
+	if err := workflow.SetQueryHandler(ctx, QueryProgress, func() (*task.Pipeline, error) {
+		return p.snapshot(), nil
+	}); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to register progress query", "taskId", taskID, "error", err)
+	}
+	return p
+}
+
+// step returns the step with the given ID
+func (p *progressTracker) step(id string) *task.PipelineStep {
+	for i := range p.pipeline.Steps {
+		if p.pipeline.Steps[i].ID == id {
+			return &p.pipeline.Steps[i]
+		}
+	}
+	return nil
+}
+
+// start marks a step as running. Its start is recorded without waiting, so
+// that steps ready together start together.
+func (p *progressTracker) start(ctx workflow.Context, id string, run stepRun) {
+	s := p.step(id)
+	if s == nil {
+		return
+	}
+	now := workflow.Now(ctx)
+	s.Status = task.StepRunning
+	s.StartedAt = &now
+	if p.taskID == "" {
+		return
+	}
+	disconnected, cancel := workflow.NewDisconnectedContext(ctx)
+	future := p.send(disconnected, StepRecordInput{
+		NodeID:      id,
+		Status:      task.StepRunning,
+		SkillCardID: run.SkillCardID,
+		EmployeeID:  run.EmployeeID,
+		Input:       run.Input,
+		At:          now,
+	})
+	p.starting[id] = startRecord{future: future, cancel: cancel}
+}
+
+// record records the outcome of a step and recomputes the percent complete.
+// A step already recorded with the same status is left alone.
+func (p *progressTracker) record(ctx workflow.Context, step StepResult) {
+	s := p.step(step.StepID)
+	if s == nil || s.Status == step.Status {
+		return
+	}
+	p.started(ctx, step.StepID)
+	p.save(ctx, StepRecordInput{
+		NodeID: step.StepID,
+		Status: step.Status,
+		Output: step.Output,
+		Error:  step.Error,
+		Reused: step.Reused,
+		At:     workflow.Now(ctx),
+	})
+	s.Status = step.Status
+	s.Error = step.Error
+	if !step.StartedAt.IsZero() {
+		startedAt := step.StartedAt
+		s.StartedAt = &startedAt
+	}
+	if !step.CompletedAt.IsZero() {
+		completedAt := step.CompletedAt
+		s.CompletedAt = &completedAt
+	}
+
+	p.pipeline.UpdatePercent()
+}
+
+// items updates the item counts of a running map step and reports the
+// progress they make
+func (p *progressTracker) items(ctx workflow.Context, id string, items task.ItemProgress) {
+	s := p.step(id)
+	if s == nil {
+		return
+	}
+	s.Items = &items
+	p.pipeline.UpdatePercent()
+	if ctx.Err() == nil {
+		p.report(ctx)
+	}
+}
+
+// started waits for the recording of a step's start, so that its outcome is
+// recorded after it. A failed recording does not fail the workflow.
+func (p *progressTracker) started(ctx workflow.Context, id string) {
+	start, ok := p.starting[id]
+	if !ok {
+		return
+	}
+	delete(p.starting, id)
+	defer start.cancel()
+	if err := start.future.Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to record task step", "taskId", p.taskID, "step", id, "error", err)
+	}
+}
+
+// save records a step of the attempt on the task. The outcome of a step of a
+// cancelled workflow is saved on a disconnected context. A failed save does
+// not fail the workflow.
+func (p *progressTracker) save(ctx workflow.Context, record StepRecordInput) {
+	if p.taskID == "" {
+		return
+	}
+	if ctx.Err() != nil {
+		disconnected, cancel := workflow.NewDisconnectedContext(ctx)
+		defer cancel()
+		ctx = disconnected
+	}
+	if err := p.send(ctx, record).Get(ctx, nil); err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to record task step", "taskId", p.taskID, "step", record.NodeID, "error", err)
+	}
+}
+
+// send starts the activity recording a step of the attempt on the task
+func (p *progressTracker) send(ctx workflow.Context, record StepRecordInput) workflow.Future {
+	for i := range p.pipeline.Steps {
+		if p.pipeline.Steps[i].ID == record.NodeID {
+			record.Order = i + 1
+			record.Name = p.pipeline.Steps[i].Name
+		}
+	}
+	record.TaskID = p.taskID
+	record.Attempt = p.attempt
+
+	var a *Activities
+	return workflow.ExecuteActivity(ctx, a.RecordStepActivity, record)
+}
+
+// report records the percent complete on the task, which announces it with a
+// task.progress event, unless it did not change since the last report. A
+// failed report does not fail the workflow.
+func (p *progressTracker) report(ctx workflow.Context) {
+	if p.taskID == "" || p.pipeline.Percent == p.reported {
+		return
+	}
+	p.reported = p.pipeline.Percent
+	var a *Activities
+	err := workflow.ExecuteActivity(ctx, a.UpdateTaskStatusActivity, TaskStatusInput{
+		TaskID:   p.taskID,
+		Progress: p.pipeline.Percent,
+	}).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to report task progress", "taskId", p.taskID, "error", err)
+	}
+}
+
+// snapshot returns a copy of the pipeline for queries
+func (p *progressTracker) snapshot() *task.Pipeline {
+	snapshot := p.pipeline
+	snapshot.Steps = append([]task.PipelineStep(nil), p.pipeline.Steps...)
+	snapshot.Paused = p.gate != nil && p.gate.paused
+	snapshot.CurrentStep = ""
+	for _, s := range snapshot.Steps {
+		if s.Status == task.StepRunning {
+			snapshot.CurrentStep = s.ID
+			break
+		}
+	}
+	return &snapshot
+}
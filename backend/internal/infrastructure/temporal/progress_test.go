@@ -0,0 +1,188 @@
+package temporal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// fakeStepRepository stores steps in memory by attempt and node
+type fakeStepRepository struct {
+	mu    sync.Mutex
+	steps map[string]*task.Step
+}
+
+func stepKey(attempt int, nodeID string) string {
+	return fmt.Sprintf("%d/%s", attempt, nodeID)
+}
+
+func (r *fakeStepRepository) SaveStep(_ context.Context, s *task.Step) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *s
+	r.steps[stepKey(s.Attempt, s.NodeID)] = &copied
+	return nil
+}
+
+func (r *fakeStepRepository) GetStep(_ context.Context, _ uuid.UUID, attempt int, nodeID string) (*task.Step, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.steps[stepKey(attempt, nodeID)]; ok {
+		copied := *s
+		return &copied, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeStepRepository) ListSteps(context.Context, uuid.UUID, int) ([]*task.Step, error) {
+	return nil, nil
+}
+
+func TestDefinitionWorkflow_RecordsSteps(t *testing.T) {
+	draft, review, publish := uuid.New(), uuid.New(), uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "draft", Name: "Draft", Type: definition.NodeSkill, SkillCardID: draft.String()},
+			{ID: "review", Type: definition.NodeSkill, SkillCardID: review.String()},
+			{ID: "illustrate", Type: definition.NodeSkill, SkillCardID: review.String(),
+				Condition: &definition.Condition{Path: "$.input.with_images", Op: definition.OpEq, Value: true}},
+			{ID: "publish", Type: definition.NodeSkill, SkillCardID: publish.String(),
+				Inputs: map[string]interface{}{"text": "$.nodes.draft.output.content"}},
+		},
+		Edges: []definition.Edge{
+			{From: "draft", To: "review"},
+			{From: "draft", To: "illustrate"},
+			{From: "review", To: "publish"},
+		},
+	}
+
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	skills := &scriptedExecutor{
+		outputs: map[uuid.UUID]string{review: `{"approved":true}`},
+		failing: map[uuid.UUID]bool{publish: true},
+		params:  make(map[uuid.UUID]map[string]interface{}),
+	}
+	steps := &fakeStepRepository{steps: make(map[string]*task.Step)}
+	a := NewActivities(skills, tasks, employees, eventbus.NewEventBus())
+	a.SetSteps(steps)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(DefinitionWorkflow)
+	RegisterActivities(env, a)
+
+	env.ExecuteWorkflow(DefinitionWorkflow, DefinitionWorkflowInput{
+		TaskID:     running.ID.String(),
+		CompanyID:  running.CompanyID.String(),
+		EmployeeID: running.AssignedEmployeeID.String(),
+		Definition: def,
+		Input:      map[string]interface{}{"with_images": false},
+		Reused:     map[string]map[string]interface{}{"draft": {"content": "draft text"}},
+		Attempt:    2,
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	require.Len(t, steps.steps, 4)
+	reused := steps.steps[stepKey(2, "draft")]
+	assert.Equal(t, task.StepCompleted, reused.Status)
+	assert.True(t, reused.Reused)
+	assert.Equal(t, "Draft", reused.Name)
+	assert.Equal(t, 1, reused.Order)
+
+	completed := steps.steps[stepKey(2, "review")]
+	assert.Equal(t, task.StepCompleted, completed.Status)
+	assert.Equal(t, true, completed.Output["approved"])
+	assert.Equal(t, running.AssignedEmployeeID, completed.ExecutedBy)
+	assert.Equal(t, &review, completed.SkillCardID)
+	assert.NotNil(t, completed.StartedAt)
+
+	assert.Equal(t, task.StepSkipped, steps.steps[stepKey(2, "illustrate")].Status)
+
+	failed := steps.steps[stepKey(2, "publish")]
+	assert.Equal(t, task.StepFailed, failed.Status)
+	assert.Contains(t, failed.Error, "provider unavailable")
+	assert.Equal(t, "draft text", failed.Input["text"], "the resolved input is kept")
+	assert.Equal(t, 4, failed.Order)
+}
+
+// startBarrier holds the recorded start of one step until another step's
+// start is being recorded
+type startBarrier struct {
+	*fakeStepRepository
+	held, awaited string
+	arrived       chan struct{}
+	timedOut      bool
+}
+
+func (r *startBarrier) SaveStep(ctx context.Context, s *task.Step) error {
+	if s.Status == task.StepRunning {
+		switch s.NodeID {
+		case r.awaited:
+			close(r.arrived)
+		case r.held:
+			select {
+			case <-r.arrived:
+			case <-time.After(time.Second):
+				r.timedOut = true
+			}
+		}
+	}
+	return r.fakeStepRepository.SaveStep(ctx, s)
+}
+
+func TestDefinitionWorkflow_RecordsStartsWithoutWaiting(t *testing.T) {
+	left, right := uuid.New(), uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "left", Type: definition.NodeSkill, SkillCardID: left.String()},
+			{ID: "right", Type: definition.NodeSkill, SkillCardID: right.String()},
+		},
+	}
+
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	skills := &scriptedExecutor{params: make(map[uuid.UUID]map[string]interface{})}
+	steps := &startBarrier{
+		fakeStepRepository: &fakeStepRepository{steps: make(map[string]*task.Step)},
+		held:               "left",
+		awaited:            "right",
+		arrived:            make(chan struct{}),
+	}
+	a := NewActivities(skills, tasks, employees, eventbus.NewEventBus())
+	a.SetSteps(steps)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(DefinitionWorkflow)
+	RegisterActivities(env, a)
+
+	env.ExecuteWorkflow(DefinitionWorkflow, DefinitionWorkflowInput{
+		TaskID:     running.ID.String(),
+		CompanyID:  running.CompanyID.String(),
+		EmployeeID: running.AssignedEmployeeID.String(),
+		Definition: def,
+		Attempt:    1,
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	assert.False(t, steps.timedOut, "the right step starts while the left one's start is being recorded")
+	assert.Equal(t, task.StepCompleted, steps.steps[stepKey(1, "left")].Status)
+	assert.Equal(t, task.StepCompleted, steps.steps[stepKey(1, "right")].Status)
+}
@@ -0,0 +1,98 @@
+package temporal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	definition "unlimited-corp/internal/domain/workflow"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// NewPublishInput returns the input publishing a publish node's content: its
+// platforms or platform and publish_mode parameters say where and how
+func NewPublishInput(companyID string, params map[string]interface{}) PublishInput {
+	mode, _ := params["publish_mode"].(string)
+	return PublishInput{
+		CompanyID: companyID,
+		Content:   params,
+		Platforms: publishTargets(params),
+		Mode:      mode,
+	}
+}
+
+// PublishOutcome returns the platforms a publication went out on, and an
+// error naming those it did not. Without platforms, the ones the publication
+// was sent to are read from it.
+func PublishOutcome(publication map[string]interface{}, platforms []string) ([]string, error) {
+	if len(platforms) == 0 {
+		platforms = stringList(publication["platforms"])
+	}
+	published, failed := publishedPlatforms(publication, platforms)
+	if len(failed) > 0 {
+		return published, fmt.Errorf("failed to publish to %s", strings.Join(failed, ", "))
+	}
+	return published, nil
+}
+
+// executePublish publishes a publish node's content with the node's timeout
+// and attempts. Content that went out on only some platforms is taken down
+// again, recorded on the saga, and fails the node. The returned future
+// resolves to a SkillExecutionResult whose output is the publication.
+func executePublish(ctx workflow.Context, input DefinitionWorkflowInput, s *saga, node *definition.Node, params map[string]interface{}) workflow.Future {
+	var a *Activities
+	future, settable := workflow.NewFuture(ctx)
+	publish := NewPublishInput(input.CompanyID, params)
+
+	workflow.Go(ctx, func(ctx workflow.Context) {
+		var publication map[string]interface{}
+		if err := workflow.ExecuteActivity(nodeContext(ctx, node), a.PublishContentActivity, publish).Get(ctx, &publication); err != nil {
+			settable.SetError(err)
+			return
+		}
+		published, err := PublishOutcome(publication, publish.Platforms)
+		if err != nil {
+			message := err.Error()
+			if len(published) > 0 {
+				undone := unpublish(ctx, input, published, publication)
+				s.undid(node.ID, undone)
+				message += "; " + DescribeUnpublish(undone, published)
+			}
+			settable.SetError(errors.New(message))
+			return
+		}
+		settable.SetValue(SkillExecutionResult{Success: true, Output: publication})
+	})
+	return future
+}
+
+// addUnpublish registers taking down what a completed publish node published
+func addUnpublish(s *saga, input DefinitionWorkflowInput, node *definition.Node, publication map[string]interface{}) {
+	published, _ := PublishOutcome(publication, nil)
+	if len(published) == 0 {
+		return
+	}
+	s.add(node.ID, node.DisplayName(), func(ctx workflow.Context) error {
+		return unpublish(ctx, input, published, publication)
+	})
+}
+
+// unpublish takes a publication down from the platforms it went out on
+func unpublish(ctx workflow.Context, input DefinitionWorkflowInput, platforms []string, publication map[string]interface{}) error {
+	var a *Activities
+	return workflow.ExecuteActivity(ctx, a.UnpublishContentActivity, UnpublishInput{
+		CompanyID:   input.CompanyID,
+		Platforms:   platforms,
+		Publication: publication,
+	}).Get(ctx, nil)
+}
+
+// DescribeUnpublish describes how taking a partial publication down from
+// the platforms it went out on ended
+func DescribeUnpublish(err error, platforms []string) string {
+	if err != nil {
+		return "failed to take it down again: " + errorMessage(err)
+	}
+	return "taken down again from " + strings.Join(platforms, ", ")
+}
@@ -0,0 +1,128 @@
+package temporal
+
+import (
+	"testing"
+
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/eventbus"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/testsuite"
+)
+
+func TestDefinitionWorkflow_PublishNode(t *testing.T) {
+	draft, check := uuid.New(), uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft.String()},
+			{ID: "publish", Name: "内容发布", Type: definition.NodePublish},
+			{ID: "check", Type: definition.NodeSkill, SkillCardID: check.String(), MaxAttempts: 1},
+		},
+		Edges: []definition.Edge{{From: "draft", To: "publish"}, {From: "publish", To: "check"}},
+	}
+	require.NoError(t, def.Validate())
+
+	setup := func(publisher *fakePublisher, failing ...uuid.UUID) (*testsuite.TestWorkflowEnvironment, *fakeTaskRepository, *fakeStepRepository, DefinitionWorkflowInput) {
+		running, employees := newRunningTask()
+		tasks := newFakeTaskRepository(running)
+		skills := &scriptedExecutor{
+			outputs: map[uuid.UUID]string{draft: `{"title":"标题","content":"正文"}`, check: `{}`},
+			failing: make(map[uuid.UUID]bool),
+			params:  make(map[uuid.UUID]map[string]interface{}),
+		}
+		for _, id := range failing {
+			skills.failing[id] = true
+		}
+		steps := &fakeStepRepository{steps: make(map[string]*task.Step)}
+		a := NewActivities(skills, tasks, employees, eventbus.NewEventBus())
+		a.SetSteps(steps)
+		a.SetPublisher(publisher)
+
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterWorkflow(DefinitionWorkflow)
+		RegisterActivities(env, a)
+
+		input := DefinitionWorkflowInput{
+			TaskID:     running.ID.String(),
+			CompanyID:  running.CompanyID.String(),
+			EmployeeID: running.AssignedEmployeeID.String(),
+			Definition: def,
+			Input:      map[string]interface{}{"platforms": []interface{}{"xiaohongshu", "weibo"}, "publish_mode": "draft"},
+			Attempt:    1,
+		}
+		return env, tasks, steps, input
+	}
+
+	t.Run("publishes the content of its input", func(t *testing.T) {
+		publisher := newFakePublisher()
+		env, tasks, steps, input := setup(publisher)
+		env.ExecuteWorkflow(DefinitionWorkflow, input)
+		require.True(t, env.IsWorkflowCompleted())
+
+		var result WorkflowResult
+		require.NoError(t, env.GetWorkflowResult(&result))
+		assert.Equal(t, "completed", result.Status)
+		require.Len(t, publisher.published, 2)
+		assert.Equal(t, "标题", publisher.published[0].post.Title)
+		assert.Equal(t, "正文", publisher.published[1].post.Body)
+
+		publication := steps.steps[stepKey(1, "publish")].Output
+		assert.Equal(t, "draft", publication["mode"])
+		assert.Equal(t, true, publication["published"])
+		assert.Equal(t, task.StatusCompleted, tasks.tasks[uuid.MustParse(input.TaskID)].Status)
+	})
+
+	t.Run("a later failure takes the publication down", func(t *testing.T) {
+		publisher := newFakePublisher()
+		env, tasks, steps, input := setup(publisher, check)
+		env.ExecuteWorkflow(DefinitionWorkflow, input)
+		require.True(t, env.IsWorkflowCompleted())
+
+		var result WorkflowResult
+		require.NoError(t, env.GetWorkflowResult(&result))
+		assert.Equal(t, "failed", result.Status)
+		assert.Contains(t, result.Error, "compensated publish")
+		assert.ElementsMatch(t, []string{"xiaohongshu/post-1", "weibo/post-2"}, publisher.unpublished)
+		assert.Equal(t, task.StepCompensated, steps.steps[stepKey(1, "publish")].Status)
+		assert.Equal(t, task.StatusFailed, tasks.tasks[uuid.MustParse(input.TaskID)].Status)
+	})
+
+	t.Run("a partial publication is taken down and fails the node", func(t *testing.T) {
+		publisher := newFakePublisher()
+		publisher.failing["weibo"] = true
+		env, tasks, steps, input := setup(publisher)
+		env.ExecuteWorkflow(DefinitionWorkflow, input)
+		require.True(t, env.IsWorkflowCompleted())
+
+		var result WorkflowResult
+		require.NoError(t, env.GetWorkflowResult(&result))
+		assert.Equal(t, "failed", result.Status)
+		assert.Equal(t, "node publish failed: failed to publish to weibo; taken down again from xiaohongshu", result.Error)
+		compensation := tasks.tasks[uuid.MustParse(input.TaskID)].Compensation
+		require.NotNil(t, compensation)
+		assert.Equal(t, []string{"publish"}, compensation.Compensated)
+		assert.Equal(t, []string{"xiaohongshu/post-1"}, publisher.unpublished)
+		assert.Equal(t, task.StepFailed, steps.steps[stepKey(1, "publish")].Status)
+		assert.Nil(t, steps.steps[stepKey(1, "check")])
+	})
+}
+
+func TestPublishOutcome(t *testing.T) {
+	publication := map[string]interface{}{
+		"platforms": []interface{}{"xiaohongshu", "weibo"},
+		"status":    map[string]interface{}{"xiaohongshu": "success", "weibo": "failed"},
+	}
+
+	published, err := PublishOutcome(publication, nil)
+	assert.Equal(t, []string{"xiaohongshu"}, published)
+	assert.EqualError(t, err, "failed to publish to weibo")
+
+	published, err = PublishOutcome(publication, []string{"xiaohongshu"})
+	assert.Equal(t, []string{"xiaohongshu"}, published)
+	assert.NoError(t, err)
+}
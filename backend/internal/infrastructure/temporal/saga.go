@@ -0,0 +1,92 @@
+package temporal
+
+import (
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+
+	"go.temporal.io/sdk/workflow"
+)
+
+// saga collects how to undo the steps a workflow finished, such as
+// unpublishing content, to compensate them when the workflow fails or is
+// cancelled
+type saga struct {
+	compensations []compensation
+	// undone are how the steps undone as they failed ended, such as a
+	// partial publication taken down again
+	undone []definition.CompensationResult
+}
+
+// compensation undoes one finished step
+type compensation struct {
+	stepID   string
+	stepName string
+	undo     func(ctx workflow.Context) error
+}
+
+// add registers how to undo a step that finished
+func (s *saga) add(stepID, stepName string, undo func(ctx workflow.Context) error) {
+	s.compensations = append(s.compensations, compensation{stepID: stepID, stepName: stepName, undo: undo})
+}
+
+// undid records how undoing a step as it failed ended
+func (s *saga) undid(stepID string, err error) {
+	outcome := definition.CompensationResult{NodeID: stepID}
+	if err != nil {
+		outcome.Error = errorMessage(err)
+	}
+	s.undone = append(s.undone, outcome)
+}
+
+// compensate undoes the finished steps one at a time, the last finished
+// first. A failed compensation does not stop the others. Each outcome is
+// recorded on its step and in the result; a cancelled workflow compensates
+// on a disconnected context. It returns the outcomes, none when there was
+// nothing to undo; the result's compensation also covers the steps undone
+// as they failed.
+func (s *saga) compensate(ctx workflow.Context, progress *progressTracker, result *WorkflowResult) []definition.CompensationResult {
+	if len(s.compensations) == 0 && len(s.undone) == 0 {
+		return nil
+	}
+	if ctx.Err() != nil {
+		disconnected, cancel := workflow.NewDisconnectedContext(ctx)
+		defer cancel()
+		ctx = disconnected
+	}
+
+	outcomes := make([]definition.CompensationResult, 0, len(s.compensations)+len(s.undone))
+	for i := len(s.compensations) - 1; i >= 0; i-- {
+		c := s.compensations[i]
+		step := StepResult{
+			StepID:    c.stepID,
+			StepName:  c.stepName,
+			Status:    task.StepCompensated,
+			StartedAt: workflow.Now(ctx),
+		}
+		outcome := definition.CompensationResult{NodeID: c.stepID}
+		if err := c.undo(ctx); err != nil {
+			step.Status = task.StepCompensationFailed
+			step.Error = errorMessage(err)
+			outcome.Error = step.Error
+			workflow.GetLogger(ctx).Warn("Failed to compensate step", "taskId", result.TaskID, "step", c.stepID, "error", err)
+		}
+		step.CompletedAt = workflow.Now(ctx)
+		result.StepsResults = append(result.StepsResults, step)
+		progress.record(ctx, step)
+		outcomes = append(outcomes, outcome)
+	}
+	result.Compensation = definition.SummarizeCompensations(append(outcomes, s.undone...))
+	s.compensations, s.undone = nil, nil
+	return outcomes
+}
+
+// withCompensations appends the summary of the compensations to the error of a workflow
+func withCompensations(message, compensations string) string {
+	switch {
+	case compensations == "":
+		return message
+	case message == "":
+		return compensations
+	}
+	return message + "; " + compensations
+}
@@ -0,0 +1,307 @@
+package temporal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/application/executor"
+	"unlimited-corp/internal/domain/publishing"
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/infrastructure/eventbus"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// fakePublisher publishes to any platform but those set to fail, and
+// records what it published and took down
+type fakePublisher struct {
+	mu          sync.Mutex
+	defaults    []string
+	failing     map[string]bool
+	published   []publishedPost
+	unpublished []string
+}
+
+// publishedPost is a post fakePublisher published
+type publishedPost struct {
+	platform string
+	post     *publishing.Post
+}
+
+func newFakePublisher(defaults ...string) *fakePublisher {
+	return &fakePublisher{defaults: defaults, failing: make(map[string]bool)}
+}
+
+func (p *fakePublisher) Publish(_ context.Context, _ uuid.UUID, platform string, post *publishing.Post, mode publishing.Mode) (*publishing.Result, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.failing[platform] {
+		return nil, fmt.Errorf("%s is down", platform)
+	}
+	p.published = append(p.published, publishedPost{platform: platform, post: post})
+	id := fmt.Sprintf("post-%d", len(p.published))
+	return &publishing.Result{Platform: platform, PostID: id, URL: "stub://" + platform + "/posts/" + id, Mode: mode}, nil
+}
+
+func (p *fakePublisher) Unpublish(_ context.Context, _ uuid.UUID, platform, postID string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.unpublished = append(p.unpublished, platform+"/"+postID)
+	return nil
+}
+
+func (p *fakePublisher) DefaultPlatforms() []string {
+	return p.defaults
+}
+
+// orderedExecutor is a scriptedExecutor that also records the order skill cards ran in
+type orderedExecutor struct {
+	*scriptedExecutor
+	order []uuid.UUID
+}
+
+func (e *orderedExecutor) Execute(ctx context.Context, execCtx *executor.ExecutionContext) (*executor.ExecutionResult, error) {
+	e.mu.Lock()
+	e.order = append(e.order, execCtx.SkillCardID)
+	e.mu.Unlock()
+	return e.scriptedExecutor.Execute(ctx, execCtx)
+}
+
+func TestDefinitionWorkflow_Compensation(t *testing.T) {
+	draft, deleteDraft, upload, remove, publish := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	def := &definition.Definition{
+		Version: 1,
+		Nodes: []definition.Node{
+			{ID: "draft", Type: definition.NodeSkill, SkillCardID: draft.String(), MaxAttempts: 1,
+				Compensate: &definition.Compensation{SkillCardID: deleteDraft.String(),
+					Inputs: map[string]interface{}{"id": "$.nodes.draft.output.id"}}},
+			{ID: "upload", Type: definition.NodeSkill, SkillCardID: upload.String(), MaxAttempts: 1,
+				Compensate: &definition.Compensation{SkillCardID: remove.String()}},
+			{ID: "publish", Type: definition.NodeSkill, SkillCardID: publish.String(), MaxAttempts: 1},
+		},
+		Edges: []definition.Edge{{From: "draft", To: "upload"}, {From: "upload", To: "publish"}},
+	}
+	require.NoError(t, def.Validate())
+
+	setup := func(failing ...uuid.UUID) (*testsuite.TestWorkflowEnvironment, *fakeTaskRepository, *fakeStepRepository, *orderedExecutor, DefinitionWorkflowInput) {
+		running, employees := newRunningTask()
+		tasks := newFakeTaskRepository(running)
+		skills := &orderedExecutor{scriptedExecutor: &scriptedExecutor{
+			outputs: map[uuid.UUID]string{
+				draft:  `{"id":"d1"}`,
+				upload: `{"url":"https://cdn.example.com/1.png"}`,
+			},
+			failing: make(map[uuid.UUID]bool),
+			params:  make(map[uuid.UUID]map[string]interface{}),
+		}}
+		for _, id := range failing {
+			skills.failing[id] = true
+		}
+		steps := &fakeStepRepository{steps: make(map[string]*task.Step)}
+		a := NewActivities(skills, tasks, employees, eventbus.NewEventBus())
+		a.SetSteps(steps)
+
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.RegisterWorkflow(DefinitionWorkflow)
+		RegisterActivities(env, a)
+
+		input := DefinitionWorkflowInput{
+			TaskID:     running.ID.String(),
+			CompanyID:  running.CompanyID.String(),
+			EmployeeID: running.AssignedEmployeeID.String(),
+			Definition: def,
+			Attempt:    1,
+		}
+		return env, tasks, steps, skills, input
+	}
+
+	t.Run("undoes the completed nodes, the last first", func(t *testing.T) {
+		env, tasks, steps, skills, input := setup(publish)
+		env.ExecuteWorkflow(DefinitionWorkflow, input)
+		require.True(t, env.IsWorkflowCompleted())
+		require.NoError(t, env.GetWorkflowError())
+
+		var result WorkflowResult
+		require.NoError(t, env.GetWorkflowResult(&result))
+		assert.Equal(t, "failed", result.Status)
+		assert.Contains(t, result.Error, "node publish failed")
+		assert.Contains(t, result.Error, "; compensated upload, draft")
+		assert.Equal(t, []uuid.UUID{draft, upload, publish, remove, deleteDraft}, skills.order)
+		assert.Equal(t, "d1", skills.params[deleteDraft]["id"])
+		assert.Equal(t, "https://cdn.example.com/1.png", skills.params[remove]["url"], "the node's output by default")
+
+		assert.Equal(t, task.StepCompensated, steps.steps[stepKey(1, "draft")].Status)
+		assert.Equal(t, task.StepCompensated, steps.steps[stepKey(1, "upload")].Status)
+		assert.Equal(t, task.StepFailed, steps.steps[stepKey(1, "publish")].Status)
+		finished := tasks.tasks[uuid.MustParse(input.TaskID)]
+		assert.Equal(t, task.StatusFailed, finished.Status)
+		assert.Contains(t, finished.ErrorMessage, "compensated upload, draft")
+		require.NotNil(t, finished.Compensation)
+		assert.Equal(t, []string{"upload", "draft"}, finished.Compensation.Compensated)
+		assert.False(t, finished.Compensation.PartiallyCompensated)
+	})
+
+	t.Run("a failed compensation does not stop the others", func(t *testing.T) {
+		env, tasks, steps, skills, input := setup(publish, remove)
+		env.ExecuteWorkflow(DefinitionWorkflow, input)
+		require.True(t, env.IsWorkflowCompleted())
+
+		var result WorkflowResult
+		require.NoError(t, env.GetWorkflowResult(&result))
+		assert.Contains(t, result.Error, "compensation of upload failed: ")
+		assert.Contains(t, result.Error, "; compensated draft")
+		assert.Contains(t, skills.params, deleteDraft)
+
+		undone := steps.steps[stepKey(1, "upload")]
+		assert.Equal(t, task.StepCompensationFailed, undone.Status)
+		assert.Contains(t, undone.Error, "provider unavailable")
+		assert.Equal(t, task.StepCompensated, steps.steps[stepKey(1, "draft")].Status)
+
+		compensation := tasks.tasks[uuid.MustParse(input.TaskID)].Compensation
+		require.NotNil(t, compensation)
+		assert.True(t, compensation.PartiallyCompensated)
+		assert.Equal(t, []string{"draft"}, compensation.Compensated)
+		require.Len(t, compensation.Failed, 1)
+		assert.Equal(t, "upload", compensation.Failed[0].StepID)
+		assert.Equal(t, result.Compensation, compensation)
+	})
+
+	t.Run("cancel undoes what completed", func(t *testing.T) {
+		env, tasks, steps, skills, input := setup()
+		env.RegisterDelayedCallback(func() { env.SignalWorkflow(SignalPause, nil) }, 0)
+		env.RegisterDelayedCallback(env.CancelWorkflow, time.Minute)
+
+		env.ExecuteWorkflow(DefinitionWorkflow, input)
+		require.True(t, env.IsWorkflowCompleted())
+		assert.True(t, temporal.IsCanceledError(env.GetWorkflowError()))
+
+		assert.Equal(t, []uuid.UUID{draft, deleteDraft}, skills.order)
+		assert.Equal(t, task.StepCompensated, steps.steps[stepKey(1, "draft")].Status)
+		cancelled := tasks.tasks[uuid.MustParse(input.TaskID)]
+		assert.Equal(t, task.StatusCancelled, cancelled.Status)
+		require.NotNil(t, cancelled.Compensation)
+		assert.Equal(t, []string{"draft"}, cancelled.Compensation.Compensated)
+	})
+}
+
+func TestAutoOpsWorkflow_FailedPublish(t *testing.T) {
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	a := NewActivities(&fakeSkillExecutor{output: []byte(`{"title":"draft"}`)}, tasks, employees, eventbus.NewEventBus())
+	a.SetApprovals(&fakeApprovalRepository{})
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(AutoOpsWorkflow)
+	RegisterActivities(env, a)
+	env.OnActivity(a.PublishContentActivity, mock.Anything, mock.Anything).Return(map[string]interface{}{
+		"published": false,
+		"status":    map[string]interface{}{"xiaohongshu": "failed"},
+	}, nil)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalApproval, ApprovalDecision{NodeID: "step_2", Action: "approve", DecidedBy: uuid.New().String()})
+	}, time.Minute)
+
+	env.ExecuteWorkflow(AutoOpsWorkflow, WorkflowInput{
+		TaskID:      running.ID.String(),
+		CompanyID:   running.CompanyID.String(),
+		EmployeeID:  running.AssignedEmployeeID.String(),
+		SkillCardID: uuid.New().String(),
+		Parameters:  map[string]interface{}{"platform": "xiaohongshu"},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result WorkflowResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	assert.Equal(t, "failed", result.Status, "a publish that did not go out does not complete the task")
+	assert.Equal(t, "failed to publish to xiaohongshu", result.Error)
+	require.Len(t, result.StepsResults, 3)
+	assert.Equal(t, "failed", result.StepsResults[2].Status)
+	assert.Equal(t, task.StatusFailed, tasks.tasks[running.ID].Status)
+}
+
+func TestAutoOpsWorkflow_UnpublishesPartialPublish(t *testing.T) {
+	running, employees := newRunningTask()
+	tasks := newFakeTaskRepository(running)
+	publisher := newFakePublisher()
+	publisher.failing["weibo"] = true
+	a := NewActivities(&fakeSkillExecutor{output: []byte(`{"title":"draft","content":"body"}`)}, tasks, employees, eventbus.NewEventBus())
+	a.SetApprovals(&fakeApprovalRepository{})
+	a.SetPublisher(publisher)
+
+	var suite testsuite.WorkflowTestSuite
+	env := suite.NewTestWorkflowEnvironment()
+	env.RegisterWorkflow(AutoOpsWorkflow)
+	RegisterActivities(env, a)
+	env.RegisterDelayedCallback(func() {
+		env.SignalWorkflow(SignalApproval, ApprovalDecision{NodeID: "step_2", Action: "approve", DecidedBy: uuid.New().String()})
+	}, time.Minute)
+
+	env.ExecuteWorkflow(AutoOpsWorkflow, WorkflowInput{
+		TaskID:      running.ID.String(),
+		CompanyID:   running.CompanyID.String(),
+		EmployeeID:  running.AssignedEmployeeID.String(),
+		SkillCardID: uuid.New().String(),
+		Parameters: map[string]interface{}{
+			"platforms":    []interface{}{"xiaohongshu", "weibo"},
+			"publish_mode": "draft",
+		},
+	})
+	require.True(t, env.IsWorkflowCompleted())
+	require.NoError(t, env.GetWorkflowError())
+
+	var result WorkflowResult
+	require.NoError(t, env.GetWorkflowResult(&result))
+	assert.Equal(t, "failed", result.Status)
+	assert.Equal(t, "failed to publish to weibo; compensated step_3", result.Error)
+	assert.Equal(t, []string{"xiaohongshu/post-1"}, publisher.unpublished)
+	require.NotNil(t, result.Compensation)
+	assert.Equal(t, []string{"step_3"}, result.Compensation.Compensated)
+	assert.Equal(t, result.Compensation, tasks.tasks[running.ID].Compensation)
+	assert.Equal(t, "weibo is down", result.StepsResults[2].Output["errors"].(map[string]interface{})["weibo"])
+	assert.Equal(t, "draft", result.StepsResults[2].Output["mode"])
+}
+
+func TestPublishContentActivity_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name  string
+		input PublishInput
+		want  string
+	}{
+		{name: "no platforms", input: PublishInput{CompanyID: uuid.New().String()}, want: "no platforms to publish to"},
+		{name: "unknown mode", input: PublishInput{CompanyID: uuid.New().String(), Platforms: []string{"weibo"}, Mode: "later"}, want: `unknown publish mode "later"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := NewActivities(&fakeSkillExecutor{}, newFakeTaskRepository(), &fakeEmployeeRepository{}, nil)
+			a.SetPublisher(newFakePublisher())
+
+			var suite testsuite.WorkflowTestSuite
+			env := suite.NewTestActivityEnvironment()
+			RegisterActivities(env, a)
+
+			_, err := env.ExecuteActivity(a.PublishContentActivity, tt.input)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestPublishedPlatforms(t *testing.T) {
+	published, failed := publishedPlatforms(map[string]interface{}{
+		"status": map[string]interface{}{"xiaohongshu": "success", "douyin": "failed"},
+	}, []string{"xiaohongshu", "douyin", "weibo"})
+	assert.Equal(t, []string{"xiaohongshu"}, published)
+	assert.Equal(t, []string{"douyin", "weibo"}, failed)
+}
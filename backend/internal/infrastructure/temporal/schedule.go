@@ -0,0 +1,150 @@
+package temporal
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+
+	"go.temporal.io/api/serviceerror"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/temporal"
+	"go.temporal.io/sdk/workflow"
+)
+
+// ScheduledTaskInput is the input of ScheduledTaskWorkflow
+type ScheduledTaskInput struct {
+	ScheduleID string `json:"scheduleId"`
+}
+
+// ScheduledTaskWorkflow is the action of every task schedule: it starts the
+// schedule's task, which the scheduler then assigns like any other task
+func ScheduledTaskWorkflow(ctx workflow.Context, input ScheduledTaskInput) (string, error) {
+	var a *Activities
+	ctx = workflow.WithActivityOptions(ctx, workflow.ActivityOptions{
+		StartToCloseTimeout: time.Minute,
+		RetryPolicy: &temporal.RetryPolicy{
+			InitialInterval:    time.Second,
+			BackoffCoefficient: 2.0,
+			MaximumInterval:    time.Minute,
+			MaximumAttempts:    5,
+		},
+	})
+
+	var taskID string
+	err := workflow.ExecuteActivity(ctx, a.SpawnScheduledTaskActivity, SpawnScheduledTaskInput{
+		ScheduleID:  input.ScheduleID,
+		WorkflowID:  workflow.GetInfo(ctx).WorkflowExecution.ID,
+		TriggeredAt: workflow.Now(ctx),
+	}).Get(ctx, &taskID)
+	if err != nil {
+		return "", err
+	}
+
+	workflow.GetLogger(ctx).Info("Scheduled task started", "scheduleId", input.ScheduleID, "taskId", taskID)
+	return taskID, nil
+}
+
+// ScheduleEngine fires task schedules with Temporal Schedules, one per task schedule
+type ScheduleEngine struct {
+	client *TemporalClient
+}
+
+// NewScheduleEngine creates a schedule engine using the given client
+func NewScheduleEngine(client *TemporalClient) *ScheduleEngine {
+	return &ScheduleEngine{client: client}
+}
+
+// ScheduleID returns the Temporal Schedule ID of a task schedule
+func ScheduleID(s *task.Schedule) string {
+	return "schedule-" + s.ID.String()
+}
+
+// Create registers the schedule. A one-off schedule fires once at its
+// instant; a cron schedule fires on its expression in its time zone.
+func (e *ScheduleEngine) Create(ctx context.Context, s *task.Schedule) error {
+	options := client.ScheduleOptions{
+		ID: ScheduleID(s),
+		Action: &client.ScheduleWorkflowAction{
+			// Temporal appends the scheduled time, so every run gets its own workflow ID
+			ID:        "scheduled-task-" + s.ID.String(),
+			Workflow:  ScheduledTaskWorkflow,
+			Args:      []interface{}{ScheduledTaskInput{ScheduleID: s.ID.String()}},
+			TaskQueue: e.client.GetTaskQueue(),
+		},
+		Paused: s.IsPaused(),
+	}
+
+	switch s.Kind {
+	case task.ScheduleOnce:
+		options.Spec = onceSpec(*s.RunAt)
+		options.RemainingActions = 1
+	default:
+		options.Spec = client.ScheduleSpec{
+			CronExpressions: []string{s.CronExpression},
+			TimeZoneName:    s.Timezone,
+		}
+	}
+
+	if _, err := e.client.GetClient().ScheduleClient().Create(ctx, options); err != nil {
+		return fmt.Errorf("failed to create schedule: %w", err)
+	}
+	return nil
+}
+
+// onceSpec matches exactly the given instant, expressed in UTC
+func onceSpec(at time.Time) client.ScheduleSpec {
+	at = at.UTC()
+	only := func(v int) []client.ScheduleRange { return []client.ScheduleRange{{Start: v}} }
+	return client.ScheduleSpec{
+		Calendars: []client.ScheduleCalendarSpec{{
+			Second:     only(at.Second()),
+			Minute:     only(at.Minute()),
+			Hour:       only(at.Hour()),
+			DayOfMonth: only(at.Day()),
+			Month:      only(int(at.Month())),
+			Year:       only(at.Year()),
+		}},
+		TimeZoneName: "UTC",
+	}
+}
+
+// Pause stops the schedule from firing
+func (e *ScheduleEngine) Pause(ctx context.Context, s *task.Schedule) error {
+	return e.handle(ctx, s).Pause(ctx, client.SchedulePauseOptions{Note: "paused through the API"})
+}
+
+// Resume lets a paused schedule fire again
+func (e *ScheduleEngine) Resume(ctx context.Context, s *task.Schedule) error {
+	return e.handle(ctx, s).Unpause(ctx, client.ScheduleUnpauseOptions{Note: "resumed through the API"})
+}
+
+// Trigger starts the schedule's task right away
+func (e *ScheduleEngine) Trigger(ctx context.Context, s *task.Schedule) error {
+	return e.handle(ctx, s).Trigger(ctx, client.ScheduleTriggerOptions{})
+}
+
+// Delete removes the schedule; a schedule already gone is not an error
+func (e *ScheduleEngine) Delete(ctx context.Context, s *task.Schedule) error {
+	err := e.handle(ctx, s).Delete(ctx)
+	var notFound *serviceerror.NotFound
+	if err != nil && !errors.As(err, &notFound) {
+		return err
+	}
+	return nil
+}
+
+// NextRuns returns the upcoming times the schedule fires
+func (e *ScheduleEngine) NextRuns(ctx context.Context, s *task.Schedule) ([]time.Time, error) {
+	description, err := e.handle(ctx, s).Describe(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe schedule: %w", err)
+	}
+	return description.Info.NextActionTimes, nil
+}
+
+func (e *ScheduleEngine) handle(ctx context.Context, s *task.Schedule) client.ScheduleHandle {
+	return e.client.GetClient().ScheduleClient().GetHandle(ctx, ScheduleID(s))
+}
@@ -0,0 +1,129 @@
+package temporal
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/infrastructure/eventbus"
+	"unlimited-corp/pkg/errors"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.temporal.io/sdk/client"
+	"go.temporal.io/sdk/testsuite"
+)
+
+// fakeScheduleRepository keeps schedules and their runs in memory
+type fakeScheduleRepository struct {
+	task.ScheduleRepository
+	mu        sync.Mutex
+	schedules map[uuid.UUID]*task.Schedule
+	runs      []*task.ScheduleRun
+}
+
+func (r *fakeScheduleRepository) GetSchedule(_ context.Context, id uuid.UUID) (*task.Schedule, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.schedules[id]; ok {
+		copied := *s
+		return &copied, nil
+	}
+	return nil, errors.ErrNotFound
+}
+
+func (r *fakeScheduleRepository) UpdateSchedule(_ context.Context, s *task.Schedule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.schedules[s.ID] = s
+	return nil
+}
+
+func (r *fakeScheduleRepository) CreateScheduleRun(_ context.Context, run *task.ScheduleRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.runs = append(r.runs, run)
+	return nil
+}
+
+func (r *fakeScheduleRepository) GetScheduleRunByWorkflow(_ context.Context, workflowID string) (*task.ScheduleRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, run := range r.runs {
+		if run.WorkflowID == workflowID {
+			return run, nil
+		}
+	}
+	return nil, errors.ErrNotFound
+}
+
+// directUnitOfWork runs units of work without a transaction
+type directUnitOfWork struct{}
+
+func (directUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func TestScheduledTaskWorkflow(t *testing.T) {
+	sch := task.NewSchedule(uuid.New(), "Friday post", task.ScheduleOnce, task.TaskTemplate{
+		Title:     "Publish the weekly post",
+		InputData: map[string]interface{}{"platform": "xiaohongshu"},
+	})
+	schedules := &fakeScheduleRepository{schedules: map[uuid.UUID]*task.Schedule{sch.ID: sch}}
+	tasks := newFakeTaskRepository()
+	bus := eventbus.NewEventBus()
+	a := NewActivities(&fakeSkillExecutor{}, tasks, &fakeEmployeeRepository{}, bus)
+	a.SetSchedules(schedules, directUnitOfWork{})
+
+	run := func() string {
+		var suite testsuite.WorkflowTestSuite
+		env := suite.NewTestWorkflowEnvironment()
+		env.SetStartWorkflowOptions(client.StartWorkflowOptions{ID: "scheduled-task-1"})
+		env.RegisterWorkflow(ScheduledTaskWorkflow)
+		RegisterActivities(env, a)
+
+		env.ExecuteWorkflow(ScheduledTaskWorkflow, ScheduledTaskInput{ScheduleID: sch.ID.String()})
+		require.True(t, env.IsWorkflowCompleted())
+		require.NoError(t, env.GetWorkflowError())
+
+		var taskID string
+		require.NoError(t, env.GetWorkflowResult(&taskID))
+		return taskID
+	}
+
+	taskID := run()
+	require.Contains(t, tasks.tasks, uuid.MustParse(taskID))
+	spawned := tasks.tasks[uuid.MustParse(taskID)]
+	assert.Equal(t, "Publish the weekly post", spawned.Title)
+	assert.Equal(t, task.StatusPending, spawned.Status)
+	assert.Equal(t, "xiaohongshu", spawned.InputData["platform"])
+
+	require.Len(t, schedules.runs, 1)
+	assert.Equal(t, "scheduled-task-1", schedules.runs[0].WorkflowID)
+	assert.Equal(t, task.ScheduleFinished, schedules.schedules[sch.ID].Status)
+	assert.NotNil(t, schedules.schedules[sch.ID].LastRunAt)
+	assert.Len(t, bus.GetHistoryByType(eventbus.EventTaskCreated, 10), 1)
+
+	// The same workflow run again reuses the task it already started
+	assert.Equal(t, taskID, run())
+	assert.Len(t, tasks.tasks, 1)
+	assert.Len(t, schedules.runs, 1)
+}
+
+func TestOnceSpec(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+
+	spec := onceSpec(time.Date(2025, 3, 7, 20, 0, 0, 0, shanghai))
+	require.Len(t, spec.Calendars, 1)
+	calendar := spec.Calendars[0]
+	assert.Equal(t, "UTC", spec.TimeZoneName)
+	assert.Equal(t, 2025, calendar.Year[0].Start)
+	assert.Equal(t, 3, calendar.Month[0].Start)
+	assert.Equal(t, 7, calendar.DayOfMonth[0].Start)
+	assert.Equal(t, 12, calendar.Hour[0].Start, "20:00 in Shanghai is 12:00 UTC")
+	assert.Equal(t, 0, calendar.Minute[0].Start)
+}
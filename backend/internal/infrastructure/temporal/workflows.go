@@ -3,6 +3,9 @@ package temporal
 import (
 	"time"
 
+	"unlimited-corp/internal/domain/task"
+	definition "unlimited-corp/internal/domain/workflow"
+
 	"go.temporal.io/sdk/temporal"
 	"go.temporal.io/sdk/workflow"
 )
@@ -14,6 +17,8 @@ type WorkflowInput struct {
 	EmployeeID  string                 `json:"employeeId"`
 	SkillCardID string                 `json:"skillCardId"`
 	Parameters  map[string]interface{} `json:"parameters"`
+	// Approval configures the review of workflows that ask for one
+	Approval ApprovalPolicy `json:"approval"`
 }
 
 // WorkflowResult represents the result of a workflow execution
@@ -25,6 +30,9 @@ type WorkflowResult struct {
 	StartedAt    time.Time              `json:"startedAt"`
 	CompletedAt  time.Time              `json:"completedAt"`
 	StepsResults []StepResult           `json:"stepsResults"`
+	// Compensation is how undoing the completed steps ended, if the
+	// workflow failed or was cancelled after any completed
+	Compensation *task.Compensation `json:"compensation,omitempty"`
 }
 
 // StepResult represents the result of a single workflow step
@@ -37,10 +45,13 @@ type StepResult struct {
 	StartedAt   time.Time              `json:"startedAt"`
 	CompletedAt time.Time              `json:"completedAt"`
 	TokensUsed  int                    `json:"tokensUsed"`
+	// Reused marks a step whose output was taken over from an earlier attempt
+	Reused bool `json:"reused,omitempty"`
 }
 
 // ContentCreationWorkflow orchestrates content creation tasks
 func ContentCreationWorkflow(ctx workflow.Context, input WorkflowInput) (*WorkflowResult, error) {
+	var a *Activities
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Starting content creation workflow", "taskId", input.TaskID)
 
@@ -63,15 +74,23 @@ func ContentCreationWorkflow(ctx workflow.Context, input WorkflowInput) (*Workfl
 		},
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	gate := newPauseGate(ctx)
+	progress := newProgressTracker(ctx, input.TaskID, 1, gate,
+		task.PipelineStep{ID: "step_1", Name: "技能卡执行"},
+	)
 
 	// Step 1: Execute skill card
 	var skillResult SkillExecutionResult
-	err := workflow.ExecuteActivity(ctx, ExecuteSkillActivity, SkillExecutionInput{
-		TaskID:      input.TaskID,
-		SkillCardID: input.SkillCardID,
-		EmployeeID:  input.EmployeeID,
-		Parameters:  input.Parameters,
-	}).Get(ctx, &skillResult)
+	err := gate.wait(ctx)
+	if err == nil {
+		progress.start(ctx, "step_1", stepRun{SkillCardID: input.SkillCardID, EmployeeID: input.EmployeeID, Input: input.Parameters})
+		err = workflow.ExecuteActivity(ctx, a.ExecuteSkillActivity, SkillExecutionInput{
+			TaskID:      input.TaskID,
+			SkillCardID: input.SkillCardID,
+			EmployeeID:  input.EmployeeID,
+			Parameters:  input.Parameters,
+		}).Get(ctx, &skillResult)
+	}
 
 	stepResult := StepResult{
 		StepID:      "step_1",
@@ -84,9 +103,12 @@ func ContentCreationWorkflow(ctx workflow.Context, input WorkflowInput) (*Workfl
 		stepResult.Status = "failed"
 		stepResult.Error = err.Error()
 		result.StepsResults = append(result.StepsResults, stepResult)
-		result.Status = "failed"
+		progress.record(ctx, stepResult)
 		result.Error = err.Error()
 		result.CompletedAt = workflow.Now(ctx)
+		if result.Status = endTask(ctx, input.TaskID, err); result.Status == "cancelled" {
+			return result, err
+		}
 		return result, nil
 	}
 
@@ -94,18 +116,11 @@ func ContentCreationWorkflow(ctx workflow.Context, input WorkflowInput) (*Workfl
 	stepResult.Output = skillResult.Output
 	stepResult.TokensUsed = skillResult.TokensUsed
 	result.StepsResults = append(result.StepsResults, stepResult)
+	progress.record(ctx, stepResult)
+	progress.report(ctx)
 
 	// Step 2: Update task status
-	err = workflow.ExecuteActivity(ctx, UpdateTaskStatusActivity, TaskStatusInput{
-		TaskID:   input.TaskID,
-		Status:   "completed",
-		Progress: 100,
-		Output:   skillResult.Output,
-	}).Get(ctx, nil)
-
-	if err != nil {
-		logger.Warn("Failed to update task status", "error", err)
-	}
+	finishTask(ctx, input.TaskID, "completed", skillResult.Output, "")
 
 	result.Status = "completed"
 	result.Output = skillResult.Output
@@ -117,6 +132,7 @@ func ContentCreationWorkflow(ctx workflow.Context, input WorkflowInput) (*Workfl
 
 // HotspotTrackingWorkflow tracks hotspots and generates content ideas
 func HotspotTrackingWorkflow(ctx workflow.Context, input WorkflowInput) (*WorkflowResult, error) {
+	var a *Activities
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Starting hotspot tracking workflow", "taskId", input.TaskID)
 
@@ -138,13 +154,25 @@ func HotspotTrackingWorkflow(ctx workflow.Context, input WorkflowInput) (*Workfl
 		},
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	gate := newPauseGate(ctx)
+	progress := newProgressTracker(ctx, input.TaskID, 1, gate,
+		task.PipelineStep{ID: "step_1", Name: "热点分析"},
+		task.PipelineStep{ID: "step_2", Name: "内容建议生成"},
+	)
 
 	// Step 1: Analyze hotspots
 	var hotspotResult SkillExecutionResult
-	err := workflow.ExecuteActivity(ctx, AnalyzeHotspotsActivity, HotspotAnalysisInput{
-		Platform: input.Parameters["platform"].(string),
-		Category: input.Parameters["category"].(string),
-	}).Get(ctx, &hotspotResult)
+	err := gate.wait(ctx)
+	if err == nil {
+		progress.start(ctx, "step_1", stepRun{Input: input.Parameters})
+		platform, _ := input.Parameters["platform"].(string)
+		category, _ := input.Parameters["category"].(string)
+		err = workflow.ExecuteActivity(ctx, a.AnalyzeHotspotsActivity, HotspotAnalysisInput{
+			CompanyID: input.CompanyID,
+			Platform:  platform,
+			Category:  category,
+		}).Get(ctx, &hotspotResult)
+	}
 
 	stepResult := StepResult{
 		StepID:      "step_1",
@@ -157,26 +185,38 @@ func HotspotTrackingWorkflow(ctx workflow.Context, input WorkflowInput) (*Workfl
 		stepResult.Status = "failed"
 		stepResult.Error = err.Error()
 		result.StepsResults = append(result.StepsResults, stepResult)
-		result.Status = "failed"
+		progress.record(ctx, stepResult)
 		result.Error = err.Error()
 		result.CompletedAt = workflow.Now(ctx)
+		if result.Status = endTask(ctx, input.TaskID, err); result.Status == "cancelled" {
+			return result, err
+		}
 		return result, nil
 	}
 
 	stepResult.Status = "completed"
 	stepResult.Output = hotspotResult.Output
 	result.StepsResults = append(result.StepsResults, stepResult)
+	progress.record(ctx, stepResult)
+	progress.report(ctx)
 
-	// Step 2: Generate content suggestions
+	// Step 2: Generate content suggestions, unless paused and then cancelled
+	if err := gate.wait(ctx); err != nil {
+		result.CompletedAt = workflow.Now(ctx)
+		result.Status = endTask(ctx, input.TaskID, err)
+		return result, err
+	}
 	var contentResult SkillExecutionResult
-	err = workflow.ExecuteActivity(ctx, GenerateContentSuggestionsActivity, ContentSuggestionInput{
+	step2Start := workflow.Now(ctx)
+	progress.start(ctx, "step_2", stepRun{Input: hotspotResult.Output})
+	err = workflow.ExecuteActivity(ctx, a.GenerateContentSuggestionsActivity, ContentSuggestionInput{
 		Hotspots: hotspotResult.Output,
 	}).Get(ctx, &contentResult)
 
 	step2Result := StepResult{
 		StepID:      "step_2",
 		StepName:    "内容建议生成",
-		StartedAt:   workflow.Now(ctx),
+		StartedAt:   step2Start,
 		CompletedAt: workflow.Now(ctx),
 	}
 
@@ -184,19 +224,25 @@ func HotspotTrackingWorkflow(ctx workflow.Context, input WorkflowInput) (*Workfl
 		step2Result.Status = "failed"
 		step2Result.Error = err.Error()
 		result.StepsResults = append(result.StepsResults, step2Result)
-		result.Status = "failed"
+		progress.record(ctx, step2Result)
 		result.Error = err.Error()
 		result.CompletedAt = workflow.Now(ctx)
+		if result.Status = endTask(ctx, input.TaskID, err); result.Status == "cancelled" {
+			return result, err
+		}
 		return result, nil
 	}
 
 	step2Result.Status = "completed"
 	step2Result.Output = contentResult.Output
 	result.StepsResults = append(result.StepsResults, step2Result)
+	progress.record(ctx, step2Result)
+	progress.report(ctx)
 
 	result.Status = "completed"
 	result.Output = contentResult.Output
 	result.CompletedAt = workflow.Now(ctx)
+	finishTask(ctx, input.TaskID, "completed", contentResult.Output, "")
 
 	logger.Info("Hotspot tracking workflow completed", "taskId", input.TaskID)
 	return result, nil
@@ -204,6 +250,7 @@ func HotspotTrackingWorkflow(ctx workflow.Context, input WorkflowInput) (*Workfl
 
 // AutoOpsWorkflow handles automated operations workflow
 func AutoOpsWorkflow(ctx workflow.Context, input WorkflowInput) (*WorkflowResult, error) {
+	var a *Activities
 	logger := workflow.GetLogger(ctx)
 	logger.Info("Starting auto ops workflow", "taskId", input.TaskID)
 
@@ -225,15 +272,26 @@ func AutoOpsWorkflow(ctx workflow.Context, input WorkflowInput) (*WorkflowResult
 		},
 	}
 	ctx = workflow.WithActivityOptions(ctx, activityOptions)
+	gate := newPauseGate(ctx)
+	approvals := newApprovalDesk(ctx, input.TaskID, input.CompanyID)
+	progress := newProgressTracker(ctx, input.TaskID, 1, gate,
+		task.PipelineStep{ID: "step_1", Name: "内容生成"},
+		task.PipelineStep{ID: "step_2", Name: "内容审核"},
+		task.PipelineStep{ID: "step_3", Name: "内容发布"},
+	)
 
 	// Step 1: Generate content
 	var generateResult SkillExecutionResult
-	err := workflow.ExecuteActivity(ctx, ExecuteSkillActivity, SkillExecutionInput{
-		TaskID:      input.TaskID,
-		SkillCardID: input.SkillCardID,
-		EmployeeID:  input.EmployeeID,
-		Parameters:  input.Parameters,
-	}).Get(ctx, &generateResult)
+	err := gate.wait(ctx)
+	if err == nil {
+		progress.start(ctx, "step_1", stepRun{SkillCardID: input.SkillCardID, EmployeeID: input.EmployeeID, Input: input.Parameters})
+		err = workflow.ExecuteActivity(ctx, a.ExecuteSkillActivity, SkillExecutionInput{
+			TaskID:      input.TaskID,
+			SkillCardID: input.SkillCardID,
+			EmployeeID:  input.EmployeeID,
+			Parameters:  input.Parameters,
+		}).Get(ctx, &generateResult)
+	}
 
 	stepResult := StepResult{
 		StepID:      "step_1",
@@ -246,9 +304,12 @@ func AutoOpsWorkflow(ctx workflow.Context, input WorkflowInput) (*WorkflowResult
 		stepResult.Status = "failed"
 		stepResult.Error = err.Error()
 		result.StepsResults = append(result.StepsResults, stepResult)
-		result.Status = "failed"
+		progress.record(ctx, stepResult)
 		result.Error = err.Error()
 		result.CompletedAt = workflow.Now(ctx)
+		if result.Status = endTask(ctx, input.TaskID, err); result.Status == "cancelled" {
+			return result, err
+		}
 		return result, nil
 	}
 
@@ -256,18 +317,24 @@ func AutoOpsWorkflow(ctx workflow.Context, input WorkflowInput) (*WorkflowResult
 	stepResult.Output = generateResult.Output
 	stepResult.TokensUsed = generateResult.TokensUsed
 	result.StepsResults = append(result.StepsResults, stepResult)
+	progress.record(ctx, stepResult)
+	progress.report(ctx)
 
-	// Step 2: Publish content (mock for now)
-	var publishResult map[string]interface{}
-	err = workflow.ExecuteActivity(ctx, PublishContentActivity, PublishInput{
-		Content:   generateResult.Output,
-		Platforms: []string{"xiaohongshu"},
-	}).Get(ctx, &publishResult)
+	// Step 2: Hold the content for human review before it goes public
+	if err := gate.wait(ctx); err != nil {
+		result.CompletedAt = workflow.Now(ctx)
+		result.Status = endTask(ctx, input.TaskID, err)
+		return result, err
+	}
+	var reviewResult SkillExecutionResult
+	step2Start := workflow.Now(ctx)
+	progress.start(ctx, "step_2", stepRun{Input: generateResult.Output})
+	err = approvals.request(ctx, "step_2", "内容审核", generateResult.Output, input.Approval).Get(ctx, &reviewResult)
 
 	step2Result := StepResult{
 		StepID:      "step_2",
-		StepName:    "内容发布",
-		StartedAt:   workflow.Now(ctx),
+		StepName:    "内容审核",
+		StartedAt:   step2Start,
 		CompletedAt: workflow.Now(ctx),
 	}
 
@@ -275,17 +342,160 @@ func AutoOpsWorkflow(ctx workflow.Context, input WorkflowInput) (*WorkflowResult
 		step2Result.Status = "failed"
 		step2Result.Error = err.Error()
 		result.StepsResults = append(result.StepsResults, step2Result)
-		// Continue even if publish fails
-	} else {
-		step2Result.Status = "completed"
-		step2Result.Output = publishResult
+		progress.record(ctx, step2Result)
+		result.Error = err.Error()
+		result.CompletedAt = workflow.Now(ctx)
+		if result.Status = endTask(ctx, input.TaskID, err); result.Status == "cancelled" {
+			return result, err
+		}
+		return result, nil
 	}
+
+	step2Result.Status = "completed"
+	step2Result.Output = reviewResult.Output
 	result.StepsResults = append(result.StepsResults, step2Result)
+	progress.record(ctx, step2Result)
+	progress.report(ctx)
+
+	// Step 3: Publish the approved content to the platforms the task names, or
+	// the default ones. Content that went out on only some platforms is
+	// unpublished again and fails the task.
+	if err := gate.wait(ctx); err != nil {
+		result.CompletedAt = workflow.Now(ctx)
+		result.Status = endTask(ctx, input.TaskID, err)
+		return result, err
+	}
+	platforms := publishTargets(input.Parameters)
+	mode, _ := input.Parameters["publish_mode"].(string)
+	var publishResult map[string]interface{}
+	step3Start := workflow.Now(ctx)
+	progress.start(ctx, "step_3", stepRun{Input: reviewResult.Output})
+	err = workflow.ExecuteActivity(ctx, a.PublishContentActivity, PublishInput{
+		CompanyID: input.CompanyID,
+		Content:   reviewResult.Output,
+		Platforms: platforms,
+		Mode:      mode,
+	}).Get(ctx, &publishResult)
+
+	compensations := &saga{}
+	if err == nil {
+		var published []string
+		published, err = PublishOutcome(publishResult, platforms)
+		if len(published) > 0 {
+			compensations.add("step_3", "内容发布", func(ctx workflow.Context) error {
+				return workflow.ExecuteActivity(ctx, a.UnpublishContentActivity, UnpublishInput{
+					CompanyID:   input.CompanyID,
+					Platforms:   published,
+					Publication: publishResult,
+				}).Get(ctx, nil)
+			})
+		}
+	}
+
+	step3Result := StepResult{
+		StepID:      "step_3",
+		StepName:    "内容发布",
+		Output:      publishResult,
+		StartedAt:   step3Start,
+		CompletedAt: workflow.Now(ctx),
+	}
+
+	if err != nil {
+		step3Result.Status = "failed"
+		step3Result.Error = err.Error()
+		result.StepsResults = append(result.StepsResults, step3Result)
+		progress.record(ctx, step3Result)
+		outcomes := compensations.compensate(ctx, progress, result)
+		result.Error = withCompensations(err.Error(), definition.DescribeCompensations(outcomes))
+		result.CompletedAt = workflow.Now(ctx)
+		if temporal.IsCanceledError(err) {
+			result.Status = stopTask(ctx, input.TaskID, true, "", result.Compensation)
+			return result, err
+		}
+		result.Status = stopTask(ctx, input.TaskID, false, result.Error, result.Compensation)
+		return result, nil
+	}
+
+	step3Result.Status = "completed"
+	result.StepsResults = append(result.StepsResults, step3Result)
+	progress.record(ctx, step3Result)
+	progress.report(ctx)
 
 	result.Status = "completed"
-	result.Output = generateResult.Output
+	result.Output = reviewResult.Output
 	result.CompletedAt = workflow.Now(ctx)
+	finishTask(ctx, input.TaskID, "completed", reviewResult.Output, "")
 
 	logger.Info("Auto ops workflow completed", "taskId", input.TaskID)
 	return result, nil
 }
+
+// publishTargets returns the platforms a task publishes to, given as a
+// platforms list or a single platform; none means the default platforms
+func publishTargets(params map[string]interface{}) []string {
+	if platforms := stringList(params["platforms"]); len(platforms) > 0 {
+		return platforms
+	}
+	if platform, _ := params["platform"].(string); platform != "" {
+		return []string{platform}
+	}
+	return nil
+}
+
+// stringList returns the strings of a decoded JSON list
+func stringList(v interface{}) []string {
+	var values []string
+	switch list := v.(type) {
+	case []string:
+		values = append(values, list...)
+	case []interface{}:
+		for _, item := range list {
+			if s, ok := item.(string); ok && s != "" {
+				values = append(values, s)
+			}
+		}
+	}
+	return values
+}
+
+// publishedPlatforms splits the platforms of a publish into those the
+// content went out on and those it did not
+func publishedPlatforms(publishResult map[string]interface{}, platforms []string) (published, failed []string) {
+	// The in-process engine reads the activity's result before any encoding
+	statuses, _ := publishResult["status"].(map[string]interface{})
+	if raw, ok := publishResult["status"].(map[string]string); ok {
+		statuses = make(map[string]interface{}, len(raw))
+		for platform, status := range raw {
+			statuses[platform] = status
+		}
+	}
+	for _, platform := range platforms {
+		if statuses[platform] == "success" {
+			published = append(published, platform)
+		} else {
+			failed = append(failed, platform)
+		}
+	}
+	return published, failed
+}
+
+// finishTask records the workflow outcome on the task
+func finishTask(ctx workflow.Context, taskID, status string, output map[string]interface{}, errMsg string) {
+	updateTask(ctx, TaskStatusInput{
+		TaskID:   taskID,
+		Status:   status,
+		Progress: 100,
+		Output:   output,
+		Error:    errMsg,
+	})
+}
+
+// updateTask applies a status update to the task. A failed update is logged
+// rather than failing the workflow, whose result already holds the outcome.
+func updateTask(ctx workflow.Context, input TaskStatusInput) {
+	var a *Activities
+	err := workflow.ExecuteActivity(ctx, a.UpdateTaskStatusActivity, input).Get(ctx, nil)
+	if err != nil {
+		workflow.GetLogger(ctx).Warn("Failed to update task status", "taskId", input.TaskID, "error", err)
+	}
+}
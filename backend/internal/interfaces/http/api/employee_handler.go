@@ -35,6 +35,8 @@ func (h *EmployeeHandler) RegisterRoutes(r *gin.RouterGroup, companyMiddleware g
 		employees.DELETE("/:id/skills/:skillId", h.RemoveSkill)
 		employees.GET("/:id/skills", h.GetSkills)
 		employees.PUT("/:id/status", h.SetStatus)
+		employees.GET("/:id/calendar", h.GetCalendar)
+		employees.PUT("/:id/calendar", h.UpdateCalendar)
 	}
 }
 
@@ -298,3 +300,62 @@ func (h *EmployeeHandler) SetStatus(c *gin.Context) {
 		"data":    emp,
 	})
 }
+
+// GetCalendar retrieves the working hours and blackouts of an employee
+func (h *EmployeeHandler) GetCalendar(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	cal, err := h.employeeService.GetCalendar(c.Request.Context(), id, companyID)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    cal,
+	})
+}
+
+// UpdateCalendar replaces the working hours and blackouts of an employee
+func (h *EmployeeHandler) UpdateCalendar(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input employeeApp.UpdateCalendarInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	input.EmployeeID = id
+	input.CompanyID = companyID
+
+	cal, err := h.employeeService.UpdateCalendar(c.Request.Context(), &input)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    cal,
+	})
+}
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	hotspotApp "unlimited-corp/internal/application/hotspot"
+	"unlimited-corp/internal/interfaces/http/helpers"
+	"unlimited-corp/internal/interfaces/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxHotspotUploadSize is the largest hotspot CSV accepted
+const maxHotspotUploadSize = 1 << 20
+
+// HotspotHandler handles hotspot HTTP requests
+type HotspotHandler struct {
+	service *hotspotApp.Service
+}
+
+// NewHotspotHandler creates a new hotspot handler
+func NewHotspotHandler(service *hotspotApp.Service) *HotspotHandler {
+	return &HotspotHandler{service: service}
+}
+
+// RegisterRoutes registers hotspot routes
+func (h *HotspotHandler) RegisterRoutes(r *gin.RouterGroup, companyMiddleware gin.HandlerFunc) {
+	hotspots := r.Group("/hotspots")
+	hotspots.Use(middleware.AuthRequired())
+	hotspots.Use(companyMiddleware)
+	{
+		hotspots.GET("", h.Latest)
+		hotspots.POST("/uploads", h.Upload)
+	}
+}
+
+// Latest returns the newest hotspot snapshot of a platform and category
+func (h *HotspotHandler) Latest(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.Latest(c.Request.Context(), companyID, c.Query("platform"), c.Query("category"))
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// Upload stores a CSV of hotspots as a source of a platform's analyses
+func (h *HotspotHandler) Upload(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	userID, ok := helpers.MustGetUserID(c)
+	if !ok {
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	if file.Size > maxHotspotUploadSize {
+		helpers.RespondError(c, http.StatusBadRequest, fmt.Sprintf("the file is larger than %d bytes", maxHotspotUploadSize))
+		return
+	}
+	f, err := file.Open()
+	if err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.service.Upload(c.Request.Context(), &hotspotApp.UploadInput{
+		CompanyID:  companyID,
+		UploadedBy: &userID,
+		Platform:   c.PostForm("platform"),
+		Filename:   file.Filename,
+		Content:    content,
+	})
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"code": 0, "message": "success", "data": result})
+}
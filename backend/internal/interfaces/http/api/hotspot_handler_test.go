@@ -0,0 +1,22 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHotspotHandler_RegisterRoutes(t *testing.T) {
+	router := gin.New()
+	handler := NewHotspotHandler(nil)
+	handler.RegisterRoutes(router.Group("/api/v1"), func(c *gin.Context) { c.Next() })
+
+	paths := make(map[string]bool)
+	for _, r := range router.Routes() {
+		paths[r.Method+" "+r.Path] = true
+	}
+
+	assert.True(t, paths["GET /api/v1/hotspots"])
+	assert.True(t, paths["POST /api/v1/hotspots/uploads"])
+}
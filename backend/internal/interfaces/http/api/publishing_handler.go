@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+
+	publishingApp "unlimited-corp/internal/application/publishing"
+	"unlimited-corp/internal/interfaces/http/helpers"
+	"unlimited-corp/internal/interfaces/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PublishingHandler handles publishing platform and credential HTTP requests
+type PublishingHandler struct {
+	service *publishingApp.Service
+}
+
+// NewPublishingHandler creates a new publishing handler
+func NewPublishingHandler(service *publishingApp.Service) *PublishingHandler {
+	return &PublishingHandler{service: service}
+}
+
+// RegisterRoutes registers publishing routes
+func (h *PublishingHandler) RegisterRoutes(r *gin.RouterGroup, companyMiddleware gin.HandlerFunc) {
+	publishing := r.Group("/publishing")
+	publishing.Use(middleware.AuthRequired())
+	publishing.Use(companyMiddleware)
+	{
+		publishing.GET("/platforms", h.ListPlatforms)
+		publishing.PUT("/credentials/:platform", h.SaveCredentials)
+		publishing.DELETE("/credentials/:platform", h.DeleteCredentials)
+	}
+}
+
+// SaveCredentialsRequest is the body of a credentials update
+type SaveCredentialsRequest struct {
+	Credentials map[string]string `json:"credentials" binding:"required"`
+}
+
+// ListPlatforms lists the configured platforms with the names of the current company's credentials
+func (h *PublishingHandler) ListPlatforms(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.ListPlatforms(c.Request.Context(), companyID)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// SaveCredentials creates or replaces the current company's credentials for a platform
+func (h *PublishingHandler) SaveCredentials(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+
+	var req SaveCredentialsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.service.SaveCredentials(c.Request.Context(), companyID, c.Param("platform"), req.Credentials)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// DeleteCredentials removes the current company's credentials for a platform
+func (h *PublishingHandler) DeleteCredentials(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteCredentials(c.Request.Context(), companyID, c.Param("platform")); err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "credentials deleted successfully"})
+}
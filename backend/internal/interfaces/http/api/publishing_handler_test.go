@@ -0,0 +1,23 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishingHandler_RegisterRoutes(t *testing.T) {
+	router := gin.New()
+	handler := NewPublishingHandler(nil)
+	handler.RegisterRoutes(router.Group("/api/v1"), func(c *gin.Context) { c.Next() })
+
+	paths := make(map[string]bool)
+	for _, r := range router.Routes() {
+		paths[r.Method+" "+r.Path] = true
+	}
+
+	assert.True(t, paths["GET /api/v1/publishing/platforms"])
+	assert.True(t, paths["PUT /api/v1/publishing/credentials/:platform"])
+	assert.True(t, paths["DELETE /api/v1/publishing/credentials/:platform"])
+}
@@ -0,0 +1,202 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	taskApp "unlimited-corp/internal/application/task"
+	"unlimited-corp/internal/interfaces/http/helpers"
+	"unlimited-corp/internal/interfaces/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleHandler handles task schedule HTTP requests
+type ScheduleHandler struct {
+	service *taskApp.Service
+}
+
+// NewScheduleHandler creates a new schedule handler
+func NewScheduleHandler(service *taskApp.Service) *ScheduleHandler {
+	return &ScheduleHandler{service: service}
+}
+
+// RegisterRoutes registers task schedule routes
+func (h *ScheduleHandler) RegisterRoutes(r *gin.RouterGroup, companyMiddleware gin.HandlerFunc) {
+	schedules := r.Group("/schedules")
+	schedules.Use(middleware.AuthRequired())
+	schedules.Use(companyMiddleware)
+	{
+		schedules.POST("", h.Create)
+		schedules.GET("", h.List)
+		schedules.GET("/:id", h.GetByID)
+		schedules.POST("/:id/pause", h.Pause)
+		schedules.POST("/:id/resume", h.Resume)
+		schedules.POST("/:id/trigger", h.Trigger)
+		schedules.GET("/:id/runs", h.ListRuns)
+		schedules.DELETE("/:id", h.Delete)
+	}
+}
+
+// Create creates a one-off or cron schedule for the current company
+func (h *ScheduleHandler) Create(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	userID, ok := helpers.MustGetUserID(c)
+	if !ok {
+		return
+	}
+
+	var input taskApp.CreateScheduleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	input.CompanyID = companyID
+	input.CreatedBy = userID
+
+	result, err := h.service.CreateSchedule(c.Request.Context(), &input)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// List lists the schedules of the current company
+func (h *ScheduleHandler) List(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.ListSchedules(c.Request.Context(), companyID)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// GetByID returns a schedule with the upcoming times it fires
+func (h *ScheduleHandler) GetByID(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.GetSchedule(c.Request.Context(), companyID, id)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// Pause stops a schedule from firing
+func (h *ScheduleHandler) Pause(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.PauseSchedule(c.Request.Context(), companyID, id)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// Resume lets a paused schedule fire again
+func (h *ScheduleHandler) Resume(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.ResumeSchedule(c.Request.Context(), companyID, id)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// Trigger starts the schedule's task right away
+func (h *ScheduleHandler) Trigger(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.TriggerSchedule(c.Request.Context(), companyID, id); err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"code": 0, "message": "schedule triggered"})
+}
+
+// ListRuns lists the tasks a schedule started, newest first
+func (h *ScheduleHandler) ListRuns(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	result, err := h.service.ListScheduleRuns(c.Request.Context(), companyID, id, limit)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// Delete removes a schedule; the tasks it started are kept
+func (h *ScheduleHandler) Delete(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteSchedule(c.Request.Context(), companyID, id); err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "schedule deleted successfully"})
+}
@@ -0,0 +1,28 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduleHandler_RegisterRoutes(t *testing.T) {
+	router := gin.New()
+	handler := NewScheduleHandler(nil)
+	handler.RegisterRoutes(router.Group("/api/v1"), func(c *gin.Context) { c.Next() })
+
+	paths := make(map[string]bool)
+	for _, r := range router.Routes() {
+		paths[r.Method+" "+r.Path] = true
+	}
+
+	assert.True(t, paths["POST /api/v1/schedules"])
+	assert.True(t, paths["GET /api/v1/schedules"])
+	assert.True(t, paths["GET /api/v1/schedules/:id"])
+	assert.True(t, paths["POST /api/v1/schedules/:id/pause"])
+	assert.True(t, paths["POST /api/v1/schedules/:id/resume"])
+	assert.True(t, paths["POST /api/v1/schedules/:id/trigger"])
+	assert.True(t, paths["GET /api/v1/schedules/:id/runs"])
+	assert.True(t, paths["DELETE /api/v1/schedules/:id"])
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"unlimited-corp/internal/application/scheduler"
+	taskApp "unlimited-corp/internal/application/task"
+	"unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/interfaces/http/helpers"
+	"unlimited-corp/internal/interfaces/http/middleware"
+	"unlimited-corp/pkg/errors"
+)
+
+// SchedulingHandler exposes scheduling explanations for tasks
+type SchedulingHandler struct {
+	scheduler   *scheduler.TaskScheduler
+	taskService *taskApp.Service
+}
+
+// NewSchedulingHandler creates a new scheduling handler
+func NewSchedulingHandler(taskScheduler *scheduler.TaskScheduler, taskService *taskApp.Service) *SchedulingHandler {
+	return &SchedulingHandler{
+		scheduler:   taskScheduler,
+		taskService: taskService,
+	}
+}
+
+// RegisterRoutes registers scheduling routes
+func (h *SchedulingHandler) RegisterRoutes(r *gin.RouterGroup, companyMiddleware gin.HandlerFunc) {
+	tasks := r.Group("/tasks")
+	tasks.Use(middleware.AuthRequired())
+	tasks.Use(companyMiddleware)
+	{
+		tasks.GET("/:id/scheduling-preview", h.Preview)
+		tasks.GET("/:id/scheduling-decision", h.GetDecision)
+		tasks.POST("/:id/heartbeat", h.Heartbeat)
+		tasks.GET("/:id/recoveries", h.ListRecoveries)
+	}
+}
+
+// RegisterOpsRoutes registers operator routes guarded by the given middleware
+func (h *SchedulingHandler) RegisterOpsRoutes(r *gin.RouterGroup, opsMiddleware gin.HandlerFunc) {
+	ops := r.Group("/ops/scheduling")
+	ops.Use(opsMiddleware)
+	{
+		ops.GET("/queues", h.QueueStatus)
+	}
+}
+
+// Preview ranks candidate employees for a task without assigning it
+func (h *SchedulingHandler) Preview(c *gin.Context) {
+	t, ok := h.getCompanyTask(c)
+	if !ok {
+		return
+	}
+
+	decision, err := h.scheduler.Preview(c.Request.Context(), t)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    decision,
+	})
+}
+
+// GetDecision returns the explanation stored with the task's latest assignment
+func (h *SchedulingHandler) GetDecision(c *gin.Context) {
+	t, ok := h.getCompanyTask(c)
+	if !ok {
+		return
+	}
+
+	decision, err := h.scheduler.GetDecision(c.Request.Context(), t.ID)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    decision,
+	})
+}
+
+// HeartbeatRequest identifies the employee renewing a task lease
+type HeartbeatRequest struct {
+	EmployeeID uuid.UUID `json:"employee_id" binding:"required"`
+}
+
+// Heartbeat renews the lease of a running task. A 404 tells the worker the
+// lease is gone and the task was recovered.
+func (h *SchedulingHandler) Heartbeat(c *gin.Context) {
+	t, ok := h.getCompanyTask(c)
+	if !ok {
+		return
+	}
+
+	var req HeartbeatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	if err := h.scheduler.Heartbeat(c.Request.Context(), t.ID, req.EmployeeID); err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// ListRecoveries lists the times the task was recovered from an expired lease
+func (h *SchedulingHandler) ListRecoveries(c *gin.Context) {
+	t, ok := h.getCompanyTask(c)
+	if !ok {
+		return
+	}
+
+	recoveries, err := h.scheduler.ListRecoveries(c.Request.Context(), t.ID)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    recoveries,
+	})
+}
+
+// QueueStatus reports the shared execution slots and every company's queue depth and wait time
+func (h *SchedulingHandler) QueueStatus(c *gin.Context) {
+	status, err := h.scheduler.QueueStatus(c.Request.Context())
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    status,
+	})
+}
+
+// getCompanyTask loads the task from the URL and checks it belongs to the current company
+func (h *SchedulingHandler) getCompanyTask(c *gin.Context) (*task.Task, bool) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return nil, false
+	}
+
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return nil, false
+	}
+
+	t, err := h.taskService.GetByID(c.Request.Context(), id)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return nil, false
+	}
+	if t.CompanyID != companyID {
+		helpers.HandleError(c, errors.ErrNotFound)
+		return nil, false
+	}
+
+	return t, true
+}
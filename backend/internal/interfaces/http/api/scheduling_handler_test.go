@@ -0,0 +1,26 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchedulingHandler_RegisterRoutes(t *testing.T) {
+	router := gin.New()
+	handler := NewSchedulingHandler(nil, nil)
+	handler.RegisterRoutes(router.Group("/api/v1"), mockCompanyMiddleware())
+	handler.RegisterOpsRoutes(router.Group("/api/v1"), func(c *gin.Context) { c.Next() })
+
+	paths := make(map[string]bool)
+	for _, r := range router.Routes() {
+		paths[r.Method+" "+r.Path] = true
+	}
+
+	assert.True(t, paths["GET /api/v1/tasks/:id/scheduling-preview"])
+	assert.True(t, paths["GET /api/v1/tasks/:id/scheduling-decision"])
+	assert.True(t, paths["POST /api/v1/tasks/:id/heartbeat"])
+	assert.True(t, paths["GET /api/v1/tasks/:id/recoveries"])
+	assert.True(t, paths["GET /api/v1/ops/scheduling/queues"])
+}
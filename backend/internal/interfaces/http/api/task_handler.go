@@ -1,13 +1,18 @@
 package api
 
 import (
+	"context"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 
 	taskApp "unlimited-corp/internal/application/task"
 	taskDomain "unlimited-corp/internal/domain/task"
+	"unlimited-corp/internal/interfaces/http/helpers"
 	"unlimited-corp/internal/interfaces/http/middleware"
 	"unlimited-corp/pkg/errors"
+	"unlimited-corp/pkg/logger"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -21,17 +26,31 @@ func NewTaskHandler(service *taskApp.Service) *TaskHandler {
 	return &TaskHandler{service: service}
 }
 
-func (h *TaskHandler) RegisterRoutes(r *gin.RouterGroup) {
+func (h *TaskHandler) RegisterRoutes(r *gin.RouterGroup, companyMiddleware gin.HandlerFunc) {
 	tasks := r.Group("/tasks")
 	tasks.Use(middleware.AuthRequired())
 	{
 		tasks.POST("", h.Create)
 		tasks.GET("", h.List)
-		tasks.GET("/:id", h.GetByID)
 		tasks.PUT("/:id", h.Update)
-		tasks.PATCH("/:id/status", h.UpdateStatus)
 		tasks.DELETE("/:id", h.Delete)
 	}
+
+	// Workflow operations and views act only on the tasks of the current company
+	companyTasks := tasks.Group("")
+	companyTasks.Use(companyMiddleware)
+	{
+		companyTasks.GET("/:id", h.GetByID)
+		companyTasks.PATCH("/:id/status", h.UpdateStatus)
+		companyTasks.POST("/:id/pause", h.Pause)
+		companyTasks.POST("/:id/resume", h.Resume)
+		companyTasks.POST("/:id/cancel", h.Cancel)
+		companyTasks.POST("/:id/retry", h.Retry)
+		companyTasks.GET("/:id/attempts", h.ListAttempts)
+		companyTasks.GET("/:id/steps", h.ListSteps)
+		companyTasks.GET("/:id/approvals", h.ListApprovals)
+		companyTasks.POST("/:id/approvals", h.DecideApproval)
+	}
 }
 
 func (h *TaskHandler) Create(c *gin.Context) {
@@ -43,7 +62,7 @@ func (h *TaskHandler) Create(c *gin.Context) {
 
 	result, err := h.service.Create(c.Request.Context(), &input)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to create task"})
+		helpers.HandleError(c, err)
 		return
 	}
 
@@ -70,13 +89,17 @@ func (h *TaskHandler) List(c *gin.Context) {
 }
 
 func (h *TaskHandler) GetByID(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid task id"})
 		return
 	}
 
-	result, err := h.service.GetByID(c.Request.Context(), id)
+	result, err := h.service.GetCompanyTask(c.Request.Context(), companyID, id)
 	if err != nil {
 		if err == errors.ErrNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "task not found"})
@@ -86,7 +109,19 @@ func (h *TaskHandler) GetByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+	// The live pipeline is best effort; the stored progress is shown regardless
+	pipeline, err := h.service.Pipeline(c.Request.Context(), result)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("Failed to get pipeline of task %s: %v", result.ID, err))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": taskView{Task: result, Pipeline: pipeline}})
+}
+
+// taskView is a task with the live pipeline of its workflow
+type taskView struct {
+	*taskDomain.Task
+	Pipeline *taskDomain.Pipeline `json:"pipeline,omitempty"`
 }
 
 func (h *TaskHandler) Update(c *gin.Context) {
@@ -116,6 +151,10 @@ func (h *TaskHandler) Update(c *gin.Context) {
 }
 
 func (h *TaskHandler) UpdateStatus(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"code": 400, "message": "invalid task id"})
@@ -130,13 +169,177 @@ func (h *TaskHandler) UpdateStatus(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.UpdateStatus(c.Request.Context(), id, input.Status)
+	result, err := h.service.UpdateStatus(c.Request.Context(), companyID, id, input.Status)
 	if err != nil {
-		if err == errors.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"code": 404, "message": "task not found"})
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// Pause pauses a running task before its next workflow step
+func (h *TaskHandler) Pause(c *gin.Context) {
+	h.control(c, h.service.Pause)
+}
+
+// Resume resumes a paused task
+func (h *TaskHandler) Resume(c *gin.Context) {
+	h.control(c, h.service.Resume)
+}
+
+// Cancel cancels a task and its workflow
+func (h *TaskHandler) Cancel(c *gin.Context) {
+	h.control(c, h.service.Cancel)
+}
+
+// control applies a workflow control operation to the current company's
+// task in the URL
+func (h *TaskHandler) control(c *gin.Context, op func(context.Context, uuid.UUID, uuid.UUID) (*taskDomain.Task, error)) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	result, err := op(c.Request.Context(), companyID, id)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// Retry runs a failed task again, reusing the outputs of the steps that
+// succeeded. The body is optional.
+func (h *TaskHandler) Retry(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := helpers.MustGetUserID(c)
+	if !ok {
+		return
+	}
+
+	var input taskApp.RetryInput
+	if err := c.ShouldBindJSON(&input); err != nil && err != io.EOF {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.service.Retry(c.Request.Context(), companyID, id, userID, &input)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// ListAttempts lists the earlier runs of a retried task
+func (h *TaskHandler) ListAttempts(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.ListAttempts(c.Request.Context(), companyID, id)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// ListSteps lists the recorded steps of the task's current run, or of the
+// run given by the attempt query parameter
+func (h *TaskHandler) ListSteps(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+	attempt := 0
+	if raw := c.Query("attempt"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			helpers.RespondError(c, http.StatusBadRequest, "invalid attempt")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "failed to update task status"})
+		attempt = n
+	}
+
+	result, err := h.service.ListSteps(c.Request.Context(), companyID, id, attempt)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// ListApprovals lists the approvals of a task with who decided them
+func (h *TaskHandler) ListApprovals(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.ListApprovals(c.Request.Context(), companyID, id)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// DecideApproval approves, rejects or edits the content a task's workflow
+// holds for review, as the current user
+func (h *TaskHandler) DecideApproval(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+	userID, ok := helpers.MustGetUserID(c)
+	if !ok {
+		return
+	}
+
+	var input taskApp.DecideApprovalInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.service.DecideApproval(c.Request.Context(), companyID, id, userID, &input)
+	if err != nil {
+		helpers.HandleError(c, err)
 		return
 	}
 
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTaskHandler_RegisterRoutes(t *testing.T) {
+	router := gin.New()
+	handler := NewTaskHandler(nil)
+	handler.RegisterRoutes(router.Group("/api/v1"), mockCompanyMiddleware())
+
+	paths := make(map[string]bool)
+	for _, r := range router.Routes() {
+		paths[r.Method+" "+r.Path] = true
+	}
+
+	assert.True(t, paths["POST /api/v1/tasks"])
+	assert.True(t, paths["PATCH /api/v1/tasks/:id/status"])
+	assert.True(t, paths["POST /api/v1/tasks/:id/pause"])
+	assert.True(t, paths["POST /api/v1/tasks/:id/resume"])
+	assert.True(t, paths["POST /api/v1/tasks/:id/cancel"])
+	assert.True(t, paths["POST /api/v1/tasks/:id/retry"])
+	assert.True(t, paths["GET /api/v1/tasks/:id/attempts"])
+	assert.True(t, paths["GET /api/v1/tasks/:id/steps"])
+	assert.True(t, paths["GET /api/v1/tasks/:id/approvals"])
+	assert.True(t, paths["POST /api/v1/tasks/:id/approvals"])
+}
@@ -0,0 +1,221 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	workflowApp "unlimited-corp/internal/application/workflow"
+	"unlimited-corp/internal/domain/workflow"
+	"unlimited-corp/internal/interfaces/http/helpers"
+	"unlimited-corp/internal/interfaces/http/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WorkflowHandler exposes workflow definition tooling and the template catalog
+type WorkflowHandler struct {
+	service *workflowApp.Service
+}
+
+// NewWorkflowHandler creates a new workflow handler
+func NewWorkflowHandler(service *workflowApp.Service) *WorkflowHandler {
+	return &WorkflowHandler{service: service}
+}
+
+// RegisterRoutes registers workflow definition and template routes
+func (h *WorkflowHandler) RegisterRoutes(r *gin.RouterGroup, companyMiddleware gin.HandlerFunc) {
+	definitions := r.Group("/workflow-definitions")
+	definitions.Use(middleware.AuthRequired())
+	{
+		definitions.POST("/validate", h.Validate)
+	}
+
+	templates := r.Group("/workflows/templates")
+	templates.Use(middleware.AuthRequired())
+	templates.Use(companyMiddleware)
+	{
+		templates.GET("", h.ListTemplates)
+		templates.POST("", h.CreateTemplate)
+		templates.GET("/:id", h.GetTemplate)
+		templates.POST("/:id/versions", h.PublishVersion)
+		templates.GET("/:id/versions/:version", h.GetVersion)
+		templates.POST("/:id/instantiate", h.Instantiate)
+	}
+}
+
+// ValidationResult reports whether a definition is valid and what is wrong with it
+type ValidationResult struct {
+	Valid      bool                 `json:"valid"`
+	Problems   []string             `json:"problems"`
+	Definition *workflow.Definition `json:"definition,omitempty"`
+}
+
+// Validate checks a workflow definition without creating a task
+func (h *WorkflowHandler) Validate(c *gin.Context) {
+	raw, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	result := ValidationResult{Problems: []string{}}
+	def, err := workflow.ParseJSON(raw)
+	if err != nil {
+		verr, ok := err.(*workflow.ValidationError)
+		if !ok {
+			helpers.HandleError(c, err)
+			return
+		}
+		result.Problems = verr.Problems
+	} else {
+		result.Valid = true
+		result.Definition = def
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// ListTemplates lists the system templates, the current company's own and the
+// community's, optionally narrowed by category and source (official, mine or community)
+func (h *WorkflowHandler) ListTemplates(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.service.ListTemplates(c.Request.Context(), companyID, workflow.TemplateFilter{
+		Category: c.Query("category"),
+		Source:   c.Query("source"),
+	})
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": gin.H{"items": result}})
+}
+
+// CreateTemplate creates a template of the current company with its first version
+func (h *WorkflowHandler) CreateTemplate(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	userID, ok := helpers.MustGetUserID(c)
+	if !ok {
+		return
+	}
+
+	var input workflowApp.CreateTemplateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	input.CompanyID = companyID
+	input.CreatedBy = userID
+
+	result, err := h.service.CreateTemplate(c.Request.Context(), &input)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// GetTemplate returns a template with its latest version and version history
+func (h *WorkflowHandler) GetTemplate(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.GetTemplate(c.Request.Context(), companyID, id)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// PublishVersion publishes a new version of a template of the current company
+func (h *WorkflowHandler) PublishVersion(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input workflowApp.VersionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+
+	result, err := h.service.PublishVersion(c.Request.Context(), companyID, id, &input)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// GetVersion returns a version of a template
+func (h *WorkflowHandler) GetVersion(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	result, err := h.service.GetVersion(c.Request.Context(), companyID, id, c.Param("version"))
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success", "data": result})
+}
+
+// Instantiate creates a task of the current company from a template version
+func (h *WorkflowHandler) Instantiate(c *gin.Context) {
+	companyID, ok := helpers.MustGetCompanyID(c)
+	if !ok {
+		return
+	}
+	id, ok := helpers.ParseUUID(c, "id")
+	if !ok {
+		return
+	}
+
+	var input workflowApp.InstantiateInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		helpers.RespondError(c, http.StatusBadRequest, "invalid request: "+err.Error())
+		return
+	}
+	input.CompanyID = companyID
+
+	result, err := h.service.Instantiate(c.Request.Context(), id, &input)
+	if err != nil {
+		helpers.HandleError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"code": 0, "message": "success", "data": result})
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkflowHandler_Validate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/validate", NewWorkflowHandler(nil).Validate)
+
+	tests := []struct {
+		name      string
+		body      string
+		wantValid bool
+		wantIssue string
+	}{
+		{
+			name:      "valid",
+			body:      `{"version":1,"nodes":[{"id":"a","type":"skill","skill_card_id":"7f6c1f1e-8a0c-4e4b-9a55-3f2d0f0e6b11"}]}`,
+			wantValid: true,
+		},
+		{
+			name:      "reads a later node",
+			body:      `{"version":1,"nodes":[{"id":"a","type":"skill","skill_card_id":"7f6c1f1e-8a0c-4e4b-9a55-3f2d0f0e6b11","inputs":{"x":"$.nodes.b.output.y"}},{"id":"b","type":"skill","skill_card_id":"7f6c1f1e-8a0c-4e4b-9a55-3f2d0f0e6b11"}]}`,
+			wantIssue: `reads node "b"`,
+		},
+		{
+			name:      "unknown field",
+			body:      `{"version":1,"steps":[]}`,
+			wantIssue: "steps",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/validate", strings.NewReader(tt.body))
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+
+			var resp struct {
+				Data ValidationResult `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, tt.wantValid, resp.Data.Valid)
+			if tt.wantIssue != "" {
+				require.NotEmpty(t, resp.Data.Problems)
+				assert.Contains(t, strings.Join(resp.Data.Problems, "; "), tt.wantIssue)
+			}
+		})
+	}
+}
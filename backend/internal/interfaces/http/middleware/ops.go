@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpsTokenHeader carries the token of operator endpoints
+const OpsTokenHeader = "X-Ops-Token"
+
+// OpsTokenRequired middleware that admits requests carrying the operator token.
+// An empty token disables the endpoints it guards.
+func OpsTokenRequired(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		provided := c.GetHeader(OpsTokenHeader)
+		if token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(token)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": "invalid ops token",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -4,9 +4,13 @@ import (
 	chatApp "unlimited-corp/internal/application/chat"
 	companyApp "unlimited-corp/internal/application/company"
 	employeeApp "unlimited-corp/internal/application/employee"
+	hotspotApp "unlimited-corp/internal/application/hotspot"
+	publishingApp "unlimited-corp/internal/application/publishing"
+	"unlimited-corp/internal/application/scheduler"
 	skillcardApp "unlimited-corp/internal/application/skillcard"
 	taskApp "unlimited-corp/internal/application/task"
 	userApp "unlimited-corp/internal/application/user"
+	workflowApp "unlimited-corp/internal/application/workflow"
 	"unlimited-corp/internal/interfaces/http/api"
 	"unlimited-corp/internal/interfaces/http/middleware"
 
@@ -15,24 +19,34 @@ import (
 
 // Server HTTP服务器
 type Server struct {
-	engine           *gin.Engine
-	userService      *userApp.Service
-	companyService   *companyApp.Service
-	skillCardService *skillcardApp.Service
-	employeeService  *employeeApp.Service
-	taskService      *taskApp.Service
-	chatService      *chatApp.Service
+	engine            *gin.Engine
+	userService       *userApp.Service
+	companyService    *companyApp.Service
+	skillCardService  *skillcardApp.Service
+	employeeService   *employeeApp.Service
+	taskService       *taskApp.Service
+	chatService       *chatApp.Service
+	workflowService   *workflowApp.Service
+	hotspotService    *hotspotApp.Service
+	publishingService *publishingApp.Service
+	taskScheduler     *scheduler.TaskScheduler
+	opsToken          string
 }
 
 // NewServer 创建HTTP服务器
-func NewServer(userService *userApp.Service, companyService *companyApp.Service, skillCardService *skillcardApp.Service, employeeService *employeeApp.Service, taskService *taskApp.Service, chatService *chatApp.Service) *Server {
+func NewServer(userService *userApp.Service, companyService *companyApp.Service, skillCardService *skillcardApp.Service, employeeService *employeeApp.Service, taskService *taskApp.Service, chatService *chatApp.Service, workflowService *workflowApp.Service, hotspotService *hotspotApp.Service, publishingService *publishingApp.Service, taskScheduler *scheduler.TaskScheduler, opsToken string) *Server {
 	return &Server{
-		userService:      userService,
-		companyService:   companyService,
-		skillCardService: skillCardService,
-		employeeService:  employeeService,
-		taskService:      taskService,
-		chatService:      chatService,
+		userService:       userService,
+		companyService:    companyService,
+		skillCardService:  skillCardService,
+		employeeService:   employeeService,
+		taskService:       taskService,
+		chatService:       chatService,
+		workflowService:   workflowService,
+		hotspotService:    hotspotService,
+		publishingService: publishingService,
+		taskScheduler:     taskScheduler,
+		opsToken:          opsToken,
 	}
 }
 
@@ -79,7 +93,28 @@ func (s *Server) Setup(mode string) *gin.Engine {
 
 	// 任务相关
 	taskHandler := api.NewTaskHandler(s.taskService)
-	taskHandler.RegisterRoutes(apiV1)
+	taskHandler.RegisterRoutes(apiV1, companyMiddleware)
+
+	// 任务计划相关
+	scheduleHandler := api.NewScheduleHandler(s.taskService)
+	scheduleHandler.RegisterRoutes(apiV1, companyMiddleware)
+
+	// 工作流定义与模板相关
+	workflowHandler := api.NewWorkflowHandler(s.workflowService)
+	workflowHandler.RegisterRoutes(apiV1, companyMiddleware)
+
+	// 热点相关
+	hotspotHandler := api.NewHotspotHandler(s.hotspotService)
+	hotspotHandler.RegisterRoutes(apiV1, companyMiddleware)
+
+	// 内容发布平台与凭证
+	publishingHandler := api.NewPublishingHandler(s.publishingService)
+	publishingHandler.RegisterRoutes(apiV1, companyMiddleware)
+
+	// 任务调度说明
+	schedulingHandler := api.NewSchedulingHandler(s.taskScheduler, s.taskService)
+	schedulingHandler.RegisterRoutes(apiV1, companyMiddleware)
+	schedulingHandler.RegisterOpsRoutes(apiV1, middleware.OpsTokenRequired(s.opsToken))
 
 	// 对话相关
 	chatHandler := api.NewChatHandler(s.chatService)
@@ -27,6 +27,7 @@ const (
 	MessageTypeTaskUpdate     MessageType = "task.update"
 	MessageTypeTaskCreated    MessageType = "task.created"
 	MessageTypeTaskCompleted  MessageType = "task.completed"
+	MessageTypeTaskApproval   MessageType = "task.approval"
 	MessageTypeEmployeeUpdate MessageType = "employee.update"
 	MessageTypeEmployeeOnline MessageType = "employee.online"
 	MessageTypeEmployeeOffline MessageType = "employee.offline"
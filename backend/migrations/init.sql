@@ -41,6 +41,17 @@ CREATE TABLE IF NOT EXISTS companies (
 
 CREATE INDEX IF NOT EXISTS idx_companies_user_id ON companies(user_id);
 
+-- 公司发布凭证表（各平台的令牌、密钥或自有 webhook 地址，接口只返回键名；配置加密密钥时加密存储）
+CREATE TABLE IF NOT EXISTS publishing_credentials (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    platform VARCHAR(50) NOT NULL,
+    credentials JSONB NOT NULL DEFAULT '{}',  -- 如 {"webhook_url": "...", "token": "...", "secret": "..."}，加密时为密文字符串
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(company_id, platform)
+);
+
 -- ========================================
 -- 3. 技能卡相关表
 -- ========================================
@@ -121,6 +132,37 @@ CREATE TABLE IF NOT EXISTS employee_skills (
 CREATE INDEX IF NOT EXISTS idx_employee_skills_employee_id ON employee_skills(employee_id);
 CREATE INDEX IF NOT EXISTS idx_employee_skills_skill_card_id ON employee_skills(skill_card_id);
 
+-- 员工-任务分配表（跟踪员工当前承担的任务，用于并发容量控制）
+CREATE TABLE IF NOT EXISTS employee_task_assignments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    task_id UUID NOT NULL,
+    decision JSONB,  -- 调度决策说明（候选员工排名及各因子评分）
+    assigned_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    released_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_employee_task_assignments_employee_id ON employee_task_assignments(employee_id);
+CREATE INDEX IF NOT EXISTS idx_employee_task_assignments_task_id ON employee_task_assignments(task_id, assigned_at DESC);
+CREATE INDEX IF NOT EXISTS idx_employee_task_assignments_company_id ON employee_task_assignments(company_id, assigned_at DESC);
+CREATE INDEX IF NOT EXISTS idx_employee_task_assignments_active ON employee_task_assignments(company_id, employee_id) WHERE released_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_employee_task_assignments_active_task ON employee_task_assignments(task_id) WHERE released_at IS NULL;
+
+-- 员工工作日历表（工作时段与停工时段，未配置时段的员工全天可用）
+CREATE TABLE IF NOT EXISTS employee_calendars (
+    employee_id UUID PRIMARY KEY REFERENCES employees(id) ON DELETE CASCADE,
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    timezone VARCHAR(64),  -- 为空时使用公司时区 companies.settings->>'timezone'
+    windows JSONB DEFAULT '[]',  -- 每周工作时段 [{weekdays, start, end}]，结束早于开始表示跨夜班
+    blackouts JSONB DEFAULT '[]',  -- 停工时段 [{start, end, reason}]
+    auto_offline BOOLEAN DEFAULT FALSE,  -- 员工是否由日历自动下线（时段开始时自动上线）
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_employee_calendars_company_id ON employee_calendars(company_id);
+
 -- ========================================
 -- 5. 任务相关表
 -- ========================================
@@ -151,6 +193,7 @@ CREATE TABLE IF NOT EXISTS tasks (
     input_data JSONB DEFAULT '{}',
     output_data JSONB,
     error_message TEXT,
+    compensation JSONB,                           -- 失败或取消后撤销已完成步骤的结果（含是否部分补偿）
     
     -- 时间
     started_at TIMESTAMP WITH TIME ZONE,
@@ -169,13 +212,16 @@ CREATE TABLE IF NOT EXISTS task_steps (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
     task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
     
-    -- 步骤信息
+    -- 步骤信息（attempt 为任务的第几次运行，node_id 为工作流节点）
+    attempt INTEGER NOT NULL DEFAULT 1,
+    node_id VARCHAR(100) NOT NULL,
     step_order INTEGER NOT NULL,
     name VARCHAR(200) NOT NULL,
     skill_card_id UUID REFERENCES skill_cards(id),
     
-    -- 状态
-    status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed', 'skipped')),
+    -- 状态（reused 表示沿用了上一次运行的输出；工作流失败或取消后，已完成步骤的补偿结果记为 compensated/compensation_failed）
+    status VARCHAR(20) DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'completed', 'failed', 'skipped', 'cancelled', 'compensated', 'compensation_failed')),
+    reused BOOLEAN DEFAULT false,
     
     -- 输入输出
     input_data JSONB,
@@ -188,12 +234,178 @@ CREATE TABLE IF NOT EXISTS task_steps (
     completed_at TIMESTAMP WITH TIME ZONE,
     
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    
+    UNIQUE(task_id, attempt, node_id)
 );
 
 CREATE INDEX IF NOT EXISTS idx_task_steps_task_id ON task_steps(task_id);
 CREATE INDEX IF NOT EXISTS idx_task_steps_status ON task_steps(status);
 
+-- 任务租约表（执行中的任务由心跳续租，租约过期视为执行器失联）
+CREATE TABLE IF NOT EXISTS task_leases (
+    task_id UUID PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    renewed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_leases_expires_at ON task_leases(expires_at);
+
+-- 调度状态表（单行，多个调度实例依次加锁派发任务）
+CREATE TABLE IF NOT EXISTS dispatch_state (
+    id SMALLINT PRIMARY KEY CHECK (id = 1),
+    virtual_time DOUBLE PRECISION NOT NULL DEFAULT 0,  -- 公平调度的虚拟时间
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO dispatch_state (id) VALUES (1) ON CONFLICT (id) DO NOTHING;
+
+-- 公平调度完成标记表（各公司超过虚拟时间的完成标记）
+CREATE TABLE IF NOT EXISTS fair_share_tags (
+    company_id UUID PRIMARY KEY REFERENCES companies(id) ON DELETE CASCADE,
+    finish_tag DOUBLE PRECISION NOT NULL
+);
+
+-- 任务恢复记录表（租约过期后的重新排队或失败处理）
+CREATE TABLE IF NOT EXISTS task_recoveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    employee_id UUID NOT NULL REFERENCES employees(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('requeued', 'failed')),
+    employee_status VARCHAR(20) NOT NULL,  -- 恢复后员工的状态
+    reason TEXT,
+    lease_expired_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    recovered_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_recoveries_task_id ON task_recoveries(task_id, recovered_at);
+
+-- 任务审批表（工作流审批节点等待人工审核的内容及审批结果）
+CREATE TABLE IF NOT EXISTS task_approvals (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    node_id VARCHAR(100) NOT NULL,
+    node_name VARCHAR(200),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected', 'edited')),
+    content JSONB DEFAULT '{}',
+    edited_content JSONB,
+    default_action VARCHAR(20) NOT NULL CHECK (default_action IN ('approve', 'reject')),
+    decided_by UUID REFERENCES users(id) ON DELETE SET NULL,  -- 为空表示超时后按默认动作处理
+    comment TEXT,
+    timed_out BOOLEAN DEFAULT false,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    decided_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_approvals_task_id ON task_approvals(task_id, created_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_task_approvals_pending ON task_approvals(task_id, node_id) WHERE status = 'pending';
+
+-- 任务执行尝试表（失败任务重试时保留的历次执行，下一次执行复用其中成功步骤的输出）
+CREATE TABLE IF NOT EXISTS task_attempts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    number INT NOT NULL,
+    temporal_workflow_id VARCHAR(255),
+    temporal_run_id VARCHAR(255),
+    status VARCHAR(20) NOT NULL,
+    error_message TEXT,
+    failed_step VARCHAR(100),
+    step_outputs JSONB DEFAULT '{}',   -- 成功步骤的输出，按步骤ID索引
+    retry_from VARCHAR(100),           -- 下一次执行从该步骤重新开始
+    retry_input JSONB,                 -- 调用方修改后的该步骤输入
+    retried_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    started_at TIMESTAMP WITH TIME ZONE,
+    ended_at TIMESTAMP WITH TIME ZONE,
+    retried_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (task_id, number)
+);
+
+-- 任务计划表（一次性定时或按cron周期创建任务，由Temporal Schedule触发）
+CREATE TABLE IF NOT EXISTS task_schedules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    name VARCHAR(200) NOT NULL,
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('once', 'cron')),
+    cron_expression VARCHAR(100),
+    run_at TIMESTAMP WITH TIME ZONE,
+    timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',  -- IANA时区，cron按该时区解释
+    task_template JSONB NOT NULL,                 -- 每次触发时创建的任务
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'paused', 'finished')),
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    last_run_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_schedules_company_id ON task_schedules(company_id);
+
+-- 任务计划执行记录表（每次触发创建的任务）
+CREATE TABLE IF NOT EXISTS task_schedule_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    schedule_id UUID NOT NULL REFERENCES task_schedules(id) ON DELETE CASCADE,
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    task_id UUID NOT NULL REFERENCES tasks(id) ON DELETE CASCADE,
+    workflow_id VARCHAR(255) NOT NULL UNIQUE,  -- 触发的Temporal工作流，保证重试时不重复创建任务
+    triggered_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_task_schedule_runs_schedule_id ON task_schedule_runs(schedule_id, triggered_at);
+
+-- 工作流模板表（company_id 为空的是系统预置模板）
+CREATE TABLE IF NOT EXISTS workflow_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    company_id UUID REFERENCES companies(id) ON DELETE CASCADE,
+    key VARCHAR(100) NOT NULL,                    -- 模板标识，作为任务类型
+    name VARCHAR(200) NOT NULL,
+    description TEXT,
+    category VARCHAR(50) NOT NULL CHECK (category IN ('content', 'hotspot', 'operations', 'custom')),
+    thumbnail VARCHAR(500),                       -- 缩略图URL
+    is_system BOOLEAN DEFAULT false,
+    is_public BOOLEAN NOT NULL DEFAULT false,     -- 是否共享到社区
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_templates_company_id ON workflow_templates(company_id);
+CREATE INDEX IF NOT EXISTS idx_workflow_templates_category ON workflow_templates(category);
+
+-- 工作流模板版本表（语义化版本，发布后不再修改）
+CREATE TABLE IF NOT EXISTS workflow_template_versions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    template_id UUID NOT NULL REFERENCES workflow_templates(id) ON DELETE CASCADE,
+    version VARCHAR(20) NOT NULL,
+    major INTEGER NOT NULL,                       -- 版本号拆分存储，便于按版本排序
+    minor INTEGER NOT NULL,
+    patch INTEGER NOT NULL,
+    definition JSONB NOT NULL,                    -- 工作流定义（节点与连线）
+    param_schema JSONB NOT NULL DEFAULT '{"type": "object", "properties": {}}',  -- 参数JSON Schema
+    node_count INTEGER NOT NULL DEFAULT 0,
+    estimated_seconds INTEGER NOT NULL DEFAULT 0, -- 预估耗时（秒）
+    changelog TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (template_id, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_template_versions_order ON workflow_template_versions(template_id, major DESC, minor DESC, patch DESC);
+
+-- 工作流模板使用记录（由模板创建的任务，用于统计使用次数与成功率）
+CREATE TABLE IF NOT EXISTS workflow_template_uses (
+    task_id UUID PRIMARY KEY REFERENCES tasks(id) ON DELETE CASCADE,
+    template_id UUID NOT NULL REFERENCES workflow_templates(id) ON DELETE CASCADE,
+    version_id UUID NOT NULL REFERENCES workflow_template_versions(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_workflow_template_uses_template_id ON workflow_template_uses(template_id);
+
 -- ========================================
 -- 6. 对话相关表
 -- ========================================
@@ -227,7 +439,67 @@ CREATE INDEX IF NOT EXISTS idx_chat_messages_session_id ON chat_messages(session
 CREATE INDEX IF NOT EXISTS idx_chat_messages_created_at ON chat_messages(created_at);
 
 -- ========================================
--- 7. 系统预置数据
+-- 7. 热点相关表
+-- ========================================
+
+-- 热点快照表（每次热点分析的评分结果，下一次分析据此计算趋势）
+CREATE TABLE IF NOT EXISTS hotspot_snapshots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    platform VARCHAR(50) NOT NULL,
+    category VARCHAR(100) NOT NULL DEFAULT '',
+    topics JSONB NOT NULL DEFAULT '[]',  -- [{key, title, url, sources, heat, trend, delta}]，按热度降序
+    captured_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_hotspot_snapshots_latest ON hotspot_snapshots(company_id, platform, category, captured_at DESC);
+
+-- 热点上传表（公司上传的 CSV，最新一份作为对应平台的数据源）
+CREATE TABLE IF NOT EXISTS hotspot_uploads (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    company_id UUID NOT NULL REFERENCES companies(id) ON DELETE CASCADE,
+    platform VARCHAR(50) NOT NULL,
+    filename VARCHAR(255) NOT NULL DEFAULT '',
+    content BYTEA NOT NULL,
+    item_count INTEGER NOT NULL DEFAULT 0,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_hotspot_uploads_latest ON hotspot_uploads(company_id, platform, created_at DESC);
+
+-- ========================================
+-- 8. 事件相关表
+-- ========================================
+
+-- 事件发件箱表（与业务数据在同一事务中写入，由中继按 id 顺序投递到事件总线，至少投递一次）
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id BIGSERIAL PRIMARY KEY,              -- 投递顺序
+    event_id VARCHAR(64) NOT NULL UNIQUE,
+    event_type VARCHAR(100) NOT NULL,
+    event JSONB NOT NULL,                  -- 完整事件 {id, type, source, payload, metadata, timestamp}
+    attempts INTEGER NOT NULL DEFAULT 0,   -- 投递失败次数，达到上限后不再投递
+    last_error TEXT NOT NULL DEFAULT '',
+    retry_at TIMESTAMP WITH TIME ZONE,     -- 失败后的下次重试时间
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    published_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(id) WHERE published_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_outbox_events_published ON outbox_events(published_at) WHERE published_at IS NOT NULL;
+
+-- 已消费事件表（消费者按事件 ID 去重，重复投递的事件只处理一次）
+CREATE TABLE IF NOT EXISTS consumed_events (
+    consumer VARCHAR(100) NOT NULL,
+    event_id VARCHAR(64) NOT NULL,
+    consumed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (consumer, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_consumed_events_consumed_at ON consumed_events(consumed_at);
+
+-- ========================================
+-- 9. 系统预置数据
 -- ========================================
 
 -- 插入系统预置技能卡
@@ -270,8 +542,72 @@ INSERT INTO skill_cards (id, name, description, category, kernel_type, kernel_co
 )
 ON CONFLICT (id) DO NOTHING;
 
+-- 插入系统预置工作流模板（对应内容创作、热点追踪、自动运营流程）
+INSERT INTO workflow_templates (id, company_id, key, name, description, category, is_system) VALUES
+('b0000001-0000-0000-0000-000000000001', NULL, 'content_creation', '内容创作', '按主题生成一篇小红书种草笔记', 'content', true),
+('b0000001-0000-0000-0000-000000000002', NULL, 'hotspot_tracking', '热点追踪', '分析平台热点，并据此生成内容建议', 'hotspot', true),
+('b0000001-0000-0000-0000-000000000003', NULL, 'auto_ops', '自动运营', '围绕热点生成内容，经人工审核后发布到平台', 'operations', true)
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO workflow_template_versions (id, template_id, version, major, minor, patch, definition, param_schema, node_count, estimated_seconds, changelog) VALUES
+(
+    'b0000002-0000-0000-0000-000000000001',
+    'b0000001-0000-0000-0000-000000000001',
+    '1.0.0', 1, 0, 0,
+    '{"version": 1, "name": "内容创作", "type": "content_creation", "nodes": [{"id": "write", "name": "文案生成", "type": "skill", "skill_card_id": "a0000001-0000-0000-0000-000000000002", "inputs": {"product": "$.input.topic", "style": "$.input.style", "keywords": "$.input.keywords"}}]}',
+    '{"type": "object", "required": ["topic"], "properties": {"topic": {"type": "string", "title": "主题", "minLength": 2, "maxLength": 200}, "style": {"type": "string", "title": "风格偏好", "default": "种草"}, "keywords": {"type": "array", "title": "关键词", "items": {"type": "string"}}}}',
+    1, 120,
+    '系统预置'
+),
+(
+    'b0000002-0000-0000-0000-000000000002',
+    'b0000001-0000-0000-0000-000000000002',
+    '1.0.0', 1, 0, 0,
+    '{"version": 1, "name": "热点追踪", "type": "hotspot_tracking", "nodes": [{"id": "analyze", "name": "热点分析", "type": "skill", "skill_card_id": "a0000001-0000-0000-0000-000000000001", "inputs": {"topic": "$.input.topic", "platform": "$.input.platform"}}, {"id": "suggest", "name": "内容建议生成", "type": "skill", "skill_card_id": "a0000001-0000-0000-0000-000000000002", "inputs": {"product": "$.nodes.analyze.output.summary", "keywords": "$.nodes.analyze.output.keywords"}}], "edges": [{"from": "analyze", "to": "suggest"}]}',
+    '{"type": "object", "required": ["platform"], "properties": {"platform": {"type": "string", "title": "目标平台", "enum": ["xiaohongshu", "douyin", "weibo"]}, "topic": {"type": "string", "title": "关注话题", "default": "全站热点"}}}',
+    2, 300,
+    '系统预置'
+),
+(
+    'b0000002-0000-0000-0000-000000000003',
+    'b0000001-0000-0000-0000-000000000003',
+    '1.0.0', 1, 0, 0,
+    '{"version": 1, "name": "自动运营", "type": "auto_ops", "nodes": [{"id": "analyze", "name": "热点分析", "type": "skill", "skill_card_id": "a0000001-0000-0000-0000-000000000001", "inputs": {"topic": "$.input.topic", "platform": "$.input.platform"}}, {"id": "generate", "name": "内容生成", "type": "skill", "skill_card_id": "a0000001-0000-0000-0000-000000000002", "inputs": {"product": "$.nodes.analyze.output.summary", "keywords": "$.nodes.analyze.output.keywords", "style": "$.input.style"}}, {"id": "review", "name": "内容审核", "type": "approval", "timeout_seconds": 86400, "default_action": "reject"}], "edges": [{"from": "analyze", "to": "generate"}, {"from": "generate", "to": "review"}]}',
+    '{"type": "object", "required": ["platform"], "properties": {"platform": {"type": "string", "title": "发布平台", "enum": ["xiaohongshu", "douyin", "weibo"], "default": "xiaohongshu"}, "topic": {"type": "string", "title": "关注话题", "default": "全站热点"}, "style": {"type": "string", "title": "风格偏好", "default": "种草"}}}',
+    3, 600,
+    '系统预置'
+),
+(
+    'b0000002-0000-0000-0000-000000000004',
+    'b0000001-0000-0000-0000-000000000003',
+    '1.1.0', 1, 1, 0,
+    '{"version": 1, "name": "自动运营", "type": "auto_ops", "nodes": [{"id": "analyze", "name": "热点分析", "type": "skill", "skill_card_id": "a0000001-0000-0000-0000-000000000001", "inputs": {"topic": "$.input.topic", "platform": "$.input.platform"}}, {"id": "generate", "name": "内容生成", "type": "skill", "skill_card_id": "a0000001-0000-0000-0000-000000000002", "inputs": {"product": "$.nodes.analyze.output.summary", "keywords": "$.nodes.analyze.output.keywords", "style": "$.input.style"}}, {"id": "review", "name": "内容审核", "type": "approval", "timeout_seconds": 86400, "default_action": "reject"}, {"id": "publish", "name": "内容发布", "type": "publish"}], "edges": [{"from": "analyze", "to": "generate"}, {"from": "generate", "to": "review"}, {"from": "review", "to": "publish"}]}',
+    '{"type": "object", "required": ["platform"], "properties": {"platform": {"type": "string", "title": "发布平台", "enum": ["xiaohongshu", "douyin", "weibo"], "default": "xiaohongshu"}, "topic": {"type": "string", "title": "关注话题", "default": "全站热点"}, "style": {"type": "string", "title": "风格偏好", "default": "种草"}, "publish_mode": {"type": "string", "title": "发布方式", "enum": ["publish", "draft"], "default": "publish"}}}',
+    4, 660,
+    '审核通过的内容发布到平台，后续失败时撤回'
+),
+(
+    'b0000002-0000-0000-0000-000000000005',
+    'b0000001-0000-0000-0000-000000000002',
+    '1.1.0', 1, 1, 0,
+    '{"version": 1, "name": "热点追踪", "type": "hotspot_tracking", "nodes": [{"id": "analyze", "name": "热点分析", "type": "hotspots"}, {"id": "suggest", "name": "内容建议生成", "type": "skill", "skill_card_id": "a0000001-0000-0000-0000-000000000002", "inputs": {"product": "$.nodes.analyze.output.summary", "keywords": "$.nodes.analyze.output.keywords"}}], "edges": [{"from": "analyze", "to": "suggest"}]}',
+    '{"type": "object", "required": ["platform"], "properties": {"platform": {"type": "string", "title": "目标平台", "enum": ["xiaohongshu", "douyin", "weibo"]}, "category": {"type": "string", "title": "热点分类"}}}',
+    2, 300,
+    '热点分析改为从配置的数据源抓取热点，去重后对比上一次分析评估趋势'
+),
+(
+    'b0000002-0000-0000-0000-000000000006',
+    'b0000001-0000-0000-0000-000000000003',
+    '1.2.0', 1, 2, 0,
+    '{"version": 1, "name": "自动运营", "type": "auto_ops", "nodes": [{"id": "analyze", "name": "热点分析", "type": "hotspots"}, {"id": "generate", "name": "内容生成", "type": "skill", "skill_card_id": "a0000001-0000-0000-0000-000000000002", "inputs": {"product": "$.nodes.analyze.output.summary", "keywords": "$.nodes.analyze.output.keywords", "style": "$.input.style"}}, {"id": "review", "name": "内容审核", "type": "approval", "timeout_seconds": 86400, "default_action": "reject"}, {"id": "publish", "name": "内容发布", "type": "publish"}], "edges": [{"from": "analyze", "to": "generate"}, {"from": "generate", "to": "review"}, {"from": "review", "to": "publish"}]}',
+    '{"type": "object", "required": ["platform"], "properties": {"platform": {"type": "string", "title": "发布平台", "enum": ["xiaohongshu", "douyin", "weibo"], "default": "xiaohongshu"}, "category": {"type": "string", "title": "热点分类"}, "style": {"type": "string", "title": "风格偏好", "default": "种草"}, "publish_mode": {"type": "string", "title": "发布方式", "enum": ["publish", "draft"], "default": "publish"}}}',
+    4, 660,
+    '热点分析改为从配置的数据源抓取热点，去重后对比上一次分析评估趋势'
+)
+ON CONFLICT (id) DO NOTHING;
+
 -- ========================================
--- 8. 更新时间触发器
+-- 10. 更新时间触发器
 -- ========================================
 
 CREATE OR REPLACE FUNCTION update_updated_at_column()
@@ -289,18 +625,27 @@ CREATE TRIGGER update_users_updated_at BEFORE UPDATE ON users FOR EACH ROW EXECU
 DROP TRIGGER IF EXISTS update_companies_updated_at ON companies;
 CREATE TRIGGER update_companies_updated_at BEFORE UPDATE ON companies FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_publishing_credentials_updated_at ON publishing_credentials;
+CREATE TRIGGER update_publishing_credentials_updated_at BEFORE UPDATE ON publishing_credentials FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
 DROP TRIGGER IF EXISTS update_skill_cards_updated_at ON skill_cards;
 CREATE TRIGGER update_skill_cards_updated_at BEFORE UPDATE ON skill_cards FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
 DROP TRIGGER IF EXISTS update_employees_updated_at ON employees;
 CREATE TRIGGER update_employees_updated_at BEFORE UPDATE ON employees FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_employee_calendars_updated_at ON employee_calendars;
+CREATE TRIGGER update_employee_calendars_updated_at BEFORE UPDATE ON employee_calendars FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
 DROP TRIGGER IF EXISTS update_tasks_updated_at ON tasks;
 CREATE TRIGGER update_tasks_updated_at BEFORE UPDATE ON tasks FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
 DROP TRIGGER IF EXISTS update_task_steps_updated_at ON task_steps;
 CREATE TRIGGER update_task_steps_updated_at BEFORE UPDATE ON task_steps FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
+DROP TRIGGER IF EXISTS update_workflow_templates_updated_at ON workflow_templates;
+CREATE TRIGGER update_workflow_templates_updated_at BEFORE UPDATE ON workflow_templates FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
+
 DROP TRIGGER IF EXISTS update_chat_sessions_updated_at ON chat_sessions;
 CREATE TRIGGER update_chat_sessions_updated_at BEFORE UPDATE ON chat_sessions FOR EACH ROW EXECUTE FUNCTION update_updated_at_column();
 
@@ -0,0 +1,36 @@
+package secret
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBox(t *testing.T) {
+	box, err := NewBox("publishing-key")
+	require.NoError(t, err)
+
+	sealed, err := box.Seal([]byte(`{"token":"t0k3n"}`))
+	require.NoError(t, err)
+	assert.NotContains(t, sealed, "t0k3n")
+
+	again, err := box.Seal([]byte(`{"token":"t0k3n"}`))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every value has its own nonce")
+
+	plaintext, err := box.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, `{"token":"t0k3n"}`, string(plaintext))
+
+	other, err := NewBox("another-key")
+	require.NoError(t, err)
+	_, err = other.Open(sealed)
+	assert.Error(t, err)
+
+	_, err = box.Open("not base64!")
+	assert.Error(t, err)
+
+	_, err = NewBox("")
+	assert.Error(t, err)
+}